@@ -1,5 +1,7 @@
 package api
 
+import "strings"
+
 // SchemaVersion is the current schema version string.
 const SchemaVersion = "v1"
 
@@ -18,6 +20,9 @@ type Topology struct {
 	Version string `json:"version"`
 	// Table is the SQLite table name to query (default: "results").
 	Table string `json:"table,omitempty"`
+	// RecordColumn is the column of Table holding each record's JSON
+	// (default: "record").
+	RecordColumn string `json:"record_column,omitempty"`
 	// Diagrams defines named diagram views that can be rendered via the
 	// {{diagram "name"}} template function. Each entry maps a diagram name
 	// to its definition. If absent, {{diagram "system"}} still works using
@@ -27,10 +32,49 @@ type Topology struct {
 	// included by nodes via the Include field. Avoids duplicating file
 	// entries across many construct types.
 	FileSets map[string][]Leaf `json:"file_sets,omitempty"`
+	// LeftDelim and RightDelim replace the {{ and }} action delimiters in
+	// every Name, ContentTemplate, Parent, and Refs template of the schema,
+	// for projecting content that itself contains {{ }} (mustache, Vue,
+	// Helm). Either may be left empty to keep its default.
+	LeftDelim  string `json:"left_delim,omitempty"`
+	RightDelim string `json:"right_delim,omitempty"`
 	// Root nodes of the filesystem.
 	Nodes []Node `json:"nodes,omitempty"`
 }
 
+// Delims returns the schema's template action delimiters, defaulting to
+// {{ and }}. Safe on a nil Topology.
+func (t *Topology) Delims() (left, right string) {
+	left, right = "{{", "}}"
+	if t != nil && t.LeftDelim != "" {
+		left = t.LeftDelim
+	}
+	if t != nil && t.RightDelim != "" {
+		right = t.RightDelim
+	}
+	return left, right
+}
+
+// RecordSource returns the SQLite table and JSON column records are read
+// from, defaulting to "results" and "record". Safe on a nil Topology.
+func (t *Topology) RecordSource() (table, column string) {
+	table, column = "results", "record"
+	if t != nil && t.Table != "" {
+		table = t.Table
+	}
+	if t != nil && t.RecordColumn != "" {
+		column = t.RecordColumn
+	}
+	return table, column
+}
+
+// IsTemplate reports whether s contains a template action, i.e. the
+// schema's left delimiter.
+func (t *Topology) IsTemplate(s string) bool {
+	left, _ := t.Delims()
+	return strings.Contains(s, left)
+}
+
 // Node represents a directory in the filesystem.
 // It can contain other nodes or leaves (files).
 type Node struct {
@@ -43,8 +87,23 @@ type Node struct {
 	// directories for JSON-projected data (e.g., tool names across MCP servers).
 	Refs []string `json:"refs,omitempty"`
 	// SkipSelfMatch prevents the selector from matching the current context node itself.
-	// Useful for recursive schemas to avoid infinite loops.
+	// Useful for recursive schemas to avoid infinite loops. Ingestion rejects
+	// tree-sitter children that select their parent's node type without it.
 	SkipSelfMatch bool `json:"skip_self_match,omitempty"`
+	// Recursive re-applies this node to each of its own matches, projecting
+	// arbitrarily deep structures (e.g., HTML element trees) without
+	// unrolling the schema by hand. Only matches directly owned by the
+	// current context are projected at each level; deeper matches appear
+	// under their nearest matched ancestor. Implies SkipSelfMatch.
+	// Sibling name collisions are disambiguated as name[2], name[3], ...
+	Recursive bool `json:"recursive,omitempty"`
+	// Parent places each match under a subdirectory of the enclosing
+	// directory instead of directly inside it. It is a template rendered
+	// against the match; slashes create nested directories on demand. E.g.
+	// "{{.receiver}}/methods" under types/ nests Go methods as
+	// types/Greeter/methods/Greet next to the Greeter type itself. An empty
+	// rendering leaves the match in the enclosing directory.
+	Parent string `json:"parent,omitempty"`
 	// Language hint for multi-language schemas (e.g., "go", "terraform", "python").
 	// Used to filter nodes during ingestion to prevent cross-language query errors.
 	Language string `json:"language,omitempty"`
@@ -52,14 +111,36 @@ type Node struct {
 	// The referenced leaves are appended to this node's Files during
 	// schema resolution.
 	Include []string `json:"include,omitempty"`
+	// SourceLeaf names the leaf in Files that holds the construct's code,
+	// "source" by default (see SourceLeafName). Its calls are indexed for
+	// callers/, it keeps doc comments and is written even when empty, and
+	// --signatures-only trims it. Virtual files that read a construct's code
+	// (_origin, callees/, ...) still look for a leaf named "source".
+	SourceLeaf string `json:"source_leaf,omitempty"`
+	// MergeClauses projects the matches of one source file that render to
+	// the same directory as one construct, whose code leaf spans them all,
+	// e.g. the equations of a Haskell function or the clauses of an Elixir
+	// def. Without it, later matches are renamed as colliding constructs.
+	MergeClauses bool `json:"merge_clauses,omitempty"`
 	// Children directories.
 	Children []Node `json:"children,omitempty"`
 	// Files within this directory.
 	Files []Leaf `json:"files,omitempty"`
 }
 
+// SourceLeafName returns the name of the node's code leaf: SourceLeaf, or
+// "source" when unset.
+func (n *Node) SourceLeafName() string {
+	if n.SourceLeaf != "" {
+		return n.SourceLeaf
+	}
+	return "source"
+}
+
 // ResolveIncludes expands all Include references in the schema tree,
-// appending the referenced FileSets leaves to each node's Files.
+// appending the referenced FileSets leaves to each node's Files and
+// clearing Include, so resolving again (or re-loading the resolved schema
+// from its JSON) doesn't add the leaves twice.
 // Call this once after parsing the schema, before ingestion or materialization.
 func (t *Topology) ResolveIncludes() {
 	if len(t.FileSets) == 0 {
@@ -75,6 +156,7 @@ func resolveNodes(nodes []Node, sets map[string][]Leaf) {
 				nodes[i].Files = append(nodes[i].Files, leaves...)
 			}
 		}
+		nodes[i].Include = nil
 		resolveNodes(nodes[i].Children, sets)
 	}
 }
@@ -92,6 +174,13 @@ type Leaf struct {
 	// "lsp_diagnostics", "lsp_defs", "lsp_refs".
 	// Mutually exclusive with ContentTemplate.
 	ContentSource string `json:"content_source,omitempty"`
+	// Command, when set, computes the file's content: the rendered
+	// ContentTemplate is piped to it on stdin (run with sh -c) and its
+	// stdout becomes the content, e.g. a doc generator or a disassembler.
+	// It runs on first read and the output is kept until the rendered
+	// content changes. The file is read-only. Mounts only run commands
+	// with --allow-exec, and only for data ingested into memory.
+	Command string `json:"command,omitempty"`
 	// Attributes defines file permissions/metadata (optional).
 	Attributes *Attributes `json:"attributes,omitempty"`
 }
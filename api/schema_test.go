@@ -95,6 +95,25 @@ func TestDiagramDef_CoexistsWithFileSets(t *testing.T) {
 	assert.Equal(t, "source", topo.Nodes[0].Files[0].Name)
 }
 
+func TestResolveIncludes_RoundTrip(t *testing.T) {
+	topo := Topology{
+		Version:  "v1",
+		FileSets: map[string][]Leaf{"common": {{Name: "source", ContentTemplate: "{{.source}}"}}},
+		Nodes:    []Node{{Name: "root", Selector: "$", Include: []string{"common"}}},
+	}
+	topo.ResolveIncludes()
+	assert.Empty(t, topo.Nodes[0].Include)
+
+	// The resolved schema, serialized and resolved again, keeps one copy
+	// of the included leaves.
+	data, err := json.Marshal(topo)
+	require.NoError(t, err)
+	var reloaded Topology
+	require.NoError(t, json.Unmarshal(data, &reloaded))
+	reloaded.ResolveIncludes()
+	assert.Equal(t, topo.Nodes, reloaded.Nodes)
+}
+
 func TestDiagramDef_AllLayouts(t *testing.T) {
 	for _, layout := range []string{"TD", "LR", "BT", "RL"} {
 		t.Run(layout, func(t *testing.T) {
@@ -109,3 +128,19 @@ func TestDiagramDef_AllLayouts(t *testing.T) {
 		})
 	}
 }
+
+func TestTopology_Delims(t *testing.T) {
+	var topo Topology
+	require.NoError(t, json.Unmarshal([]byte(`{"version":"v1","left_delim":"[[","right_delim":"]]"}`), &topo))
+	left, right := topo.Delims()
+	assert.Equal(t, "[[", left)
+	assert.Equal(t, "]]", right)
+	assert.True(t, topo.IsTemplate("[[.name]]"))
+	assert.False(t, topo.IsTemplate("{{ literal }}"))
+
+	var nilTopo *Topology
+	left, right = nilTopo.Delims()
+	assert.Equal(t, "{{", left)
+	assert.Equal(t, "}}", right)
+	assert.True(t, nilTopo.IsTemplate("{{.name}}"))
+}
@@ -31,6 +31,8 @@ type MountMetadata struct {
 	Timestamp  time.Time `json:"timestamp"`
 	Writable   bool      `json:"writable"`
 	Addr       string    `json:"addr,omitempty"` // listen address for MCP HTTP servers
+
+	ControlSocket string `json:"control_socket,omitempty"` // unix socket for stats/invalidate/reload (mounts only)
 }
 
 // agentPromptTemplate is the instruction file generated for LLM agents.
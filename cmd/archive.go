@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// tarballExts are the source-tree archive extensions --data accepts, each
+// with its compression.
+var tarballExts = []struct{ ext, compression string }{
+	{".tar", ""},
+	{".tar.gz", "gzip"},
+	{".tgz", "gzip"},
+	{".tar.zst", "zstd"},
+	{".tzst", "zstd"},
+}
+
+// tarballCompression reports whether path names a tar archive of a source
+// tree and, if so, how it is compressed ("" for none).
+func tarballCompression(path string) (compression string, ok bool) {
+	lower := strings.ToLower(path)
+	for _, t := range tarballExts {
+		if strings.HasSuffix(lower, t.ext) {
+			return t.compression, true
+		}
+	}
+	return "", false
+}
+
+// extractTarball extracts the tar archive src into a new temp directory
+// and returns the directory to ingest: the archive's single top-level
+// directory when it has one (as in GitHub's repo-<sha>/ snapshots), else
+// the temp directory. Directories ingestion skips (.git, node_modules)
+// aren't extracted, nor are links and special files. The caller removes
+// tmpDir.
+func extractTarball(src, compression string) (root, tmpDir string, err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = f.Close() }()
+	r, finish, err := decompress(f, compression)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", src, err)
+	}
+
+	tmpDir, err = os.MkdirTemp("", "mache-archive-*")
+	if err != nil {
+		_ = finish()
+		return "", "", err
+	}
+	err = extractTar(tar.NewReader(r), tmpDir)
+	if ferr := finish(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return "", "", fmt.Errorf("%s: %w", src, err)
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return "", "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(tmpDir, entries[0].Name()), tmpDir, nil
+	}
+	return tmpDir, tmpDir, nil
+}
+
+// decompress returns the decompressed content of r; finish reports
+// whether decompression failed once it has been read.
+func decompress(r io.Reader, compression string) (out io.Reader, finish func() error, err error) {
+	switch compression {
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr.Close, nil
+	case "zstd":
+		// The standard library has no zstd decoder; use the zstd command.
+		cmd := exec.Command("zstd", "-dc")
+		cmd.Stdin = r
+		pipe, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, nil, fmt.Errorf("reading a zstd archive needs the zstd command: %w", err)
+		}
+		return pipe, func() error {
+			_, _ = io.Copy(io.Discard, pipe)
+			if err := cmd.Wait(); err != nil {
+				return fmt.Errorf("zstd: %w", err)
+			}
+			return nil
+		}, nil
+	}
+	return r, func() error { return nil }, nil
+}
+
+// extractTar writes tr's directories and regular files under dir, keeping
+// their modification times.
+func extractTar(tr *tar.Reader, dir string) error {
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name == "." {
+			continue
+		}
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("entry %q escapes the archive", hdr.Name)
+		}
+		if skippedArchivePath(name, hdr.Typeflag == tar.TypeDir) {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := writeTarFile(tr, target, hdr); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		_ = os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+	}
+}
+
+// skippedArchivePath reports whether name lies in a directory ingestion
+// skips, or is one when isDir.
+func skippedArchivePath(name string, isDir bool) bool {
+	parts := strings.Split(name, "/")
+	if !isDir {
+		parts = parts[:len(parts)-1]
+	}
+	for _, p := range parts {
+		if shouldSkipDir(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeTarFile(tr *tar.Reader, target string, hdr *tar.Header) error {
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode().Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, tr); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tarEntry struct {
+	name, body string
+	typ        byte
+}
+
+// writeTar writes entries as a tar archive to path, gzipped when gz.
+func writeTar(t *testing.T, path string, gz bool, entries []tarEntry) time.Time {
+	t.Helper()
+	mtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typ, Mode: 0o644, Size: int64(len(e.body)), ModTime: mtime}
+		switch e.typ {
+		case tar.TypeDir:
+			hdr.Mode, hdr.Size = 0o755, 0
+		case tar.TypeSymlink:
+			hdr.Linkname, hdr.Size = e.body, 0
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		if hdr.Size > 0 {
+			_, err := tw.Write([]byte(e.body))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	data := buf.Bytes()
+	if gz {
+		var zbuf bytes.Buffer
+		zw := gzip.NewWriter(&zbuf)
+		_, err := zw.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		data = zbuf.Bytes()
+	}
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	return mtime
+}
+
+func TestTarballCompression(t *testing.T) {
+	for path, want := range map[string]string{
+		"repo.tar": "", "repo.tar.gz": "gzip", "repo.TGZ": "gzip", "repo.tar.zst": "zstd", "repo.tzst": "zstd",
+	} {
+		got, ok := tarballCompression(path)
+		assert.True(t, ok, path)
+		assert.Equal(t, want, got, path)
+	}
+	for _, path := range []string{"index.db.gz", "data.json", "src"} {
+		_, ok := tarballCompression(path)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestExtractTarball(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "repo.tar.gz")
+	mtime := writeTar(t, archive, true, []tarEntry{
+		{name: "repo-abc123/", typ: tar.TypeDir},
+		{name: "repo-abc123/main.go", body: "package main\n", typ: tar.TypeReg},
+		{name: "repo-abc123/pkg/util/util.go", body: "package util\n", typ: tar.TypeReg},
+		{name: "repo-abc123/node_modules/dep/index.js", body: "x", typ: tar.TypeReg},
+		{name: "repo-abc123/.git/HEAD", body: "ref: refs/heads/main\n", typ: tar.TypeReg},
+		{name: "repo-abc123/link", body: "/etc/passwd", typ: tar.TypeSymlink},
+	})
+
+	root, tmpDir, err := extractTarball(archive, "gzip")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(tmpDir) })
+	assert.Equal(t, filepath.Join(tmpDir, "repo-abc123"), root, "a single top-level directory is the root")
+
+	data, err := os.ReadFile(filepath.Join(root, "pkg", "util", "util.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package util\n", string(data))
+	info, err := os.Stat(filepath.Join(root, "main.go"))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(mtime), "modification times are kept")
+
+	for _, skipped := range []string{"node_modules", ".git", "link"} {
+		_, err := os.Lstat(filepath.Join(root, skipped))
+		assert.True(t, os.IsNotExist(err), skipped)
+	}
+}
+
+func TestExtractTarball_FlatAndUnsafe(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "src.tar")
+	writeTar(t, archive, false, []tarEntry{
+		{name: "./a.go", body: "package a\n", typ: tar.TypeReg},
+		{name: "b/b.go", body: "package b\n", typ: tar.TypeReg},
+	})
+	root, tmpDir, err := extractTarball(archive, "")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(tmpDir) })
+	assert.Equal(t, tmpDir, root)
+	assert.FileExists(t, filepath.Join(root, "a.go"))
+	assert.FileExists(t, filepath.Join(root, "b", "b.go"))
+
+	evil := filepath.Join(t.TempDir(), "evil.tar")
+	writeTar(t, evil, false, []tarEntry{{name: "../escape.go", body: "package x\n", typ: tar.TypeReg}})
+	_, _, err = extractTarball(evil, "")
+	assert.ErrorContains(t, err, "escapes the archive")
+}
+
+func TestExtractTarball_Zstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd command not installed")
+	}
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "src.tar")
+	writeTar(t, plain, false, []tarEntry{{name: "a.go", body: "package a\n", typ: tar.TypeReg}})
+	require.NoError(t, exec.Command("zstd", "-q", plain, "-o", plain+".zst").Run())
+
+	root, tmpDir, err := extractTarball(plain+".zst", "zstd")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(tmpDir) })
+	assert.FileExists(t, filepath.Join(root, "a.go"))
+}
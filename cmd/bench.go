@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchDB      string
+	benchData    string
+	benchSchema  string
+	benchSamples int
+	benchSeed    int64
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Time opening, traversing, and reading a graph",
+	Long: `Measure how fast mache serves a real data source, to compare backends and
+catch regressions without writing Go benchmarks. Against an index DB (--db)
+or a source directory (-d, ingested in memory) it times:
+
+  open+scan      opening the graph and building its indexes
+  list-children  every ListChildren call of a full traversal from the root
+  cold read      GetNode (and, for files, reading the content) on N random
+                 paths of a freshly opened graph
+  warm re-read   the same N paths again
+
+and reports count, total, p50, p99, and max per phase.`,
+	Example: "  mache bench --db index.db -n 500\n  mache bench -d ./src -s go",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if (benchDB == "") == (benchData == "") {
+			return fmt.Errorf("exactly one of --db or -d is required")
+		}
+		var schema *api.Topology
+		if benchSchema != "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			if schema, err = resolveSchema(benchSchema, cwd); err != nil {
+				return err
+			}
+		}
+
+		source := benchDB
+		var open benchOpener
+		if benchDB != "" {
+			if _, err := os.Stat(benchDB); err != nil {
+				return fmt.Errorf("open index: %w", err)
+			}
+			open = dbBenchOpener(benchDB, schema)
+		} else {
+			source = benchData
+			if schema == nil {
+				var err error
+				if schema, err = inferDirSchema(benchData); err != nil {
+					return fmt.Errorf("infer schema: %w", err)
+				}
+			}
+			open = dirBenchOpener(benchData, schema)
+		}
+		return runBench(cmd.OutOrStdout(), source, open, benchSamples, benchSeed)
+	},
+}
+
+func init() {
+	benchCmd.Flags().StringVar(&benchDB, "db", "", "Index DB built by mache build")
+	benchCmd.Flags().StringVarP(&benchData, "data", "d", "", "Source directory to ingest in memory")
+	benchCmd.Flags().StringVarP(&benchSchema, "schema", "s", "", "Schema file or preset (inferred for -d when omitted)")
+	benchCmd.Flags().IntVarP(&benchSamples, "samples", "n", 1000, "Random paths to read in the cold and warm phases")
+	benchCmd.Flags().Int64Var(&benchSeed, "seed", 1, "Seed for picking the random paths")
+	rootCmd.AddCommand(benchCmd)
+}
+
+// benchOpener opens a fresh instance of the graph under test. The returned
+// func releases it.
+type benchOpener func() (graph.Graph, func(), error)
+
+func dbBenchOpener(dbPath string, schema *api.Topology) benchOpener {
+	if schema == nil {
+		schema = &api.Topology{}
+	}
+	return func() (graph.Graph, func(), error) {
+		g, err := graph.OpenSQLiteGraph(dbPath, schema, schemaRender(schema))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := g.EagerScan(); err != nil {
+			_ = g.Close()
+			return nil, nil, fmt.Errorf("scan: %w", err)
+		}
+		return g, func() { _ = g.Close() }, nil
+	}
+}
+
+func dirBenchOpener(dir string, schema *api.Topology) benchOpener {
+	return func() (graph.Graph, func(), error) {
+		store := graph.NewMemoryStore()
+		if err := ingest.NewEngine(schema, store).Ingest(dir); err != nil {
+			_ = store.Close()
+			return nil, nil, fmt.Errorf("ingestion: %w", err)
+		}
+		return store, func() { _ = store.Close() }, nil
+	}
+}
+
+// benchPhase is the latency samples of one measured operation.
+type benchPhase struct {
+	name    string
+	samples []time.Duration
+}
+
+// runBench measures every phase and writes the report to w.
+func runBench(w io.Writer, source string, open benchOpener, samples int, seed int64) error {
+	openPhase := benchPhase{name: "open+scan"}
+	start := time.Now()
+	g, closeGraph, err := open()
+	if err != nil {
+		return err
+	}
+	openPhase.samples = append(openPhase.samples, time.Since(start))
+
+	listPhase := benchPhase{name: "list-children"}
+	ids, dirs, err := benchTraverse(g, &listPhase)
+	closeGraph()
+	if err != nil {
+		return err
+	}
+
+	// Shuffle a copy so the sample is stable for a seed whatever the
+	// traversal order.
+	picked := slices.Clone(ids)
+	slices.Sort(picked)
+	rand.New(rand.NewSource(seed)).Shuffle(len(picked), func(i, j int) {
+		picked[i], picked[j] = picked[j], picked[i]
+	})
+	if samples >= 0 && samples < len(picked) {
+		picked = picked[:samples]
+	}
+
+	// A second instance, so the cold phase doesn't hit caches the traversal
+	// warmed.
+	g, closeGraph, err = open()
+	if err != nil {
+		return err
+	}
+	defer closeGraph()
+	cold := benchPhase{name: "cold read"}
+	warm := benchPhase{name: "warm re-read"}
+	for _, phase := range []*benchPhase{&cold, &warm} {
+		for _, id := range picked {
+			d, err := benchRead(g, id)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", phase.name, id, err)
+			}
+			phase.samples = append(phase.samples, d)
+		}
+	}
+
+	_, _ = fmt.Fprintf(w, "bench %s: %d nodes (%d dirs, %d files), %d sampled, seed %d\n",
+		source, len(ids), dirs, len(ids)-dirs, len(picked), seed)
+	_, _ = fmt.Fprintf(w, "%-14s %7s %12s %12s %12s %12s\n", "phase", "count", "total", "p50", "p99", "max")
+	for _, p := range []benchPhase{openPhase, listPhase, cold, warm} {
+		total, p50, p99, maxD := p.stats()
+		_, err = fmt.Fprintf(w, "%-14s %7d %12s %12s %12s %12s\n",
+			p.name, len(p.samples), fmtBenchDur(total), fmtBenchDur(p50), fmtBenchDur(p99), fmtBenchDur(maxD))
+	}
+	return err
+}
+
+// benchTraverse walks the graph from the root, timing each ListChildren call
+// into phase. Returns every node ID reached and how many are directories.
+func benchTraverse(g graph.Graph, phase *benchPhase) ([]string, int, error) {
+	var ids []string
+	dirs := 0
+	seen := make(map[string]bool)
+	queue := []string{""}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		start := time.Now()
+		children, err := g.ListChildren(dir)
+		phase.samples = append(phase.samples, time.Since(start))
+		if err != nil {
+			return nil, 0, fmt.Errorf("list %q: %w", dir, err)
+		}
+		for _, child := range children {
+			id := graph.NormalizeID(child)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+			if n, err := g.GetNode(id); err == nil && n.Mode.IsDir() {
+				dirs++
+				queue = append(queue, id)
+			}
+		}
+	}
+	return ids, dirs, nil
+}
+
+// benchRead times GetNode on id plus, for a file, reading its whole content.
+func benchRead(g graph.Graph, id string) (time.Duration, error) {
+	start := time.Now()
+	n, err := g.GetNode(id)
+	if err != nil {
+		return 0, err
+	}
+	if !n.Mode.IsDir() {
+		if _, err := renderedSize(g, id); err != nil {
+			return 0, err
+		}
+	}
+	return time.Since(start), nil
+}
+
+// stats returns the total, median, 99th percentile (nearest rank), and
+// maximum of the phase's samples.
+func (p benchPhase) stats() (total, p50, p99, maxD time.Duration) {
+	if len(p.samples) == 0 {
+		return 0, 0, 0, 0
+	}
+	sorted := slices.Clone(p.samples)
+	slices.Sort(sorted)
+	for _, d := range sorted {
+		total += d
+	}
+	rank := func(q float64) time.Duration {
+		i := int(math.Ceil(q*float64(len(sorted)))) - 1
+		return sorted[min(max(i, 0), len(sorted)-1)]
+	}
+	return total, rank(0.50), rank(0.99), sorted[len(sorted)-1]
+}
+
+func fmtBenchDur(d time.Duration) string {
+	if d >= time.Millisecond {
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(100 * time.Nanosecond).String()
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBench(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte("package demo\n\nfunc Main() {}\n\nfunc Helper() {}\n"), 0o644))
+	schema, err := loadPresetSchema("go")
+	require.NoError(t, err)
+
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := ingest.NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, ingest.NewEngine(schema, w).Ingest(src))
+	require.NoError(t, w.Close())
+
+	for name, open := range map[string]benchOpener{
+		"db":  dbBenchOpener(dbPath, nil),
+		"dir": dirBenchOpener(src, schema),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, runBench(&out, "src", open, 3, 7))
+			s := out.String()
+			assert.Regexp(t, `^bench src: \d+ nodes \(\d+ dirs, \d+ files\), 3 sampled, seed 7\n`, s)
+			assert.Regexp(t, `(?m)^open\+scan\s+1\s`, s)
+			assert.Regexp(t, `(?m)^list-children\s+\d+\s`, s)
+			assert.Regexp(t, `(?m)^cold read\s+3\s`, s)
+			assert.Regexp(t, `(?m)^warm re-read\s+3\s`, s)
+		})
+	}
+}
+
+func TestBenchPhaseStats(t *testing.T) {
+	p := benchPhase{}
+	for i := 100; i >= 1; i-- {
+		p.samples = append(p.samples, time.Duration(i)*time.Millisecond)
+	}
+	total, p50, p99, maxD := p.stats()
+	assert.Equal(t, 5050*time.Millisecond, total)
+	assert.Equal(t, 50*time.Millisecond, p50)
+	assert.Equal(t, 99*time.Millisecond, p99)
+	assert.Equal(t, 100*time.Millisecond, maxD)
+}
@@ -23,6 +23,14 @@ var buildCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		source := args[0]
 		output := args[1]
+		overrides, err := ingest.ParseLangOverrides(buildLangMap)
+		if err != nil {
+			return fmt.Errorf("--lang: %w", err)
+		}
+		collisions, err := ingest.ParseCollisionStrategy(buildOnCollision)
+		if err != nil {
+			return fmt.Errorf("--on-collision: %w", err)
+		}
 
 		// Load or infer schema. Falls back to FCA inference when no schema file is provided.
 		var schema *api.Topology
@@ -90,12 +98,26 @@ var buildCmd = &cobra.Command{
 		defer func() { _ = writer.Close() }()
 
 		// 3. Setup Engine
+		ingest.IngestWorkers = buildWorkers
+		ingest.UnnamedBucket = buildUnnamed
+		ingest.SplitVisibility = buildSplitVis
+		ingest.Collisions = collisions
+		ingest.SignaturesOnly = buildSigsOnly
+		ingest.NoProjectFiles = buildNoProjFiles
+		ingest.GroupGenerated = buildGroupGen
+		ingest.LangOverrides = overrides
 		engine := ingest.NewEngine(schema, writer)
 
 		// 4. Ingest
 		start := time.Now()
 		log.Printf("Building %s from %s...", output, source)
-		if err := engine.Ingest(source); err != nil {
+		ctx := cmdContext(cmd)
+		if err := engine.IngestContext(ctx, source); err != nil {
+			if ctx.Err() != nil {
+				// Don't leave a half-written index behind.
+				_ = writer.Close()
+				_ = os.Remove(output)
+			}
 			return err
 		}
 		log.Printf("Done in %v.", time.Since(start))
@@ -103,6 +125,25 @@ var buildCmd = &cobra.Command{
 	},
 }
 
+var (
+	buildWorkers     int
+	buildUnnamed     bool
+	buildSplitVis    bool
+	buildOnCollision string
+	buildSigsOnly    bool
+	buildNoProjFiles bool
+	buildGroupGen    bool
+	buildLangMap     []string
+)
+
 func init() {
+	buildCmd.Flags().IntVar(&buildWorkers, "workers", 0, "Parallel ingestion workers (0 = one per CPU)")
+	buildCmd.Flags().BoolVar(&buildUnnamed, "unnamed", false, "Keep records whose name renders empty under _unnamed/<id> instead of skipping them")
+	buildCmd.Flags().BoolVar(&buildSplitVis, "split-visibility", false, "Split each construct group into exported/ and internal/ by the language's visibility rules")
+	buildCmd.Flags().StringVar(&buildOnCollision, "on-collision", "file", "Where a construct goes when another file already projected one at its path: file (name.from_<file>), number (name_2), error, or subdir (name/<file>/)")
+	buildCmd.Flags().BoolVar(&buildSigsOnly, "signatures-only", false, "Cut each construct's source down to its declaration; the whole construct stays in _full")
+	buildCmd.Flags().BoolVar(&buildNoProjFiles, "no-project-files", false, "Don't keep files the schema doesn't project (non-code files, unparseable source) under _project_files")
+	buildCmd.Flags().BoolVar(&buildGroupGen, "group-generated", false, "Project the constructs of generated files (Code generated ... DO NOT EDIT. and similar headers) under _generated/ instead of beside hand-written code")
+	buildCmd.Flags().StringArrayVar(&buildLangMap, "lang", nil, "Parse files matching a glob as a language, whatever their extension (repeatable; e.g. '*.txt=sql')")
 	rootCmd.AddCommand(buildCmd)
 }
@@ -6,7 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/agentic-research/mache/internal/nfsmount"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -157,3 +160,28 @@ func TestRoot_VersionString(t *testing.T) {
 	v := fmt.Sprintf("%s (commit %s, built %s)", Version, Commit, Date)
 	assert.Contains(t, v, "test-version")
 }
+
+func TestRoot_NFSCacheTimeouts(t *testing.T) {
+	newCmd := func() *cobra.Command {
+		c := &cobra.Command{}
+		c.Flags().DurationVar(&attrTimeout, "attr-timeout", defaultReadOnlyCacheTimeout, "")
+		c.Flags().DurationVar(&entryTimeout, "entry-timeout", defaultReadOnlyCacheTimeout, "")
+		return c
+	}
+	oldAttr, oldEntry := attrTimeout, entryTimeout
+	defer func() { attrTimeout, entryTimeout = oldAttr, oldEntry }()
+
+	// Read-only: small positive default.
+	c := newCmd()
+	assert.Equal(t, nfsmount.CacheTimeouts{Attr: time.Second, Entry: time.Second}, nfsCacheTimeouts(c, false))
+
+	// Writable: noac unless set explicitly.
+	assert.Equal(t, nfsmount.CacheTimeouts{}, nfsCacheTimeouts(c, true))
+	require.NoError(t, c.Flags().Set("attr-timeout", "3s"))
+	assert.Equal(t, nfsmount.CacheTimeouts{Attr: 3 * time.Second}, nfsCacheTimeouts(c, true))
+
+	// Read-only with explicit values.
+	c = newCmd()
+	require.NoError(t, c.Flags().Set("entry-timeout", "10s"))
+	assert.Equal(t, nfsmount.CacheTimeouts{Attr: time.Second, Entry: 10 * time.Second}, nfsCacheTimeouts(c, false))
+}
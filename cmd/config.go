@@ -73,6 +73,9 @@ func resolveSchema(schemaRef, configDir string) (*api.Topology, error) {
 		}
 	}
 
+	if info, err := os.Stat(schemaPath); err == nil && info.IsDir() {
+		return readSchemaDir(schemaPath)
+	}
 	data, err := os.ReadFile(schemaPath)
 	if err != nil {
 		return nil, fmt.Errorf("read schema %q: %w", schemaPath, err)
@@ -76,6 +76,23 @@ func TestResolveSchema_AllPresets(t *testing.T) {
 	}
 }
 
+func TestSplitLangFlag(t *testing.T) {
+	preset, overrides, err := splitLangFlag([]string{"*.txt=sql", "go", "go"})
+	require.NoError(t, err)
+	assert.Equal(t, "go", preset)
+	assert.Equal(t, []string{"*.txt=sql"}, overrides)
+
+	preset, overrides, err = splitLangFlag([]string{"*.tpl=html"})
+	require.NoError(t, err)
+	assert.Empty(t, preset)
+	assert.Equal(t, []string{"*.tpl=html"}, overrides)
+
+	_, _, err = splitLangFlag([]string{"cobol"})
+	assert.ErrorContains(t, err, "unknown preset schema")
+	_, _, err = splitLangFlag([]string{"go", "python"})
+	assert.ErrorContains(t, err, "conflicting preset schemas")
+}
+
 func TestResolveSchema_RelativePath(t *testing.T) {
 	dir := t.TempDir()
 	schema := `{"version": "v1", "nodes": []}`
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/vfs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cpDB             string
+	cpSchema         string
+	cpFollowSymlinks bool
+)
+
+var cpCmd = &cobra.Command{
+	Use:   "cp <path> <dest>",
+	Short: "Copy a subtree of an index DB's projection to a directory on disk",
+	Long: `Write the files and directories under <path> of an index DB built by
+"mache build" to <dest>, as a mount would show them: construct files plus the
+virtual entries (_location, callers/, ...). Like cp -r, an existing <dest>
+directory receives the subtree as <dest>/<base of path>; otherwise <dest> is
+created. Virtual symlinks (callers/, callees/, ...) are written as relative
+symlinks, which dangle when they point outside the copied subtree;
+--follow-symlinks copies what they point to instead, leaving the symlinks
+inside those copies as symlinks.`,
+	Example: "  mache cp --db index.db vulns/2024 ./out/\n  mache cp --db index.db --follow-symlinks demo/functions/Main ./main",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cpDB == "" {
+			return fmt.Errorf("--db is required")
+		}
+		schema := &api.Topology{}
+		if cpSchema != "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			if schema, err = resolveSchema(cpSchema, cwd); err != nil {
+				return err
+			}
+		}
+		if _, err := os.Stat(cpDB); err != nil {
+			return fmt.Errorf("open index: %w", err)
+		}
+		g, err := graph.OpenSQLiteGraph(cpDB, schema, schemaRender(schema))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = g.Close() }()
+		sj, _ := json.MarshalIndent(schema, "", "  ")
+		c := &projectionCopier{graph: g, resolver: vfs.NewDefaultResolver(g, append(sj, '\n'))}
+		return c.copyTo(args[0], args[1], cpFollowSymlinks)
+	},
+}
+
+func init() {
+	cpCmd.Flags().StringVar(&cpDB, "db", "", "Index DB built by mache build (required)")
+	cpCmd.Flags().StringVarP(&cpSchema, "schema", "s", "", "Schema the DB was built with (renders record-backed content)")
+	cpCmd.Flags().BoolVar(&cpFollowSymlinks, "follow-symlinks", false, "Copy what virtual symlinks (callers/, callees/, ...) point to instead of the symlinks")
+	rootCmd.AddCommand(cpCmd)
+}
+
+// projectionCopier writes parts of a graph's projection to disk, listing
+// directories the way the NFS mount does: a virtual directory's entries
+// from the resolver, otherwise the node's children plus the resolver's
+// extras.
+type projectionCopier struct {
+	graph    graph.Graph
+	resolver *vfs.Resolver
+}
+
+// copyTo copies the projection at src to dst with cp -r's naming: into
+// dst/<base of src> when dst is an existing directory, else to dst.
+func (c *projectionCopier) copyTo(src, dst string, follow bool) error {
+	src = "/" + graph.NormalizeID(src)
+	if c.resolver.Resolve(src) == nil && src != "/" {
+		if _, err := c.graph.GetNode(src); err != nil {
+			if errors.Is(err, graph.ErrNotFound) {
+				return fmt.Errorf("%s: no such path in the projection", src)
+			}
+			return err
+		}
+	}
+	if info, err := os.Stat(dst); err == nil && info.IsDir() && src != "/" {
+		dst = filepath.Join(dst, path.Base(src))
+	}
+	return c.copyPath(src, dst, follow)
+}
+
+// copyPath writes the entry at src ("/"-rooted) to dst. Symlinks are
+// followed only when follow is set, and not within what they point to.
+func (c *projectionCopier) copyPath(src, dst string, follow bool) error {
+	if e := c.resolver.Resolve(src); e != nil {
+		switch e.Kind {
+		case vfs.KindSymlink:
+			if !follow {
+				return os.Symlink(string(e.Content), dst)
+			}
+			return c.copyPath(path.Join(path.Dir(src), string(e.Content)), dst, false)
+		case vfs.KindDir:
+			entries, _ := c.resolver.ListDir(src)
+			return c.copyDir(src, dst, entries, nil, follow)
+		default:
+			data, ok := c.resolver.ReadContent(src)
+			if !ok {
+				data = e.Content
+			}
+			return os.WriteFile(dst, data, 0o644)
+		}
+	}
+
+	var node *graph.Node
+	if src != "/" {
+		n, err := c.graph.GetNode(src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+		if !n.Mode.IsDir() {
+			data, err := readAllContent(c.graph, n.ID)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", src, err)
+			}
+			return os.WriteFile(dst, data, 0o644)
+		}
+		node = n
+	}
+	stats, err := c.graph.ListChildStats(src)
+	if err != nil {
+		return fmt.Errorf("list %s: %w", src, err)
+	}
+	return c.copyDir(src, dst, c.resolver.DirExtras(src, node), stats, follow)
+}
+
+// copyDir creates dst and copies the virtual entries and graph children of
+// the directory src into it.
+func (c *projectionCopier) copyDir(src, dst string, extras []vfs.DirExtra, children []graph.NodeStat, follow bool) error {
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	for _, e := range extras {
+		if err := c.copyPath(path.Join(src, e.Name), filepath.Join(dst, e.Name), follow); err != nil {
+			return err
+		}
+	}
+	for _, st := range children {
+		name := path.Base(st.ID)
+		if err := c.copyPath(path.Join(src, name), filepath.Join(dst, name), follow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
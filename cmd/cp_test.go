@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/agentic-research/mache/internal/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openCopier(t *testing.T) *projectionCopier {
+	t.Helper()
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte(`package demo
+
+type Config struct{}
+
+func Main() {
+	run()
+}
+
+func run() {}
+`), 0o644))
+
+	schema, err := loadPresetSchema("go")
+	require.NoError(t, err)
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := ingest.NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, ingest.NewEngine(schema, w).Ingest(src))
+	require.NoError(t, w.Close())
+
+	g, err := graph.OpenSQLiteGraph(dbPath, &api.Topology{}, schemaRender(&api.Topology{}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = g.Close() })
+	return &projectionCopier{graph: g, resolver: vfs.NewDefaultResolver(g, nil)}
+}
+
+func TestProjectionCopier(t *testing.T) {
+	c := openCopier(t)
+	out := t.TempDir()
+
+	// An existing destination directory receives the subtree by name.
+	require.NoError(t, c.copyTo("demo/functions", out, false))
+	data, err := os.ReadFile(filepath.Join(out, "functions", "Main", "source"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "func Main()")
+	assert.NoDirExists(t, filepath.Join(out, "types"), "only the subtree is copied")
+
+	// Virtual symlinks stay symlinks, relative to the projection: copying
+	// it whole keeps them resolvable.
+	out = t.TempDir()
+	require.NoError(t, c.copyTo("/", out, false))
+	assert.FileExists(t, filepath.Join(out, "demo", "types", "Config", "source"))
+	links, err := os.ReadDir(filepath.Join(out, "demo", "functions", "run", "callers"))
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	link := filepath.Join(out, "demo", "functions", "run", "callers", links[0].Name())
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	data, err = os.ReadFile(link)
+	require.NoError(t, err, "the link resolves within the copy")
+	assert.Contains(t, string(data), "func Main()")
+
+	// A new destination is created and a file copies as a file.
+	dst := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, c.copyTo("/demo/functions/Main/source", dst, false))
+	assert.FileExists(t, dst)
+
+	assert.ErrorContains(t, c.copyTo("demo/nope", t.TempDir(), false), "no such path")
+}
+
+func TestProjectionCopier_FollowSymlinks(t *testing.T) {
+	c := openCopier(t)
+	dst := filepath.Join(t.TempDir(), "run")
+	require.NoError(t, c.copyTo("demo/functions/run", dst, true))
+
+	links, err := os.ReadDir(filepath.Join(dst, "callers"))
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	caller := filepath.Join(dst, "callers", links[0].Name())
+	info, err := os.Lstat(caller)
+	require.NoError(t, err)
+	assert.True(t, info.Mode().IsRegular(), "the caller is copied, not linked")
+	data, err := os.ReadFile(caller)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "func Main()")
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/agentic-research/mache/internal/lang"
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	findDB    string
+	findLang  string
+	findQuery string
+)
+
+var findCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Run a tree-sitter query over the source files of an index DB",
+	Long: `Re-parse the source files an index DB was built from (its file index)
+and run a tree-sitter S-expression query over each file in --lang, printing
+"file:line: text" per match. The location is the @scope capture when the
+query has one, else the earliest capture; text is the first line of that
+capture. Files that no longer exist at their indexed path are skipped.`,
+	Example: `  mache find --db index.db --lang go --query '(call_expression function: (selector_expression field: (field_identifier) @f (#eq? @f "Done")))'`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if findDB == "" || findLang == "" || findQuery == "" {
+			return fmt.Errorf("--db, --lang, and --query are required")
+		}
+		l := lang.ForName(findLang)
+		if l == nil {
+			return fmt.Errorf("unknown language %q", findLang)
+		}
+		if _, err := os.Stat(findDB); err != nil {
+			return fmt.Errorf("open index: %w", err)
+		}
+		index, err := ingest.LoadFileIndex(findDB)
+		if err != nil {
+			return fmt.Errorf("read file index: %w", err)
+		}
+		if len(index) == 0 {
+			return fmt.Errorf("%s has no file index (was it built by mache build?)", findDB)
+		}
+		paths := make([]string, 0, len(index))
+		for p := range index {
+			if fl := lang.ForPath(p); fl != nil && fl.Name == l.Name {
+				paths = append(paths, p)
+			}
+		}
+		sort.Strings(paths)
+		return findInFiles(cmdContext(cmd), cmd.OutOrStdout(), paths, l, findQuery)
+	},
+}
+
+func init() {
+	findCmd.Flags().StringVar(&findDB, "db", "", "Index DB built by mache build (required)")
+	findCmd.Flags().StringVar(&findLang, "lang", "", "Language of the files to search, e.g. go (required)")
+	findCmd.Flags().StringVar(&findQuery, "query", "", "Tree-sitter S-expression query (required)")
+	rootCmd.AddCommand(findCmd)
+}
+
+// findInFiles parses each of paths as language l, runs query over it, and
+// writes "file:line: text" for every match to w.
+func findInFiles(ctx context.Context, w io.Writer, paths []string, l *lang.Language, query string) error {
+	walker := ingest.NewSitterWalker()
+	defer walker.Close()
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(l.Grammar())
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("find: skipping %s: %v", path, err)
+			continue
+		}
+		tree, err := parser.ParseCtx(ctx, nil, source)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		root := tree.RootNode()
+		matches, err := walker.Query(ingest.SitterRoot{
+			Node:     root,
+			FileRoot: root,
+			Source:   source,
+			Lang:     l.Grammar(),
+			LangName: l.Name,
+		}, query)
+		if err != nil {
+			tree.Close()
+			return err
+		}
+		for _, m := range matches {
+			if n := locationNode(m); n != nil {
+				text, _, _ := strings.Cut(n.Content(source), "\n")
+				_, _ = fmt.Fprintf(w, "%s:%d: %s\n", path, n.StartPoint().Row+1, text)
+			}
+		}
+		tree.Close()
+	}
+	return nil
+}
+
+// locationNode returns the node a match is reported at: its @scope capture,
+// else its earliest (and, among those, outermost) capture.
+func locationNode(m ingest.Match) *sitter.Node {
+	cn, ok := m.(interface{ GetCaptureNode(string) *sitter.Node })
+	if !ok {
+		return nil
+	}
+	if n := cn.GetCaptureNode("scope"); n != nil {
+		return n
+	}
+	var first *sitter.Node
+	for name := range m.Values() {
+		n := cn.GetCaptureNode(name)
+		if n == nil {
+			continue
+		}
+		if first == nil || n.StartByte() < first.StartByte() ||
+			n.StartByte() == first.StartByte() && n.EndByte() > first.EndByte() {
+			first = n
+		}
+	}
+	return first
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/agentic-research/mache/internal/lang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindInFiles(t *testing.T) {
+	src := t.TempDir()
+	mainGo := filepath.Join(src, "main.go")
+	require.NoError(t, os.WriteFile(mainGo, []byte(`package demo
+
+func Main(ctx context.Context) {
+	<-ctx.Done()
+	run(
+		ctx.Done(),
+	)
+}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "util.go"), []byte("package demo\n\nfunc run(...any) {}\n"), 0o644))
+
+	schema, err := loadPresetSchema("go")
+	require.NoError(t, err)
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := ingest.NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, ingest.NewEngine(schema, w).Ingest(src))
+	require.NoError(t, w.Close())
+
+	index, err := ingest.LoadFileIndex(dbPath)
+	require.NoError(t, err)
+	var paths []string
+	for p := range index {
+		paths = append(paths, p)
+	}
+	require.Len(t, paths, 2)
+	mainReal, err := filepath.EvalSymlinks(mainGo)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	query := `(call_expression function: (selector_expression field: (field_identifier) @f (#eq? @f "Done"))) @scope`
+	require.NoError(t, findInFiles(context.Background(), &out, paths, lang.ForName("go"), query))
+	assert.Equal(t, mainReal+":4: ctx.Done()\n"+mainReal+":6: ctx.Done()\n", out.String())
+
+	// Without @scope the earliest capture locates the match.
+	out.Reset()
+	require.NoError(t, findInFiles(context.Background(), &out, paths, lang.ForName("go"), `(function_declaration name: (identifier) @name)`))
+	assert.Contains(t, out.String(), mainReal+":3: Main\n")
+	assert.Contains(t, out.String(), ":3: run\n")
+
+	// A file that has gone away is skipped, not fatal.
+	out.Reset()
+	require.NoError(t, findInFiles(context.Background(), &out, []string{filepath.Join(src, "gone.go")}, lang.ForName("go"), query))
+	assert.Empty(t, out.String())
+
+	assert.Error(t, findInFiles(context.Background(), &out, paths, lang.ForName("go"), `(not_a_node) @x`))
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/spf13/cobra"
+)
+
+var (
+	grepDB        string
+	grepSchema    string
+	grepFilesOnly bool
+	grepKind      string
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep <regex>",
+	Short: "Search the file content of an index DB without mounting it",
+	Long: `Walk every file node of an index DB built by "mache build" and print
+"path:line" for each line matching the regular expression, as matches are
+found. -l prints each matching path once instead; --kind limits the search to
+constructs under directories of that name (functions, types, methods, ...).`,
+	Example: "  mache grep --db index.db 'ctx\\.Done\\(\\)'\n  mache grep --db index.db -l --kind functions TODO",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if grepDB == "" {
+			return fmt.Errorf("--db is required")
+		}
+		re, err := regexp.Compile(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		schema := &api.Topology{}
+		if grepSchema != "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			if schema, err = resolveSchema(grepSchema, cwd); err != nil {
+				return err
+			}
+		}
+		if _, err := os.Stat(grepDB); err != nil {
+			return fmt.Errorf("open index: %w", err)
+		}
+		g, err := graph.OpenSQLiteGraph(grepDB, schema, schemaRender(schema))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = g.Close() }()
+		return grepGraph(cmd.OutOrStdout(), g, re, grepFilesOnly, grepKind)
+	},
+}
+
+func init() {
+	grepCmd.Flags().StringVar(&grepDB, "db", "", "Index DB built by mache build (required)")
+	grepCmd.Flags().StringVarP(&grepSchema, "schema", "s", "", "Schema the DB was built with (renders record-backed content)")
+	grepCmd.Flags().BoolVarP(&grepFilesOnly, "files-with-matches", "l", false, "Print only the paths of files with a match")
+	grepCmd.Flags().StringVar(&grepKind, "kind", "", "Only search constructs under directories of this name (e.g. functions)")
+	rootCmd.AddCommand(grepCmd)
+}
+
+// grepGraph walks g depth-first from the root and writes each line of file
+// content matching re to w as "path:line", or with filesOnly each matching
+// path once. A non-empty kind restricts the search to files below a
+// construct directory whose parent is named kind.
+func grepGraph(w io.Writer, g graph.Graph, re *regexp.Regexp, filesOnly bool, kind string) error {
+	var walk func(id string) error
+	walk = func(id string) error {
+		stats, err := g.ListChildStats(id)
+		if err != nil {
+			return fmt.Errorf("list %s: %w", id, err)
+		}
+		for _, st := range stats {
+			if st.IsDir {
+				if err := walk(st.ID); err != nil {
+					return err
+				}
+				continue
+			}
+			if kind != "" && !underKind(st.ID, kind) {
+				continue
+			}
+			content, err := readAllContent(g, st.ID)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", st.ID, err)
+			}
+			for line := range strings.SplitSeq(string(content), "\n") {
+				if !re.MatchString(line) {
+					continue
+				}
+				if filesOnly {
+					_, _ = fmt.Fprintln(w, st.ID)
+					break
+				}
+				_, _ = fmt.Fprintf(w, "%s:%s\n", st.ID, line)
+			}
+		}
+		return nil
+	}
+	return walk("")
+}
+
+// underKind reports whether the file id lies inside a construct directory
+// listed under a directory named kind ("pkg/functions/Main/source" for
+// "functions").
+func underKind(id, kind string) bool {
+	segs := strings.Split(id, "/")
+	return slices.Contains(segs[:max(len(segs)-2, 0)], kind)
+}
+
+// readAllContent reads a file node's content to the end.
+func readAllContent(g graph.Graph, id string) ([]byte, error) {
+	var out []byte
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := g.ReadContent(id, buf, int64(len(out)))
+		out = append(out, buf[:n]...)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return out, err
+		}
+		if n == 0 || err != nil {
+			return out, nil
+		}
+	}
+}
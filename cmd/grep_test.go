@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrepGraph(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte(`package demo
+
+// TODO: configure
+type Config struct{}
+
+func Main() {
+	// TODO: wire up
+	run()
+}
+`), 0o644))
+
+	schema, err := loadPresetSchema("go")
+	require.NoError(t, err)
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := ingest.NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, ingest.NewEngine(schema, w).Ingest(src))
+	require.NoError(t, w.Close())
+
+	g, err := graph.OpenSQLiteGraph(dbPath, &api.Topology{}, schemaRender(&api.Topology{}))
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+
+	var out bytes.Buffer
+	require.NoError(t, grepGraph(&out, g, regexp.MustCompile(`TODO: wire`), false, ""))
+	assert.Contains(t, out.String(), "demo/functions/Main/source:\t// TODO: wire up\n")
+
+	out.Reset()
+	require.NoError(t, grepGraph(&out, g, regexp.MustCompile(`TODO`), true, "functions"))
+	assert.Equal(t, "demo/functions/Main/source\n", out.String(), "-l prints each path once; --kind skips types")
+
+	out.Reset()
+	require.NoError(t, grepGraph(&out, g, regexp.MustCompile(`no such text`), false, ""))
+	assert.Empty(t, out.String())
+}
+
+func TestUnderKind(t *testing.T) {
+	assert.True(t, underKind("demo/functions/Main/source", "functions"))
+	assert.False(t, underKind("demo/types/Config/source", "functions"))
+	assert.False(t, underKind("functions/source", "functions"), "the kind directory itself holds constructs, not files")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importSCIP string
+	importDB   string
+	importRoot string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import --scip <index.scip> --db <index.db>",
+	Short: "Add an external code-intelligence index's cross-references to an index DB",
+	Long: `Read a SCIP index written by an LSP-grade indexer (scip-go, scip-typescript,
+scip-python, ...) and add its cross-references to an index DB built by
+"mache build". Each reference from one construct to a symbol another construct
+defines then appears in the defining construct's callers/ and the referencing
+construct's callees/, including the calls tree-sitter extraction misses
+(dynamic dispatch, macros, aliased imports).
+
+The source files must be unchanged since the build: occurrences are matched to
+constructs by locating each construct's source in its file.`,
+	Example: "  scip-go --output index.scip\n  mache build . index.db\n  mache import --scip index.scip --db index.db",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if importSCIP == "" || importDB == "" {
+			return fmt.Errorf("--scip and --db are required")
+		}
+		stats, err := ingest.ImportSCIP(importDB, importSCIP, importRoot)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "Imported %d references to %d definitions from %d documents (%d documents not in the DB, %d constructs changed since the build).\n",
+			stats.References, stats.Defs, stats.Documents, stats.Unmatched, stats.Stale)
+		return err
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importSCIP, "scip", "", "SCIP index to import (required)")
+	importCmd.Flags().StringVar(&importDB, "db", "", "Index DB built by mache build to add the references to (required)")
+	importCmd.Flags().StringVar(&importRoot, "root", "", "Directory the index's document paths are relative to (default: the index's project root)")
+	rootCmd.AddCommand(importCmd)
+}
@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/agentic-research/mache/api"
@@ -46,8 +48,7 @@ func detectProjectLanguages(dir string) (map[string]int, error) {
 			}
 			return nil
 		}
-		ext := filepath.Ext(path)
-		if l := lang.ForExt(ext); l != nil {
+		if l := lang.ForPath(path); l != nil {
 			counts[l.Name]++
 		}
 		return nil
@@ -55,6 +56,34 @@ func detectProjectLanguages(dir string) (map[string]int, error) {
 	return counts, err
 }
 
+// inferenceReport records how --infer arrived at its schema. It is served
+// beside the schema as /_schema.inferred.json.
+type inferenceReport struct {
+	Data string `json:"data"`
+	// Method is the inference method: "fca" or "greedy", or "preset" when
+	// every detected language was covered by its preset schema.
+	Method string `json:"method"`
+	// Languages counts the source files of each detected language.
+	Languages map[string]int `json:"languages,omitempty"`
+	// Presets are the languages projected by their embedded preset schema,
+	// Inferred those whose schema FCA inferred from sampled files.
+	Presets  []string `json:"presets,omitempty"`
+	Inferred []string `json:"inferred,omitempty"`
+}
+
+// inferredSchemaJSON renders schema with report under an "inference" key.
+// The schema loader ignores that key, so the file works as a --schema as is.
+func inferredSchemaJSON(schema *api.Topology, report *inferenceReport) ([]byte, error) {
+	data, err := json.MarshalIndent(struct {
+		*api.Topology
+		Inference *inferenceReport `json:"inference"`
+	}{schema, report}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
 // inferDirSchema detects languages in a directory and produces a unified
 // Topology using preset schemas where available and FCA inference for the rest.
 //
@@ -64,6 +93,14 @@ func detectProjectLanguages(dir string) (map[string]int, error) {
 //  3. Remaining languages → sample files + FCA inference
 //  4. Merge into one multi-language topology (with namespace nodes if >1 language)
 func inferDirSchema(dataPath string) (*api.Topology, error) {
+	return inferDirSchemaWith(&lattice.Inferrer{Config: lattice.InferConfig{Method: "fca"}}, dataPath, nil)
+}
+
+// inferDirSchemaWith is inferDirSchema running FCA inference through inf,
+// so the caller can inspect its lattices afterwards (--dump-lattice), and
+// filling report, when non-nil, with the languages found and how each was
+// handled.
+func inferDirSchemaWith(inf *lattice.Inferrer, dataPath string, report *inferenceReport) (*api.Topology, error) {
 	languageCounts, err := detectProjectLanguages(dataPath)
 	if err != nil {
 		return nil, fmt.Errorf("language scan: %w", err)
@@ -93,6 +130,16 @@ func inferDirSchema(dataPath string) (*api.Topology, error) {
 		}
 	}
 
+	if report != nil {
+		report.Method = "preset"
+		if len(inferLangs) > 0 {
+			report.Method = "fca"
+		}
+		report.Languages = languageCounts
+		report.Presets = presetLangs
+		report.Inferred = inferLangs
+	}
+
 	// Collect nodes from both paths
 	var allNodes []api.Node
 
@@ -107,6 +154,14 @@ func inferDirSchema(dataPath string) (*api.Topology, error) {
 			// Single language: return the preset directly (no namespace wrapper)
 			return topo, nil
 		}
+		if languageScoped(topo.Nodes, l) {
+			// Its nodes already apply only to its own files (OCaml's
+			// modules, which pair interface and implementation files by
+			// {{._parent.module}} at the top level): no namespace node.
+			allNodes = append(allNodes, topo.Nodes...)
+			log.Printf("  %s: using preset schema", l)
+			continue
+		}
 		// Multi-language: wrap in namespace node
 		allNodes = append(allNodes, api.Node{
 			Name:     l,
@@ -119,7 +174,7 @@ func inferDirSchema(dataPath string) (*api.Topology, error) {
 
 	// 2. FCA inference for remaining languages
 	if len(inferLangs) > 0 {
-		inferredNodes, err := inferLanguages(dataPath, inferLangs, languageCounts)
+		inferredNodes, err := inferLanguages(inf, dataPath, inferLangs, languageCounts)
 		if err != nil {
 			return nil, fmt.Errorf("inference: %w", err)
 		}
@@ -133,9 +188,41 @@ func inferDirSchema(dataPath string) (*api.Topology, error) {
 	return &api.Topology{Version: api.SchemaVersion, Nodes: allNodes}, nil
 }
 
+// languageScoped reports whether every node names langName, or a section
+// of it, as its language.
+func languageScoped(nodes []api.Node, langName string) bool {
+	for _, n := range nodes {
+		if n.Language != langName && !strings.HasPrefix(n.Language, langName+"/") {
+			return false
+		}
+	}
+	return len(nodes) > 0
+}
+
+// inferFileSchema produces a Topology for a single source file. Like
+// inferDirSchema, the language's preset schema wins when one exists, so
+// mounting one file yields the same projection it would have inside its
+// repository; other languages fall back to FCA inference on the file's AST.
+func inferFileSchema(inf *lattice.Inferrer, path string, l *lang.Language, report *inferenceReport) (*api.Topology, error) {
+	presetKey, isPreset := sourceCodePresets[l.Name]
+	if report != nil {
+		report.Languages = map[string]int{l.Name: 1}
+		if isPreset {
+			report.Method, report.Presets = "preset", []string{l.Name}
+		} else {
+			report.Method, report.Inferred = "fca", []string{l.Name}
+		}
+	}
+	if isPreset {
+		log.Printf("Using %s preset schema for %s", l.DisplayName, filepath.Base(path))
+		return loadPresetSchema(presetKey)
+	}
+	return inferFromTreeSitterFile(inf, path, l.Grammar(), l.DisplayName)
+}
+
 // inferLanguages runs parallel tree-sitter sampling + FCA inference for the
 // given languages. Returns namespace-wrapped nodes for each language.
-func inferLanguages(dataPath string, langs []string, languageCounts map[string]int) ([]api.Node, error) {
+func inferLanguages(inf *lattice.Inferrer, dataPath string, langs []string, languageCounts map[string]int) ([]api.Node, error) {
 	type langResult struct {
 		lang    string
 		records []any
@@ -170,7 +257,7 @@ func inferLanguages(dataPath string, langs []string, languageCounts map[string]i
 				if ingest.ShouldSkipFile(path, info.Size()) {
 					return nil
 				}
-				l := lang.ForExt(filepath.Ext(path))
+				l := lang.ForPath(path)
 				if l == nil || l.Name != targetName {
 					return nil
 				}
@@ -219,10 +306,6 @@ func inferLanguages(dataPath string, langs []string, languageCounts map[string]i
 	}
 
 	// Run FCA inference
-	inf := &lattice.Inferrer{
-		Config: lattice.InferConfig{Method: "fca"},
-	}
-
 	topo, err := inf.InferMultiLanguage(recordsByLang)
 	if err != nil {
 		return nil, err
@@ -236,3 +319,16 @@ func inferLanguages(dataPath string, langs []string, languageCounts map[string]i
 
 	return topo.Nodes, nil
 }
+
+// writeLatticeDump writes the concept lattices inf computed to path.
+func writeLatticeDump(inf *lattice.Inferrer, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("dump lattice: %w", err)
+	}
+	if err := inf.DumpLattice(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("dump lattice: %w", err)
+	}
+	return f.Close()
+}
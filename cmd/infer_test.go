@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/agentic-research/mache/internal/lang"
+	"github.com/agentic-research/mache/internal/lattice"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -126,6 +132,23 @@ func TestInferDirSchema_MultiLanguage(t *testing.T) {
 	// typescript may or may not produce FCA results from 1 file, but go should be there
 }
 
+func TestInferDirSchemaWith_DumpLattice(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte("greet() {\n  echo hi\n}\n\nmain() {\n  greet\n}\n"), 0o644))
+
+	inf := &lattice.Inferrer{Config: lattice.InferConfig{Method: "fca", KeepLattice: true}}
+	_, err := inferDirSchemaWith(inf, dir, nil)
+	require.NoError(t, err)
+
+	out := filepath.Join(t.TempDir(), "lattice.txt")
+	require.NoError(t, writeLatticeDump(inf, out))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "lattice bash: ")
+	assert.Contains(t, string(data), "-> node ")
+}
+
 func TestInferDirSchema_NoSourceFiles(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -138,3 +161,99 @@ func TestInferDirSchema_NoSourceFiles(t *testing.T) {
 	require.NotNil(t, topo)
 	assert.Empty(t, topo.Nodes)
 }
+
+func TestInferFileSchema_UsesPreset(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\nfunc main() {}"), 0o644))
+
+	topo, err := inferFileSchema(&lattice.Inferrer{Config: lattice.DefaultInferConfig()}, path, lang.ForName("go"), nil)
+	require.NoError(t, err)
+
+	preset, err := loadPresetSchema("go")
+	require.NoError(t, err)
+	assert.Equal(t, preset, topo)
+}
+
+func TestInferFileSchema_FallsBackToFCA(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "run.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nhello() { echo hi; }\nhello\n"), 0o644))
+
+	// bash has no preset, so the schema comes from inference on the file.
+	topo, err := inferFileSchema(&lattice.Inferrer{Config: lattice.DefaultInferConfig()}, path, lang.ForName("bash"), nil)
+	require.NoError(t, err)
+	require.NotNil(t, topo)
+}
+
+func TestInferredSchemaJSON_RoundTrip(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte("def greet(name):\n    return name\n\n\nclass Greeter:\n    pass\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte("greet() {\n  echo hi\n}\n\nmain() {\n  greet\n}\n"), 0o644))
+
+	report := &inferenceReport{Data: dir}
+	inf := &lattice.Inferrer{Config: lattice.InferConfig{Method: "fca"}}
+	schema, err := inferDirSchemaWith(inf, dir, report)
+	require.NoError(t, err)
+	schema.ResolveIncludes()
+
+	assert.Equal(t, "fca", report.Method)
+	assert.Equal(t, map[string]int{"bash": 1, "python": 1}, report.Languages)
+	assert.Equal(t, []string{"python"}, report.Presets)
+	assert.Equal(t, []string{"bash"}, report.Inferred)
+
+	data, err := inferredSchemaJSON(schema, report)
+	require.NoError(t, err)
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Contains(t, raw, "nodes")
+	assert.Equal(t, "fca", raw["inference"].(map[string]any)["method"])
+
+	// The served file loads as a schema and projects the same tree as the
+	// one it was rendered from.
+	var reloaded api.Topology
+	require.NoError(t, json.Unmarshal(data, &reloaded))
+	reloaded.ResolveIncludes()
+	assert.Equal(t, projectedPaths(t, schema, dir), projectedPaths(t, &reloaded, dir))
+}
+
+// projectedPaths ingests dir with schema and returns every path it projects.
+func projectedPaths(t *testing.T, schema *api.Topology, dir string) []string {
+	t.Helper()
+	store := graph.NewMemoryStore()
+	require.NoError(t, ingest.NewEngine(schema, store).Ingest(dir))
+	var paths []string
+	var walk func(id string)
+	walk = func(id string) {
+		children, err := store.ListChildren(id)
+		require.NoError(t, err)
+		for _, c := range children {
+			paths = append(paths, c)
+			walk(c)
+		}
+	}
+	walk("/")
+	require.NotEmpty(t, paths)
+	return paths
+}
+
+func TestInferDirSchema_LanguageScopedPresetUnwrapped(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc main() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shapes.ml"), []byte("let area s = s *. s\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shapes.mli"), []byte("val area : float -> float\n"), 0o644))
+
+	topo, err := inferDirSchema(dir)
+	require.NoError(t, err)
+	topo.ResolveIncludes()
+
+	// Go is namespaced; the OCaml preset's module nodes stay at the top so
+	// the interface and implementation still merge under the module.
+	paths := projectedPaths(t, topo, dir)
+	assert.Contains(t, paths, "go")
+	assert.Contains(t, paths, "Shapes/implementation")
+	assert.Contains(t, paths, "Shapes/signature")
+	assert.Contains(t, paths, "Shapes/functions/area")
+}
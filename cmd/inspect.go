@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectDB     string
+	inspectSchema string
+)
+
+var inspectNodeCmd = &cobra.Command{
+	Use:   "inspect-node <path>",
+	Short: "Print everything an index DB records about one node",
+	Long: `Print the metadata mache holds for a single node of an index DB built by
+"mache build": mode, origin, properties, children, content reference, rendered
+content size, the raw nodes-table row, and (with --schema) the schema level
+that projected it. Useful for debugging why a node projected with the wrong
+content or origin.`,
+	Example: "  mache inspect-node --db index.db -s schema.json demo/functions/Main/source",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if inspectDB == "" {
+			return fmt.Errorf("--db is required")
+		}
+		var schema *api.Topology
+		if inspectSchema != "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			if schema, err = resolveSchema(inspectSchema, cwd); err != nil {
+				return err
+			}
+		}
+		return inspectNode(cmd.OutOrStdout(), inspectDB, schema, args[0])
+	},
+}
+
+func init() {
+	inspectNodeCmd.Flags().StringVar(&inspectDB, "db", "", "Index DB built by mache build (required)")
+	inspectNodeCmd.Flags().StringVarP(&inspectSchema, "schema", "s", "", "Schema the DB was built with (resolves the schema level)")
+	rootCmd.AddCommand(inspectNodeCmd)
+}
+
+// nodeRow is a raw row of an index DB's nodes table.
+type nodeRow struct {
+	parentID, recordID, sourceFile sql.NullString
+	name                           string
+	kind, size                     int64
+	record                         []byte
+}
+
+// inspectNode writes the metadata report for path to w. schema may be nil.
+func inspectNode(w io.Writer, dbPath string, schema *api.Topology, path string) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("open index: %w", err)
+	}
+	gschema := schema
+	if gschema == nil {
+		gschema = &api.Topology{}
+	}
+	g, err := graph.OpenSQLiteGraph(dbPath, gschema, schemaRender(gschema))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = g.Close() }()
+
+	id := graph.NormalizeID(path)
+	node, err := g.GetNode(id)
+	if errors.Is(err, graph.ErrNotFound) {
+		return fmt.Errorf("node %q not found in %s", id, dbPath)
+	}
+	if err != nil {
+		return err
+	}
+	row, err := readNodeRow(dbPath, id)
+	if err != nil {
+		return err
+	}
+
+	field := func(name, format string, args ...any) {
+		_, _ = fmt.Fprintf(w, "%-16s "+format+"\n", append([]any{name + ":"}, args...)...)
+	}
+
+	field("ID", "%s", node.ID)
+	field("Mode", "%v", node.Mode)
+	field("ModTime", "%s", node.ModTime.Format("2006-01-02 15:04:05.000 MST"))
+
+	switch {
+	case node.Origin != nil:
+		field("Origin", "%s [%d:%d]", node.Origin.FilePath, node.Origin.StartByte, node.Origin.EndByte)
+	case row != nil && row.sourceFile.Valid:
+		field("Origin", "%s (byte range not stored in index DBs)", row.sourceFile.String)
+	default:
+		field("Origin", "none")
+	}
+
+	props := node.Properties
+	if props == nil && row != nil && row.kind == graph.NodeKindDir && len(row.record) > 0 {
+		// Directory properties are stored JSON-encoded in the record column.
+		_ = json.Unmarshal(row.record, &props)
+	}
+	if len(props) == 0 {
+		field("Properties", "none")
+	} else {
+		field("Properties", "%d", len(props))
+		keys := make([]string, 0, len(props))
+		for k := range props {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(w, "  %s = %s\n", k, props[k])
+		}
+	}
+
+	if node.Mode.IsDir() {
+		children, err := g.ListChildren(id)
+		if err != nil {
+			return fmt.Errorf("list children: %w", err)
+		}
+		field("Children", "%d", len(children))
+		for _, c := range children {
+			_, _ = fmt.Fprintf(w, "  %s\n", c)
+		}
+	} else {
+		if ref := node.Ref; ref != nil {
+			field("ContentRef", "db=%q record=%q template=%q len=%d", ref.DBPath, ref.RecordID, ref.Template, ref.ContentLen)
+		} else {
+			field("ContentRef", "none (inline)")
+		}
+		n, err := renderedSize(g, id)
+		if err != nil {
+			return fmt.Errorf("read content: %w", err)
+		}
+		field("Rendered size", "%d bytes", n)
+	}
+
+	if row != nil {
+		field("Row", "parent_id=%q name=%q kind=%d size=%d record_id=%q record=%d bytes",
+			row.parentID.String, row.name, row.kind, row.size, row.recordID.String, len(row.record))
+	}
+
+	if schema == nil {
+		field("Schema level", "unknown (pass --schema)")
+	} else if chain := schemaLevelOf(schema, id); chain != nil {
+		field("Schema level", "%s", strings.Join(chain, " > "))
+	} else {
+		field("Schema level", "none (parent path, placeholder, or not from this schema)")
+	}
+	return nil
+}
+
+// readNodeRow returns the nodes-table row for id, or nil when the DB has no
+// nodes table (a record DB projected at mount time).
+func readNodeRow(dbPath, id string) (*nodeRow, error) {
+	db, err := sql.Open("sqlite", dbPath+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	var r nodeRow
+	err = db.QueryRow("SELECT parent_id, name, kind, size, record_id, record, source_file FROM nodes WHERE id = ?", id).
+		Scan(&r.parentID, &r.name, &r.kind, &r.size, &r.recordID, &r.record, &r.sourceFile)
+	switch {
+	case err == nil:
+		return &r, nil
+	case errors.Is(err, sql.ErrNoRows), strings.Contains(err.Error(), "no such table"):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("read nodes row: %w", err)
+	}
+}
+
+// renderedSize reads a file node's content to the end and returns its length,
+// which can differ from the recorded size when a template renders it.
+func renderedSize(g graph.Graph, id string) (int64, error) {
+	buf := make([]byte, 64*1024)
+	var total int64
+	for {
+		n, err := g.ReadContent(id, buf, total)
+		total += int64(n)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return total, err
+		}
+		if n == 0 || err != nil {
+			return total, nil
+		}
+	}
+}
+
+// schemaLevelOf maps a node ID onto the schema tree, one level per path
+// segment: a static name matching the segment wins, else the first templated
+// sibling. A recursive node may match repeatedly, and a final segment can
+// match a file leaf, reported with a "(file)" suffix. Returns nil if some
+// segment has no schema level, e.g. a directory created by a Parent path.
+func schemaLevelOf(schema *api.Topology, id string) []string {
+	var chain []string
+	var cur *api.Node
+	level := schema.Nodes
+	segs := strings.Split(id, "/")
+	for i, seg := range segs {
+		if cur != nil && i == len(segs)-1 {
+			if leaf := matchSchemaLeaf(schema, cur.Files, seg); leaf != nil {
+				return append(chain, leaf.Name+" (file)")
+			}
+		}
+		next := matchSchemaNode(schema, level, seg)
+		if next == nil && cur != nil && cur.Recursive {
+			next = cur
+		}
+		if next == nil {
+			return nil
+		}
+		chain = append(chain, next.Name)
+		cur = next
+		level = next.Children
+	}
+	return chain
+}
+
+func matchSchemaNode(schema *api.Topology, nodes []api.Node, seg string) *api.Node {
+	for i := range nodes {
+		if nodes[i].Name == seg {
+			return &nodes[i]
+		}
+	}
+	for i := range nodes {
+		if schema.IsTemplate(nodes[i].Name) {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
+
+func matchSchemaLeaf(schema *api.Topology, files []api.Leaf, seg string) *api.Leaf {
+	for i := range files {
+		if files[i].Name == seg {
+			return &files[i]
+		}
+	}
+	for i := range files {
+		if schema.IsTemplate(files[i].Name) {
+			return &files[i]
+		}
+	}
+	return nil
+}
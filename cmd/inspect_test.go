@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectNode(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte("package demo\n\nfunc Main() {}\n"), 0o644))
+
+	schema, err := loadPresetSchema("go")
+	require.NoError(t, err)
+
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := ingest.NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, ingest.NewEngine(schema, w).Ingest(src))
+	require.NoError(t, w.Close())
+
+	var out bytes.Buffer
+	require.NoError(t, inspectNode(&out, dbPath, schema, "/demo/functions/Main/source"))
+	s := out.String()
+	assert.Contains(t, s, "ID:              demo/functions/Main/source")
+	assert.Contains(t, s, "main.go (byte range not stored in index DBs)")
+	assert.Contains(t, s, "Rendered size:   14 bytes")
+	assert.Contains(t, s, "Schema level:    {{.pkg}} > functions > {{.name}} > source (file)")
+
+	out.Reset()
+	require.NoError(t, inspectNode(&out, dbPath, nil, "demo/functions/Main"))
+	s = out.String()
+	assert.Contains(t, s, "Mode:            dr")
+	assert.Contains(t, s, "  location = main.go:3:3")
+	assert.Contains(t, s, "  demo/functions/Main/source")
+	assert.Contains(t, s, "Schema level:    unknown (pass --schema)")
+
+	assert.ErrorContains(t, inspectNode(&out, dbPath, nil, "demo/nope"), "not found")
+}
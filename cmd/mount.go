@@ -1,15 +1,18 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -25,7 +28,6 @@ import (
 	"github.com/agentic-research/mache/internal/linter"
 	"github.com/agentic-research/mache/internal/materialize"
 	"github.com/agentic-research/mache/internal/nfsmount"
-	machetmpl "github.com/agentic-research/mache/internal/template"
 	"github.com/agentic-research/mache/internal/writeback"
 	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/spf13/cobra"
@@ -38,33 +40,123 @@ var (
 )
 
 var (
-	schemaPath  string
-	dataPath    string
-	controlPath string
-	writable    bool
-	inferSchema bool
-	quiet       bool
-	agentMode   bool
-	outPath     string
-	outFormat   string
-	nfsOpts     string
-	snapshot    bool
-	maxFileSize string
+	schemaPath   string
+	dataPath     string
+	controlPath  string
+	writable     bool
+	inferSchema  bool
+	quiet        bool
+	agentMode    bool
+	outPath      string
+	outFormat    string
+	nfsOpts      string
+	nfsAddr      string
+	nfsPort      int
+	attrTimeout  time.Duration
+	entryTimeout time.Duration
+	maxConcur    int
+	snapshot     bool
+	maxFileSize  string
+	workers      int
+	unnamed      bool
+	splitVis     bool
+	onCollision  string
+	sigsOnly     bool
+	langMap      []string
+	spillNodes   int
+	debugSchema  bool
+	withAST      bool
+	withRaw      bool
+	skipErrors   bool
+	noSchemaFile bool
+	noQueryDir   bool
+	noProjFiles  bool
+	groupGen     bool
+	noDiagDir    bool
+	denyWrite    []string
+	writePaths   []string
+	withHistory  bool
+	coverageFile string
+	profileKind  string
+	profileOut   string
+	profileMount bool
+	allowExec    bool
+	dumpLattice  string
 )
 
+// defaultReadOnlyCacheTimeout is the NFS attribute cache lifetime used for
+// read-only mounts when --attr-timeout/--entry-timeout are not given.
+// Writable mounts default to 0 so write-back results are visible at once.
+const defaultReadOnlyCacheTimeout = time.Second
+
+// checkpointInterval is how often a writable arena mount checkpoints its
+// master DB (--checkpoint-interval); 0 leaves only the checkpoint on unmount.
+var checkpointInterval time.Duration
+
+// ingestTimings renders the per-file timings of the mount's source ingest,
+// served as /_diagnostics/ingest-timings; nil when no Engine ingested the
+// graph (e.g. a .db mounted directly).
+var ingestTimings func() []byte
+
+// identifierCounts renders how often each ref token occurs in the mount's
+// source, served as /_diagnostics/identifiers; nil when no Engine ingested
+// the graph.
+var identifierCounts func() []byte
+
+// ingestManifest renders the source files the mount's ingest read, served
+// as /_manifest.json; nil when no Engine ingested the graph.
+var ingestManifest func() []byte
+
+// inferredSchema is the schema --infer produced with its inferenceReport,
+// served as /_schema.inferred.json; nil without --infer.
+var inferredSchema []byte
+
 func init() {
-	rootCmd.Flags().StringVarP(&schemaPath, "schema", "s", "", "Path to topology schema")
-	rootCmd.Flags().StringVarP(&dataPath, "data", "d", "", "Path to data source")
+	rootCmd.Flags().StringVarP(&schemaPath, "schema", "s", "", "Path to topology schema, or a directory of per-language schemas (go.json, python.json)")
+	rootCmd.Flags().StringVarP(&dataPath, "data", "d", "", "Path to data source (- reads JSON or JSON Lines from stdin)")
 	rootCmd.Flags().StringVar(&controlPath, "control", "", "Path to Leyline control block (enables hot-swap)")
 	rootCmd.Flags().BoolVarP(&writable, "writable", "w", false, "Enable write-back (splice edits into source files)")
 	rootCmd.Flags().BoolVar(&inferSchema, "infer", false, "Auto-infer schema from data via FCA")
+	rootCmd.Flags().StringVar(&dumpLattice, "dump-lattice", "", "With --infer, write the FCA concept lattice to this file, marking which concepts became schema nodes")
 	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Suppress standard output")
 	rootCmd.Flags().BoolVar(&agentMode, "agent", false, "Agent mode: auto-mount to temp dir with instructions")
-	rootCmd.Flags().StringVar(&outPath, "out", "", "Write to path instead of mounting; not compatible with --agent")
+	rootCmd.Flags().StringVar(&outPath, "out", "", "Write to path instead of mounting; a .gz suffix gzips the output; not compatible with --agent")
 	rootCmd.Flags().StringVar(&outFormat, "format", "sqlite", "Output format for --out: sqlite, zip, boltdb (requires -tags boltdb)")
 	rootCmd.Flags().StringVar(&nfsOpts, "nfs-opts", "", "Extra NFS mount options (comma-separated, appended to defaults)")
+	rootCmd.Flags().StringVar(&nfsAddr, "nfs-addr", "127.0.0.1", "Address the NFS server listens on; anything but loopback exports the mount to other hosts, without authentication")
+	rootCmd.Flags().IntVar(&nfsPort, "nfs-port", 0, "Port the NFS server listens on (0 = ephemeral)")
+	rootCmd.Flags().DurationVar(&attrTimeout, "attr-timeout", defaultReadOnlyCacheTimeout, "NFS file attribute cache timeout (writable mounts default to 0)")
+	rootCmd.Flags().DurationVar(&entryTimeout, "entry-timeout", defaultReadOnlyCacheTimeout, "NFS directory/lookup cache timeout (writable mounts default to 0)")
+	rootCmd.Flags().IntVar(&maxConcur, "max-concurrency", 0, "Limit how many file reads resolve content (render or parse) at once; others wait (0 = unlimited)")
 	rootCmd.Flags().BoolVar(&snapshot, "snapshot", false, "Copy data source to temp before mounting (true sandbox; copy is not atomic; default is zero-copy)")
+	rootCmd.Flags().BoolVar(&noSchemaFile, "no-schema-file", false, "Leave _schema.json and _schema.inferred.json out of the root listing (still readable by path)")
+	rootCmd.Flags().BoolVar(&noQueryDir, "no-query-dir", false, "Leave .query out of the root listing")
+	rootCmd.Flags().BoolVar(&noProjFiles, "no-project-files", false, "Don't keep files the schema doesn't project (non-code files, unparseable source) under _project_files")
+	rootCmd.Flags().BoolVar(&groupGen, "group-generated", false, "Project the constructs of generated files (Code generated ... DO NOT EDIT. and similar headers) under _generated/ instead of beside hand-written code")
+	rootCmd.Flags().BoolVar(&noDiagDir, "no-diagnostics", false, "Leave _diagnostics out of directory listings on writable mounts")
+	rootCmd.Flags().StringArrayVar(&denyWrite, "deny-write", nil, "Reject writes to paths matching this glob even with --writable (repeatable; e.g. '_project_files')")
+	rootCmd.Flags().StringArrayVar(&writePaths, "writable-path", nil, "With --writable, allow writes only to paths matching this glob; the rest stay read-only (repeatable; e.g. 'src')")
+	rootCmd.Flags().DurationVar(&checkpointInterval, "checkpoint-interval", 5*time.Minute, "WAL checkpoint interval for writable arena mounts (--control --writable; 0 = only on unmount)")
 	rootCmd.Flags().StringVar(&maxFileSize, "max-file-size", "100MB", "Skip files larger than this during ingestion (e.g. 100MB, 1GB, 0 to disable)")
+	rootCmd.Flags().IntVar(&workers, "workers", 0, "Parallel ingestion workers (0 = one per CPU)")
+	rootCmd.Flags().BoolVar(&unnamed, "unnamed", false, "Keep records whose name renders empty under _unnamed/<id> instead of skipping them")
+	rootCmd.Flags().BoolVar(&splitVis, "split-visibility", false, "Split each construct group into exported/ and internal/ by the language's visibility rules")
+	rootCmd.Flags().StringVar(&onCollision, "on-collision", "file", "Where a construct goes when another file already projected one at its path: file (name.from_<file>), number (name_2), error, or subdir (name/<file>/)")
+	rootCmd.Flags().BoolVar(&sigsOnly, "signatures-only", false, "Cut each construct's source down to its declaration; the whole construct stays in _full")
+	rootCmd.Flags().StringArrayVar(&langMap, "lang", nil, "Parse files matching a glob as a language, whatever their extension (repeatable; e.g. '*.txt=sql'); a bare name mounts with that embedded preset schema (e.g. --lang go)")
+	rootCmd.Flags().BoolVar(&debugSchema, "debug-schema", false, "Add a _schema_path file to each projected directory naming the schema node that produced it, and a _sexp file to each source construct showing its tree-sitter parse tree")
+	rootCmd.Flags().BoolVar(&skipErrors, "skip-errors", false, "Skip and count records or JSON files that fail to parse instead of failing the mount")
+	rootCmd.Flags().BoolVar(&withAST, "ast", false, "Add an _ast.json file to each source construct serializing its tree-sitter syntax tree (node types, byte ranges, leaf text)")
+	rootCmd.Flags().BoolVar(&withRaw, "with-raw", false, "Add a read-only _source/ root mirroring the source tree's files alongside the projection")
+	rootCmd.Flags().BoolVar(&withHistory, "history", false, "Add _history/ with the git repository's commits at the root and, in each construct, the commits that changed its lines")
+	rootCmd.Flags().StringVar(&coverageFile, "coverage", "", "Go cover profile (go test -coverprofile) to show as a _coverage file in each function")
+	rootCmd.Flags().BoolVar(&allowExec, "allow-exec", false, "Let the schema's computed leaves (leaves with a command) run their commands")
+	rootCmd.Flags().DurationVar(&ingest.LeafCommandTimeout, "exec-timeout", ingest.LeafCommandTimeout, "Kill a computed leaf's command after this long")
+	rootCmd.Flags().DurationVar(&ingest.ParseTimeout, "parse-timeout", ingest.ParseTimeout, "Give up parsing a source file after this long and project it raw under _project_files/ (0 = no limit)")
+	rootCmd.Flags().StringVar(&profileKind, "profile", "", "Write a profile of the ingest for diagnosing slow or memory-hungry mounts: cpu, mem (heap), or trace")
+	rootCmd.Flags().StringVar(&profileOut, "profile-out", "", "File for --profile (default mache.<kind>.pprof, or mache.trace)")
+	rootCmd.Flags().BoolVar(&profileMount, "profile-mount", false, "Keep --profile running while mounted, writing it on unmount, instead of stopping once the mount is up")
+	rootCmd.Flags().IntVar(&spillNodes, "spill-nodes", 0, "Keep at most this many nodes in memory during ingest and spill the rest to a temp file (0 = all in memory)")
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(listCmd)
@@ -94,6 +186,19 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
+		if profileKind != "" {
+			path := profileOut
+			if path == "" {
+				path = defaultProfilePath(profileKind)
+			}
+			stop, err := startProfile(profileKind, path)
+			if err != nil {
+				return fmt.Errorf("--profile: %w", err)
+			}
+			stopProfile = stop
+			defer stop()
+		}
+
 		// Apply --max-file-size
 		if maxFileSize != "" {
 			mfs, err := ingest.ParseSize(maxFileSize)
@@ -102,11 +207,47 @@ var rootCmd = &cobra.Command{
 			}
 			ingest.MaxIngestFileSize = mfs
 		}
+		ingest.IngestWorkers = workers
+		ingest.UnnamedBucket = unnamed
+		ingest.SplitVisibility = splitVis
+		collisions, err := ingest.ParseCollisionStrategy(onCollision)
+		if err != nil {
+			return fmt.Errorf("--on-collision: %w", err)
+		}
+		ingest.Collisions = collisions
+		ingest.SignaturesOnly = sigsOnly
+		ingest.DebugSchema = debugSchema
+		ingest.WithRawSource = withRaw
+		ingest.SkipErrors = skipErrors
+		ingest.NoProjectFiles = noProjFiles
+		ingest.GroupGenerated = groupGen
+		langPreset, overrideSpecs, err := splitLangFlag(langMap)
+		if err != nil {
+			return fmt.Errorf("--lang: %w", err)
+		}
+		overrides, err := ingest.ParseLangOverrides(overrideSpecs)
+		if err != nil {
+			return fmt.Errorf("--lang: %w", err)
+		}
+		ingest.LangOverrides = overrides
+
+		if err := nfsmount.ValidateWriteGlobs(denyWrite); err != nil {
+			return fmt.Errorf("--deny-write: %w", err)
+		}
+		if err := nfsmount.ValidateWriteGlobs(writePaths); err != nil {
+			return fmt.Errorf("--writable-path: %w", err)
+		}
 
 		// Validate flag combinations
 		if outPath != "" && agentMode {
 			return fmt.Errorf("--out and --agent cannot be used together (--agent enables writable mode, --out requires read-only)")
 		}
+		if maxConcur < 0 {
+			return fmt.Errorf("--max-concurrency must be 0 (unlimited) or more, got %d", maxConcur)
+		}
+		if withHistory && snapshot {
+			return fmt.Errorf("--history and --snapshot cannot be used together (the snapshot leaves out .git)")
+		}
 
 		// Agent mode: auto-generate mount point and configure
 		if agentMode {
@@ -147,13 +288,58 @@ var rootCmd = &cobra.Command{
 			dataPath = filepath.Join(defaultDir, "data.json")
 		}
 
+		// A gzipped index (from --out index.db.gz) is decompressed to a temp
+		// file and mounted from there.
+		if isGzipPath(dataPath) && filepath.Ext(strings.TrimSuffix(dataPath, ".gz")) == ".db" {
+			unzipped, err := gunzipTemp(dataPath, "mache-data-*.db")
+			if err != nil {
+				return fmt.Errorf("decompress %s: %w", dataPath, err)
+			}
+			defer func() { _ = os.Remove(unzipped) }()
+			log.Printf("Decompressed %s to %s", dataPath, unzipped)
+			dataPath = unzipped
+		}
+
+		// A source-tree archive is extracted to a temp dir and projected
+		// from there. Edits would be lost with the temp dir, so it mounts
+		// read-only.
+		if compression, ok := tarballCompression(dataPath); ok {
+			if writable {
+				return fmt.Errorf("--data %s: an archive mounts read-only; extract it to write back", dataPath)
+			}
+			root, tmpDir, err := extractTarball(dataPath, compression)
+			if err != nil {
+				return fmt.Errorf("extract %s: %w", dataPath, err)
+			}
+			defer func() { _ = os.RemoveAll(tmpDir) }()
+			log.Printf("Extracted %s to %s", dataPath, tmpDir)
+			dataPath = root
+		}
+
 		// 2. Load Schema (or infer from data)
 		var schema *api.Topology
-		if inferSchema {
+		var schemaFile string       // set when loaded from a file (enables hot-reload)
+		var report *inferenceReport // set when the schema was inferred
+		if langPreset != "" {
+			if inferSchema || cmd.Flags().Changed("schema") {
+				return fmt.Errorf("--lang %s selects a preset schema; drop --schema and --infer", langPreset)
+			}
+			preset, err := loadPresetSchema(langPreset)
+			if err != nil {
+				return err
+			}
+			log.Printf("Using embedded %s schema", langPreset)
+			schema = preset
+		} else if inferSchema {
+			if dataPath == stdinDataPath {
+				return fmt.Errorf("--infer cannot read --data %s; pass a --schema", stdinDataPath)
+			}
 			inf := &lattice.Inferrer{Config: lattice.DefaultInferConfig()}
+			inf.Config.KeepLattice = dumpLattice != ""
 			var inferred *api.Topology
 			var err error
 			ext := filepath.Ext(dataPath)
+			report = &inferenceReport{Data: dataPath, Method: inf.Config.Method}
 
 			switch ext {
 			case ".db":
@@ -174,20 +360,23 @@ var rootCmd = &cobra.Command{
 					start = time.Now()
 					// Enable Git hints
 					inf.Config.Hints = ingest.GetGitHints()
+					report.Method = "greedy"
 					inferred, err = inf.InferFromRecords(recs)
 				}
 				log.Printf("Schema inference done in %v", time.Since(start))
 			default:
 				// Try tree-sitter language lookup from the registry
-				if l := lang.ForExt(ext); l != nil {
-					inferred, err = inferFromTreeSitterFile(inf, dataPath, l.Grammar(), l.DisplayName)
+				if l := lang.ForPath(dataPath); l != nil {
+					inferred, err = inferFileSchema(inf, dataPath, l, report)
 				} else {
 					// Check if it's a directory
 					info, errStat := os.Stat(dataPath)
 					if errStat == nil && info.IsDir() {
 						log.Printf("Inferring schema from directory %s...", dataPath)
 						start := time.Now()
-						inferred, err = inferDirSchema(dataPath)
+						// Same unsampled FCA config inferDirSchema uses.
+						inf = &lattice.Inferrer{Config: lattice.InferConfig{Method: "fca", KeepLattice: inf.Config.KeepLattice}}
+						inferred, err = inferDirSchemaWith(inf, dataPath, report)
 						if err == nil {
 							log.Printf("Schema inferred in %v", time.Since(start))
 						}
@@ -201,6 +390,12 @@ var rootCmd = &cobra.Command{
 				return fmt.Errorf("schema inference failed: %w", err)
 			}
 			schema = inferred
+			if dumpLattice != "" {
+				if err := writeLatticeDump(inf, dumpLattice); err != nil {
+					return err
+				}
+				log.Printf("Concept lattice written to %s", dumpLattice)
+			}
 
 			// Write inferred schema if --schema path was provided explicitly (not default)
 			if cmd.Flags().Changed("schema") {
@@ -210,12 +405,21 @@ var rootCmd = &cobra.Command{
 				}
 				log.Printf("Inferred schema written to %s", schemaPath)
 			}
+		} else if info, err := os.Stat(schemaPath); err == nil && info.IsDir() {
+			dirSchema, err := readSchemaDir(schemaPath)
+			if err != nil {
+				return err
+			}
+			log.Printf("Loaded per-language schemas from %s", schemaPath)
+			schema = dirSchema
+			schemaFile = schemaPath
 		} else if s, err := os.ReadFile(schemaPath); err == nil {
 			log.Printf("Loaded schema from %s", schemaPath)
 			schema = &api.Topology{}
 			if err := json.Unmarshal(s, schema); err != nil {
 				return fmt.Errorf("failed to parse schema: %w", err)
 			}
+			schemaFile = schemaPath
 		} else {
 			if cmd.Flags().Changed("schema") {
 				return fmt.Errorf("failed to read schema file: %w", err)
@@ -226,10 +430,26 @@ var rootCmd = &cobra.Command{
 
 		// 2b. Expand file_set includes before ingestion/mount.
 		schema.ResolveIncludes()
+		if report != nil {
+			if inferredSchema, err = inferredSchemaJSON(schema, report); err != nil {
+				return fmt.Errorf("render inferred schema: %w", err)
+			}
+		}
+
+		// Computed leaves run arbitrary commands from the schema, so only
+		// on request.
+		commandLeaves := ingest.SchemaCommandLeaves(schema)
+		if len(commandLeaves) > 0 && !allowExec {
+			return fmt.Errorf("schema leaves %s run commands; pass --allow-exec to let them", strings.Join(commandLeaves, ", "))
+		}
+		if len(commandLeaves) > 0 && outPath != "" {
+			return fmt.Errorf("schema leaves %s run commands, which only a mount can: drop --out", strings.Join(commandLeaves, ", "))
+		}
 
 		// 3. Create the Graph backend
 		var g graph.Graph
-		var engine *ingest.Engine // non-nil for MemoryStore paths (needed for write-back)
+		var engine *ingest.Engine    // non-nil for MemoryStore paths (needed for write-back)
+		var reloader *schemaReloader // non-nil for read-only MemoryStore mounts of a schema file
 
 		if controlPath != "" {
 			return mountControl(controlPath, schema, mountPoint)
@@ -285,8 +505,11 @@ var rootCmd = &cobra.Command{
 			agentMetadata.Source = snapshotPath
 		}
 
-		if _, err := os.Stat(dataPath); err == nil {
+		if _, err := os.Stat(dataPath); err == nil || dataPath == stdinDataPath {
 			if filepath.Ext(dataPath) == ".db" {
+				if len(commandLeaves) > 0 {
+					return fmt.Errorf("schema leaves %s run commands, which a .db source can't: mount the source it was built from", strings.Join(commandLeaves, ", "))
+				}
 				// --out with .db source: ingest via SQLiteWriter, materialize, exit.
 				// Skip OpenSQLiteGraph/EagerScan entirely — no mount needed.
 				if outPath != "" {
@@ -304,7 +527,7 @@ var rootCmd = &cobra.Command{
 					}
 					eng := ingest.NewEngine(schema, writer)
 					start := time.Now()
-					if err := eng.Ingest(dataPath); err != nil {
+					if err := eng.IngestContext(cmdContext(cmd), dataPath); err != nil {
 						_ = writer.Close()
 						return fmt.Errorf("ingest for --out: %w", err)
 					}
@@ -321,7 +544,7 @@ var rootCmd = &cobra.Command{
 					if mErr != nil {
 						return mErr
 					}
-					if mErr = mat.Materialize(indexPath, outPath); mErr != nil {
+					if mErr = materializeOut(mat, indexPath, outPath); mErr != nil {
 						return fmt.Errorf("materialize (%s): %w", outFormat, mErr)
 					}
 					log.Printf("Wrote %s (format: %s)", outPath, outFormat)
@@ -330,13 +553,14 @@ var rootCmd = &cobra.Command{
 
 				// SQLite source: eager scan before mount to avoid fuse-t NFS timeouts
 				log.Printf("Opening %s (direct SQL backend)...", dataPath)
-				sg, err := graph.OpenSQLiteGraph(dataPath, schema, machetmpl.Render)
+				sg, err := graph.OpenSQLiteGraph(dataPath, schema, schemaRender(schema))
 				if err != nil {
 					return fmt.Errorf("open sqlite graph: %w", err)
 				}
 				defer func() { _ = sg.Close() }()
 
 				sg.SetCallExtractor(newCallExtractor())
+				sg.SetUnnamedBucket(unnamed)
 
 				start := time.Now()
 				log.Print("Scanning records...")
@@ -346,8 +570,9 @@ var rootCmd = &cobra.Command{
 				log.Printf("Scanning records done in %v", time.Since(start))
 
 				g = sg
-			} else if !writable && ingest.SchemaUsesTreeSitter(schema) {
+			} else if !writable && ingest.SchemaUsesTreeSitter(schema) && dataPath != stdinDataPath && len(commandLeaves) == 0 {
 				// Read-only source: ingest to SQLite index, mount via SQLiteGraph (fast path).
+				// Computed leaves need the MemoryStore below, which runs their commands.
 				// Uses persistent cache so re-mounts can skip unchanged files.
 				mountName := filepath.Base(mountPoint)
 				cacheDir := filepath.Join(os.TempDir(), "mache")
@@ -385,7 +610,7 @@ var rootCmd = &cobra.Command{
 				if fileIndex != nil {
 					eng.SetFileIndex(fileIndex)
 				}
-				if err := eng.Ingest(dataPath); err != nil {
+				if err := eng.IngestContext(cmdContext(cmd), dataPath); err != nil {
 					_ = writer.Close()
 					return fmt.Errorf("ingestion failed: %w", err)
 				}
@@ -394,6 +619,9 @@ var rootCmd = &cobra.Command{
 				}
 				log.Printf("Indexing complete in %v", time.Since(start))
 				eng.PrintRoutingSummary()
+				ingestTimings = func() []byte { return ingest.FormatFileTimings(eng.FileTimings()) }
+				identifierCounts = func() []byte { return ingest.FormatIdentifierCounts(eng.IdentifierCounts()) }
+				ingestManifest = func() []byte { return ingest.FormatManifest(eng.Manifest()) }
 
 				// --out: materialize virtuals, write to target format, exit (no mount)
 				if outPath != "" {
@@ -404,18 +632,18 @@ var rootCmd = &cobra.Command{
 					if err != nil {
 						return err
 					}
-					if err := mat.Materialize(indexPath, outPath); err != nil {
+					if err := materializeOut(mat, indexPath, outPath); err != nil {
 						return fmt.Errorf("materialize (%s): %w", outFormat, err)
 					}
 					_ = os.Remove(indexPath)
 					log.Printf("Wrote %s (format: %s)", outPath, outFormat)
-					if outFormat == "sqlite" {
+					if outFormat == "sqlite" && !isGzipPath(outPath) {
 						log.Printf("Load into leyline: leyline load --db %s --control /tmp/ll.ctrl", outPath)
 					}
 					return nil
 				}
 
-				sg, err := graph.OpenSQLiteGraph(indexPath, schema, machetmpl.Render)
+				sg, err := graph.OpenSQLiteGraph(indexPath, schema, schemaRender(schema))
 				if err != nil {
 					return fmt.Errorf("open indexed graph: %w", err)
 				}
@@ -425,54 +653,44 @@ var rootCmd = &cobra.Command{
 				}()
 
 				sg.SetCallExtractor(newCallExtractor())
+				sg.SetUnnamedBucket(unnamed)
 				g = sg
 			} else {
-				// Writable or non-tree-sitter: MemoryStore + ingestion pipeline
-				store := graph.NewMemoryStore()
-				resolver := graph.NewSQLiteResolver(machetmpl.Render)
+				// Writable, non-tree-sitter, computed leaves, or stdin: MemoryStore + ingestion pipeline
+				resolver := graph.NewSQLiteResolver(schemaRender(schema))
 				defer resolver.Close()
-				store.SetResolver(resolver.Resolve)
-
-				// Wire call extractor for callees/ resolution
-				store.SetCallExtractor(newCallExtractor())
 
-				engine = ingest.NewEngine(schema, store)
-
-				if filepath.Ext(dataPath) == ".git" {
-					log.Printf("Ingesting git history from %s...", dataPath)
-					start := time.Now()
-					recs, err := ingest.LoadGitCommits(dataPath)
-					if err != nil {
-						return fmt.Errorf("load git: %w", err)
-					}
-					if err := engine.IngestRecords(recs); err != nil {
-						return fmt.Errorf("ingest git records: %w", err)
+				store, eng, err := ingestMemoryStore(cmdContext(cmd), schema, dataPath, resolver)
+				if err != nil {
+					return err
+				}
+				engine = eng
+				currentEngine := func() *ingest.Engine { return eng }
+
+				// Read-only mounts of a schema file can be re-projected in
+				// place on SIGHUP. Writable mounts keep a fixed store because
+				// write-back splices through this engine and store.
+				// Piped data can't be re-read, so stdin mounts don't reload.
+				if schemaFile != "" && !writable && dataPath != stdinDataPath {
+					hotSwap := graph.NewHotSwapGraph(store)
+					defer func() { _ = hotSwap.Close() }() // safe to ignore
+					reloader = &schemaReloader{
+						schemaPath: schemaFile,
+						dataPath:   dataPath,
+						hotSwap:    hotSwap,
+						resolver:   resolver,
 					}
-					log.Printf("Ingestion complete in %v", time.Since(start))
-					engine.PrintRoutingSummary()
+					reloader.engine.Store(eng)
+					// The ingest reports follow the engine of the latest reload.
+					currentEngine = reloader.engine.Load
+					g = hotSwap
 				} else {
-					log.Printf("Ingesting data from %s...", dataPath)
-					start := time.Now()
-					if err := engine.Ingest(dataPath); err != nil {
-						return fmt.Errorf("ingestion failed: %w", err)
-					}
-					log.Printf("Ingestion complete in %v", time.Since(start))
-					engine.PrintRoutingSummary()
+					defer func() { _ = store.Close() }() // safe to ignore
+					g = store
 				}
-
-				// Wire live graph refresher: re-ingest stale files on read
-				store.SetRefresher(engine.ReIngestFile)
-
-				// Enable SQL query support for MemoryStore
-				if err := store.InitRefsDB(); err != nil {
-					return fmt.Errorf("init refs db: %w", err)
-				}
-				defer func() { _ = store.Close() }() // safe to ignore
-				if err := store.FlushRefs(); err != nil {
-					log.Printf("Warning: refs flush failed: %v", err)
-				}
-
-				g = store
+				ingestTimings = func() []byte { return ingest.FormatFileTimings(currentEngine().FileTimings()) }
+				identifierCounts = func() []byte { return ingest.FormatIdentifierCounts(currentEngine().IdentifierCounts()) }
+				ingestManifest = func() []byte { return ingest.FormatManifest(currentEngine().Manifest()) }
 			}
 		} else {
 			if cmd.Flags().Changed("data") {
@@ -485,6 +703,7 @@ var rootCmd = &cobra.Command{
 		// Agent mode: save metadata sidecar and generate prompt content
 		var promptContent []byte
 		if agentMode && agentMetadata != nil {
+			agentMetadata.ControlSocket = socketPath(mountPoint)
 			if err := saveMountMetadata(mountPoint, agentMetadata); err != nil {
 				log.Printf("Warning: failed to save mount metadata: %v", err)
 			}
@@ -506,10 +725,57 @@ var rootCmd = &cobra.Command{
 		// Fire-and-forget: push content to ley-line for embedding
 		go leyline.TriggerEmbedding(g, 100)
 
-		return mountNFS(schema, g, engine, mountPoint, writable, promptContent)
+		cache := nfsCacheTimeouts(cmd, writable)
+		return mountNFS(schema, g, engine, mountPoint, writable, cache, promptContent, reloader)
 	},
 }
 
+// hiddenEntries returns the synthetic entry names the --no-* listing flags
+// hide.
+func hiddenEntries() []string {
+	var names []string
+	for _, h := range []struct {
+		hide bool
+		name string
+	}{
+		{noSchemaFile, graph.SchemaDotJSON},
+		{noSchemaFile, graph.SchemaInferredJSON},
+		{noQueryDir, ".query"},
+		{noProjFiles, ingest.ProjectFilesDir},
+		{noDiagDir, graph.DiagnosticsDir},
+	} {
+		if h.hide {
+			names = append(names, h.name)
+		}
+	}
+	return names
+}
+
+// nfsListenAddr returns the NFS server address from --nfs-addr and
+// --nfs-port, warning when it reaches beyond this host.
+func nfsListenAddr() string {
+	if ip := net.ParseIP(nfsAddr); nfsAddr != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		log.Printf("Warning: NFS server on %s is reachable from other hosts and has no authentication; use it only on a trusted network", nfsAddr)
+	}
+	return net.JoinHostPort(nfsAddr, strconv.Itoa(nfsPort))
+}
+
+// nfsCacheTimeouts resolves --attr-timeout/--entry-timeout. Writable mounts
+// use 0 (noac) unless a timeout is set explicitly, trading write-back
+// visibility for fewer round-trips only when the user asks for it.
+func nfsCacheTimeouts(cmd *cobra.Command, writable bool) nfsmount.CacheTimeouts {
+	cache := nfsmount.CacheTimeouts{Attr: attrTimeout, Entry: entryTimeout}
+	if writable {
+		if !cmd.Flags().Changed("attr-timeout") {
+			cache.Attr = 0
+		}
+		if !cmd.Flags().Changed("entry-timeout") {
+			cache.Entry = 0
+		}
+	}
+	return cache
+}
+
 // mountControl starts Mache in hot-swap mode using the Control Block.
 func mountControl(path string, schema *api.Topology, mountPoint string) error {
 	ctrl, err := control.OpenOrCreate(path)
@@ -567,7 +833,7 @@ func mountControl(path string, schema *api.Topology, mountPoint string) error {
 	}
 
 	// Read-only hot-swap mode (existing logic)
-	initialGraph, err := graph.OpenSQLiteGraph(dbPath, schema, machetmpl.Render)
+	initialGraph, err := graph.OpenSQLiteGraph(dbPath, schema, schemaRender(schema))
 	if err != nil {
 		return fmt.Errorf("open initial graph %s: %w", dbPath, err)
 	}
@@ -593,7 +859,7 @@ func mountControl(path string, schema *api.Topology, mountPoint string) error {
 				}
 
 				// Open new graph
-				newGraph, err := graph.OpenSQLiteGraph(newDBPath, schema, machetmpl.Render)
+				newGraph, err := graph.OpenSQLiteGraph(newDBPath, schema, schemaRender(schema))
 				if err != nil {
 					log.Printf("Error opening new graph %s: %v", newDBPath, err)
 					_ = os.Remove(newDBPath)
@@ -613,7 +879,8 @@ func mountControl(path string, schema *api.Topology, mountPoint string) error {
 		}
 	}()
 
-	return mountNFS(schema, hotSwap, nil, mountPoint, false, nil)
+	// Hot-swapped generations must be visible immediately: never cache.
+	return mountNFS(schema, hotSwap, nil, mountPoint, false, nfsmount.CacheTimeouts{}, nil, nil)
 }
 
 // mountControlWritable opens the extracted DB in read-write mode and
@@ -623,11 +890,12 @@ func mountControlWritable(masterDBPath, arenaPath string, schema *api.Topology,
 	flusher.Start(100 * time.Millisecond)
 	defer func() { _ = flusher.Close() }() // final flush on unmount
 
-	wg, err := graph.OpenWritableGraph(masterDBPath, schema, machetmpl.Render, flusher)
+	wg, err := graph.OpenWritableGraph(masterDBPath, schema, schemaRender(schema), flusher)
 	if err != nil {
 		return fmt.Errorf("open writable graph: %w", err)
 	}
-	defer func() { _ = wg.Close() }()
+	defer func() { _ = wg.Close() }() // final checkpoint, before the final flush
+	wg.StartCheckpoints(checkpointInterval)
 
 	log.Println("Writable arena mode: edits write to master DB and flush to arena (100ms coalesce).")
 
@@ -637,6 +905,14 @@ func mountControlWritable(masterDBPath, arenaPath string, schema *api.Topology,
 // mountWritableNFS mounts a WritableGraph via NFS with arena write-back.
 func mountWritableNFS(schema *api.Topology, wg *graph.WritableGraph, mountPoint string) error {
 	graphFs := nfsmount.NewGraphFS(wg, schema)
+	if err := graphFs.SetDenyWrite(denyWrite); err != nil {
+		return err
+	}
+	if err := graphFs.SetWritablePaths(writePaths); err != nil {
+		return err
+	}
+	graphFs.SetHidden(hiddenEntries())
+	graphFs.SetMaxConcurrency(maxConcur)
 
 	graphFs.SetWriteBack(func(nodeID string, origin graph.SourceOrigin, content []byte) error {
 		// Update DB record, then request coalesced arena flush (non-blocking).
@@ -647,15 +923,25 @@ func mountWritableNFS(schema *api.Topology, wg *graph.WritableGraph, mountPoint
 		return nil
 	})
 
-	srv, err := nfsmount.NewServer(graphFs)
+	srv, err := nfsmount.NewServer(graphFs, nfsListenAddr())
 	if err != nil {
 		return fmt.Errorf("start NFS server: %w", err)
 	}
 	defer func() { _ = srv.Close() }()
 
-	log.Printf("Mounting mache at %s (NFS on localhost:%d)...", mountPoint, srv.Port())
+	if stop, err := newMountSocket(wg, graphFs, nil).listen(socketPath(mountPoint)); err != nil {
+		log.Printf("Warning: control socket unavailable: %v", err)
+	} else {
+		defer stop()
+		log.Printf("Control socket: %s", socketPath(mountPoint))
+	}
+
+	log.Printf("Mounting mache at %s (NFS on %s:%d)...", mountPoint, srv.MountHost(), srv.Port())
 
-	if err := nfsmount.Mount(srv.Port(), mountPoint, true, nfsOpts); err != nil {
+	if !profileMount {
+		stopProfile()
+	}
+	if err := nfsmount.Mount(srv.MountHost(), srv.Port(), mountPoint, true, nfsmount.CacheTimeouts{}, nfsOpts); err != nil {
 		return err
 	}
 	log.Print("Mounted (writable). Press Ctrl-C to unmount.")
@@ -673,22 +959,92 @@ func mountWritableNFS(schema *api.Topology, wg *graph.WritableGraph, mountPoint
 }
 
 // mountNFS starts an NFS server backed by GraphFS and mounts it.
-func mountNFS(schema *api.Topology, g graph.Graph, engine *ingest.Engine, mountPoint string, writable bool, promptContent []byte) error {
+// A non-nil reloader re-projects the graph on SIGHUP while mounted.
+func mountNFS(schema *api.Topology, g graph.Graph, engine *ingest.Engine, mountPoint string, writable bool, cache nfsmount.CacheTimeouts, promptContent []byte, reloader *schemaReloader) error {
 	graphFs := nfsmount.NewGraphFS(g, schema)
 	if len(promptContent) > 0 {
 		graphFs.SetPromptContent(promptContent)
 	}
+	if err := graphFs.SetDenyWrite(denyWrite); err != nil {
+		return err
+	}
+	if err := graphFs.SetWritablePaths(writePaths); err != nil {
+		return err
+	}
+	graphFs.SetHidden(hiddenEntries())
+	graphFs.SetMaxConcurrency(maxConcur)
+	if debugSchema {
+		graphFs.SetSExpr(ingest.SExpr)
+	}
+	if withAST {
+		graphFs.SetAST(ingest.ASTJSON)
+	}
+	if ingestTimings != nil {
+		graphFs.SetIngestTimings(ingestTimings)
+	}
+	if identifierCounts != nil {
+		graphFs.SetIdentifiers(identifierCounts)
+	}
+	if ingest.GroupsImports(schema) {
+		graphFs.SetImportCycles(func() []byte { return ingest.FormatImportCycles(ingest.ImportCycles(g)) })
+	}
+	if ingestManifest != nil {
+		graphFs.SetManifest(ingestManifest)
+	}
+	if inferredSchema != nil {
+		graphFs.SetInferredSchema(inferredSchema)
+	}
+	if withHistory {
+		history, err := ingest.OpenGitHistory(dataPath)
+		if err != nil {
+			return fmt.Errorf("--history: %w", err)
+		}
+		graphFs.SetHistory(history)
+	}
+	if coverageFile != "" {
+		profile, err := ingest.OpenGoCoverProfile(coverageFile, dataPath)
+		if err != nil {
+			return fmt.Errorf("--coverage: %w", err)
+		}
+		graphFs.SetCoverage(profile)
+	}
+	ctlSock := newMountSocket(g, graphFs, reloader)
+	if reloader != nil {
+		reloader.onError = ctlSock.recordError
+		stop := reloader.watch(graphFs)
+		defer stop()
+	}
 
 	// Wire write-back if requested (validate → format → splice → surgical update → invalidate)
 	if writable && engine != nil {
 		store, isMemStore := g.(*graph.MemoryStore)
-		graphFs.SetWriteBack(func(nodeID string, origin graph.SourceOrigin, content []byte) error {
+		writeBack := func(nodeID string, origin graph.SourceOrigin, content []byte) error {
 			// Retrieve node to update DraftData
 			node, err := g.GetNode(nodeID)
 			if err != nil {
+				if origin.StartByte == origin.EndByte {
+					// A construct created on the mount: its origin is the
+					// empty range at the end of a sibling's file.
+					return appendConstruct(engine, origin, content)
+				}
 				return fmt.Errorf("node not found: %w", err)
 			}
 
+			// JSON field leaf: set the field and re-ingest the document.
+			if origin.JSONPath != "" {
+				if err := writeJSONField(engine, origin, content); err != nil {
+					if isMemStore {
+						store.WriteStatus.Store(filepath.Dir(nodeID), err.Error())
+					}
+					return err
+				}
+				if isMemStore {
+					store.WriteStatus.Store(filepath.Dir(nodeID), "ok")
+				}
+				g.Invalidate(nodeID)
+				return nil
+			}
+
 			// 1. Validate syntax before touching source file
 			if err := writeback.Validate(content, origin.FilePath); err != nil {
 				log.Printf("writeback: validation failed for %s: %v (saving draft)", origin.FilePath, err)
@@ -699,6 +1055,7 @@ func mountNFS(schema *api.Topology, g graph.Graph, engine *ingest.Engine, mountP
 					draft := make([]byte, len(content))
 					copy(draft, content)
 					node.DraftData = draft
+					store.AddNode(node) // a spilling store hands out copies
 				}
 				return nil
 			}
@@ -720,28 +1077,35 @@ func mountNFS(schema *api.Topology, g graph.Graph, engine *ingest.Engine, mountP
 			}
 
 			// 3. Splice formatted content into source file
+			// Splice may convert line endings or trim a trailing newline,
+			// so the node is sized by what it wrote, not by formatted.
 			oldLen := origin.EndByte - origin.StartByte
-			if err := writeback.Splice(origin, formatted); err != nil {
+			spliced, err := writeback.Splice(origin, formatted)
+			if err != nil {
 				return err
 			}
+			written := spliced.Content
 
 			// 4. Surgical node update — no re-ingest
 			newOrigin := &graph.SourceOrigin{
 				FilePath:  origin.FilePath,
 				StartByte: origin.StartByte,
-				EndByte:   origin.StartByte + uint32(len(formatted)),
+				EndByte:   origin.StartByte + uint32(len(written)),
 			}
 			if isMemStore {
-				delta := int32(len(formatted)) - int32(oldLen)
+				delta := int32(len(written)) - int32(oldLen)
 				if delta != 0 {
 					store.ShiftOrigins(origin.FilePath, origin.EndByte, delta)
 				}
+				if spliced.LineDelta != 0 {
+					store.ShiftLines(origin.FilePath, spliced.EndLine, spliced.LineDelta)
+				}
 				// Use source file mtime for deterministic timestamps
 				modTime := time.Now()
 				if fi, err := os.Stat(origin.FilePath); err == nil {
 					modTime = fi.ModTime()
 				}
-				_ = store.UpdateNodeContent(nodeID, formatted, newOrigin, modTime)
+				_ = store.UpdateNodeContent(nodeID, written, newOrigin, modTime)
 				store.RecordFileMtime(origin.FilePath, modTime)
 				store.WriteStatus.Store(filepath.Dir(nodeID), "ok")
 			}
@@ -749,21 +1113,38 @@ func mountNFS(schema *api.Topology, g graph.Graph, engine *ingest.Engine, mountP
 			// 5. Invalidate cached size/content
 			g.Invalidate(nodeID)
 			return nil
+		}
+		graphFs.SetWriteBack(func(nodeID string, origin graph.SourceOrigin, content []byte) error {
+			err := writeBack(nodeID, origin, content)
+			if err != nil {
+				ctlSock.recordError(fmt.Errorf("write %s: %w", nodeID, err))
+			}
+			return err
 		})
 		log.Println("Write-back enabled: edits will splice into source files.")
 	} else if writable {
 		log.Println("Warning: --writable ignored (only supported for non-.db sources)")
 	}
 
-	srv, err := nfsmount.NewServer(graphFs)
+	srv, err := nfsmount.NewServer(graphFs, nfsListenAddr())
 	if err != nil {
 		return fmt.Errorf("start NFS server: %w", err)
 	}
 	defer func() { _ = srv.Close() }()
 
-	log.Printf("Mounting mache at %s (NFS on localhost:%d)...", mountPoint, srv.Port())
+	if stop, err := ctlSock.listen(socketPath(mountPoint)); err != nil {
+		log.Printf("Warning: control socket unavailable: %v", err)
+	} else {
+		defer stop()
+		log.Printf("Control socket: %s", socketPath(mountPoint))
+	}
+
+	log.Printf("Mounting mache at %s (NFS on %s:%d)...", mountPoint, srv.MountHost(), srv.Port())
 
-	if err := nfsmount.Mount(srv.Port(), mountPoint, writable, nfsOpts); err != nil {
+	if !profileMount {
+		stopProfile()
+	}
+	if err := nfsmount.Mount(srv.MountHost(), srv.Port(), mountPoint, writable, cache, nfsOpts); err != nil {
 		return err
 	}
 	log.Print("Mounted. Press Ctrl-C to unmount.")
@@ -781,6 +1162,35 @@ func mountNFS(schema *api.Topology, g graph.Graph, engine *ingest.Engine, mountP
 	return nil
 }
 
+// appendConstruct writes a construct created on the mount into its source
+// file at origin (an empty range at EOF) and re-ingests that file so the new
+// node is projected. Invalid content is rejected outright: unlike an edit
+// there is no node yet to hold a draft.
+func appendConstruct(engine *ingest.Engine, origin graph.SourceOrigin, content []byte) error {
+	if err := writeback.Validate(content, origin.FilePath); err != nil {
+		return fmt.Errorf("new construct for %s: %w", origin.FilePath, err)
+	}
+	formatted := bytes.TrimRight(writeback.FormatBuffer(content, origin.FilePath), "\n")
+	insert := make([]byte, 0, len(formatted)+2)
+	insert = append(insert, '\n')
+	insert = append(insert, formatted...)
+	insert = append(insert, '\n')
+	if _, err := writeback.Splice(origin, insert); err != nil {
+		return err
+	}
+	return engine.ReIngestFile(origin.FilePath)
+}
+
+// writeJSONField sets the field a JSON leaf was rendered from and re-ingests
+// the data file, so directory names and other leaves derived from the same
+// document pick up the change.
+func writeJSONField(engine *ingest.Engine, origin graph.SourceOrigin, content []byte) error {
+	if err := writeback.SetJSONField(origin, content); err != nil {
+		return err
+	}
+	return engine.ReIngestFile(origin.FilePath)
+}
+
 // FUSE backend removed in v0.7.0 (ADR-0006). NFS is the only mount backend.
 // For FUSE mounts, use ley-line-open's `leyline serve`.
 
@@ -989,9 +1399,18 @@ var cleanCmd = &cobra.Command{
 	},
 }
 
-// Execute runs the root command.
+// Execute runs the root command. The first SIGINT/SIGTERM cancels the
+// command's context, so a long ingest stops cleanly; a second one kills the
+// process as usual.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/nfsmount"
+)
+
+// maxRecentErrors bounds the error history reported by the stats command.
+const maxRecentErrors = 20
+
+// nodeCountTTL bounds how stale the node counts reported by stats may get
+// after the graph changes (write-back, re-ingest); a reload recounts.
+const nodeCountTTL = 5 * time.Second
+
+// socketPath returns the control socket path for a mount point, beside its
+// sidecar.
+func socketPath(mountPoint string) string {
+	return mountPoint + ".sock"
+}
+
+// mountSocket answers line-oriented commands about a live mount on a unix
+// socket:
+//
+//	stats              JSON: node count, content cache, recent errors
+//	invalidate <path>  evict cached size and content for a node
+//	reload             re-project the schema (same as SIGHUP)
+//
+// Each command gets a one-line reply: JSON for stats, "ok" on success, or
+// "error: <message>".
+type mountSocket struct {
+	g        graph.Graph
+	gfs      *nfsmount.GraphFS
+	reloader *schemaReloader
+	started  time.Time
+
+	mu     sync.Mutex
+	errors []mountError
+
+	countMu   sync.Mutex
+	nodes     int
+	dirs      int
+	countedAt time.Time
+}
+
+type mountError struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// mountStats is the reply to the stats command.
+type mountStats struct {
+	PID          int               `json:"pid"`
+	Uptime       string            `json:"uptime"`
+	Nodes        int               `json:"nodes"`
+	Dirs         int               `json:"dirs"`
+	Cache        *graph.CacheStats `json:"cache,omitempty"`
+	RecentErrors []mountError      `json:"recent_errors"`
+}
+
+func newMountSocket(g graph.Graph, gfs *nfsmount.GraphFS, reloader *schemaReloader) *mountSocket {
+	return &mountSocket{g: g, gfs: gfs, reloader: reloader, started: time.Now()}
+}
+
+// recordError keeps err for the stats command, dropping the oldest entry
+// past maxRecentErrors.
+func (m *mountSocket) recordError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors = append(m.errors, mountError{Time: time.Now(), Message: err.Error()})
+	if len(m.errors) > maxRecentErrors {
+		m.errors = m.errors[len(m.errors)-maxRecentErrors:]
+	}
+}
+
+// listen serves commands on a unix socket at path until the returned
+// function is called, which also removes the socket file.
+func (m *mountSocket) listen(path string) (stop func(), err error) {
+	_ = os.Remove(path) // stale socket from a crashed mount
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("control socket: %v", err)
+				}
+				return
+			}
+			go m.serve(conn)
+		}
+	}()
+	return func() {
+		_ = ln.Close()
+		wg.Wait()
+		_ = os.Remove(path)
+	}, nil
+}
+
+// serve answers commands from conn, one per line, until the client closes it.
+func (m *mountSocket) serve(conn io.ReadWriteCloser) {
+	defer func() { _ = conn.Close() }()
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if _, err := io.WriteString(conn, m.dispatch(line)+"\n"); err != nil {
+			return
+		}
+	}
+}
+
+// dispatch runs one command line and returns its reply.
+func (m *mountSocket) dispatch(line string) string {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "stats":
+		data, err := json.Marshal(m.stats())
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return string(data)
+	case "invalidate":
+		if arg == "" {
+			return "error: usage: invalidate <path>"
+		}
+		id := graph.NormalizeID(arg)
+		if _, err := m.g.GetNode(id); err != nil {
+			return fmt.Sprintf("error: %s: %v", arg, err)
+		}
+		m.g.Invalidate(id)
+		if m.gfs != nil {
+			m.gfs.PublishEvent(nfsmount.EventInvalidate, id)
+		}
+		return "ok"
+	case "reload":
+		if m.reloader == nil {
+			return "error: reload needs a read-only mount of a --schema file"
+		}
+		if err := m.reloader.reloadInto(m.gfs); err != nil {
+			return "error: " + err.Error()
+		}
+		m.countMu.Lock()
+		m.countedAt = time.Time{}
+		m.countMu.Unlock()
+		return "ok"
+	default:
+		return fmt.Sprintf("error: unknown command %q (want stats, invalidate <path>, or reload)", name)
+	}
+}
+
+func (m *mountSocket) stats() mountStats {
+	st := mountStats{
+		PID:    os.Getpid(),
+		Uptime: time.Since(m.started).Round(time.Second).String(),
+	}
+	st.Nodes, st.Dirs = m.nodeCounts()
+	if cr, ok := m.g.(graph.CacheReporter); ok {
+		cs := cr.CacheStats()
+		st.Cache = &cs
+	}
+	m.mu.Lock()
+	st.RecentErrors = append([]mountError{}, m.errors...)
+	m.mu.Unlock()
+	return st
+}
+
+// nodeCounts returns the graph's node and directory counts, walking it
+// again only when the last count is older than nodeCountTTL.
+func (m *mountSocket) nodeCounts() (nodes, dirs int) {
+	m.countMu.Lock()
+	defer m.countMu.Unlock()
+	if m.countedAt.IsZero() || time.Since(m.countedAt) > nodeCountTTL {
+		m.nodes, m.dirs = countNodes(m.g)
+		m.countedAt = time.Now()
+	}
+	return m.nodes, m.dirs
+}
+
+// countNodes walks the graph from the root and returns how many nodes it
+// reaches and how many of them are directories.
+func countNodes(g graph.Graph) (nodes, dirs int) {
+	queue := []string{""}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		children, err := g.ListChildStats(dir)
+		if err != nil {
+			continue
+		}
+		for _, c := range children {
+			nodes++
+			if c.IsDir {
+				dirs++
+				queue = append(queue, c.ID)
+			}
+		}
+	}
+	return nodes, dirs
+}
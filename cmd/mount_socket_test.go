@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/nfsmount"
+	machetmpl "github.com/agentic-research/mache/internal/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountSocket(t *testing.T) {
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`[{"name":"alice","role":"admin"}]`), 0o644))
+	schemaFile := filepath.Join(dir, "schema.json")
+	require.NoError(t, os.WriteFile(schemaFile, []byte(`{"version":"v1","nodes":[{"name":"users","selector":"$","children":[`+
+		`{"name":"{{.name}}","selector":"$[*]","files":[{"name":"role","content_template":"{{.role}}"}]}]}]}`), 0o644))
+
+	resolver := graph.NewSQLiteResolver(machetmpl.Render)
+	defer resolver.Close()
+	schema, err := readSchemaFile(schemaFile)
+	require.NoError(t, err)
+	store, _, err := ingestMemoryStore(context.Background(), schema, dataFile, resolver)
+	require.NoError(t, err)
+	hotSwap := graph.NewHotSwapGraph(store)
+	defer func() { _ = hotSwap.Close() }()
+	r := &schemaReloader{schemaPath: schemaFile, dataPath: dataFile, hotSwap: hotSwap, resolver: resolver}
+
+	ms := newMountSocket(hotSwap, nfsmount.NewGraphFS(hotSwap, schema), r)
+	r.onError = ms.recordError
+	ms.recordError(errors.New("write users/alice/role: boom"))
+
+	sock := filepath.Join(dir, "mnt.sock")
+	stop, err := ms.listen(sock)
+	require.NoError(t, err)
+	defer stop()
+
+	conn, err := net.Dial("unix", sock)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	replies := bufio.NewScanner(conn)
+	send := func(line string) string {
+		t.Helper()
+		_, err := conn.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+		require.True(t, replies.Scan(), "no reply to %q", line)
+		return replies.Text()
+	}
+
+	var st mountStats
+	require.NoError(t, json.Unmarshal([]byte(send("stats")), &st))
+	assert.Equal(t, os.Getpid(), st.PID)
+	assert.Equal(t, 3, st.Nodes, "users, users/alice, users/alice/role")
+	assert.Equal(t, 2, st.Dirs)
+	require.NotNil(t, st.Cache)
+	require.Len(t, st.RecentErrors, 1)
+	assert.Equal(t, "write users/alice/role: boom", st.RecentErrors[0].Message)
+
+	assert.Equal(t, "ok", send("invalidate /users/alice/role"))
+	assert.Contains(t, send("invalidate users/nobody"), "error: users/nobody")
+	assert.Equal(t, "error: usage: invalidate <path>", send("invalidate"))
+	assert.Contains(t, send("frobnicate"), `error: unknown command "frobnicate"`)
+
+	// Counts are cached for nodeCountTTL; a reload recounts.
+	store.AddRoot(&graph.Node{ID: "extra", Mode: 0o444})
+	require.NoError(t, json.Unmarshal([]byte(send("stats")), &st))
+	assert.Equal(t, 3, st.Nodes, "a fresh count is reused")
+	ms.countMu.Lock()
+	ms.countedAt = time.Now().Add(-nodeCountTTL - time.Second)
+	ms.countMu.Unlock()
+	require.NoError(t, json.Unmarshal([]byte(send("stats")), &st))
+	assert.Equal(t, 4, st.Nodes, "a stale count is redone")
+
+	assert.Equal(t, "ok", send("reload"))
+	require.NoError(t, json.Unmarshal([]byte(send("stats")), &st))
+	assert.Equal(t, 3, st.Nodes, "the reloaded projection is counted")
+	require.NoError(t, os.WriteFile(schemaFile, []byte(`{not json`), 0o644))
+	assert.Contains(t, send("reload"), "error: ")
+	require.NoError(t, json.Unmarshal([]byte(send("stats")), &st))
+	require.Len(t, st.RecentErrors, 2, "a failed reload is recorded")
+	assert.Contains(t, st.RecentErrors[1].Message, "reload: ")
+
+	stop()
+	_, err = os.Stat(sock)
+	assert.True(t, os.IsNotExist(err), "stop removes the socket file")
+}
+
+func TestMountSocket_ReloadUnsupported(t *testing.T) {
+	ms := newMountSocket(graph.NewMemoryStore(), nil, nil)
+	assert.Equal(t, "error: reload needs a read-only mount of a --schema file", ms.dispatch("reload"))
+}
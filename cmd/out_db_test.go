@@ -85,3 +85,66 @@ func TestOutFlag_DBSource(t *testing.T) {
 	assert.Contains(t, names, "items/foo/value")
 	assert.Contains(t, names, "items/baz/value")
 }
+
+// TestOutFlag_Gzip verifies that an --out path ending in .gz is gzipped and
+// that gunzipTemp restores the index for mounting.
+func TestOutFlag_Gzip(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcDB := filepath.Join(tmpDir, "test.db")
+
+	db, err := sql.Open("sqlite", srcDB)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE results (id TEXT PRIMARY KEY, record TEXT NOT NULL);
+		INSERT INTO results VALUES ('a', '{"item":{"name":"foo","value":"bar"}}');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	schemaFile := filepath.Join(tmpDir, "schema.json")
+	require.NoError(t, os.WriteFile(schemaFile, []byte(`{
+		"version": "v1",
+		"nodes": [{
+			"name": "items",
+			"selector": "$",
+			"children": [{
+				"name": "{{.item.name}}",
+				"selector": "$[*]",
+				"files": [{"name": "value", "content_template": "{{.item.value}}"}]
+			}]
+		}]
+	}`), 0o644))
+
+	oldSchema, oldData, oldOut, oldFormat := schemaPath, dataPath, outPath, outFormat
+	defer func() {
+		schemaPath, dataPath, outPath, outFormat = oldSchema, oldData, oldOut, oldFormat
+	}()
+	schemaPath = schemaFile
+	dataPath = srcDB
+	outPath = filepath.Join(tmpDir, "index.db.gz")
+	outFormat = "sqlite"
+
+	require.NoError(t, rootCmd.RunE(rootCmd, []string{filepath.Join(tmpDir, "mnt")}))
+
+	raw, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	require.Greater(t, len(raw), 2)
+	assert.Equal(t, []byte{0x1f, 0x8b}, raw[:2], "output is gzip")
+
+	unzipped, err := gunzipTemp(outPath, "mache-test-*.db")
+	require.NoError(t, err)
+	defer func() { _ = os.Remove(unzipped) }()
+
+	idx, err := sql.Open("sqlite", unzipped)
+	require.NoError(t, err)
+	defer func() { _ = idx.Close() }()
+	var n int
+	require.NoError(t, idx.QueryRow(`SELECT COUNT(*) FROM nodes WHERE id = 'items/foo/value'`).Scan(&n))
+	assert.Equal(t, 1, n)
+
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Name(), ".mache-out-", "temp output is removed")
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync"
+)
+
+// Profile kinds for --profile.
+const (
+	profileCPU   = "cpu"
+	profileMem   = "mem"
+	profileTrace = "trace"
+)
+
+// stopProfile ends the --profile profile and writes it out; a no-op when
+// none is running. The mount calls it once the mount is up, so the profile
+// covers ingest, unless --profile-mount keeps it running until unmount.
+var stopProfile = func() {}
+
+// defaultProfilePath is where --profile writes a profile of kind without
+// --profile-out.
+func defaultProfilePath(kind string) string {
+	if kind == profileTrace {
+		return "mache.trace"
+	}
+	return "mache." + kind + ".pprof"
+}
+
+// startProfile starts a profile of kind (cpu, mem, or trace) written to
+// path and returns the function that stops it. cpu and trace record from
+// now until the stop; mem is a heap profile taken at the stop, after a GC,
+// so it shows what is still live alongside everything allocated since
+// start. Calling stop more than once writes the profile once.
+func startProfile(kind, path string) (stop func(), err error) {
+	if kind != profileCPU && kind != profileMem && kind != profileTrace {
+		return nil, fmt.Errorf("unknown profile %q (want cpu, mem, or trace)", kind)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	var end func() error
+	switch kind {
+	case profileCPU:
+		if err := pprof.StartCPUProfile(f); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		end = func() error {
+			pprof.StopCPUProfile()
+			return nil
+		}
+	case profileTrace:
+		if err := trace.Start(f); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		end = func() error {
+			trace.Stop()
+			return nil
+		}
+	case profileMem:
+		end = func() error {
+			runtime.GC()
+			return pprof.WriteHeapProfile(f)
+		}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			err := end()
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				log.Printf("Warning: %s profile: %v", kind, err)
+				return
+			}
+			log.Printf("Wrote %s profile to %s", kind, path)
+		})
+	}, nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartProfile(t *testing.T) {
+	for _, kind := range []string{profileCPU, profileMem, profileTrace} {
+		t.Run(kind, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), defaultProfilePath(kind))
+			stop, err := startProfile(kind, path)
+			require.NoError(t, err)
+			_ = make([]byte, 1<<20)
+			stop()
+			stop() // writes once
+
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.NotZero(t, info.Size())
+		})
+	}
+}
+
+func TestStartProfile_UnknownKind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "p")
+	_, err := startProfile("block", path)
+	assert.ErrorContains(t, err, "unknown profile")
+	assert.NoFileExists(t, path)
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/agentic-research/mache/internal/nfsmount"
+)
+
+// stdinDataPath is the --data value that reads records from stdin.
+const stdinDataPath = "-"
+
+// ingestMemoryStore ingests dataPath into a fresh MemoryStore under schema,
+// with the call extractor, live refresher, and refs DB wired up. Cancelling
+// ctx aborts the ingest. With --spill-nodes, nodes beyond that many spill to a
+// temp file. The caller owns the returned store and must Close it.
+func ingestMemoryStore(ctx context.Context, schema *api.Topology, dataPath string, resolver *graph.SQLiteResolver) (*graph.MemoryStore, *ingest.Engine, error) {
+	store := graph.NewMemoryStore()
+	if spillNodes > 0 {
+		var err error
+		if store, err = graph.NewSpillingMemoryStore(spillNodes); err != nil {
+			return nil, nil, err
+		}
+	}
+	store.SetResolver(resolver.Resolve)
+	if allowExec {
+		store.SetComputer(ingest.RunLeafCommand)
+	}
+
+	// Wire call extractor for callees/ resolution
+	store.SetCallExtractor(newCallExtractor())
+
+	engine := ingest.NewEngine(schema, store)
+
+	switch {
+	case dataPath == stdinDataPath:
+		log.Print("Ingesting data from stdin...")
+		start := time.Now()
+		if err := engine.IngestReader(ctx, os.Stdin); err != nil {
+			_ = store.Close()
+			return nil, nil, fmt.Errorf("ingest stdin: %w", err)
+		}
+		log.Printf("Ingestion complete in %v", time.Since(start))
+	case filepath.Ext(dataPath) == ".git":
+		log.Printf("Ingesting git history from %s...", dataPath)
+		start := time.Now()
+		recs, err := ingest.LoadGitCommits(dataPath)
+		if err != nil {
+			_ = store.Close()
+			return nil, nil, fmt.Errorf("load git: %w", err)
+		}
+		if err := engine.IngestRecords(recs); err != nil {
+			_ = store.Close()
+			return nil, nil, fmt.Errorf("ingest git records: %w", err)
+		}
+		log.Printf("Ingestion complete in %v", time.Since(start))
+	default:
+		log.Printf("Ingesting data from %s...", dataPath)
+		start := time.Now()
+		if err := engine.IngestContext(ctx, dataPath); err != nil {
+			_ = store.Close()
+			return nil, nil, fmt.Errorf("ingestion failed: %w", err)
+		}
+		log.Printf("Ingestion complete in %v", time.Since(start))
+	}
+	engine.PrintRoutingSummary()
+
+	// Wire live graph refresher: re-ingest stale files on read
+	store.SetRefresher(engine.ReIngestFile)
+
+	// Enable SQL query support for MemoryStore
+	if err := store.InitRefsDB(); err != nil {
+		_ = store.Close()
+		return nil, nil, fmt.Errorf("init refs db: %w", err)
+	}
+	if err := store.FlushRefs(); err != nil {
+		log.Printf("Warning: refs flush failed: %v", err)
+	}
+	return store, engine, nil
+}
+
+// schemaReloader re-projects a read-only MemoryStore mount when its schema
+// file changes: on SIGHUP it re-reads the schema, re-ingests the data into
+// a new store, and swaps it in behind the mounted filesystem. A schema that
+// fails to load or ingest leaves the current graph mounted.
+type schemaReloader struct {
+	schemaPath string
+	dataPath   string
+	hotSwap    *graph.HotSwapGraph
+	resolver   *graph.SQLiteResolver
+
+	// engine is the ingest engine behind the mounted store, replaced on
+	// each successful reload so /_manifest.json and the other ingest reports
+	// describe the current projection.
+	engine atomic.Pointer[ingest.Engine]
+
+	// onError, if set, is told about reloads that fail.
+	onError func(error)
+}
+
+// Reload re-reads the schema file, re-ingests the data, and swaps the new
+// graph in. Returns the new schema.
+func (r *schemaReloader) Reload() (*api.Topology, error) {
+	schema, err := readSchemaFile(r.schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	store, engine, err := ingestMemoryStore(context.Background(), schema, r.dataPath, r.resolver)
+	if err != nil {
+		return nil, err
+	}
+	r.hotSwap.Swap(store)
+	r.engine.Store(engine)
+	return schema, nil
+}
+
+// readSchemaFile loads a schema JSON file and expands its file_set
+// includes, or loads a directory of per-language schemas (see
+// readSchemaDir).
+func readSchemaFile(path string) (*api.Topology, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return readSchemaDir(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read schema: %w", err)
+	}
+	schema := &api.Topology{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	schema.ResolveIncludes()
+	return schema, nil
+}
+
+// reloadInto reloads and publishes the new schema on gfs, logging the
+// outcome. On failure the current graph stays mounted.
+func (r *schemaReloader) reloadInto(gfs *nfsmount.GraphFS) error {
+	log.Printf("Reloading schema from %s...", r.schemaPath)
+	start := time.Now()
+	schema, err := r.Reload()
+	if err != nil {
+		log.Printf("Schema reload failed (keeping current graph): %v", err)
+		if r.onError != nil {
+			r.onError(fmt.Errorf("reload: %w", err))
+		}
+		return err
+	}
+	gfs.SetSchema(schema)
+	log.Printf("Schema reloaded in %v", time.Since(start))
+	return nil
+}
+
+// watch reloads on SIGHUP and publishes the new schema as /_schema.json.
+// The returned function stops watching.
+func (r *schemaReloader) watch(gfs *nfsmount.GraphFS) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range hup {
+			log.Print("SIGHUP received")
+			_ = r.reloadInto(gfs)
+		}
+	}()
+	log.Printf("Schema hot-reload enabled: kill -HUP %d to re-project after editing %s", os.Getpid(), r.schemaPath)
+	return func() {
+		signal.Stop(hup)
+		close(hup)
+		<-done
+	}
+}
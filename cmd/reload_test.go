@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	machetmpl "github.com/agentic-research/mache/internal/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`[{"name":"alice","role":"admin"},{"name":"bob","role":"user"}]`), 0o644))
+
+	schemaFile := filepath.Join(dir, "schema.json")
+	writeSchema := func(dirName, field string) {
+		t.Helper()
+		s := `{"version":"v1","nodes":[{"name":"` + dirName + `","selector":"$","children":[` +
+			`{"name":"{{.` + field + `}}","selector":"$[*]","files":[{"name":"name","content_template":"{{.name}}"}]}]}]}`
+		require.NoError(t, os.WriteFile(schemaFile, []byte(s), 0o644))
+	}
+	writeSchema("users", "name")
+
+	resolver := graph.NewSQLiteResolver(machetmpl.Render)
+	defer resolver.Close()
+
+	r := &schemaReloader{schemaPath: schemaFile, dataPath: dataFile, resolver: resolver}
+	schema, err := readSchemaFile(schemaFile)
+	require.NoError(t, err)
+	store, eng, err := ingestMemoryStore(context.Background(), schema, dataFile, resolver)
+	require.NoError(t, err)
+	r.hotSwap = graph.NewHotSwapGraph(store)
+	r.engine.Store(eng)
+	defer func() { _ = r.hotSwap.Close() }()
+
+	_, err = r.hotSwap.GetNode("users/alice/name")
+	require.NoError(t, err)
+
+	// Re-project by role under a new root.
+	writeSchema("roles", "role")
+	reloaded, err := r.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, "roles", reloaded.Nodes[0].Name)
+
+	node, err := r.hotSwap.GetNode("roles/admin/name")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", string(node.Data))
+	_, err = r.hotSwap.GetNode("users/alice/name")
+	assert.Error(t, err, "old projection should be gone after reload")
+	reloadedEng := r.engine.Load()
+	assert.NotSame(t, eng, reloadedEng, "ingest reports should follow the reloaded engine")
+
+	// A broken schema keeps the current graph mounted.
+	require.NoError(t, os.WriteFile(schemaFile, []byte(`{not json`), 0o644))
+	_, err = r.Reload()
+	assert.Error(t, err)
+	_, err = r.hotSwap.GetNode("roles/admin/name")
+	assert.NoError(t, err)
+	assert.Same(t, reloadedEng, r.engine.Load())
+}
+
+func TestIngestMemoryStore_Stdin(t *testing.T) {
+	in := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(in, []byte("{\"item\":{\"name\":\"alice\"}}\n{\"item\":{\"name\":\"bob\"}}\n"), 0o644))
+	f, err := os.Open(in)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	orig := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() { os.Stdin = orig })
+
+	schema := &api.Topology{Version: "v1", Nodes: []api.Node{{
+		Name:     "users",
+		Selector: "$",
+		Children: []api.Node{{
+			Name:     "{{.item.name}}",
+			Selector: "$[*]",
+			Files:    []api.Leaf{{Name: "name", ContentTemplate: "{{.item.name}}"}},
+		}},
+	}}}
+	resolver := graph.NewSQLiteResolver(machetmpl.Render)
+	defer resolver.Close()
+
+	store, _, err := ingestMemoryStore(context.Background(), schema, stdinDataPath, resolver)
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	node, err := store.GetNode("users/bob/name")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", string(node.Data))
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	examplesData  string
+	examplesPaths []string
+)
+
+var schemaFromExamplesCmd = &cobra.Command{
+	Use:   "schema-from-examples",
+	Short: "Build a schema from example paths over a sample JSON file",
+	Long: `Describe the directory layout you want by example and print a schema
+that produces it from data shaped like --data. Each --path is a
+slash-separated path ending in file=field:
+
+  vulns/{cveID}/description=shortDescription
+
+Static segments become plain directories. A {field} segment becomes one
+directory per record, named by that field: mache finds the array whose
+elements hold the field and selects it, or groups within the enclosing
+record when the record holds the field itself. The file renders the field
+of the innermost record ("." renders the whole record as JSON). Fields may
+be dotted (meta.id) to pick a nested one. Paths sharing a prefix share its
+directories.`,
+	Example: `  mache schema-from-examples --data kev.json \
+    --path 'vulns/{cveID}/description=shortDescription' \
+    --path 'vulns/{cveID}/vendor=vendorProject' > schema.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if examplesData == "" || len(examplesPaths) == 0 {
+			return fmt.Errorf("--data and at least one --path are required")
+		}
+		content, err := os.ReadFile(examplesData)
+		if err != nil {
+			return err
+		}
+		var doc any
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return fmt.Errorf("parse %s: %w", examplesData, err)
+		}
+		topo, err := ingest.SchemaFromExamples(doc, examplesPaths)
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(topo, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+		return err
+	},
+}
+
+func init() {
+	schemaFromExamplesCmd.Flags().StringVar(&examplesData, "data", "", "Sample JSON file the schema is for (required)")
+	schemaFromExamplesCmd.Flags().StringArrayVar(&examplesPaths, "path", nil, "Example path, e.g. 'vulns/{cveID}/description=shortDescription' (repeatable, required)")
+	rootCmd.AddCommand(schemaFromExamplesCmd)
+}
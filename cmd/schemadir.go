@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/lang"
+)
+
+// readSchemaDir loads a directory of per-language schemas, one JSON file
+// per language named after it (go.json, python.json), into one schema. Each
+// file's top-level nodes are scoped to its language, so the engine projects
+// a source file through the nodes of its detected language only, as it does
+// for a namespaced multi-language schema. A node may name a section of the
+// file's language instead ("vue/script" in vue.json).
+func readSchemaDir(dir string) (*api.Topology, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("schema directory %s holds no .json schemas", dir)
+	}
+	sort.Strings(paths)
+	merged := &api.Topology{Version: api.SchemaVersion}
+	for _, p := range paths {
+		name := strings.TrimSuffix(filepath.Base(p), ".json")
+		l := lang.ForName(name)
+		if l == nil {
+			return nil, fmt.Errorf("schema %s: %q is not a language name", p, name)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("read schema: %w", err)
+		}
+		schema := &api.Topology{}
+		if err := json.Unmarshal(data, schema); err != nil {
+			return nil, fmt.Errorf("parse schema %s: %w", p, err)
+		}
+		schema.ResolveIncludes()
+		if err := mergeLanguageSchema(merged, schema, l.Name); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", p, err)
+		}
+	}
+	return merged, nil
+}
+
+// mergeLanguageSchema adds schema's nodes, scoped to langName, and its
+// diagrams to merged. Settings that apply to the whole schema (delimiters,
+// record table) must agree across the directory.
+func mergeLanguageSchema(merged, schema *api.Topology, langName string) error {
+	for _, setting := range []struct {
+		name     string
+		dst, src *string
+	}{
+		{"left_delim", &merged.LeftDelim, &schema.LeftDelim},
+		{"right_delim", &merged.RightDelim, &schema.RightDelim},
+		{"table", &merged.Table, &schema.Table},
+		{"record_column", &merged.RecordColumn, &schema.RecordColumn},
+	} {
+		switch {
+		case *setting.src == "" || *setting.src == *setting.dst:
+		case *setting.dst == "":
+			*setting.dst = *setting.src
+		default:
+			return fmt.Errorf("%s %q conflicts with %q set by another schema", setting.name, *setting.src, *setting.dst)
+		}
+	}
+	for name, d := range schema.Diagrams {
+		if _, dup := merged.Diagrams[name]; dup {
+			return fmt.Errorf("diagram %q is also defined by another schema", name)
+		}
+		if merged.Diagrams == nil {
+			merged.Diagrams = make(map[string]api.DiagramDef)
+		}
+		merged.Diagrams[name] = d
+	}
+	for _, n := range schema.Nodes {
+		switch {
+		case n.Language == "":
+			n.Language = langName
+		case n.Language != langName && !strings.HasPrefix(n.Language, langName+"/"):
+			return fmt.Errorf("node %q is for language %q, not %s", n.Name, n.Language, langName)
+		}
+		merged.Nodes = append(merged.Nodes, n)
+	}
+	return nil
+}
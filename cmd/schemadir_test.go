@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/internal/graph"
+	machetmpl "github.com/agentic-research/mache/internal/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSchemaDir(t *testing.T) {
+	schemaDir := t.TempDir()
+	for _, language := range []string{"go", "python"} {
+		preset, err := loadPresetSchema(language)
+		require.NoError(t, err)
+		data, err := json.Marshal(preset)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(schemaDir, language+".json"), data, 0o644))
+	}
+	schema, err := readSchemaFile(schemaDir)
+	require.NoError(t, err)
+	for _, n := range schema.Nodes {
+		assert.Contains(t, []string{"go", "python"}, n.Language, "node %s", n.Name)
+	}
+
+	dataDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "main.go"), []byte("package main\n\nfunc Hello() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "app.py"), []byte("def greet():\n    pass\n"), 0o644))
+
+	resolver := graph.NewSQLiteResolver(machetmpl.Render)
+	defer resolver.Close()
+	store, _, err := ingestMemoryStore(context.Background(), schema, dataDir, resolver)
+	require.NoError(t, err)
+
+	_, err = store.GetNode("main/functions/Hello/source")
+	require.NoError(t, err, "Go files go through go.json")
+	_, err = store.GetNode("functions/greet/source")
+	require.NoError(t, err, "Python files go through python.json")
+	// Unscoped, go.json's queries would be invalid for Python and send
+	// the file to _project_files/.
+	_, err = store.GetNode("_project_files/app.py")
+	assert.Error(t, err)
+}
+
+func TestReadSchemaDir_Errors(t *testing.T) {
+	write := func(t *testing.T, files map[string]string) string {
+		t.Helper()
+		dir := t.TempDir()
+		for name, content := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+		}
+		return dir
+	}
+
+	_, err := readSchemaDir(write(t, nil))
+	assert.ErrorContains(t, err, "no .json schemas")
+
+	_, err = readSchemaDir(write(t, map[string]string{"notes.json": `{"nodes":[]}`}))
+	assert.ErrorContains(t, err, "not a language name")
+
+	_, err = readSchemaDir(write(t, map[string]string{"go.json": `{"nodes":[{"name":"x","language":"python"}]}`}))
+	assert.ErrorContains(t, err, `for language "python"`)
+
+	_, err = readSchemaDir(write(t, map[string]string{
+		"go.json":     `{"left_delim":"[[","nodes":[]}`,
+		"python.json": `{"left_delim":"<<","nodes":[]}`,
+	}))
+	assert.ErrorContains(t, err, "left_delim")
+}
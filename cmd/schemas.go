@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/agentic-research/mache/api"
 	"github.com/agentic-research/mache/internal/lang"
@@ -42,6 +43,27 @@ func PresetNames() []string {
 	return names
 }
 
+// splitLangFlag separates --lang values: one without "=" names a preset
+// schema to mount with (--lang go), the rest are glob=lang parse
+// overrides. Naming two different presets is an error.
+func splitLangFlag(specs []string) (preset string, overrides []string, err error) {
+	for _, spec := range specs {
+		if strings.Contains(spec, "=") {
+			overrides = append(overrides, spec)
+			continue
+		}
+		name := strings.TrimSpace(spec)
+		if _, ok := presetSchemas[name]; !ok {
+			return "", nil, fmt.Errorf("unknown preset schema %q (available: %v)", name, PresetNames())
+		}
+		if preset != "" && preset != name {
+			return "", nil, fmt.Errorf("conflicting preset schemas %q and %q", preset, name)
+		}
+		preset = name
+	}
+	return preset, overrides, nil
+}
+
 // loadPresetSchema loads a bundled schema by preset name.
 func loadPresetSchema(name string) (*api.Topology, error) {
 	path, ok := presetSchemas[name]
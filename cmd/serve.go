@@ -18,7 +18,6 @@ import (
 	"github.com/agentic-research/mache/internal/graph"
 	"github.com/agentic-research/mache/internal/ingest"
 	"github.com/agentic-research/mache/internal/leyline"
-	machetmpl "github.com/agentic-research/mache/internal/template"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/spf13/cobra"
 )
@@ -335,7 +334,7 @@ func buildServeGraph(dataSource string, schema *api.Topology) (graph.Graph, func
 		if err := materializeVirtuals(dataSource, schema, false); err != nil {
 			return nil, noop, fmt.Errorf("materialize virtuals: %w", err)
 		}
-		sg, err := graph.OpenSQLiteGraph(dataSource, schema, machetmpl.Render)
+		sg, err := graph.OpenSQLiteGraph(dataSource, schema, schemaRender(schema))
 		if err != nil {
 			return nil, noop, fmt.Errorf("open sqlite graph: %w", err)
 		}
@@ -349,7 +348,7 @@ func buildServeGraph(dataSource string, schema *api.Topology) (graph.Graph, func
 
 	// MemoryStore path for JSON/source files
 	store := graph.NewMemoryStore()
-	resolver := graph.NewSQLiteResolver(machetmpl.Render)
+	resolver := graph.NewSQLiteResolver(schemaRender(schema))
 	store.SetResolver(resolver.Resolve)
 	store.SetCallExtractor(newCallExtractor())
 
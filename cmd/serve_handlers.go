@@ -160,6 +160,7 @@ type nodeEntry struct {
 	Path string `json:"path"`
 	Type string `json:"type"`
 	Size int64  `json:"size,omitempty"`
+	ID   string `json:"id,omitempty"` // graph.StableID in decimal; a string, since JSON numbers lose 64-bit precision
 }
 
 func makeListDirHandler(g graph.Graph) server.ToolHandlerFunc {
@@ -191,6 +192,7 @@ func makeListDirHandler(g graph.Graph) server.ToolHandlerFunc {
 				Path: childID,
 				Type: typ,
 				Size: node.ContentSize(),
+				ID:   string(node.Properties[graph.StableIDProperty]),
 			})
 		}
 
@@ -567,6 +567,15 @@ func (lg *lazyGraph) ShiftOrigins(filePath string, afterByte uint32, delta int32
 	}
 }
 
+func (lg *lazyGraph) ShiftLines(filePath string, afterLine, delta int) {
+	g, _ := lg.get()
+	if g != nil {
+		if wb, ok := g.(writeBacker); ok {
+			wb.ShiftLines(filePath, afterLine, delta)
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Interface types for optional graph backend capabilities
 // ---------------------------------------------------------------------------
@@ -593,4 +602,5 @@ type defsMapProvider interface {
 type writeBacker interface {
 	UpdateNodeContent(id string, data []byte, origin *graph.SourceOrigin, modTime time.Time) error
 	ShiftOrigins(filePath string, afterByte uint32, delta int32)
+	ShiftLines(filePath string, afterLine, delta int)
 }
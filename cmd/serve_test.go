@@ -178,6 +178,25 @@ func TestListDir_IncludesFiles(t *testing.T) {
 	assert.Equal(t, int64(14), entries[0].Size) // len("func main() {}")
 }
 
+func TestListDir_StableID(t *testing.T) {
+	store := buildTestGraph(t)
+	n, err := store.GetNode("pkg/util")
+	require.NoError(t, err)
+	n.Properties = map[string][]byte{graph.StableIDProperty: []byte("18446744073709551557")}
+	handler := makeListDirHandler(store)
+
+	result, err := handler(context.Background(), makeRequest(map[string]any{"path": "pkg"}))
+	require.NoError(t, err)
+	var entries []nodeEntry
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &entries))
+	ids := map[string]string{}
+	for _, e := range entries {
+		ids[e.Name] = e.ID
+	}
+	assert.Equal(t, "18446744073709551557", ids["util"], "exact beyond float64 precision")
+	assert.Empty(t, ids["main"], "no recorded ID")
+}
+
 func TestListDir_Empty(t *testing.T) {
 	store := buildTestGraph(t)
 	handler := makeListDirHandler(store)
@@ -34,6 +34,12 @@ func makeWriteFileHandler(g graph.Graph) server.ToolHandlerFunc {
 		if node.Origin == nil {
 			return mcp.NewToolResultError(fmt.Sprintf("%s has no source origin — only source-code nodes support write-back", path)), nil
 		}
+		if node.Generated() {
+			return mcp.NewToolResultError(fmt.Sprintf("%s is generated code — edit its generator input instead", path)), nil
+		}
+		if node.Origin.JSONPath != "" {
+			return mcp.NewToolResultError(fmt.Sprintf("%s is a JSON data field — edit it through a writable mount", path)), nil
+		}
 
 		origin := *node.Origin
 		newContent := []byte(content)
@@ -58,29 +64,42 @@ func makeWriteFileHandler(g graph.Graph) server.ToolHandlerFunc {
 		// 2. Format (gofumpt for Go, hclwrite for HCL)
 		formatted := writeback.FormatBuffer(newContent, origin.FilePath)
 
-		// 3. Splice into source file
+		// 3. Splice into source file. Hold the file's write-back lock and
+		// re-read the origin under it: a concurrent write to a sibling may
+		// have shifted the node since it was looked up above.
+		unlock := writeback.LockFile(origin.FilePath)
+		defer unlock()
+		if node, err = g.GetNode(path); err != nil || node.Origin == nil {
+			return mcp.NewToolResultError(fmt.Sprintf("not found: %s", path)), nil
+		}
+		origin = *node.Origin
 		oldLen := origin.EndByte - origin.StartByte
-		if err := writeback.Splice(origin, formatted); err != nil {
+		spliced, err := writeback.Splice(origin, formatted)
+		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("splice failed: %v", err)), nil
 		}
+		written := spliced.Content
 
-		// 4. Surgical node update
+		// 4. Surgical node update, sized by what Splice wrote
 		wb := g.(writeBacker)
 		newOrigin := &graph.SourceOrigin{
 			FilePath:  origin.FilePath,
 			StartByte: origin.StartByte,
-			EndByte:   origin.StartByte + uint32(len(formatted)),
+			EndByte:   origin.StartByte + uint32(len(written)),
 		}
-		delta := int32(len(formatted)) - int32(oldLen)
+		delta := int32(len(written)) - int32(oldLen)
 		if delta != 0 {
 			wb.ShiftOrigins(origin.FilePath, origin.EndByte, delta)
 		}
+		if spliced.LineDelta != 0 {
+			wb.ShiftLines(origin.FilePath, spliced.EndLine, spliced.LineDelta)
+		}
 
 		modTime := time.Now()
 		if fi, err := os.Stat(origin.FilePath); err == nil {
 			modTime = fi.ModTime()
 		}
-		_ = wb.UpdateNodeContent(path, formatted, newOrigin, modTime)
+		_ = wb.UpdateNodeContent(path, written, newOrigin, modTime)
 		g.Invalidate(path)
 
 		type writeResult struct {
@@ -1,14 +1,39 @@
 package cmd
 
 import (
+	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
 	"github.com/agentic-research/mache/internal/ingest"
+	"github.com/agentic-research/mache/internal/materialize"
+	machetmpl "github.com/agentic-research/mache/internal/template"
+	"github.com/spf13/cobra"
 )
 
+// schemaRender returns the template renderer for schema, honoring its
+// left_delim/right_delim.
+func schemaRender(schema *api.Topology) graph.TemplateRenderer {
+	left, right := schema.Delims()
+	return machetmpl.Delims{Left: left, Right: right}.Render
+}
+
+// cmdContext returns the command's context, which Execute cancels on
+// SIGINT/SIGTERM. Commands run directly through RunE (as in tests) have
+// none, so it falls back to context.Background.
+func cmdContext(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
 // shouldSkipDir delegates to ingest.ShouldSkipDir.
 func shouldSkipDir(base string) bool {
 	return ingest.ShouldSkipDir(base)
@@ -34,6 +59,85 @@ func copyFile(src, dst string) error {
 	return out.Close()
 }
 
+// isGzipPath reports whether path names a gzip-compressed file.
+func isGzipPath(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// gzipFile compresses src into dst, creating dst if it doesn't exist.
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// gunzipTemp decompresses src into a new temp file named after pattern (as
+// for os.CreateTemp) and returns its path. The caller removes it.
+func gunzipTemp(src, pattern string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = in.Close() }()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", src, err)
+	}
+
+	out, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, zr); err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+		return "", fmt.Errorf("%s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// materializeOut writes the index at indexPath to outPath with mat. An
+// outPath ending in .gz is materialized to a temp file first and gzipped.
+func materializeOut(mat materialize.Materializer, indexPath, outPath string) error {
+	if !isGzipPath(outPath) {
+		return mat.Materialize(indexPath, outPath)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(outPath), ".mache-out-*")
+	if err != nil {
+		return err
+	}
+	_ = tmp.Close()
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := mat.Materialize(indexPath, tmp.Name()); err != nil {
+		return err
+	}
+	if err := gzipFile(tmp.Name(), outPath); err != nil {
+		return fmt.Errorf("gzip %s: %w", outPath, err)
+	}
+	return nil
+}
+
 // copyDir recursively copies srcDir to dstDir, skipping hidden dirs and
 // common build artifact directories. Returns the number of files copied.
 func copyDir(srcDir, dstDir string) (int, error) {
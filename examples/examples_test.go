@@ -31,6 +31,131 @@ func TestSchemasParse(t *testing.T) {
 	}
 }
 
+func TestVueSchemaIngest(t *testing.T) {
+	schemaBytes, err := os.ReadFile("vue-schema.json")
+	require.NoError(t, err)
+	var schema api.Topology
+	require.NoError(t, json.Unmarshal(schemaBytes, &schema))
+	schema.ResolveIncludes()
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, ingest.NewEngine(&schema, store).Ingest("testdata/HelloCard.vue"))
+
+	for _, path := range []string{
+		"HelloCard/script/imports/vue",
+		"HelloCard/script/functions/wave",
+		"HelloCard/script/variables/greeting",
+		"HelloCard/template/div.card/h1#title",
+		"HelloCard/template/div.card/button.primary",
+		"HelloCard/style/rules/.card",
+		"HelloCard/style/rules/.primary",
+		"HelloCard/style/source",
+	} {
+		_, err := store.GetNode(path)
+		assert.NoError(t, err, "node %s not found", path)
+	}
+
+	fn, err := store.GetNode("HelloCard/script/functions/wave/source")
+	require.NoError(t, err)
+	assert.Equal(t, "function wave(): void {\n  greeting.value = \"Hello, \" + greeting.value\n}", string(fn.Data))
+	require.NotNil(t, fn.Origin)
+	src, err := os.ReadFile("testdata/HelloCard.vue")
+	require.NoError(t, err)
+	assert.Equal(t, string(fn.Data), string(src[fn.Origin.StartByte:fn.Origin.EndByte]), "origin is an offset into the component file")
+
+	_, err = store.GetNode("_project_files/HelloCard.vue")
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+}
+
+func TestOCamlSchemaIngest(t *testing.T) {
+	schemaBytes, err := os.ReadFile("ocaml-schema.json")
+	require.NoError(t, err)
+	var schema api.Topology
+	require.NoError(t, json.Unmarshal(schemaBytes, &schema))
+
+	// The implementation and interface files of one module, on their own.
+	dir := t.TempDir()
+	for _, name := range []string{"shapes.ml", "shapes.mli"} {
+		src, err := os.ReadFile(filepath.Join("testdata", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), src, 0o644))
+	}
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, ingest.NewEngine(&schema, store).Ingest(dir))
+
+	for _, path := range []string{
+		"Shapes/implementation",
+		"Shapes/signature",
+		"Shapes/functions/area/source",
+		"Shapes/functions/describe/source",
+		"Shapes/values/unit_square/source",
+		"Shapes/types/shape/source",
+		"Shapes/modules/Units/source",
+	} {
+		_, err := store.GetNode(path)
+		assert.NoError(t, err, "node %s not found", path)
+	}
+
+	sig, err := store.GetNode("Shapes/signature")
+	require.NoError(t, err)
+	assert.Contains(t, string(sig.Data), "val describe : shape -> string")
+
+	fn, err := store.GetNode("Shapes/functions/describe/source")
+	require.NoError(t, err)
+	assert.Equal(t, `let describe s = sprintf "%.2f" (area s)`, string(fn.Data))
+	callers, err := store.GetCallers("area")
+	require.NoError(t, err)
+	assert.NotEmpty(t, callers, "describe calls area")
+
+	_, err = store.GetNode("Shapes/values/describe")
+	assert.ErrorIs(t, err, graph.ErrNotFound, "bindings with parameters are functions")
+
+	// Each file's constructs come only from that file: the interface's
+	// type declaration doesn't collide with the implementation's.
+	_, err = store.GetNode("Shapes/types/shape.from_shapes_mli")
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+}
+
+func TestScalaSchemaIngest(t *testing.T) {
+	schemaBytes, err := os.ReadFile("scala-schema.json")
+	require.NoError(t, err)
+	var schema api.Topology
+	require.NoError(t, json.Unmarshal(schemaBytes, &schema))
+	schema.ResolveIncludes()
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, ingest.NewEngine(&schema, store).Ingest(filepath.Join("testdata", "wordcount.scala")))
+
+	pkg := "com/example/etl/"
+	for _, path := range []string{
+		"imports/org.apache.spark.sql.{DataFrame, SparkSession}/source",
+		"vals/defaultInput/source",
+		"functions/tokenize/source",
+		"traits/Job/functions/run/source",
+		"objects/WordCount/source",
+		"objects/WordCount/vals/name/source",
+		"objects/WordCount/functions/run/source",
+		"objects/WordCount/functions/count/source",
+		"objects/WordCount/objects/Defaults/vals/partitions/source",
+		"classes/WordFrequency/functions/render/source",
+	} {
+		_, err := store.GetNode(pkg + path)
+		assert.NoError(t, err, "node %s not found", pkg+path)
+	}
+
+	// Members of a nested object belong to it alone, and locals of a
+	// method body are part of its source.
+	_, err = store.GetNode(pkg + "objects/WordCount/vals/partitions")
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+	_, err = store.GetNode(pkg + "objects/WordCount/vals/lines")
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+
+	callers, err := store.GetCallers("count")
+	require.NoError(t, err)
+	assert.NotEmpty(t, callers, "run calls count")
+}
+
 func TestMCPSchemaIngest(t *testing.T) {
 	schemaBytes, err := os.ReadFile("mcp-schema.json")
 	require.NoError(t, err)
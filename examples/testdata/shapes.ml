@@ -0,0 +1,17 @@
+open Printf
+
+type shape = Circle of float | Square of float
+
+(** Area of a shape. *)
+let area s =
+  match s with
+  | Circle r -> 3.14159 *. r *. r
+  | Square s -> s *. s
+
+let describe s = sprintf "%.2f" (area s)
+
+let unit_square = Square 1.0
+
+module Units = struct
+  let cm_per_inch = 2.54
+end
@@ -0,0 +1,8 @@
+type shape = Circle of float | Square of float
+
+(** Area of a shape. *)
+val area : shape -> float
+
+val describe : shape -> string
+
+val unit_square : shape
@@ -0,0 +1,35 @@
+package com.example.etl
+
+import org.apache.spark.sql.{DataFrame, SparkSession}
+
+val defaultInput = "data/input.txt"
+
+def tokenize(line: String): Seq[String] =
+  line.toLowerCase.split("\\W+").filter(_.nonEmpty).toSeq
+
+trait Job {
+  def name: String
+  def run(spark: SparkSession): Unit
+}
+
+object WordCount extends Job {
+  val name = "wordcount"
+
+  def run(spark: SparkSession): Unit = {
+    val lines = spark.read.textFile(defaultInput)
+    report(count(lines.toDF()))
+  }
+
+  private def count(df: DataFrame): DataFrame =
+    df.groupBy("value").count()
+
+  private def report(df: DataFrame): Unit = df.show()
+
+  object Defaults {
+    val partitions = 8
+  }
+}
+
+case class WordFrequency(word: String, count: Long) {
+  def render: String = s"$word: $count"
+}
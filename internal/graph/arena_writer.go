@@ -42,6 +42,10 @@ type ArenaFlusher struct {
 	tick     *time.Ticker
 	stopCh   chan struct{}
 	stopped  bool
+
+	// beforeFlush runs before each flush reads the master file; the
+	// WritableGraph uses it to fold its WAL into the .db. See SetBeforeFlush.
+	beforeFlush func() error
 }
 
 // NewArenaFlusher creates a flusher that targets the given arena file
@@ -59,6 +63,16 @@ func NewArenaFlusher(arenaPath, masterDBPath string, ctrl *control.Controller) *
 	}
 }
 
+// SetBeforeFlush registers fn to run before every flush reads the master
+// .db file. A master in WAL mode must be checkpointed here, since the flush
+// copies only the main file. If fn fails the flush is skipped and the
+// flusher stays dirty, so the next tick retries. nil removes the hook.
+func (f *ArenaFlusher) SetBeforeFlush(fn func() error) {
+	f.mu.Lock()
+	f.beforeFlush = fn
+	f.mu.Unlock()
+}
+
 // Start begins the coalescing goroutine that flushes at most once per
 // interval when dirty. Safe to call multiple times (idempotent).
 func (f *ArenaFlusher) Start(interval time.Duration) {
@@ -141,14 +155,35 @@ func (f *ArenaFlusher) Close() error {
 	return nil
 }
 
+// markRollbackJournal rewrites the file-format version bytes (offsets 18
+// and 19) of a checkpointed WAL-mode database image to 1, the legacy
+// rollback-journal format. The arena copy has no -wal file beside it, and
+// read-only readers would otherwise try to open one.
+func markRollbackJournal(dbBytes []byte) {
+	if len(dbBytes) >= 20 && dbBytes[18] == 2 && dbBytes[19] == 2 {
+		dbBytes[18], dbBytes[19] = 1, 1
+	}
+}
+
 // flushInternal reads the master .db file, diffs it page-by-page against
 // the inactive arena buffer, writes only changed pages, flips the active
 // buffer index, increments the sequence, and updates the control block.
 func (f *ArenaFlusher) flushInternal() error {
+	f.mu.Lock()
+	beforeFlush := f.beforeFlush
+	f.mu.Unlock()
+	if beforeFlush != nil {
+		if err := beforeFlush(); err != nil {
+			f.RequestFlush()
+			return fmt.Errorf("before flush: %w", err)
+		}
+	}
+
 	dbBytes, err := os.ReadFile(f.masterDBPath)
 	if err != nil {
 		return fmt.Errorf("read master db: %w", err)
 	}
+	markRollbackJournal(dbBytes)
 
 	af, err := os.OpenFile(f.arenaPath, os.O_RDWR, 0)
 	if err != nil {
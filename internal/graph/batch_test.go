@@ -198,7 +198,7 @@ func TestListChildStats_SnapshotConsistency(t *testing.T) {
 		store.mu.Lock()
 		for i := range N {
 			id := fmt.Sprintf("snap/child_%03d", i)
-			store.nodes[id] = &Node{ID: id, Mode: 0o444, Data: make([]byte, 99)}
+			store.nodes.put(&Node{ID: id, Mode: 0o444, Data: make([]byte, 99)})
 		}
 		store.mu.Unlock()
 	}()
@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// CommandProperty is the Properties key of a computed leaf: a file node
+// whose content is the output of this command run over its rendered
+// content (see api.Leaf.Command). MemoryStore runs the command on first
+// use, with its ComputeFunc, and serves the output in place of the node's
+// Data.
+const CommandProperty = "command"
+
+// ComputeFunc runs a computed leaf's command with input on stdin and
+// returns its output.
+type ComputeFunc func(command string, input []byte) ([]byte, error)
+
+// commandsDisabled is the content of computed leaves in a store without a
+// ComputeFunc.
+const commandsDisabled = "not computed: running schema commands is disabled (mount with --allow-exec)\n"
+
+// pendingComputeSize is the size listings report for a computed leaf whose
+// command hasn't run yet: listing never runs commands, and a page is
+// enough that a client trusting the listed size still reads. Opening or
+// stating the leaf runs the command and reports the output's real size.
+const pendingComputeSize = 4096
+
+// computedLeaf caches one computed leaf's output for the input it ran on.
+type computedLeaf struct {
+	input []byte
+	once  sync.Once
+	data  []byte
+	done  atomic.Bool
+}
+
+// SetComputer runs the commands of computed leaves with fn. Without a
+// ComputeFunc, a computed leaf reads as a note that its command didn't run.
+func (s *MemoryStore) SetComputer(fn ComputeFunc) {
+	s.computedMu.Lock()
+	defer s.computedMu.Unlock()
+	s.computer = fn
+	s.computed = make(map[string]*computedLeaf)
+}
+
+// computedView returns n with a computed leaf's command output as its
+// content. Other nodes are returned as they are.
+func (s *MemoryStore) computedView(n *Node) *Node {
+	command, ok := n.Properties[CommandProperty]
+	if !ok || n.Mode.IsDir() {
+		return n
+	}
+	view := *n
+	view.Data = s.compute(n.ID, string(command), n.Data)
+	return &view
+}
+
+// compute returns the output of command over input for the leaf id,
+// running it once per distinct input. Concurrent first reads share one
+// run. A failed run's error is the content, and is kept like any output
+// until the leaf's input changes, so a failing command isn't retried on
+// every read.
+func (s *MemoryStore) compute(id, command string, input []byte) []byte {
+	s.computedMu.Lock()
+	if s.computer == nil {
+		s.computedMu.Unlock()
+		return []byte(commandsDisabled)
+	}
+	run := s.computer
+	c, ok := s.computed[id]
+	if !ok || !bytes.Equal(c.input, input) {
+		c = &computedLeaf{input: input}
+		s.computed[id] = c
+	}
+	s.computedMu.Unlock()
+
+	c.once.Do(func() {
+		out, err := run(command, input)
+		if err != nil {
+			out = []byte(err.Error() + "\n")
+		}
+		c.data = out
+		c.done.Store(true)
+	})
+	return c.data
+}
+
+// computedSize returns the size listings report for the computed leaf n:
+// its output's, once its command has run on n's current content, and
+// pendingComputeSize before. It never runs the command.
+func (s *MemoryStore) computedSize(n *Node) int64 {
+	s.computedMu.Lock()
+	defer s.computedMu.Unlock()
+	if s.computer == nil {
+		return int64(len(commandsDisabled))
+	}
+	if c, ok := s.computed[n.ID]; ok && c.done.Load() && bytes.Equal(c.input, n.Data) {
+		return int64(len(c.data))
+	}
+	return pendingComputeSize
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_ComputedLeaf(t *testing.T) {
+	store := NewMemoryStore()
+	store.AddRoot(&Node{ID: "Foo", Mode: 0o40000, Children: []string{"Foo/_doc"}})
+	doc := &Node{
+		ID:         "Foo/_doc",
+		Data:       []byte("func Foo() {}"),
+		Properties: map[string][]byte{CommandProperty: []byte("upper")},
+	}
+	store.AddNode(doc)
+
+	// Without a ComputeFunc, the leaf says why it has no output.
+	n, err := store.GetNode("Foo/_doc")
+	require.NoError(t, err)
+	assert.Equal(t, commandsDisabled, string(n.Data))
+
+	var runs atomic.Int32
+	store.SetComputer(func(command string, input []byte) ([]byte, error) {
+		runs.Add(1)
+		assert.Equal(t, "upper", command)
+		return []byte(strings.ToUpper(string(input))), nil
+	})
+
+	// Listing doesn't run the command; it reports a placeholder size.
+	stats, err := store.ListChildStats("Foo")
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(pendingComputeSize), stats[0].ContentSize)
+	assert.Zero(t, runs.Load(), "listing runs no command")
+
+	// A read runs it once; later listings report the output's size.
+	buf := make([]byte, 64)
+	nr, err := store.ReadContent("/Foo/_doc", buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "FUNC FOO() {}", string(buf[:nr]))
+	stats, err = store.ListChildStats("Foo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("FUNC FOO() {}")), stats[0].ContentSize)
+	assert.Equal(t, int32(1), runs.Load())
+
+	stored, _ := store.nodes.get("Foo/_doc")
+	assert.Equal(t, "func Foo() {}", string(stored.Data), "the stored node keeps the input")
+
+	// New input (the construct was re-ingested) runs the command again.
+	store.AddNode(&Node{ID: "Foo/_doc", Data: []byte("func Foo(x int) {}"), Properties: doc.Properties})
+	n, err = store.GetNode("Foo/_doc")
+	require.NoError(t, err)
+	assert.Equal(t, "FUNC FOO(X INT) {}", string(n.Data))
+	assert.Equal(t, int32(2), runs.Load())
+}
+
+func TestMemoryStore_ComputedLeafError(t *testing.T) {
+	store := NewMemoryStore()
+	store.AddNode(&Node{ID: "Foo/_doc", Data: []byte("x"), Properties: map[string][]byte{CommandProperty: []byte("false")}})
+	var runs atomic.Int32
+	store.SetComputer(func(string, []byte) ([]byte, error) {
+		runs.Add(1)
+		return nil, errors.New("exit status 1")
+	})
+
+	for range 2 {
+		n, err := store.GetNode("Foo/_doc")
+		require.NoError(t, err)
+		assert.Equal(t, "exit status 1\n", string(n.Data))
+	}
+	assert.Equal(t, int32(1), runs.Load(), "a failure is kept, not retried on every read")
+}
@@ -1,18 +1,22 @@
 package graph
 
 import (
-	"bytes"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/RoaringBitmap/roaring"
@@ -22,6 +26,18 @@ import (
 
 var ErrNotFound = errors.New("node not found")
 
+// ErrRefsDisabled is returned by QueryRefs when the mache_refs module
+// failed to register: the mount serves its projection without the SQL refs
+// index.
+var ErrRefsDisabled = errors.New("refs index disabled: mache_refs module failed to register")
+
+// registerRefs registers the mache_refs vtab module; a variable so tests
+// can make registration fail.
+var registerRefs = refsvtab.Register
+
+// registerNodes registers the mache_nodes vtab module.
+var registerNodes = refsvtab.RegisterNodes
+
 // ErrActNotSupported is returned by Graph implementations that do not support actions.
 var ErrActNotSupported = errors.New("act not supported by this graph")
 
@@ -38,17 +54,22 @@ type ActionResult struct {
 // Instead of storing the full byte content in RAM, we store enough info to re-fetch it on demand.
 type ContentRef struct {
 	DBPath     string // Path to the SQLite database
-	RecordID   string // Row ID in the results table
+	Table      string // Records table; "" means "results"
+	Column     string // JSON column of Table; "" means "record"
+	RecordID   string // Row ID in Table
 	Template   string // Content template to re-render
 	ContentLen int64  // Pre-computed rendered byte length
 }
 
 // SourceOrigin tracks the byte range of a construct in its source file.
-// Used by write-back to splice edits into the original source.
+// Used by write-back to splice edits into the original source. A leaf
+// rendered from one field of a JSON data file has a JSONPath instead of a
+// byte range; write-back sets that field and rewrites the file.
 type SourceOrigin struct {
 	FilePath  string `json:"file"`
 	StartByte uint32 `json:"start_byte"`
 	EndByte   uint32 `json:"end_byte"`
+	JSONPath  string `json:"json_path,omitempty"`
 }
 
 // NodeStat holds the immutable stat fields needed for directory listing.
@@ -60,6 +81,7 @@ type NodeStat struct {
 	ContentSize int64
 	ModTime     time.Time
 	HasOrigin   bool // true if write-back is possible (Origin != nil)
+	Generated   bool // projected from a generated file; never writable
 }
 
 // Node is the universal primitive.
@@ -92,6 +114,26 @@ func (n *Node) ContentSize() int64 {
 	return 0
 }
 
+// Generated reports whether the node was projected from a file carrying a
+// generated-code header. Such nodes are read-only even on writable mounts,
+// since edits would be overwritten by the next generator run.
+func (n *Node) Generated() bool {
+	return string(n.Properties["generated"]) == "true"
+}
+
+// StableID returns the identifier external tools key a node by: the FNV-1a
+// 64-bit hash of its ID and the file it was projected from, relative to the
+// ingest root ("" for nodes not from one file). Byte offsets are left out,
+// so a construct keeps its ID when code above it changes; renaming it or
+// moving it to another file gives it a new one.
+func StableID(id, sourceFile string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(filepath.ToSlash(sourceFile)))
+	return h.Sum64()
+}
+
 // ContentResolverFunc resolves a ContentRef into byte content.
 type ContentResolverFunc func(ref *ContentRef) ([]byte, error)
 
@@ -127,22 +169,78 @@ type Graph interface {
 	Act(id, action, payload string) (*ActionResult, error)
 }
 
+// CallSiteLocator is implemented by graphs that record where each reference
+// occurs, not just which node makes it. Used by callers/ to show call-site
+// line numbers.
+type CallSiteLocator interface {
+	// CallSiteLines returns the sorted, de-duplicated 1-based source lines
+	// at which nodeID references token, or nil if none were recorded.
+	CallSiteLines(token, nodeID string) []int
+}
+
+// FormatLines encodes call-site lines for the lines column of a refs
+// table: "7,12". Lines added for the same reference later are appended
+// after a comma.
+func FormatLines(lines []int) string {
+	strs := make([]string, len(lines))
+	for i, l := range lines {
+		strs[i] = strconv.Itoa(l)
+	}
+	return strings.Join(strs, ",")
+}
+
+// ParseLines decodes a lines column written with FormatLines into sorted,
+// de-duplicated lines, skipping anything malformed.
+func ParseLines(s string) []int {
+	var lines []int
+	for _, f := range strings.Split(s, ",") {
+		if l, err := strconv.Atoi(f); err == nil {
+			lines = append(lines, l)
+		}
+	}
+	slices.Sort(lines)
+	return slices.Compact(lines)
+}
+
+// DefsProvider is implemented by graphs that keep a definition index. Used
+// by the root _all-functions/, _all-types/, and _all-methods/ directories.
+type DefsProvider interface {
+	// DefsMap returns a snapshot of the token→dirIDs definition map.
+	DefsMap() map[string][]string
+}
+
 // -----------------------------------------------------------------------------
 // Phase 1 Implementation: In-Memory Graph with Lazy Content Resolution
 // -----------------------------------------------------------------------------
 
 type MemoryStore struct {
 	mu       sync.RWMutex
-	nodes    map[string]*Node
+	nodes    nodeMap
 	roots    []string            // Top-level nodes (e.g. "vulns")
 	rootsSet map[string]struct{} // O(1) dedup for AddRoot
 	resolver ContentResolverFunc
 	cache    *ContentCache
-	refs     map[string][]string // token -> []nodeID (callers: who calls token)
 	defs     map[string][]string // token -> []construct_dir_id (definitions: where token is defined)
 
+	// token -> bitmap of the internal IDs (nodeIntID) of the nodes
+	// referencing it (callers: who calls token). A bitmap per token
+	// instead of a slice of node IDs keeps the index small on repos with
+	// millions of call sites, and is what FlushRefs writes out as is.
+	refs map[string]*roaring.Bitmap
+
+	// token -> caller internal ID -> call-site lines (see CallSiteLines).
+	refLines map[string]map[uint32][]int
+
+	// token -> test construct dir IDs covering it (see TestsFor).
+	tests map[string][]string
+
+	// source node ID -> TypeRefPrefix tokens it references (see TypesUsed).
+	// Kept apart from refs so types never appear as callers.
+	typeRefs map[string][]string
+
 	// Roaring bitmap index: file path → set of node internal IDs.
 	// Enables O(k) DeleteFileNodes and ShiftOrigins instead of O(N) full scan.
+	// Internal IDs are also assigned to nodes that reference a token.
 	fileToNodes map[string]*roaring.Bitmap // FilePath → bitmap of internal node IDs
 	nodeIntID   map[string]uint32          // Node.ID → internal bitmap uint32 ID
 	intToNodeID []string                   // reverse: uint32 → Node.ID
@@ -160,6 +258,10 @@ type MemoryStore struct {
 	dbID       string // unique ID for vtab registry
 	flushOnce  sync.Once
 	flushErr   error
+	// refsEnabled is cleared by InitRefsDB when the vtab module fails to
+	// register. QueryRefs then fails with ErrRefsDisabled; callers/ reads
+	// the in-memory refs and keeps working.
+	refsEnabled bool
 
 	extractor CallExtractor
 
@@ -167,6 +269,12 @@ type MemoryStore struct {
 	fileMtimes map[string]time.Time        // source file → mtime at index time
 	refresher  func(filePath string) error // called when a source file is stale
 	refreshMu  sync.Map                    // filePath → *sync.Mutex (per-file refresh serialization)
+
+	// Computed leaves (see CommandProperty): the command runner and the
+	// outputs so far, by node ID.
+	computedMu sync.Mutex
+	computer   ComputeFunc
+	computed   map[string]*computedLeaf
 }
 
 // NormalizeID strips a leading slash from node IDs.
@@ -194,15 +302,37 @@ func SliceContent(data, buf []byte, offset int64) int {
 
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		nodes:       make(map[string]*Node),
+		nodes:       make(memNodeMap),
 		roots:       []string{},
 		rootsSet:    make(map[string]struct{}),
-		refs:        make(map[string][]string),
+		refs:        make(map[string]*roaring.Bitmap),
 		defs:        make(map[string][]string),
 		fileToNodes: make(map[string]*roaring.Bitmap),
 		nodeIntID:   make(map[string]uint32),
 		fileMtimes:  make(map[string]time.Time),
+		refsEnabled: true,
+	}
+}
+
+// NewSpillingMemoryStore returns a MemoryStore that keeps at most hotNodes
+// nodes in memory and spills the rest to a temp file, trading lookup speed
+// for bounded RSS on very large trees. Close removes the spill file.
+//
+// GetNode returns a copy: a change to it is kept only once it is added
+// again with AddNode. The cap covers node bodies (data, children,
+// properties); the ref, def, and per-file indexes stay in memory at a few
+// dozen bytes per node.
+func NewSpillingMemoryStore(hotNodes int) (*MemoryStore, error) {
+	if hotNodes <= 0 {
+		return nil, fmt.Errorf("spill: hot node limit must be positive, got %d", hotNodes)
+	}
+	nodes, err := newSpillNodeMap(hotNodes)
+	if err != nil {
+		return nil, err
 	}
+	s := NewMemoryStore()
+	s.nodes = nodes
+	return s, nil
 }
 
 // SetCallExtractor configures the parser for on-demand callee resolution.
@@ -214,7 +344,7 @@ func (s *MemoryStore) SetCallExtractor(fn CallExtractor) {
 // Cache size scales with node count: 25% of nodes, floor 1024, ceiling 16384.
 func (s *MemoryStore) SetResolver(fn ContentResolverFunc) {
 	s.resolver = fn
-	size := len(s.nodes) / 4
+	size := s.nodes.len() / 4
 	if size < 1024 {
 		size = 1024
 	}
@@ -224,6 +354,14 @@ func (s *MemoryStore) SetResolver(fn ContentResolverFunc) {
 	s.cache = NewContentCache(size)
 }
 
+// CacheStats reports the lazy content cache. Zero until SetResolver.
+func (s *MemoryStore) CacheStats() CacheStats {
+	if s.cache == nil {
+		return CacheStats{}
+	}
+	return s.cache.Stats()
+}
+
 // RootIDs returns a copy of the top-level root node IDs.
 func (s *MemoryStore) RootIDs() []string {
 	s.mu.RLock()
@@ -238,7 +376,8 @@ func (s *MemoryStore) RootIDs() []string {
 func (s *MemoryStore) AddRoot(n *Node) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.nodes[n.ID] = n
+	s.nodes.put(n)
+	s.indexNode(n)
 	if _, dup := s.rootsSet[n.ID]; dup {
 		return
 	}
@@ -250,10 +389,21 @@ func (s *MemoryStore) AddRoot(n *Node) {
 func (s *MemoryStore) AddNode(n *Node) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.nodes[n.ID] = n
+	s.warnOriginCollision(n)
+	s.nodes.put(n)
 	s.indexNode(n)
 }
 
+// warnOriginCollision logs when n replaces a node projected from a different
+// source file, which silently drops the earlier construct. Caller holds s.mu.
+func (s *MemoryStore) warnOriginCollision(n *Node) {
+	old, ok := s.nodes.get(n.ID)
+	if !ok || old == n || old.Origin == nil || n.Origin == nil || old.Origin.FilePath == n.Origin.FilePath {
+		return
+	}
+	log.Printf("[WARN] node ID collision: %s from %s replaces the one from %s", n.ID, n.Origin.FilePath, old.Origin.FilePath)
+}
+
 // AddFileChildren atomically adds file nodes and appends their IDs to the
 // parent directory's Children slice. Single lock acquisition for the batch.
 func (s *MemoryStore) AddFileChildren(parent *Node, files []*Node) {
@@ -263,13 +413,17 @@ func (s *MemoryStore) AddFileChildren(parent *Node, files []*Node) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for _, f := range files {
-		s.nodes[f.ID] = f
+		s.warnOriginCollision(f)
+		s.nodes.put(f)
 		s.indexNode(f)
 	}
 	for _, f := range files {
-		parent.Children = append(parent.Children, f.ID)
+		// A re-ingested file keeps leaves that had no origin to delete them by.
+		if !slices.Contains(parent.Children, f.ID) {
+			parent.Children = append(parent.Children, f.ID)
+		}
 	}
-	s.nodes[parent.ID] = parent
+	s.nodes.put(parent)
 }
 
 // ListChildStats returns stat snapshots for all children under a single RLock.
@@ -278,15 +432,15 @@ func (s *MemoryStore) AddFileChildren(parent *Node, files []*Node) {
 // Missing children are silently skipped.
 func (s *MemoryStore) ListChildStats(id string) ([]NodeStat, error) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
 
 	var childIDs []string
 	if id == "" || id == "/" {
 		childIDs = s.roots
 	} else {
 		id = NormalizeID(id)
-		n, ok := s.nodes[id]
+		n, ok := s.nodes.get(id)
 		if !ok {
+			s.mu.RUnlock()
 			return nil, ErrNotFound
 		}
 		childIDs = n.Children
@@ -294,16 +448,23 @@ func (s *MemoryStore) ListChildStats(id string) ([]NodeStat, error) {
 
 	stats := make([]NodeStat, 0, len(childIDs))
 	for _, cid := range childIDs {
-		if n, ok := s.nodes[cid]; ok {
+		if n, ok := s.nodes.get(cid); ok {
+			size := n.ContentSize()
+			// A listing doesn't run commands (see computedSize).
+			if _, ok := n.Properties[CommandProperty]; ok && !n.Mode.IsDir() {
+				size = s.computedSize(n)
+			}
 			stats = append(stats, NodeStat{
 				ID:          n.ID,
 				IsDir:       n.Mode.IsDir(),
-				ContentSize: n.ContentSize(),
+				ContentSize: size,
 				ModTime:     n.ModTime,
 				HasOrigin:   n.Origin != nil,
+				Generated:   n.Generated(),
 			})
 		}
 	}
+	s.mu.RUnlock()
 	return stats, nil
 }
 
@@ -314,7 +475,7 @@ func (s *MemoryStore) UpdateNodeContent(id string, data []byte, origin *SourceOr
 	defer s.mu.Unlock()
 
 	id = NormalizeID(id)
-	n, ok := s.nodes[id]
+	n, ok := s.nodes.get(id)
 	if !ok {
 		return ErrNotFound
 	}
@@ -323,7 +484,9 @@ func (s *MemoryStore) UpdateNodeContent(id string, data []byte, origin *SourceOr
 	n.ModTime = modTime
 	if origin != nil {
 		n.Origin = origin
+		s.indexNode(n)
 	}
+	s.nodes.put(n)
 	return nil
 }
 
@@ -333,11 +496,12 @@ func (s *MemoryStore) UpdateNodeContext(id string, ctx []byte) error {
 	defer s.mu.Unlock()
 
 	id = NormalizeID(id)
-	n, ok := s.nodes[id]
+	n, ok := s.nodes.get(id)
 	if !ok {
 		return ErrNotFound
 	}
 	n.Context = ctx
+	s.nodes.put(n)
 	return nil
 }
 
@@ -347,18 +511,7 @@ func (s *MemoryStore) indexNode(n *Node) {
 	if n.Origin == nil {
 		return
 	}
-	// Assign internal ID if not already assigned
-	intID, ok := s.nodeIntID[n.ID]
-	if !ok {
-		intID = s.nextIntID
-		s.nextIntID++
-		s.nodeIntID[n.ID] = intID
-		// Grow reverse map
-		for uint32(len(s.intToNodeID)) <= intID {
-			s.intToNodeID = append(s.intToNodeID, "")
-		}
-		s.intToNodeID[intID] = n.ID
-	}
+	intID := s.internID(n.ID)
 	// Set bit in file→nodes bitmap
 	bm, exists := s.fileToNodes[n.Origin.FilePath]
 	if !exists {
@@ -377,6 +530,23 @@ func (s *MemoryStore) indexNode(n *Node) {
 	}
 }
 
+// internID returns the internal bitmap ID of node id, assigning one if it
+// has none yet. Must be called with s.mu held.
+func (s *MemoryStore) internID(id string) uint32 {
+	if intID, ok := s.nodeIntID[id]; ok {
+		return intID
+	}
+	intID := s.nextIntID
+	s.nextIntID++
+	s.nodeIntID[id] = intID
+	// Grow reverse map
+	for uint32(len(s.intToNodeID)) <= intID {
+		s.intToNodeID = append(s.intToNodeID, "")
+	}
+	s.intToNodeID[intID] = id
+	return intID
+}
+
 // SetRefresher configures a callback invoked when a source file is stale.
 // The callback should re-ingest the file and update the store.
 func (s *MemoryStore) SetRefresher(fn func(filePath string) error) {
@@ -415,14 +585,54 @@ func (s *MemoryStore) IsFileStale(filePath string) bool {
 	return !info.ModTime().Equal(tracked)
 }
 
-// AddRef records a reference from a file (nodeID) to a token.
-func (s *MemoryStore) AddRef(token, nodeID string) error {
+// AddRef records a reference from a file (nodeID) to a token. lines, if
+// given, are the 1-based source lines of the call sites and are served by
+// CallSiteLines.
+func (s *MemoryStore) AddRef(token, nodeID string, lines ...int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.refs[token] = append(s.refs[token], nodeID)
+	if strings.HasPrefix(token, TypeRefPrefix) {
+		if s.typeRefs == nil {
+			s.typeRefs = make(map[string][]string)
+		}
+		if !slices.Contains(s.typeRefs[nodeID], token) {
+			s.typeRefs[nodeID] = append(s.typeRefs[nodeID], token)
+		}
+		return nil
+	}
+	intID := s.internID(nodeID)
+	bm := s.refs[token]
+	if bm == nil {
+		bm = roaring.New()
+		s.refs[token] = bm
+	}
+	bm.Add(intID)
+	if len(lines) > 0 {
+		if s.refLines == nil {
+			s.refLines = make(map[string]map[uint32][]int)
+		}
+		byNode := s.refLines[token]
+		if byNode == nil {
+			byNode = make(map[uint32][]int)
+			s.refLines[token] = byNode
+		}
+		byNode[intID] = append(byNode[intID], lines...)
+	}
 	return nil
 }
 
+// CallSiteLines implements CallSiteLocator.
+func (s *MemoryStore) CallSiteLines(token, nodeID string) []int {
+	s.mu.RLock()
+	var lines []int
+	if intID, ok := s.nodeIntID[nodeID]; ok {
+		lines = slices.Clone(s.refLines[token][intID])
+	}
+	s.mu.RUnlock()
+	slices.Sort(lines)
+	return slices.Compact(lines)
+}
+
 // AddDef records that a construct (dirID) defines the given token.
 // Used by callees/ resolution: token → where it is defined.
 // Uses copy-on-write: creates a new slice instead of appending to the existing one,
@@ -435,21 +645,83 @@ func (s *MemoryStore) AddDef(token, dirID string) error {
 	copy(newSlice, existing)
 	newSlice[len(existing)] = dirID
 	s.defs[token] = newSlice
+
+	if n, ok := s.nodes.get(dirID); ok {
+		for _, subject := range testSubjects(string(n.Properties["lang"]), token) {
+			if s.tests == nil {
+				s.tests = make(map[string][]string)
+			}
+			s.tests[subject] = append(slices.Clone(s.tests[subject]), dirID)
+		}
+	}
 	return nil
 }
 
+// TestsFor implements TestLocator. Tests are found by the language's
+// TestNamer when their defs are added.
+func (s *MemoryStore) TestsFor(token string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	for _, id := range s.tests[token] {
+		if _, ok := s.nodes.get(id); ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// TypesUsed implements TypeRefLocator from the type refs added for the
+// construct's source node.
+func (s *MemoryStore) TypesUsed(dirID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dirID = NormalizeID(dirID)
+	node, ok := s.nodes.get(dirID)
+	if !ok {
+		return nil
+	}
+	var tokens []string
+	for _, c := range node.Children {
+		if path.Base(c) == "source" {
+			tokens = s.typeRefs[c]
+			break
+		}
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	return resolveTypeRefs(dirID, string(node.Properties["pkg"]), tokens, func(token string) []string {
+		return s.defs[token]
+	})
+}
+
 // RefsMap returns a snapshot of the token→nodeIDs reference map.
 // Used by community detection to build the co-reference graph.
 func (s *MemoryStore) RefsMap() map[string][]string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	cp := make(map[string][]string, len(s.refs))
-	for k, v := range s.refs {
-		cp[k] = append([]string(nil), v...)
+	for k, bm := range s.refs {
+		cp[k] = s.nodeIDs(bm)
 	}
 	return cp
 }
 
+// nodeIDs returns the node IDs of the internal IDs in bm, in ID order.
+// Must be called with s.mu held.
+func (s *MemoryStore) nodeIDs(bm *roaring.Bitmap) []string {
+	ids := make([]string, 0, bm.GetCardinality())
+	it := bm.Iterator()
+	for it.HasNext() {
+		if intID := it.Next(); int(intID) < len(s.intToNodeID) && s.intToNodeID[intID] != "" {
+			ids = append(ids, s.intToNodeID[intID])
+		}
+	}
+	return ids
+}
+
 // DefsMap returns a snapshot of the token→dirIDs definition map.
 // Used by find_definition to locate where symbols are defined.
 func (s *MemoryStore) DefsMap() map[string][]string {
@@ -467,31 +739,41 @@ func (s *MemoryStore) DefsMap() map[string][]string {
 func (s *MemoryStore) DeleteFileNodes(filePath string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.deleteFileNodes(filePath)
+	s.deleteFileNodes(filePath, nil)
 }
 
 // ReplaceFileNodes atomically replaces all nodes from a file with a new set.
 // This prevents race conditions where files disappear during re-ingestion.
+// Refs and defs of the old nodes are purged, so the caller adds the new
+// set's afterwards.
 func (s *MemoryStore) ReplaceFileNodes(filePath string, newNodes []*Node) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.deleteFileNodes(filePath)
+	keep := make(map[string]struct{}, len(newNodes))
+	for _, n := range newNodes {
+		keep[n.ID] = struct{}{}
+	}
+	s.deleteFileNodes(filePath, keep)
 
 	for _, n := range newNodes {
-		s.nodes[n.ID] = n
+		s.warnOriginCollision(n)
+		s.nodes.put(n)
 		s.indexNode(n)
 	}
 }
 
-// deleteFileNodes performs deletion with lock already held.
-func (s *MemoryStore) deleteFileNodes(filePath string) {
+// deleteFileNodes performs deletion with lock already held. Nodes in keep
+// are about to be re-added under the same ID, so their parents keep the
+// child links.
+func (s *MemoryStore) deleteFileNodes(filePath string, keep map[string]struct{}) {
 	// Canonicalize path to match Ingest behavior
 	if realPath, err := filepath.EvalSymlinks(filePath); err == nil {
 		filePath = realPath
 	}
 
-	// 1. Collect IDs to delete via bitmap index
+	// 1. Collect IDs to delete via bitmap index. Every node with an origin
+	// is indexed as it is added, so a file without a bitmap has no nodes.
 	bm, hasBitmap := s.fileToNodes[filePath]
 	var toDelete []string
 	if hasBitmap {
@@ -505,25 +787,25 @@ func (s *MemoryStore) deleteFileNodes(filePath string) {
 				}
 			}
 		}
-	} else {
-		// Fallback: full scan for nodes not yet indexed (e.g. added before indexing)
-		for id, n := range s.nodes {
-			if n.Origin != nil && n.Origin.FilePath == filePath {
-				toDelete = append(toDelete, id)
-			}
-		}
 	}
 
-	// 2. Build deletion set for O(1) lookups
+	// 2. Build deletion set for O(1) lookups. Constructs own their file
+	// nodes, so the dirs holding them are where the file's defs point.
 	deleteSet := make(map[string]struct{}, len(toDelete))
+	constructDirs := make(map[string]struct{}, len(toDelete))
+	deletedInts := roaring.New()
 	for _, id := range toDelete {
 		deleteSet[id] = struct{}{}
-		delete(s.nodes, id)
+		if dir := path.Dir(id); dir != "." {
+			constructDirs[dir] = struct{}{}
+		}
+		s.nodes.del(id)
 		// Clean up bitmap index entries
 		if intID, ok := s.nodeIntID[id]; ok {
 			if hasBitmap {
 				bm.Remove(intID)
 			}
+			deletedInts.Add(intID)
 			delete(s.nodeIntID, id)
 			if int(intID) < len(s.intToNodeID) {
 				s.intToNodeID[intID] = ""
@@ -536,46 +818,74 @@ func (s *MemoryStore) deleteFileNodes(filePath string) {
 		delete(s.fileToNodes, filePath)
 	}
 
-	// 3. Clean up children pointers in remaining nodes
-	for _, n := range s.nodes {
-		if n.Mode.IsDir() && len(n.Children) > 0 {
-			newChildren := n.Children[:0]
-			changed := false
-			for _, c := range n.Children {
-				if _, del := deleteSet[c]; del {
-					changed = true
-				} else {
-					newChildren = append(newChildren, c)
-				}
-			}
-			if changed {
-				n.Children = newChildren
+	// 3. Unlink the deleted nodes from their parents, the only nodes
+	// listing them as children.
+	for dir := range constructDirs {
+		n, ok := s.nodes.get(dir)
+		if !ok || !n.Mode.IsDir() {
+			continue
+		}
+		newChildren := make([]string, 0, len(n.Children))
+		for _, c := range n.Children {
+			_, del := deleteSet[c]
+			if _, kept := keep[c]; !del || kept {
+				newChildren = append(newChildren, c)
 			}
 		}
+		if len(newChildren) != len(n.Children) {
+			n.Children = newChildren
+			s.nodes.put(n)
+		}
 	}
 
-	// 4. Clean stale refs: remove deleted node IDs from token→[]nodeID map.
+	// 4. Clean stale refs: remove deleted nodes from the token bitmaps.
 	// Without this, renamed/deleted functions persist as phantom callers.
-	for token, nodeIDs := range s.refs {
-		filtered := nodeIDs[:0]
-		for _, nid := range nodeIDs {
-			if _, del := deleteSet[nid]; !del {
-				filtered = append(filtered, nid)
+	// Their internal IDs were released above and are never reused.
+	for token, refs := range s.refs {
+		if !refs.Intersects(deletedInts) {
+			continue
+		}
+		refs.AndNot(deletedInts)
+		if refs.IsEmpty() {
+			delete(s.refs, token)
+		}
+	}
+	for id := range deleteSet {
+		delete(s.typeRefs, id)
+	}
+	for token, byNode := range s.refLines {
+		for intID := range byNode {
+			if deletedInts.Contains(intID) {
+				delete(byNode, intID)
 			}
 		}
+		if len(byNode) == 0 {
+			delete(s.refLines, token)
+		}
+	}
+
+	for token, dirIDs := range s.tests {
+		filtered := slices.DeleteFunc(slices.Clone(dirIDs), func(id string) bool {
+			_, del := deleteSet[id]
+			_, owned := constructDirs[id]
+			return del || owned
+		})
 		if len(filtered) == 0 {
-			delete(s.refs, token)
-		} else if len(filtered) < len(nodeIDs) {
-			s.refs[token] = filtered
+			delete(s.tests, token)
+		} else {
+			s.tests[token] = filtered
 		}
 	}
 
 	// 5. Clean stale defs: remove deleted dir IDs from token→[]dirID map.
-	// Without this, renamed functions persist as phantom callees.
+	// Without this, renamed functions persist as phantom callees, and
+	// re-ingesting a file registers its defs a second time.
 	for token, dirIDs := range s.defs {
 		filtered := dirIDs[:0]
 		for _, did := range dirIDs {
-			if _, del := deleteSet[did]; !del {
+			_, del := deleteSet[did]
+			_, owned := constructDirs[did]
+			if !del && !owned {
 				filtered = append(filtered, did)
 			}
 		}
@@ -587,6 +897,33 @@ func (s *MemoryStore) deleteFileNodes(filePath string) {
 	}
 }
 
+// ShiftLines moves the recorded call-site lines (see CallSiteLines) of
+// nodes from filePath that lie after afterLine by delta, after a write-back
+// added or removed lines above them.
+func (s *MemoryStore) ShiftLines(filePath string, afterLine, delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bm, ok := s.fileToNodes[filePath]
+	if !ok || delta == 0 {
+		return
+	}
+	for _, byNode := range s.refLines {
+		for intID, lines := range byNode {
+			if !bm.Contains(intID) {
+				continue
+			}
+			shifted := slices.Clone(lines)
+			for i, l := range shifted {
+				if l > afterLine {
+					shifted[i] = max(l+delta, 1)
+				}
+			}
+			byNode[intID] = shifted
+		}
+	}
+}
+
 // ShiftOrigins adjusts StartByte/EndByte for all nodes from filePath whose
 // origin starts at or after afterByte. delta is the signed byte count change
 // (positive = content grew, negative = content shrank).
@@ -610,7 +947,7 @@ func (s *MemoryStore) ShiftOrigins(filePath string, afterByte uint32, delta int3
 		if nodeID == "" {
 			continue
 		}
-		n, exists := s.nodes[nodeID]
+		n, exists := s.nodes.get(nodeID)
 		if !exists || n.Origin == nil {
 			continue
 		}
@@ -627,8 +964,11 @@ func (s *MemoryStore) ShiftOrigins(filePath string, afterByte uint32, delta int3
 			if newEnd < 0 {
 				newEnd = 0
 			}
-			n.Origin.StartByte = uint32(newStart)
-			n.Origin.EndByte = uint32(newEnd)
+			shifted := *n.Origin
+			shifted.StartByte = uint32(newStart)
+			shifted.EndByte = uint32(newEnd)
+			n.Origin = &shifted
+			s.nodes.put(n)
 		}
 	}
 }
@@ -638,14 +978,14 @@ func (s *MemoryStore) GetCallers(token string) ([]*Node, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	ids, ok := s.refs[token]
+	refs, ok := s.refs[token]
 	if !ok {
 		return nil, nil
 	}
 
 	var nodes []*Node
-	for _, id := range ids {
-		if n, ok := s.nodes[id]; ok {
+	for _, id := range s.nodeIDs(refs) {
+		if n, ok := s.nodes.get(id); ok {
 			nodes = append(nodes, n)
 		}
 	}
@@ -714,7 +1054,7 @@ func (s *MemoryStore) GetCallees(id string) ([]*Node, error) {
 	// 1. Find the "source" file child
 	s.mu.RLock()
 	id = NormalizeID(id)
-	node, ok := s.nodes[id]
+	node, ok := s.nodes.get(id)
 	s.mu.RUnlock()
 
 	if !ok || !node.Mode.IsDir() {
@@ -779,7 +1119,7 @@ func (s *MemoryStore) GetCallees(id string) ([]*Node, error) {
 					if defID == id || seen[defID] {
 						continue
 					}
-					if defNode, ok := s.nodes[defID]; ok {
+					if defNode, ok := s.nodes.get(defID); ok {
 						results = append(results, defNode)
 						seen[defID] = true
 						resolved = true
@@ -801,7 +1141,7 @@ func (s *MemoryStore) GetCallees(id string) ([]*Node, error) {
 							if defID == id || seen[defID] {
 								continue
 							}
-							if defNode, ok := s.nodes[defID]; ok {
+							if defNode, ok := s.nodes.get(defID); ok {
 								results = append(results, defNode)
 								seen[defID] = true
 								resolved = true
@@ -822,7 +1162,7 @@ func (s *MemoryStore) GetCallees(id string) ([]*Node, error) {
 				if defID == id || seen[defID] {
 					continue
 				}
-				if defNode, ok := s.nodes[defID]; ok {
+				if defNode, ok := s.nodes.get(defID); ok {
 					results = append(results, defNode)
 					seen[defID] = true
 				}
@@ -844,14 +1184,13 @@ func (s *MemoryStore) Act(id, action, payload string) (*ActionResult, error) {
 // GetNode implements Graph.
 func (s *MemoryStore) GetNode(id string) (*Node, error) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	id = NormalizeID(id)
-	n, ok := s.nodes[id]
+	n, ok := s.nodes.get(NormalizeID(id))
+	s.mu.RUnlock()
 	if !ok {
 		return nil, ErrNotFound
 	}
-	return n, nil
+	// Outside the lock: a computed leaf's command may take a while.
+	return s.computedView(n), nil
 }
 
 // ListChildren implements Graph.
@@ -866,7 +1205,7 @@ func (s *MemoryStore) ListChildren(id string) ([]string, error) {
 	}
 
 	id = NormalizeID(id)
-	n, ok := s.nodes[id]
+	n, ok := s.nodes.get(id)
 	if !ok {
 		return nil, ErrNotFound
 	}
@@ -955,11 +1294,30 @@ func (s *MemoryStore) resolveContent(id string, ref *ContentRef) ([]byte, error)
 // and all invoke the resolver for the same key. This is benign — the first
 // writer wins via Put's dedup check, and subsequent resolver results are
 // discarded. The resolver (SQLite query + template render) is idempotent.
+// SQLiteGraph collapses such misses with a singleflight.Group, since
+// rendering a large leaf once per parallel chunk read is not cheap.
 type ContentCache struct {
 	mu      sync.RWMutex
 	entries map[string][]byte
 	keys    []string
 	maxSize int
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// CacheStats is a snapshot of a ContentCache's size and hit counters.
+type CacheStats struct {
+	Entries  int    `json:"entries"`
+	Capacity int    `json:"capacity"`
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+}
+
+// CacheReporter is implemented by graphs that serve content through a
+// ContentCache. Used by the mount control socket's stats command.
+type CacheReporter interface {
+	CacheStats() CacheStats
 }
 
 // NewContentCache creates a FIFO-bounded content cache.
@@ -976,9 +1334,26 @@ func (c *ContentCache) Get(key string) ([]byte, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	v, ok := c.entries[key]
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
 	return v, ok
 }
 
+// Stats returns the current entry count, capacity, and hit/miss counts.
+func (c *ContentCache) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return CacheStats{
+		Entries:  len(c.entries),
+		Capacity: c.maxSize,
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+	}
+}
+
 // Put stores content, evicting the oldest entry if at capacity.
 // Deduplicates: if key already exists, updates the value without
 // adding a duplicate key entry.
@@ -1027,14 +1402,24 @@ func (c *ContentCache) Delete(key string) {
 // InitRefsDB opens an in-memory SQLite database with the same schema as
 // SQLiteGraph's sidecar (node_refs + file_ids + mache_refs vtab).
 // Must be called before FlushRefs. Safe to call multiple times (idempotent).
+// If the vtab module fails to register, it logs a warning and disables the
+// SQL refs index instead of failing.
 func (s *MemoryStore) InitRefsDB() error {
-	if s.refsDB != nil {
+	if s.refsDB != nil || !s.refsEnabled {
 		return nil
 	}
 
-	refsMod, err := refsvtab.Register()
+	refsMod, err := registerRefs()
+	if err != nil {
+		log.Printf("Warning: %v; mounting without the SQL refs index", err)
+		s.refsEnabled = false
+		return nil
+	}
+	// mache_nodes is optional on top of mache_refs. Register it before
+	// the first connection opens, so the connection sees the module.
+	nodesMod, err := registerNodes()
 	if err != nil {
-		return err
+		log.Printf("Warning: %v; querying without mache_nodes", err)
 	}
 
 	// Use a temp file (not :memory:) because the vtab's xFilter runs inside
@@ -1095,6 +1480,17 @@ func (s *MemoryStore) InitRefsDB() error {
 		_ = os.Remove(refsPath) // cleanup temp file
 		return fmt.Errorf("create mache_refs vtab: %w", err)
 	}
+	if nodesMod != nil {
+		nodesMod.RegisterSource(dbID, s.nodeRows)
+		query := fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS mache_nodes USING mache_nodes(%s)", dbID)
+		if _, err := db.Exec(query); err != nil {
+			nodesMod.UnregisterSource(dbID)
+			refsMod.UnregisterDB(dbID)
+			_ = db.Close()          // ignore close error
+			_ = os.Remove(refsPath) // cleanup temp file
+			return fmt.Errorf("create mache_nodes vtab: %w", err)
+		}
+	}
 
 	s.refsDB = db
 	s.refsDBPath = refsPath
@@ -1113,39 +1509,38 @@ func (s *MemoryStore) FlushRefs() error {
 }
 
 func (s *MemoryStore) flushRefsInternal() error {
+	if !s.refsEnabled {
+		return nil
+	}
 	if s.refsDB == nil {
 		return fmt.Errorf("refsDB not initialized: call InitRefsDB first")
 	}
 
+	// The token bitmaps already hold internal node IDs; those double as
+	// the sidecar's file IDs. Serialize under the lock, write after.
 	s.mu.RLock()
-	refs := s.refs
-	s.mu.RUnlock()
-
-	if len(refs) == 0 {
+	if len(s.refs) == 0 {
+		s.mu.RUnlock()
 		return nil
 	}
-
-	// Build file ID map from all unique paths
-	fileIDMap := make(map[string]uint32)
-	var nextID uint32
-	for _, paths := range refs {
-		for _, p := range paths {
-			if _, ok := fileIDMap[p]; !ok {
-				fileIDMap[p] = nextID
-				nextID++
-			}
+	referenced := roaring.New()
+	bitmaps := make(map[string][]byte, len(s.refs))
+	for token, bm := range s.refs {
+		referenced.Or(bm)
+		data, err := bm.ToBytes()
+		if err != nil {
+			s.mu.RUnlock()
+			return fmt.Errorf("serialize bitmap for %s: %w", token, err)
 		}
+		bitmaps[token] = data
 	}
-
-	// Build roaring bitmaps per token
-	bitmaps := make(map[string]*roaring.Bitmap, len(refs))
-	for token, paths := range refs {
-		bm := roaring.New()
-		for _, p := range paths {
-			bm.Add(fileIDMap[p])
+	fileIDMap := make(map[string]uint32, referenced.GetCardinality())
+	for _, id := range referenced.ToArray() {
+		if int(id) < len(s.intToNodeID) && s.intToNodeID[id] != "" {
+			fileIDMap[s.intToNodeID[id]] = id
 		}
-		bitmaps[token] = bm
 	}
+	s.mu.RUnlock()
 
 	// Write both tables in a single transaction
 	tx, err := s.refsDB.Begin()
@@ -1172,13 +1567,8 @@ func (s *MemoryStore) flushRefsInternal() error {
 	}
 	defer func() { _ = refStmt.Close() }() // safe to ignore
 
-	var buf bytes.Buffer
-	for token, bm := range bitmaps {
-		buf.Reset()
-		if _, err := bm.WriteTo(&buf); err != nil {
-			return fmt.Errorf("serialize bitmap for %s: %w", token, err)
-		}
-		if _, err := refStmt.Exec(token, buf.Bytes()); err != nil {
+	for token, data := range bitmaps {
+		if _, err := refStmt.Exec(token, data); err != nil {
 			return fmt.Errorf("insert ref %s: %w", token, err)
 		}
 	}
@@ -1186,22 +1576,100 @@ func (s *MemoryStore) flushRefsInternal() error {
 	return tx.Commit()
 }
 
+// nodeRows lists every node as a mache_nodes row. A construct directory
+// (one with a source child) takes its source's size and reports its line
+// span, its callers count, and its complexity; a file takes its
+// directory's language.
+func (s *MemoryStore) nodeRows() ([]refsvtab.NodeRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rows := make([]refsvtab.NodeRow, 0, s.nodes.len())
+	s.nodes.each(func(n *Node) {
+		row := refsvtab.NodeRow{Path: n.ID, Kind: "file", Size: n.ContentSize()}
+		if !n.Mode.IsDir() {
+			if parent, ok := s.nodes.get(path.Dir(n.ID)); ok {
+				row.Lang = string(parent.Properties["lang"])
+			}
+			rows = append(rows, row)
+			return
+		}
+		row.Kind, row.Size = "dir", 0
+		row.Lang = string(n.Properties["lang"])
+		for _, c := range n.Children {
+			if path.Base(c) != "source" {
+				continue
+			}
+			if src, ok := s.nodes.get(c); ok {
+				row.Size = src.ContentSize()
+			}
+			var callers uint64
+			if bm, ok := s.refs[path.Base(n.ID)]; ok {
+				callers = bm.GetCardinality()
+			}
+			row.RefCount = sql.NullInt64{Int64: int64(callers), Valid: true}
+			break
+		}
+		if start, end, ok := locationLines(n.Properties["location"]); ok {
+			row.Lines = sql.NullInt64{Int64: int64(end - start + 1), Valid: true}
+		}
+		if c, err := strconv.ParseInt(string(n.Properties[ComplexityProperty]), 10, 64); err == nil {
+			row.Complexity = sql.NullInt64{Int64: c, Valid: true}
+		}
+		rows = append(rows, row)
+	})
+	return rows, nil
+}
+
+// locationLines parses the line span of a "file:start:end" location.
+func locationLines(loc []byte) (start, end int, ok bool) {
+	rest, endStr, found := cutLast(string(loc), ":")
+	if !found {
+		return 0, 0, false
+	}
+	_, startStr, found := cutLast(rest, ":")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	end, err = strconv.Atoi(endStr)
+	return start, end, err == nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
 // QueryRefs executes a SQL query against the in-memory refs database,
 // which includes the mache_refs virtual table.
 func (s *MemoryStore) QueryRefs(query string, args ...any) (*sql.Rows, error) {
+	if !s.refsEnabled {
+		return nil, ErrRefsDisabled
+	}
 	if s.refsDB == nil {
 		return nil, fmt.Errorf("refsDB not initialized: call InitRefsDB first")
 	}
 	return s.refsDB.Query(query, args...)
 }
 
-// Close closes the refs database and removes the temp file.
+// Close closes the refs database and the node spill file, if any, and
+// removes their temp files.
 func (s *MemoryStore) Close() error {
+	nodesErr := s.nodes.close()
 	if s.refsDB != nil {
 		// Unregister from vtab module to prevent leaks/races
-		if mod, err := refsvtab.Register(); err == nil && mod != nil {
+		if mod, err := registerRefs(); err == nil && mod != nil {
 			mod.UnregisterDB(s.dbID)
 		}
+		if mod, err := registerNodes(); err == nil && mod != nil {
+			mod.UnregisterSource(s.dbID)
+		}
 
 		err := s.refsDB.Close()
 		if s.refsDBPath != "" {
@@ -1209,7 +1677,7 @@ func (s *MemoryStore) Close() error {
 			_ = os.Remove(s.refsDBPath + "-wal")
 			_ = os.Remove(s.refsDBPath + "-shm")
 		}
-		return err
+		return errors.Join(err, nodesErr)
 	}
-	return nil
+	return nodesErr
 }
@@ -251,7 +251,20 @@ func RunGraphSuite(t *testing.T, factory GraphFactory) {
 
 func memoryStoreFactory(t *testing.T) Graph {
 	t.Helper()
-	s := NewMemoryStore()
+	return addSuiteNodes(NewMemoryStore())
+}
+
+// spillingStoreFactory holds two nodes in memory, so most of the canonical
+// shape lives in the spill file.
+func spillingStoreFactory(t *testing.T) Graph {
+	t.Helper()
+	s, err := NewSpillingMemoryStore(2)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return addSuiteNodes(s)
+}
+
+func addSuiteNodes(s *MemoryStore) *MemoryStore {
 	s.AddRoot(&Node{
 		ID:       "pkg",
 		Mode:     fs.ModeDir,
@@ -366,6 +379,7 @@ func writableGraphFactory(t *testing.T) Graph {
 // ---------------------------------------------------------------------------
 
 func TestMemoryStore_GraphSuite(t *testing.T)   { RunGraphSuite(t, memoryStoreFactory) }
+func TestSpillingStore_GraphSuite(t *testing.T) { RunGraphSuite(t, spillingStoreFactory) }
 func TestHotSwapGraph_GraphSuite(t *testing.T)  { RunGraphSuite(t, hotSwapFactory) }
 func TestSQLiteGraph_GraphSuite(t *testing.T)   { RunGraphSuite(t, sqliteGraphFactory) }
 func TestWritableGraph_GraphSuite(t *testing.T) { RunGraphSuite(t, writableGraphFactory) }
@@ -1,8 +1,13 @@
 package graph
 
 import (
+	"bytes"
 	"fmt"
 	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -52,6 +57,30 @@ func TestMemoryStore_AddNodeFileWithData(t *testing.T) {
 	}
 }
 
+func TestMemoryStore_WarnsOnOriginCollision(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	store := NewMemoryStore()
+	store.AddNode(&Node{ID: "fns/init/source", Origin: &SourceOrigin{FilePath: "/src/a/util.go"}})
+	store.AddNode(&Node{ID: "fns/init/source", Origin: &SourceOrigin{FilePath: "/src/a/util.go", EndByte: 4}})
+	assert.Empty(t, logs.String(), "re-adding from the same file is not a collision")
+
+	store.AddFileChildren(&Node{ID: "fns/init", Mode: fs.ModeDir}, []*Node{
+		{ID: "fns/init/source", Origin: &SourceOrigin{FilePath: "/src/b/util.go"}},
+	})
+	assert.Contains(t, logs.String(), "fns/init/source from /src/b/util.go replaces the one from /src/a/util.go")
+}
+
+func TestStableID(t *testing.T) {
+	// External indexes key nodes by this value: it must not change.
+	assert.Equal(t, uint64(8788703371416604658), StableID("main/functions/Hello", "main.go"))
+	assert.Equal(t, StableID("pkg/Hello", "a/b.go"), StableID("pkg/Hello", filepath.FromSlash("a/b.go")))
+	assert.NotEqual(t, StableID("pkg/Hello", "a.go"), StableID("pkg/Hello", "b.go"))
+	assert.NotEqual(t, StableID("pkg/Hello", ""), StableID("pkg", "/Hello"), "ID and file are delimited")
+}
+
 func TestMemoryStore_GetNodeNormalizesLeadingSlash(t *testing.T) {
 	store := NewMemoryStore()
 	store.AddNode(&Node{ID: "foo", Mode: fs.ModeDir})
@@ -267,6 +296,54 @@ func TestMemoryStore_VTab_LIKE(t *testing.T) {
 	assert.Contains(t, paths, "ui/helper.go")
 }
 
+func TestMemoryStore_VTab_Nodes(t *testing.T) {
+	store := NewMemoryStore()
+	require.NoError(t, store.InitRefsDB())
+	defer func() { _ = store.Close() }()
+
+	construct := func(id, location, complexity string) {
+		store.AddNode(&Node{ID: id, Mode: fs.ModeDir, Children: []string{id + "/source"}, Properties: map[string][]byte{
+			"lang": []byte("go"), "location": []byte(location), ComplexityProperty: []byte(complexity),
+		}})
+		store.AddNode(&Node{ID: id + "/source", Data: []byte("func " + path.Base(id) + "() {}")})
+	}
+	store.AddRoot(&Node{ID: "pkg", Mode: fs.ModeDir, Children: []string{"pkg/Big", "pkg/Small", "pkg/Caller"}})
+	construct("pkg/Big", "pkg/big.go:10:130", "14")
+	construct("pkg/Small", "pkg/small.go:3:5", "1")
+	construct("pkg/Caller", "pkg/caller.go:1:4", "2")
+	for _, caller := range []string{"pkg/Caller", "pkg/Small"} {
+		require.NoError(t, store.AddRef("Big", caller))
+	}
+	require.NoError(t, store.AddRef("Small", "pkg/Caller"))
+	require.NoError(t, store.FlushRefs())
+
+	var rows []string
+	collect := func(query string) {
+		t.Helper()
+		rows = nil
+		r, err := store.QueryRefs(query)
+		require.NoError(t, err)
+		defer func() { _ = r.Close() }()
+		for r.Next() {
+			var row string
+			require.NoError(t, r.Scan(&row))
+			rows = append(rows, row)
+		}
+		require.NoError(t, r.Err())
+	}
+
+	collect(`SELECT path || ' ' || lang || ' ' || size || ' ' || lines || ' ' || refcount || ' ' || complexity
+		FROM mache_nodes WHERE kind = 'dir' AND lines > 100 AND refcount > 1`)
+	assert.Equal(t, []string{"pkg/Big go 13 121 2 14"}, rows)
+
+	collect(`SELECT path || ' ' || COALESCE(refcount, 'null') || ' ' || COALESCE(lang, 'null') FROM mache_nodes WHERE path IN ('pkg', 'pkg/Small/source')`)
+	assert.ElementsMatch(t, []string{"pkg null null", "pkg/Small/source null go"}, rows)
+
+	// What the constructs over complexity 1 call.
+	collect(`SELECT r.token FROM mache_refs r JOIN mache_nodes n ON n.path = r.path WHERE n.complexity > 1 ORDER BY r.token`)
+	assert.Equal(t, []string{"Big", "Small"}, rows)
+}
+
 func TestMemoryStore_FlushRefs_Idempotent(t *testing.T) {
 	store := NewMemoryStore()
 	require.NoError(t, store.InitRefsDB())
@@ -300,6 +377,25 @@ func TestMemoryStore_FlushRefs_Empty(t *testing.T) {
 	require.NoError(t, store.FlushRefs())
 }
 
+func TestMemoryStore_RefsDisabled(t *testing.T) {
+	failRefsRegistration(t)
+	store := NewMemoryStore()
+	require.NoError(t, store.InitRefsDB(), "a failed registration disables refs instead of failing")
+	defer func() { _ = store.Close() }()
+
+	store.AddNode(&Node{ID: "pkg/main/source.go", Data: []byte("fmt.Println()")})
+	require.NoError(t, store.AddRef("Println", "pkg/main/source.go"))
+	require.NoError(t, store.FlushRefs())
+	_, err := store.QueryRefs("SELECT 1")
+	assert.ErrorIs(t, err, ErrRefsDisabled)
+
+	// callers/ reads the in-memory index, which needs no vtab.
+	callers, err := store.GetCallers("Println")
+	require.NoError(t, err)
+	require.Len(t, callers, 1)
+	assert.Equal(t, "pkg/main/source.go", callers[0].ID)
+}
+
 func TestMemoryStore_QueryRefs_BeforeInit(t *testing.T) {
 	store := NewMemoryStore()
 
@@ -477,11 +573,12 @@ func TestMemoryStore_DeleteFileNodes_CleansRefsAndDefs(t *testing.T) {
 	store.DeleteFileNodes("/src/main.go")
 
 	// Refs for "Validate" should only contain FuncB
-	store.mu.RLock()
-	assert.Equal(t, []string{"pkg/FuncB"}, store.refs["Validate"])
+	refs := store.RefsMap()
+	assert.Equal(t, []string{"pkg/FuncB"}, refs["Validate"])
 	// "OnlyA" should be entirely removed
-	_, hasOnlyA := store.refs["OnlyA"]
+	_, hasOnlyA := refs["OnlyA"]
 	assert.False(t, hasOnlyA, "OnlyA ref should be deleted when its only node is removed")
+	store.mu.RLock()
 	// FuncA def should be removed, FuncB should remain
 	_, hasFuncADef := store.defs["FuncA"]
 	assert.False(t, hasFuncADef, "FuncA def should be deleted")
@@ -489,6 +586,31 @@ func TestMemoryStore_DeleteFileNodes_CleansRefsAndDefs(t *testing.T) {
 	store.mu.RUnlock()
 }
 
+func TestMemoryStore_ReplaceFileNodes_SameIDs(t *testing.T) {
+	store := NewMemoryStore()
+	store.AddRoot(&Node{ID: "pkg", Mode: fs.ModeDir, Children: []string{"pkg/Run"}})
+	store.AddNode(&Node{ID: "pkg/Run", Mode: fs.ModeDir, Children: []string{"pkg/Run/source"}})
+	origin := &SourceOrigin{FilePath: "/src/main.go", StartByte: 0, EndByte: 6}
+	store.AddNode(&Node{ID: "pkg/Run/source", Data: []byte("func A"), Origin: origin})
+	require.NoError(t, store.AddRef("Stop", "pkg/Run/source"))
+	require.NoError(t, store.AddDef("Run", "pkg/Run"))
+
+	// Re-ingest of main.go: same construct, new content.
+	store.ReplaceFileNodes("/src/main.go", []*Node{{ID: "pkg/Run/source", Data: []byte("func B"), Origin: origin}})
+
+	children, err := store.ListChildren("pkg/Run")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pkg/Run/source"}, children, "replacing a node under the same ID keeps its parent link")
+	node, err := store.GetNode("pkg/Run/source")
+	require.NoError(t, err)
+	assert.Equal(t, "func B", string(node.Data))
+
+	assert.Empty(t, store.RefsMap()["Stop"], "refs of the replaced nodes are purged for the caller to re-add")
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	assert.Empty(t, store.defs["Run"], "defs pointing at the file's constructs are purged too")
+}
+
 func TestMemoryStore_ShiftOrigins_PositiveDelta(t *testing.T) {
 	store := NewMemoryStore()
 
@@ -752,3 +874,67 @@ func TestSetResolver_CacheScalesWithNodes(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryStore_RefsReaddedAfterReplace(t *testing.T) {
+	store := NewMemoryStore()
+	origin := &SourceOrigin{FilePath: "/src/main.go", StartByte: 0, EndByte: 6}
+	store.AddNode(&Node{ID: "pkg/Run/source", Data: []byte("func A"), Origin: origin})
+	require.NoError(t, store.AddRef("Stop", "pkg/Run/source", 3))
+	require.NoError(t, store.AddRef("Stop", "pkg/Run/source", 4))
+
+	callers, err := store.GetCallers("Stop")
+	require.NoError(t, err)
+	require.Len(t, callers, 1, "a node calling a token twice is one caller")
+
+	// Re-ingest: the node is replaced under the same ID and its refs re-added.
+	store.ReplaceFileNodes("/src/main.go", []*Node{{ID: "pkg/Run/source", Data: []byte("func B"), Origin: origin}})
+	require.NoError(t, store.AddRef("Start", "pkg/Run/source", 5))
+
+	assert.Equal(t, map[string][]string{"Start": {"pkg/Run/source"}}, store.RefsMap())
+	callers, err = store.GetCallers("Start")
+	require.NoError(t, err)
+	require.Len(t, callers, 1)
+	assert.Equal(t, "func B", string(callers[0].Data))
+	assert.Equal(t, []int{5}, store.CallSiteLines("Start", "pkg/Run/source"))
+	assert.Nil(t, store.CallSiteLines("Stop", "pkg/Run/source"))
+}
+
+func TestMemoryStore_CallSiteLines(t *testing.T) {
+	store := NewMemoryStore()
+	store.AddNode(&Node{
+		ID:     "pkg/FuncA",
+		Data:   []byte("func A"),
+		Origin: &SourceOrigin{FilePath: "/src/main.go", StartByte: 0, EndByte: 6},
+	})
+
+	require.NoError(t, store.AddRef("Validate", "pkg/FuncA", 12, 7))
+	require.NoError(t, store.AddRef("Validate", "pkg/FuncA", 7))
+	require.NoError(t, store.AddRef("Other", "pkg/FuncA"))
+
+	assert.Equal(t, []int{7, 12}, store.CallSiteLines("Validate", "pkg/FuncA"))
+	assert.Nil(t, store.CallSiteLines("Other", "pkg/FuncA"), "no lines recorded")
+	assert.Nil(t, store.CallSiteLines("Validate", "pkg/Missing"))
+
+	store.DeleteFileNodes("/src/main.go")
+	assert.Nil(t, store.CallSiteLines("Validate", "pkg/FuncA"))
+	store.mu.RLock()
+	assert.Empty(t, store.refLines)
+	store.mu.RUnlock()
+}
+
+func TestMemoryStore_ShiftLines(t *testing.T) {
+	store := NewMemoryStore()
+	for id, start := range map[string]uint32{"pkg/FuncA/source": 0, "pkg/FuncB/source": 40} {
+		store.AddNode(&Node{ID: id, Origin: &SourceOrigin{FilePath: "/src/main.go", StartByte: start, EndByte: start + 30}})
+	}
+	store.AddNode(&Node{ID: "other/Run/source", Origin: &SourceOrigin{FilePath: "/src/other.go", EndByte: 10}})
+	require.NoError(t, store.AddRef("Validate", "pkg/FuncA/source", 3))
+	require.NoError(t, store.AddRef("Validate", "pkg/FuncB/source", 9, 12))
+	require.NoError(t, store.AddRef("Validate", "other/Run/source", 9))
+
+	// FuncA (lines 1-5) grew by two lines.
+	store.ShiftLines("/src/main.go", 5, 2)
+	assert.Equal(t, []int{3}, store.CallSiteLines("Validate", "pkg/FuncA/source"))
+	assert.Equal(t, []int{11, 14}, store.CallSiteLines("Validate", "pkg/FuncB/source"))
+	assert.Equal(t, []int{9}, store.CallSiteLines("Validate", "other/Run/source"), "other files keep their lines")
+}
@@ -27,6 +27,16 @@ func (h *HotSwapGraph) Swap(newGraph Graph) {
 	h.current = newGraph
 }
 
+// Close closes the current graph if it implements io.Closer.
+func (h *HotSwapGraph) Close() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if closer, ok := h.current.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 // GetNode delegates to current graph.
 func (h *HotSwapGraph) GetNode(id string) (*Node, error) {
 	h.mu.RLock()
@@ -62,6 +72,66 @@ func (h *HotSwapGraph) GetCallers(token string) ([]*Node, error) {
 	return h.current.GetCallers(token)
 }
 
+// CallSiteLines delegates to current graph if it records call sites.
+func (h *HotSwapGraph) CallSiteLines(token, nodeID string) []int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if loc, ok := h.current.(CallSiteLocator); ok {
+		return loc.CallSiteLines(token, nodeID)
+	}
+	return nil
+}
+
+// TestsFor delegates to current graph if it indexes tests.
+func (h *HotSwapGraph) TestsFor(token string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if loc, ok := h.current.(TestLocator); ok {
+		return loc.TestsFor(token)
+	}
+	return nil
+}
+
+// TypesUsed delegates to current graph if it indexes type refs.
+func (h *HotSwapGraph) TypesUsed(dirID string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if loc, ok := h.current.(TypeRefLocator); ok {
+		return loc.TypesUsed(dirID)
+	}
+	return nil
+}
+
+// Topology delegates to current graph if it can describe its structure.
+func (h *HotSwapGraph) Topology() *Topology {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if td, ok := h.current.(TopologyDescriber); ok {
+		return td.Topology()
+	}
+	return nil
+}
+
+// DefsMap delegates to current graph if it keeps a definition index.
+func (h *HotSwapGraph) DefsMap() map[string][]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if dp, ok := h.current.(DefsProvider); ok {
+		return dp.DefsMap()
+	}
+	return nil
+}
+
+// CacheStats delegates to current graph if it reports a content cache.
+func (h *HotSwapGraph) CacheStats() CacheStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if cr, ok := h.current.(CacheReporter); ok {
+		return cr.CacheStats()
+	}
+	return CacheStats{}
+}
+
 // GetCallees delegates to current graph.
 func (h *HotSwapGraph) GetCallees(id string) ([]*Node, error) {
 	h.mu.RLock()
@@ -50,6 +50,18 @@ func TestHotSwapGraph_Swap_ClosesOldGraph(t *testing.T) {
 	assert.True(t, old.closed, "Swap should close the old graph if it implements io.Closer")
 }
 
+func TestHotSwapGraph_Close_ClosesCurrentGraph(t *testing.T) {
+	old := &closableGraph{Graph: NewMemoryStore()}
+	cur := &closableGraph{Graph: NewMemoryStore()}
+	h := NewHotSwapGraph(old)
+	h.Swap(cur)
+	old.closed = false
+
+	require.NoError(t, h.Close())
+	assert.True(t, cur.closed)
+	assert.False(t, old.closed, "only the current graph is closed")
+}
+
 func TestHotSwapGraph_Swap_NoCloseIfNotCloser(t *testing.T) {
 	h := NewHotSwapGraph(NewMemoryStore())
 	h.Swap(NewMemoryStore()) // should not panic
@@ -0,0 +1,34 @@
+package graph
+
+// nodeMap is the node index behind MemoryStore. A change to a node
+// returned by get is only certain to be kept once the node is put back:
+// memNodeMap hands out live pointers, spillNodeMap copies. The caller
+// (MemoryStore) serializes access with its own lock.
+type nodeMap interface {
+	get(id string) (*Node, bool)
+	put(n *Node)
+	del(id string)
+	len() int
+	// each calls fn for every node. fn must not add or delete nodes.
+	each(fn func(n *Node))
+	close() error
+}
+
+// memNodeMap is the default, all-in-memory nodeMap.
+type memNodeMap map[string]*Node
+
+func (m memNodeMap) get(id string) (*Node, bool) {
+	n, ok := m[id]
+	return n, ok
+}
+
+func (m memNodeMap) put(n *Node)   { m[n.ID] = n }
+func (m memNodeMap) del(id string) { delete(m, id) }
+func (m memNodeMap) len() int      { return len(m) }
+func (m memNodeMap) close() error  { return nil }
+
+func (m memNodeMap) each(fn func(n *Node)) {
+	for _, n := range m {
+		fn(n)
+	}
+}
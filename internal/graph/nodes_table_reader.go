@@ -9,6 +9,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 // NodeKindFile and NodeKindDir are the kind values in the nodes table.
@@ -27,13 +29,16 @@ const (
 // reference but does not close it.
 type NodesTableReader struct {
 	db        *sql.DB
-	tableName string           // source records table ("results" or schema.Table)
-	render    TemplateRenderer // for record_id fallback rendering
-	levels    []*schemaLevel   // compiled schema levels
-	fileMode  os.FileMode      // permission for file nodes
-	dirMode   os.FileMode      // permission for dir nodes
-	sizeCache sync.Map         // file path → int64
-	cache     *ContentCache    // FIFO-bounded rendered content
+	tableName string             // source records table ("results" or schema.Table)
+	recordCol string             // JSON column of tableName ("record" or schema.RecordColumn)
+	render    TemplateRenderer   // for record_id fallback rendering
+	levels    []*schemaLevel     // compiled schema levels
+	fileMode  os.FileMode        // permission for file nodes
+	dirMode   os.FileMode        // permission for dir nodes
+	sizeCache sync.Map           // file path → int64
+	cache     *ContentCache      // FIFO-bounded rendered content
+	renders   singleflight.Group // one resolve per file for concurrent misses
+	records   recordCache        // parsed records shared by their leaves
 }
 
 // DB returns the underlying database connection.
@@ -41,12 +46,13 @@ type NodesTableReader struct {
 func (r *NodesTableReader) DB() *sql.DB { return r.db }
 
 // NewNodesTableReader creates a reader for the nodes-table schema.
-func NewNodesTableReader(db *sql.DB, tableName string, render TemplateRenderer,
+func NewNodesTableReader(db *sql.DB, tableName, recordCol string, render TemplateRenderer,
 	levels []*schemaLevel, fileMode, dirMode os.FileMode, cacheSize int,
 ) *NodesTableReader {
 	return &NodesTableReader{
 		db:        db,
 		tableName: tableName,
+		recordCol: recordCol,
 		render:    render,
 		levels:    levels,
 		fileMode:  fileMode,
@@ -174,7 +180,17 @@ func (r *NodesTableReader) resolveContent(id string) ([]byte, error) {
 	if c, ok := r.cache.Get(id); ok {
 		return c, nil
 	}
+	v, err, _ := r.renders.Do(id, func() (any, error) {
+		return r.loadContent(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
 
+// loadContent reads or renders id's content and caches it.
+func (r *NodesTableReader) loadContent(id string) ([]byte, error) {
 	var record sql.NullString
 	var recordID sql.NullString
 	err := r.db.QueryRow("SELECT record, record_id FROM nodes WHERE id = ?", id).
@@ -204,16 +220,12 @@ func (r *NodesTableReader) resolveContent(id string) ([]byte, error) {
 
 // renderFromRecord fetches a record by ID and renders content via template.
 func (r *NodesTableReader) renderFromRecord(filePath, recordID string) ([]byte, error) {
-	var raw string
-	if err := r.db.QueryRow("SELECT record FROM "+r.tableName+" WHERE id = ?", recordID).Scan(&raw); err != nil {
-		return nil, fmt.Errorf("fetch record %s: %w", recordID, err)
-	}
-
-	var parsed any
-	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
-		return nil, fmt.Errorf("parse record %s: %w", recordID, err)
+	values, err := r.records.get(recordID, func() (map[string]any, error) {
+		return fetchRecord(r.db, r.tableName, r.recordCol, recordID)
+	})
+	if err != nil {
+		return nil, err
 	}
-	values, _ := parsed.(map[string]any)
 
 	segments := strings.Split(filePath, "/")
 	_, fileLeaf := walkSchemaLevels(r.levels, segments)
@@ -228,6 +240,31 @@ func (r *NodesTableReader) renderFromRecord(filePath, recordID string) ([]byte,
 	return []byte(rendered), nil
 }
 
+// fetchRecord reads record recordID's JSON column from table and parses it.
+// A record that isn't a JSON object yields a nil map.
+func fetchRecord(db *sql.DB, table, column, recordID string) (map[string]any, error) {
+	var raw string
+	if err := db.QueryRow("SELECT "+column+" FROM "+table+" WHERE id = ?", recordID).Scan(&raw); err != nil {
+		return nil, fmt.Errorf("fetch record %s: %w", recordID, err)
+	}
+	var parsed any
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("parse record %s: %w", recordID, err)
+	}
+	values, _ := parsed.(map[string]any)
+	return values, nil
+}
+
+// SourceFile implements SourceFileLocator using the source_file column.
+func (r *NodesTableReader) SourceFile(id string) (string, bool) {
+	var sf sql.NullString
+	err := r.db.QueryRow("SELECT source_file FROM nodes WHERE id = ?", NormalizeID(id)).Scan(&sf)
+	if err != nil || !sf.Valid || sf.String == "" {
+		return "", false
+	}
+	return sf.String, true
+}
+
 // GetCallers returns nodes that reference the given token via node_refs table.
 func (r *NodesTableReader) GetCallers(token string) ([]*Node, error) {
 	rows, err := r.db.Query("SELECT node_id FROM node_refs WHERE token = ?", token)
@@ -251,6 +288,17 @@ func (r *NodesTableReader) GetCallers(token string) ([]*Node, error) {
 	return nodes, rows.Err()
 }
 
+// CallSiteLines implements CallSiteLocator using node_refs' lines column.
+// DBs written before it existed have no lines.
+func (r *NodesTableReader) CallSiteLines(token, nodeID string) []int {
+	var lines sql.NullString
+	err := r.db.QueryRow("SELECT lines FROM node_refs WHERE token = ? AND node_id = ?", token, nodeID).Scan(&lines)
+	if err != nil || !lines.Valid {
+		return nil
+	}
+	return ParseLines(lines.String)
+}
+
 // Invalidate evicts cached content and size for a node.
 func (r *NodesTableReader) Invalidate(id string) {
 	r.sizeCache.Delete(id)
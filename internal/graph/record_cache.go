@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// recordCacheTTL bounds how long a parsed record is reused. Reading a
+// directory reads its leaves back to back, so a short window is enough for
+// them to share one parse without holding records for long.
+const recordCacheTTL = 2 * time.Second
+
+// recordCacheSize bounds how many parsed records a recordCache holds.
+const recordCacheSize = 64
+
+// recordCache memoizes parsed record JSON by record ID, so the leaves of
+// one record directory (raw.json, description, status, ...) fetch and parse
+// the record once rather than once per leaf. Concurrent misses for a record
+// share a single load. The values maps are shared; renderers only read them.
+type recordCache struct {
+	mu      sync.Mutex
+	entries map[string]recordCacheEntry
+	loads   singleflight.Group
+}
+
+type recordCacheEntry struct {
+	values   map[string]any
+	loadedAt time.Time
+}
+
+// get returns the parsed record recordID, calling load when it isn't cached
+// or its entry is older than recordCacheTTL.
+func (c *recordCache) get(recordID string, load func() (map[string]any, error)) (map[string]any, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[recordID]; ok && time.Since(e.loadedAt) < recordCacheTTL {
+		c.mu.Unlock()
+		return e.values, nil
+	}
+	c.mu.Unlock()
+
+	v, err, _ := c.loads.Do(recordID, func() (any, error) {
+		values, err := load()
+		if err != nil {
+			return nil, err
+		}
+		c.put(recordID, values)
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]any), nil
+}
+
+// put caches values, first dropping expired entries and, when still full,
+// an arbitrary one.
+func (c *recordCache) put(recordID string, values map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]recordCacheEntry, recordCacheSize)
+	}
+	if len(c.entries) >= recordCacheSize {
+		for id, e := range c.entries {
+			if time.Since(e.loadedAt) >= recordCacheTTL {
+				delete(c.entries, id)
+			}
+		}
+		for id := range c.entries {
+			if len(c.entries) < recordCacheSize {
+				break
+			}
+			delete(c.entries, id)
+		}
+	}
+	c.entries[recordID] = recordCacheEntry{values: values, loadedAt: time.Now()}
+}
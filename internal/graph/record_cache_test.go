@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordCache(t *testing.T) {
+	var c recordCache
+	var loads int
+	load := func() (map[string]any, error) {
+		loads++
+		return map[string]any{"n": loads}, nil
+	}
+
+	v, err := c.get("r1", load)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v["n"])
+	v, err = c.get("r1", load)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v["n"], "reused within the TTL")
+	assert.Equal(t, 1, loads)
+
+	// An expired entry is loaded again.
+	c.mu.Lock()
+	e := c.entries["r1"]
+	e.loadedAt = time.Now().Add(-recordCacheTTL)
+	c.entries["r1"] = e
+	c.mu.Unlock()
+	v, err = c.get("r1", load)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v["n"])
+
+	// Errors aren't cached.
+	boom := errors.New("boom")
+	_, err = c.get("r2", func() (map[string]any, error) { return nil, boom })
+	assert.ErrorIs(t, err, boom)
+	v, err = c.get("r2", load)
+	require.NoError(t, err)
+	assert.Equal(t, 3, v["n"])
+
+	// The cache stays bounded.
+	for i := range 2 * recordCacheSize {
+		_, err := c.get(fmt.Sprintf("bulk-%d", i), load)
+		require.NoError(t, err)
+	}
+	assert.LessOrEqual(t, len(c.entries), recordCacheSize)
+}
+
+func TestRecordCache_ConcurrentMissesLoadOnce(t *testing.T) {
+	var c recordCache
+	var mu sync.Mutex
+	loads := 0
+	release := make(chan struct{})
+	load := func() (map[string]any, error) {
+		mu.Lock()
+		loads++
+		mu.Unlock()
+		<-release
+		return map[string]any{}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = c.get("r", load)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, 1, loads)
+}
@@ -281,3 +281,15 @@ func TestContentCache_ConcurrentReadWrite(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestContentCache_Stats(t *testing.T) {
+	c := NewContentCache(2)
+	c.Put("a", []byte("x"))
+	c.Put("b", []byte("y"))
+	c.Put("c", []byte("z"))
+	_, _ = c.Get("a")
+	_, _ = c.Get("c")
+	_, _ = c.Get("c")
+
+	assert.Equal(t, CacheStats{Entries: 2, Capacity: 2, Hits: 2, Misses: 1}, c.Stats())
+}
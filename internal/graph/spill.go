@@ -0,0 +1,277 @@
+package graph
+
+import (
+	"bytes"
+	"container/list"
+	"database/sql"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// spillNodeMap keeps the most recently used nodes in memory and the rest
+// gob-encoded in a temp-file SQLite table, bounding the memory node bodies
+// take by the hot set rather than the tree. get hands out a copy, so a
+// change reaches the map only through put, whether or not the node has
+// since been evicted.
+type spillNodeMap struct {
+	mu    sync.Mutex
+	db    *sql.DB
+	path  string
+	limit int
+	count int                      // distinct nodes, hot or spilled
+	hot   map[string]*list.Element // ID → element holding a *Node
+	order *list.List               // front = most recently used
+}
+
+// newSpillNodeMap creates a spilling node map holding at most limit nodes
+// in memory.
+func newSpillNodeMap(limit int) (*spillNodeMap, error) {
+	tmp, err := os.CreateTemp("", "mache-nodes-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("create spill file: %w", err)
+	}
+	path := tmp.Name()
+	_ = tmp.Close()
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("open spill file: %w", err)
+	}
+	// One connection: every statement runs under mu, and scratch data needs
+	// no journal or fsync.
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"PRAGMA journal_mode=OFF",
+		"PRAGMA synchronous=OFF",
+		"CREATE TABLE nodes (id TEXT PRIMARY KEY, node BLOB NOT NULL)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			_ = db.Close()
+			_ = os.Remove(path)
+			return nil, fmt.Errorf("init spill file: %w", err)
+		}
+	}
+	return &spillNodeMap{
+		db:    db,
+		path:  path,
+		limit: limit,
+		hot:   make(map[string]*list.Element),
+		order: list.New(),
+	}, nil
+}
+
+func (m *spillNodeMap) get(id string) (*Node, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.hot[id]; ok {
+		m.order.MoveToFront(e)
+		n := *e.Value.(*Node)
+		return &n, true
+	}
+	var blob []byte
+	err := m.db.QueryRow("SELECT node FROM nodes WHERE id = ?", id).Scan(&blob)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("spill: read %s: %v", id, err)
+		}
+		return nil, false
+	}
+	n, err := decodeNode(blob)
+	if err != nil {
+		log.Printf("spill: decode %s: %v", id, err)
+		return nil, false
+	}
+	m.hot[id] = m.order.PushFront(n)
+	m.evict()
+	c := *n
+	return &c, true
+}
+
+func (m *spillNodeMap) put(n *Node) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := *n
+	if e, ok := m.hot[n.ID]; ok {
+		e.Value = &c
+		m.order.MoveToFront(e)
+		return
+	}
+	var one int
+	if err := m.db.QueryRow("SELECT 1 FROM nodes WHERE id = ?", n.ID).Scan(&one); errors.Is(err, sql.ErrNoRows) {
+		m.count++
+	}
+	m.hot[n.ID] = m.order.PushFront(&c)
+	m.evict()
+}
+
+func (m *spillNodeMap) del(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existed := false
+	if e, ok := m.hot[id]; ok {
+		m.order.Remove(e)
+		delete(m.hot, id)
+		existed = true
+	}
+	res, err := m.db.Exec("DELETE FROM nodes WHERE id = ?", id)
+	if err != nil {
+		log.Printf("spill: delete %s: %v", id, err)
+	} else if n, _ := res.RowsAffected(); n > 0 {
+		existed = true
+	}
+	if existed {
+		m.count--
+	}
+}
+
+func (m *spillNodeMap) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.count
+}
+
+// spillScanBatch is how many spilled nodes each reads per query.
+const spillScanBatch = 256
+
+// each visits the hot nodes, then the spilled ones in batches, without
+// promoting them into the hot set. fn gets copies, as from get, and runs
+// without mu held so it may call get.
+func (m *spillNodeMap) each(fn func(n *Node)) {
+	m.mu.Lock()
+	hot := make([]*Node, 0, len(m.hot))
+	seen := make(map[string]bool, len(m.hot))
+	for id, e := range m.hot {
+		c := *e.Value.(*Node)
+		hot = append(hot, &c)
+		seen[id] = true
+	}
+	m.mu.Unlock()
+	for _, n := range hot {
+		fn(n)
+	}
+
+	for after := ""; ; {
+		m.mu.Lock()
+		batch, last, err := m.scan(after)
+		m.mu.Unlock()
+		if err != nil {
+			log.Printf("spill: scan: %v", err)
+			return
+		}
+		for _, n := range batch {
+			if !seen[n.ID] {
+				fn(n)
+			}
+		}
+		if last == "" {
+			return
+		}
+		after = last
+	}
+}
+
+// scan decodes up to spillScanBatch spilled nodes with IDs after after, in
+// ID order, returning the last ID read or "" at the end. Caller holds mu.
+func (m *spillNodeMap) scan(after string) (nodes []*Node, last string, err error) {
+	rows, err := m.db.Query("SELECT id, node FROM nodes WHERE id > ? ORDER BY id LIMIT ?", after, spillScanBatch)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = rows.Close() }()
+	read := 0
+	for rows.Next() {
+		var id string
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return nil, "", err
+		}
+		read++
+		last = id
+		n, err := decodeNode(blob)
+		if err != nil {
+			log.Printf("spill: decode %s: %v", id, err)
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	if read < spillScanBatch {
+		last = ""
+	}
+	return nodes, last, rows.Err()
+}
+
+func decodeNode(blob []byte) (*Node, error) {
+	n := new(Node)
+	if err := gob.NewDecoder(bytes.NewReader(blob)).Decode(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (m *spillNodeMap) close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.db.Close()
+	_ = os.Remove(m.path)
+	return err
+}
+
+// evict writes back and drops the least recently used nodes once the hot
+// set is over its limit, a quarter of the limit at a time so the writes
+// batch into few transactions. Caller holds mu.
+func (m *spillNodeMap) evict() {
+	if m.order.Len() <= m.limit {
+		return
+	}
+	n := m.write(m.order.Len() - m.limit + m.limit/4)
+	for i := 0; i < n; i++ {
+		e := m.order.Back()
+		delete(m.hot, e.Value.(*Node).ID)
+		m.order.Remove(e)
+	}
+}
+
+// write stores the count least recently used hot nodes in one transaction
+// and returns how many it stored. Caller holds mu.
+func (m *spillNodeMap) write(count int) int {
+	if count <= 0 {
+		return 0
+	}
+	tx, err := m.db.Begin()
+	if err != nil {
+		log.Printf("spill: write: %v", err)
+		return 0
+	}
+	stmt, err := tx.Prepare("INSERT OR REPLACE INTO nodes (id, node) VALUES (?, ?)")
+	if err != nil {
+		_ = tx.Rollback()
+		log.Printf("spill: write: %v", err)
+		return 0
+	}
+	defer func() { _ = stmt.Close() }()
+	written := 0
+	var buf bytes.Buffer
+	for e := m.order.Back(); e != nil && written < count; e = e.Prev() {
+		n := e.Value.(*Node)
+		buf.Reset()
+		if err := gob.NewEncoder(&buf).Encode(n); err != nil {
+			log.Printf("spill: encode %s: %v", n.ID, err)
+			break
+		}
+		if _, err := stmt.Exec(n.ID, buf.Bytes()); err != nil {
+			log.Printf("spill: write %s: %v", n.ID, err)
+			break
+		}
+		written++
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("spill: write: %v", err)
+		return 0
+	}
+	return written
+}
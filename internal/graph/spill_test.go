@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillingMemoryStore(t *testing.T) {
+	s, err := NewSpillingMemoryStore(4)
+	require.NoError(t, err)
+
+	const n = 50
+	root := &Node{ID: "pkg", Mode: fs.ModeDir}
+	s.AddRoot(root)
+	for i := range n {
+		id := fmt.Sprintf("pkg/f%02d", i)
+		s.AddNode(&Node{
+			ID:         id,
+			Data:       []byte(id),
+			Properties: map[string][]byte{"i": []byte(fmt.Sprint(i))},
+			Origin:     &SourceOrigin{FilePath: fmt.Sprintf("f%d.go", i%2), StartByte: uint32(i)},
+		})
+		root.Children = append(root.Children, id)
+		s.AddNode(root)
+	}
+	assert.LessOrEqual(t, s.nodes.(*spillNodeMap).order.Len(), 4, "hot set is bounded")
+	assert.Equal(t, n+1, s.nodes.len())
+
+	// Every node round-trips through the spill file.
+	for i := range n {
+		got, err := s.GetNode(fmt.Sprintf("pkg/f%02d", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("pkg/f%02d", i), string(got.Data))
+		assert.Equal(t, fmt.Sprint(i), string(got.Properties["i"]))
+		assert.Equal(t, uint32(i), got.Origin.StartByte)
+	}
+	children, err := s.ListChildren("pkg")
+	require.NoError(t, err)
+	assert.Len(t, children, n)
+
+	// A change made through a fresh pointer survives eviction.
+	require.NoError(t, s.UpdateNodeContent("pkg/f07", []byte("edited"), nil, time.Time{}))
+	for i := range n {
+		_, _ = s.GetNode(fmt.Sprintf("pkg/f%02d", i))
+	}
+	got, err := s.GetNode("pkg/f07")
+	require.NoError(t, err)
+	assert.Equal(t, "edited", string(got.Data))
+
+	// Deleting a file's nodes also prunes them from spilled parents.
+	s.DeleteFileNodes("f0.go")
+	children, err = s.ListChildren("pkg")
+	require.NoError(t, err)
+	assert.Len(t, children, n/2)
+	_, err = s.GetNode("pkg/f00")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, n/2+1, s.nodes.len())
+
+	path := s.nodes.(*spillNodeMap).path
+	require.NoError(t, s.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "Close removes the spill file")
+}
+
+func TestSpillingMemoryStore_CopiesAndCounts(t *testing.T) {
+	s, err := NewSpillingMemoryStore(4)
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	s.AddRoot(&Node{ID: "pkg", Mode: fs.ModeDir})
+	for i := range 20 {
+		s.AddNode(&Node{ID: fmt.Sprintf("pkg/f%02d", i), Origin: &SourceOrigin{FilePath: "a.go", StartByte: uint32(10 * i), EndByte: uint32(10*i + 5)}})
+	}
+	for i := range 20 {
+		s.AddNode(&Node{ID: fmt.Sprintf("pkg/f%02d", i), Data: []byte("again")})
+	}
+	assert.Equal(t, 21, s.nodes.len(), "re-adding a spilled node doesn't count it twice")
+
+	// A node changed without AddNode is unchanged, hot or spilled.
+	n, err := s.GetNode("pkg/f00")
+	require.NoError(t, err)
+	n.Data = []byte("lost")
+	got, err := s.GetNode("pkg/f00")
+	require.NoError(t, err)
+	assert.Equal(t, "again", string(got.Data))
+
+	// Scanning every node doesn't disturb the hot set.
+	hot := s.nodes.(*spillNodeMap).order.Front().Value.(*Node).ID
+	seen := 0
+	s.nodes.each(func(*Node) { seen++ })
+	assert.Equal(t, 21, seen)
+	assert.Equal(t, hot, s.nodes.(*spillNodeMap).order.Front().Value.(*Node).ID)
+
+	s.DeleteFileNodes("a.go")
+	assert.Equal(t, 1, s.nodes.len())
+}
+
+func TestSpillingMemoryStore_ShiftOriginsPersists(t *testing.T) {
+	s, err := NewSpillingMemoryStore(2)
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	for i := range 10 {
+		s.AddNode(&Node{ID: fmt.Sprintf("f%d", i), Origin: &SourceOrigin{FilePath: "a.go", StartByte: uint32(10 * i), EndByte: uint32(10*i + 5)}})
+	}
+	s.ShiftOrigins("a.go", 0, 3)
+	for i := range 10 {
+		_, _ = s.GetNode(fmt.Sprintf("f%d", (i+5)%10))
+	}
+	got, err := s.GetNode("f4")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(43), got.Origin.StartByte, "the shift survives eviction")
+}
+
+func TestNewSpillingMemoryStore_RejectsZero(t *testing.T) {
+	_, err := NewSpillingMemoryStore(0)
+	assert.Error(t, err)
+}
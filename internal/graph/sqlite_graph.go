@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -18,7 +19,7 @@ import (
 
 	"github.com/RoaringBitmap/roaring"
 	"github.com/agentic-research/mache/api"
-	"github.com/agentic-research/mache/internal/refsvtab"
+	"golang.org/x/sync/singleflight"
 	_ "modernc.org/sqlite"
 )
 
@@ -51,6 +52,7 @@ type SQLiteGraph struct {
 	db        *sql.DB
 	dbPath    string
 	tableName string // source table name (default: "results")
+	recordCol string // JSON column of tableName (default: "record")
 	schema    *api.Topology
 	render    TemplateRenderer
 	levels    []*schemaLevel // compiled schema tree, immutable after construction
@@ -59,6 +61,10 @@ type SQLiteGraph struct {
 	// Kept separate from source DB to preserve immutability of Venturi data.
 	refsDB *sql.DB
 	dbID   string // unique ID for vtab registry
+	// refsEnabled is false when the vtab module failed to register and the
+	// sidecar was skipped: callers/ is then empty and QueryRefs fails with
+	// ErrRefsDisabled, but the projection still mounts.
+	refsEnabled bool
 
 	// Lazy scan: one pass per root node populates dirChildren + recordIDs.
 	// sync.Once ensures exactly one scan per root, even under concurrent FUSE access.
@@ -75,11 +81,16 @@ type SQLiteGraph struct {
 
 	// In-memory ref accumulator: token → bitmap of file IDs.
 	// Populated by AddRef during ingestion, written to refsDB by FlushRefs.
-	flushOnce   sync.Once
-	pendingMu   sync.Mutex
-	pendingRefs map[string]*roaring.Bitmap
-	nextFileID  uint32
-	fileIDMap   map[string]uint32 // path → file ID (in-memory during ingestion)
+	flushOnce    sync.Once
+	pendingMu    sync.Mutex
+	pendingRefs  map[string]*roaring.Bitmap
+	pendingLines map[string]map[uint32][]int // token → file ID → call-site lines
+
+	// Test index (see TestsFor), built from the defs on first use.
+	testsOnce  sync.Once
+	tests      map[string][]string // token → test construct dir IDs
+	nextFileID uint32
+	fileIDMap  map[string]uint32 // path → file ID (in-memory during ingestion)
 
 	// Size cache: file path → rendered byte length (legacy scan path only).
 	// The nodes-table fast path uses ntr.SizeCache instead.
@@ -87,6 +98,13 @@ type SQLiteGraph struct {
 
 	cache *ContentCache // FIFO-bounded rendered content (legacy scan path only)
 
+	// renders collapses concurrent cache misses for one file into a single
+	// render, so parallel chunk reads of a large leaf don't each render it.
+	renders singleflight.Group
+
+	// records shares each parsed record among the leaves rendered from it.
+	records recordCache
+
 	// Nodes-table fast path: when non-nil, all read methods delegate here.
 	// Initialized only when the DB has a "nodes" table (built by mache build).
 	ntr           *NodesTableReader
@@ -94,6 +112,10 @@ type SQLiteGraph struct {
 
 	extractor CallExtractor
 	defs      map[string][]string // symbol_name → []construct_dir_id (populated by AddDef)
+
+	// unnamed routes records whose name fields are NULL or render empty to
+	// <parent>/_unnamed/<record id> instead of dropping them.
+	unnamed bool
 }
 
 // schemaLevel is a compiled representation of one level in the schema tree.
@@ -105,6 +127,12 @@ type schemaLevel struct {
 	children   []*schemaLevel
 	files      []api.Leaf
 	depth      int
+	leftDelim  string // schema's template left delimiter
+}
+
+// isStaticFile reports whether the level's file f has a fixed name.
+func (l *schemaLevel) isStaticFile(f api.Leaf) bool {
+	return !strings.Contains(f.Name, l.leftDelim)
 }
 
 // EagerScan pre-scans all root nodes so no FUSE callback ever blocks on a scan.
@@ -139,25 +167,24 @@ func OpenSQLiteGraph(dbPath string, schema *api.Topology, render TemplateRendere
 		useNodesTable = true
 	}
 
-	tableName := schema.Table
-	if tableName == "" {
-		tableName = "results"
-	}
+	tableName, recordCol := schema.RecordSource()
 
 	// When the main DB has a nodes table (built by mache build), node_refs
 	// is already present with (token, node_id) pairs. No sidecar needed.
 	if useNodesTable {
 		levels := compileLevels(schema)
-		ntr := NewNodesTableReader(db, tableName, render, levels, 0o444, 0o555, 2048)
+		ntr := NewNodesTableReader(db, tableName, recordCol, render, levels, 0o444, 0o555, 2048)
 		return &SQLiteGraph{
 			db:            db,
 			dbPath:        dbPath,
 			tableName:     tableName,
+			recordCol:     recordCol,
 			schema:        schema,
 			render:        render,
 			levels:        levels,
 			ntr:           ntr,
 			useNodesTable: true,
+			refsEnabled:   true,
 		}, nil
 	}
 
@@ -173,10 +200,21 @@ func OpenSQLiteGraph(dbPath string, schema *api.Topology, render TemplateRendere
 	// Register the mache_refs vtab module globally before opening refsDB.
 	// sql.Open is lazy (no connection until first query), so registering
 	// before the first Exec ensures the new connection sees the module.
-	refsMod, err := refsvtab.Register()
+	// The refs index is optional: without the module, mount the projection
+	// and leave callers/ and QueryRefs disabled.
+	refsMod, err := registerRefs()
 	if err != nil {
-		_ = db.Close() // ignore error
-		return nil, err
+		log.Printf("Warning: %v; mounting without callers/ or the SQL refs index", err)
+		return &SQLiteGraph{
+			db:        db,
+			dbPath:    dbPath,
+			tableName: tableName,
+			recordCol: recordCol,
+			schema:    schema,
+			render:    render,
+			levels:    compileLevels(schema),
+			cache:     NewContentCache(2048),
+		}, nil
 	}
 
 	refsDB, err := sql.Open("sqlite", refsPath)
@@ -205,6 +243,12 @@ func OpenSQLiteGraph(dbPath string, schema *api.Topology, render TemplateRendere
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			path TEXT UNIQUE NOT NULL
 		);
+		CREATE TABLE IF NOT EXISTS ref_lines (
+			token TEXT NOT NULL,
+			file_id INTEGER NOT NULL,
+			lines TEXT NOT NULL,
+			PRIMARY KEY (token, file_id)
+		);
 	`)
 	if err != nil {
 		_ = db.Close()     // ignore error
@@ -228,6 +272,7 @@ func OpenSQLiteGraph(dbPath string, schema *api.Topology, render TemplateRendere
 		db:            db,
 		dbPath:        dbPath,
 		tableName:     tableName,
+		recordCol:     recordCol,
 		schema:        schema,
 		render:        render,
 		levels:        compileLevels(schema),
@@ -237,30 +282,32 @@ func OpenSQLiteGraph(dbPath string, schema *api.Topology, render TemplateRendere
 		fileIDMap:     make(map[string]uint32),
 		cache:         NewContentCache(2048),
 		useNodesTable: false,
+		refsEnabled:   true,
 	}, nil
 }
 
 func compileLevels(schema *api.Topology) []*schemaLevel {
 	var out []*schemaLevel
 	for _, node := range schema.Nodes {
-		out = append(out, compileOneLevel(node, 0))
+		out = append(out, compileOneLevel(schema, node, 0))
 	}
 	return out
 }
 
-func compileOneLevel(node api.Node, depth int) *schemaLevel {
+func compileOneLevel(schema *api.Topology, node api.Node, depth int) *schemaLevel {
 	l := &schemaLevel{
 		nameRaw:  node.Name,
 		selector: node.Selector,
 		files:    node.Files,
 		depth:    depth,
 	}
-	if !strings.Contains(node.Name, "{{") {
+	l.leftDelim, _ = schema.Delims()
+	if !schema.IsTemplate(node.Name) {
 		l.isStatic = true
 		l.staticName = node.Name
 	}
 	for _, child := range node.Children {
-		l.children = append(l.children, compileOneLevel(child, depth+1))
+		l.children = append(l.children, compileOneLevel(schema, child, depth+1))
 	}
 	return l
 }
@@ -269,6 +316,13 @@ func compileOneLevel(node api.Node, depth int) *schemaLevel {
 // Graph interface
 // ---------------------------------------------------------------------------
 
+// SetUnnamedBucket makes the scan keep records whose name-template fields
+// are NULL (or render to nothing) under <parent>/_unnamed/<record id>/
+// rather than dropping them. Must be called before the first scan.
+func (g *SQLiteGraph) SetUnnamedBucket(on bool) {
+	g.unnamed = on
+}
+
 // SetCallExtractor configures the parser for on-demand callee resolution.
 func (g *SQLiteGraph) SetCallExtractor(fn CallExtractor) {
 	g.extractor = fn
@@ -296,6 +350,15 @@ func (g *SQLiteGraph) DefsMap() map[string][]string {
 	return cp
 }
 
+// SourceFile implements SourceFileLocator. Only the nodes-table fast path
+// records source files; record-backed graphs have none.
+func (g *SQLiteGraph) SourceFile(id string) (string, bool) {
+	if !g.useNodesTable {
+		return "", false
+	}
+	return g.ntr.SourceFile(id)
+}
+
 func (g *SQLiteGraph) GetNode(id string) (*Node, error) {
 	if g.useNodesTable {
 		return g.ntr.GetNode(id)
@@ -315,6 +378,8 @@ func (g *SQLiteGraph) GetNode(id string) (*Node, error) {
 	// File node — return cached size if available (avoids content render for stat).
 	// First call renders content and populates both contentCache and sizeCache.
 	// Subsequent calls return a lightweight node with ContentRef (no SQL/render).
+	// Large leaves that are a bare string field are sized from the record
+	// instead, and rendered only when read.
 	if fileLeaf != nil {
 		if cachedSize, ok := g.sizeCache.Load(id); ok {
 			return &Node{
@@ -323,6 +388,10 @@ func (g *SQLiteGraph) GetNode(id string) (*Node, error) {
 				Ref:  &ContentRef{ContentLen: cachedSize.(int64)},
 			}, nil
 		}
+		if n, ok := g.storedContentLen(segments, fileLeaf); ok && n >= lazyContentSize {
+			g.sizeCache.Store(id, n)
+			return &Node{ID: id, Mode: 0o444, Ref: &ContentRef{ContentLen: n}}, nil
+		}
 		content, err := g.resolveContent(id, segments, fileLeaf)
 		if err != nil {
 			return nil, err
@@ -431,8 +500,7 @@ func (g *SQLiteGraph) ListChildStats(id string) ([]NodeStat, error) {
 		// Check if this child name matches a file leaf at the current schema level
 		if level != nil {
 			for _, f := range level.files {
-				fname := f.Name
-				if !strings.Contains(fname, "{{") && fname == childBase {
+				if level.isStaticFile(f) && f.Name == childBase {
 					isFile = true
 					break
 				}
@@ -486,9 +554,10 @@ func (g *SQLiteGraph) ReadContent(id string, buf []byte, offset int64) (int, err
 // This eliminates the read-modify-write cycle per call — all bitmap mutations
 // happen in RAM, and FlushRefs writes them in a single transaction.
 // Not used for nodes-table path (refs already in main DB from mache build).
-func (g *SQLiteGraph) AddRef(token, nodeID string) error {
-	if g.useNodesTable {
-		return nil // refs already in main DB
+// Call-site lines go to the ref_lines table.
+func (g *SQLiteGraph) AddRef(token, nodeID string, lines ...int) error {
+	if g.useNodesTable || !g.refsEnabled {
+		return nil // refs already in main DB, or disabled
 	}
 	g.pendingMu.Lock()
 	defer g.pendingMu.Unlock()
@@ -506,6 +575,17 @@ func (g *SQLiteGraph) AddRef(token, nodeID string) error {
 		g.pendingRefs[token] = bm
 	}
 	bm.Add(fid)
+	if len(lines) > 0 {
+		if g.pendingLines == nil {
+			g.pendingLines = make(map[string]map[uint32][]int)
+		}
+		byFile := g.pendingLines[token]
+		if byFile == nil {
+			byFile = make(map[uint32][]int)
+			g.pendingLines[token] = byFile
+		}
+		byFile[fid] = append(byFile[fid], lines...)
+	}
 	return nil
 }
 
@@ -525,8 +605,12 @@ func (g *SQLiteGraph) FlushRefs() error {
 }
 
 func (g *SQLiteGraph) flushRefsInternal() error {
+	if !g.refsEnabled {
+		return nil
+	}
 	g.pendingMu.Lock()
 	refs := g.pendingRefs
+	refLines := g.pendingLines
 	fileIDs := g.fileIDMap
 	g.pendingMu.Unlock()
 
@@ -571,9 +655,42 @@ func (g *SQLiteGraph) flushRefsInternal() error {
 		}
 	}
 
+	if len(refLines) > 0 {
+		linesStmt, err := tx.Prepare("INSERT OR REPLACE INTO ref_lines (token, file_id, lines) VALUES (?, ?, ?)")
+		if err != nil {
+			return fmt.Errorf("prepare ref_lines insert: %w", err)
+		}
+		defer func() { _ = linesStmt.Close() }() // safe to ignore
+		for token, byFile := range refLines {
+			for fid, lines := range byFile {
+				if _, err := linesStmt.Exec(token, fid, FormatLines(lines)); err != nil {
+					return fmt.Errorf("insert ref lines %s: %w", token, err)
+				}
+			}
+		}
+	}
+
 	return tx.Commit()
 }
 
+// CallSiteLines implements CallSiteLocator: from node_refs' lines column
+// for a DB built by mache build, otherwise from the sidecar's ref_lines.
+func (g *SQLiteGraph) CallSiteLines(token, nodeID string) []int {
+	if g.useNodesTable {
+		return g.ntr.CallSiteLines(token, nodeID)
+	}
+	if !g.refsEnabled {
+		return nil
+	}
+	var lines string
+	err := g.refsDB.QueryRow(`SELECT l.lines FROM ref_lines l JOIN file_ids f ON f.id = l.file_id
+		WHERE l.token = ? AND f.path = ?`, token, nodeID).Scan(&lines)
+	if err != nil {
+		return nil
+	}
+	return ParseLines(lines)
+}
+
 // GetCallers returns the list of files (nodes) that reference the given token.
 // For nodes-table path: queries main DB's node_refs (token, node_id) directly.
 // For legacy path: reads roaring bitmaps from the sidecar refs database.
@@ -581,23 +698,62 @@ func (g *SQLiteGraph) GetCallers(token string) ([]*Node, error) {
 	if g.useNodesTable {
 		return g.getCallersFromMainDB(token)
 	}
+	if !g.refsEnabled {
+		return nil, nil
+	}
 	return g.getCallersFromSidecar(token)
 }
 
-// GetCallees implements Graph.
+// GetCallees implements Graph. For a DB built by mache build, the calls
+// extracted from the construct's source are joined by the definitions it
+// references according to an index imported with mache import.
 func (g *SQLiteGraph) GetCallees(id string) ([]*Node, error) {
 	id = NormalizeID(id)
+	nodes, err := g.extractedCallees(id)
+	if err != nil || !g.useNodesTable {
+		return nodes, err
+	}
+	return g.appendImportedCallees(id, nodes), nil
+}
 
-	// 1. Find the "source" file child
+// appendImportedCallees appends to nodes the constructs that id's source
+// references according to the imported_refs table, if the DB has one.
+func (g *SQLiteGraph) appendImportedCallees(id string, nodes []*Node) []*Node {
+	leaf := id + "/" + SourceLeafOf(g.constructProps(id))
+	rows, err := g.db.Query("SELECT dir_id FROM imported_refs WHERE node_id = ?", leaf)
+	if err != nil {
+		return nodes // no index imported
+	}
+	defer func() { _ = rows.Close() }()
+	seen := map[string]bool{id: true}
+	for _, n := range nodes {
+		seen[n.ID] = true
+	}
+	for rows.Next() {
+		var defID string
+		if rows.Scan(&defID) == nil && !seen[defID] {
+			seen[defID] = true
+			nodes = append(nodes, &Node{ID: defID, Mode: os.ModeDir | 0o555})
+		}
+	}
+	return nodes
+}
+
+// extractedCallees resolves the calls extracted from id's source to the
+// constructs defining them.
+func (g *SQLiteGraph) extractedCallees(id string) ([]*Node, error) {
+	// 1. Find the code leaf
 	children, err := g.ListChildren(id)
 	if err != nil {
 		return nil, nil
 	}
 
+	props := g.constructProps(id)
+	leaf := SourceLeafOf(props)
 	var sourceID string
 	for _, child := range children {
 		base := filepath.Base(child)
-		if base == "source" {
+		if base == leaf {
 			// nodes-table path returns bare names; sidecar path returns full paths
 			if g.useNodesTable {
 				sourceID = id + "/" + child
@@ -625,19 +781,7 @@ func (g *SQLiteGraph) GetCallees(id string) ([]*Node, error) {
 	}
 
 	// 3. Determine langName from construct node Properties (stored in record column)
-	var langName string
-	if g.useNodesTable {
-		var recordJSON sql.NullString
-		_ = g.db.QueryRow("SELECT record FROM nodes WHERE id = ? AND kind = 1", id).Scan(&recordJSON)
-		if recordJSON.Valid && recordJSON.String != "" {
-			var props map[string][]byte
-			if json.Unmarshal([]byte(recordJSON.String), &props) == nil {
-				if lang, ok := props["lang"]; ok {
-					langName = string(lang)
-				}
-			}
-		}
-	}
+	langName := string(props["lang"])
 
 	// 4. Extract qualified calls
 	if g.extractor == nil {
@@ -744,6 +888,139 @@ func (g *SQLiteGraph) GetCallees(id string) ([]*Node, error) {
 	return nodes, nil
 }
 
+// TypesUsed implements TypeRefLocator for the nodes-table layout, reading
+// the construct's type refs from node_refs. Record-backed graphs have none.
+func (g *SQLiteGraph) TypesUsed(dirID string) []string {
+	if !g.useNodesTable {
+		return nil
+	}
+	dirID = NormalizeID(dirID)
+	srcID := FindSourceChild(g, dirID)
+	if srcID == "" {
+		return nil
+	}
+	// The token range keeps the scan on the primary key's type: prefix.
+	rows, err := g.db.Query("SELECT token FROM node_refs WHERE token >= ? AND token < ? AND node_id = ?",
+		TypeRefPrefix, typeRefPrefixEnd, srcID)
+	if err != nil {
+		return nil
+	}
+	var tokens []string
+	for rows.Next() {
+		var tok string
+		if rows.Scan(&tok) == nil {
+			tokens = append(tokens, tok)
+		}
+	}
+	_ = rows.Close()
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var pkg string
+	var recordJSON sql.NullString
+	_ = g.db.QueryRow("SELECT record FROM nodes WHERE id = ? AND kind = 1", dirID).Scan(&recordJSON)
+	if recordJSON.Valid && recordJSON.String != "" {
+		var props map[string][]byte
+		if json.Unmarshal([]byte(recordJSON.String), &props) == nil {
+			pkg = string(props["pkg"])
+		}
+	}
+	return resolveTypeRefs(dirID, pkg, tokens, g.lookupDefs)
+}
+
+// lookupDefs returns the dir IDs defining token, from the in-process defs
+// index or else the node_defs table of a pre-built DB.
+func (g *SQLiteGraph) lookupDefs(token string) []string {
+	g.pendingMu.Lock()
+	ids := slices.Clone(g.defs[token])
+	g.pendingMu.Unlock()
+	if len(ids) > 0 {
+		return ids
+	}
+	rows, err := g.db.Query("SELECT dir_id FROM node_defs WHERE token = ?", token)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// TestsFor implements TestLocator. Tests are found by the language's
+// TestNamer among the defs added during ingestion and, for a DB built by
+// mache build, the node_defs table, indexed the first time it is called.
+func (g *SQLiteGraph) TestsFor(token string) []string {
+	g.testsOnce.Do(g.indexTests)
+	ids := slices.Clone(g.tests[token])
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+func (g *SQLiteGraph) indexTests() {
+	g.tests = make(map[string][]string)
+	add := func(name, dirID string) {
+		if !isTestName(name) {
+			return
+		}
+		for _, subject := range testSubjects(g.constructLang(dirID), name) {
+			g.tests[subject] = append(g.tests[subject], dirID)
+		}
+	}
+	for name, ids := range g.DefsMap() {
+		for _, id := range ids {
+			add(name, id)
+		}
+	}
+	if !g.useNodesTable {
+		return
+	}
+	rows, err := g.db.Query("SELECT token, dir_id FROM node_defs")
+	if err != nil {
+		return
+	}
+	var defs [][2]string
+	for rows.Next() {
+		var token, dirID string
+		if rows.Scan(&token, &dirID) == nil {
+			defs = append(defs, [2]string{token, dirID})
+		}
+	}
+	_ = rows.Close()
+	for _, d := range defs {
+		add(d[0], d[1])
+	}
+}
+
+// constructLang returns the "lang" property of a construct directory in
+// the nodes table, "" when it has none.
+func (g *SQLiteGraph) constructLang(dirID string) string {
+	return string(g.constructProps(dirID)["lang"])
+}
+
+// constructProps returns the Properties of construct directory dirID, kept
+// as JSON in its record column, or nil outside the nodes-table path.
+func (g *SQLiteGraph) constructProps(dirID string) map[string][]byte {
+	if !g.useNodesTable {
+		return nil
+	}
+	var recordJSON sql.NullString
+	_ = g.db.QueryRow("SELECT record FROM nodes WHERE id = ? AND kind = 1", dirID).Scan(&recordJSON)
+	if !recordJSON.Valid || recordJSON.String == "" {
+		return nil
+	}
+	var props map[string][]byte
+	if json.Unmarshal([]byte(recordJSON.String), &props) != nil {
+		return nil
+	}
+	return props
+}
+
 // getCallersFromMainDB queries the main DB's node_refs table directly.
 // node_refs schema: (token TEXT, node_id TEXT) — written by mache build.
 func (g *SQLiteGraph) getCallersFromMainDB(token string) ([]*Node, error) {
@@ -817,6 +1094,18 @@ func (g *SQLiteGraph) Invalidate(id string) {
 	}
 }
 
+// CacheStats reports the rendered content cache of whichever read path is
+// active.
+func (g *SQLiteGraph) CacheStats() CacheStats {
+	if g.ntr != nil {
+		return g.ntr.cache.Stats()
+	}
+	if g.cache != nil {
+		return g.cache.Stats()
+	}
+	return CacheStats{}
+}
+
 // QueryRefs executes a SQL query against the refs database.
 // For nodes-table path: queries the main DB (node_refs has (token, node_id)).
 // For legacy path: queries the sidecar (includes mache_refs virtual table).
@@ -824,6 +1113,9 @@ func (g *SQLiteGraph) QueryRefs(query string, args ...any) (*sql.Rows, error) {
 	if g.useNodesTable {
 		return g.db.Query(query, args...)
 	}
+	if !g.refsEnabled {
+		return nil, ErrRefsDisabled
+	}
 	return g.refsDB.Query(query, args...)
 }
 
@@ -907,7 +1199,7 @@ func (g *SQLiteGraph) refsMapFromBitmaps() map[string][]string {
 func (g *SQLiteGraph) Close() error {
 	// Unregister from vtab module to prevent leaks/races (sidecar path only)
 	if g.refsDB != nil {
-		if mod, err := refsvtab.Register(); err == nil && mod != nil {
+		if mod, err := registerRefs(); err == nil && mod != nil {
 			mod.UnregisterDB(g.dbID)
 		}
 	}
@@ -956,10 +1248,20 @@ func walkSchemaLevels(levels []*schemaLevel, segments []string) (*schemaLevel, *
 	for i := 1; i < len(segments); i++ {
 		seg := segments[i]
 
+		// _unnamed/<record id> stands in for one templated level (see
+		// SQLiteGraph.SetUnnamedBucket), so it spans two segments.
+		if seg == UnnamedDir && len(current.children) > 0 {
+			if i == len(segments)-1 {
+				return current, nil
+			}
+			i++
+			current = current.children[0]
+			continue
+		}
+
 		// Check if this segment matches a file at the current level
 		for j := range current.files {
-			fname := current.files[j].Name
-			if !strings.Contains(fname, "{{") && fname == seg {
+			if current.isStaticFile(current.files[j]) && current.files[j].Name == seg {
 				return current, &current.files[j]
 			}
 		}
@@ -1001,6 +1303,7 @@ func (g *SQLiteGraph) ensureScanned(rootName string) error {
 type scanResult struct {
 	entries  []pathEntry
 	leafDirs []leafMapping
+	unnamed  bool // some level fell back to _unnamed/<record id>
 }
 
 type pathEntry struct {
@@ -1053,11 +1356,11 @@ func extractFieldPaths(templates []string) []string {
 
 // buildScanQuery builds a SELECT using json_extract for only the fields
 // needed by name templates. Avoids transferring and parsing full record JSON.
-func buildScanQuery(fieldPaths []string, tableName string) string {
+func buildScanQuery(fieldPaths []string, tableName, recordCol string) string {
 	cols := make([]string, 0, len(fieldPaths)+1)
 	cols = append(cols, "id")
 	for _, fp := range fieldPaths {
-		cols = append(cols, fmt.Sprintf("json_extract(record, '$.%s')", fp))
+		cols = append(cols, fmt.Sprintf("json_extract(%s, '$.%s')", recordCol, fp))
 	}
 	return "SELECT " + strings.Join(cols, ", ") + " FROM " + tableName
 }
@@ -1124,7 +1427,7 @@ func (g *SQLiteGraph) scanRoot(rootName string) error {
 
 	// Analyze schema to find which fields the name templates need
 	fieldPaths := extractFieldPaths(collectNameTemplates(level))
-	query := buildScanQuery(fieldPaths, g.tableName)
+	query := buildScanQuery(fieldPaths, g.tableName, g.recordCol)
 
 	// Read-only transaction for snapshot consistency — if the source DB is
 	// being written to during scan, we get a consistent point-in-time view.
@@ -1161,16 +1464,18 @@ func (g *SQLiteGraph) scanRoot(rootName string) error {
 	count := 0
 	scanErrs := 0
 	nullSkips := 0
+	unnamed := 0
 	for rows.Next() {
 		if err := rows.Scan(scanPtrs...); err != nil {
 			scanErrs++
 			continue
 		}
 
-		// Check for NULL fields (records missing required template values)
+		// Check for NULL fields (records missing required template values).
+		// With the unnamed bucket they render empty and land in _unnamed/.
 		skip := false
 		for i := range fieldPaths {
-			if !scanVals[i+1].Valid {
+			if !scanVals[i+1].Valid && !g.unnamed {
 				skip = true
 				break
 			}
@@ -1189,7 +1494,11 @@ func (g *SQLiteGraph) scanRoot(rootName string) error {
 
 		result.entries = result.entries[:0]
 		result.leafDirs = result.leafDirs[:0]
+		result.unnamed = false
 		g.collectPathEntries(level, values, rootName, scanVals[0].String, &result)
+		if result.unnamed {
+			unnamed++
+		}
 
 		for _, e := range result.entries {
 			childSlices[e.parent] = append(childSlices[e.parent], e.child)
@@ -1228,6 +1537,9 @@ func (g *SQLiteGraph) scanRoot(rootName string) error {
 		log.Printf("scan %q: %d records processed, %d scan errors, %d null-skipped",
 			rootName, count, scanErrs, nullSkips)
 	}
+	if unnamed > 0 {
+		log.Printf("scan %q: %d records without a name placed under %s/", rootName, unnamed, UnnamedDir)
+	}
 
 	// Final flush of remaining data
 	flushChildSlices(childSlices, &g.dirChildren)
@@ -1268,13 +1580,20 @@ func flushChildSlices(slices map[string][]string, target *sync.Map) {
 // parent→child entries and leaf directory→recordID mappings.
 func (g *SQLiteGraph) collectPathEntries(level *schemaLevel, values map[string]any, parentPath, recordID string, result *scanResult) {
 	for _, child := range level.children {
+		dir := parentPath
 		name, err := g.render(child.nameRaw, values)
-		if err != nil || name == "" {
-			continue
+		if err != nil || IsBlankName(name) {
+			if !g.unnamed || child.isStatic {
+				continue
+			}
+			dir = parentPath + "/" + UnnamedDir
+			result.entries = append(result.entries, pathEntry{parent: parentPath, child: dir})
+			name = recordID
+			result.unnamed = true
 		}
 
-		childPath := parentPath + "/" + name
-		result.entries = append(result.entries, pathEntry{parent: parentPath, child: childPath})
+		childPath := dir + "/" + name
+		result.entries = append(result.entries, pathEntry{parent: dir, child: childPath})
 
 		// Recurse into deeper directory levels
 		if len(child.children) > 0 {
@@ -1291,6 +1610,12 @@ func (g *SQLiteGraph) collectPathEntries(level *schemaLevel, values map[string]a
 	}
 }
 
+// IsBlankName reports whether a rendered name template produced no usable
+// name: nothing, or text/template's "<no value>" for a missing field.
+func IsBlankName(name string) bool {
+	return name == "" || name == "<no value>"
+}
+
 func (g *SQLiteGraph) findRootLevel(name string) *schemaLevel {
 	for _, l := range g.levels {
 		if l.isStatic && l.staticName == name {
@@ -1325,7 +1650,18 @@ func (g *SQLiteGraph) resolveContent(filePath string, segments []string, leaf *a
 	if c, ok := g.cache.Get(filePath); ok {
 		return c, nil
 	}
+	v, err, _ := g.renders.Do(filePath, func() (any, error) {
+		return g.renderContent(filePath, segments, leaf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
 
+// renderContent fetches the record behind filePath and renders leaf from
+// it, caching the result.
+func (g *SQLiteGraph) renderContent(filePath string, segments []string, leaf *api.Leaf) ([]byte, error) {
 	var content []byte
 	{
 		// Legacy mode: find parent directory's record ID
@@ -1341,16 +1677,12 @@ func (g *SQLiteGraph) resolveContent(filePath string, segments []string, leaf *a
 		recordID := ridVal.(string)
 
 		// Fetch record from source DB (primary key lookup — instant)
-		var raw string
-		if err := g.db.QueryRow("SELECT record FROM "+g.tableName+" WHERE id = ?", recordID).Scan(&raw); err != nil {
-			return nil, fmt.Errorf("fetch record %s: %w", recordID, err)
-		}
-
-		var parsed any
-		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
-			return nil, fmt.Errorf("parse record %s: %w", recordID, err)
+		values, err := g.records.get(recordID, func() (map[string]any, error) {
+			return fetchRecord(g.db, g.tableName, g.recordCol, recordID)
+		})
+		if err != nil {
+			return nil, err
 		}
-		values, _ := parsed.(map[string]any)
 
 		rendered, err := g.render(leaf.ContentTemplate, values)
 		if err != nil {
@@ -1364,6 +1696,45 @@ func (g *SQLiteGraph) resolveContent(filePath string, segments []string, leaf *a
 	return content, nil
 }
 
+// lazyContentSize is the leaf size from which GetNode stats a file from its
+// stored length rather than rendering it.
+const lazyContentSize = 1 << 20
+
+// bareFieldRe matches a content template that is a single field reference
+// between delimiters, e.g. "item.description" in "{{.item.description}}".
+var bareFieldRe = regexp.MustCompile(`^\s*\.([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)\s*$`)
+
+// storedContentLen returns the byte length leaf renders to for the file at
+// segments without rendering it, when that can be read off the record: the
+// template is a bare field reference and the field holds a string.
+func (g *SQLiteGraph) storedContentLen(segments []string, leaf *api.Leaf) (int64, bool) {
+	left, right := g.schema.Delims()
+	tmpl := leaf.ContentTemplate
+	if len(tmpl) < len(left)+len(right) || !strings.HasPrefix(tmpl, left) || !strings.HasSuffix(tmpl, right) {
+		return 0, false
+	}
+	m := bareFieldRe.FindStringSubmatch(tmpl[len(left) : len(tmpl)-len(right)])
+	if m == nil {
+		return 0, false
+	}
+	if err := g.ensureScanned(segments[0]); err != nil {
+		return 0, false
+	}
+	ridVal, ok := g.recordIDs.Load(strings.Join(segments[:len(segments)-1], "/"))
+	if !ok {
+		return 0, false
+	}
+
+	var typ sql.NullString
+	var n sql.NullInt64
+	query := fmt.Sprintf("SELECT json_type(%[1]s, '$.%[2]s'), length(CAST(json_extract(%[1]s, '$.%[2]s') AS BLOB)) FROM %[3]s WHERE id = ?",
+		g.recordCol, m[1], g.tableName)
+	if err := g.db.QueryRow(query, ridVal.(string)).Scan(&typ, &n); err != nil || typ.String != "text" || !n.Valid {
+		return 0, false
+	}
+	return n.Int64, true
+}
+
 // Act returns ErrActNotSupported — SQLiteGraph is a passive data graph.
 func (g *SQLiteGraph) Act(id, action, payload string) (*ActionResult, error) {
 	return nil, ErrActNotSupported
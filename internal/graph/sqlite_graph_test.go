@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"text/template"
 
 	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/refsvtab"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	_ "modernc.org/sqlite"
@@ -59,6 +62,10 @@ func createTestDB(t *testing.T, records map[string]string) string {
 }
 
 func createTestDBWithTable(t *testing.T, tableName string, records map[string]string) string {
+	return createTestDBWithColumn(t, tableName, "record", records)
+}
+
+func createTestDBWithColumn(t *testing.T, tableName, column string, records map[string]string) string {
 	t.Helper()
 	dir := t.TempDir()
 	dbPath := filepath.Join(dir, "test.db")
@@ -69,11 +76,11 @@ func createTestDBWithTable(t *testing.T, tableName string, records map[string]st
 	}
 	defer func() { _ = db.Close() }()
 
-	if _, err := db.Exec(fmt.Sprintf("CREATE TABLE %s (id TEXT PRIMARY KEY, record TEXT NOT NULL)", tableName)); err != nil {
+	if _, err := db.Exec(fmt.Sprintf("CREATE TABLE %s (id TEXT PRIMARY KEY, %s TEXT NOT NULL)", tableName, column)); err != nil {
 		t.Fatal(err)
 	}
 	for id, rec := range records {
-		if _, err := db.Exec(fmt.Sprintf("INSERT INTO %s (id, record) VALUES (?, ?)", tableName), id, rec); err != nil {
+		if _, err := db.Exec(fmt.Sprintf("INSERT INTO %s (id, %s) VALUES (?, ?)", tableName, column), id, rec); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -396,6 +403,114 @@ func TestSQLiteGraph_NVD_RawJSON(t *testing.T) {
 	}
 }
 
+func TestSQLiteGraph_UnnamedBucket(t *testing.T) {
+	dbPath := createTestDB(t, map[string]string{
+		"r1": `{"item":{"cveID":"CVE-2024-0001","vendorProject":"Acme"}}`,
+		"r2": `{"item":{"vendorProject":"Nameless"}}`,
+		"r3": `{"item":{"cveID":"","vendorProject":"Blank"}}`,
+	})
+
+	// Default: records without a name are dropped.
+	g, err := OpenSQLiteGraph(dbPath, kevSchema(), testRender)
+	require.NoError(t, err)
+	children, err := g.ListChildren("vulns")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vulns/CVE-2024-0001"}, children)
+	require.NoError(t, g.Close())
+
+	g, err = OpenSQLiteGraph(dbPath, kevSchema(), testRender)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	g.SetUnnamedBucket(true)
+
+	children, err = g.ListChildren("vulns")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vulns/CVE-2024-0001", "vulns/_unnamed"}, children)
+	children, err = g.ListChildren("vulns/_unnamed")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vulns/_unnamed/r2", "vulns/_unnamed/r3"}, children)
+
+	node, err := g.GetNode("vulns/_unnamed/r2")
+	require.NoError(t, err)
+	assert.True(t, node.Mode.IsDir())
+	buf := make([]byte, 64)
+	n, err := g.ReadContent("vulns/_unnamed/r2/vendor", buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Nameless", string(buf[:n]))
+	n, err = g.ReadContent("vulns/_unnamed/r3/vendor", buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Blank", string(buf[:n]))
+}
+
+func TestSQLiteGraph_CustomDelims(t *testing.T) {
+	dbPath := createTestDB(t, map[string]string{
+		"r1": `{"item":{"cveID":"CVE-2024-0001","vendorProject":"Acme"}}`,
+	})
+	schema := &api.Topology{
+		Version:    "v1",
+		LeftDelim:  "[[",
+		RightDelim: "]]",
+		Nodes: []api.Node{{
+			Name:     "vulns",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "[[.item.cveID]]",
+				Selector: "$[*]",
+				Files:    []api.Leaf{{Name: "banner", ContentTemplate: "{{ vendor }}=[[.item.vendorProject]]"}},
+			}},
+		}},
+	}
+	render := func(tmpl string, values map[string]any) (string, error) {
+		t, err := template.New("").Delims("[[", "]]").Parse(tmpl)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		err = t.Execute(&buf, values)
+		return buf.String(), err
+	}
+
+	g, err := OpenSQLiteGraph(dbPath, schema, render)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+
+	children, err := g.ListChildren("vulns")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vulns/CVE-2024-0001"}, children)
+	stats, err := g.ListChildStats("vulns/CVE-2024-0001")
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.False(t, stats[0].IsDir, "banner has a static name under [[ ]] delimiters")
+	buf := make([]byte, 64)
+	n, err := g.ReadContent("vulns/CVE-2024-0001/banner", buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "{{ vendor }}=Acme", string(buf[:n]))
+}
+
+func TestSQLiteGraph_UnnamedBucket_NestedLevels(t *testing.T) {
+	dbPath := createTestDB(t, map[string]string{
+		"CVE-2024-0001": `{"item":{"cve":{"id":"CVE-2024-0001","published":"2024-01-15T00:00:00Z","vulnStatus":"Analyzed"}}}`,
+		"CVE-2024-0009": `{"item":{"cve":{"id":"CVE-2024-0009","vulnStatus":"Received"}}}`,
+	})
+	g, err := OpenSQLiteGraph(dbPath, nvdSchema(), testRender)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	g.SetUnnamedBucket(true)
+
+	// Both date levels fail, so each gets its own bucket; the ID level still names it.
+	dir := "by-cve/_unnamed/CVE-2024-0009/_unnamed/CVE-2024-0009/CVE-2024-0009"
+	node, err := g.GetNode(dir)
+	require.NoError(t, err)
+	assert.True(t, node.Mode.IsDir())
+	buf := make([]byte, 64)
+	n, err := g.ReadContent(dir+"/status", buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Received", string(buf[:n]))
+
+	_, err = g.GetNode("by-cve/2024/01/CVE-2024-0001")
+	assert.NoError(t, err)
+}
+
 func TestSQLiteGraph_LeadingSlashNormalization(t *testing.T) {
 	dbPath := createTestDB(t, map[string]string{
 		"CVE-2024-0001": `{"schema":"kev","identifier":"CVE-2024-0001","item":{"cveID":"CVE-2024-0001","vendorProject":"Acme","product":"Widget","shortDescription":"test"}}`,
@@ -702,6 +817,140 @@ func BenchmarkScanRoot_Synthetic(b *testing.B) {
 	}
 }
 
+// largeLeafDB builds a one-record KEV database whose shortDescription is
+// size bytes.
+func largeLeafDB(tb testing.TB, size int) (dbPath, content string) {
+	tb.Helper()
+	content = strings.Repeat("0123456789abcdef", size/16)
+	rec, err := json.Marshal(map[string]any{"item": map[string]any{
+		"cveID": "CVE-2024-0001", "vendorProject": "Acme", "shortDescription": content,
+	}})
+	require.NoError(tb, err)
+
+	dbPath = filepath.Join(tb.TempDir(), "large.db")
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(tb, err)
+	defer func() { _ = db.Close() }()
+	_, err = db.Exec("CREATE TABLE results (id TEXT PRIMARY KEY, record TEXT NOT NULL)")
+	require.NoError(tb, err)
+	_, err = db.Exec("INSERT INTO results (id, record) VALUES (?, ?)", "CVE-2024-0001", string(rec))
+	require.NoError(tb, err)
+	return dbPath, content
+}
+
+// readChunked reads id through ReadContent in chunk-sized pieces, as an
+// editor or the NFS server does.
+func readChunked(g *SQLiteGraph, id string, size int64, chunk int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	buf := make([]byte, chunk)
+	for off := int64(0); off < size; {
+		n, err := g.ReadContent(id, buf, off)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			break
+		}
+		out = append(out, buf[:n]...)
+		off += int64(n)
+	}
+	return out, nil
+}
+
+func TestSQLiteGraph_LargeLeaf_StatWithoutRender(t *testing.T) {
+	dbPath, content := largeLeafDB(t, 2<<20)
+	g, err := OpenSQLiteGraph(dbPath, kevSchema(), testRender)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+
+	const id = "vulns/CVE-2024-0001/description"
+	node, err := g.GetNode(id)
+	require.NoError(t, err)
+	assert.Nil(t, node.Data, "a large leaf is stat'ed without rendering it")
+	require.NotNil(t, node.Ref)
+	assert.Equal(t, int64(len(content)), node.Ref.ContentLen)
+	assert.Zero(t, g.CacheStats().Entries)
+
+	got, err := readChunked(g, id, node.Ref.ContentLen, 64<<10)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(got))
+	assert.Equal(t, 1, g.CacheStats().Entries, "rendered once for all chunks")
+
+	// Small leaves keep returning their content inline.
+	vendor, err := g.GetNode("vulns/CVE-2024-0001/vendor")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", string(vendor.Data))
+}
+
+func TestSQLiteGraph_LeavesShareRecordParse(t *testing.T) {
+	dbPath := createTestDB(t, map[string]string{
+		"CVE-2024-0001": `{"item":{"cveID":"CVE-2024-0001","vendorProject":"Acme","product":"Widget","shortDescription":"Overflow"}}`,
+		"CVE-2024-0002": `{"item":{"cveID":"CVE-2024-0002","vendorProject":"Beta","product":"Gadget","shortDescription":"XSS"}}`,
+	})
+	g, err := OpenSQLiteGraph(dbPath, kevSchema(), testRender)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+
+	buf := make([]byte, 64)
+	for leaf, want := range map[string]string{"vendor": "Acme", "product": "Widget", "description": "Overflow"} {
+		n, err := g.ReadContent("vulns/CVE-2024-0001/"+leaf, buf, 0)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(buf[:n]))
+	}
+	assert.Len(t, g.records.entries, 1, "one parse for the directory's leaves")
+
+	n, err := g.ReadContent("vulns/CVE-2024-0002/vendor", buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Beta", string(buf[:n]))
+	assert.Len(t, g.records.entries, 2)
+}
+
+// BenchmarkSQLiteGraph_ReadDirLeaves reads every leaf of one record
+// directory from cold content caches, as listing and catting a CVE does.
+func BenchmarkSQLiteGraph_ReadDirLeaves(b *testing.B) {
+	dbPath, _ := largeLeafDB(b, 64<<10)
+	g, err := OpenSQLiteGraph(dbPath, kevSchema(), testRender)
+	require.NoError(b, err)
+	defer func() { _ = g.Close() }()
+	require.NoError(b, g.EagerScan())
+
+	leaves := []string{"vendor", "product", "description"}
+	buf := make([]byte, 128<<10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, leaf := range leaves {
+			id := "vulns/CVE-2024-0001/" + leaf
+			g.Invalidate(id)
+			if _, err := g.ReadContent(id, buf, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkSQLiteGraph_StatThenChunkedRead stats a 10MB leaf and reads it
+// in 128KB chunks, starting from cold caches each iteration.
+func BenchmarkSQLiteGraph_StatThenChunkedRead(b *testing.B) {
+	dbPath, _ := largeLeafDB(b, 10<<20)
+	g, err := OpenSQLiteGraph(dbPath, kevSchema(), testRender)
+	require.NoError(b, err)
+	defer func() { _ = g.Close() }()
+	require.NoError(b, g.EagerScan())
+
+	const id = "vulns/CVE-2024-0001/description"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Invalidate(id)
+		node, err := g.GetNode(id)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := readChunked(g, id, node.Ref.ContentLen, 128<<10); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Cross-reference tests (AddRef / FlushRefs / GetCallers)
 // ---------------------------------------------------------------------------
@@ -768,6 +1017,58 @@ func TestSQLiteGraph_AddRef_FlushRefs_GetCallers(t *testing.T) {
 	}
 }
 
+func TestSQLiteGraph_CallSiteLines_Sidecar(t *testing.T) {
+	dbPath := createTestDB(t, map[string]string{
+		"CVE-2024-0001": `{"schema":"kev","identifier":"CVE-2024-0001","item":{"cveID":"CVE-2024-0001","vendorProject":"Acme","product":"Widget","shortDescription":"test"}}`,
+	})
+	g, err := OpenSQLiteGraph(dbPath, kevSchema(), testRender)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+
+	require.NoError(t, g.AddRef("Println", "pkg/main/source", 12, 7))
+	require.NoError(t, g.AddRef("Println", "pkg/main/source", 7))
+	require.NoError(t, g.AddRef("Sprintf", "pkg/main/source"))
+	require.NoError(t, g.FlushRefs())
+
+	assert.Equal(t, []int{7, 12}, g.CallSiteLines("Println", "pkg/main/source"))
+	assert.Nil(t, g.CallSiteLines("Sprintf", "pkg/main/source"), "no lines recorded")
+	assert.Nil(t, g.CallSiteLines("Println", "pkg/util/source"))
+}
+
+// failRefsRegistration makes the mache_refs module fail to register for
+// the rest of the test.
+func failRefsRegistration(t *testing.T) {
+	t.Helper()
+	orig := registerRefs
+	registerRefs = func() (*refsvtab.RefsModule, error) { return nil, errors.New("refsvtab: register module: boom") }
+	t.Cleanup(func() { registerRefs = orig })
+}
+
+func TestSQLiteGraph_RefsDisabled(t *testing.T) {
+	failRefsRegistration(t)
+	dbPath := createTestDB(t, map[string]string{
+		"CVE-2024-0001": `{"schema":"kev","identifier":"CVE-2024-0001","item":{"cveID":"CVE-2024-0001","vendorProject":"Acme","product":"Widget","shortDescription":"test"}}`,
+	})
+
+	g, err := OpenSQLiteGraph(dbPath, kevSchema(), testRender)
+	require.NoError(t, err, "the projection mounts without the refs index")
+	defer func() { _ = g.Close() }()
+
+	node, err := g.GetNode("vulns/CVE-2024-0001/vendor")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", string(node.Data))
+
+	require.NoError(t, g.AddRef("Println", "vulns/CVE-2024-0001/vendor"))
+	require.NoError(t, g.FlushRefs())
+	callers, err := g.GetCallers("Println")
+	require.NoError(t, err)
+	assert.Empty(t, callers)
+	_, err = g.QueryRefs("SELECT 1")
+	assert.ErrorIs(t, err, ErrRefsDisabled)
+	_, err = os.Stat(dbPath + ".refs.db")
+	assert.True(t, os.IsNotExist(err), "no sidecar is created")
+}
+
 func TestSQLiteGraph_FlushRefs_Idempotent(t *testing.T) {
 	dbPath := createTestDB(t, map[string]string{
 		"CVE-2024-0001": `{"schema":"kev","identifier":"CVE-2024-0001","item":{"cveID":"CVE-2024-0001","vendorProject":"Acme","product":"Widget","shortDescription":"test"}}`,
@@ -1100,6 +1401,28 @@ func TestSQLiteGraph_CustomTableName(t *testing.T) {
 	assert.Equal(t, "Acme", string(buf[:n]))
 }
 
+func TestSQLiteGraph_CustomRecordColumn(t *testing.T) {
+	dbPath := createTestDBWithColumn(t, "vulnerabilities", "payload", map[string]string{
+		"CVE-2024-0001": `{"item":{"cveID":"CVE-2024-0001","vendorProject":"Acme","product":"Widget","shortDescription":"RCE in Widget"}}`,
+	})
+	schema := kevSchema()
+	schema.Table = "vulnerabilities"
+	schema.RecordColumn = "payload"
+
+	g, err := OpenSQLiteGraph(dbPath, schema, testRender)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+
+	children, err := g.ListChildren("vulns")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vulns/CVE-2024-0001"}, children, "scan reads names from the custom column")
+
+	buf := make([]byte, 1024)
+	n, err := g.ReadContent("vulns/CVE-2024-0001/product", buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Widget", string(buf[:n]), "content renders from the custom column")
+}
+
 func TestSQLiteGraph_DefaultTableName(t *testing.T) {
 	// Schema with no Table field should default to "results"
 	dbPath := createTestDB(t, map[string]string{
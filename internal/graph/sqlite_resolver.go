@@ -33,8 +33,15 @@ func (r *SQLiteResolver) Resolve(ref *ContentRef) ([]byte, error) {
 		return nil, err
 	}
 
+	table, column := ref.Table, ref.Column
+	if table == "" {
+		table = "results"
+	}
+	if column == "" {
+		column = "record"
+	}
 	var raw string
-	err = db.QueryRow("SELECT record FROM results WHERE id = ?", ref.RecordID).Scan(&raw)
+	err = db.QueryRow("SELECT "+column+" FROM "+table+" WHERE id = ?", ref.RecordID).Scan(&raw)
 	if err != nil {
 		return nil, fmt.Errorf("resolve record %s: %w", ref.RecordID, err)
 	}
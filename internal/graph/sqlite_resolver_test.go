@@ -39,6 +39,25 @@ func TestSQLiteResolver_Resolve_Basic(t *testing.T) {
 	assert.Equal(t, "{{.severity}}:rendered", string(content))
 }
 
+func TestSQLiteResolver_Resolve_CustomTable(t *testing.T) {
+	dbPath := createTestDBWithColumn(t, "vulnerabilities", "payload", map[string]string{
+		"CVE-2024-1234": `{"severity": "CRITICAL"}`,
+	})
+
+	r := NewSQLiteResolver(testRender)
+	defer r.Close()
+
+	content, err := r.Resolve(&ContentRef{
+		DBPath:   dbPath,
+		Table:    "vulnerabilities",
+		Column:   "payload",
+		RecordID: "CVE-2024-1234",
+		Template: "{{.severity}}",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "CRITICAL", string(content))
+}
+
 func TestSQLiteResolver_Resolve_RecordNotFound(t *testing.T) {
 	dbPath := createTestDB(t, map[string]string{})
 
@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TestNamer maps a test function name to the construct names it likely
+// covers, or nil if name is not a test. Registered per language via
+// RegisterTestNamer and consulted when defs are added, so the _tests/
+// virtual directory can list a construct's tests.
+type TestNamer func(name string) []string
+
+var testNamers sync.Map // langName → TestNamer
+
+func init() {
+	RegisterTestNamer("go", GoTestSubjects)
+	RegisterTestNamer("python", PythonTestSubjects)
+}
+
+// RegisterTestNamer sets the test-to-code heuristic for a language,
+// replacing any earlier registration.
+func RegisterTestNamer(langName string, fn TestNamer) {
+	testNamers.Store(langName, fn)
+}
+
+// testSubjects returns the constructs a def named name in langName tests.
+func testSubjects(langName, name string) []string {
+	fn, ok := testNamers.Load(langName)
+	if !ok {
+		return nil
+	}
+	return fn.(TestNamer)(name)
+}
+
+// isTestName reports whether any language's TestNamer takes name for a
+// test, so indexes can skip looking up the language of other defs.
+func isTestName(name string) bool {
+	found := false
+	testNamers.Range(func(_, fn any) bool {
+		found = len(fn.(TestNamer)(name)) > 0
+		return !found
+	})
+	return found
+}
+
+// GoTestSubjects implements the Go convention: TestFoo and TestFoo_case
+// test Foo. The character after "Test" must not be lowercase, matching
+// what `go test` itself accepts as a test function.
+func GoTestSubjects(name string) []string {
+	rest, ok := strings.CutPrefix(name, "Test")
+	if !ok || rest == "" {
+		return nil
+	}
+	if r, _ := utf8.DecodeRuneInString(rest); unicode.IsLower(r) {
+		return nil
+	}
+	subject, _, _ := strings.Cut(rest, "_")
+	if subject == "" {
+		return nil
+	}
+	return []string{subject}
+}
+
+// PythonTestSubjects implements the pytest convention: test_parse_args
+// tests parse_args. Since snake_case hides where the subject ends, every
+// underscore-delimited prefix is a candidate (test_parse_args_empty →
+// parse_args_empty, parse_args, parse).
+func PythonTestSubjects(name string) []string {
+	rest, ok := strings.CutPrefix(name, "test_")
+	if !ok || rest == "" {
+		return nil
+	}
+	var subjects []string
+	for {
+		subjects = append(subjects, rest)
+		i := strings.LastIndexByte(rest, '_')
+		if i <= 0 {
+			return subjects
+		}
+		rest = rest[:i]
+	}
+}
+
+// TestLocator is implemented by graphs that index test functions by the
+// constructs they exercise.
+type TestLocator interface {
+	// TestsFor returns the sorted dir IDs of test constructs covering token.
+	TestsFor(token string) []string
+}
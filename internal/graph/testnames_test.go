@@ -0,0 +1,52 @@
+package graph
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoTestSubjects(t *testing.T) {
+	assert.Equal(t, []string{"Foo"}, GoTestSubjects("TestFoo"))
+	assert.Equal(t, []string{"Foo"}, GoTestSubjects("TestFoo_EmptyInput"))
+	assert.Equal(t, []string{"User"}, GoTestSubjects("TestUser_Validate"))
+	assert.Nil(t, GoTestSubjects("Testify"), "lowercase after Test is not a test")
+	assert.Nil(t, GoTestSubjects("Test"))
+	assert.Nil(t, GoTestSubjects("Test_foo"))
+	assert.Nil(t, GoTestSubjects("Foo"))
+}
+
+func TestPythonTestSubjects(t *testing.T) {
+	assert.Equal(t, []string{"parse_args_empty", "parse_args", "parse"}, PythonTestSubjects("test_parse_args_empty"))
+	assert.Equal(t, []string{"main"}, PythonTestSubjects("test_main"))
+	assert.Nil(t, PythonTestSubjects("test_"))
+	assert.Nil(t, PythonTestSubjects("parse"))
+}
+
+func TestMemoryStore_TestsFor(t *testing.T) {
+	store := NewMemoryStore()
+	goDir := func(id string) {
+		store.AddNode(&Node{
+			ID:         id,
+			Mode:       fs.ModeDir,
+			Properties: map[string][]byte{"lang": []byte("go")},
+		})
+	}
+	goDir("pkg/functions/Foo")
+	goDir("pkg/functions/TestFoo")
+	goDir("pkg/functions/TestFoo_Empty")
+	goDir("pkg/functions/TestBar")
+	store.AddNode(&Node{ID: "py/functions/test_Foo", Mode: fs.ModeDir}) // no lang → no namer
+
+	require.NoError(t, store.AddDef("Foo", "pkg/functions/Foo"))
+	require.NoError(t, store.AddDef("TestFoo_Empty", "pkg/functions/TestFoo_Empty"))
+	require.NoError(t, store.AddDef("TestFoo", "pkg/functions/TestFoo"))
+	require.NoError(t, store.AddDef("TestBar", "pkg/functions/TestBar"))
+	require.NoError(t, store.AddDef("test_Foo", "py/functions/test_Foo"))
+
+	assert.Equal(t, []string{"pkg/functions/TestFoo", "pkg/functions/TestFoo_Empty"}, store.TestsFor("Foo"))
+	assert.Equal(t, []string{"pkg/functions/TestBar"}, store.TestsFor("Bar"))
+	assert.Nil(t, store.TestsFor("Baz"))
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"path"
+	"slices"
+)
+
+// topologySamples is how many realized paths each level of a Topology
+// lists.
+const topologySamples = 3
+
+// Topology is the realized structure of a schema-projected tree: each
+// schema level with the files it holds and a few paths that actually exist
+// at that level. Served as /_topology.json so agents can plan navigation
+// without executing the schema's templates themselves.
+type Topology struct {
+	Roots []TopologyLevel `json:"roots"`
+}
+
+// TopologyLevel is one directory level of a Topology.
+type TopologyLevel struct {
+	Name     string          `json:"name"`             // static name or name template
+	Depth    int             `json:"depth"`            // 0 for roots
+	Levels   int             `json:"levels,omitempty"` // roots only: directory levels from the root down
+	Files    []string        `json:"files,omitempty"`  // file leaves in each directory at this level
+	Samples  []string        `json:"samples,omitempty"`
+	Children []TopologyLevel `json:"children,omitempty"`
+}
+
+// TopologyDescriber is implemented by graphs projected from a schema that
+// can describe their realized structure. Topology returns nil when the
+// graph has no schema levels.
+type TopologyDescriber interface {
+	Topology() *Topology
+}
+
+// Topology implements TopologyDescriber from the compiled schema levels,
+// sampling the scanned directory listings for realized paths.
+func (g *SQLiteGraph) Topology() *Topology {
+	return describeTopology(g.levels, g)
+}
+
+// describeTopology walks levels, drawing each level's samples from the
+// children of its parent level's samples.
+func describeTopology(levels []*schemaLevel, g Graph) *Topology {
+	if len(levels) == 0 {
+		return nil
+	}
+	t := &Topology{Roots: make([]TopologyLevel, 0, len(levels))}
+	for _, l := range levels {
+		var samples []string
+		if l.isStatic {
+			if _, err := g.GetNode(l.staticName); err == nil {
+				samples = []string{l.staticName}
+			}
+		}
+		root := describeLevel(l, samples, g)
+		root.Levels = levelHeight(l)
+		t.Roots = append(t.Roots, root)
+	}
+	return t
+}
+
+// describeLevel describes l, whose realized directories include samples.
+func describeLevel(l *schemaLevel, samples []string, g Graph) TopologyLevel {
+	tl := TopologyLevel{Name: l.nameRaw, Depth: l.depth, Samples: samples}
+	for _, f := range l.files {
+		tl.Files = append(tl.Files, f.Name)
+	}
+	for _, child := range l.children {
+		tl.Children = append(tl.Children, describeLevel(child, sampleChildren(child, l.children, samples, g), g))
+	}
+	return tl
+}
+
+// sampleChildren returns up to topologySamples directories under parents
+// that belong to level l: those named l's static name, or, for a templated
+// level, any not claimed by a static sibling. _unnamed/ buckets are skipped.
+func sampleChildren(l *schemaLevel, siblings []*schemaLevel, parents []string, g Graph) []string {
+	var static []string
+	for _, s := range siblings {
+		if s.isStatic {
+			static = append(static, s.staticName)
+		}
+	}
+	var out []string
+	for _, parent := range parents {
+		stats, err := g.ListChildStats(parent)
+		if err != nil {
+			continue
+		}
+		for _, st := range stats {
+			if !st.IsDir {
+				continue
+			}
+			base := path.Base(st.ID)
+			if base == UnnamedDir {
+				continue
+			}
+			if l.isStatic && base != l.staticName || !l.isStatic && slices.Contains(static, base) {
+				continue
+			}
+			out = append(out, st.ID)
+			if len(out) == topologySamples {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// levelHeight counts the directory levels from l down to its deepest
+// descendant.
+func levelHeight(l *schemaLevel) int {
+	h := 0
+	for _, c := range l.children {
+		h = max(h, levelHeight(c))
+	}
+	return h + 1
+}
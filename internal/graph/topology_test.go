@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteGraph_Topology(t *testing.T) {
+	records := map[string]string{}
+	for _, id := range []string{"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0003", "CVE-2024-0004"} {
+		records[id] = `{"item":{"cveID":"` + id + `","vendorProject":"Acme"}}`
+	}
+	g, err := OpenSQLiteGraph(createTestDB(t, records), kevSchema(), testRender)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+
+	topo := g.Topology()
+	require.NotNil(t, topo)
+	require.Len(t, topo.Roots, 1)
+	root := topo.Roots[0]
+	assert.Equal(t, "vulns", root.Name)
+	assert.Equal(t, 0, root.Depth)
+	assert.Equal(t, 2, root.Levels)
+	assert.Equal(t, []string{"vulns"}, root.Samples)
+
+	require.Len(t, root.Children, 1)
+	cve := root.Children[0]
+	assert.Equal(t, "{{.item.cveID}}", cve.Name)
+	assert.Equal(t, 1, cve.Depth)
+	assert.Equal(t, []string{"vendor", "product", "description"}, cve.Files)
+	assert.Len(t, cve.Samples, topologySamples, "samples are capped")
+	for _, s := range cve.Samples {
+		_, err := g.GetNode(s)
+		assert.NoError(t, err, "sample %s exists", s)
+	}
+}
@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// TypeRefPrefix namespaces type-reference tokens in the refs index
+// ("type:User"), so a construct's type dependencies never show up as
+// callers of a same-named function.
+const TypeRefPrefix = "type:"
+
+// typeRefPrefixEnd is the least string greater than every TypeRefPrefix
+// token, bounding range scans over them.
+const typeRefPrefixEnd = "type;"
+
+// TypeCategories are the schema category directories that hold type
+// constructs.
+var TypeCategories = []string{"types", "classes", "structs", "interfaces", "enums", "traits", "type_aliases"}
+
+// IsTypeConstruct reports whether dirID is a construct directory under one
+// of the TypeCategories, e.g. "auth/types/User".
+func IsTypeConstruct(dirID string) bool {
+	_, category := ConstructCategory(dirID)
+	return slices.Contains(TypeCategories, category)
+}
+
+// ConstructCategory splits construct directory dirID into the path leading
+// to its category directory and the category's name:
+// "auth/types/User" → ("auth/", "types"). The exported/ or internal/
+// directory that --split-visibility inserts below the category is skipped,
+// so "auth/types/exported/User" splits the same way.
+func ConstructCategory(dirID string) (prefix, category string) {
+	dir := path.Dir(dirID)
+	if b := path.Base(dir); b == ExportedDir || b == InternalDir {
+		dir = path.Dir(dir)
+	}
+	return path.Split(dir)
+}
+
+// TypeRefLocator is implemented by graphs that index the types each
+// construct references (parameters, results, locals, fields). Used by the
+// types-used/ directory.
+type TypeRefLocator interface {
+	// TypesUsed returns the sorted dir IDs of the type constructs the
+	// construct at dirID references.
+	TypesUsed(dirID string) []string
+}
+
+// resolveTypeRefs maps the type ref tokens of construct dirID to type
+// construct dir IDs through defs. A bare name prefers the construct's own
+// package (pkg); a qualified one ("auth.User") falls back to its bare name
+// when the qualifier is an import alias the defs index doesn't know.
+func resolveTypeRefs(dirID, pkg string, tokens []string, defs func(token string) []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, tok := range tokens {
+		name := strings.TrimPrefix(tok, TypeRefPrefix)
+		var candidates []string
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			if candidates = defs(name); len(candidates) == 0 {
+				candidates = defs(name[i+1:])
+			}
+		} else {
+			if pkg != "" {
+				candidates = defs(pkg + "." + name)
+			}
+			if len(candidates) == 0 {
+				candidates = defs(name)
+			}
+		}
+		for _, id := range candidates {
+			if id != dirID && !seen[id] && IsTypeConstruct(id) {
+				seen[id] = true
+				out = append(out, id)
+			}
+		}
+	}
+	slices.Sort(out)
+	return out
+}
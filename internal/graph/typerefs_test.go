@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_TypesUsed(t *testing.T) {
+	store := NewMemoryStore()
+	construct := func(id, pkg string) {
+		store.AddNode(&Node{
+			ID:         id,
+			Mode:       fs.ModeDir,
+			Properties: map[string][]byte{"pkg": []byte(pkg)},
+			Children:   []string{id + "/source"},
+		})
+		store.AddNode(&Node{ID: id + "/source"})
+	}
+	construct("app/functions/Login", "app")
+	construct("app/types/User", "app")
+	construct("other/types/User", "other")
+	construct("auth/types/Token", "auth")
+	construct("app/functions/Token", "app") // same name, not a type
+
+	for _, d := range [][2]string{
+		{"User", "app/types/User"},
+		{"app.User", "app/types/User"},
+		{"User", "other/types/User"},
+		{"other.User", "other/types/User"},
+		{"Token", "auth/types/Token"},
+		{"auth.Token", "auth/types/Token"},
+		{"Token", "app/functions/Token"},
+		{"app.Token", "app/functions/Token"},
+	} {
+		require.NoError(t, store.AddDef(d[0], d[1]))
+	}
+	for _, tok := range []string{"type:User", "type:auth.Token", "type:Missing", "type:User"} {
+		require.NoError(t, store.AddRef(tok, "app/functions/Login/source"))
+	}
+
+	assert.Equal(t, []string{"app/types/User", "auth/types/Token"}, store.TypesUsed("app/functions/Login"),
+		"a bare name resolves within the package; a qualified one by package")
+	assert.Nil(t, store.TypesUsed("app/types/User"))
+	assert.Nil(t, store.TypesUsed("nope"))
+
+	callers, err := store.GetCallers("User")
+	require.NoError(t, err)
+	assert.Empty(t, callers, "type refs don't leak into callers")
+}
+
+func TestResolveTypeRefs_AliasedImport(t *testing.T) {
+	defs := map[string][]string{"Token": {"auth/types/Token"}}
+	got := resolveTypeRefs("app/functions/Login", "app", []string{"type:a.Token"}, func(tok string) []string { return defs[tok] })
+	assert.Equal(t, []string{"auth/types/Token"}, got, "an unknown qualifier falls back to the bare name")
+}
+
+func TestConstructCategory(t *testing.T) {
+	for id, want := range map[string][2]string{
+		"auth/types/User":           {"auth/", "types"},
+		"auth/types/exported/User":  {"auth/", "types"},
+		"auth/functions/internal/x": {"auth/", "functions"},
+		"types/User":                {"", "types"},
+	} {
+		prefix, category := ConstructCategory(id)
+		assert.Equal(t, want, [2]string{prefix, category}, id)
+	}
+	assert.True(t, IsTypeConstruct("auth/types/internal/user"))
+}
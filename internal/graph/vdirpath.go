@@ -1,28 +1,87 @@
 package graph
 
 import (
+	"path"
 	"path/filepath"
 	"strings"
 )
 
 // Virtual directory path helpers used by the NFS backend (internal/nfsmount).
-// These parse callers/, callees/, and _diagnostics/ virtual directory paths
+// These parse callers/, callees/, _tests/, types-used/, _history/, and _diagnostics/ virtual directory paths
 // without any Graph dependency.
 
 // Well-known virtual directory and file names.
 const (
-	SchemaDotJSON  = "_schema.json"
-	DiagnosticsDir = "_diagnostics"
-	ContextFile    = "context"
-	LocationFile   = "location"
-	PromptFile     = "PROMPT.txt"
-	CallersDir     = "callers"
-	CalleesDir     = "callees"
-	DiagLastWrite  = "last-write-status"
-	DiagASTErrors  = "ast-errors"
-	DiagLint       = "lint"
+	SchemaDotJSON      = "_schema.json"
+	SchemaInferredJSON = "_schema.inferred.json"
+	TopologyJSON       = "_topology.json"
+	ManifestJSON       = "_manifest.json"
+	EventsFile         = "_events"
+	DiagnosticsDir     = "_diagnostics"
+	ContextFile        = "context"
+	LocationFile       = "location"
+	RawFile            = "_raw"
+	OriginFile         = "_origin"
+	RefCountFile       = "_refcount"
+	SchemaPathFile     = "_schema_path"
+	SExprFile          = "_sexp"
+	ASTFile            = "_ast.json"
+	CoverageFile       = "_coverage"
+	UnnamedDir         = "_unnamed"
+	ExportedDir        = "exported"
+	InternalDir        = "internal"
+	PromptFile         = "PROMPT.txt"
+	CallersDir         = "callers"
+	CalleesDir         = "callees"
+	TestsDir           = "_tests"
+	TypesUsedDir       = "types-used"
+	AllFunctionsDir    = "_all-functions"
+	AllTypesDir        = "_all-types"
+	AllMethodsDir      = "_all-methods"
+	OutlineDir         = "_outline"
+	HistoryDir         = "_history"
+	RecentDir          = "_recent"
+	SortedBySizeDir    = "_sorted-by-size"
+	DiagLastWrite      = "last-write-status"
+	DiagASTErrors      = "ast-errors"
+	DiagLint           = "lint"
+	DiagDraftDiff      = "draft-diff"
+	DiagWhitespace     = "whitespace"
+	DiagIngestTimes    = "ingest-timings"
+	DiagIdentifiers    = "identifiers"
+	DiagImportCycles   = "import-cycles"
 )
 
+// SchemaPathProperty is the Properties key recording which schema node
+// produced a directory (see ingest.DebugSchema).
+const SchemaPathProperty = "schema_path"
+
+// StableIDProperty is the Properties key holding a construct directory's
+// StableID in decimal.
+const StableIDProperty = "id"
+
+// ComplexityProperty is the Properties key holding a source construct's
+// approximate cyclomatic complexity in decimal.
+const ComplexityProperty = "complexity"
+
+// OriginProperty is the Properties key holding where a source construct
+// starts, as "file:line:col" with an absolute path and 1-based line and
+// column (see vfs.OriginHandler).
+const OriginProperty = "origin"
+
+// SourceLeafProperty is the Properties key naming a construct directory's
+// code leaf when its schema node sets source_leaf (see SourceLeafOf).
+const SourceLeafProperty = "source_leaf"
+
+// SourceLeafOf returns the name of the code leaf of a construct directory
+// with properties props: its SourceLeafProperty, or "source".
+func SourceLeafOf(props map[string][]byte) string {
+	if leaf := props[SourceLeafProperty]; len(leaf) > 0 {
+		return string(leaf)
+	}
+	return "source"
+}
+
 // IsCallersPath returns true if the path contains a /callers segment boundary.
 func IsCallersPath(path string) bool {
 	return strings.HasSuffix(path, "/callers") || strings.Contains(path, "/callers/")
@@ -46,11 +105,50 @@ func ParseCalleesPath(path string) (parentDir, entryName string) {
 	return parseVDirPath(path, "/callees")
 }
 
+// IsTestsPath returns true if the path contains a /_tests segment boundary.
+func IsTestsPath(path string) bool {
+	return strings.HasSuffix(path, "/"+TestsDir) || strings.Contains(path, "/"+TestsDir+"/")
+}
+
+// ParseTestsPath splits a _tests path into (parentDir, entryName).
+// E.g. "/funcs/Foo/_tests/funcs_TestFoo_source" → ("/funcs/Foo", "funcs_TestFoo_source")
+func ParseTestsPath(path string) (parentDir, entryName string) {
+	return parseVDirPath(path, "/"+TestsDir)
+}
+
+// IsTypesUsedPath returns true if the path contains a /types-used segment boundary.
+func IsTypesUsedPath(path string) bool {
+	return strings.HasSuffix(path, "/"+TypesUsedDir) || strings.Contains(path, "/"+TypesUsedDir+"/")
+}
+
+// ParseTypesUsedPath splits a types-used path into (parentDir, entryName).
+// E.g. "/funcs/Foo/types-used/types_User" → ("/funcs/Foo", "types_User")
+func ParseTypesUsedPath(path string) (parentDir, entryName string) {
+	return parseVDirPath(path, "/"+TypesUsedDir)
+}
+
+// IsHistoryPath returns true if the path contains a /_history segment boundary.
+func IsHistoryPath(path string) bool {
+	return strings.HasSuffix(path, "/"+HistoryDir) || strings.Contains(path, "/"+HistoryDir+"/")
+}
+
+// ParseHistoryPath splits a _history path into (parentDir, entryName).
+// E.g. "/funcs/Foo/_history/3f2a9c1e8b7d" → ("/funcs/Foo", "3f2a9c1e8b7d"),
+// "/_history/3f2a9c1e8b7d/message" → ("/", "3f2a9c1e8b7d/message")
+func ParseHistoryPath(path string) (parentDir, entryName string) {
+	return parseVDirPath(path, "/"+HistoryDir)
+}
+
 // VDirSymlinkTarget computes the relative symlink target from a virtual dir entry
 // back to the target node in the graph. Works for both callers/ and callees/.
+// It climbs one level per segment of vdirParentDir, whatever its depth or
+// form ("/", "funcs/Foo", "/funcs/Foo/"), plus one for the virtual dir itself.
 func VDirSymlinkTarget(vdirParentDir, targetID string) string {
-	depth := strings.Count(vdirParentDir, "/") + 1 // +1 for the virtual dir itself
-	return strings.Repeat("../", depth) + targetID
+	depth := 1
+	if dir := strings.Trim(path.Clean("/"+vdirParentDir), "/"); dir != "" {
+		depth += strings.Count(dir, "/") + 1
+	}
+	return strings.Repeat("../", depth) + strings.TrimPrefix(targetID, "/")
 }
 
 // FindSourceChild finds the "source" file child of a directory node.
@@ -72,6 +170,32 @@ func FindSourceChild(g Graph, dirID string) string {
 	return ""
 }
 
+// SourceFileLocator is implemented by graphs that can report which source
+// file a node was projected from without materializing a SourceOrigin
+// (e.g., the SQLite nodes table's source_file column).
+type SourceFileLocator interface {
+	SourceFile(id string) (string, bool)
+}
+
+// RawSourceFile returns the absolute path of the source file a construct
+// directory was projected from, via its "source" child. Returns "" when the
+// directory has no source child or its origin is unknown.
+func RawSourceFile(g Graph, dirID string) string {
+	srcID := FindSourceChild(g, dirID)
+	if srcID == "" {
+		return ""
+	}
+	if n, err := g.GetNode(srcID); err == nil && n.Origin != nil && n.Origin.FilePath != "" {
+		return n.Origin.FilePath
+	}
+	if sl, ok := g.(SourceFileLocator); ok {
+		if path, ok := sl.SourceFile(srcID); ok {
+			return path
+		}
+	}
+	return ""
+}
+
 // IsDiagPath returns true if the path contains a /_diagnostics segment.
 func IsDiagPath(path string) bool {
 	return strings.Contains(path, "/"+DiagnosticsDir)
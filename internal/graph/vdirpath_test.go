@@ -1,9 +1,13 @@
 package graph
 
 import (
+	"database/sql"
+	"path"
 	"testing"
 
+	"github.com/agentic-research/mache/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsCallersPath(t *testing.T) {
@@ -151,11 +155,46 @@ func TestVDirSymlinkTarget(t *testing.T) {
 	target := VDirSymlinkTarget("/funcs/Foo", "funcs/Bar/source")
 	assert.Equal(t, "../../../funcs/Bar/source", target)
 
-	// / → depth 1, +1 = 2
+	// / → just the virtual dir to climb
 	target = VDirSymlinkTarget("/", "funcs/Foo/source")
-	assert.Equal(t, "../../funcs/Foo/source", target)
+	assert.Equal(t, "../funcs/Foo/source", target)
 
 	// deeper path
 	target = VDirSymlinkTarget("/a/b/c/d", "x/y")
 	assert.Equal(t, "../../../../../x/y", target)
+
+	// Resolved from the entry's directory, every target lands on its node.
+	for _, parent := range []string{"/", "", "/a", "a", "/a/", "/a/b/c/d/e/f", "a/b//c"} {
+		for _, id := range []string{"x", "x/y/source", "/x/y"} {
+			target := VDirSymlinkTarget(parent, id)
+			entryDir := path.Join("/", parent, CallersDir)
+			assert.Equal(t, path.Join("/", id), path.Join(entryDir, target), "parent %q, target %q", parent, id)
+		}
+	}
+}
+
+func TestRawSourceFile_Origin(t *testing.T) {
+	store := NewMemoryStore()
+	store.AddNode(&Node{ID: "pkg/Foo", Mode: 0o755 | 1<<31, Children: []string{"pkg/Foo/source"}})
+	store.AddNode(&Node{ID: "pkg/Foo/source", Mode: 0o444, Origin: &SourceOrigin{FilePath: "/src/foo.go", EndByte: 4}})
+	store.AddNode(&Node{ID: "pkg/Bar", Mode: 0o755 | 1<<31})
+
+	assert.Equal(t, "/src/foo.go", RawSourceFile(store, "pkg/Foo"))
+	assert.Equal(t, "", RawSourceFile(store, "pkg/Bar"))
+}
+
+func TestRawSourceFile_NodesTable(t *testing.T) {
+	dbPath := createNodesTableDB(t)
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE nodes SET source_file = '/src/auth.go' WHERE id = 'pkg/auth/source'")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	g, err := OpenSQLiteGraph(dbPath, &api.Topology{Table: "results"}, stubRender)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = g.Close() })
+
+	assert.Equal(t, "/src/auth.go", RawSourceFile(g, "pkg/auth"))
+	assert.Equal(t, "", RawSourceFile(g, "pkg/main"), "no source_file recorded")
 }
@@ -3,6 +3,7 @@ package graph
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
@@ -18,6 +19,9 @@ type WritableGraph struct {
 	dbPath  string            // temp file path (the writable master)
 	flusher *ArenaFlusher
 	mu      sync.RWMutex
+
+	stopCheckpoints chan struct{} // closed by Close; nil until StartCheckpoints
+	checkpointsDone chan struct{}
 }
 
 // OpenWritableGraph opens a writable connection to the master .db.
@@ -29,8 +33,11 @@ func OpenWritableGraph(masterDBPath string, schema *api.Topology, render Templat
 	}
 	db.SetMaxOpenConns(2)
 
-	// journal_mode=DELETE: after commit, the .db file IS the serialized form.
-	if _, err := db.Exec("PRAGMA journal_mode=DELETE"); err != nil {
+	// journal_mode=WAL: commits append to the -wal file instead of rewriting
+	// the .db. The flusher copies only the .db, so every flush checkpoints
+	// first (see Checkpoint), and the periodic checkpoints keep the WAL from
+	// growing over a long session.
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("set journal mode: %w", err)
 	}
@@ -46,16 +53,17 @@ func OpenWritableGraph(masterDBPath string, schema *api.Topology, render Templat
 		return nil, fmt.Errorf("nodes table not found in %s", masterDBPath)
 	}
 
-	tableName := schema.Table
-	if tableName == "" {
-		tableName = "results"
-	}
+	tableName, recordCol := schema.RecordSource()
 
-	return &WritableGraph{
-		ntr:     NewNodesTableReader(db, tableName, render, compileLevels(schema), 0o644, 0o755, 2048),
+	g := &WritableGraph{
+		ntr:     NewNodesTableReader(db, tableName, recordCol, render, compileLevels(schema), 0o644, 0o755, 2048),
 		dbPath:  masterDBPath,
 		flusher: flusher,
-	}, nil
+	}
+	if flusher != nil {
+		flusher.SetBeforeFlush(g.Checkpoint)
+	}
+	return g, nil
 }
 
 // ---------------------------------------------------------------------------
@@ -121,8 +129,64 @@ func (g *WritableGraph) FlushNow() error {
 	return g.flusher.FlushNow()
 }
 
-// Close closes the database connection.
+// Checkpoint folds the WAL into the master .db file and truncates it
+// (PRAGMA wal_checkpoint(TRUNCATE)). The ArenaFlusher runs it before every
+// flush, so the .db it copies holds all committed writes.
+func (g *WritableGraph) Checkpoint() error {
+	g.mu.Lock()
+	var busy, logFrames, checkpointed int
+	err := g.ntr.DB().QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed)
+	g.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("wal checkpoint: %w", err)
+	}
+	if busy != 0 {
+		return fmt.Errorf("wal checkpoint: database busy (%d of %d frames checkpointed)", checkpointed, logFrames)
+	}
+	return nil
+}
+
+// StartCheckpoints runs Checkpoint every interval until Close, so the WAL
+// of a long-running session stays bounded. interval <= 0 disables periodic
+// checkpoints (Close still checkpoints). Call at most once.
+func (g *WritableGraph) StartCheckpoints(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	g.stopCheckpoints = make(chan struct{})
+	g.checkpointsDone = make(chan struct{})
+	go func() {
+		defer close(g.checkpointsDone)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if err := g.Checkpoint(); err != nil {
+					log.Printf("writable graph: %v", err)
+				}
+			case <-g.stopCheckpoints:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops periodic checkpoints, runs a final checkpoint, detaches
+// from the flusher, and closes the database connection. Close the graph
+// before the ArenaFlusher so the final flush sees the checkpointed file.
 func (g *WritableGraph) Close() error {
+	if g.stopCheckpoints != nil {
+		close(g.stopCheckpoints)
+		<-g.checkpointsDone
+		g.stopCheckpoints = nil
+	}
+	if err := g.Checkpoint(); err != nil {
+		log.Printf("writable graph: final %v", err)
+	}
+	if g.flusher != nil {
+		g.flusher.SetBeforeFlush(nil)
+	}
 	return g.ntr.DB().Close()
 }
 
@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritableGraph_CheckpointTruncatesWAL(t *testing.T) {
+	dbPath := createNodesTableDB(t)
+	g, err := OpenWritableGraph(dbPath, &api.Topology{Table: "results"}, stubRender, nil)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+
+	require.NoError(t, g.UpdateRecord("pkg/auth/source", []byte("changed")))
+	info, err := os.Stat(dbPath + "-wal")
+	require.NoError(t, err)
+	require.NotZero(t, info.Size(), "update should land in the WAL")
+
+	require.NoError(t, g.Checkpoint())
+	info, err = os.Stat(dbPath + "-wal")
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "TRUNCATE checkpoint empties the WAL")
+}
+
+func TestWritableGraph_FlushCheckpointsWAL(t *testing.T) {
+	dbPath := createNodesTableDB(t)
+	arenaPath := filepath.Join(t.TempDir(), "test.arena")
+	require.NoError(t, CreateArena(dbPath, arenaPath))
+
+	flusher := NewArenaFlusher(arenaPath, dbPath, nil)
+	g, err := OpenWritableGraph(dbPath, &api.Topology{Table: "results"}, stubRender, flusher)
+	require.NoError(t, err)
+	g.StartCheckpoints(10 * time.Millisecond)
+	defer func() { _ = g.Close() }()
+
+	require.NoError(t, g.UpdateRecord("pkg/auth/source", []byte("changed")))
+	require.NoError(t, g.FlushNow())
+
+	// The flush checkpointed first, so the arena copy holds the update and
+	// opens as a plain rollback-journal DB with no -wal beside it.
+	extractedPath, err := ExtractActiveDB(arenaPath)
+	require.NoError(t, err)
+	defer func() { _ = os.Remove(extractedPath) }()
+	edb, err := sql.Open("sqlite", extractedPath+"?mode=ro")
+	require.NoError(t, err)
+	defer func() { _ = edb.Close() }()
+
+	var record string
+	require.NoError(t, edb.QueryRow("SELECT record FROM nodes WHERE id = 'pkg/auth/source'").Scan(&record))
+	assert.Equal(t, "changed", record)
+	var mode string
+	require.NoError(t, edb.QueryRow("PRAGMA journal_mode").Scan(&mode))
+	assert.NotEqual(t, "wal", mode)
+}
@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"encoding/json"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Bounds on the syntax tree ASTJSON serializes: nodes deeper than
+// maxASTDepth below the construct, or past the first maxASTNodes, are left
+// out and their parent marked truncated.
+const (
+	maxASTDepth = 64
+	maxASTNodes = 20000
+)
+
+// syntaxNode is one named tree-sitter node of a construct's syntax tree as
+// served in its _ast.json. Byte offsets are into the source file, so an
+// edit to a node can be spliced back into the construct's source.
+type syntaxNode struct {
+	Type      string        `json:"type"`
+	Field     string        `json:"field,omitempty"`
+	StartByte uint32        `json:"start_byte"`
+	EndByte   uint32        `json:"end_byte"`
+	Text      string        `json:"text,omitempty"`
+	Missing   bool          `json:"missing,omitempty"`
+	Truncated bool          `json:"truncated,omitempty"`
+	Children  []*syntaxNode `json:"children,omitempty"`
+}
+
+// ASTJSON re-parses the source file at path as langName (or by its
+// extension when langName is "") and returns the named subtree of the node
+// spanning bytes [start, end) as indented JSON ASTNodes. Leaves carry
+// their source text.
+func ASTJSON(path, langName string, start, end uint32) ([]byte, error) {
+	n, content, err := parseSpan(path, langName, start, end)
+	if err != nil {
+		return nil, err
+	}
+	budget := maxASTNodes
+	return json.MarshalIndent(buildAST(n, "", 0, content, &budget), "", "  ")
+}
+
+// buildAST converts n's named subtree, spending one of budget's nodes on
+// each node it includes.
+func buildAST(n *sitter.Node, field string, depth int, source []byte, budget *int) *syntaxNode {
+	*budget--
+	node := &syntaxNode{
+		Type:      n.Type(),
+		Field:     field,
+		StartByte: n.StartByte(),
+		EndByte:   n.EndByte(),
+		Missing:   n.IsMissing(),
+	}
+	if n.NamedChildCount() == 0 {
+		node.Text = n.Content(source)
+		return node
+	}
+	if depth == maxASTDepth {
+		node.Truncated = true
+		return node
+	}
+	for i := 0; i < int(n.ChildCount()); i++ {
+		c := n.Child(i)
+		if !c.IsNamed() {
+			continue
+		}
+		if *budget <= 0 {
+			node.Truncated = true
+			break
+		}
+		node.Children = append(node.Children, buildAST(c, n.FieldNameForChild(i), depth+1, source, budget))
+	}
+	return node
+}
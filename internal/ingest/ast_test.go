@@ -0,0 +1,59 @@
+package ingest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestASTJSON(t *testing.T) {
+	src := "package auth\n\nfunc Login(user string) error {\n\treturn nil\n}\n"
+	path := filepath.Join(t.TempDir(), "auth.go")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	start := strings.Index(src, "func")
+	end := strings.LastIndex(src, "}") + 1
+
+	data, err := ASTJSON(path, "go", uint32(start), uint32(end))
+	require.NoError(t, err)
+	var root syntaxNode
+	require.NoError(t, json.Unmarshal(data, &root))
+
+	assert.Equal(t, "function_declaration", root.Type)
+	assert.Equal(t, uint32(start), root.StartByte)
+	assert.Equal(t, uint32(end), root.EndByte)
+	assert.Empty(t, root.Text, "only leaves carry text")
+	require.Len(t, root.Children, 4)
+
+	name := root.Children[0]
+	assert.Equal(t, syntaxNode{Type: "identifier", Field: "name", StartByte: 19, EndByte: 24, Text: "Login"}, *name)
+	assert.Equal(t, "Login", src[name.StartByte:name.EndByte], "offsets index the source file")
+	assert.Equal(t, "parameters", root.Children[1].Field)
+	assert.Equal(t, "user", root.Children[1].Children[0].Children[0].Text)
+	assert.Equal(t, "body", root.Children[3].Field)
+
+	_, err = ASTJSON(path, "go", 0, uint32(len(src)+10))
+	assert.Error(t, err, "a range past the end means the file changed")
+}
+
+func TestBuildAST_Truncates(t *testing.T) {
+	src := []byte("package p\n\nfunc A() {}\n\nfunc B() {}\n")
+	path := filepath.Join(t.TempDir(), "p.go")
+	require.NoError(t, os.WriteFile(path, src, 0o644))
+	n, content, err := parseSpan(path, "go", 0, uint32(len(src)))
+	require.NoError(t, err)
+
+	budget := 4
+	root := buildAST(n, "", 0, content, &budget)
+	assert.Equal(t, "source_file", root.Type)
+	assert.True(t, root.Truncated, "children past the node budget are left out")
+	require.Len(t, root.Children, 2)
+	assert.Equal(t, "package_clause", root.Children[0].Type)
+	assert.Equal(t, "function_declaration", root.Children[1].Type)
+	assert.True(t, root.Children[1].Truncated)
+	assert.Empty(t, root.Children[1].Children)
+}
@@ -1,9 +1,13 @@
 package ingest
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
 	"path/filepath"
 	"testing"
+	"unicode/utf16"
 
 	"github.com/agentic-research/mache/internal/graph"
 	"github.com/stretchr/testify/assert"
@@ -118,6 +122,78 @@ func TestIsBinaryFile(t *testing.T) {
 	assert.False(t, isBinaryFile(filepath.Join(tmpDir, "nope")), "missing file should return false")
 }
 
+// utf16LE encodes s as UTF-16LE with a byte order mark.
+func utf16LE(s string) []byte {
+	out := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func TestLooksBinary(t *testing.T) {
+	// Bytes that are neither NUL nor text, as in a compressed stream.
+	noisy := bytes.Repeat([]byte{0x01, 0x9c, 'x', 0x02, 0xe3, 0x7f, 'a', 0x15}, 64)
+
+	tests := []struct {
+		name string
+		head []byte
+		want bool
+	}{
+		{"ascii", []byte("package main\n"), false},
+		{"utf-8", []byte("// héllo, 世界\n"), false},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, "package main\n"...), false},
+		{"utf-16le bom", utf16LE("package main\n"), false},
+		{"utf-16be bom", []byte{0xFE, 0xFF, 0, 'p', 0, 'k'}, false},
+		{"latin-1", []byte("caf\xe9 cr\xe8me br\xfbl\xe9e, a dessert of rich custard\n"), false},
+		{"ansi colors", []byte("\x1b[31merror\x1b[0m: failed\r\n"), false},
+		{"cut-off utf-8", []byte("abc\xe4\xb8"), false},
+		{"nul", []byte("abc\x00def"), true},
+		{"utf-16 without bom", []byte{'p', 0, 'k', 0}, true},
+		{"no nul but noisy", noisy, true},
+		{"empty", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, looksBinary(tt.head))
+		})
+	}
+}
+
+func TestEngine_Ingest_UTF16AndBOMSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	wideFile := filepath.Join(tmpDir, "wide.go")
+	require.NoError(t, os.WriteFile(wideFile, utf16LE("package main\n\nfunc Wide() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "bom.go"),
+		append([]byte{0xEF, 0xBB, 0xBF}, "package main\n\nfunc Marked() {}\n"...), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "notes.txt"), utf16LE("windows notes\r\n"), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(loadGoSchema(t), store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	wide, err := store.GetNode("main/functions/Wide/source")
+	require.NoError(t, err, "UTF-16 source is parsed, not skipped as binary")
+	assert.Equal(t, "func Wide() {}", string(wide.Data), "transcoded to UTF-8")
+	marked, err := store.GetNode("main/functions/Marked/source")
+	require.NoError(t, err)
+	assert.Equal(t, "func Marked() {}", string(marked.Data))
+	notes, err := store.GetNode("_project_files/notes.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "windows notes\r\n", string(notes.Data))
+
+	raw, err := os.ReadFile(wideFile)
+	require.NoError(t, err)
+	sum := sha256.Sum256(raw)
+	var hashed string
+	for _, m := range engine.Manifest() {
+		if m.Path == "wide.go" {
+			hashed = m.SHA256
+		}
+	}
+	assert.Equal(t, hex.EncodeToString(sum[:]), hashed, "the manifest hashes the file, not the transcoded text")
+}
+
 func TestEngine_Ingest_SkipsBinaryFiles(t *testing.T) {
 	schema := loadGoSchema(t)
 
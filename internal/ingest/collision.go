@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CollisionStrategy decides where a construct goes when a construct from
+// another source file is already projected at its path, like the init()
+// functions of two files in one Go package.
+type CollisionStrategy string
+
+const (
+	// CollisionFile suffixes the later construct with its source file:
+	// init.from_b_go, then init.from_b_go.2 for a same-named file
+	// elsewhere.
+	CollisionFile CollisionStrategy = "file"
+	// CollisionNumber numbers the later constructs: init_2, init_3.
+	CollisionNumber CollisionStrategy = "number"
+	// CollisionError fails the ingest.
+	CollisionError CollisionStrategy = "error"
+	// CollisionSubdir keeps the later construct's name and nests it in a
+	// directory named after its source file: init/b.go/source.
+	CollisionSubdir CollisionStrategy = "subdir"
+)
+
+// Collisions is the strategy for constructs whose path is taken. Overloads
+// that differ in arity are told apart by parameter count first, whatever
+// the strategy. Configurable via --on-collision.
+var Collisions = CollisionFile
+
+// ParseCollisionStrategy parses an --on-collision value.
+func ParseCollisionStrategy(s string) (CollisionStrategy, error) {
+	switch c := CollisionStrategy(strings.TrimSpace(s)); c {
+	case CollisionFile, CollisionNumber, CollisionError, CollisionSubdir:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown collision strategy %q (want file, number, error, or subdir)", s)
+}
+
+// candidate returns the n-th name (from 1) to try for a construct named
+// name from sourceFile, a base name, whose own path is taken.
+func (s CollisionStrategy) candidate(name, sourceFile string, n int) string {
+	var base string
+	switch s {
+	case CollisionNumber:
+		return fmt.Sprintf("%s_%d", name, n+1)
+	case CollisionSubdir:
+		base = sourceFile
+	default:
+		base = name + dedupSuffix(sourceFile)
+	}
+	if n == 1 {
+		return base
+	}
+	return fmt.Sprintf("%s.%d", base, n)
+}
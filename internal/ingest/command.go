@@ -0,0 +1,70 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/agentic-research/mache/api"
+)
+
+// LeafCommandTimeout bounds each run of a computed leaf's command (see
+// api.Leaf.Command); a run that takes longer is killed. Configurable via
+// --exec-timeout.
+var LeafCommandTimeout = 30 * time.Second
+
+// RunLeafCommand runs a computed leaf's command with sh -c, input on stdin,
+// and returns its stdout. It is the graph.ComputeFunc of mounts started
+// with --allow-exec. A failed or timed-out run's error carries the end of
+// its stderr.
+func RunLeafCommand(command string, input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), LeafCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(input)
+	// Killing sh leaves any child it started holding stdout open; stop
+	// waiting for it shortly after.
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %v", LeafCommandTimeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > maxCommandStderr {
+			msg = "..." + msg[len(msg)-maxCommandStderr:]
+		}
+		if msg != "" {
+			return nil, fmt.Errorf("command %q: %w: %s", command, err, msg)
+		}
+		return nil, fmt.Errorf("command %q: %w", command, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// maxCommandStderr is how much of a failed command's stderr its error keeps.
+const maxCommandStderr = 1024
+
+// SchemaCommandLeaves returns the names of the schema's computed leaves,
+// those with a Command, in schema order.
+func SchemaCommandLeaves(schema *api.Topology) []string {
+	var names []string
+	var walk func(nodes []api.Node)
+	walk = func(nodes []api.Node) {
+		for _, n := range nodes {
+			for _, f := range n.Files {
+				if f.Command != "" {
+					names = append(names, f.Name)
+				}
+			}
+			walk(n.Children)
+		}
+	}
+	walk(schema.Nodes)
+	return names
+}
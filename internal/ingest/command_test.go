@@ -0,0 +1,68 @@
+package ingest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLeafCommand(t *testing.T) {
+	out, err := RunLeafCommand("tr a-z A-Z", []byte("func Foo() {}"))
+	require.NoError(t, err)
+	assert.Equal(t, "FUNC FOO() {}", string(out))
+
+	_, err = RunLeafCommand("echo boom >&2; exit 3", nil)
+	assert.ErrorContains(t, err, "exit status 3: boom")
+
+	old := LeafCommandTimeout
+	defer func() { LeafCommandTimeout = old }()
+	LeafCommandTimeout = 50 * time.Millisecond
+	_, err = RunLeafCommand("sleep 5", nil)
+	assert.ErrorContains(t, err, "timed out")
+}
+
+func TestEngine_ComputedLeaf(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc Foo() {}\n"), 0o644))
+
+	var schema api.Topology
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "version": "v1",
+  "nodes": [
+    {
+      "name": "functions",
+      "selector": "$",
+      "children": [
+        {
+          "name": "{{.name}}",
+          "selector": "(function_declaration name: (identifier) @name) @scope",
+          "files": [
+            { "name": "source", "content_template": "{{.scope}}" },
+            { "name": "_upper", "content_template": "{{.scope}}", "command": "tr a-z A-Z" }
+          ]
+        }
+      ]
+    }
+  ]
+}`), &schema))
+	assert.Equal(t, []string{"_upper"}, SchemaCommandLeaves(&schema))
+
+	store := graph.NewMemoryStore()
+	store.SetComputer(RunLeafCommand)
+	require.NoError(t, NewEngine(&schema, store).Ingest(dir))
+
+	n, err := store.GetNode("functions/Foo/_upper")
+	require.NoError(t, err)
+	assert.Equal(t, "FUNC FOO() {}", string(n.Data))
+	assert.Nil(t, n.Origin, "a computed leaf isn't written back")
+	src, err := store.GetNode("functions/Foo/source")
+	require.NoError(t, err)
+	assert.Equal(t, "func Foo() {}", string(src.Data))
+}
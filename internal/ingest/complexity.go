@@ -0,0 +1,64 @@
+package ingest
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// decisionNodes are the node types, across the tree-sitter grammars, that
+// each add a path through a construct: branches, loops, cases, and catch
+// clauses. Defaults and plain else branches add none.
+var decisionNodes = map[string]bool{
+	"if_statement":           true,
+	"if_expression":          true,
+	"elif_clause":            true,
+	"else_if_clause":         true,
+	"for_statement":          true,
+	"for_in_statement":       true,
+	"for_expression":         true,
+	"enhanced_for_statement": true,
+	"foreach_statement":      true,
+	"while_statement":        true,
+	"while_expression":       true,
+	"do_statement":           true,
+	"loop_expression":        true,
+	"expression_case":        true,
+	"type_case":              true,
+	"communication_case":     true,
+	"switch_case":            true,
+	"case_clause":            true,
+	"case_statement":         true,
+	"match_arm":              true,
+	"catch_clause":           true,
+	"except_clause":          true,
+	"rescue":                 true,
+	"conditional_expression": true,
+	"ternary_expression":     true,
+	"boolean_operator":       true, // Python's and/or
+}
+
+// cyclomaticComplexity approximates the cyclomatic complexity of the
+// construct n: one plus its decision points, counting each short-circuit
+// && and || as one. Nested constructs (closures, methods of a class)
+// count toward n.
+func cyclomaticComplexity(n *sitter.Node) int {
+	complexity := 1
+	stack := []*sitter.Node{n}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch typ := cur.Type(); {
+		case decisionNodes[typ]:
+			complexity++
+		case typ == "binary_expression":
+			if op := cur.ChildByFieldName("operator"); op != nil && (op.Type() == "&&" || op.Type() == "||") {
+				complexity++
+			}
+		}
+		for i := int(cur.ChildCount()) - 1; i >= 0; i-- {
+			if c := cur.Child(i); c != nil && c.IsNamed() {
+				stack = append(stack, c)
+			}
+		}
+	}
+	return complexity
+}
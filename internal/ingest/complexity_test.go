@@ -0,0 +1,46 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+func TestEngine_Complexity(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte(`package main
+
+func Simple() int { return 1 }
+
+func Branchy(xs []int) int {
+	n := 0
+	for _, x := range xs {
+		if x > 0 && x < 10 {
+			n++
+		}
+		switch x {
+		case 1:
+		case 2:
+		default:
+		}
+	}
+	return n
+}
+`), 0o644))
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(tmpDir))
+
+	for name, want := range map[string]string{"Simple": "1", "Branchy": "6"} {
+		n, err := store.GetNode("main/functions/" + name)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(n.Properties[graph.ComplexityProperty]), name)
+	}
+	pkg, err := store.GetNode("main")
+	require.NoError(t, err)
+	assert.NotContains(t, pkg.Properties, graph.ComplexityProperty, "only constructs with files")
+}
@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// decoratorsValue is the template value holding a construct's decorators or
+// annotations, one per line, as written in the source: Python decorators
+// (@app.route("/")), Java and Kotlin annotations (@Override), and
+// JavaScript/TypeScript decorators (@Input()). It is empty for constructs
+// without any, so a "decorators" leaf rendering {{.decorators}} is skipped
+// for them. A capture of the same name takes precedence.
+const decoratorsValue = "decorators"
+
+// matchDecorators returns decoratorsOf the match's @scope. ok is false
+// for matches without a tree-sitter scope.
+func matchDecorators(match Match) (decorators string, ok bool) {
+	sm, isSitter := match.(interface{ GetCaptureNode(string) *sitter.Node })
+	if !isSitter {
+		return "", false
+	}
+	scope := sm.GetCaptureNode("scope")
+	root, isRoot := match.Context().(SitterRoot)
+	if scope == nil || !isRoot {
+		return "", false
+	}
+	return decoratorsOf(scope, root.Source), true
+}
+
+// decoratorsOf returns the decorators or annotations applied to scope, one
+// per line. Python wraps a decorated definition in a decorated_definition
+// whose decorator children precede it; Java and Kotlin list annotations in
+// the construct's modifiers; JavaScript and TypeScript give the construct
+// decorator children of its own.
+func decoratorsOf(scope *sitter.Node, source []byte) string {
+	var lines []string
+	add := func(n *sitter.Node) {
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			switch c := n.NamedChild(i); c.Type() {
+			case "decorator", "annotation", "marker_annotation":
+				lines = append(lines, c.Content(source))
+			}
+		}
+	}
+	if parent := scope.Parent(); parent != nil && parent.Type() == "decorated_definition" {
+		add(parent)
+	}
+	add(scope)
+	for i := 0; i < int(scope.NamedChildCount()); i++ {
+		if c := scope.NamedChild(i); c.Type() == "modifiers" {
+			add(c)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -300,11 +300,11 @@ type minimalStore struct {
 	nodes map[string]*graph.Node
 }
 
-func (m *minimalStore) AddNode(n *graph.Node)    { m.nodes[n.ID] = n }
-func (m *minimalStore) AddRoot(_ *graph.Node)    {}
-func (m *minimalStore) AddRef(_, _ string) error { return nil }
-func (m *minimalStore) AddDef(_, _ string) error { return nil }
-func (m *minimalStore) DeleteFileNodes(_ string) {}
+func (m *minimalStore) AddNode(n *graph.Node)              { m.nodes[n.ID] = n }
+func (m *minimalStore) AddRoot(_ *graph.Node)              {}
+func (m *minimalStore) AddRef(_, _ string, _ ...int) error { return nil }
+func (m *minimalStore) AddDef(_, _ string) error           { return nil }
+func (m *minimalStore) DeleteFileNodes(_ string)           {}
 func (m *minimalStore) AddFileChildren(parent *graph.Node, files []*graph.Node) {
 	for _, f := range files {
 		m.nodes[f.ID] = f
@@ -3,8 +3,6 @@ package ingest
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -19,6 +18,7 @@ import (
 	"sync/atomic"
 	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/agentic-research/mache/api"
 	"github.com/agentic-research/mache/internal/graph"
@@ -33,12 +33,16 @@ const inlineThreshold = 4096
 // to detect binary content (same heuristic as git).
 const binarySniffSize = 512
 
+// DefaultMaxInFlightRecords is the in-flight record bound used when
+// Engine.MaxInFlightRecords is zero.
+const DefaultMaxInFlightRecords = 4096
+
 // IngestionTarget combines Graph reading with writing capabilities.
 type IngestionTarget interface {
 	graph.Graph
 	AddNode(n *graph.Node)
 	AddRoot(n *graph.Node)
-	AddRef(token, nodeID string) error
+	AddRef(token, nodeID string, lines ...int) error // lines: optional 1-based call-site lines
 	AddDef(token, dirID string) error
 	DeleteFileNodes(filePath string)
 	AddFileChildren(parent *graph.Node, files []*graph.Node)
@@ -51,6 +55,11 @@ type Engine struct {
 	RootPath         string // absolute path to the root of the ingestion
 	RespectGitignore bool   // when true, skip files matching .gitignore patterns (default: true)
 	routedFiles      map[string]int
+	skippedRecords   int                        // malformed records dropped under SkipErrors
+	skippedFiles     int                        // unparseable JSON files dropped under SkipErrors
+	fileTimings      map[string]FileTiming      // rel path → last ingest timing (see FileTimings)
+	identifiers      map[string]fileIdentifiers // rel path → ref token counts (see IdentifierCounts)
+	manifest         map[string]ManifestEntry   // rel path → source file ingested (see Manifest)
 	childSeen        map[string]map[string]bool // parentID → set of child IDs (O(1) dedup)
 	gitignore        *gitignoreMatcher          // loaded from .gitignore when RespectGitignore is true
 	sitterWalker     *SitterWalker              // shared across files for query cache reuse
@@ -58,6 +67,11 @@ type Engine struct {
 	fileIndex        map[string]FileIndexEntry  // cached file metadata for incremental re-ingestion
 	mu               sync.Mutex
 
+	// MaxInFlightRecords bounds how many SQLite records may be read but not
+	// yet applied to the store, capping memory when the collector falls
+	// behind the workers. Zero uses DefaultMaxInFlightRecords.
+	MaxInFlightRecords int
+
 	// diagramOnce guards lazy computation of cachedCommunities + cachedRefs.
 	diagramOnce       sync.Once
 	cachedCommunities *graph.CommunityResult
@@ -76,12 +90,19 @@ type recordJob struct {
 	raw      string
 }
 
+// recordSource locates the stored records that large file content is
+// lazily rendered from. An empty dbPath inlines all content.
+type recordSource struct {
+	dbPath, table, column string
+}
+
 // recordResult is the output from a worker: all nodes for one record.
 type recordResult struct {
 	nodes       []*graph.Node
 	parentLinks []parentLink
 	refLinks    []refLink
 	err         error
+	malformed   bool // err is a JSON syntax error in the record itself
 }
 
 type parentLink struct {
@@ -107,34 +128,84 @@ type treeSitterJob struct {
 // parsedTreeSitterFile is the result of tree-sitter parsing (parallel or sequential).
 // Contains the pre-parsed AST and file content, ready for processTreeSitterResult.
 type parsedTreeSitterFile struct {
-	job      treeSitterJob
-	realPath string
-	content  []byte
-	tree     *sitter.Tree
-	context  []byte            // extracted imports/globals context
-	imports  map[string]string // structured imports: alias → path (Go only, nil for others)
-	parseErr error             // non-nil if tree-sitter parsing failed
-	readErr  error             // non-nil if file read failed
-}
-
-// langForExt is a thin wrapper over the lang registry.
-// Returns nil, "" for unsupported extensions.
-func langForExt(ext string) (*sitter.Language, string) {
-	l := lang.ForExt(ext)
+	job        treeSitterJob
+	realPath   string
+	content    []byte
+	transcoded bool // content is the file transcoded to UTF-8 (see readSourceFile)
+	tree       *sitter.Tree
+	context    []byte            // extracted imports/globals context
+	imports    map[string]string // structured imports: alias → path (Go only, nil for others)
+	parseErr   error             // non-nil if tree-sitter parsing failed
+	parseTime  time.Duration     // time spent in the tree-sitter parse
+	sections   []sfcSection      // single-file component sections (nil for others)
+	readErr    error             // non-nil if file read failed
+}
+
+// langForPath is a thin wrapper over the lang registry, matching registered
+// basenames (Dockerfile) as well as extensions.
+// Returns nil, "" for unsupported files.
+func langForPath(path string) (*sitter.Language, string) {
+	l := lang.ForPath(path)
 	if l == nil {
 		return nil, ""
 	}
 	return l.Grammar(), l.Name
 }
 
-// isBinaryFile returns true if the file appears to contain binary content.
-// Uses the same heuristic as git: if the first 512 bytes contain a null byte,
-// the file is binary. SQLite files (.db) are handled before this is called.
 // MaxIngestFileSize is the largest file we'll read into memory during
 // ingestion or schema inference. Files above this are silently skipped.
 // Set to 0 to disable the size limit. Configurable via --max-file-size.
 var MaxIngestFileSize int64 = 100 << 20 // 100 MB
 
+// IngestWorkers is how many goroutines parse source files and SQLite records
+// in parallel. Zero or less uses runtime.NumCPU(). Configurable via --workers.
+var IngestWorkers int
+
+// UnnamedBucket keeps matches whose name template fails or renders empty
+// under <parent>/_unnamed/<record id or match index> instead of dropping
+// them. Off by default. Configurable via --unnamed.
+var UnnamedBucket bool
+
+// SkipErrors drops records and JSON files that fail to parse, logging and
+// counting them (see PrintRoutingSummary) instead of failing the ingest.
+// Off by default. Configurable via --skip-errors.
+var SkipErrors bool
+
+// NoProjectFiles leaves files the schema doesn't project (non-code files,
+// and source that fails to parse or yields no constructs) out of the
+// ProjectFilesDir bucket entirely, so only parsed source is retained. Off by
+// default. Configurable via --no-project-files.
+var NoProjectFiles bool
+
+// ProjectFilesDir is the root under which files the schema doesn't project
+// are kept verbatim at their relative paths.
+const ProjectFilesDir = "_project_files"
+
+// DebugSchema records on each projected directory the path of schema node
+// names that produced it, as Properties[graph.SchemaPathProperty]. Off by
+// default. Configurable via --debug-schema.
+var DebugSchema bool
+
+// schemaPathOf returns the schema path of schema under parent ("" for a
+// top-level node), or "" when DebugSchema is off.
+func schemaPathOf(parent string, schema api.Node) string {
+	if !DebugSchema {
+		return ""
+	}
+	if parent == "" {
+		return schema.Name
+	}
+	return parent + " > " + schema.Name
+}
+
+// ingestWorkers returns the effective IngestWorkers.
+func ingestWorkers() int {
+	if IngestWorkers > 0 {
+		return IngestWorkers
+	}
+	return runtime.NumCPU()
+}
+
 // ParseSize parses a human-readable size string (e.g. "100MB", "1GB", "0").
 // Returns bytes. Supported suffixes: KB, MB, GB (case-insensitive).
 func ParseSize(s string) (int64, error) {
@@ -211,6 +282,9 @@ func ensureFile(path, kind string) (os.FileInfo, error) {
 	return info, nil
 }
 
+// isBinaryFile returns true if the file appears to contain binary content,
+// judging by its first binarySniffSize bytes (see looksBinary). SQLite
+// files (.db) are handled before this is called.
 func isBinaryFile(path string) bool {
 	f, err := os.Open(path)
 	if err != nil {
@@ -223,7 +297,7 @@ func isBinaryFile(path string) bool {
 	if err != nil && err != io.EOF {
 		return false
 	}
-	return bytes.ContainsRune(buf[:n], 0)
+	return looksBinary(buf[:n])
 }
 
 func NewEngine(schema *api.Topology, store IngestionTarget) *Engine {
@@ -274,11 +348,12 @@ func SchemaUsesTreeSitter(schema *api.Topology) bool {
 	return hasTreeSitterSelectors(schema.Nodes)
 }
 
-// hasTreeSitterSelectors recursively checks for tree-sitter S-expression selectors.
+// hasTreeSitterSelectors recursively checks for tree-sitter S-expression
+// selectors, including top-level alternations ("[(a) (b)] @scope").
 func hasTreeSitterSelectors(nodes []api.Node) bool {
 	for _, n := range nodes {
 		sel := strings.TrimSpace(n.Selector)
-		if len(sel) > 0 && sel[0] == '(' {
+		if len(sel) > 0 && (sel[0] == '(' || sel[0] == '[') {
 			return true
 		}
 		if hasTreeSitterSelectors(n.Children) {
@@ -306,14 +381,34 @@ func filterNodesByLanguage(nodes []api.Node, langName string) []api.Node {
 // Ingest processes a file or directory.
 // Safe to call multiple times — internal dedup state is reset on each call.
 func (e *Engine) Ingest(path string) error {
+	return e.IngestContext(context.Background(), path)
+}
+
+// IngestContext is Ingest with cancellation: the directory walk, the parse
+// and record workers, and the collectors stop once ctx is done, and it
+// returns ctx.Err().
+func (e *Engine) IngestContext(ctx context.Context, path string) error {
+	if err := CheckSchemaCycles(e.Schema); err != nil {
+		return err
+	}
+
 	// Reset dedup state so stale entries from a prior Ingest don't persist.
 	e.childSeen = make(map[string]map[string]bool)
+	e.mu.Lock()
+	e.fileTimings = nil
+	e.identifiers = nil
+	e.manifest = nil
+	e.mu.Unlock()
 
 	// Create a shared SitterWalker for query cache reuse across files.
 	// Compiled tree-sitter queries are identical for all files of the same
 	// language, so sharing avoids recompilation (e.g., 50K×20 → ~20).
+	// Cleared on return: ReIngestFile must not reuse the closed queries.
 	e.sitterWalker = NewSitterWalker()
-	defer e.sitterWalker.Close()
+	defer func() {
+		e.sitterWalker.Close()
+		e.sitterWalker = nil
+	}()
 
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -323,13 +418,19 @@ func (e *Engine) Ingest(path string) error {
 	if err != nil {
 		realPath = absPath
 	}
-	e.RootPath = realPath
-
 	info, err := os.Stat(realPath)
 	if err != nil {
 		return err
 	}
 
+	// A single-file ingest is rooted at the file's directory so that node
+	// paths, _project_files/ entries, and location metadata are named
+	// relative to it, exactly as they would be for a directory ingest.
+	e.RootPath = realPath
+	if !info.IsDir() {
+		e.RootPath = filepath.Dir(realPath)
+	}
+
 	if info.IsDir() {
 		// Load .gitignore patterns when enabled (default: true).
 		if e.RespectGitignore {
@@ -344,86 +445,51 @@ func (e *Engine) Ingest(path string) error {
 		treeSitter := SchemaUsesTreeSitter(e.Schema)
 
 		if treeSitter {
-			return e.ingestTreeSitterParallel(realPath)
-		}
-
-		return filepath.WalkDir(realPath, func(p string, d os.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				if p != realPath && ShouldSkipDir(d.Name()) {
-					return filepath.SkipDir
-				}
-				// Check gitignore for directories
-				if e.gitignore != nil && p != realPath {
-					rel, relErr := filepath.Rel(realPath, p)
-					if relErr == nil {
-						rel = filepath.ToSlash(rel)
-						if e.gitignore.Match(rel, true) {
-							return filepath.SkipDir
-						}
-					}
-				}
-				return nil
-			}
-			// Check gitignore for files
-			if e.gitignore != nil {
-				rel, relErr := filepath.Rel(realPath, p)
-				if relErr == nil {
-					rel = filepath.ToSlash(rel)
-					if e.gitignore.Match(rel, false) {
-						return nil
-					}
+			err = e.ingestTreeSitterParallel(ctx, realPath)
+		} else {
+			err = e.walkSourceFiles(ctx, realPath, func(p string, info os.FileInfo) error {
+				// Determine if we should parse or treat as raw based on schema type
+				switch filepath.Ext(p) {
+				case ".json", ".jsonl", ".ndjson", ".db":
+					return e.ingestFile(ctx, p, info.ModTime())
 				}
-			}
-			// Skip symlinks to directories (e.g., kodata/templates -> ../templates)
-			// WalkDir doesn't follow symlinks, so d.IsDir() is false for them,
-			// but os.ReadFile will follow and fail with "is a directory".
-			if d.Type()&os.ModeSymlink != 0 {
-				target, err := os.Stat(p)
-				if err == nil && target.IsDir() {
+				// Skip binary files (executables, object files, images, etc.)
+				if isBinaryFile(p) {
 					return nil
 				}
-			}
-			// Determine if we should parse or treat as raw based on schema type
-			ext := filepath.Ext(p)
-			info, err := d.Info()
-			if err != nil {
-				return err
-			}
-			if ShouldSkipFile(p, info.Size()) {
-				return nil
-			}
-			shouldParse := false
-			switch ext {
-			case ".json", ".db":
-				shouldParse = true
-			}
-
-			if shouldParse {
-				return e.ingestFile(p, info.ModTime())
-			}
-			// Skip binary files (executables, object files, images, etc.)
-			if isBinaryFile(p) {
-				return nil
-			}
-			return e.ingestRawFile(p, info.ModTime())
-		})
+				return e.ingestRawFile(p, info.ModTime())
+			})
+		}
+		if err != nil {
+			return err
+		}
+		return e.mirrorSourceTree(ctx, realPath)
 	}
-	info, err = os.Stat(realPath)
-	if err != nil {
+	if e.fileUnchanged(realPath, info) {
+		return nil
+	}
+	if err := e.ingestFile(ctx, realPath, info.ModTime()); err != nil {
 		return err
 	}
-	return e.ingestFile(path, info.ModTime())
+	return e.mirrorRawFile(realPath, info.ModTime())
+}
+
+// fileUnchanged reports whether the file index records realPath with the
+// same mtime and size, meaning a previous ingest is still current.
+func (e *Engine) fileUnchanged(realPath string, info os.FileInfo) bool {
+	if e.fileIndex == nil {
+		return false
+	}
+	entry, ok := e.fileIndex[realPath]
+	return ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size()
 }
 
 // ingestTreeSitterParallel processes a tree-sitter source directory using
 // parallel file parsing. Phase 1 walks the directory and sends file jobs to
 // a worker pool that performs the CPU-heavy tree-sitter parsing in parallel.
 // Phase 2 applies the parsed results sequentially (processNode + store mutations).
-func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
-	numWorkers := runtime.NumCPU()
+func (e *Engine) ingestTreeSitterParallel(ctx context.Context, rootPath string) error {
+	numWorkers := ingestWorkers()
 	jobs := make(chan treeSitterJob, numWorkers*4)
 	parsed := make(chan parsedTreeSitterFile, numWorkers*4)
 
@@ -436,6 +502,11 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 			parser := sitter.NewParser()
 			for job := range jobs {
 				result := parsedTreeSitterFile{job: job}
+				if err := ctx.Err(); err != nil {
+					result.readErr = err
+					parsed <- result
+					continue
+				}
 				absPath, err := filepath.Abs(job.path)
 				if err != nil {
 					result.readErr = err
@@ -447,7 +518,7 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 					result.realPath = absPath
 				}
 
-				result.content, err = os.ReadFile(result.realPath)
+				result.content, result.transcoded, err = readSourceFile(result.realPath)
 				if err != nil {
 					result.readErr = err
 					parsed <- result
@@ -455,7 +526,9 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 				}
 
 				parser.SetLanguage(job.lang)
-				tree, err := parser.ParseCtx(context.Background(), nil, result.content)
+				parseStart := time.Now()
+				tree, err := parseSource(ctx, parser, result.content)
+				result.parseTime = time.Since(parseStart)
 				if err != nil {
 					result.parseErr = err
 				} else {
@@ -470,6 +543,9 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 					if job.langName == "go" {
 						result.imports = e.sitterWalker.ExtractGoImports(tree.RootNode(), result.content, job.lang)
 					}
+					if isSingleFileComponent(job.langName) {
+						result.sections = parseSFCSections(ctx, tree.RootNode(), result.content, job.langName)
+					}
 				}
 				parsed <- result
 			}
@@ -490,6 +566,9 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 			if err != nil {
 				return err
 			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			if d.IsDir() {
 				if p != rootPath && ShouldSkipDir(d.Name()) {
 					return filepath.SkipDir
@@ -528,8 +607,7 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 				return nil
 			}
 
-			ext := filepath.Ext(p)
-			lang, langName := langForExt(ext)
+			lang, langName := e.langFor(p)
 			if lang != nil {
 				// Skip unchanged files when an index is available.
 				// Use resolved (symlink-evaluated) path for consistent cache key,
@@ -539,21 +617,24 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 					if resolved, err := filepath.EvalSymlinks(p); err == nil {
 						lookupPath = resolved
 					}
-					if entry, ok := e.fileIndex[lookupPath]; ok {
-						if entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size() {
-							return nil // unchanged, skip re-parsing
-						}
+					if e.fileUnchanged(lookupPath, info) {
+						e.recordManifest(lookupPath, langName, nil, info.ModTime())
+						return nil // unchanged, skip re-parsing
 					}
 				}
 				fileCount.Add(1)
-				jobs <- treeSitterJob{
+				select {
+				case jobs <- treeSitterJob{
 					path:     p,
 					lang:     lang,
 					langName: langName,
 					modTime:  info.ModTime(),
+				}:
+				case <-ctx.Done():
+					return ctx.Err()
 				}
 			} else {
-				if !isBinaryFile(p) {
+				if !NoProjectFiles && !isBinaryFile(p) {
 					rawFiles = append(rawFiles, struct {
 						path    string
 						modTime time.Time
@@ -588,6 +669,10 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 
 	processed := 0
 	for i := range results {
+		if err := ctx.Err(); err != nil {
+			<-doneCh
+			return err
+		}
 		processed++
 		if processed%1000 == 0 {
 			log.Printf("Ingested %d/%d files...", processed, fileCount.Load())
@@ -615,7 +700,10 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 
 	// Process raw (non-tree-sitter) files sequentially (cheap, no parsing).
 	for _, rf := range rawFiles {
-		if err := e.ingestRawFileUnder(rf.path, "_project_files", rf.modTime); err != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := e.routeToProjectFiles(rf.path, rf.modTime); err != nil {
 			if firstErr == nil {
 				firstErr = err
 			}
@@ -629,16 +717,21 @@ func (e *Engine) ingestTreeSitterParallel(rootPath string) error {
 	return firstErr
 }
 
-func (e *Engine) ingestFile(path string, modTime time.Time) error {
+func (e *Engine) ingestFile(ctx context.Context, path string, modTime time.Time) error {
+	if lang, langName, ok := e.langOverride(path); ok {
+		return e.ingestTreeSitter(path, lang, langName, modTime)
+	}
 	ext := filepath.Ext(path)
 
 	switch ext {
 	case ".db":
-		return e.ingestSQLiteStreaming(path)
+		return e.ingestSQLiteStreaming(ctx, path)
 	case ".json":
 		return e.ingestJSON(path, modTime)
+	case ".jsonl", ".ndjson":
+		return e.ingestJSONLines(ctx, path)
 	default:
-		if lang, langName := langForExt(ext); lang != nil {
+		if lang, langName := langForPath(path); lang != nil {
 			return e.ingestTreeSitter(path, lang, langName, modTime)
 		}
 		if isBinaryFile(path) {
@@ -657,9 +750,17 @@ func (e *Engine) ingestJSON(path string, modTime time.Time) error {
 	if err != nil {
 		return err
 	}
+	e.recordManifest(path, "json", content, modTime)
 
 	var data any
 	if err := json.Unmarshal(content, &data); err != nil {
+		if SkipErrors {
+			log.Printf("ingest: skipping malformed json %s: %v", path, err)
+			e.mu.Lock()
+			e.skippedFiles++
+			e.mu.Unlock()
+			return nil
+		}
 		return fmt.Errorf("failed to parse json %s: %w", path, err)
 	}
 
@@ -671,9 +772,10 @@ func (e *Engine) ingestJSON(path string, modTime time.Time) error {
 	}
 	e.Store.DeleteFileNodes(realPath)
 
-	walker := NewJsonWalker()
+	// Locate matches so single-field leaves get a JSONPath write-back origin.
+	walker := NewLocatingJsonWalker()
 	for _, nodeSchema := range e.Schema.Nodes {
-		if err := e.processNode(nodeSchema, walker, data, "", "", "", modTime, e.Store, nil, nil, nil, nil); err != nil {
+		if err := e.processNode(nodeSchema, schemaPathOf("", nodeSchema), walker, data, "", "", realPath, modTime, e.Store, nil, nil, nil, nil); err != nil {
 			return fmt.Errorf("failed to process schema node %s: %w", nodeSchema.Name, err)
 		}
 	}
@@ -681,22 +783,67 @@ func (e *Engine) ingestJSON(path string, modTime time.Time) error {
 }
 
 // bufferingTarget buffers file nodes for atomic replacement while passing
-// directory updates through immediately.
+// directory updates through immediately. Refs and defs are held back too:
+// replacing the file's old nodes purges theirs, so the new ones are added
+// by flushIndex after the swap.
 type bufferingTarget struct {
 	IngestionTarget
 	bufferedNodes []*graph.Node
+	buffered      map[string]*graph.Node // bufferedNodes by ID
+	refs          []bufferedRef
+	defs          [][2]string // token, dirID
+}
+
+type bufferedRef struct {
+	token, nodeID string
+	lines         []int
 }
 
 func (b *bufferingTarget) AddNode(n *graph.Node) {
 	if n.Mode.IsDir() {
 		b.IngestionTarget.AddNode(n)
 	} else {
-		b.bufferedNodes = append(b.bufferedNodes, n)
+		b.buffer(n)
 	}
 }
 
+func (b *bufferingTarget) buffer(n *graph.Node) {
+	if b.buffered == nil {
+		b.buffered = make(map[string]*graph.Node)
+	}
+	b.buffered[n.ID] = n
+	b.bufferedNodes = append(b.bufferedNodes, n)
+}
+
+func (b *bufferingTarget) AddRef(token, nodeID string, lines ...int) error {
+	b.refs = append(b.refs, bufferedRef{token: token, nodeID: nodeID, lines: lines})
+	return nil
+}
+
 func (b *bufferingTarget) AddDef(token, dirID string) error {
-	return b.IngestionTarget.AddDef(token, dirID)
+	b.defs = append(b.defs, [2]string{token, dirID})
+	return nil
+}
+
+// flushIndex adds the buffered defs and refs to the underlying store.
+func (b *bufferingTarget) flushIndex() error {
+	for _, d := range b.defs {
+		if err := b.IngestionTarget.AddDef(d[0], d[1]); err != nil {
+			return fmt.Errorf("add def %s -> %s: %w", d[0], d[1], err)
+		}
+	}
+	for _, r := range b.refs {
+		if err := b.IngestionTarget.AddRef(r.token, r.nodeID, r.lines...); err != nil {
+			return fmt.Errorf("add ref %s -> %s: %w", r.token, r.nodeID, err)
+		}
+	}
+	return nil
+}
+
+// staleFrom reports whether id is a file node left in the store by an
+// earlier ingest of sourcePath, which the swap is about to replace.
+func (b *bufferingTarget) staleFrom(id, sourcePath string) bool {
+	return b.buffered[id] == nil && sourcePath != "" && originFile(b, id) == sourcePath
 }
 
 // AddFileChildren buffers file nodes for the later ReplaceFileNodes atomic swap
@@ -704,8 +851,8 @@ func (b *bufferingTarget) AddDef(token, dirID string) error {
 // Children are appended in-memory here; the real store sees the complete parent.
 // Safe without locking because bufferingTarget is single-goroutine.
 func (b *bufferingTarget) AddFileChildren(parent *graph.Node, files []*graph.Node) {
-	b.bufferedNodes = append(b.bufferedNodes, files...)
 	for _, f := range files {
+		b.buffer(f)
 		parent.Children = append(parent.Children, f.ID)
 	}
 	b.IngestionTarget.AddNode(parent)
@@ -717,39 +864,34 @@ func (b *bufferingTarget) AddFileChildren(parent *graph.Node, files []*graph.Nod
 // struct — workers do it in parallel, the sequential path does it inline.
 //
 // Steps:
-//  1. Parse error → BROKEN_ node with SHA256(path) ID (no collision)
-//  2. Filter schema nodes by language
+//  1. Parse error (including a panic or timeout) → route to _project_files
+//  2. Filter schema nodes by language (plus a pass per component section)
 //  3. No applicable nodes → route to _project_files
 //  4. Extract address refs
 //  5. processNode for each applicable schema node
 //  6. Invalid query error → route to _project_files
 //  7. No buffered nodes → route to _project_files
+//     (then tag nodes of generated files read-only)
 //  8. Atomic swap via ReplaceFileNodes
 //  9. RecordFile for incremental re-ingestion
 func (e *Engine) processTreeSitterResult(result *parsedTreeSitterFile) error {
-	// 1. Handle parse errors — use SHA256(path) for unique BROKEN_ IDs.
+	manifestPath := result.realPath
+	if manifestPath == "" {
+		manifestPath = result.job.path
+	}
+	manifestContent := result.content
+	if result.transcoded {
+		manifestContent = nil // hash the file's own bytes
+	}
+	e.recordManifest(manifestPath, result.job.langName, manifestContent, result.job.modTime)
+
+	// 1. Parse failed (see parseSource) → route to _project_files/.
 	if result.parseErr != nil {
-		log.Printf("ingest: parse failed for %s (using raw fallback): %v", result.job.path, result.parseErr)
-		pathForID := result.realPath
-		if pathForID == "" {
-			pathForID = result.job.path
-		}
-		sum := sha256.Sum256([]byte(pathForID))
-		fallbackID := "BROKEN_" + hex.EncodeToString(sum[:8])
-		fileNode := &graph.Node{
-			ID:      fallbackID,
-			Mode:    0o444,
-			ModTime: result.job.modTime,
-			Data:    result.content,
-			Origin: &graph.SourceOrigin{
-				FilePath:  result.realPath,
-				StartByte: 0,
-				EndByte:   uint32(len(result.content)),
-			},
-		}
-		e.Store.AddNode(fileNode)
-		e.Store.AddRoot(fileNode)
-		return nil
+		log.Printf("ingest: parse failed for %s (routing to _project_files/): %v", result.job.path, result.parseErr)
+		e.mu.Lock()
+		e.routedFiles[result.job.langName]++
+		e.mu.Unlock()
+		return e.routeToProjectFiles(result.job.path, result.job.modTime)
 	}
 
 	// Select walker: ASTWalker (pure Go, SQL) when available, else SitterWalker (CGO).
@@ -780,13 +922,38 @@ func (e *Engine) processTreeSitterResult(result *parsedTreeSitterFile) error {
 
 	bt := &bufferingTarget{IngestionTarget: e.Store}
 	sourceFile := filepath.Base(result.job.path)
-
-	// 2. Filter schema nodes by language.
-	applicableNodes := filterNodesByLanguage(e.Schema.Nodes, result.job.langName)
+	processStart := time.Now()
+	defer func() { e.recordTiming(result, time.Since(processStart), len(bt.bufferedNodes)) }()
+
+	// 2. Filter schema nodes by language. Each component section is a
+	// further pass over its own tree (SitterWalker only).
+	passes := []schemaPass{{nodes: filterNodesByLanguage(e.Schema.Nodes, result.job.langName), root: root}}
+	if pass, ok := modulePass(e.Schema.Nodes, result.job.langName, result.job.path, root); ok {
+		passes[0] = pass
+	}
+	if _, ok := w.(*SitterWalker); ok {
+		for _, sec := range result.sections {
+			nodes := filterNodesBySection(e.Schema.Nodes, sec.language)
+			if len(nodes) == 0 {
+				continue
+			}
+			passes = append(passes, schemaPass{
+				nodes: nodes,
+				root: SitterRoot{
+					Node:     sec.tree.RootNode(),
+					FileRoot: sec.tree.RootNode(),
+					Source:   result.content,
+					Lang:     sec.lang,
+					LangName: sec.langName,
+				},
+				parentValues: sfcParentValues(result.job.path, sec.name),
+			})
+		}
+	}
 
 	// 3. No applicable schema nodes → route to _project_files/.
-	if len(applicableNodes) == 0 {
-		return e.ingestRawFileUnder(result.job.path, "_project_files", result.job.modTime)
+	if len(passes) == 1 && len(passes[0].nodes) == 0 {
+		return e.routeToProjectFiles(result.job.path, result.job.modTime)
 	}
 
 	// 4. Extract file-level address refs (e.g., HCL variable declarations).
@@ -805,26 +972,40 @@ func (e *Engine) processTreeSitterResult(result *parsedTreeSitterFile) error {
 		}
 	}
 
+	// Files with a generated-code header stay browsable but read-only, and
+	// with GroupGenerated are projected under GeneratedDir.
+	generated := isGeneratedSource(result.content)
+	rootPath := ""
+	if generated && GroupGenerated {
+		rootPath = e.ensureDirPath(bt, "", []string{GeneratedDir}, result.job.modTime)
+	}
+
 	// 5. processNode for each applicable schema node.
-	for _, nodeSchema := range applicableNodes {
-		if err := e.processNode(nodeSchema, w, root, "", sourceFile, result.realPath, result.job.modTime, bt, result.context, fileAddrRefs, nil, result.imports); err != nil {
-			// 6. Invalid query → route to _project_files/.
-			if strings.Contains(err.Error(), "invalid query") {
-				e.mu.Lock()
-				e.routedFiles[result.job.langName]++
-				e.mu.Unlock()
-				return e.ingestRawFileUnder(result.job.path, "_project_files", result.job.modTime)
+	for _, pass := range passes {
+		for _, nodeSchema := range pass.nodes {
+			if err := e.processNode(nodeSchema, schemaPathOf("", nodeSchema), w, pass.root, rootPath, sourceFile, result.realPath, result.job.modTime, bt, result.context, fileAddrRefs, pass.parentValues, result.imports); err != nil {
+				// 6. Invalid query → route to _project_files/.
+				if strings.Contains(err.Error(), "invalid query") {
+					e.mu.Lock()
+					e.routedFiles[result.job.langName]++
+					e.mu.Unlock()
+					return e.routeToProjectFiles(result.job.path, result.job.modTime)
+				}
+				return fmt.Errorf("failed to process schema node %s: %w", nodeSchema.Name, err)
 			}
-			return fmt.Errorf("failed to process schema node %s: %w", nodeSchema.Name, err)
 		}
 	}
 
 	// 7. No nodes produced → route to _project_files/.
 	if len(bt.bufferedNodes) == 0 {
-		return e.ingestRawFileUnder(result.job.path, "_project_files", result.job.modTime)
+		return e.routeToProjectFiles(result.job.path, result.job.modTime)
+	}
+
+	if generated {
+		markGenerated(bt.bufferedNodes...)
 	}
 
-	// 8. Atomic swap of file nodes.
+	// 8. Atomic swap of file nodes, then index the new ones.
 	if ms, ok := e.Store.(*graph.MemoryStore); ok {
 		ms.ReplaceFileNodes(result.realPath, bt.bufferedNodes)
 	} else {
@@ -833,6 +1014,10 @@ func (e *Engine) processTreeSitterResult(result *parsedTreeSitterFile) error {
 			e.Store.AddNode(n)
 		}
 	}
+	if err := bt.flushIndex(); err != nil {
+		return err
+	}
+	e.recordIdentifiers(result, bt.refs)
 
 	// 9. Record file metadata for incremental re-ingestion.
 	if sw, ok := e.Store.(*SQLiteWriter); ok {
@@ -859,14 +1044,16 @@ func (e *Engine) ingestTreeSitter(path string, grammar *sitter.Language, langNam
 		return err
 	}
 
-	content, err := os.ReadFile(realPath)
+	content, transcoded, err := readSourceFile(realPath)
 	if err != nil {
 		return err
 	}
 
 	parser := sitter.NewParser()
 	parser.SetLanguage(grammar)
-	tree, parseErr := parser.ParseCtx(context.Background(), nil, content)
+	parseStart := time.Now()
+	tree, parseErr := parseSource(context.Background(), parser, content)
+	parseTime := time.Since(parseStart)
 
 	result := &parsedTreeSitterFile{
 		job: treeSitterJob{
@@ -875,10 +1062,12 @@ func (e *Engine) ingestTreeSitter(path string, grammar *sitter.Language, langNam
 			langName: langName,
 			modTime:  modTime,
 		},
-		realPath: realPath,
-		content:  content,
-		tree:     tree,
-		parseErr: parseErr,
+		realPath:   realPath,
+		content:    content,
+		transcoded: transcoded,
+		tree:       tree,
+		parseErr:   parseErr,
+		parseTime:  parseTime,
 	}
 
 	// Extract context (imports, globals) when parse succeeded.
@@ -894,6 +1083,9 @@ func (e *Engine) ingestTreeSitter(path string, grammar *sitter.Language, langNam
 		if langName == "go" {
 			result.imports = walker.ExtractGoImports(tree.RootNode(), content, grammar)
 		}
+		if isSingleFileComponent(langName) {
+			result.sections = parseSFCSections(context.Background(), tree.RootNode(), content, langName)
+		}
 	}
 
 	return e.processTreeSitterResult(result)
@@ -904,6 +1096,26 @@ func (e *Engine) ingestRawFile(path string, modTime time.Time) error {
 }
 
 func (e *Engine) ingestRawFileUnder(path, prefix string, modTime time.Time) error {
+	return e.addRawFile(path, prefix, modTime, false)
+}
+
+// routeToProjectFiles projects path verbatim under ProjectFilesDir. Under
+// NoProjectFiles it only drops the nodes an earlier ingest of path left, so
+// a file that stops parsing doesn't keep stale constructs.
+func (e *Engine) routeToProjectFiles(path string, modTime time.Time) error {
+	if NoProjectFiles {
+		if absPath, err := filepath.Abs(path); err == nil {
+			e.Store.DeleteFileNodes(absPath)
+		}
+		return nil
+	}
+	return e.ingestRawFileUnder(path, ProjectFilesDir, modTime)
+}
+
+// addRawFile adds path as a raw file node under prefix at its path relative
+// to RootPath. A mirror copy (see WithRawSource) has no origin and leaves
+// the file's other nodes in place.
+func (e *Engine) addRawFile(path, prefix string, modTime time.Time, mirror bool) error {
 	rel, err := filepath.Rel(e.RootPath, path)
 	if err != nil {
 		return err
@@ -978,30 +1190,48 @@ func (e *Engine) ingestRawFileUnder(path, prefix string, modTime time.Time) erro
 		return nil
 	}
 
-	content, err := os.ReadFile(path)
+	content, transcoded, err := readSourceFile(path)
 	if err != nil {
 		return err
 	}
+	if !mirror {
+		manifestContent := content
+		if transcoded {
+			manifestContent = nil // hash the file's own bytes
+		}
+		e.recordManifest(path, "", manifestContent, modTime)
+	}
 
 	// Use time.Now() to force NFS cache invalidation
 	// modTime := time.Now()
 	// Replaced with actual modTime passed from caller
 
-	absPath, _ := filepath.Abs(path)
-	e.Store.DeleteFileNodes(absPath)
-
 	fileNode := &graph.Node{
 		ID:      fileID,
 		Mode:    0o444,
 		ModTime: modTime,
 		Data:    content,
-		Origin: &graph.SourceOrigin{
+	}
+	if mirror {
+		// Replace an earlier copy in place; it is already linked.
+		_, err := e.Store.GetNode(fileID)
+		e.Store.AddNode(fileNode)
+		if err == nil {
+			return nil
+		}
+	} else {
+		absPath, _ := filepath.Abs(path)
+		e.Store.DeleteFileNodes(absPath)
+		fileNode.Origin = &graph.SourceOrigin{
 			FilePath:  absPath,
 			StartByte: 0,
 			EndByte:   uint32(len(content)),
-		},
+		}
+		if isGeneratedSource(content) {
+			markGenerated(fileNode)
+		}
+		e.Store.AddNode(fileNode)
 	}
-	e.Store.AddNode(fileNode)
 
 	// Link to parent
 	if parentID == "" {
@@ -1017,10 +1247,31 @@ func (e *Engine) ingestRawFileUnder(path, prefix string, modTime time.Time) erro
 	return nil
 }
 
-// ingestSQLiteStreaming processes a SQLite database using a parallel worker pool.
-// Reader goroutine streams rows, workers parse JSON + render templates,
-// collector applies nodes to the store. Saturates all CPU cores.
-func (e *Engine) ingestSQLiteStreaming(dbPath string) error {
+// ingestSQLiteStreaming processes a SQLite database using a parallel worker
+// pool (see ingestRecordStream). Large file content is left in the database
+// and rendered lazily.
+func (e *Engine) ingestSQLiteStreaming(ctx context.Context, dbPath string) error {
+	e.recordManifest(dbPath, "sqlite", nil, time.Time{})
+	table, column := e.Schema.RecordSource()
+	total, err := countSQLiteTable(dbPath, table)
+	if err != nil {
+		total = 0 // unknown; progress omits percentage and ETA
+	}
+	return e.ingestRecordStream(ctx, dbPath, total, func(emit func(id, raw string) error) error {
+		return streamSQLiteTableRaw(dbPath, table, column, emit)
+	})
+}
+
+// ingestRecordStream projects a stream of JSON records through the schema.
+// Reader goroutine streams raw records via read, workers parse JSON + render
+// templates, collector applies nodes to the store. Saturates all CPU cores.
+// dbPath is the SQLite database holding the records for lazy content, or ""
+// to inline all content. total is the record count for progress, 0 if unknown.
+//
+// On cancellation the reader stops, workers pass the remaining jobs through
+// unprocessed, and the collector drops their results, so it returns
+// ctx.Err() without waiting for the rest of the stream.
+func (e *Engine) ingestRecordStream(ctx context.Context, dbPath string, total int, read func(emit func(id, raw string) error) error) error {
 	// Pre-create root directory nodes from schema
 	for _, nodeSchema := range e.Schema.Nodes {
 		rootNode := &graph.Node{
@@ -1031,10 +1282,21 @@ func (e *Engine) ingestSQLiteStreaming(dbPath string) error {
 		e.Store.AddRoot(rootNode)
 	}
 
-	numWorkers := runtime.NumCPU()
+	numWorkers := ingestWorkers()
 	jobs := make(chan recordJob, numWorkers*2)
 	results := make(chan recordResult, numWorkers*2)
 
+	// Backpressure: the reader takes a slot per record and the collector
+	// returns it once the record's nodes are in the store, so raw rows,
+	// parsed records, and pending nodes never exceed the limit combined.
+	limit := e.MaxInFlightRecords
+	if limit <= 0 {
+		limit = DefaultMaxInFlightRecords
+	}
+	inFlight := make(chan struct{}, limit)
+
+	progress := newIngestProgress(total)
+
 	// Workers: parse JSON, render templates, build nodes.
 	// DiagramFuncMap is safe for concurrent reads (built once, then shared).
 	diagramFuncs := e.DiagramFuncMap()
@@ -1047,6 +1309,10 @@ func (e *Engine) ingestSQLiteStreaming(dbPath string) error {
 			defer workerWg.Done()
 			w := NewJsonWalker()
 			for job := range jobs {
+				if err := ctx.Err(); err != nil {
+					results <- recordResult{err: err}
+					continue
+				}
 				results <- processRecord(e.Schema, w, dbPath, job, diagramFuncs, diagramCache)
 			}
 		}()
@@ -1064,13 +1330,20 @@ func (e *Engine) ingestSQLiteStreaming(dbPath string) error {
 		count := 0
 		for res := range results {
 			count++
-			if count%50000 == 0 {
-				log.Printf("Processed %d records...", count)
+			progress.Update(count)
+			if res.err == nil && ctx.Err() != nil {
+				res.err = ctx.Err()
 			}
 			if res.err != nil {
-				if collectErr == nil {
+				if res.malformed && SkipErrors {
+					log.Printf("ingest: skipping malformed record: %v", res.err)
+					e.mu.Lock()
+					e.skippedRecords++
+					e.mu.Unlock()
+				} else if collectErr == nil {
 					collectErr = res.err
 				}
+				<-inFlight
 				continue
 			}
 			for _, node := range res.nodes {
@@ -1094,6 +1367,7 @@ func (e *Engine) ingestSQLiteStreaming(dbPath string) error {
 					parent, err := e.Store.GetNode(link.parentID)
 					if err == nil {
 						parent.Children = append(parent.Children, link.childID)
+						e.Store.AddNode(parent)
 					}
 				}
 			}
@@ -1104,12 +1378,18 @@ func (e *Engine) ingestSQLiteStreaming(dbPath string) error {
 					}
 				}
 			}
+			<-inFlight
 		}
-		log.Printf("Processed %d records total.", count)
+		progress.Done(count)
 	}()
 
-	// Reader: stream raw rows from SQLite (I/O bound, single goroutine)
-	readErr := StreamSQLiteRaw(dbPath, func(id, raw string) error {
+	// Reader: stream raw records (I/O bound, single goroutine)
+	readErr := read(func(id, raw string) error {
+		select {
+		case inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 		jobs <- recordJob{recordID: id, raw: raw}
 		return nil
 	})
@@ -1119,6 +1399,9 @@ func (e *Engine) ingestSQLiteStreaming(dbPath string) error {
 	close(results)  // signal collector: no more results
 	collectWg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if collectErr != nil {
 		return collectErr
 	}
@@ -1134,7 +1417,7 @@ func (e *Engine) ingestSQLiteStreaming(dbPath string) error {
 func processRecord(schema *api.Topology, walker Walker, dbPath string, job recordJob, extraFuncs template.FuncMap, tmplCache *sync.Map) recordResult {
 	var parsed any
 	if err := json.Unmarshal([]byte(job.raw), &parsed); err != nil {
-		return recordResult{err: fmt.Errorf("parse record %s: %w", job.recordID, err)}
+		return recordResult{err: fmt.Errorf("parse record %s: %w", job.recordID, err), malformed: true}
 	}
 
 	wrapper := []any{parsed}
@@ -1145,9 +1428,13 @@ func processRecord(schema *api.Topology, walker Walker, dbPath string, job recor
 	// should be accessible via {{._parent.item.Advisory.Severity}} etc.
 	recordValues, _ := parsed.(map[string]any)
 
+	delims := schemaDelims(schema)
+	src := recordSource{dbPath: dbPath}
+	src.table, src.column = schema.RecordSource()
 	for _, nodeSchema := range schema.Nodes {
+		rootSchemaPath := schemaPathOf("", nodeSchema)
 		for _, childSchema := range nodeSchema.Children {
-			collectNodes(&result, childSchema, walker, wrapper, nodeSchema.Name, dbPath, job.recordID, extraFuncs, tmplCache, recordValues)
+			collectNodes(&result, childSchema, schemaPathOf(rootSchemaPath, childSchema), walker, wrapper, nodeSchema.Name, src, job.recordID, delims, extraFuncs, tmplCache, recordValues)
 			if result.err != nil {
 				return result
 			}
@@ -1160,9 +1447,10 @@ func processRecord(schema *api.Topology, walker Walker, dbPath string, job recor
 // collectNodes is the pure equivalent of processNode — builds node lists
 // without any store access. Safe to call from multiple goroutines.
 //
-// extraFuncs/tmplCache are threaded through for content template rendering
-// (e.g., {{diagram}}). When nil, uses base RenderTemplate.
-func collectNodes(result *recordResult, schema api.Node, walker Walker, ctx any, parentPath, dbPath, recordID string, extraFuncs template.FuncMap, tmplCache *sync.Map, parentMatchValues map[string]any) {
+// Templates are parsed with delims. extraFuncs/tmplCache are threaded
+// through for content template rendering (e.g., {{diagram}}). When nil,
+// uses the base functions only.
+func collectNodes(result *recordResult, schema api.Node, schemaPath string, walker Walker, ctx any, parentPath string, src recordSource, recordID string, delims machetmpl.Delims, extraFuncs template.FuncMap, tmplCache *sync.Map, parentMatchValues map[string]any) {
 	matches, err := walker.Query(ctx, schema.Selector)
 	if err != nil {
 		result.err = fmt.Errorf("query failed for %s: %w", schema.Name, err)
@@ -1177,8 +1465,9 @@ func collectNodes(result *recordResult, schema api.Node, walker Walker, ctx any,
 	}
 
 	for _, match := range matches {
-		name, err := RenderTemplate(schema.Name, match.Values())
-		if err != nil {
+		name, err := delims.Render(schema.Name, match.Values())
+		unnamed := UnnamedBucket && (err != nil || graph.IsBlankName(name))
+		if err != nil && !unnamed {
 			// Skip records whose structure doesn't match this schema node.
 			// This allows a single schema to handle mixed-format data sources
 			// (e.g. vunnel OS format + OSV format in the same results table).
@@ -1186,7 +1475,36 @@ func collectNodes(result *recordResult, schema api.Node, walker Walker, ctx any,
 			continue
 		}
 
-		currentPath := filepath.Join(parentPath, name)
+		dirPath := parentPath
+		if schema.Parent != "" {
+			parts, err := renderParentPath(delims, schema.Parent, match.Values())
+			if err != nil {
+				log.Printf("[WARN] skipping record: %v", err)
+				continue
+			}
+			for _, part := range parts {
+				partPath := filepath.Join(dirPath, part)
+				result.nodes = append(result.nodes, &graph.Node{
+					ID:      toNodeID(partPath),
+					Mode:    os.ModeDir | 0o555,
+					ModTime: time.Unix(0, 0),
+				})
+				result.parentLinks = append(result.parentLinks, parentLink{childID: toNodeID(partPath), parentID: toNodeID(dirPath)})
+				dirPath = partPath
+			}
+		}
+		if unnamed {
+			bucket := filepath.Join(dirPath, graph.UnnamedDir)
+			result.nodes = append(result.nodes, &graph.Node{
+				ID:      toNodeID(bucket),
+				Mode:    os.ModeDir | 0o555,
+				ModTime: time.Unix(0, 0),
+			})
+			result.parentLinks = append(result.parentLinks, parentLink{childID: toNodeID(bucket), parentID: toNodeID(dirPath)})
+			dirPath, name = bucket, recordID
+		}
+
+		currentPath := filepath.Join(dirPath, name)
 		id := toNodeID(currentPath)
 
 		node := &graph.Node{
@@ -1194,12 +1512,15 @@ func collectNodes(result *recordResult, schema api.Node, walker Walker, ctx any,
 			Mode:    os.ModeDir | 0o555,
 			ModTime: time.Unix(0, 0),
 		}
+		if schemaPath != "" {
+			node.Properties = map[string][]byte{graph.SchemaPathProperty: []byte(schemaPath)}
+		}
 
 		// Recurse children
 		nextCtx := match.Context()
 		if nextCtx != nil {
 			for _, childSchema := range schema.Children {
-				collectNodes(result, childSchema, walker, nextCtx, currentPath, dbPath, recordID, extraFuncs, tmplCache, match.Values())
+				collectNodes(result, childSchema, schemaPathOf(schemaPath, childSchema), walker, nextCtx, currentPath, src, recordID, delims, extraFuncs, tmplCache, match.Values())
 				if result.err != nil {
 					return
 				}
@@ -1208,7 +1529,7 @@ func collectNodes(result *recordResult, schema api.Node, walker Walker, ctx any,
 
 		// Process files
 		for _, fileSchema := range schema.Files {
-			fileName, err := RenderTemplate(fileSchema.Name, match.Values())
+			fileName, err := delims.Render(fileSchema.Name, match.Values())
 			if err != nil {
 				log.Printf("collectNodes: skip file name render %q: %v", fileSchema.Name, err)
 				continue
@@ -1218,9 +1539,9 @@ func collectNodes(result *recordResult, schema api.Node, walker Walker, ctx any,
 
 			var content string
 			if len(extraFuncs) > 0 && tmplCache != nil {
-				content, err = RenderTemplateWithFuncs(fileSchema.ContentTemplate, match.Values(), extraFuncs, tmplCache)
+				content, err = delims.RenderWithFuncs(fileSchema.ContentTemplate, match.Values(), extraFuncs, tmplCache)
 			} else {
-				content, err = RenderTemplate(fileSchema.ContentTemplate, match.Values())
+				content, err = delims.Render(fileSchema.ContentTemplate, match.Values())
 			}
 			if err != nil {
 				log.Printf("collectNodes: skip file content render %q: %v", fileId, err)
@@ -1233,15 +1554,22 @@ func collectNodes(result *recordResult, schema api.Node, walker Walker, ctx any,
 				ModTime: time.Unix(0, 0),
 			}
 
-			// Inline small content, lazy-resolve large content from SQLite
-			if len(content) > inlineThreshold {
+			// Inline small content, lazy-resolve large content from SQLite.
+			// A computed leaf keeps its command's input inline.
+			switch {
+			case fileSchema.Command != "":
+				fileNode.Data = []byte(content)
+				fileNode.Properties = map[string][]byte{graph.CommandProperty: []byte(fileSchema.Command)}
+			case src.dbPath != "" && len(content) > inlineThreshold:
 				fileNode.Ref = &graph.ContentRef{
-					DBPath:     dbPath,
+					DBPath:     src.dbPath,
+					Table:      src.table,
+					Column:     src.column,
 					RecordID:   recordID,
 					Template:   fileSchema.ContentTemplate,
 					ContentLen: int64(len(content)),
 				}
-			} else {
+			default:
 				fileNode.Data = []byte(content)
 			}
 
@@ -1253,7 +1581,7 @@ func collectNodes(result *recordResult, schema api.Node, walker Walker, ctx any,
 
 		// Collect schema-declared refs (cross-reference tokens for callers/)
 		for _, refTmpl := range schema.Refs {
-			token, err := RenderTemplate(refTmpl, match.Values())
+			token, err := delims.Render(refTmpl, match.Values())
 			if err != nil {
 				result.err = fmt.Errorf("failed to render ref %s: %w", refTmpl, err)
 				return
@@ -1264,11 +1592,119 @@ func collectNodes(result *recordResult, schema api.Node, walker Walker, ctx any,
 		}
 
 		// Link to parent (collector will apply this)
-		parentID := toNodeID(parentPath)
+		parentID := toNodeID(dirPath)
 		result.parentLinks = append(result.parentLinks, parentLink{childID: id, parentID: parentID})
 	}
 }
 
+// linkChild appends node to the children of the directory at parentPath,
+// or registers it as a root when parentPath is empty.
+func (e *Engine) linkChild(store IngestionTarget, parentPath string, node *graph.Node) {
+	if parentPath == "" {
+		store.AddRoot(node)
+		return
+	}
+	parentId := toNodeID(parentPath)
+	parent, err := store.GetNode(parentId)
+	if err != nil {
+		return
+	}
+	if e.childSeen[parentId] == nil {
+		e.childSeen[parentId] = make(map[string]bool, len(parent.Children))
+		for _, c := range parent.Children {
+			e.childSeen[parentId][c] = true
+		}
+	}
+	if !e.childSeen[parentId][node.ID] {
+		e.childSeen[parentId][node.ID] = true
+		parent.Children = append(parent.Children, node.ID)
+		store.AddNode(parent)
+	}
+}
+
+// ensureDirPath creates the directories parts under base that don't exist
+// yet, linking each to its parent, and returns the joined path.
+func (e *Engine) ensureDirPath(store IngestionTarget, base string, parts []string, modTime time.Time) string {
+	dirPath := base
+	for _, part := range parts {
+		// Not filepath.Dir(dirPath): that is "." for a top-level
+		// directory, which must become a root instead.
+		parentPath := dirPath
+		dirPath = filepath.Join(dirPath, part)
+		if _, err := store.GetNode(toNodeID(dirPath)); err == nil {
+			continue
+		}
+		node := &graph.Node{
+			ID:      toNodeID(dirPath),
+			Mode:    os.ModeDir | 0o555,
+			ModTime: modTime,
+		}
+		store.AddNode(node)
+		e.linkChild(store, parentPath, node)
+	}
+	return dirPath
+}
+
+// renderParentPath renders a Node.Parent template into path segments.
+// Empty segments are dropped, so an empty rendering yields no segments and
+// the match stays in the enclosing directory; "." and ".." are rejected so
+// a match cannot escape it.
+func renderParentPath(delims machetmpl.Delims, tmpl string, values map[string]any) ([]string, error) {
+	rendered, err := delims.Render(tmpl, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render parent %s: %w", tmpl, err)
+	}
+	var parts []string
+	for _, part := range strings.Split(rendered, "/") {
+		switch part {
+		case "":
+			continue
+		case ".", "..":
+			return nil, fmt.Errorf("parent %q renders to invalid path %q", tmpl, rendered)
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// existingFileChild returns the ID of the child of existing (with ID id)
+// that one of the schema's files would overwrite for values, or "" if none.
+func existingFileChild(delims machetmpl.Delims, existing *graph.Node, id string, files []api.Leaf, values map[string]any) string {
+	for _, f := range files {
+		fileName, err := delims.Render(f.Name, values)
+		if err != nil {
+			continue
+		}
+		if childID := id + "/" + fileName; slices.Contains(existing.Children, childID) {
+			return childID
+		}
+	}
+	return ""
+}
+
+// collidingFile returns the ID of the file node a construct from sourcePath
+// at id would overwrite, or "" if id is free for it. Nodes from an earlier
+// ingest of the same file don't count: re-ingesting keeps the IDs.
+func collidingFile(delims machetmpl.Delims, store IngestionTarget, id, sourcePath string, files []api.Leaf, values map[string]any) string {
+	existing, err := store.GetNode(id)
+	if err != nil {
+		return ""
+	}
+	child := existingFileChild(delims, existing, id, files, values)
+	if bt, ok := store.(*bufferingTarget); ok && child != "" && bt.staleFrom(child, sourcePath) {
+		return ""
+	}
+	return child
+}
+
+// originFile returns the source file a node was projected from, or "unknown".
+func originFile(store IngestionTarget, id string) string {
+	if n, err := store.GetNode(id); err == nil && n.Origin != nil && n.Origin.FilePath != "" {
+		return n.Origin.FilePath
+	}
+	return "unknown"
+}
+
 // toNodeID converts a filesystem path to a graph node ID by normalizing
 // separators and stripping the leading slash.
 func toNodeID(p string) string {
@@ -1283,317 +1719,733 @@ func dedupSuffix(sourceFile string) string {
 	return ".from_" + sanitized
 }
 
-func (e *Engine) processNode(schema api.Node, walker Walker, ctx any, parentPath, sourceFile, absSourceFile string, modTime time.Time, store IngestionTarget, fileContext []byte, fileAddressRefs []string, parentMatchValues map[string]any, fileImports map[string]string) error {
-	matches, err := walker.Query(ctx, schema.Selector)
-	if err != nil {
-		return fmt.Errorf("query failed for %s: %w", schema.Name, err)
-	}
+// maxProjectionDepth bounds how deeply processNode nests schema nodes
+// within one file. Only recursive schemas over deeply nested sources
+// (generated markup, callback pyramids) come near it.
+const maxProjectionDepth = 4096
+
+// fileIngest is the per-file state shared by every schema node projected
+// from one parsed file.
+type fileIngest struct {
+	walker          Walker
+	sourceFile      string
+	absSourceFile   string
+	modTime         time.Time
+	store           IngestionTarget
+	fileContext     []byte
+	fileAddressRefs []string
+	fileImports     map[string]string
+}
 
-	// Inject _parent context into child matches when parent values are available.
-	if parentMatchValues != nil {
-		for i, m := range matches {
-			matches[i] = &parentAwareMatch{inner: m, parentValues: parentMatchValues}
-		}
-	}
+// schemaFrame is one application of a schema node to a query context on
+// processNode's stack.
+type schemaFrame struct {
+	schema       api.Node
+	schemaPath   string
+	ctx          any
+	parentPath   string
+	parentValues map[string]any
+	depth        int
+	grouped      bool // the parent schema node is a "$" grouping node
+
+	// A recursive node re-applied to one of its own matches reuses the
+	// matches of the query that found it (those inside the match) rather
+	// than querying again: tree-sitter tracks every open match while it
+	// descends, so re-querying each level of deep nesting costs O(depth²)
+	// per query.
+	inherit   bool
+	inherited []Match
+	raw       []Match // recursive nodes: query results before _parent wrapping
+
+	queried      bool
+	matches      []Match
+	siblingNames map[string]int // recursive nodes: rendered name → count
+	next         int            // index of the next match to open
+	pending      *openedMatch   // waiting for its children before its files are written
+}
 
-	for _, match := range matches {
-		// Skip self-match if requested (e.g. for recursive schemas to avoid infinite loops)
-		if schema.SkipSelfMatch {
-			// Check for Tree-sitter node equality using byte ranges
-			if parentRoot, ok := ctx.(SitterRoot); ok {
-				if childCtx, ok := match.Context().(SitterRoot); ok {
-					if parentRoot.Node.StartByte() == childCtx.Node.StartByte() &&
-						parentRoot.Node.EndByte() == childCtx.Node.EndByte() &&
-						parentRoot.Node.Type() == childCtx.Node.Type() {
-						continue
-					}
-				}
+// openedMatch is a match whose directory node exists but whose files are not
+// yet written.
+type openedMatch struct {
+	match       Match
+	id          string
+	currentPath string
+	clause      *graph.SourceOrigin // under MergeClauses, the clauses before this one
+}
+
+// processNode projects schema against ctx, and each match's children
+// against the match, depth first: a match's directory is created, its
+// children are projected, then its files are written. The traversal keeps
+// an explicit stack instead of recursing, so recursive schemas over deeply
+// nested sources don't grow the goroutine stack with the input.
+func (e *Engine) processNode(schema api.Node, schemaPath string, walker Walker, ctx any, parentPath, sourceFile, absSourceFile string, modTime time.Time, store IngestionTarget, fileContext []byte, fileAddressRefs []string, parentMatchValues map[string]any, fileImports map[string]string) error {
+	in := &fileIngest{
+		walker:          walker,
+		sourceFile:      sourceFile,
+		absSourceFile:   absSourceFile,
+		modTime:         modTime,
+		store:           store,
+		fileContext:     fileContext,
+		fileAddressRefs: fileAddressRefs,
+		fileImports:     fileImports,
+	}
+	stack := []*schemaFrame{{
+		schema:       schema,
+		schemaPath:   schemaPath,
+		ctx:          ctx,
+		parentPath:   parentPath,
+		parentValues: parentMatchValues,
+	}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		if f.pending != nil {
+			if err := e.closeMatch(f.schema, f.pending, in); err != nil {
+				return err
 			}
+			f.pending = nil
 		}
-
-		name, err := RenderTemplate(schema.Name, match.Values())
+		if !f.queried {
+			if err := f.query(walker); err != nil {
+				return err
+			}
+		}
+		if f.next == len(f.matches) {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		i, match := f.next, f.matches[f.next]
+		f.next++
+		om, err := e.openMatch(f, i, match, in)
 		if err != nil {
-			log.Printf("[WARN] skipping file: failed to render name %s: %v", schema.Name, err)
+			return err
+		}
+		if om == nil {
 			continue
 		}
+		f.pending = om
 
-		// Normalize path
-		currentPath := filepath.Join(parentPath, name)
-		id := toNodeID(currentPath)
+		nextCtx := match.Context()
+		if nextCtx == nil {
+			continue
+		}
+		if f.depth >= maxProjectionDepth {
+			log.Printf("[WARN] %s: not projecting below %d nested schema levels", om.id, maxProjectionDepth)
+			continue
+		}
+		// Push in reverse so children run in schema order, followed by the
+		// node itself again when it is recursive.
+		if f.schema.Recursive {
+			self := f.child(f.schema, f.schemaPath, nextCtx, om, match)
+			self.inherited, self.inherit = matchesWithin(f.raw, match)
+			stack = append(stack, self)
+		}
+		for k := len(f.schema.Children) - 1; k >= 0; k-- {
+			child := f.schema.Children[k]
+			stack = append(stack, f.child(child, schemaPathOf(f.schemaPath, child), nextCtx, om, match))
+		}
+	}
+	return nil
+}
 
-		// Dedup: when this node has files and a node with the same ID
-		// already exists with file children (i.e., from a different source file),
-		// append a source-file suffix to disambiguate.
-		// This handles cases like multiple init() functions across Go files.
-		if len(schema.Files) > 0 && sourceFile != "" {
-			if existing, err := store.GetNode(id); err == nil && len(existing.Children) > 0 {
-				suffix := dedupSuffix(sourceFile)
-				name = name + suffix
-				currentPath = filepath.Join(parentPath, name)
-				id = toNodeID(currentPath)
-			}
+// child returns the frame applying schema to match's context, under om.
+func (f *schemaFrame) child(schema api.Node, schemaPath string, ctx any, om *openedMatch, match Match) *schemaFrame {
+	return &schemaFrame{
+		schema:       schema,
+		schemaPath:   schemaPath,
+		ctx:          ctx,
+		parentPath:   om.currentPath,
+		parentValues: match.Values(),
+		depth:        f.depth + 1,
+		grouped:      f.schema.Selector == "$",
+	}
+}
+
+// query runs the frame's selector against its context.
+func (f *schemaFrame) query(walker Walker) error {
+	f.queried = true
+	matches := f.inherited
+	if !f.inherit {
+		var err error
+		matches, err = walker.Query(f.ctx, f.schema.Selector)
+		if err != nil {
+			return fmt.Errorf("query failed for %s: %w", f.schema.Name, err)
 		}
+	}
+	if f.schema.Recursive {
+		f.raw = slices.Clone(matches)
+	}
 
-		// Create/Update Node — preserve existing children when merging
-		// multiple files into the same node (e.g. multiple .go files in one package).
-		var existingChildren []string
-		if existing, err := store.GetNode(id); err == nil {
-			existingChildren = existing.Children
+	// Inject _parent context into child matches when parent values are available.
+	if f.parentValues != nil {
+		for i, m := range matches {
+			matches[i] = &parentAwareMatch{inner: m, parentValues: f.parentValues}
 		}
+	}
 
-		node := &graph.Node{
-			ID:       id,
-			Mode:     os.ModeDir | 0o555, // Read-only dir
-			ModTime:  modTime,            // Propagate source file time
-			Children: existingChildren,
-		}
-
-		// Store language name, package name for callees/ resolution (SitterWalker path)
-		if _, ok := walker.(*SitterWalker); ok {
-			if ctxAny := match.Context(); ctxAny != nil {
-				if root, ok := ctxAny.(SitterRoot); ok && root.LangName != "" {
-					if node.Properties == nil {
-						node.Properties = make(map[string][]byte)
-					}
-					node.Properties["lang"] = []byte(root.LangName)
+	// Recursive nodes only project the outermost matches at each level;
+	// nested matches are picked up when the node is re-applied below.
+	if f.schema.Recursive {
+		matches = outermostMatches(f.ctx, matches)
+		f.siblingNames = make(map[string]int, len(matches))
+	}
+	f.matches = matches
+	return nil
+}
 
-					// Extract Go package name for qualified def resolution
-					if root.LangName == "go" && root.FileRoot != nil {
-						if pkgName := extractGoPackageName(root.FileRoot, root.Source, root.Lang); pkgName != "" {
-							node.Properties["pkg"] = []byte(pkgName)
-						}
-					}
-				}
-			}
+// matchesWithin returns the matches among raw whose @scope lies inside
+// outer's @scope. ok is false when a scope origin is missing and the
+// matches have to be found by querying instead.
+func matchesWithin(raw []Match, outer Match) (within []Match, ok bool) {
+	op, isOP := outer.(OriginProvider)
+	if !isOP {
+		return nil, false
+	}
+	start, end, ok := op.CaptureOrigin("scope")
+	if !ok {
+		return nil, false
+	}
+	within = make([]Match, 0)
+	for _, m := range raw {
+		mop, isOP := m.(OriginProvider)
+		if !isOP {
+			return nil, false
 		}
+		ms, me, ok := mop.CaptureOrigin("scope")
+		if !ok {
+			return nil, false
+		}
+		if ms >= start && me <= end {
+			within = append(within, m)
+		}
+	}
+	return within, true
+}
 
-		// Store structured imports (avoids regex re-parsing at query time).
-		// Independent of walker type — persist whenever fileImports is non-nil.
-		if fileImports != nil {
-			if node.Properties == nil {
-				node.Properties = make(map[string][]byte)
-			}
-			if importJSON, err := json.Marshal(fileImports); err == nil {
-				node.Properties["imports"] = importJSON
-			}
+// openMatch creates the directory node for the i-th match of f and
+// registers its defs and schema refs. It returns nil when the match is
+// skipped.
+func (e *Engine) openMatch(f *schemaFrame, i int, match Match, in *fileIngest) (*openedMatch, error) {
+	delims := e.delims()
+	schema, ctx, parentPath, schemaPath, siblingNames := f.schema, f.ctx, f.parentPath, f.schemaPath, f.siblingNames
+	walker, store, modTime := in.walker, in.store, in.modTime
+	sourceFile, absSourceFile, fileImports := in.sourceFile, in.absSourceFile, in.fileImports
+
+	// Skip self-match if requested (e.g. for recursive schemas to avoid infinite loops)
+	if schema.SkipSelfMatch && isSelfMatch(ctx, match) {
+		return nil, nil
+	}
+
+	name, err := delims.Render(schema.Name, match.Values())
+	unnamed := UnnamedBucket && (err != nil || graph.IsBlankName(name))
+	if err != nil && !unnamed {
+		log.Printf("[WARN] skipping file: failed to render name %s: %v", schema.Name, err)
+		return nil, nil
+	}
+
+	// Disambiguate repeated sibling names: div, div[2], div[3], ...
+	if siblingNames != nil {
+		siblingNames[name]++
+		if n := siblingNames[name]; n > 1 {
+			name = fmt.Sprintf("%s[%d]", name, n)
 		}
-		store.AddNode(node)
+	}
 
-		// Register definition: construct name → directory ID
-		if len(schema.Files) > 0 {
-			if err := store.AddDef(name, id); err != nil {
-				return fmt.Errorf("add def %s -> %s: %w", name, id, err)
-			}
-			// Register qualified definition (package.name → directory ID)
-			if node.Properties != nil {
-				if pkg, ok := node.Properties["pkg"]; ok && len(pkg) > 0 {
-					qualKey := string(pkg) + "." + name
-					if err := store.AddDef(qualKey, id); err != nil {
-						return fmt.Errorf("add qualified def %s -> %s: %w", qualKey, id, err)
+	// Re-parent under a directory derived from the match, e.g. a method
+	// under its receiver type.
+	dirPath := parentPath
+	if schema.Parent != "" {
+		parts, err := renderParentPath(delims, schema.Parent, match.Values())
+		if err != nil {
+			log.Printf("[WARN] skipping match: %v", err)
+			return nil, nil
+		}
+		dirPath = e.ensureDirPath(store, parentPath, parts, modTime)
+	}
+	if SplitVisibility && f.grouped && len(schema.Files) > 0 && !unnamed {
+		if dir, ok := visibilityDir(match, name); ok {
+			dirPath = e.ensureDirPath(store, dirPath, []string{dir}, modTime)
+		}
+	}
+	if unnamed {
+		dirPath = e.ensureDirPath(store, dirPath, []string{graph.UnnamedDir}, modTime)
+		name = strconv.Itoa(i)
+	}
+
+	// Normalize path
+	currentPath := filepath.Join(dirPath, name)
+	id := toNodeID(currentPath)
+
+	// Dedup: when this node has files and a node with the same ID
+	// already exists with those files (i.e., from a different source file),
+	// rename it as Collisions says; by default, append a source-file
+	// suffix. This handles cases like multiple init() functions across Go
+	// files, and overloaded methods.
+	// A directory created only as a Parent (holding e.g. methods/) is
+	// claimed rather than suffixed.
+	// Under MergeClauses, a construct this file already projected here is
+	// an earlier clause of this one rather than a collision.
+	overload := "" // the bare name, when name carries an arity suffix
+	var clause *graph.SourceOrigin
+	if schema.MergeClauses && absSourceFile != "" {
+		clause = priorClause(store, id, schema.SourceLeafName(), absSourceFile)
+	}
+	if len(schema.Files) > 0 && sourceFile != "" && clause == nil {
+		if taken := collidingFile(delims, store, id, absSourceFile, schema.Files, match.Values()); taken != "" {
+			// Overloads first try a parameter-count suffix (add_2args);
+			// only same-arity collisions fall back to the strategy.
+			an, ok := arityName(name, match)
+			anID := toNodeID(filepath.Join(dirPath, an))
+			if ok && collidingFile(delims, store, anID, absSourceFile, schema.Files, match.Values()) == "" {
+				overload, name = name, an
+				currentPath, id = filepath.Join(dirPath, name), anID
+			} else {
+				if Collisions == CollisionError {
+					return nil, fmt.Errorf("node ID collision: %s from %s is already projected from %s", id, absSourceFile, originFile(store, taken))
+				}
+				// Under CollisionSubdir the construct keeps its name and
+				// moves into a directory beside the earlier one's files.
+				dir := dirPath
+				if Collisions == CollisionSubdir {
+					dir = currentPath
+				}
+				// Same-named files in different directories share a suffix;
+				// number further collisions rather than overwrite the earlier
+				// construct.
+				for n := 1; ; n++ {
+					leaf := Collisions.candidate(name, sourceFile, n)
+					currentPath = filepath.Join(dir, leaf)
+					id = toNodeID(currentPath)
+					taken := collidingFile(delims, store, id, absSourceFile, schema.Files, match.Values())
+					if taken == "" {
+						if Collisions != CollisionSubdir {
+							name = leaf
+						}
+						break
+					}
+					if Collisions != CollisionNumber {
+						log.Printf("[WARN] node ID collision: %s from %s is already projected from %s", id, absSourceFile, originFile(store, taken))
 					}
 				}
+				dirPath = dir
 			}
 		}
+	}
 
-		// Register schema-declared refs (cross-reference tokens for callers/)
-		for _, refTmpl := range schema.Refs {
-			token, err := RenderTemplate(refTmpl, match.Values())
-			if err != nil {
-				return fmt.Errorf("failed to render ref %s: %w", refTmpl, err)
-			}
-			if token != "" {
-				if err := store.AddRef(token, id); err != nil {
-					return fmt.Errorf("add ref %s -> %s: %w", token, id, err)
+	// Create/Update Node — preserve existing children when merging
+	// multiple files into the same node (e.g. multiple .go files in one package).
+	var existingChildren []string
+	if existing, err := store.GetNode(id); err == nil {
+		existingChildren = existing.Children
+	}
+
+	node := &graph.Node{
+		ID:       id,
+		Mode:     os.ModeDir | 0o555, // Read-only dir
+		ModTime:  modTime,            // Propagate source file time
+		Children: existingChildren,
+	}
+
+	// Store language name, package name for callees/ resolution (SitterWalker path)
+	if _, ok := walker.(*SitterWalker); ok {
+		if ctxAny := match.Context(); ctxAny != nil {
+			if root, ok := ctxAny.(SitterRoot); ok && root.LangName != "" {
+				if node.Properties == nil {
+					node.Properties = make(map[string][]byte)
+				}
+				node.Properties["lang"] = []byte(root.LangName)
+				if len(schema.Files) > 0 && root.Node != nil {
+					node.Properties[graph.ComplexityProperty] = strconv.AppendInt(nil, int64(cyclomaticComplexity(root.Node)), 10)
 				}
-			}
-		}
 
-		// Link to parent
-		if parentPath == "" {
-			store.AddRoot(node)
-		} else {
-			parentId := toNodeID(parentPath)
-			parent, err := store.GetNode(parentId)
-			if err == nil {
-				if e.childSeen[parentId] == nil {
-					e.childSeen[parentId] = make(map[string]bool, len(parent.Children))
-					for _, c := range parent.Children {
-						e.childSeen[parentId][c] = true
+				// Extract Go package name for qualified def resolution
+				if root.LangName == "go" && root.FileRoot != nil {
+					if pkgName := extractGoPackageName(root.FileRoot, root.Source, root.Lang); pkgName != "" {
+						node.Properties["pkg"] = []byte(pkgName)
+					}
+				}
+				// Elixir: the enclosing module qualifies defs (MyApp.Accounts.create)
+				if root.LangName == "elixir" && root.Node != nil {
+					if mod := enclosingElixirModule(root.Node.Parent(), root.Source); mod != "" {
+						node.Properties["pkg"] = []byte(mod)
 					}
 				}
-				if !e.childSeen[parentId][id] {
-					e.childSeen[parentId][id] = true
-					parent.Children = append(parent.Children, id)
-					store.AddNode(parent)
+				// Scala: the package and enclosing types qualify defs
+				// (com.example.etl.WordCount.run)
+				if root.LangName == "scala" && root.Node != nil {
+					if owner := enclosingScalaOwner(root.Node.Parent(), root.Source); owner != "" {
+						node.Properties["pkg"] = []byte(owner)
+					}
 				}
 			}
 		}
+	}
 
-		// Recurse children
-		nextCtx := match.Context()
-		if nextCtx != nil {
-			for _, childSchema := range schema.Children {
-				if err := e.processNode(childSchema, walker, nextCtx, currentPath, sourceFile, absSourceFile, modTime, store, fileContext, fileAddressRefs, match.Values(), fileImports); err != nil {
-					return err
-				}
-			}
+	if schemaPath != "" {
+		if node.Properties == nil {
+			node.Properties = make(map[string][]byte)
 		}
+		node.Properties[graph.SchemaPathProperty] = []byte(schemaPath)
+	}
 
-		// Extract calls for this match (refs index)
-		var calls []string
-		if sw, ok := walker.(*SitterWalker); ok {
-			if ctxAny := match.Context(); ctxAny != nil {
-				if root, ok := ctxAny.(SitterRoot); ok {
-					if c, err := sw.ExtractCalls(root.Node, root.Source, root.Lang, root.LangName); err == nil {
-						calls = c
-					}
-					// Extract address-aware refs (env:, path:, url:) from the
-					// match scope. These typed tokens bridge across languages
-					// (e.g., Go os.Getenv calls within this function scope).
-					if addrRefs, err := sw.ExtractAddressRefs(root.Node, root.Source, root.Lang, root.LangName); err == nil {
-						calls = append(calls, addrRefs...)
-					}
-				}
-			}
+	// Store structured imports (avoids regex re-parsing at query time).
+	// Independent of walker type — persist whenever fileImports is non-nil.
+	if fileImports != nil {
+		if node.Properties == nil {
+			node.Properties = make(map[string][]byte)
+		}
+		if importJSON, err := json.Marshal(fileImports); err == nil {
+			node.Properties["imports"] = importJSON
+		}
+	}
+	if node.Properties == nil {
+		node.Properties = make(map[string][]byte)
+	}
+	node.Properties[graph.StableIDProperty] = strconv.AppendUint(nil, graph.StableID(id, e.relPath(absSourceFile)), 10)
+	if schema.SourceLeaf != "" {
+		node.Properties[graph.SourceLeafProperty] = []byte(schema.SourceLeaf)
+	}
+	store.AddNode(node)
+
+	// Register definition: construct name → directory ID. An overload
+	// is also defined under its bare name, so calls to it resolve to
+	// every arity. A later clause was defined with the first.
+	if len(schema.Files) > 0 && clause == nil {
+		if err := store.AddDef(name, id); err != nil {
+			return nil, fmt.Errorf("add def %s -> %s: %w", name, id, err)
 		}
-		// Append file-level address refs (e.g., HCL variable declarations)
-		// that weren't already found at the scope level. This avoids
-		// duplicate refs when a Go function both calls os.Getenv and the
-		// file-root also matches the same pattern.
-		if len(fileAddressRefs) > 0 {
-			scopeSeen := make(map[string]bool, len(calls))
-			for _, c := range calls {
-				scopeSeen[c] = true
+		if overload != "" {
+			if err := store.AddDef(overload, id); err != nil {
+				return nil, fmt.Errorf("add def %s -> %s: %w", overload, id, err)
 			}
-			for _, ref := range fileAddressRefs {
-				if !scopeSeen[ref] {
-					calls = append(calls, ref)
+		}
+		// Register qualified definition (package.name → directory ID)
+		if node.Properties != nil {
+			if pkg, ok := node.Properties["pkg"]; ok && len(pkg) > 0 {
+				qualKey := string(pkg) + "." + name
+				if err := store.AddDef(qualKey, id); err != nil {
+					return nil, fmt.Errorf("add qualified def %s -> %s: %w", qualKey, id, err)
 				}
 			}
 		}
+	}
 
-		// Re-fetch current node (updated by recursion) — preserve Children + Properties
-		var currentChildren []string
-		var currentProps map[string][]byte
-		if current, err := store.GetNode(id); err == nil {
-			currentChildren = current.Children
-			currentProps = current.Properties
+	// Register schema-declared refs (cross-reference tokens for callers/)
+	for _, refTmpl := range schema.Refs {
+		token, err := delims.Render(refTmpl, match.Values())
+		if err != nil {
+			return nil, fmt.Errorf("failed to render ref %s: %w", refTmpl, err)
 		}
+		if token != "" {
+			if err := store.AddRef(token, id); err != nil {
+				return nil, fmt.Errorf("add ref %s -> %s: %w", token, id, err)
+			}
+		}
+	}
 
-		// Pre-compute doc comments from backward scan (available to all file templates)
-		docText, extStart, extEnd, hasScope := extractDocComments(match)
+	// Link to parent
+	e.linkChild(store, dirPath, node)
 
-		node = &graph.Node{
-			ID:         id,
-			Mode:       os.ModeDir | 0o555, // Read-only dir
-			ModTime:    modTime,            // Propagate source file time
-			Children:   currentChildren,
-			Context:    fileContext,
-			Properties: currentProps,
-		}
+	return &openedMatch{match: match, id: id, currentPath: currentPath, clause: clause}, nil
+}
 
-		// Set location property on directory node from source file's origin
-		if hasScope && absSourceFile != "" {
-			if root, ok := match.Context().(SitterRoot); ok {
-				if node.Properties == nil {
-					node.Properties = make(map[string][]byte)
+// priorClause returns the origin of the code leaf of the construct id when
+// an earlier match of absSourceFile projected it, nil otherwise.
+func priorClause(store IngestionTarget, id, sourceLeaf, absSourceFile string) *graph.SourceOrigin {
+	leafID := id + "/" + sourceLeaf
+	var leaf *graph.Node
+	if bt, ok := store.(*bufferingTarget); ok {
+		// What the store holds is from an earlier ingest of the file.
+		leaf = bt.buffered[leafID]
+	} else if n, err := store.GetNode(leafID); err == nil {
+		leaf = n
+	}
+	if leaf == nil || leaf.Origin == nil || leaf.Origin.FilePath != absSourceFile {
+		return nil
+	}
+	return leaf.Origin
+}
+
+// closeMatch writes om's files and the call, address, and type refs of its
+// source, once its children have been projected.
+func (e *Engine) closeMatch(schema api.Node, om *openedMatch, in *fileIngest) error {
+	delims := e.delims()
+	match, id, currentPath := om.match, om.id, om.currentPath
+	walker, store, modTime := in.walker, in.store, in.modTime
+	absSourceFile, fileContext, fileAddressRefs := in.absSourceFile, in.fileContext, in.fileAddressRefs
+
+	// Extract calls for this match (refs index)
+	var calls []string
+	var callLines map[string][]int // token -> call-site lines in the source file
+	if sw, ok := walker.(*SitterWalker); ok {
+		if ctxAny := match.Context(); ctxAny != nil {
+			if root, ok := ctxAny.(SitterRoot); ok {
+				if c, lines, err := sw.ExtractCallSites(root.Node, root.Source, root.Lang, root.LangName); err == nil {
+					calls, callLines = c, lines
 				}
-				relPath, err := filepath.Rel(e.RootPath, absSourceFile)
-				if err == nil {
-					startLine := byteOffsetToLine(root.Source, extStart)
-					endLine := byteOffsetToLine(root.Source, extEnd)
-					node.Properties["location"] = []byte(fmt.Sprintf("%s:%d:%d", relPath, startLine, endLine))
+				// Extract address-aware refs (env:, path:, url:) from the
+				// match scope. These typed tokens bridge across languages
+				// (e.g., Go os.Getenv calls within this function scope).
+				if addrRefs, err := sw.ExtractAddressRefs(root.Node, root.Source, root.Lang, root.LangName); err == nil {
+					calls = append(calls, addrRefs...)
+				}
+				// Type refs live in their own token namespace (see
+				// graph.TypeRefPrefix), so they stay out of callers/.
+				if typeRefs, err := sw.ExtractTypeRefs(root.Node, root.Source, root.Lang, root.LangName); err == nil {
+					calls = append(calls, typeRefs...)
 				}
 			}
 		}
-		store.AddNode(node)
-
-		// Collect file children for batch write (single lock acquisition).
-		var fileNodes []*graph.Node
-		var sourceFileID string
-		for _, fileSchema := range schema.Files {
-			fileName, err := RenderTemplate(fileSchema.Name, match.Values())
-			if err != nil {
-				log.Printf("processNode: skip file name render %q: %v", fileSchema.Name, err)
-				continue
+	}
+	// Append file-level address refs (e.g., HCL variable declarations)
+	// that weren't already found at the scope level. This avoids
+	// duplicate refs when a Go function both calls os.Getenv and the
+	// file-root also matches the same pattern.
+	if len(fileAddressRefs) > 0 {
+		scopeSeen := make(map[string]bool, len(calls))
+		for _, c := range calls {
+			scopeSeen[c] = true
+		}
+		for _, ref := range fileAddressRefs {
+			if !scopeSeen[ref] {
+				calls = append(calls, ref)
 			}
-			filePath := filepath.Join(currentPath, fileName)
-			fileId := toNodeID(filePath)
+		}
+	}
 
-			// Augment template values with doc comment text
-			vals := match.Values()
-			if docText != "" {
-				vals["doc"] = docText
-			}
+	// Re-fetch current node (updated by recursion) — preserve Children + Properties
+	var currentChildren []string
+	var currentProps map[string][]byte
+	if current, err := store.GetNode(id); err == nil {
+		currentChildren = current.Children
+		currentProps = current.Properties
+	}
 
-			content, err := e.RenderContentTemplate(fileSchema.ContentTemplate, vals)
-			if err != nil {
-				log.Printf("processNode: skip file content render %q: %v", fileId, err)
-				continue
-			}
+	// Pre-compute doc comments from backward scan (available to all file templates)
+	docText, extStart, extEnd, hasScope := extractDocComments(match)
+	decorators, hasDecorators := matchDecorators(match)
 
-			// Skip empty optional files (e.g. "doc" when no doc comments exist)
-			if content == "" && fileSchema.Name != "source" {
-				continue
-			}
+	// A later clause extends the construct back over the earlier ones.
+	merged := false
+	if om.clause != nil && hasScope && om.clause.StartByte < extStart {
+		if root, ok := match.Context().(SitterRoot); ok && extEnd <= uint32(len(root.Source)) {
+			extStart, merged = om.clause.StartByte, true
+		}
+	}
 
-			fileNode := &graph.Node{
-				ID:      fileId,
-				Mode:    0o444,
-				ModTime: modTime,
-				Data:    []byte(content),
+	node := &graph.Node{
+		ID:         id,
+		Mode:       os.ModeDir | 0o555, // Read-only dir
+		ModTime:    modTime,            // Propagate source file time
+		Children:   currentChildren,
+		Context:    fileContext,
+		Properties: currentProps,
+	}
+
+	// Set location property on directory node from source file's origin
+	if hasScope && absSourceFile != "" {
+		if root, ok := match.Context().(SitterRoot); ok {
+			if node.Properties == nil {
+				node.Properties = make(map[string][]byte)
 			}
+			relPath, err := filepath.Rel(e.RootPath, absSourceFile)
+			if err == nil {
+				startLine := byteOffsetToLine(root.Source, extStart)
+				endLine := byteOffsetToLine(root.Source, extEnd)
+				node.Properties["location"] = []byte(fmt.Sprintf("%s:%d:%d", relPath, startLine, endLine))
+			}
+			if len(schema.Files) > 0 {
+				node.Properties[graph.OriginProperty] = originPosition(absSourceFile, root.Source, extStart)
+			}
+		}
+	}
+	store.AddNode(node)
 
-			// Extend source file content to include preceding doc comments
-			if hasScope && docText != "" && fileSchema.Name == "source" {
-				if root, ok := match.Context().(SitterRoot); ok {
-					if extEnd <= uint32(len(root.Source)) {
-						fileNode.Data = root.Source[extStart:extEnd]
-					}
+	// Collect file children for batch write (single lock acquisition).
+	var fileNodes []*graph.Node
+	var sourceFileID string
+	sourceLeaf := schema.SourceLeafName()
+	for _, fileSchema := range schema.Files {
+		fileName, err := delims.Render(fileSchema.Name, match.Values())
+		if err != nil {
+			log.Printf("processNode: skip file name render %q: %v", fileSchema.Name, err)
+			continue
+		}
+		filePath := filepath.Join(currentPath, fileName)
+		fileId := toNodeID(filePath)
+
+		// Augment template values with doc comment text
+		vals := match.Values()
+		if docText != "" {
+			vals["doc"] = docText
+		}
+		if _, captured := vals[decoratorsValue]; hasDecorators && !captured {
+			vals[decoratorsValue] = decorators
+		}
+
+		content, err := e.RenderContentTemplate(fileSchema.ContentTemplate, vals)
+		if err != nil {
+			log.Printf("processNode: skip file content render %q: %v", fileId, err)
+			continue
+		}
+
+		// Skip empty optional files (e.g. "doc" when no doc comments exist)
+		if content == "" && fileSchema.Name != sourceLeaf {
+			continue
+		}
+
+		fileNode := &graph.Node{
+			ID:      fileId,
+			Mode:    0o444,
+			ModTime: modTime,
+			Data:    []byte(content),
+		}
+
+		// Extend source file content to include preceding doc comments
+		// (and, merged, the earlier clauses)
+		if hasScope && (docText != "" || merged) && fileSchema.Name == sourceLeaf {
+			if root, ok := match.Context().(SitterRoot); ok {
+				if extEnd <= uint32(len(root.Source)) {
+					fileNode.Data = root.Source[extStart:extEnd]
 				}
 			}
+		}
 
-			// Set write-back origin from backward scan
-			if hasScope && absSourceFile != "" {
+		// Set write-back origin from backward scan
+		if hasScope && absSourceFile != "" {
+			fileNode.Origin = &graph.SourceOrigin{
+				FilePath:  absSourceFile,
+				StartByte: extStart,
+				EndByte:   extEnd,
+			}
+		} else if op, ok := match.(OriginProvider); ok && absSourceFile != "" {
+			// Fallback for non-sitter matches
+			if start, end, ok := op.CaptureOrigin("scope"); ok {
 				fileNode.Origin = &graph.SourceOrigin{
 					FilePath:  absSourceFile,
-					StartByte: extStart,
-					EndByte:   extEnd,
-				}
-			} else if op, ok := match.(OriginProvider); ok && absSourceFile != "" {
-				// Fallback for non-sitter matches
-				if start, end, ok := op.CaptureOrigin("scope"); ok {
-					fileNode.Origin = &graph.SourceOrigin{
-						FilePath:  absSourceFile,
-						StartByte: start,
-						EndByte:   end,
-					}
+					StartByte: start,
+					EndByte:   end,
 				}
 			}
+		}
+		if fileNode.Origin == nil && absSourceFile != "" {
+			if path := jsonFieldPath(delims, match, fileSchema.ContentTemplate); path != "" {
+				fileNode.Origin = &graph.SourceOrigin{FilePath: absSourceFile, JSONPath: path}
+			}
+		}
 
-			fileNodes = append(fileNodes, fileNode)
-			if fileSchema.Name == "source" {
-				sourceFileID = fileId
+		// A computed leaf holds its command's input; the store serves the
+		// output. It has no range of its own to write back to.
+		if fileSchema.Command != "" {
+			fileNode.Origin = nil
+			fileNode.Properties = map[string][]byte{graph.CommandProperty: []byte(fileSchema.Command)}
+		}
+
+		// Signatures only: the whole construct moves to _full, keeping
+		// the origin, and the code leaf keeps the declaration, read-only.
+		var full *graph.Node
+		if SignaturesOnly && hasScope && fileSchema.Name == sourceLeaf {
+			if sig, ok := signatureOf(match, extStart); ok {
+				whole := *fileNode
+				whole.ID = toNodeID(filepath.Join(currentPath, FullSourceFile))
+				full = &whole
+				fileNode.Data, fileNode.Origin = sig, nil
 			}
 		}
 
-		// Batch write: single lock acquisition for all file nodes + parent update.
-		if len(fileNodes) > 0 {
-			store.AddFileChildren(node, fileNodes)
+		fileNodes = append(fileNodes, fileNode)
+		if full != nil {
+			fileNodes = append(fileNodes, full)
 		}
+		if fileSchema.Name == sourceLeaf {
+			sourceFileID = fileId
+		}
+	}
 
-		// Refs AFTER batch (source file must exist in store first)
-		if sourceFileID != "" {
-			for _, token := range calls {
-				if err := store.AddRef(token, sourceFileID); err != nil {
-					return fmt.Errorf("add ref %s -> %s: %w", token, sourceFileID, err)
-				}
+	// Batch write: single lock acquisition for all file nodes + parent update.
+	if len(fileNodes) > 0 {
+		store.AddFileChildren(node, fileNodes)
+	}
+
+	// Refs AFTER batch (source file must exist in store first)
+	if sourceFileID != "" {
+		for _, token := range calls {
+			if err := store.AddRef(token, sourceFileID, callLines[token]...); err != nil {
+				return fmt.Errorf("add ref %s -> %s: %w", token, sourceFileID, err)
 			}
 		}
 	}
 	return nil
 }
 
+// isSelfMatch reports whether match covers the same Tree-sitter node as ctx
+// (same byte range and node type).
+func isSelfMatch(ctx any, match Match) bool {
+	parentRoot, ok := ctx.(SitterRoot)
+	if !ok {
+		return false
+	}
+	childCtx, ok := match.Context().(SitterRoot)
+	if !ok {
+		return false
+	}
+	return parentRoot.Node.StartByte() == childCtx.Node.StartByte() &&
+		parentRoot.Node.EndByte() == childCtx.Node.EndByte() &&
+		parentRoot.Node.Type() == childCtx.Node.Type()
+}
+
+// outermostMatches drops self-matches of ctx and any match whose @scope lies
+// inside another match's @scope, preserving order. Tree-sitter queries match
+// the whole subtree, so without this a recursive node would project every
+// nested element at every level. Matches sharing one scope node (a Go field
+// declaration naming several fields) are siblings and all kept. Matches
+// without a scope origin are kept.
+func outermostMatches(ctx any, matches []Match) []Match {
+	type scoped struct {
+		idx        int
+		start, end uint32
+	}
+	keep := make([]bool, len(matches))
+	var spans []scoped
+	for i, m := range matches {
+		if isSelfMatch(ctx, m) {
+			continue
+		}
+		keep[i] = true
+		if op, ok := m.(OriginProvider); ok {
+			if start, end, ok := op.CaptureOrigin("scope"); ok {
+				spans = append(spans, scoped{i, start, end})
+			}
+		}
+	}
+
+	// Sort by start ascending, end descending so every enclosing scope is
+	// visited before the scopes it contains.
+	sort.Slice(spans, func(a, b int) bool {
+		if spans[a].start != spans[b].start {
+			return spans[a].start < spans[b].start
+		}
+		return spans[a].end > spans[b].end
+	})
+	var outerStart, outerEnd uint32
+	for k, sp := range spans {
+		if k > 0 && sp.end <= outerEnd {
+			if sp.start != outerStart || sp.end != outerEnd {
+				keep[sp.idx] = false
+			}
+			continue
+		}
+		outerStart, outerEnd = sp.start, sp.end
+	}
+
+	out := make([]Match, 0, len(matches))
+	for i, m := range matches {
+		if keep[i] {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
 // byteOffsetToLine converts a byte offset to a 1-based line number in content.
 func byteOffsetToLine(content []byte, offset uint32) int {
 	line := 1
@@ -1605,6 +2457,15 @@ func byteOffsetToLine(content []byte, offset uint32) int {
 	return line
 }
 
+// originPosition renders byte offset off in src, the content of file, as
+// "file:line:col" with a 1-based line and column counted in characters.
+func originPosition(file string, src []byte, off uint32) []byte {
+	prefix := src[:min(int(off), len(src))]
+	line := bytes.Count(prefix, []byte("\n")) + 1
+	col := utf8.RuneCount(prefix[bytes.LastIndexByte(prefix, '\n')+1:]) + 1
+	return fmt.Appendf(nil, "%s:%d:%d", file, line, col)
+}
+
 // extractDocComments walks backward from a tree-sitter @scope capture to find
 // contiguous preceding comment nodes. Returns the doc comment text (just the
 // comments) and the extended byte range for write-back origin tracking.
@@ -1621,19 +2482,7 @@ func extractDocComments(match Match) (docText string, startByte, endByte uint32,
 	startByte = scopeNode.StartByte()
 	endByte = scopeNode.EndByte()
 
-	// Walk backward to find contiguous comment siblings
-	n := scopeNode
-	prev := n.PrevSibling()
-	for prev != nil && prev.Type() == "comment" {
-		// Check adjacency: <= 2 bytes gap (allow \n or \n\n)
-		if int(n.StartByte())-int(prev.EndByte()) <= 2 {
-			startByte = prev.StartByte()
-			n = prev
-			prev = prev.PrevSibling()
-		} else {
-			break
-		}
-	}
+	startByte = leadingCommentsStart(scopeNode)
 
 	// Extract doc comment text (just the comments, not the scope body)
 	if startByte < scopeNode.StartByte() {
@@ -1649,6 +2498,45 @@ func extractDocComments(match Match) (docText string, startByte, endByte uint32,
 	return docText, startByte, endByte, hasScope
 }
 
+// leadingCommentsStart returns where the run of comment siblings directly
+// before n begins, or n's own start when there is none. Comments belong to
+// the run when at most 2 bytes (\n or \n\n) separate them from the next
+// node. Siblings are scanned forward in one pass with a cursor:
+// PrevSibling rescans the parent from its first child on every call.
+func leadingCommentsStart(n *sitter.Node) uint32 {
+	parent := n.Parent()
+	if parent == nil {
+		return n.StartByte()
+	}
+	c := sitter.NewTreeCursor(parent)
+	defer c.Close()
+	if !c.GoToFirstChild() {
+		return n.StartByte()
+	}
+	runStart, runEnd, inRun := uint32(0), uint32(0), false
+	for {
+		sib := c.CurrentNode()
+		if sib.StartByte() >= n.StartByte() {
+			break
+		}
+		if sib.Type() == "comment" {
+			if !inRun || int(sib.StartByte())-int(runEnd) > 2 {
+				runStart = sib.StartByte()
+			}
+			runEnd, inRun = sib.EndByte(), true
+		} else {
+			inRun = false
+		}
+		if !c.GoToNextSibling() {
+			break
+		}
+	}
+	if inRun && int(n.StartByte())-int(runEnd) <= 2 {
+		return runStart
+	}
+	return n.StartByte()
+}
+
 // --- Go package name extraction for qualified defs ---
 
 var (
@@ -1683,6 +2571,65 @@ func extractGoPackageName(fileRoot *sitter.Node, source []byte, lang *sitter.Lan
 	return ""
 }
 
+// enclosingElixirModule returns the fully qualified name of the innermost
+// defmodule containing n (inclusive), joining nested module names:
+// defmodule A do defmodule B do ... → "A.B". Returns "" outside any module.
+func enclosingElixirModule(n *sitter.Node, source []byte) string {
+	var names []string
+	for ; n != nil; n = n.Parent() {
+		if n.Type() != "call" {
+			continue
+		}
+		target := n.ChildByFieldName("target")
+		if target == nil || target.Content(source) != "defmodule" {
+			continue
+		}
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			args := n.NamedChild(i)
+			if args.Type() != "arguments" {
+				continue
+			}
+			if args.NamedChildCount() > 0 && args.NamedChild(0).Type() == "alias" {
+				names = append(names, args.NamedChild(0).Content(source))
+			}
+			break
+		}
+	}
+	slices.Reverse(names)
+	return strings.Join(names, ".")
+}
+
+// enclosingScalaOwner returns the fully qualified name of the innermost
+// class, object, or trait containing n (inclusive), or of its package
+// outside any: com.example.etl.WordCount. Header package clauses (package
+// com.example, then package etl) and package blocks both count. Returns ""
+// in the root package outside any type.
+func enclosingScalaOwner(n *sitter.Node, source []byte) string {
+	var names []string
+	for ; n != nil; n = n.Parent() {
+		switch n.Type() {
+		case "class_definition", "object_definition", "trait_definition", "package_clause":
+			if name := n.ChildByFieldName("name"); name != nil {
+				names = append(names, name.Content(source))
+			}
+		case "compilation_unit":
+			// Clauses without a body apply to the rest of the file;
+			// a package block is n's ancestor, handled above.
+			for i := int(n.NamedChildCount()) - 1; i >= 0; i-- {
+				c := n.NamedChild(i)
+				if c.Type() != "package_clause" || c.ChildByFieldName("body") != nil {
+					continue
+				}
+				if name := c.ChildByFieldName("name"); name != nil {
+					names = append(names, name.Content(source))
+				}
+			}
+		}
+	}
+	slices.Reverse(names)
+	return strings.Join(names, ".")
+}
+
 // GetLanguage returns the tree-sitter language for a language name string.
 // Returns nil for unsupported languages.
 // Deprecated: use lang.ForName(name).Grammar() instead.
@@ -1761,7 +2708,18 @@ func (e *Engine) DiagramFuncMap() template.FuncMap {
 // functions plus the Engine's diagram function. This is the method that
 // processNode and collectNodes should use for file content rendering.
 func (e *Engine) RenderContentTemplate(tmpl string, values map[string]any) (string, error) {
-	return RenderTemplateWithFuncs(tmpl, values, e.DiagramFuncMap(), &e.diagramTmplCache)
+	return e.delims().RenderWithFuncs(tmpl, values, e.DiagramFuncMap(), &e.diagramTmplCache)
+}
+
+// delims returns the action delimiters of the engine's schema templates.
+func (e *Engine) delims() machetmpl.Delims {
+	return schemaDelims(e.Schema)
+}
+
+// schemaDelims returns the action delimiters schema's templates use.
+func schemaDelims(schema *api.Topology) machetmpl.Delims {
+	left, right := schema.Delims()
+	return machetmpl.Delims{Left: left, Right: right}
 }
 
 // ReIngestFile re-ingests a single file, preserving the existing RootPath.
@@ -1783,7 +2741,10 @@ func (e *Engine) ReIngestFile(path string) error {
 	}
 
 	// Re-ingest the single file using the existing schema and store
-	if err := e.ingestFile(realPath, info.ModTime()); err != nil {
+	if err := e.ingestFile(context.Background(), realPath, info.ModTime()); err != nil {
+		return err
+	}
+	if err := e.mirrorRawFile(realPath, info.ModTime()); err != nil {
 		return err
 	}
 
@@ -1795,15 +2756,30 @@ func (e *Engine) ReIngestFile(path string) error {
 	return nil
 }
 
-// PrintRoutingSummary outputs a summary of files routed to _project_files/.
+// PrintRoutingSummary outputs a summary of files routed to _project_files/
+// and of malformed input skipped under SkipErrors.
 func (e *Engine) PrintRoutingSummary() {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	if len(e.routedFiles) > 0 {
+	if len(e.routedFiles) > 0 || e.skippedRecords > 0 || e.skippedFiles > 0 {
 		log.Printf("Routing summary:")
 		for lang, count := range e.routedFiles {
 			log.Printf("  %s: %d files routed to _project_files/", lang, count)
 		}
+		if e.skippedRecords > 0 {
+			log.Printf("  %d malformed records skipped", e.skippedRecords)
+		}
+		if e.skippedFiles > 0 {
+			log.Printf("  %d malformed JSON files skipped", e.skippedFiles)
+		}
 	}
 }
+
+// Skipped returns how many malformed records and JSON files were dropped
+// under SkipErrors.
+func (e *Engine) Skipped() (records, files int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.skippedRecords, e.skippedFiles
+}
@@ -1,7 +1,8 @@
 package ingest
 
 func init() {
-	// Context extraction is Go-only; other languages return nil.
+	// Context queries capture the file-level declarations every construct's
+	// context file shows; languages without one get no context.
 	RegisterContextQuery("go", `
 		(import_declaration) @ctx
 		(const_declaration) @ctx
@@ -9,6 +10,37 @@ func init() {
 		(type_declaration) @ctx
 	`)
 
+	// Python: module-level imports and the __all__ export list.
+	RegisterContextQuery("python", `
+		(module (import_statement) @ctx)
+		(module (import_from_statement) @ctx)
+		(module (future_import_statement) @ctx)
+		(module (expression_statement
+			(assignment left: (identifier) @_name)) @ctx
+			(#eq? @_name "__all__"))
+	`)
+
+	// JavaScript/TypeScript: import statements and top-level consts,
+	// exported or not (CommonJS require()s are consts too).
+	jsContext := `
+		(program (import_statement) @ctx)
+		(program (lexical_declaration "const") @ctx)
+		(program (export_statement (lexical_declaration "const")) @ctx)
+	`
+	RegisterContextQuery("javascript", jsContext)
+	RegisterContextQuery("typescript", jsContext)
+
+	// OCaml: opened modules.
+	RegisterContextQuery("ocaml", `
+		(compilation_unit (open_module) @ctx)
+	`)
+
+	// Scala: the package clauses and file-level imports.
+	RegisterContextQuery("scala", `
+		(compilation_unit (package_clause) @ctx)
+		(compilation_unit (import_declaration) @ctx)
+	`)
+
 	// Register Go qualified call query — captures both @call and @pkg.
 	// Pattern 0: bare calls like foo()
 	// Pattern 1: qualified calls like auth.Validate()
@@ -47,6 +79,18 @@ func init() {
 		(call_expression function: (field_expression field: (field_identifier) @call))
 	`)
 
+	// Register OCaml queries — applications of a (possibly qualified) value.
+	RegisterRefQuery("ocaml", `
+		(application_expression function: (value_path (value_name) @call))
+	`)
+
+	// Register Scala queries — calls of a bare or member-selected name
+	// (helper(x), spark.read.text(path)).
+	RegisterRefQuery("scala", `
+		(call_expression function: (identifier) @call)
+		(call_expression function: (field_expression field: (identifier) @call))
+	`)
+
 	// Register Elixir queries — local and qualified function calls.
 	// Pattern 0: local calls like func_name(args)
 	// Pattern 1: qualified calls like Module.func_name(args)
@@ -55,6 +99,13 @@ func init() {
 		(call target: (dot right: (identifier) @call))
 	`)
 
+	// Elixir qualified calls: MyApp.Accounts.create(attrs) → @pkg
+	// "MyApp.Accounts", resolved against module-qualified defs.
+	RegisterQualifiedCallQuery("elixir", `
+		(call target: (identifier) @call)
+		(call target: (dot left: (alias) @pkg right: (identifier) @call))
+	`)
+
 	// --- Address-aware ref queries ---
 	// These emit typed ref tokens (scheme:value) that bridge across languages.
 	// The @ref capture is unquoted and prefixed with the scheme automatically.
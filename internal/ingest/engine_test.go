@@ -1,13 +1,24 @@
 package ingest
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/agentic-research/mache/api"
 	"github.com/agentic-research/mache/internal/graph"
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/python"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -73,6 +84,143 @@ func TestEngine_IngestJson(t *testing.T) {
 	assert.Equal(t, "admin", string(node.Data))
 }
 
+func TestEngine_IngestJson_FieldOrigins(t *testing.T) {
+	schema := &api.Topology{Nodes: []api.Node{{
+		Name:     "users",
+		Selector: "$",
+		Children: []api.Node{{
+			Name:     "{{.name}}",
+			Selector: "users[*]",
+			Files: []api.Leaf{
+				{Name: "role", ContentTemplate: "{{.role}}"},
+				{Name: "raw.json", ContentTemplate: "{{. | json}}"},
+			},
+		}},
+	}}}
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`{"users": [{"name": "Alice", "role": "admin"}]}`), 0o644))
+	realPath, err := filepath.EvalSymlinks(dataFile)
+	require.NoError(t, err)
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	require.NoError(t, engine.Ingest(dataFile))
+
+	role, err := store.GetNode("users/Alice/role")
+	require.NoError(t, err)
+	require.NotNil(t, role.Origin)
+	assert.Equal(t, graph.SourceOrigin{FilePath: realPath, JSONPath: "$.users[0].role"}, *role.Origin)
+
+	raw, err := store.GetNode("users/Alice/raw.json")
+	require.NoError(t, err)
+	assert.Nil(t, raw.Origin, "templates other than a single field reference are read-only")
+
+	// Re-ingesting after an edit replaces the leaves without duplicating them.
+	require.NoError(t, os.WriteFile(dataFile, []byte(`{"users": [{"name": "Alice", "role": "owner"}]}`), 0o644))
+	require.NoError(t, engine.ReIngestFile(dataFile))
+	role, err = store.GetNode("users/Alice/role")
+	require.NoError(t, err)
+	assert.Equal(t, "owner", string(role.Data))
+	alice, err := store.GetNode("users/Alice")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"users/Alice/role", "users/Alice/raw.json"}, alice.Children)
+}
+
+func TestEngine_IngestJson_CustomDelims(t *testing.T) {
+	schema := &api.Topology{
+		LeftDelim:  "<%",
+		RightDelim: "%>",
+		Nodes: []api.Node{{
+			Name:     "components",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "<% .name %>",
+				Selector: "components[*]",
+				Files: []api.Leaf{
+					{Name: "template.vue", ContentTemplate: "<% .template %>"},
+					{Name: "card", ContentTemplate: "<h1>{{ title }}</h1><!-- <% .name %> -->"},
+				},
+			}},
+		}},
+	}
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`{"components": [{"name": "Hello", "template": "<p>{{ msg }}</p>"}]}`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(dataFile))
+
+	tmpl, err := store.GetNode("components/Hello/template.vue")
+	require.NoError(t, err)
+	assert.Equal(t, "<p>{{ msg }}</p>", string(tmpl.Data))
+	require.NotNil(t, tmpl.Origin, "a single-field leaf maps back to its JSON path under custom delimiters too")
+	assert.Equal(t, "$.components[0].template", tmpl.Origin.JSONPath)
+
+	card, err := store.GetNode("components/Hello/card")
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>{{ title }}</h1><!-- Hello -->", string(card.Data))
+}
+
+func TestEngine_IngestJson_UnnamedBucket(t *testing.T) {
+	schema := &api.Topology{Nodes: []api.Node{{
+		Name:     "users",
+		Selector: "$",
+		Children: []api.Node{{
+			Name:     "{{.name}}",
+			Selector: "users[*]",
+			Files:    []api.Leaf{{Name: "role", ContentTemplate: "{{.role}}"}},
+		}},
+	}}}
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`{"users": [{"name": "Alice", "role": "admin"}, {"role": "ghost"}]}`), 0o644))
+
+	old := UnnamedBucket
+	defer func() { UnnamedBucket = old }()
+	UnnamedBucket = true
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(dataFile))
+
+	users, err := store.ListChildren("users")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"users/Alice", "users/_unnamed"}, users)
+	role, err := store.GetNode("users/_unnamed/1/role")
+	require.NoError(t, err, "a match without a name is kept under its index")
+	assert.Equal(t, "ghost", string(role.Data))
+}
+
+func TestEngine_IngestJson_DebugSchema(t *testing.T) {
+	schema := &api.Topology{Nodes: []api.Node{{
+		Name:     "users",
+		Selector: "$",
+		Children: []api.Node{{
+			Name:     "{{.name}}",
+			Selector: "users[*]",
+			Files:    []api.Leaf{{Name: "role", ContentTemplate: "{{.role}}"}},
+		}},
+	}}}
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`{"users": [{"name": "Alice", "role": "admin"}]}`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(dataFile))
+	alice, err := store.GetNode("users/Alice")
+	require.NoError(t, err)
+	assert.NotContains(t, alice.Properties, graph.SchemaPathProperty, "off by default")
+
+	old := DebugSchema
+	defer func() { DebugSchema = old }()
+	DebugSchema = true
+
+	store = graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(dataFile))
+	users, err := store.GetNode("users")
+	require.NoError(t, err)
+	assert.Equal(t, "users", string(users.Properties[graph.SchemaPathProperty]))
+	alice, err = store.GetNode("users/Alice")
+	require.NoError(t, err)
+	assert.Equal(t, "users > {{.name}}", string(alice.Properties[graph.SchemaPathProperty]))
+}
+
 func TestEngine_IngestRecords(t *testing.T) {
 	// Schema designed for a list of records
 	schema := &api.Topology{
@@ -127,6 +275,107 @@ func loadGoSchema(t *testing.T) *api.Topology {
 	return &topo
 }
 
+func TestEngine_SourceLeaf(t *testing.T) {
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "functions",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:       "{{.name}}",
+				Selector:   "(function_declaration name: (identifier) @name) @scope",
+				SourceLeaf: "code",
+				Files: []api.Leaf{
+					{Name: "code", ContentTemplate: "{{.scope}}"},
+					{Name: "source", ContentTemplate: "{{.scope}}"},
+				},
+			}},
+		}},
+	}
+
+	goFile := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(goFile, []byte(`package demo
+
+// Main runs.
+func Main() {
+	helper()
+}
+
+func helper() {}
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(goFile))
+
+	callers, err := store.GetCallers("helper")
+	require.NoError(t, err)
+	require.Len(t, callers, 1)
+	assert.Equal(t, "functions/Main/code", callers[0].ID, "calls are indexed for the declared code leaf")
+
+	code, err := store.GetNode("functions/Main/code")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(code.Data), "// Main runs."), "the code leaf keeps the doc comment")
+	source, err := store.GetNode("functions/Main/source")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(source.Data), "func Main()"), "a leaf merely named source is an ordinary leaf")
+
+	assert.Equal(t, "source", (&api.Node{}).SourceLeafName())
+}
+
+func TestEngine_MergeClauses(t *testing.T) {
+	schema := func(merge bool) *api.Topology {
+		return &api.Topology{
+			Version: "v1",
+			Nodes: []api.Node{{
+				Name:     "functions",
+				Selector: "$",
+				Children: []api.Node{{
+					Name:         "{{.name}}",
+					Selector:     "(function_definition name: (identifier) @name) @scope",
+					MergeClauses: merge,
+					Files:        []api.Leaf{{Name: "source", ContentTemplate: "{{.scope}}"}},
+				}},
+			}},
+		}
+	}
+	src := filepath.Join(t.TempDir(), "shapes.py")
+	require.NoError(t, os.WriteFile(src, []byte(`def area(shape):
+    return 0
+
+def area(shape, scale):
+    helper()
+    return 1
+
+def helper():
+    pass
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema(true), store).Ingest(src))
+
+	source, err := store.GetNode("functions/area/source")
+	require.NoError(t, err)
+	assert.Equal(t, "def area(shape):\n    return 0\n\ndef area(shape, scale):\n    helper()\n    return 1", string(source.Data))
+	require.NotNil(t, source.Origin)
+	assert.Equal(t, uint32(0), source.Origin.StartByte, "the origin spans every clause")
+	dir, err := store.GetNode("functions/area")
+	require.NoError(t, err)
+	assert.Equal(t, "shapes.py:1:6", string(dir.Properties["location"]))
+	assert.Equal(t, []string{"functions/area"}, store.DefsMap()["area"], "defined once")
+	callers, err := store.GetCallers("helper")
+	require.NoError(t, err)
+	require.Len(t, callers, 1)
+	assert.Equal(t, "functions/area/source", callers[0].ID)
+
+	store = graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema(false), store).Ingest(src))
+	source, err = store.GetNode("functions/area/source")
+	require.NoError(t, err)
+	assert.Equal(t, "def area(shape):\n    return 0", string(source.Data))
+	_, err = store.GetNode("functions/area.from_shapes_py/source")
+	assert.NoError(t, err, "unmerged, a later clause is a collision")
+}
+
 func TestEngine_IngestTreeSitter_GoSchema(t *testing.T) {
 	schema := loadGoSchema(t)
 
@@ -316,6 +565,315 @@ type TypeB struct{}
 	assert.Contains(t, fns.Children, "shared/functions/FuncB")
 }
 
+func TestEngine_IngestTreeSitter_SpillingStore(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	for i := range 8 {
+		src := fmt.Sprintf("package p%d\n\nfunc F%d() { G%d() }\n\nfunc G%d() {}\n\ntype T%d struct{}\n", i%3, i, i, i, i)
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("f%d.go", i)), []byte(src), 0o644))
+	}
+
+	// tree flattens a store into ID → sorted children or content.
+	tree := func(g graph.Graph) map[string]string {
+		out := make(map[string]string)
+		var walk func(id string)
+		walk = func(id string) {
+			n, err := g.GetNode(id)
+			require.NoError(t, err)
+			if !n.Mode.IsDir() {
+				out[id] = string(n.Data)
+				return
+			}
+			children := slices.Clone(n.Children)
+			slices.Sort(children)
+			out[id] = strings.Join(children, ",")
+			for _, c := range children {
+				walk(c)
+			}
+		}
+		roots, err := g.ListChildren("")
+		require.NoError(t, err)
+		for _, r := range roots {
+			walk(r)
+		}
+		return out
+	}
+
+	mem := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, mem).Ingest(tmpDir))
+
+	spill, err := graph.NewSpillingMemoryStore(3)
+	require.NoError(t, err)
+	defer func() { _ = spill.Close() }()
+	require.NoError(t, NewEngine(schema, spill).Ingest(tmpDir))
+
+	want := tree(mem)
+	assert.Contains(t, want, "p1/functions/F4/source")
+	assert.Equal(t, want, tree(spill))
+}
+
+func TestEngine_IngestTreeSitter_RecursiveHTML(t *testing.T) {
+	data, err := os.ReadFile("../../examples/html-schema.json")
+	require.NoError(t, err)
+	var schema api.Topology
+	require.NoError(t, json.Unmarshal(data, &schema))
+	schema.ResolveIncludes()
+
+	tmpDir := t.TempDir()
+	err = os.WriteFile(filepath.Join(tmpDir, "index.html"), []byte(`<!DOCTYPE html>
+<html>
+<head><title>Demo</title><script>var x = 1;</script></head>
+<body>
+  <div id="main" class="page wide">
+    <form action="/search"><input name="q"/><p>hi <b>there</b></p><p>two</p></form>
+  </div>
+  <div class="footer">bye</div>
+</body>
+</html>
+`), 0o644)
+	require.NoError(t, err)
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(&schema, store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	// Only the outermost element is a root; nesting follows the DOM.
+	roots, err := store.ListChildren("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"html"}, roots)
+
+	body, err := store.GetNode("html/body")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"html/body/div#main", "html/body/div.footer"}, filterDirs(t, store, body.Children))
+
+	src, err := store.GetNode("html/body/div#main/form/source")
+	require.NoError(t, err)
+	assert.Contains(t, string(src.Data), `<form action="/search">`)
+	assert.NotNil(t, src.Origin, "element source should support write-back")
+
+	// Repeated siblings get a 1-based index suffix.
+	text, err := store.GetNode("html/body/div#main/form/p[2]/text")
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(text.Data))
+	text, err = store.GetNode("html/body/div#main/form/p/text")
+	require.NoError(t, err)
+	assert.Equal(t, "hi there", string(text.Data))
+	_, err = store.GetNode("html/body/div#main/form/p/b")
+	require.NoError(t, err)
+
+	// Attributes become leaves; empty ones are omitted.
+	action, err := store.GetNode("html/body/div#main/form/attributes")
+	require.NoError(t, err)
+	assert.Equal(t, "action=/search\n", string(action.Data))
+	class, err := store.GetNode("html/body/div#main/class")
+	require.NoError(t, err)
+	assert.Equal(t, "page wide", string(class.Data))
+	_, err = store.GetNode("html/body/div#main/form/href")
+	assert.Error(t, err)
+
+	_, err = store.GetNode("html/head/script/source")
+	require.NoError(t, err)
+	_, err = store.GetNode("html/head/title/text")
+	require.NoError(t, err)
+
+	// Nested elements are not duplicated at shallower levels.
+	_, err = store.GetNode("html/form")
+	assert.Error(t, err)
+	_, err = store.GetNode("html/body/form")
+	assert.Error(t, err)
+}
+
+func TestEngine_IngestTreeSitter_DeeplyNested(t *testing.T) {
+	data, err := os.ReadFile("../../examples/html-schema.json")
+	require.NoError(t, err)
+	var schema api.Topology
+	require.NoError(t, json.Unmarshal(data, &schema))
+	schema.ResolveIncludes()
+
+	const depth = 1000
+	html := strings.Repeat("<div>", depth) + "deep" + strings.Repeat("</div>", depth)
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "deep.html"), []byte(html), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(&schema, store).Ingest(tmpDir))
+
+	text, err := store.GetNode(strings.Repeat("div/", depth) + "text")
+	require.NoError(t, err)
+	assert.Equal(t, "deep", string(text.Data))
+}
+
+// filterDirs returns the directory entries among ids.
+func filterDirs(t *testing.T, store *graph.MemoryStore, ids []string) []string {
+	t.Helper()
+	var dirs []string
+	for _, id := range ids {
+		n, err := store.GetNode(id)
+		require.NoError(t, err)
+		if n.Mode.IsDir() {
+			dirs = append(dirs, id)
+		}
+	}
+	return dirs
+}
+
+func TestEngine_IngestTreeSitter_MethodsUnderReceiver(t *testing.T) {
+	data, err := os.ReadFile("../../examples/go-receiver-schema.json")
+	require.NoError(t, err)
+	var schema api.Topology
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	// Methods live in a file that sorts before the type's file, so the
+	// receiver directory is created before the type claims it.
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a_methods.go"), []byte(`package greet
+
+func (g *Greeter) Greet() string { return "hi " + g.name }
+
+func (g Greeter) Name() string { return g.name }
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "b_types.go"), []byte(`package greet
+
+type Greeter struct{ name string }
+
+func New() *Greeter { return &Greeter{} }
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(&schema, store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	typeDir, err := store.GetNode("greet/types/Greeter")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"greet/types/Greeter/methods", "greet/types/Greeter/source"}, typeDir.Children)
+
+	src, err := store.GetNode("greet/types/Greeter/source")
+	require.NoError(t, err)
+	assert.Contains(t, string(src.Data), "Greeter struct{ name string }")
+
+	methods, err := store.GetNode("greet/types/Greeter/methods")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"greet/types/Greeter/methods/Greet", "greet/types/Greeter/methods/Name"}, methods.Children)
+
+	greet, err := store.GetNode("greet/types/Greeter/methods/Greet/source")
+	require.NoError(t, err)
+	assert.Contains(t, string(greet.Data), "func (g *Greeter) Greet()")
+
+	types, err := store.GetNode("greet/types")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"greet/types/Greeter"}, types.Children, "methods must not appear flat under types/")
+}
+
+func TestEngine_IngestTreeSitter_ElixirModules(t *testing.T) {
+	data, err := os.ReadFile("../../examples/elixir-schema.json")
+	require.NoError(t, err)
+	var schema api.Topology
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "accounts.ex"), []byte(`defmodule MyApp.Accounts do
+  def create(attrs) do
+    validate(attrs)
+  end
+
+  defp validate(attrs) do
+    attrs
+  end
+end
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "my_app.ex"), []byte(`defmodule MyApp do
+  def start(opts) do
+    MyApp.Accounts.create(opts)
+  end
+end
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(&schema, store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	// Dotted module names nest: MyApp.Accounts lives under MyApp.
+	app, err := store.GetNode("modules/MyApp")
+	require.NoError(t, err)
+	assert.Contains(t, app.Children, "modules/MyApp/source")
+	assert.Contains(t, app.Children, "modules/MyApp/Accounts")
+
+	_, err = store.GetNode("modules/MyApp/Accounts/public_functions/create/source")
+	require.NoError(t, err)
+	_, err = store.GetNode("modules/MyApp/Accounts/private_functions/validate/source")
+	require.NoError(t, err)
+	_, err = store.GetNode("modules/MyApp/Accounts/public_functions/validate")
+	assert.Error(t, err, "defp must not be listed as public")
+
+	// Module-qualified defs resolve MyApp.Accounts.create(...) calls.
+	defs := store.DefsMap()
+	assert.Equal(t, []string{"modules/MyApp/Accounts/public_functions/create"}, defs["MyApp.Accounts.create"])
+	assert.Equal(t, []string{"modules/MyApp/public_functions/start"}, defs["MyApp.start"])
+}
+
+func TestEngine_IngestTreeSitter_ScalaPackages(t *testing.T) {
+	data, err := os.ReadFile("../../examples/scala-schema.json")
+	require.NoError(t, err)
+	var schema api.Topology
+	require.NoError(t, json.Unmarshal(data, &schema))
+	schema.ResolveIncludes()
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "jobs.scala"), []byte(`package com.example
+package etl
+
+object Jobs {
+  def run(): Unit = Loader.load()
+}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "loader.scala"), []byte(`package com.example.io {
+  object Loader {
+    def load(): Unit = ()
+  }
+}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "script.sc"), []byte(`def main(): Unit = println("hi")
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(&schema, store).Ingest(tmpDir))
+
+	// Packages nest from the mount root; files without one go to _root_.
+	roots, err := store.ListChildren("")
+	require.NoError(t, err)
+	assert.Contains(t, roots, "com")
+	assert.Contains(t, roots, "_root_")
+	_, err = store.GetNode("com/example/io/objects/Loader/functions/load/source")
+	require.NoError(t, err)
+	_, err = store.GetNode("_root_/functions/main/source")
+	require.NoError(t, err)
+
+	// Defs are qualified by package clauses, chained or block, and
+	// enclosing types.
+	defs := store.DefsMap()
+	assert.Equal(t, []string{"com/example/io/objects/Loader/functions/load"}, defs["com.example.io.Loader.load"])
+	assert.Equal(t, []string{"com/example/io/objects/Loader"}, defs["com.example.io.Loader"])
+	assert.Contains(t, defs["com.example.etl.Jobs.run"], "etl/objects/Jobs/functions/run")
+
+	callers, err := store.GetCallers("load")
+	require.NoError(t, err)
+	assert.NotEmpty(t, callers, "Jobs.run calls Loader.load")
+}
+
+func TestRenderParentPath(t *testing.T) {
+	parts, err := renderParentPath(schemaDelims(nil), "{{.receiver}}/methods", map[string]any{"receiver": "Greeter"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Greeter", "methods"}, parts)
+
+	_, err = renderParentPath(schemaDelims(nil), "{{.receiver}}/methods", map[string]any{"receiver": ".."})
+	assert.Error(t, err, "must not escape the enclosing directory")
+
+	parts, err = renderParentPath(schemaDelims(nil), "{{.receiver}}", map[string]any{"receiver": ""})
+	require.NoError(t, err)
+	assert.Empty(t, parts, "empty parent keeps the enclosing directory")
+}
+
 func TestEngine_IngestTreeSitter_GroupedDeclarations(t *testing.T) {
 	schema := loadGoSchema(t)
 
@@ -373,6 +931,354 @@ var (
 	assert.NotContains(t, string(vaSource.Data), "VarB")
 }
 
+func TestEngine_SplitVisibility(t *testing.T) {
+	old := SplitVisibility
+	defer func() { SplitVisibility = old }()
+	SplitVisibility = true
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "auth.go"), []byte(`package auth
+
+import "fmt"
+
+type User struct{}
+
+type token string
+
+func Login() {}
+
+func (u *User) Name() string { return "" }
+
+func (u *User) check() { fmt.Println() }
+
+func hash() {}
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(dir))
+
+	for _, id := range []string{
+		"auth/types/exported/User",
+		"auth/types/internal/token",
+		"auth/functions/exported/Login",
+		"auth/functions/internal/hash",
+		"auth/methods/exported/User.Name",
+		"auth/methods/internal/User.check",
+	} {
+		_, err := store.GetNode(id + "/source")
+		assert.NoError(t, err, id)
+	}
+	imports, err := store.ListChildren("auth/imports")
+	require.NoError(t, err)
+	assert.Equal(t, []string{`auth/imports/"fmt"`}, imports, "imports aren't declarations")
+	assert.True(t, graph.IsTypeConstruct("auth/types/exported/User"))
+}
+
+func TestEngine_SignaturesOnly(t *testing.T) {
+	old := SignaturesOnly
+	defer func() { SignaturesOnly = old }()
+	SignaturesOnly = true
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "auth.go")
+	require.NoError(t, os.WriteFile(src, []byte(`package auth
+
+// Login signs the user in.
+func Login(name string) error {
+	return nil
+}
+
+type User struct {
+	Name string
+}
+
+type Store interface {
+	Get(id string) User
+}
+
+type token string
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(dir))
+
+	for id, want := range map[string]string{
+		"auth/functions/Login": "// Login signs the user in.\nfunc Login(name string) error",
+		"auth/types/User":      "User struct",
+		"auth/types/Store":     "Store interface",
+	} {
+		sig, err := store.GetNode(id + "/source")
+		require.NoError(t, err, id)
+		assert.Equal(t, want, string(sig.Data), id)
+		assert.Nil(t, sig.Origin, "%s: a cut-down source isn't written back", id)
+
+		full, err := store.GetNode(id + "/" + FullSourceFile)
+		require.NoError(t, err, id)
+		assert.True(t, strings.HasPrefix(string(full.Data), want+" {"), id)
+		require.NotNil(t, full.Origin, id)
+		assert.Equal(t, src, full.Origin.FilePath)
+	}
+
+	alias, err := store.GetNode("auth/types/token/source")
+	require.NoError(t, err)
+	assert.Equal(t, "token string", string(alias.Data), "a declaration without a body is kept whole")
+	_, err = store.GetNode("auth/types/token/" + FullSourceFile)
+	assert.Error(t, err)
+}
+
+func TestEngine_NoProjectFiles(t *testing.T) {
+	old := NoProjectFiles
+	defer func() { NoProjectFiles = old }()
+	NoProjectFiles = true
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc Run() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# demo\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tool.py"), []byte("def run():\n    pass\n"), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(dir))
+
+	_, err := store.GetNode("main/functions/Run/source")
+	require.NoError(t, err, "code the schema projects is still ingested")
+	_, err = store.GetNode(ProjectFilesDir)
+	assert.ErrorIs(t, err, graph.ErrNotFound, "nothing lands in _project_files")
+	_, err = store.GetNode(ProjectFilesDir + "/README.md")
+	assert.Error(t, err)
+}
+
+func TestSignatureOf(t *testing.T) {
+	tests := []struct {
+		langName string
+		grammar  *sitter.Language
+		src      string
+		query    string
+		want     string
+	}{
+		{
+			langName: "python",
+			grammar:  python.GetLanguage(),
+			src:      "@cache\ndef run(x={}):\n    return x\n",
+			query:    `(decorated_definition) @scope`,
+			want:     "@cache\ndef run(x={}):",
+		},
+		{
+			langName: "python",
+			grammar:  python.GetLanguage(),
+			src:      "class Job(Base):\n    def run(self): pass\n",
+			query:    `(class_definition) @scope`,
+			want:     "class Job(Base):",
+		},
+		{
+			langName: "javascript",
+			grammar:  javascript.GetLanguage(),
+			src:      "export class Job extends Base {\n  run() {}\n}\n",
+			query:    `(export_statement) @scope`,
+			want:     "export class Job extends Base",
+		},
+	}
+	walker := NewSitterWalker()
+	defer walker.Close()
+	for _, tt := range tests {
+		t.Run(tt.langName, func(t *testing.T) {
+			parser := sitter.NewParser()
+			defer parser.Close()
+			parser.SetLanguage(tt.grammar)
+			tree, err := parser.ParseCtx(context.Background(), nil, []byte(tt.src))
+			require.NoError(t, err)
+			defer tree.Close()
+
+			root := tree.RootNode()
+			matches, err := walker.Query(SitterRoot{
+				Node: root, FileRoot: root, Source: []byte(tt.src), Lang: tt.grammar, LangName: tt.langName,
+			}, tt.query)
+			require.NoError(t, err)
+			require.Len(t, matches, 1)
+
+			sig, ok := signatureOf(matches[0], 0)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, string(sig))
+		})
+	}
+}
+
+func TestVisibilityDir(t *testing.T) {
+	tests := []struct {
+		langName string
+		grammar  *sitter.Language
+		src      string
+		query    string
+		want     map[string]string
+	}{
+		{
+			langName: "python",
+			grammar:  python.GetLanguage(),
+			src:      "def run(): pass\ndef _helper(): pass\ndef __init__(): pass\n",
+			query:    `(function_definition name: (identifier) @name) @scope`,
+			want:     map[string]string{"run": graph.ExportedDir, "_helper": graph.InternalDir, "__init__": graph.ExportedDir},
+		},
+		{
+			langName: "javascript",
+			grammar:  javascript.GetLanguage(),
+			src:      "export function run() {}\nfunction helper() {}\nexport const limit = 1\nconst cache = {}\n",
+			query:    `[(function_declaration name: (identifier) @name) (lexical_declaration (variable_declarator name: (identifier) @name))] @scope`,
+			want:     map[string]string{"run": graph.ExportedDir, "helper": graph.InternalDir, "limit": graph.ExportedDir, "cache": graph.InternalDir},
+		},
+	}
+	walker := NewSitterWalker()
+	defer walker.Close()
+	for _, tt := range tests {
+		t.Run(tt.langName, func(t *testing.T) {
+			parser := sitter.NewParser()
+			defer parser.Close()
+			parser.SetLanguage(tt.grammar)
+			tree, err := parser.ParseCtx(context.Background(), nil, []byte(tt.src))
+			require.NoError(t, err)
+			defer tree.Close()
+
+			root := tree.RootNode()
+			matches, err := walker.Query(SitterRoot{
+				Node: root, FileRoot: root, Source: []byte(tt.src), Lang: tt.grammar, LangName: tt.langName,
+			}, tt.query)
+			require.NoError(t, err)
+
+			got := make(map[string]string)
+			for _, m := range matches {
+				dir, ok := visibilityDir(m, "")
+				require.True(t, ok)
+				got[m.Values()["name"].(string)] = dir
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEngine_IngestTreeSitter_GoTypeMembers(t *testing.T) {
+	schema := loadGoSchema(t)
+	goFile := filepath.Join(t.TempDir(), "greet.go")
+	require.NoError(t, os.WriteFile(goFile, []byte(`package greet
+
+type Greeter struct {
+	Name, Nick string
+	io.Writer
+	Opts struct {
+		Loud bool
+	}
+}
+
+type Speaker interface {
+	Speak(to string) string
+	io.Closer
+}
+
+type ID int
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(goFile))
+
+	fields, err := store.ListChildren("greet/types/Greeter/fields")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"greet/types/Greeter/fields/Name",
+		"greet/types/Greeter/fields/Nick",
+		"greet/types/Greeter/fields/Opts",
+	}, fields, "embedded fields have no name; nested struct fields stay in their field")
+	typ, err := store.GetNode("greet/types/Greeter/fields/Nick/type")
+	require.NoError(t, err)
+	assert.Equal(t, "string", string(typ.Data))
+
+	speak, err := store.GetNode("greet/types/Speaker/methods/Speak/source")
+	require.NoError(t, err)
+	assert.Equal(t, "Speak(to string) string", string(speak.Data))
+	methods, err := store.ListChildren("greet/types/Speaker/methods")
+	require.NoError(t, err)
+	assert.Len(t, methods, 1)
+
+	for _, id := range []string{"greet/types/Greeter/methods", "greet/types/Speaker/fields", "greet/types/ID/fields"} {
+		_, err := store.GetNode(id)
+		assert.Error(t, err, "%s is only projected for the matching kind of type", id)
+	}
+}
+
+func TestEngine_IngestTreeSitter_GoFieldTags(t *testing.T) {
+	schema := loadGoSchema(t)
+	goFile := filepath.Join(t.TempDir(), "user.go")
+	require.NoError(t, os.WriteFile(goFile, []byte("package user\n\ntype User struct {\n\tName string `json:\"name\" validate:\"required\"`\n\tAge  int\n}\n"), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(goFile))
+
+	tag, err := store.GetNode("user/types/User/fields/Name/tag")
+	require.NoError(t, err)
+	assert.Equal(t, `json:"name" validate:"required"`, string(tag.Data))
+	_, err = store.GetNode("user/types/User/fields/Age/tag")
+	assert.Error(t, err, "untagged fields have no tag leaf")
+	_, err = store.GetNode("user/types/User/fields/Age/type")
+	assert.NoError(t, err)
+}
+
+func loadPresetSchemaFile(t *testing.T, name string) *api.Topology {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "cmd", "schemas", name+".json"))
+	require.NoError(t, err)
+	var topo api.Topology
+	require.NoError(t, json.Unmarshal(data, &topo))
+	topo.ResolveIncludes()
+	return &topo
+}
+
+func TestEngine_IngestTreeSitter_Decorators(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.py"), []byte(`@dataclass
+class Config:
+    @property
+    @cache(ttl=60)
+    def name(self):
+        return "x"
+
+    def plain(self):
+        pass
+
+@app.route("/")
+def index():
+    pass
+`), 0o644))
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadPresetSchemaFile(t, "python"), store).Ingest(dir))
+
+	for id, want := range map[string]string{
+		"classes/Config/decorators":              "@dataclass",
+		"classes/Config/methods/name/decorators": "@property\n@cache(ttl=60)",
+		"functions/index/decorators":             `@app.route("/")`,
+	} {
+		node, err := store.GetNode(id)
+		require.NoError(t, err, id)
+		assert.Equal(t, want, string(node.Data), id)
+	}
+	_, err := store.GetNode("classes/Config/methods/plain/decorators")
+	assert.Error(t, err, "undecorated methods have no decorators leaf")
+
+	javaDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(javaDir, "Svc.java"), []byte(`@Service
+public class Svc {
+    @Override
+    @Deprecated(since = "2")
+    public String toString() { return ""; }
+}
+`), 0o644))
+	store = graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadPresetSchemaFile(t, "java"), store).Ingest(javaDir))
+	for id, want := range map[string]string{
+		"classes/Svc/decorators":                  "@Service",
+		"classes/Svc/methods/toString/decorators": "@Override\n@Deprecated(since = \"2\")",
+	} {
+		node, err := store.GetNode(id)
+		require.NoError(t, err, id)
+		assert.Equal(t, want, string(node.Data), id)
+	}
+}
+
 func TestEngine_IngestTreeSitter_InitFunctionDedup(t *testing.T) {
 	schema := loadGoSchema(t)
 
@@ -424,6 +1330,106 @@ func init() {
 	assert.Contains(t, fns.Children, "mypkg/functions/init.from_b_go")
 }
 
+func TestEngine_IngestTreeSitter_CollisionStrategy(t *testing.T) {
+	schema := loadGoSchema(t)
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name+".go"), []byte(`package mypkg
+
+func init() {
+	// setup from `+name+`.go
+}
+`), 0o644))
+	}
+
+	tests := []struct {
+		strategy CollisionStrategy
+		second   string // where b.go's init() goes
+	}{
+		{CollisionFile, "mypkg/functions/init.from_b_go"},
+		{CollisionNumber, "mypkg/functions/init_2"},
+		{CollisionSubdir, "mypkg/functions/init/b.go"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			old := Collisions
+			defer func() { Collisions = old }()
+			Collisions = tt.strategy
+
+			store := graph.NewMemoryStore()
+			require.NoError(t, NewEngine(schema, store).Ingest(tmpDir))
+
+			first, err := store.GetNode("mypkg/functions/init/source")
+			require.NoError(t, err)
+			assert.Contains(t, string(first.Data), "setup from a.go")
+			second, err := store.GetNode(tt.second + "/source")
+			require.NoError(t, err)
+			assert.Contains(t, string(second.Data), "setup from b.go")
+
+			parent, err := store.GetNode(filepath.Dir(tt.second))
+			require.NoError(t, err)
+			assert.Contains(t, parent.Children, tt.second)
+		})
+	}
+
+	t.Run(string(CollisionError), func(t *testing.T) {
+		old := Collisions
+		defer func() { Collisions = old }()
+		Collisions = CollisionError
+
+		err := NewEngine(schema, graph.NewMemoryStore()).Ingest(tmpDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "node ID collision: mypkg/functions/init from "+filepath.Join(tmpDir, "b.go"))
+	})
+}
+
+func TestParseCollisionStrategy(t *testing.T) {
+	for _, s := range []string{"file", "number", "error", "subdir"} {
+		got, err := ParseCollisionStrategy(s)
+		require.NoError(t, err)
+		assert.Equal(t, CollisionStrategy(s), got)
+	}
+	_, err := ParseCollisionStrategy("rename")
+	assert.Error(t, err)
+}
+
+func TestEngine_IngestTreeSitter_CollisionAcrossDirs(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	// Three same-named files in different directories project init() to the
+	// same package node, so the basename suffix alone is not unique.
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a", "b", "c"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, dir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, dir, "util.go"), []byte(`package mypkg
+
+func init() {
+	// setup from `+dir+`
+}
+`), 0o644))
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	for id, dir := range map[string]string{
+		"mypkg/functions/init":                "a",
+		"mypkg/functions/init.from_util_go":   "b",
+		"mypkg/functions/init.from_util_go.2": "c",
+	} {
+		src, err := store.GetNode(id + "/source")
+		require.NoError(t, err, id)
+		assert.Contains(t, string(src.Data), "setup from "+dir, id)
+	}
+	assert.Contains(t, logs.String(), "node ID collision: mypkg/functions/init.from_util_go")
+	assert.Contains(t, logs.String(), filepath.Join("b", "util.go"), "the warning names the earlier source file")
+}
+
 // TestEngine_Ingest_SkipsHiddenDirs verifies that the directory walk skips
 // dot-prefixed directories like .mache-mount, .git, etc. This prevents
 // recursive self-mounting when mache's FUSE mount lives inside the data source.
@@ -526,3 +1532,345 @@ func Other() {}
 	}
 	assert.True(t, found, "Main/source should be a caller of Other")
 }
+
+func TestEngine_IngestTreeSitter_CallSiteLines(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.go"), []byte(`package demo
+
+func Main() {
+	Other()
+	x := 1
+	Other(); Other()
+	_ = x
+}
+
+func Other() {}
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	// Lines are file lines, de-duplicated when a line has several calls.
+	assert.Equal(t, []int{4, 6}, store.CallSiteLines("Other", "demo/functions/Main/source"))
+	assert.Nil(t, store.CallSiteLines("Other", "demo/functions/Other/source"))
+}
+
+func TestSQLiteWriter_CallSiteLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.go"), []byte(`package demo
+
+func Main() {
+	Other()
+
+	Other()
+}
+
+func Other() {}
+`), 0o644))
+
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, NewEngine(loadGoSchema(t), w).Ingest(tmpDir))
+	require.NoError(t, w.Close())
+
+	g, err := graph.OpenSQLiteGraph(dbPath, &api.Topology{}, nil)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	assert.Equal(t, []int{4, 6}, g.CallSiteLines("Other", "demo/functions/Main/source"), "the built DB keeps call-site lines")
+	assert.Nil(t, g.CallSiteLines("Other", "demo/functions/Other/source"))
+}
+
+func TestEngine_IngestTreeSitter_GeneratedFile(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "gen.go"), []byte(`// Code generated by stringer; DO NOT EDIT.
+
+package demo
+
+func Generated() {}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "hand.go"), []byte(`package demo
+
+func Handwritten() {}
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	gen, err := store.GetNode("demo/functions/Generated/source")
+	require.NoError(t, err)
+	assert.True(t, gen.Generated())
+
+	hand, err := store.GetNode("demo/functions/Handwritten/source")
+	require.NoError(t, err)
+	assert.False(t, hand.Generated())
+}
+
+func TestEngine_IngestTreeSitter_GroupGenerated(t *testing.T) {
+	old := GroupGenerated
+	defer func() { GroupGenerated = old }()
+	GroupGenerated = true
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "gen.go"), []byte(`// Code generated by stringer; DO NOT EDIT.
+
+package demo
+
+func Generated() {}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "hand.go"), []byte(`package demo
+
+// Do not edit without talking to the platform team.
+func Handwritten() {}
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(tmpDir))
+
+	gen, err := store.GetNode("_generated/demo/functions/Generated/source")
+	require.NoError(t, err)
+	assert.True(t, gen.Generated())
+	_, err = store.GetNode("demo/functions/Generated")
+	assert.Error(t, err, "generated constructs leave the hand-written tree")
+
+	hand, err := store.GetNode("demo/functions/Handwritten/source")
+	require.NoError(t, err)
+	assert.False(t, hand.Generated())
+}
+
+func TestEngine_IngestTreeSitter_Cancelled(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		src := fmt.Sprintf("package demo\n\nfunc F%02d() {}\n", i)
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("f%02d.go", i)), []byte(src), 0o644))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store := graph.NewMemoryStore()
+	err := NewEngine(schema, store).IngestContext(ctx, tmpDir)
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = store.GetNode("demo/functions/F00")
+	assert.Error(t, err, "nothing is ingested after cancellation")
+}
+
+func TestEngine_IngestTreeSitter_TestsFor(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "foo.go"), []byte("package demo\n\nfunc Foo() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "foo_test.go"), []byte(`package demo
+
+import "testing"
+
+func TestFoo(t *testing.T) { Foo() }
+
+func TestFoo_Twice(t *testing.T) { Foo(); Foo() }
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	assert.Equal(t, []string{"demo/functions/TestFoo", "demo/functions/TestFoo_Twice"}, store.TestsFor("Foo"))
+}
+
+func TestSQLiteGraph_TestsFor(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "foo.go"), []byte("package demo\n\nfunc Foo() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "foo_test.go"), []byte(`package demo
+
+import "testing"
+
+func TestFoo(t *testing.T) { Foo() }
+
+func TestFoo_Twice(t *testing.T) { Foo(); Foo() }
+`), 0o644))
+
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, NewEngine(loadGoSchema(t), w).Ingest(tmpDir))
+	require.NoError(t, w.Close())
+
+	g, err := graph.OpenSQLiteGraph(dbPath, &api.Topology{}, nil)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	assert.Equal(t, []string{"demo/functions/TestFoo", "demo/functions/TestFoo_Twice"}, g.TestsFor("Foo"), "found from the built DB's defs")
+	assert.Empty(t, g.TestsFor("TestFoo"))
+}
+
+func TestEngine_IngestTreeSitter_TypesUsed(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "auth"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "auth", "token.go"), []byte("package auth\n\ntype Token struct{}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "user.go"), []byte(`package app
+
+import "example.com/auth"
+
+type User struct{ Name string }
+
+func User2() {}
+
+func Login(name string) (*User, auth.Token) {
+	var u User
+	u.Name = name
+	return &u, auth.Token{}
+}
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(tmpDir))
+
+	assert.Equal(t, []string{"app/types/User", "auth/types/Token"}, store.TypesUsed("app/functions/Login"))
+	callers, err := store.GetCallers("User")
+	require.NoError(t, err)
+	assert.Empty(t, callers)
+}
+
+func TestEngine_FileTimings(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	var big strings.Builder
+	big.WriteString("package demo\n")
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&big, "\nfunc F%d() { _ = %d }\n", i, i)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "big.go"), []byte(big.String()), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "small.go"), []byte("package demo\n\nfunc Small() {}\n"), 0o644))
+
+	engine := NewEngine(schema, graph.NewMemoryStore())
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	timings := engine.FileTimings()
+	require.Len(t, timings, 2)
+	byPath := map[string]FileTiming{}
+	for i, ft := range timings {
+		byPath[ft.Path] = ft
+		if i > 0 {
+			assert.GreaterOrEqual(t, timings[i-1].Total(), ft.Total(), "slowest first")
+		}
+	}
+	assert.Greater(t, byPath["big.go"].Nodes, byPath["small.go"].Nodes)
+	assert.Positive(t, byPath["big.go"].Parse)
+
+	report := string(FormatFileTimings(timings))
+	lines := strings.Split(strings.TrimSuffix(report, "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "nodes")
+	assert.True(t, strings.HasSuffix(lines[1], timings[0].Path))
+}
+
+func TestEngine_IdentifierCounts(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	src := "package demo\n\nfunc A() {\n\tlog()\n\tlog()\n\tsave()\n}\n\nfunc B() {\n\tlog()\n}\n\nfunc log()  {}\nfunc save() {}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "demo.go"), []byte(src), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	counts := engine.IdentifierCounts()
+	require.NotEmpty(t, counts)
+	assert.Equal(t, IdentifierCount{Language: "go", Token: "log", Count: 3}, counts[0], "every call site counts")
+	assert.Contains(t, counts, IdentifierCount{Language: "go", Token: "save", Count: 1})
+
+	// Re-ingesting a file replaces its counts rather than adding to them.
+	src = strings.Replace(src, "\tlog()\n\tlog()\n", "", 1)
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "demo.go"), []byte(src), 0o644))
+	require.NoError(t, engine.ReIngestFile(filepath.Join(tmpDir, "demo.go")))
+	assert.Contains(t, engine.IdentifierCounts(), IdentifierCount{Language: "go", Token: "log", Count: 1})
+
+	report := string(FormatIdentifierCounts(engine.IdentifierCounts()))
+	lines := strings.Split(strings.TrimSuffix(report, "\n"), "\n")
+	assert.Contains(t, lines[0], "identifier")
+	assert.Len(t, lines, len(engine.IdentifierCounts())+1)
+}
+
+func TestEngine_Manifest(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	src := []byte("package demo\n\nfunc Hello() {}\n")
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "hello.go"), src, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("# demo\n"), 0o644))
+
+	engine := NewEngine(schema, graph.NewMemoryStore())
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	manifest := engine.Manifest()
+	require.Len(t, manifest, 2)
+	assert.Equal(t, "README.md", manifest[0].Path, "sorted by path")
+	assert.Empty(t, manifest[0].Language)
+
+	hello := manifest[1]
+	assert.Equal(t, "hello.go", hello.Path)
+	assert.Equal(t, "go", hello.Language)
+	assert.Equal(t, int64(len(src)), hello.Size)
+	sum := sha256.Sum256(src)
+	assert.Equal(t, hex.EncodeToString(sum[:]), hello.SHA256)
+	assert.False(t, hello.ModTime.IsZero())
+
+	var doc struct {
+		Files []ManifestEntry `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal(FormatManifest(manifest), &doc))
+	require.Len(t, doc.Files, 2)
+	assert.Equal(t, hello.SHA256, doc.Files[1].SHA256)
+	assert.True(t, hello.ModTime.Equal(doc.Files[1].ModTime))
+}
+
+func TestEngine_Ingest_SingleFile(t *testing.T) {
+	schema := loadGoSchema(t)
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc Hello() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "other.go"), []byte("package main\n\nfunc Other() {}\n"), 0o644))
+
+	// Relative paths must work: ingest resolves the file before routing it.
+	t.Chdir(tmpDir)
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	require.NoError(t, engine.Ingest("main.go"))
+
+	// Rooted at the file's directory, but only the named file is projected.
+	realDir, err := filepath.EvalSymlinks(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, realDir, engine.RootPath)
+	_, err = store.GetNode("main/functions/Hello/source")
+	require.NoError(t, err)
+	_, err = store.GetNode("main/functions/Other")
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+
+	fn, err := store.GetNode("main/functions/Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "main.go:3:3", string(fn.Properties["location"]))
+}
+
+func TestEngine_Ingest_SingleRawFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(&api.Topology{Version: "v1"}, store)
+	require.NoError(t, engine.Ingest(path))
+
+	n, err := store.GetNode("notes.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(n.Data))
+}
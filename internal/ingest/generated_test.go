@@ -0,0 +1,37 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsGeneratedSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb\n", true},
+		{"go after license", "// Copyright 2024 Acme\n// SPDX-License-Identifier: MIT\n\n// Code generated by mockgen. DO NOT EDIT.\npackage mocks\n", true},
+		{"python", "# -*- coding: utf-8 -*-\n# Generated by the protocol buffer compiler.  DO NOT EDIT!\n", true},
+		{"at-generated", "/**\n * @generated SignedSource<<abc>>\n */\n", true},
+		{"code generated under #", "# Code generated by sqlc. DO NOT EDIT.\n", true},
+		{"dotnet", "// <auto-generated>\n//     This code was generated by a tool.\n// </auto-generated>\n", true},
+		{"thrift", "/**\n * Autogenerated by Thrift Compiler (0.19.0)\n */\n", true},
+		{"handwritten", "package main\n\nfunc main() {}\n", false},
+		{"do not edit note", "// Do not edit without talking to the platform team.\npackage main\n", false},
+		{"generated in prose", "-- This file is auto-generated; changes will be lost.\n", false},
+		{"go marker mid-line", "// See the Code generated by x. DO NOT EDIT. notes\npackage main\n", false},
+		{"at-generated mid-comment", "// the @generated tag marks generated files\n", false},
+		{"marker outside comment", "package main\n\nconst msg = \"DO NOT EDIT\"\n", false},
+		{"marker too deep", strings.Repeat("// license\n", generatedScanLines) + "// Code generated. DO NOT EDIT.\n", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isGeneratedSource([]byte(tt.content)))
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package ingest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// commitIDLen is how many hex digits of a commit's SHA name it in the
+// _history projection; git lengthens an ID that would be ambiguous.
+const commitIDLen = 12
+
+// GitHistory reads the commit history of a git repository for the _history
+// projection (see vfs.HistoryHandler). A commit's files are cached by ID,
+// since commits never change; commit lists are cached until HEAD moves.
+type GitHistory struct {
+	root string // repository top level, symlinks resolved
+
+	mu      sync.Mutex
+	head    string              // HEAD when commits and ranges were listed
+	commits []string            // reachable from head, newest first
+	ranges  map[string][]string // "file:start,end" → commits, at head
+	files   map[string]map[string][]byte
+}
+
+// OpenGitHistory opens the history of the git repository containing path.
+func OpenGitHistory(path string) (*GitHistory, error) {
+	dir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", path, err)
+	}
+	root, err := filepath.EvalSymlinks(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, err
+	}
+	return &GitHistory{root: root, files: make(map[string]map[string][]byte)}, nil
+}
+
+// git runs a git command in the repository and returns its output.
+func (h *GitHistory) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = h.root
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// sync drops the cached commit lists if HEAD has moved. Callers hold h.mu.
+func (h *GitHistory) sync() error {
+	out, err := h.git("rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	if head := strings.TrimSpace(out); head != h.head {
+		h.head, h.commits, h.ranges = head, nil, make(map[string][]string)
+	}
+	return nil
+}
+
+// Commits lists the IDs of the commits reachable from HEAD, newest first.
+func (h *GitHistory) Commits() ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.sync(); err != nil {
+		return nil, err
+	}
+	if h.commits == nil {
+		out, err := h.git("log", fmt.Sprintf("--abbrev=%d", commitIDLen), "--format=%h", h.head)
+		if err != nil {
+			return nil, err
+		}
+		h.commits = strings.Fields(out)
+	}
+	return h.commits, nil
+}
+
+// CommitFiles returns the files of commit id's _history directory: its
+// message, author, date (ISO 8601), and changes, the files it changed one
+// per line after their git status letter ("M\tmain.go"). A merge's changes
+// are against its first parent.
+func (h *GitHistory) CommitFiles(id string) (map[string][]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if files, ok := h.files[id]; ok {
+		return files, nil
+	}
+	out, err := h.git("show", "-s", "--format=%an <%ae>%n%aI%n%B", id, "--")
+	if err != nil {
+		return nil, err
+	}
+	meta := strings.SplitN(out, "\n", 3)
+	if len(meta) < 3 {
+		return nil, fmt.Errorf("git show %s: unexpected output", id)
+	}
+	changes, err := h.git("show", "--format=", "--name-status", "--diff-merges=first-parent", id, "--")
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{
+		"author":  []byte(meta[0] + "\n"),
+		"date":    []byte(meta[1] + "\n"),
+		"message": []byte(strings.TrimSpace(meta[2]) + "\n"),
+		"changes": []byte(strings.TrimLeft(changes, "\n")),
+	}
+	h.files[id] = files
+	return files, nil
+}
+
+// LineCommits lists the commits, newest first, that changed lines start
+// through end (1-based, inclusive) of file as it is at HEAD, following the
+// lines back through edits that moved them. A file outside the repository
+// or not committed has no commits.
+func (h *GitHistory) LineCommits(file string, start, end int) ([]string, error) {
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(h.root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil, nil
+	}
+	key := fmt.Sprintf("%s:%d,%d", rel, start, end)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.sync(); err != nil {
+		return nil, err
+	}
+	if ids, ok := h.ranges[key]; ok {
+		return ids, nil
+	}
+	// git log -L fails for a file HEAD doesn't have and for lines past its
+	// end (the file was edited since); both mean no history to show.
+	out, err := h.git("log", fmt.Sprintf("--abbrev=%d", commitIDLen), "--format=%h", "-s",
+		fmt.Sprintf("-L%d,%d:%s", start, end, filepath.ToSlash(rel)), h.head)
+	ids := []string{}
+	if err == nil {
+		ids = strings.Fields(out)
+	}
+	h.ranges[key] = ids
+	return ids, nil
+}
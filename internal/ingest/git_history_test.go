@@ -0,0 +1,77 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHistory(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.name", "Tester")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+
+	src := filepath.Join(dir, "main.go")
+	commit := func(content, msg string) {
+		require.NoError(t, os.WriteFile(src, []byte(content), 0o644))
+		runGit(t, dir, "add", "main.go")
+		runGit(t, dir, "commit", "-m", msg)
+	}
+	commit("package main\n\nfunc A() {}\n\nfunc B() {}\n", "Add A and B")
+	commit("package main\n\nfunc A() { println() }\n\nfunc B() {}\n", "Change A\n\nIt prints now.")
+
+	h, err := OpenGitHistory(src)
+	require.NoError(t, err)
+
+	ids, err := h.Commits()
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+	assert.Len(t, ids[0], commitIDLen)
+
+	files, err := h.CommitFiles(ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, "Change A\n\nIt prints now.\n", string(files["message"]))
+	assert.Equal(t, "Tester <test@example.com>\n", string(files["author"]))
+	assert.NotEmpty(t, files["date"])
+	assert.Equal(t, "M\tmain.go\n", string(files["changes"]))
+
+	files, err = h.CommitFiles(ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, "A\tmain.go\n", string(files["changes"]), "a root commit's files are added")
+
+	// A changed in both commits, B only when it was added.
+	a, err := h.LineCommits(src, 3, 3)
+	require.NoError(t, err)
+	assert.Equal(t, ids, a)
+	b, err := h.LineCommits(src, 5, 5)
+	require.NoError(t, err)
+	assert.Equal(t, ids[1:], b)
+
+	// Lines HEAD doesn't have, and files git doesn't track, have no history.
+	none, err := h.LineCommits(src, 50, 60)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+	untracked := filepath.Join(dir, "new.go")
+	require.NoError(t, os.WriteFile(untracked, []byte("package main\n"), 0o644))
+	none, err = h.LineCommits(untracked, 1, 1)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	// A new commit moves HEAD and is listed.
+	commit("package main\n\nfunc A() { println() }\n\nfunc B() { println() }\n", "Change B")
+	ids2, err := h.Commits()
+	require.NoError(t, err)
+	assert.Len(t, ids2, 3)
+	b, err = h.LineCommits(src, 5, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ids2[0], ids2[2]}, b)
+}
+
+func TestOpenGitHistory_NotARepository(t *testing.T) {
+	_, err := OpenGitHistory(t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,186 @@
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// coverBlock is one block of a Go cover profile: the line it starts on,
+// its statement count, and whether any test ran it.
+type coverBlock struct {
+	startLine  int
+	statements int
+	covered    bool
+}
+
+// GoCoverProfile is a Go cover profile (go test -coverprofile) read for the
+// _coverage projection (see vfs.CoverageHandler). The profile names files
+// by import path; they are matched to source files under root through the
+// module path in root's go.mod, else by the most trailing path segments
+// shared with one profile file.
+type GoCoverProfile struct {
+	root   string // symlinks resolved
+	module string // module path from root/go.mod, "" without one
+	blocks map[string][]coverBlock
+	files  []string // the profile's file names
+
+	mu    sync.Mutex
+	names map[string]string // source file → profile file name ("" when none)
+}
+
+// OpenGoCoverProfile reads the Go cover profile at profilePath for the
+// source tree at root.
+func OpenGoCoverProfile(profilePath, root string) (*GoCoverProfile, error) {
+	f, err := os.Open(profilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	// The same block is listed once per test binary that covered it
+	// (-coverpkg); it ran if any of them ran it.
+	type blockKey struct {
+		name  string
+		start string
+		end   string
+	}
+	merged := make(map[blockKey]*coverBlock)
+	var order []blockKey
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if lineNo == 1 {
+			if !strings.HasPrefix(line, "mode:") {
+				return nil, fmt.Errorf("%s: not a Go cover profile (no mode: line)", profilePath)
+			}
+			continue
+		}
+		name, start, end, b, err := parseCoverLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", profilePath, lineNo, err)
+		}
+		key := blockKey{name, start, end}
+		if m, ok := merged[key]; ok {
+			m.covered = m.covered || b.covered
+			continue
+		}
+		merged[key] = &b
+		order = append(order, key)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", profilePath, err)
+	}
+
+	p := &GoCoverProfile{
+		root:   root,
+		blocks: make(map[string][]coverBlock),
+		names:  make(map[string]string),
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		p.root = resolved
+	}
+	if data, err := os.ReadFile(filepath.Join(p.root, "go.mod")); err == nil {
+		p.module = goModulePath(data)
+	}
+	for _, key := range order {
+		if p.blocks[key.name] == nil {
+			p.files = append(p.files, key.name)
+		}
+		p.blocks[key.name] = append(p.blocks[key.name], *merged[key])
+	}
+	return p, nil
+}
+
+// parseCoverLine parses a profile line,
+// "name.go:startLine.startCol,endLine.endCol statements count".
+func parseCoverLine(line string) (name, start, end string, b coverBlock, err error) {
+	colon := strings.LastIndexByte(line, ':')
+	if colon < 0 {
+		return "", "", "", b, fmt.Errorf("malformed block %q", line)
+	}
+	name = line[:colon]
+	fields := strings.Fields(line[colon+1:])
+	if len(fields) != 3 {
+		return "", "", "", b, fmt.Errorf("malformed block %q", line)
+	}
+	start, end, ok := strings.Cut(fields[0], ",")
+	if !ok {
+		return "", "", "", b, fmt.Errorf("malformed block %q", line)
+	}
+	startLine, err1 := strconv.Atoi(strings.SplitN(start, ".", 2)[0])
+	_, err2 := strconv.Atoi(strings.SplitN(end, ".", 2)[0])
+	statements, err3 := strconv.Atoi(fields[1])
+	count, err4 := strconv.ParseInt(fields[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return "", "", "", b, fmt.Errorf("malformed block %q", line)
+	}
+	return name, start, end, coverBlock{startLine: startLine, statements: statements, covered: count > 0}, nil
+}
+
+// goModulePath returns the module path declared in a go.mod file.
+func goModulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		if f := strings.Fields(line); len(f) >= 2 && f[0] == "module" {
+			return strings.Trim(f[1], `"`)
+		}
+	}
+	return ""
+}
+
+// profileName returns the profile's name for source file file, or "".
+// Caller holds p.mu.
+func (p *GoCoverProfile) profileName(file string) string {
+	if name, ok := p.names[file]; ok {
+		return name
+	}
+	name := ""
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		resolved = file
+	}
+	if rel, err := filepath.Rel(p.root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+		rel = filepath.ToSlash(rel)
+		switch {
+		case p.module != "" && p.blocks[path.Join(p.module, rel)] != nil:
+			name = path.Join(p.module, rel)
+		case p.blocks[filepath.ToSlash(resolved)] != nil:
+			name = filepath.ToSlash(resolved)
+		default:
+			name = longestSuffixMatch(p.files, strings.Split(rel, "/"))
+		}
+	}
+	p.names[file] = name
+	return name
+}
+
+// Statements counts the statements of the blocks starting within lines
+// start through end (1-based, inclusive) of file, and how many of them ran.
+// ok is false when the profile doesn't cover file.
+func (p *GoCoverProfile) Statements(file string, start, end int) (covered, total int, ok bool) {
+	p.mu.Lock()
+	name := p.profileName(file)
+	p.mu.Unlock()
+	if name == "" {
+		return 0, 0, false
+	}
+	for _, b := range p.blocks[name] {
+		if b.startLine < start || b.startLine > end {
+			continue
+		}
+		total += b.statements
+		if b.covered {
+			covered += b.statements
+		}
+	}
+	return covered, total, true
+}
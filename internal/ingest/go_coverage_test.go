@@ -0,0 +1,77 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCoverProfile(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cover.out")
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+	return path
+}
+
+func TestGoCoverProfile(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "pkg"), 0o755))
+	src := filepath.Join(root, "pkg", "foo.go")
+	require.NoError(t, os.WriteFile(src, []byte("package pkg\n"), 0o644))
+
+	profile := writeCoverProfile(t, `mode: set
+example.com/app/pkg/foo.go:3.14,5.2 2 1
+example.com/app/pkg/foo.go:5.2,7.3 1 0
+example.com/app/pkg/foo.go:10.20,12.2 3 0
+example.com/app/pkg/foo.go:10.20,12.2 3 1
+example.com/app/other.go:1.1,2.2 1 1
+`)
+	p, err := OpenGoCoverProfile(profile, root)
+	require.NoError(t, err)
+
+	covered, total, ok := p.Statements(src, 3, 7)
+	require.True(t, ok)
+	assert.Equal(t, 2, covered)
+	assert.Equal(t, 3, total)
+
+	covered, total, ok = p.Statements(src, 10, 12)
+	require.True(t, ok)
+	assert.Equal(t, 3, covered, "a block run by any test binary is covered")
+	assert.Equal(t, 3, total)
+
+	covered, total, ok = p.Statements(src, 20, 30)
+	assert.True(t, ok)
+	assert.Zero(t, covered+total, "no blocks start in the range")
+
+	_, _, ok = p.Statements(filepath.Join(root, "pkg", "bar.go"), 1, 10)
+	assert.False(t, ok, "not in the profile")
+}
+
+func TestGoCoverProfile_SuffixMatch(t *testing.T) {
+	// Without a go.mod, files match the profile entry sharing the most
+	// trailing path segments.
+	root := t.TempDir()
+	src := filepath.Join(root, "internal", "util", "util.go")
+	profile := writeCoverProfile(t, `mode: count
+github.com/x/y/cmd/util.go:1.1,3.2 1 4
+github.com/x/y/internal/util/util.go:1.1,3.2 2 4
+`)
+	p, err := OpenGoCoverProfile(profile, root)
+	require.NoError(t, err)
+	covered, total, ok := p.Statements(src, 1, 3)
+	require.True(t, ok)
+	assert.Equal(t, 2, covered)
+	assert.Equal(t, 2, total)
+}
+
+func TestGoCoverProfile_Malformed(t *testing.T) {
+	_, err := OpenGoCoverProfile(writeCoverProfile(t, "foo.go:1.1,2.2 1 1\n"), t.TempDir())
+	assert.ErrorContains(t, err, "not a Go cover profile")
+
+	_, err = OpenGoCoverProfile(writeCoverProfile(t, "mode: set\nfoo.go:1.1 1 1\n"), t.TempDir())
+	assert.ErrorContains(t, err, "cover.out:2: malformed block")
+}
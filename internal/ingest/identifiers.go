@@ -0,0 +1,77 @@
+package ingest
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// IdentifierCount is how often a ref token (a call, or a typed token such
+// as a type or env ref) occurs in the source files of one language.
+type IdentifierCount struct {
+	Language string
+	Token    string
+	Count    int // call sites, or constructs for tokens without call-site lines
+}
+
+// fileIdentifiers is one source file's ref tokens and their counts.
+type fileIdentifiers struct {
+	language string
+	counts   map[string]int
+}
+
+// recordIdentifiers stores the counts of the ref tokens the parsed file
+// produced, replacing any from an earlier ingest of it. A ref counts each
+// of its call-site lines, or once without lines.
+func (e *Engine) recordIdentifiers(result *parsedTreeSitterFile, refs []bufferedRef) {
+	path := result.realPath
+	if path == "" {
+		path = result.job.path
+	}
+	path = e.relPath(path)
+	counts := make(map[string]int)
+	for _, r := range refs {
+		counts[r.token] += max(1, len(r.lines))
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.identifiers == nil {
+		e.identifiers = make(map[string]fileIdentifiers)
+	}
+	e.identifiers[path] = fileIdentifiers{language: result.job.langName, counts: counts}
+}
+
+// IdentifierCounts returns the ref tokens of the tree-sitter files ingested
+// so far, summed per language, most frequent first.
+func (e *Engine) IdentifierCounts() []IdentifierCount {
+	type key struct{ language, token string }
+	sums := make(map[key]int)
+	e.mu.Lock()
+	for _, f := range e.identifiers {
+		for token, n := range f.counts {
+			sums[key{f.language, token}] += n
+		}
+	}
+	e.mu.Unlock()
+
+	counts := make([]IdentifierCount, 0, len(sums))
+	for k, n := range sums {
+		counts = append(counts, IdentifierCount{Language: k.language, Token: k.token, Count: n})
+	}
+	slices.SortFunc(counts, func(a, b IdentifierCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Language, b.Language), strings.Compare(a.Token, b.Token))
+	})
+	return counts
+}
+
+// FormatIdentifierCounts renders counts as an aligned table, one token per
+// line, in the order given.
+func FormatIdentifierCounts(counts []IdentifierCount) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%8s  %-12s %s\n", "count", "language", "identifier")
+	for _, c := range counts {
+		fmt.Fprintf(&b, "%8d  %-12s %s\n", c.Count, c.Language, c.Token)
+	}
+	return []byte(b.String())
+}
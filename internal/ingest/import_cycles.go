@@ -0,0 +1,232 @@
+package ingest
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// importsDir is the directory a schema groups a package's imports under,
+// one entry per imported path (the Go preset's <pkg>/imports/).
+const importsDir = "imports"
+
+// GroupsImports reports whether schema groups imports per package: an
+// imports node below a top-level node, as in the Go preset. Only then can
+// ImportCycles find any.
+func GroupsImports(schema *api.Topology) bool {
+	var nested func(nodes []api.Node) bool
+	nested = func(nodes []api.Node) bool {
+		for _, n := range nodes {
+			if n.Name == importsDir || nested(n.Children) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, n := range schema.Nodes {
+		if nested(n.Children) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportCycle is a set of packages that import each other, directly or
+// through one another: a strongly connected component of the import graph.
+type ImportCycle struct {
+	Packages []string // the component's package directories, sorted
+	Path     []string // a shortest cycle through Packages[0], ending where it starts
+}
+
+// ImportCycles finds the circular imports among g's packages: directories
+// holding an imports/ directory. Each import entry is resolved to the
+// package whose path shares the most trailing segments with it, so
+// "github.com/acme/app/internal/auth" resolves to internal/auth; entries
+// matching no package, or two equally well, are external and ignored.
+func ImportCycles(g graph.Graph) []ImportCycle {
+	var pkgs []string
+	imports := make(map[string][]string)
+	var walk func(id string)
+	walk = func(id string) {
+		stats, err := g.ListChildStats(id)
+		if err != nil {
+			return
+		}
+		for _, s := range stats {
+			if !s.IsDir {
+				continue
+			}
+			if path.Base(s.ID) == importsDir && id != "" {
+				entries, _ := g.ListChildren(s.ID)
+				pkgs = append(pkgs, id)
+				for _, e := range entries {
+					imports[id] = append(imports[id], path.Base(e))
+				}
+				continue
+			}
+			walk(s.ID)
+		}
+	}
+	walk("")
+
+	edges := make(map[string][]string, len(pkgs))
+	for _, p := range pkgs {
+		for _, imp := range imports[p] {
+			if target := resolveImport(pkgs, imp); target != "" && target != p && !slices.Contains(edges[p], target) {
+				edges[p] = append(edges[p], target)
+			}
+		}
+		slices.Sort(edges[p])
+	}
+
+	var cycles []ImportCycle
+	for _, scc := range stronglyConnected(pkgs, edges) {
+		if len(scc) < 2 {
+			continue
+		}
+		slices.Sort(scc)
+		cycles = append(cycles, ImportCycle{Packages: scc, Path: shortestCycle(scc[0], scc, edges)})
+	}
+	slices.SortFunc(cycles, func(a, b ImportCycle) int { return strings.Compare(a.Packages[0], b.Packages[0]) })
+	return cycles
+}
+
+// importSegments splits an import entry into path segments: "a/b" (Go,
+// JavaScript, without ./ and an extension), "a.b" (Python, Java), or
+// "a::b" (Rust).
+func importSegments(imp string) []string {
+	imp = strings.Trim(imp, "\"'`")
+	switch {
+	case strings.Contains(imp, "::"):
+		imp = strings.ReplaceAll(imp, "::", "/")
+	case strings.Contains(imp, "/"):
+		imp = strings.TrimSuffix(imp, path.Ext(imp))
+	default:
+		imp = strings.ReplaceAll(imp, ".", "/")
+	}
+	var segs []string
+	for _, s := range strings.Split(imp, "/") {
+		if s != "" && s != "." && s != ".." {
+			segs = append(segs, s)
+		}
+	}
+	return segs
+}
+
+// resolveImport returns the package of pkgs whose path shares the most
+// trailing segments with imp, or "" when none or several do.
+func resolveImport(pkgs []string, imp string) string {
+	return longestSuffixMatch(pkgs, importSegments(imp))
+}
+
+// longestSuffixMatch returns the slash-separated path of paths sharing the
+// most trailing segments with segs, or "" when none or several do.
+func longestSuffixMatch(paths, segs []string) string {
+	best, bestLen, tie := "", 0, false
+	for _, p := range paths {
+		ps := strings.Split(p, "/")
+		n := 0
+		for n < len(ps) && n < len(segs) && ps[len(ps)-1-n] == segs[len(segs)-1-n] {
+			n++
+		}
+		switch {
+		case n > bestLen:
+			best, bestLen, tie = p, n, false
+		case n == bestLen && n > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
+
+// stronglyConnected returns the strongly connected components of the
+// graph over nodes (Tarjan's algorithm).
+func stronglyConnected(nodes []string, edges map[string][]string) [][]string {
+	index := make(map[string]int, len(nodes))
+	low := make(map[string]int, len(nodes))
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+	var connect func(v string)
+	connect = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range edges[v] {
+			if _, seen := index[w]; !seen {
+				connect(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var scc []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		sccs = append(sccs, scc)
+	}
+	for _, v := range nodes {
+		if _, seen := index[v]; !seen {
+			connect(v)
+		}
+	}
+	return sccs
+}
+
+// shortestCycle returns a shortest path from start back to itself through
+// the members of its component, breadth first.
+func shortestCycle(start string, members []string, edges map[string][]string) []string {
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range edges[v] {
+			if w == start {
+				cycle := []string{start}
+				for u := v; u != start; u = prev[u] {
+					cycle = append(cycle, u)
+				}
+				slices.Reverse(cycle[1:])
+				return append(cycle, start)
+			}
+			if _, seen := prev[w]; !seen && slices.Contains(members, w) {
+				prev[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return nil
+}
+
+// FormatImportCycles renders cycles one per paragraph: the packages that
+// import each other, then a cycle through them. It is nil without cycles.
+func FormatImportCycles(cycles []ImportCycle) []byte {
+	if len(cycles) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	for _, c := range cycles {
+		fmt.Fprintf(&b, "%d packages import each other: %s\n", len(c.Packages), strings.Join(c.Packages, ", "))
+		fmt.Fprintf(&b, "  %s\n", strings.Join(c.Path, " -> "))
+	}
+	return []byte(b.String())
+}
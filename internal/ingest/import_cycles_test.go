@@ -0,0 +1,71 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+func TestImportCycles(t *testing.T) {
+	dir := t.TempDir()
+	for path, src := range map[string]string{
+		"api/api.go":         "package api\n\nimport \"example.com/app/auth\"\n\nvar _ = auth.X\n",
+		"auth/auth.go":       "package auth\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/session\"\n)\n\nvar X = session.Y\nvar _ = fmt.Sprint\n",
+		"session/session.go": "package session\n\nimport \"example.com/app/api\"\n\nvar Y = 1\nvar _ = api.Z\n",
+		"cli/cli.go":         "package cli\n\nimport \"example.com/app/api\"\n\nvar _ = api.Z\n",
+		"store/store.go":     "package store\n\nimport \"example.com/app/cache\"\n\nvar _ = cache.C\n",
+		"cache/cache.go":     "package cache\n\nimport \"example.com/app/store\"\n\nvar C = store.S\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte(src), 0o644))
+	}
+	schema := loadGoSchema(t)
+	require.True(t, GroupsImports(schema))
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(dir))
+
+	cycles := ImportCycles(store)
+	assert.Equal(t, []ImportCycle{
+		{Packages: []string{"api", "auth", "session"}, Path: []string{"api", "auth", "session", "api"}},
+		{Packages: []string{"cache", "store"}, Path: []string{"cache", "store", "cache"}},
+	}, cycles)
+	assert.Equal(t, `3 packages import each other: api, auth, session
+  api -> auth -> session -> api
+2 packages import each other: cache, store
+  cache -> store -> cache
+`, string(FormatImportCycles(cycles)))
+}
+
+func TestImportCycles_None(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "a"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", "a.go"), []byte("package a\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n"), 0o644))
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(dir))
+
+	assert.Empty(t, ImportCycles(store))
+	assert.Nil(t, FormatImportCycles(nil), "nothing to serve without cycles")
+}
+
+func TestResolveImport(t *testing.T) {
+	pkgs := []string{"internal/auth", "pkg/auth", "session", "web/components"}
+	tests := []struct {
+		imp, want string
+	}{
+		{`"example.com/app/internal/auth"`, "internal/auth"},
+		{`"example.com/app/session"`, "session"},
+		{`"example.com/other/auth"`, ""}, // two packages match equally
+		{`"fmt"`, ""},
+		{"app.session", "session"},
+		{"crate::session", "session"},
+		{`'../web/components.js'`, "web/components"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, resolveImport(pkgs, tt.imp), tt.imp)
+	}
+}
@@ -0,0 +1,53 @@
+package ingest
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	machetmpl "github.com/agentic-research/mache/internal/template"
+)
+
+// fieldRefRe matches the inside of a template action that is nothing but
+// one field reference, e.g. .role in {{.role}} or .item.cve.id.
+var fieldRefRe = regexp.MustCompile(`^\s*\.([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)\s*$`)
+
+// jsonFieldPath returns the JSONPath of the scalar field tmpl renders from a
+// located JSON match, or "" when the leaf can't be inverted: the template
+// does more than reference one field, the field is an object or array, or
+// the match carries no path (NewJsonWalker).
+func jsonFieldPath(delims machetmpl.Delims, match Match, tmpl string) string {
+	lj, ok := match.Context().(locatedJSON)
+	if !ok {
+		return ""
+	}
+	action, ok := strings.CutPrefix(tmpl, delims.Left)
+	if !ok {
+		return ""
+	}
+	if action, ok = strings.CutSuffix(action, delims.Right); !ok {
+		return ""
+	}
+	m := fieldRefRe.FindStringSubmatch(action)
+	if m == nil {
+		return ""
+	}
+	cur := lj.value
+	path := slices.Clone(lj.path)
+	for _, f := range strings.Split(m[1], ".") {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return ""
+		}
+		if cur, ok = obj[f]; !ok {
+			return ""
+		}
+		path = path.Child(f)
+	}
+	switch cur.(type) {
+	case string, float64, bool, nil:
+		return path.String()
+	default:
+		return ""
+	}
+}
@@ -2,17 +2,27 @@ package ingest
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/ohler55/ojg/jp"
 )
 
 // JsonWalker implements Walker for JSON-like data.
-type JsonWalker struct{}
+type JsonWalker struct {
+	locate bool // record each match's JSONPath (NewLocatingJsonWalker)
+}
 
 func NewJsonWalker() *JsonWalker {
 	return &JsonWalker{}
 }
 
+// NewLocatingJsonWalker returns a JsonWalker whose matches also carry their
+// normalized JSONPath from the document root (see locatedJSON), so leaves
+// rendered from a single field can be written back to the source file.
+func NewLocatingJsonWalker() *JsonWalker {
+	return &JsonWalker{locate: true}
+}
+
 // Query implements Walker.
 func (w *JsonWalker) Query(root any, selector string) ([]Match, error) {
 	// Parse JSONPath
@@ -21,6 +31,10 @@ func (w *JsonWalker) Query(root any, selector string) ([]Match, error) {
 		return nil, fmt.Errorf("invalid jsonpath '%s': %w", selector, err)
 	}
 
+	if w.locate {
+		return locateMatches(x, root), nil
+	}
+
 	// Execute query
 	results := x.Get(root)
 
@@ -33,8 +47,44 @@ func (w *JsonWalker) Query(root any, selector string) ([]Match, error) {
 	return matches, nil
 }
 
+// locatedJSON is the Context of a located match: the matched value and its
+// path, so child queries against it extend the path rather than restart it.
+type locatedJSON struct {
+	value any
+	path  jp.Expr
+}
+
+func locateMatches(x jp.Expr, root any) []Match {
+	base := jp.R()
+	if lj, ok := root.(locatedJSON); ok {
+		root, base = lj.value, lj.path
+	}
+
+	// Locate reports nothing for the bare root selector.
+	if len(x) == 1 {
+		if _, ok := x[0].(jp.Root); ok {
+			return []Match{&jsonMatch{value: root, path: base}}
+		}
+	}
+
+	locs := x.Locate(root, 0)
+	matches := make([]Match, len(locs))
+	for i, loc := range locs {
+		rel := loc
+		if _, ok := rel[0].(jp.Root); ok {
+			rel = rel[1:]
+		}
+		matches[i] = &jsonMatch{
+			value: loc.First(root),
+			path:  append(slices.Clone(base), rel...),
+		}
+	}
+	return matches
+}
+
 type jsonMatch struct {
 	value any
+	path  jp.Expr // nil unless produced by a locating walker
 }
 
 // Values implements Match.
@@ -47,5 +97,8 @@ func (m *jsonMatch) Values() map[string]any {
 
 // Context implements Match.
 func (m *jsonMatch) Context() any {
+	if m.path != nil {
+		return locatedJSON{value: m.value, path: m.path}
+	}
 	return m.value
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/agentic-research/mache/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -49,4 +50,32 @@ func TestJsonWalker(t *testing.T) {
 		// For primitive, we decide on a convention. Let's use "value" key.
 		assert.Equal(t, map[string]any{"value": "1.0"}, matches[0].Values())
 	})
+
+	t.Run("locating walker records paths", func(t *testing.T) {
+		lw := NewLocatingJsonWalker()
+		roots, err := lw.Query(data, "$")
+		require.NoError(t, err)
+		require.Len(t, roots, 1)
+
+		users, err := lw.Query(roots[0].Context(), "users[*]")
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.Equal(t, map[string]any{"name": "Bob", "role": "user"}, users[1].Values())
+		assert.Equal(t, "$.users[1].role", jsonFieldPath(schemaDelims(nil), users[1], "{{.role}}"))
+		assert.Equal(t, "$.users[1].role", jsonFieldPath(schemaDelims(nil), users[1], "{{ .role }}"))
+
+		// Only a lone reference to a scalar field is invertible.
+		assert.Empty(t, jsonFieldPath(schemaDelims(nil), users[1], "role: {{.role}}"))
+		assert.Empty(t, jsonFieldPath(schemaDelims(nil), users[1], "{{.missing}}"))
+		assert.Empty(t, jsonFieldPath(schemaDelims(nil), roots[0], "{{.meta}}"))
+		assert.Equal(t, "$.meta.version", jsonFieldPath(schemaDelims(nil), roots[0], "{{.meta.version}}"))
+		brackets := schemaDelims(&api.Topology{LeftDelim: "[[", RightDelim: "]]"})
+		assert.Equal(t, "$.users[1].role", jsonFieldPath(brackets, users[1], "[[.role]]"))
+		assert.Empty(t, jsonFieldPath(brackets, users[1], "{{.role}}"))
+
+		// The plain walker carries no paths.
+		plain, err := w.Query(data, "$.users[*]")
+		require.NoError(t, err)
+		assert.Empty(t, jsonFieldPath(schemaDelims(nil), plain[0], "{{.role}}"))
+	})
 }
@@ -0,0 +1,133 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+	"unicode"
+)
+
+// StreamJSONLinesRaw iterates over the records of a JSON Lines file
+// (.jsonl / .ndjson) yielding raw (id, json) strings without parsing, one
+// line at a time. The ID is the 1-based line number; blank lines are
+// skipped.
+func StreamJSONLinesRaw(path string, fn func(id, raw string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open json lines %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }() // safe to ignore
+
+	r := bufio.NewReaderSize(f, 64<<10)
+	for lineNo := 1; ; lineNo++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+		if raw := bytes.TrimSpace(line); len(raw) > 0 {
+			if fnErr := fn(strconv.Itoa(lineNo), string(raw)); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// ingestJSONLines projects each line of a JSON Lines file as a record, like
+// a SQLite row, streaming the file so it never has to fit in memory.
+// Content is inlined since there is no database to re-render it from.
+func (e *Engine) ingestJSONLines(ctx context.Context, path string) error {
+	if _, err := ensureFile(path, "a JSON Lines file"); err != nil {
+		return err
+	}
+	e.recordManifest(path, "jsonl", nil, time.Time{})
+	err := e.ingestRecordStream(ctx, "", 0, func(emit func(id, raw string) error) error {
+		return StreamJSONLinesRaw(path, emit)
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return err
+}
+
+// StreamJSONValuesRaw iterates over the whitespace-separated JSON values
+// read from r — JSON Lines, or values concatenated or pretty-printed across
+// lines — yielding raw (id, json) strings. The ID is the 1-based value index.
+func StreamJSONValuesRaw(r io.Reader, fn func(id, raw string) error) error {
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("read record %d: %w", n, err)
+		}
+		if err := fn(strconv.Itoa(n), string(raw)); err != nil {
+			return err
+		}
+	}
+}
+
+// IngestReader ingests JSON data read from r, such as a pipe on stdin. A
+// top-level array is one document, projected like a .json file; anything
+// else is a stream of records, projected like a .jsonl file without
+// buffering the input. Nodes have no source file, so they are not
+// refreshed or written back.
+func (e *Engine) IngestReader(ctx context.Context, r io.Reader) error {
+	if err := CheckSchemaCycles(e.Schema); err != nil {
+		return err
+	}
+	e.childSeen = make(map[string]map[string]bool)
+
+	br := bufio.NewReaderSize(r, 64<<10)
+	first, err := peekNonSpace(br)
+	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+	if first != '[' {
+		return e.ingestRecordStream(ctx, "", 0, func(emit func(id, raw string) error) error {
+			return StreamJSONValuesRaw(br, emit)
+		})
+	}
+
+	var data any
+	if err := json.NewDecoder(br).Decode(&data); err != nil {
+		return fmt.Errorf("failed to parse json: %w", err)
+	}
+	modTime := time.Now()
+	walker := NewJsonWalker()
+	for _, nodeSchema := range e.Schema.Nodes {
+		if err := e.processNode(nodeSchema, schemaPathOf("", nodeSchema), walker, data, "", "", "", modTime, e.Store, nil, nil, nil, nil); err != nil {
+			return fmt.Errorf("failed to process schema node %s: %w", nodeSchema.Name, err)
+		}
+	}
+	return nil
+}
+
+// peekNonSpace discards leading whitespace from br and returns the next
+// byte without consuming it, or 0 at end of input.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if errors.Is(err, io.EOF) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(b[0])) {
+			return b[0], nil
+		}
+		_, _ = br.ReadByte() // just peeked
+	}
+}
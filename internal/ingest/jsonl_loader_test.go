@@ -0,0 +1,162 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamJSONLinesRaw(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"a\":1}\n\n  {\"a\":2}  \r\n{\"a\":3}"), 0o644))
+
+	var ids, raws []string
+	require.NoError(t, StreamJSONLinesRaw(path, func(id, raw string) error {
+		ids = append(ids, id)
+		raws = append(raws, raw)
+		return nil
+	}))
+	assert.Equal(t, []string{"1", "3", "4"}, ids, "IDs are line numbers; blank lines are skipped")
+	assert.Equal(t, []string{`{"a":1}`, `{"a":2}`, `{"a":3}`}, raws, "an unterminated last line is read")
+}
+
+func TestEngine_IngestJSONLines(t *testing.T) {
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "items",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "{{.item.name}}",
+				Selector: "$[*]",
+				Files: []api.Leaf{
+					{Name: "role", ContentTemplate: "{{.item.role}}"},
+					{Name: "bio", ContentTemplate: "{{.item.bio}}"},
+				},
+			}},
+		}},
+	}
+	bio := strings.Repeat("x", inlineThreshold+1)
+	for _, ext := range []string{".jsonl", ".ndjson"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data"+ext)
+			data := `{"item":{"name":"alice","role":"admin","bio":"` + bio + `"}}` + "\n" +
+				`{"item":{"name":"bob","role":"user"}}` + "\n"
+			require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+			store := graph.NewMemoryStore()
+			require.NoError(t, NewEngine(schema, store).Ingest(path))
+
+			items, err := store.ListChildren("items")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"items/alice", "items/bob"}, items)
+			role, err := store.GetNode("items/bob/role")
+			require.NoError(t, err)
+			assert.Equal(t, "user", string(role.Data))
+			long, err := store.GetNode("items/alice/bio")
+			require.NoError(t, err)
+			assert.Nil(t, long.Ref, "large content is inlined without a database")
+			assert.Equal(t, bio, string(long.Data))
+		})
+	}
+}
+
+func TestEngine_IngestJSONLines_BadLine(t *testing.T) {
+	schema := &api.Topology{Nodes: []api.Node{{Name: "items", Selector: "$"}}}
+	path := filepath.Join(t.TempDir(), "data.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"ok\":true}\n{not json\n"), 0o644))
+
+	err := NewEngine(schema, graph.NewMemoryStore()).Ingest(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "data.jsonl")
+	assert.Contains(t, err.Error(), "parse record 2")
+}
+
+func TestEngine_SkipErrors(t *testing.T) {
+	old := SkipErrors
+	defer func() { SkipErrors = old }()
+	SkipErrors = true
+
+	schema := &api.Topology{Nodes: []api.Node{{
+		Name:     "items",
+		Selector: "$",
+		Children: []api.Node{{Name: "{{.item.id}}", Selector: "$[*]"}},
+	}}}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.jsonl"), []byte(`{"item":{"id":"a"}}`+"\n{not json\n"+`{"item":{"id":"b"}}`+"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`[{"id":`), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	require.NoError(t, engine.Ingest(dir))
+
+	records, files := engine.Skipped()
+	assert.Equal(t, 1, records)
+	assert.Equal(t, 1, files)
+	_, err := store.GetNode("items/a")
+	assert.NoError(t, err, "good records around the bad one are still ingested")
+	_, err = store.GetNode("items/b")
+	assert.NoError(t, err)
+}
+
+func TestStreamJSONValuesRaw(t *testing.T) {
+	var ids, raws []string
+	in := "{\"a\":1}\n\n{\n  \"a\": 2\n} {\"a\":3}"
+	require.NoError(t, StreamJSONValuesRaw(strings.NewReader(in), func(id, raw string) error {
+		ids = append(ids, id)
+		raws = append(raws, raw)
+		return nil
+	}))
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+	assert.Equal(t, []string{`{"a":1}`, "{\n  \"a\": 2\n}", `{"a":3}`}, raws, "values may span lines or share one")
+
+	err := StreamJSONValuesRaw(strings.NewReader(`{"a":1} {oops`), func(string, string) error { return nil })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read record 2")
+}
+
+func TestEngine_IngestReader(t *testing.T) {
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "items",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "{{.item.name}}",
+				Selector: "$[*]",
+				Files:    []api.Leaf{{Name: "role", ContentTemplate: "{{.item.role}}"}},
+			}},
+		}},
+	}
+	for name, in := range map[string]string{
+		"records":  "  {\"item\":{\"name\":\"alice\",\"role\":\"admin\"}}\n{\"item\":{\"name\":\"bob\",\"role\":\"user\"}}\n",
+		"document": `[{"item":{"name":"alice","role":"admin"}},{"item":{"name":"bob","role":"user"}}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := graph.NewMemoryStore()
+			require.NoError(t, NewEngine(schema, store).IngestReader(context.Background(), strings.NewReader(in)))
+
+			items, err := store.ListChildren("items")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"items/alice", "items/bob"}, items)
+			role, err := store.GetNode("items/bob/role")
+			require.NoError(t, err)
+			assert.Equal(t, "user", string(role.Data))
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		store := graph.NewMemoryStore()
+		require.NoError(t, NewEngine(schema, store).IngestReader(context.Background(), strings.NewReader("\n")))
+		items, err := store.ListChildren("items")
+		require.NoError(t, err)
+		assert.Empty(t, items)
+	})
+}
@@ -0,0 +1,111 @@
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/agentic-research/mache/internal/lang"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// LangOverride routes files matching Glob to the tree-sitter language Lang,
+// whatever their extension.
+type LangOverride struct {
+	Glob string // path.Match pattern; matched against the basename when it has no "/"
+	Lang string // canonical language name or alias
+}
+
+// LangOverrides maps files to languages ahead of the extension heuristic.
+// The first matching glob wins. Configurable via --lang.
+var LangOverrides []LangOverride
+
+// modelineRe matches a "mache:lang=<name>" modeline in any comment syntax.
+var modelineRe = regexp.MustCompile(`\bmache:lang=([A-Za-z0-9_+-]+)`)
+
+// modelineSniffSize caps how much of a file's first line is read for a
+// modeline.
+const modelineSniffSize = 256
+
+// ParseLangOverrides parses "glob=lang" specs (e.g. "*.txt=sql") and
+// rejects unknown languages.
+func ParseLangOverrides(specs []string) ([]LangOverride, error) {
+	var out []LangOverride
+	for _, spec := range specs {
+		glob, name, ok := strings.Cut(spec, "=")
+		glob, name = strings.TrimSpace(glob), strings.TrimSpace(name)
+		if !ok || glob == "" || name == "" {
+			return nil, fmt.Errorf("invalid language override %q (want glob=lang)", spec)
+		}
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid language override %q: %w", spec, err)
+		}
+		if lang.ForName(name) == nil {
+			return nil, fmt.Errorf("invalid language override %q: unknown language %q", spec, name)
+		}
+		out = append(out, LangOverride{Glob: glob, Lang: name})
+	}
+	return out, nil
+}
+
+// modelineLang returns the language named by a "mache:lang=" modeline on
+// the first line of the file at p, or "".
+func modelineLang(p string) string {
+	f, err := os.Open(p)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+	line, _ := bufio.NewReaderSize(f, modelineSniffSize).ReadSlice('\n')
+	if m := modelineRe.FindSubmatch(line); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// langOverride returns the language a modeline or a LangOverrides glob
+// assigns to p. A modeline wins over the globs. ok is false when neither
+// applies, leaving the choice to the extension.
+func (e *Engine) langOverride(p string) (grammar *sitter.Language, name string, ok bool) {
+	if name := modelineLang(p); name != "" {
+		if l := lang.ForName(name); l != nil {
+			return l.Grammar(), l.Name, true
+		}
+		log.Printf("[WARN] %s: unknown modeline language %q, using the extension", p, name)
+	}
+	if len(LangOverrides) == 0 {
+		return nil, "", false
+	}
+	rel := filepath.ToSlash(p)
+	if e.RootPath != "" {
+		if r, err := filepath.Rel(e.RootPath, p); err == nil {
+			rel = filepath.ToSlash(r)
+		}
+	}
+	for _, o := range LangOverrides {
+		target := rel
+		if !strings.Contains(o.Glob, "/") {
+			target = path.Base(rel)
+		}
+		if matched, _ := path.Match(o.Glob, target); matched {
+			if l := lang.ForName(o.Lang); l != nil {
+				return l.Grammar(), l.Name, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// langFor returns the tree-sitter language of the file at p: an override
+// if one applies, otherwise by extension.
+func (e *Engine) langFor(p string) (*sitter.Language, string) {
+	if grammar, name, ok := e.langOverride(p); ok {
+		return grammar, name
+	}
+	return langForPath(p)
+}
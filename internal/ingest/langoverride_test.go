@@ -0,0 +1,60 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLangOverrides(t *testing.T) {
+	got, err := ParseLangOverrides([]string{"*.txt=sql", " templates/*.tmpl = go "})
+	require.NoError(t, err)
+	assert.Equal(t, []LangOverride{{Glob: "*.txt", Lang: "sql"}, {Glob: "templates/*.tmpl", Lang: "go"}}, got)
+
+	for _, bad := range []string{"*.txt", "=sql", "*.txt=", "*.txt=cobol", "[=go"} {
+		_, err := ParseLangOverrides([]string{bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestEngine_LangOverride_Glob(t *testing.T) {
+	old := LangOverrides
+	defer func() { LangOverrides = old }()
+	LangOverrides = []LangOverride{{Glob: "*.go.tmpl", Lang: "go"}}
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "server.go.tmpl"), []byte("package demo\n\nfunc Templated() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "other.tmpl"), []byte("package demo\n\nfunc NotGo() {}\n"), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(tmpDir))
+
+	_, err := store.GetNode("demo/functions/Templated/source")
+	assert.NoError(t, err, "the glob routes the template to Go")
+	_, err = store.GetNode("demo/functions/NotGo")
+	assert.Error(t, err, "files outside the glob keep the extension heuristic")
+}
+
+func TestEngine_LangOverride_Modeline(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "snippet.txt"), []byte("// mache:lang=go\npackage demo\n\nfunc FromText() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "bogus.txt"), []byte("// mache:lang=cobol\npackage demo\n\nfunc Bogus() {}\n"), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(tmpDir))
+
+	_, err := store.GetNode("demo/functions/FromText/source")
+	assert.NoError(t, err, "the modeline routes the .txt file to Go")
+	_, err = store.GetNode("demo/functions/Bogus")
+	assert.Error(t, err, "an unknown modeline language falls back to the extension")
+
+	// A single-file ingest goes through ingestFile.
+	single := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), single).Ingest(filepath.Join(tmpDir, "snippet.txt")))
+	_, err = single.GetNode("demo/functions/FromText/source")
+	assert.NoError(t, err)
+}
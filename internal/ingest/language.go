@@ -16,3 +16,13 @@ func DetectLanguageFromExt(ext string) (langName string, grammar *sitter.Languag
 	}
 	return l.Name, l.Grammar(), true
 }
+
+// DetectLanguageFromPath is DetectLanguageFromExt for a file path, also
+// matching registered basenames such as Dockerfile.
+func DetectLanguageFromPath(path string) (langName string, grammar *sitter.Language, ok bool) {
+	l := lang.ForPath(path)
+	if l == nil {
+		return "", nil, false
+	}
+	return l.Name, l.Grammar(), true
+}
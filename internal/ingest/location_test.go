@@ -3,6 +3,7 @@ package ingest
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/agentic-research/mache/internal/graph"
@@ -145,3 +146,35 @@ func Hello() {
 	expected := "main.go:3:5"
 	assert.Equal(t, expected, string(locData))
 }
+
+func TestEngine_OriginProperty(t *testing.T) {
+	tmpDir := t.TempDir()
+	goFile := filepath.Join(tmpDir, "main.go")
+	require.NoError(t, os.WriteFile(goFile, []byte("package main\n\nvar é = 1; func Hello() {}\n"), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(tmpDir))
+
+	hello, err := store.GetNode("main/functions/Hello")
+	require.NoError(t, err)
+	assert.Equal(t, goFile+":3:12", string(hello.Properties[graph.OriginProperty]), "column counts é as one character")
+}
+
+func TestEngine_StableID(t *testing.T) {
+	tmpDir := t.TempDir()
+	goFile := filepath.Join(tmpDir, "main.go")
+	stableID := func(content string) string {
+		t.Helper()
+		require.NoError(t, os.WriteFile(goFile, []byte(content), 0o644))
+		store := graph.NewMemoryStore()
+		require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(tmpDir))
+		n, err := store.GetNode("main/functions/Hello")
+		require.NoError(t, err)
+		return string(n.Properties[graph.StableIDProperty])
+	}
+
+	id := stableID("package main\n\nfunc Hello() {}\n")
+	assert.Equal(t, strconv.FormatUint(graph.StableID("main/functions/Hello", "main.go"), 10), id)
+	assert.Equal(t, id, stableID("package main\n\nimport \"fmt\"\n\nfunc Before() { fmt.Println() }\n\nfunc Hello() {}\n"),
+		"code above the construct doesn't change its ID")
+}
@@ -0,0 +1,98 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestEntry describes one source file that contributed to the mount,
+// enough to check the mount against a known state of the source tree.
+type ManifestEntry struct {
+	Path     string    `json:"path"` // relative to the ingest root
+	ModTime  time.Time `json:"mtime"`
+	Size     int64     `json:"size"`
+	Language string    `json:"language,omitempty"` // grammar or data format; empty for raw files
+	SHA256   string    `json:"sha256"`
+}
+
+// relPath returns path relative to the ingest root, slash-separated, or
+// path itself when it lies outside the root.
+func (e *Engine) relPath(path string) string {
+	if rel, err := filepath.Rel(e.RootPath, path); err == nil && e.RootPath != "" && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// recordManifest stores the manifest entry for path, replacing any from an
+// earlier ingest of it. content is the file as read for ingestion; when it
+// is nil (streamed or unchanged files) the file is hashed from disk. Files
+// that can't be read are left out.
+func (e *Engine) recordManifest(path, language string, content []byte, modTime time.Time) {
+	entry := ManifestEntry{Path: e.relPath(path), ModTime: modTime, Language: language}
+	if content != nil {
+		sum := sha256.Sum256(content)
+		entry.Size, entry.SHA256 = int64(len(content)), hex.EncodeToString(sum[:])
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		defer func() { _ = f.Close() }()
+		info, err := f.Stat()
+		if err != nil {
+			return
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, f)
+		if err != nil {
+			return
+		}
+		entry.Size, entry.SHA256 = n, hex.EncodeToString(h.Sum(nil))
+		if modTime.IsZero() {
+			entry.ModTime = info.ModTime()
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.manifest == nil {
+		e.manifest = make(map[string]ManifestEntry)
+	}
+	e.manifest[entry.Path] = entry
+}
+
+// Manifest returns the source files ingested so far, sorted by path.
+func (e *Engine) Manifest() []ManifestEntry {
+	e.mu.Lock()
+	entries := make([]ManifestEntry, 0, len(e.manifest))
+	for _, m := range e.manifest {
+		entries = append(entries, m)
+	}
+	e.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// FormatManifest renders entries as the indented JSON served at
+// /_manifest.json: {"files": [...]}.
+func FormatManifest(entries []ManifestEntry) []byte {
+	if entries == nil {
+		entries = []ManifestEntry{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Files []ManifestEntry `json:"files"`
+	}{entries}, "", "  ")
+	if err != nil {
+		return nil
+	}
+	return append(data, '\n')
+}
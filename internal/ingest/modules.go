@@ -0,0 +1,49 @@
+package ingest
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/lang"
+)
+
+// In languages with interface files, an implementation file (OCaml's
+// shapes.ml) and the interface file of the same base name (shapes.mli)
+// define one module, Shapes. Top-level schema nodes of language
+// "<language>/interface" apply to interface files in place of the
+// language's own nodes, and every top-level node of either file sees the
+// module's name as {{._parent.module}}. Schema nodes naming a directory
+// after the module merge the two files there, e.g. Shapes/implementation
+// beside Shapes/signature.
+
+// interfaceSection is the suffix of the schema language selecting a
+// language's interface files ("ocaml/interface").
+const interfaceSection = "interface"
+
+// modulePass returns the schema pass over a file of a language with
+// interface files. ok is false for other languages.
+func modulePass(nodes []api.Node, langName, path string, root any) (pass schemaPass, ok bool) {
+	l := lang.ForName(langName)
+	if l == nil || len(l.InterfaceExtensions) == 0 {
+		return schemaPass{}, false
+	}
+	pass = schemaPass{root: root, parentValues: map[string]any{"module": moduleName(path)}}
+	if l.IsInterface(path) {
+		pass.nodes = filterNodesBySection(nodes, l.Name+"/"+interfaceSection)
+	} else {
+		pass.nodes = filterNodesByLanguage(nodes, langName)
+	}
+	return pass, true
+}
+
+// moduleName returns the module a file defines: its base name without the
+// extension, capitalized ("shapes.ml" → "Shapes").
+func moduleName(path string) string {
+	base := filepath.Base(path)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,63 @@
+package ingest
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/agentic-research/mache/internal/lang"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// arityName returns name with the parameter count of the construct match
+// was captured from, e.g. "add_2args", so overloads (Java, C++, TypeScript)
+// land at predictable sibling paths. ok is false when the language has no
+// overloading or the construct has no parameter list.
+func arityName(name string, match Match) (string, bool) {
+	root, ok := match.Context().(SitterRoot)
+	if !ok || root.Node == nil {
+		return "", false
+	}
+	if l := lang.ForName(root.LangName); l == nil || !l.Overloads {
+		return "", false
+	}
+	n, ok := constructArity(root.Node)
+	if !ok {
+		return "", false
+	}
+	return name + "_" + strconv.Itoa(n) + "args", true
+}
+
+// constructArity counts the parameters of a function-like node. The list is
+// its "parameters" field, found through C/C++ declarator nesting
+// (function_definition > pointer_declarator > function_declarator) or, for
+// Kotlin, a function_value_parameters child.
+func constructArity(n *sitter.Node) (int, bool) {
+	params := parameterList(n, 4)
+	if params == nil {
+		return 0, false
+	}
+	count := 0
+	for i := 0; i < int(params.NamedChildCount()); i++ {
+		typ := params.NamedChild(i).Type()
+		if typ == "comment" || strings.HasSuffix(typ, "modifiers") {
+			continue
+		}
+		count++
+	}
+	return count, true
+}
+
+// parameterList finds n's parameter list, following declarator fields at
+// most depth levels deep.
+func parameterList(n *sitter.Node, depth int) *sitter.Node {
+	if n == nil || depth == 0 {
+		return nil
+	}
+	if p := n.ChildByFieldName("parameters"); p != nil {
+		return p
+	}
+	if p := childOfType(n, "function_value_parameters"); p != nil {
+		return p
+	}
+	return parameterList(n.ChildByFieldName("declarator"), depth-1)
+}
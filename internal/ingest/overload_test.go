@@ -0,0 +1,108 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/lang"
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_IngestTreeSitter_OverloadsByArity(t *testing.T) {
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "methods",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "{{.name}}",
+				Selector: "(method_declaration name: (identifier) @name) @scope",
+				Files:    []api.Leaf{{Name: "source", ContentTemplate: "{{.scope}}"}},
+			}},
+		}},
+	}
+	path := filepath.Join(t.TempDir(), "Calc.java")
+	require.NoError(t, os.WriteFile(path, []byte(`class Calc {
+	int add(int a) { return a; }
+	int add(int a, int b) { return a + b; }
+	long add(long a) { return a; }
+	double add(double a) { return a; }
+}
+`), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(path))
+
+	for id, want := range map[string]string{
+		"methods/add":                "int add(int a)",
+		"methods/add_2args":          "int add(int a, int b)",
+		"methods/add_1args":          "long add(long a)",
+		"methods/add.from_Calc_java": "double add(double a)",
+	} {
+		src, err := store.GetNode(id + "/source")
+		require.NoError(t, err, id)
+		assert.Contains(t, string(src.Data), want, id)
+	}
+	assert.Contains(t, store.DefsMap()["add"], "methods/add_2args", "overloads are defined under the bare name too")
+}
+
+func TestEngine_IngestTreeSitter_NoArityWithoutOverloading(t *testing.T) {
+	schema := loadGoSchema(t)
+	tmpDir := t.TempDir()
+	for _, f := range []string{"a.go", "b.go"} {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, f), []byte("package p\n\nfunc init() {}\n"), 0o644))
+	}
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(tmpDir))
+
+	_, err := store.GetNode("p/functions/init.from_b_go")
+	assert.NoError(t, err, "Go has no overloading, so collisions keep the source-file suffix")
+	_, err = store.GetNode("p/functions/init_0args")
+	assert.Error(t, err)
+}
+
+func TestConstructArity(t *testing.T) {
+	for _, tc := range []struct {
+		lang, src, typ string
+		want           int
+	}{
+		{"java", "class A { void f(int a, String... rest) {} }", "method_declaration", 2},
+		{"cpp", "int *f(int a, char b) { return 0; }", "function_definition", 2},
+		{"typescript", "function f(a: number, b?: string) {}", "function_declaration", 2},
+		{"typescript", "class A { m() {} }", "method_definition", 0},
+	} {
+		t.Run(tc.lang+"/"+tc.typ, func(t *testing.T) {
+			parser := sitter.NewParser()
+			defer parser.Close()
+			parser.SetLanguage(lang.ForName(tc.lang).Grammar())
+			tree, err := parser.ParseCtx(context.Background(), nil, []byte(tc.src))
+			require.NoError(t, err)
+			defer tree.Close()
+
+			n := findNodeOfType(tree.RootNode(), tc.typ)
+			require.NotNil(t, n)
+			got, ok := constructArity(n)
+			require.True(t, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// findNodeOfType returns the first node of type typ in a pre-order walk.
+func findNodeOfType(n *sitter.Node, typ string) *sitter.Node {
+	if n.Type() == typ {
+		return n
+	}
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		if found := findNodeOfType(n.NamedChild(i), typ); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// ParseTimeout bounds tree-sitter's parse of each source file; a file that
+// takes longer is projected raw under _project_files/ and the ingest goes
+// on. Zero disables the bound. Configurable via --parse-timeout.
+var ParseTimeout = 30 * time.Second
+
+// parseTree runs the parse itself; tests replace it to simulate grammars
+// that hang or misbehave.
+var parseTree = func(ctx context.Context, parser *sitter.Parser, content []byte) (*sitter.Tree, error) {
+	return parser.ParseCtx(ctx, nil, content)
+}
+
+// parseSource parses content with parser within ParseTimeout. A panic, a
+// nil tree, or a parse past the deadline comes back as an error, so one
+// pathological file can't abort or stall the ingest; the caller projects
+// the file raw instead. A crash inside the grammar's C code can't be
+// recovered from and still ends the process.
+//
+// The deadline is tree-sitter's operation limit rather than a context
+// timeout: cancelling a context just after a parse completes can leave the
+// parser's cancellation flag set, halting its next parse.
+func parseSource(ctx context.Context, parser *sitter.Parser, content []byte) (tree *sitter.Tree, err error) {
+	parser.SetOperationLimit(int(ParseTimeout.Microseconds()))
+	defer func() {
+		if r := recover(); r != nil {
+			tree, err = nil, fmt.Errorf("tree-sitter panicked: %v", r)
+		}
+		if err != nil {
+			// A halted parse resumes where it stopped on the next call
+			// unless the parser is reset.
+			parser.Reset()
+		}
+	}()
+	tree, err = parseTree(ctx, parser, content)
+	switch {
+	case errors.Is(err, sitter.ErrOperationLimit):
+		return nil, fmt.Errorf("parse timed out after %v", ParseTimeout)
+	case err != nil:
+		return nil, err
+	case tree == nil:
+		return nil, errors.New("tree-sitter returned no tree")
+	}
+	return tree, nil
+}
@@ -0,0 +1,93 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// stubParseTree replaces parseTree for the test: content mentioning "Bad"
+// is handed to bad, anything else parses normally.
+func stubParseTree(t *testing.T, bad func(ctx context.Context, content []byte) (*sitter.Tree, error)) {
+	t.Helper()
+	orig := parseTree
+	t.Cleanup(func() { parseTree = orig })
+	parseTree = func(ctx context.Context, parser *sitter.Parser, content []byte) (*sitter.Tree, error) {
+		if bytes.Contains(content, []byte("Bad")) {
+			return bad(ctx, content)
+		}
+		return orig(ctx, parser, content)
+	}
+}
+
+func TestEngine_Ingest_RoutesFailedParses(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		bad     func(ctx context.Context, content []byte) (*sitter.Tree, error)
+	}{
+		{"timeout", 50 * time.Millisecond, func(context.Context, []byte) (*sitter.Tree, error) {
+			return nil, sitter.ErrOperationLimit
+		}},
+		{"nil tree", ParseTimeout, func(context.Context, []byte) (*sitter.Tree, error) {
+			return nil, nil
+		}},
+		{"panic", ParseTimeout, func(context.Context, []byte) (*sitter.Tree, error) {
+			panic("grammar bug")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubParseTree(t, tt.bad)
+			orig := ParseTimeout
+			t.Cleanup(func() { ParseTimeout = orig })
+			ParseTimeout = tt.timeout
+
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "good.go"), []byte("package main\n\nfunc Hello() {}\n"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.go"), []byte("package main\n\nfunc Bad() {}\n"), 0o644))
+
+			store := graph.NewMemoryStore()
+			require.NoError(t, NewEngine(loadGoSchema(t), store).Ingest(dir))
+
+			_, err := store.GetNode("main/functions/Hello/source")
+			require.NoError(t, err, "the well-behaved file is still projected")
+			_, err = store.GetNode("main/functions/Bad")
+			assert.ErrorIs(t, err, graph.ErrNotFound)
+			raw, err := store.GetNode("_project_files/bad.go")
+			require.NoError(t, err, "the failed file is projected raw")
+			assert.Contains(t, string(raw.Data), "func Bad()")
+		})
+	}
+}
+
+func TestParseSource_Timeout(t *testing.T) {
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(golang.GetLanguage())
+
+	orig := ParseTimeout
+	t.Cleanup(func() { ParseTimeout = orig })
+	ParseTimeout = time.Microsecond
+
+	// Large enough that tree-sitter checks its operation limit mid-parse.
+	content := bytes.Repeat([]byte("func f() { x := []int{1, 2, 3}; _ = x }\n"), 20000)
+	content = append([]byte("package main\n"), content...)
+	_, err := parseSource(context.Background(), parser, content)
+	require.ErrorContains(t, err, "parse timed out after 1µs")
+
+	ParseTimeout = 0
+	tree, err := parseSource(context.Background(), parser, []byte("package main\n"))
+	require.NoError(t, err, "the parser is usable again after a timeout")
+	assert.Equal(t, "source_file", tree.RootNode().Type())
+}
@@ -0,0 +1,120 @@
+package ingest
+
+import (
+	"fmt"
+	"regexp"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// schemaQuery is a compiled schema selector with its text predicates
+// (#eq?, #not-eq?, #match?, #not-match?) parsed once, so regexes compile at
+// query-compile time rather than per match, and a bad one is a query error
+// instead of a panic.
+type schemaQuery struct {
+	q          *sitter.Query
+	predicates [][]textPredicate // by pattern index
+}
+
+// textPredicate requires every node captured as capture to equal a literal
+// or another capture's text (#eq?), or to match a regex (#match?). negate
+// inverts it (#not-eq?, #not-match?).
+type textPredicate struct {
+	capture string
+	negate  bool
+	re      *regexp.Regexp // #match? / #not-match?
+	other   string         // #eq? against a capture; "" compares to literal
+	literal string
+}
+
+// compileSchemaQuery compiles selector for lang and parses its predicates.
+// Predicates other than the four text ones (e.g. #set!) are ignored.
+func compileSchemaQuery(lang *sitter.Language, selector string) (*schemaQuery, error) {
+	q, err := sitter.NewQuery([]byte(selector), lang)
+	if err != nil {
+		return nil, err
+	}
+	sq := &schemaQuery{q: q, predicates: make([][]textPredicate, q.PatternCount())}
+	for i := range sq.predicates {
+		for _, steps := range q.PredicatesForPattern(uint32(i)) {
+			p, ok, err := parseTextPredicate(q, steps)
+			if err != nil {
+				q.Close()
+				return nil, err
+			}
+			if ok {
+				sq.predicates[i] = append(sq.predicates[i], p)
+			}
+		}
+	}
+	return sq, nil
+}
+
+// parseTextPredicate parses one predicate's steps; ok is false for operators
+// other than the text ones. sitter.NewQuery has already checked the arity
+// and argument kinds.
+func parseTextPredicate(q *sitter.Query, steps []sitter.QueryPredicateStep) (p textPredicate, ok bool, err error) {
+	op := q.StringValueForId(steps[0].ValueId)
+	switch op {
+	case "eq?", "not-eq?":
+		if steps[2].Type == sitter.QueryPredicateStepTypeCapture {
+			p.other = q.CaptureNameForId(steps[2].ValueId)
+		} else {
+			p.literal = q.StringValueForId(steps[2].ValueId)
+		}
+	case "match?", "not-match?":
+		if p.re, err = regexp.Compile(q.StringValueForId(steps[2].ValueId)); err != nil {
+			return p, false, fmt.Errorf("#%s: %w", op, err)
+		}
+	default:
+		return p, false, nil
+	}
+	p.capture = q.CaptureNameForId(steps[1].ValueId)
+	p.negate = op == "not-eq?" || op == "not-match?"
+	return p, true, nil
+}
+
+// accept reports whether m satisfies every predicate of its pattern.
+func (sq *schemaQuery) accept(m *sitter.QueryMatch, source []byte) bool {
+	if int(m.PatternIndex) >= len(sq.predicates) {
+		return true
+	}
+	for _, p := range sq.predicates[m.PatternIndex] {
+		if !p.accept(sq.q, m, source) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p textPredicate) accept(q *sitter.Query, m *sitter.QueryMatch, source []byte) bool {
+	want := p.literal
+	if p.other != "" {
+		found := false
+		for _, c := range m.Captures {
+			if q.CaptureNameForId(c.Index) == p.other {
+				want, found = c.Node.Content(source), true
+				break
+			}
+		}
+		if !found {
+			return true // the other capture is optional and absent
+		}
+	}
+	for _, c := range m.Captures {
+		if q.CaptureNameForId(c.Index) != p.capture {
+			continue
+		}
+		text := c.Node.Content(source)
+		var ok bool
+		if p.re != nil {
+			ok = p.re.MatchString(text)
+		} else {
+			ok = text == want
+		}
+		if ok == p.negate {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package ingest
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// progressLogEvery is how often (in records) progress is logged when stderr
+// is not a terminal, so CI logs and redirected output stay readable.
+const progressLogEvery = 50000
+
+// progressRedraw throttles the interactive progress line.
+const progressRedraw = 200 * time.Millisecond
+
+// ingestProgress reports record throughput and ETA during streaming ingest.
+// On a terminal it redraws one status line on stderr; otherwise it falls
+// back to periodic log lines. Nothing is written when logging is discarded
+// (e.g. --quiet).
+type ingestProgress struct {
+	total       int // 0 when unknown
+	start       time.Time
+	lastDraw    time.Time
+	interactive bool
+	out         io.Writer
+	now         func() time.Time
+}
+
+func newIngestProgress(total int) *ingestProgress {
+	return &ingestProgress{
+		total:       total,
+		start:       time.Now(),
+		interactive: log.Writer() == os.Stderr && isTerminal(os.Stderr),
+		out:         os.Stderr,
+		now:         time.Now,
+	}
+}
+
+// Update records that n records have been applied.
+func (p *ingestProgress) Update(n int) {
+	if !p.interactive {
+		if n%progressLogEvery == 0 {
+			log.Printf("Processed %s...", p.status(n, p.now()))
+		}
+		return
+	}
+	now := p.now()
+	if now.Sub(p.lastDraw) < progressRedraw {
+		return
+	}
+	p.lastDraw = now
+	_, _ = fmt.Fprintf(p.out, "\r\033[KProcessed %s", p.status(n, now))
+}
+
+// Done finishes the progress line and logs the final count.
+func (p *ingestProgress) Done(n int) {
+	if p.interactive {
+		_, _ = fmt.Fprint(p.out, "\r\033[K")
+	}
+	log.Printf("Processed %d records total in %v.", n, p.now().Sub(p.start).Round(time.Millisecond))
+}
+
+// status renders e.g. "5000/20000 records (25%), 2500 rec/s, ETA 6s".
+func (p *ingestProgress) status(n int, now time.Time) string {
+	elapsed := now.Sub(p.start)
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(n) / elapsed.Seconds()
+	}
+	if p.total <= 0 {
+		return fmt.Sprintf("%d records, %.0f rec/s", n, rate)
+	}
+	s := fmt.Sprintf("%d/%d records (%d%%), %.0f rec/s", n, p.total, n*100/p.total, rate)
+	if rate > 0 && n < p.total {
+		eta := time.Duration(float64(p.total-n) / rate * float64(time.Second))
+		s += fmt.Sprintf(", ETA %v", eta.Round(time.Second))
+	}
+	return s
+}
+
+// isTerminal reports whether f is a character device (a TTY).
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,49 @@
+package ingest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestProgress_Status(t *testing.T) {
+	start := time.Unix(0, 0)
+	p := &ingestProgress{total: 20000, start: start}
+
+	assert.Equal(t, "5000/20000 records (25%), 2500 rec/s, ETA 6s",
+		p.status(5000, start.Add(2*time.Second)))
+	assert.Equal(t, "20000/20000 records (100%), 10000 rec/s",
+		p.status(20000, start.Add(2*time.Second)), "no ETA once complete")
+
+	p.total = 0
+	assert.Equal(t, "5000 records, 2500 rec/s",
+		p.status(5000, start.Add(2*time.Second)), "unknown total omits percentage and ETA")
+}
+
+func TestIngestProgress_InteractiveThrottle(t *testing.T) {
+	start := time.Unix(0, 0)
+	now := start
+	var out bytes.Buffer
+	p := &ingestProgress{
+		total:       100,
+		start:       start,
+		interactive: true,
+		out:         &out,
+		now:         func() time.Time { return now },
+	}
+
+	now = start.Add(time.Second)
+	p.Update(10)
+	assert.Contains(t, out.String(), "\r\033[KProcessed 10/100 records (10%)")
+
+	out.Reset()
+	now = now.Add(progressRedraw / 2)
+	p.Update(11)
+	assert.Empty(t, out.String(), "redraws within the throttle window are skipped")
+
+	now = now.Add(progressRedraw)
+	p.Update(12)
+	assert.Contains(t, out.String(), "12/100")
+}
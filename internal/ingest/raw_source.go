@@ -0,0 +1,89 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RawSourceRoot is the root directory that mirrors the ingested tree's
+// literal file layout when WithRawSource is on.
+const RawSourceRoot = "_source"
+
+// WithRawSource adds a read-only RawSourceRoot beside the projection,
+// holding every non-binary file of the ingested tree at its relative path,
+// for when the projection doesn't capture what an agent needs. Off by
+// default. Configurable via --with-raw.
+var WithRawSource bool
+
+// mirrorSourceTree copies every file under root into RawSourceRoot. A
+// no-op unless WithRawSource is on.
+func (e *Engine) mirrorSourceTree(ctx context.Context, root string) error {
+	if !WithRawSource {
+		return nil
+	}
+	return e.walkSourceFiles(ctx, root, func(p string, info os.FileInfo) error {
+		if isBinaryFile(p) {
+			return nil
+		}
+		return e.mirrorRawFile(p, info.ModTime())
+	})
+}
+
+// mirrorRawFile copies one file into RawSourceRoot, replacing an earlier
+// copy. Mirror nodes carry no origin, so they are never written back and
+// re-ingesting the file's projection leaves them alone. A no-op unless
+// WithRawSource is on.
+func (e *Engine) mirrorRawFile(path string, modTime time.Time) error {
+	if !WithRawSource {
+		return nil
+	}
+	return e.addRawFile(path, RawSourceRoot, modTime, true)
+}
+
+// walkSourceFiles calls fn for each file under root that ingestion
+// considers: skipped directories, .gitignore'd paths, symlinks to
+// directories, and oversized files are left out.
+func (e *Engine) walkSourceFiles(ctx context.Context, root string, fn func(p string, info os.FileInfo) error) error {
+	ignored := func(p string, isDir bool) bool {
+		if e.gitignore == nil || p == root {
+			return false
+		}
+		rel, err := filepath.Rel(root, p)
+		return err == nil && e.gitignore.Match(filepath.ToSlash(rel), isDir)
+	}
+	return filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && (ShouldSkipDir(d.Name()) || ignored(p, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ignored(p, false) {
+			return nil
+		}
+		// Skip symlinks to directories (e.g., kodata/templates -> ../templates)
+		// WalkDir doesn't follow symlinks, so d.IsDir() is false for them,
+		// but os.ReadFile will follow and fail with "is a directory".
+		if d.Type()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(p); err == nil && target.IsDir() {
+				return nil
+			}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if ShouldSkipFile(p, info.Size()) {
+			return nil
+		}
+		return fn(p, info)
+	})
+}
@@ -0,0 +1,60 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_WithRawSource(t *testing.T) {
+	old := WithRawSource
+	defer func() { WithRawSource = old }()
+	WithRawSource = true
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "pkg"), 0o755))
+	mainGo := filepath.Join(tmpDir, "pkg", "main.go")
+	require.NoError(t, os.WriteFile(mainGo, []byte("package main\n\nfunc Hello() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("# demo\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "logo.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("build/\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "build"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "build", "out.txt"), []byte("x"), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(loadGoSchema(t), store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	// The projection is unchanged...
+	_, err := store.GetNode("main/functions/Hello/source")
+	require.NoError(t, err)
+
+	// ...and _source mirrors the files at their real paths.
+	src, err := store.GetNode("_source/pkg/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc Hello() {}\n", string(src.Data))
+	assert.Nil(t, src.Origin, "mirror copies are never written back")
+	readme, err := store.GetNode("_source/README.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# demo\n", string(readme.Data))
+	_, err = store.GetNode("_source/logo.png")
+	assert.ErrorIs(t, err, graph.ErrNotFound, "binary files are skipped")
+	_, err = store.GetNode("_source/build")
+	assert.ErrorIs(t, err, graph.ErrNotFound, "ignored paths are skipped")
+
+	// Re-ingesting a changed file refreshes both views.
+	require.NoError(t, os.WriteFile(mainGo, []byte("package main\n\nfunc Bye() {}\n"), 0o644))
+	require.NoError(t, engine.ReIngestFile(mainGo))
+	_, err = store.GetNode("main/functions/Bye/source")
+	require.NoError(t, err)
+	src, err = store.GetNode("_source/pkg/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc Bye() {}\n", string(src.Data))
+	children, err := store.ListChildren("_source/pkg")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"_source/pkg/main.go"}, children)
+}
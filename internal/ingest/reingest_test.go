@@ -82,3 +82,35 @@ func TestEngine_ReIngestFile_PreservesRootPath(t *testing.T) {
 	_, err = store.GetNode("pkg/functions/Util/source")
 	assert.ErrorIs(t, err, graph.ErrNotFound, "Util should be gone")
 }
+
+func TestEngine_ReIngestFile_PurgesStaleRefs(t *testing.T) {
+	schema := loadGoSchema(t)
+	tmpDir := t.TempDir()
+	goFile := filepath.Join(tmpDir, "main.go")
+	require.NoError(t, os.WriteFile(goFile, []byte("package main\n\nfunc Run() {\n\tStart()\n\tStop()\n}\n\nfunc Start() {}\n\nfunc Stop() {}\n"), 0o644))
+
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	require.NoError(t, engine.Ingest(tmpDir))
+
+	callerIDs := func(token string) []string {
+		t.Helper()
+		nodes, err := store.GetCallers(token)
+		require.NoError(t, err)
+		var ids []string
+		for _, n := range nodes {
+			ids = append(ids, n.ID)
+		}
+		return ids
+	}
+	require.Equal(t, []string{"main/functions/Run/source"}, callerIDs("Stop"))
+
+	// Edit Run to drop the Stop call, then re-ingest as write-back does.
+	require.NoError(t, os.WriteFile(goFile, []byte("package main\n\nfunc Run() {\n\tStart()\n}\n\nfunc Start() {}\n\nfunc Stop() {}\n"), 0o644))
+	require.NoError(t, engine.ReIngestFile(goFile))
+
+	assert.Empty(t, callerIDs("Stop"), "Run no longer calls Stop")
+	assert.Equal(t, []string{"main/functions/Run/source"}, callerIDs("Start"), "the remaining call is indexed once")
+	assert.Len(t, store.RefsMap()["Start"], 1)
+	assert.Equal(t, []string{"main/functions/Run"}, store.DefsMap()["Run"], "re-ingest keeps the construct's ID and registers it once")
+}
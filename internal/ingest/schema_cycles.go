@@ -0,0 +1,143 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agentic-research/mache/api"
+)
+
+// CheckSchemaCycles reports tree-sitter schema nodes whose selector can match
+// the very node their parent matched. A child query runs against the parent's
+// @scope node and tree-sitter queries include that root, so a child selecting
+// the same node type re-projects its parent inside itself; with recursive
+// schemas this never terminates. Such children must set skip_self_match (or
+// recursive, which implies it). Passthrough ("$") and JSONPath selectors are
+// transparent: they keep the enclosing query context. Selectors with
+// predicates (#eq?, #match?, ...) are not flagged, since whether they match
+// the parent depends on its text (e.g. Elixir's def vs defmodule calls).
+func CheckSchemaCycles(schema *api.Topology) error {
+	return checkCycles(schema.Nodes, "", nil, "")
+}
+
+// checkCycles walks nodes with the scope types of the nearest tree-sitter
+// ancestor (ctxTypes), whose path is ctxPath.
+func checkCycles(nodes []api.Node, parentPath string, ctxTypes []string, ctxPath string) error {
+	for _, n := range nodes {
+		path := n.Name
+		if parentPath != "" {
+			path = parentPath + "/" + n.Name
+		}
+		types := scopeNodeTypes(n.Selector)
+		if !n.SkipSelfMatch && !n.Recursive && !strings.Contains(n.Selector, "(#") {
+			for _, t := range types {
+				for _, ct := range ctxTypes {
+					if t == ct {
+						return fmt.Errorf("schema cycle: %q selects (%s) @scope, the same node as its ancestor %q; set \"skip_self_match\": true on %q",
+							path, t, ctxPath, path)
+					}
+				}
+			}
+		}
+		childTypes, childPath := ctxTypes, ctxPath
+		if len(types) > 0 {
+			childTypes, childPath = types, path
+		}
+		if err := checkCycles(n.Children, path, childTypes, childPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scopeNodeTypes returns the node type(s) captured as @scope by a tree-sitter
+// selector: one type for "(type ...) @scope", one per branch for an
+// alternation "[(a ...) (b ...)] @scope", accumulated over all top-level
+// patterns. Returns nil for non-S-expression selectors, wildcards, and
+// selectors without a @scope capture.
+func scopeNodeTypes(selector string) []string {
+	sel := strings.TrimSpace(selector)
+	if sel == "" || (sel[0] != '(' && sel[0] != '[') {
+		return nil
+	}
+
+	var types []string
+	var stack []int
+	lastOpen, lastClose := -1, -1
+	for i := 0; i < len(sel); i++ {
+		switch sel[i] {
+		case '"':
+			// Skip string literals so parens inside predicates don't count.
+			for i++; i < len(sel) && sel[i] != '"'; i++ {
+				if sel[i] == '\\' {
+					i++
+				}
+			}
+		case ';':
+			// Comment to end of line.
+			for i < len(sel) && sel[i] != '\n' {
+				i++
+			}
+		case '(', '[':
+			stack = append(stack, i)
+		case ')', ']':
+			if len(stack) == 0 {
+				return nil
+			}
+			lastOpen, lastClose = stack[len(stack)-1], i
+			stack = stack[:len(stack)-1]
+		case '@':
+			name := captureName(sel[i+1:])
+			if name == "scope" && lastClose >= 0 && strings.TrimSpace(sel[lastClose+1:i]) == "" {
+				types = append(types, groupNodeTypes(sel[lastOpen:lastClose+1])...)
+			}
+			i += len(name)
+		}
+	}
+	return types
+}
+
+// captureName returns the identifier at the start of s.
+func captureName(s string) string {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '_' || r == '-' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if end < 0 {
+		return s
+	}
+	return s[:end]
+}
+
+// groupNodeTypes extracts node types from "(type ...)" or the top-level
+// branches of "[(a ...) (b ...)]".
+func groupNodeTypes(group string) []string {
+	if group[0] == '(' {
+		if t := captureName(strings.TrimSpace(group[1:])); t != "" && t != "_" {
+			return []string{t}
+		}
+		return nil
+	}
+
+	var types []string
+	depth := 0
+	for i := 1; i < len(group)-1; i++ {
+		switch group[i] {
+		case '"':
+			for i++; i < len(group) && group[i] != '"'; i++ {
+				if group[i] == '\\' {
+					i++
+				}
+			}
+		case '(', '[':
+			if depth == 0 && group[i] == '(' {
+				if t := captureName(strings.TrimSpace(group[i+1:])); t != "" && t != "_" {
+					types = append(types, t)
+				}
+			}
+			depth++
+		case ')', ']':
+			depth--
+		}
+	}
+	return types
+}
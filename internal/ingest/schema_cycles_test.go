@@ -0,0 +1,98 @@
+package ingest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeNodeTypes(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{`(function_declaration name: (identifier) @name) @scope`, []string{"function_declaration"}},
+		{`(call method: (identifier) @_m arguments: (argument_list (string) @name) (#eq? @_m "a)b")) @scope`, []string{"call"}},
+		{`[(element (start_tag) @open) (script_element) (style_element)] @scope`, []string{"element", "script_element", "style_element"}},
+		{"(a) @scope\n; comment (b) @scope\n(c) @scope", []string{"a", "c"}},
+		{`(function_declaration name: (identifier) @scope)`, []string{"identifier"}},
+		{`(_) @scope`, nil},
+		{`(function_declaration) @name`, nil},
+		{`$`, nil},
+		{`$.items[*]`, nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, scopeNodeTypes(tt.selector), tt.selector)
+	}
+}
+
+func TestCheckSchemaCycles(t *testing.T) {
+	fn := `(function_declaration name: (identifier) @name) @scope`
+	schema := &api.Topology{Nodes: []api.Node{{
+		Name:     "functions",
+		Selector: "$",
+		Children: []api.Node{{
+			Name:     "{{.name}}",
+			Selector: fn,
+			Children: []api.Node{{
+				Name:     "again",
+				Selector: "$",
+				Children: []api.Node{{Name: "{{.name}}", Selector: fn}},
+			}},
+		}},
+	}}}
+
+	err := CheckSchemaCycles(schema)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"functions/{{.name}}/again/{{.name}}" selects (function_declaration) @scope`)
+	assert.Contains(t, err.Error(), `ancestor "functions/{{.name}}"`)
+
+	schema.Nodes[0].Children[0].Children[0].Children[0].SkipSelfMatch = true
+	assert.NoError(t, CheckSchemaCycles(schema))
+
+	// Predicates make the match text-dependent; not flagged.
+	schema.Nodes[0].Children[0].Children[0].Children[0].Selector = `(function_declaration name: (identifier) @name (#eq? @name "init")) @scope`
+	assert.NoError(t, CheckSchemaCycles(schema))
+	schema.Nodes[0].Children[0].Children[0].Children[0].Selector = fn
+
+	// A different type in between breaks the self-match.
+	schema.Nodes[0].Children[0].Children[0].Children[0].SkipSelfMatch = false
+	schema.Nodes[0].Children[0].Children[0].Selector = `(block) @scope`
+	assert.NoError(t, CheckSchemaCycles(schema))
+}
+
+func TestCheckSchemaCycles_ShippedSchemas(t *testing.T) {
+	paths, err := filepath.Glob("../../cmd/schemas/*.json")
+	require.NoError(t, err)
+	examples, err := filepath.Glob("../../examples/*-schema.json")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, p := range append(paths, examples...) {
+		data, err := os.ReadFile(p)
+		require.NoError(t, err)
+		var topo api.Topology
+		require.NoError(t, json.Unmarshal(data, &topo), p)
+		assert.NoError(t, CheckSchemaCycles(&topo), p)
+	}
+}
+
+func TestEngine_Ingest_RejectsSchemaCycle(t *testing.T) {
+	schema := &api.Topology{Nodes: []api.Node{{
+		Name:     "{{.name}}",
+		Selector: `(function_declaration name: (identifier) @name) @scope`,
+		Children: []api.Node{{Name: "{{.name}}", Selector: `(function_declaration name: (identifier) @name) @scope`}},
+	}}}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc A() {}\n"), 0o644))
+
+	err := NewEngine(schema, graph.NewMemoryStore()).Ingest(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "schema cycle")
+}
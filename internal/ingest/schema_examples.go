@@ -0,0 +1,302 @@
+package ingest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/ohler55/ojg/jp"
+)
+
+// exampleDir is one directory of the layout described by example paths.
+// Paths sharing a prefix share its directories.
+type exampleDir struct {
+	seg   string // static name or {field}
+	dirs  []*exampleDir
+	files []exampleFile
+}
+
+type exampleFile struct {
+	name  string
+	field string // dotted field path, or "." for the whole record
+}
+
+func (d *exampleDir) child(seg string) *exampleDir {
+	for _, c := range d.dirs {
+		if c.seg == seg {
+			return c
+		}
+	}
+	c := &exampleDir{seg: seg}
+	d.dirs = append(d.dirs, c)
+	return c
+}
+
+// placeholder returns the field named by a {field} segment.
+func placeholder(seg string) (string, bool) {
+	if len(seg) > 2 && strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+		return seg[1 : len(seg)-1], true
+	}
+	return "", false
+}
+
+// parseExamplePath adds spec, "dir/{field}/.../file=field", to root.
+func parseExamplePath(root *exampleDir, spec string) error {
+	dirPart, last, ok := strings.Cut(spec, "=")
+	if !ok {
+		return fmt.Errorf("path %q: want <dirs>/<file>=<field>", spec)
+	}
+	segs := strings.Split(dirPart, "/")
+	fileName := segs[len(segs)-1]
+	segs = segs[:len(segs)-1]
+	field := strings.TrimSpace(last)
+	if fileName == "" || field == "" {
+		return fmt.Errorf("path %q: want <dirs>/<file>=<field>", spec)
+	}
+	if _, ok := placeholder(fileName); ok {
+		return fmt.Errorf("path %q: file name %s must be static", spec, fileName)
+	}
+	d := root
+	for _, seg := range segs {
+		if seg == "" {
+			return fmt.Errorf("path %q: empty directory name", spec)
+		}
+		d = d.child(seg)
+	}
+	for _, f := range d.files {
+		if f.name == fileName && f.field != field {
+			return fmt.Errorf("path %q: file %s already reads %s", spec, fileName, f.field)
+		}
+	}
+	d.files = append(d.files, exampleFile{name: fileName, field: field})
+	return nil
+}
+
+// SchemaFromExamples builds a topology from example paths over a sample
+// JSON document (mache schema-from-examples). Each spec is a slash-separated
+// path ending in file=field, e.g. "vulns/{cveID}/description=shortDescription":
+// static segments become plain directories, each {field} a directory per
+// record named by that field, and the file renders the record's field ("."
+// for the whole record as JSON). Fields are located in doc with the JSON
+// walker: a placeholder selects the array whose elements hold the field
+// most often (the enclosing record itself when it holds it), and files read
+// fields of the innermost record.
+func SchemaFromExamples(doc any, specs []string) (*api.Topology, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no example paths")
+	}
+	root := &exampleDir{}
+	for _, spec := range specs {
+		if err := parseExamplePath(root, spec); err != nil {
+			return nil, err
+		}
+	}
+	if len(root.files) > 0 {
+		return nil, fmt.Errorf("file %s needs a directory above it", root.files[0].name)
+	}
+	nodes, err := exampleNodes(root.dirs, []any{doc})
+	if err != nil {
+		return nil, err
+	}
+	return &api.Topology{Version: api.SchemaVersion, Nodes: nodes}, nil
+}
+
+// exampleNodes builds the nodes for dirs, whose parent directory holds one
+// of recs each.
+func exampleNodes(dirs []*exampleDir, recs []any) ([]api.Node, error) {
+	nodes := make([]api.Node, 0, len(dirs))
+	for _, d := range dirs {
+		node := api.Node{Name: d.seg, Selector: "$"}
+		childRecs := recs
+		if field, ok := placeholder(d.seg); ok {
+			loc, err := locateField(recs, field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", d.seg, err)
+			}
+			node.Name = "{{" + fieldRef(loc.field) + "}}"
+			if loc.array != nil {
+				sel := append(append(jp.R(), loc.array...), jp.Wildcard('*'))
+				node.Selector = selectorString(sel)
+				childRecs = records(sel, recs)
+			}
+		}
+		for _, f := range d.files {
+			leaf, err := exampleLeaf(f, childRecs)
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", d.seg, f.name, err)
+			}
+			node.Files = append(node.Files, leaf)
+		}
+		children, err := exampleNodes(d.dirs, childRecs)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = children
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// fieldLoc is where a field was found relative to a record: in the
+// elements of the array at array, or in the record itself when array is
+// nil. field is the path to it from there.
+type fieldLoc struct {
+	array jp.Expr
+	field jp.Expr
+}
+
+// depth counts the array steps from the record to the field's object, so
+// the record itself ranks above an array at the document root ($[*]).
+func (l fieldLoc) depth() int {
+	if l.array == nil {
+		return 0
+	}
+	return len(l.array) + 1
+}
+
+func (l fieldLoc) key() string {
+	return fmt.Sprintf("%d %s %s", l.depth(), l.array, l.field)
+}
+
+// locateField finds where field lives across recs, locating every
+// occurrence as the JSON walker does. Of the places it is found, the one
+// holding it most often wins, then the shallowest.
+func locateField(recs []any, field string) (fieldLoc, error) {
+	x := jp.R().D()
+	for _, k := range strings.Split(field, ".") {
+		x = x.C(k)
+	}
+	counts := map[string]int{}
+	locs := map[string]fieldLoc{}
+	for _, rec := range recs {
+		for _, m := range locateMatches(x, rec) {
+			loc, ok := splitAtRecord(m.(*jsonMatch).path)
+			if !ok {
+				continue
+			}
+			key := loc.key()
+			counts[key]++
+			locs[key] = loc
+		}
+	}
+	best, bestKey := fieldLoc{}, ""
+	for key, loc := range locs {
+		switch {
+		case bestKey == "",
+			counts[key] > counts[bestKey],
+			counts[key] == counts[bestKey] && loc.depth() < best.depth(),
+			counts[key] == counts[bestKey] && loc.depth() == best.depth() && key < bestKey:
+			best, bestKey = loc, key
+		}
+	}
+	if bestKey == "" {
+		return fieldLoc{}, fmt.Errorf("field %q not found in the data", field)
+	}
+	return best, nil
+}
+
+// splitAtRecord splits a located path at its last array index: the array
+// before it, with any outer indexes widened to [*], and the field path
+// within the element. ok is false when the match is itself an array element.
+func splitAtRecord(path jp.Expr) (fieldLoc, bool) {
+	if len(path) > 0 {
+		if _, isRoot := path[0].(jp.Root); isRoot {
+			path = path[1:]
+		}
+	}
+	last := -1
+	for i, frag := range path {
+		if _, ok := frag.(jp.Nth); ok {
+			last = i
+		}
+	}
+	if last == len(path)-1 {
+		return fieldLoc{}, false
+	}
+	if last < 0 {
+		return fieldLoc{field: path}, true
+	}
+	array := make(jp.Expr, last)
+	for i, frag := range path[:last] {
+		if _, ok := frag.(jp.Nth); ok {
+			frag = jp.Wildcard('*')
+		}
+		array[i] = frag
+	}
+	return fieldLoc{array: array, field: path[last+1:]}, true
+}
+
+// selectorString renders sel the way schemas write selectors, with [*]
+// for each wildcard.
+func selectorString(sel jp.Expr) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, frag := range sel[1:] {
+		if k, ok := frag.(jp.Child); ok {
+			b.WriteString(strings.TrimPrefix(jp.R().C(string(k)).String(), "$"))
+		} else {
+			b.WriteString("[*]")
+		}
+	}
+	return b.String()
+}
+
+// records returns the objects sel selects across recs.
+func records(sel jp.Expr, recs []any) []any {
+	var out []any
+	for _, rec := range recs {
+		for _, v := range sel.Get(rec) {
+			if _, ok := v.(map[string]any); ok {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// exampleLeaf builds the file f of a directory holding one of recs.
+func exampleLeaf(f exampleFile, recs []any) (api.Leaf, error) {
+	if f.field == "." {
+		return api.Leaf{Name: f.name, ContentTemplate: "{{. | json}}"}, nil
+	}
+	x := jp.R()
+	for _, k := range strings.Split(f.field, ".") {
+		x = x.C(k)
+	}
+	for _, rec := range recs {
+		vals := x.Get(rec)
+		if len(vals) == 0 {
+			continue
+		}
+		ref := fieldRef(x[1:])
+		switch vals[0].(type) {
+		case map[string]any, []any:
+			return api.Leaf{Name: f.name, ContentTemplate: "{{" + ref + " | json}}"}, nil
+		}
+		return api.Leaf{Name: f.name, ContentTemplate: "{{" + ref + "}}"}, nil
+	}
+	return api.Leaf{}, fmt.Errorf("field %q not found in the records of this directory", f.field)
+}
+
+var templateIdentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// fieldRef renders a path of object keys as a template reference:
+// .a.b, or index . "a-b" "c" when a key isn't an identifier.
+func fieldRef(path jp.Expr) string {
+	keys := make([]string, len(path))
+	plain := true
+	for i, frag := range path {
+		keys[i] = string(frag.(jp.Child))
+		plain = plain && templateIdentRe.MatchString(keys[i])
+	}
+	if plain {
+		return "." + strings.Join(keys, ".")
+	}
+	ref := "index ."
+	for _, k := range keys {
+		ref += " " + strconv.Quote(k)
+	}
+	return ref
+}
@@ -0,0 +1,160 @@
+package ingest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const kevSample = `{
+  "title": "Known Exploited Vulnerabilities",
+  "vulnerabilities": [
+    {"cveID": "CVE-2021-44228", "vendorProject": "Apache", "shortDescription": "Log4j RCE",
+     "notes": {"refs": ["a", "b"]}},
+    {"cveID": "CVE-2023-4966", "vendorProject": "Citrix", "shortDescription": "Citrix Bleed",
+     "notes": {"refs": []}}
+  ]
+}`
+
+func decodeSample(t *testing.T, s string) any {
+	t.Helper()
+	var doc any
+	require.NoError(t, json.Unmarshal([]byte(s), &doc))
+	return doc
+}
+
+func TestSchemaFromExamples(t *testing.T) {
+	doc := decodeSample(t, kevSample)
+	topo, err := SchemaFromExamples(doc, []string{
+		"vulns/{cveID}/description=shortDescription",
+		"vulns/{cveID}/refs=notes.refs",
+		"vulns/{cveID}/raw.json=.",
+		"by-vendor/{vendorProject}/{cveID}/description=shortDescription",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, api.SchemaVersion, topo.Version)
+	require.Len(t, topo.Nodes, 2)
+	vulns := topo.Nodes[0]
+	assert.Equal(t, "vulns", vulns.Name)
+	assert.Equal(t, "$", vulns.Selector)
+	require.Len(t, vulns.Children, 1, "paths sharing a prefix share its directories")
+	cve := vulns.Children[0]
+	assert.Equal(t, "{{.cveID}}", cve.Name)
+	assert.Equal(t, "$.vulnerabilities[*]", cve.Selector)
+	assert.Equal(t, []api.Leaf{
+		{Name: "description", ContentTemplate: "{{.shortDescription}}"},
+		{Name: "refs", ContentTemplate: "{{.notes.refs | json}}"},
+		{Name: "raw.json", ContentTemplate: "{{. | json}}"},
+	}, cve.Files)
+
+	vendor := topo.Nodes[1].Children[0]
+	assert.Equal(t, "{{.vendorProject}}", vendor.Name)
+	assert.Equal(t, "$.vulnerabilities[*]", vendor.Selector)
+	require.Len(t, vendor.Children, 1)
+	assert.Equal(t, "{{.cveID}}", vendor.Children[0].Name)
+	assert.Equal(t, "$", vendor.Children[0].Selector, "a field of the same record groups within it")
+
+	// The schema projects the sample it was built from.
+	dataFile := filepath.Join(t.TempDir(), "kev.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(kevSample), 0o644))
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(topo, store).Ingest(dataFile))
+	desc, err := store.GetNode("vulns/CVE-2023-4966/description")
+	require.NoError(t, err)
+	assert.Equal(t, "Citrix Bleed", string(desc.Data))
+	desc, err = store.GetNode("by-vendor/Apache/CVE-2021-44228/description")
+	require.NoError(t, err)
+	assert.Equal(t, "Log4j RCE", string(desc.Data))
+}
+
+func TestSchemaFromExamples_Locate(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		path     string
+		selector []string // along the path
+		dirName  string   // of the deepest directory
+		content  string
+	}{
+		{
+			name:     "top-level array",
+			data:     `[{"id": "a", "v": 1}, {"id": "b", "v": 2}]`,
+			path:     "items/{id}/v=v",
+			selector: []string{"$", "$[*]"},
+			dirName:  "{{.id}}",
+			content:  "{{.v}}",
+		},
+		{
+			name:     "nested field names the directory",
+			data:     `{"rows": [{"meta": {"id": "a"}, "v": 1}]}`,
+			path:     "{id}/v=v",
+			selector: []string{"$.rows[*]"},
+			dirName:  "{{.meta.id}}",
+			content:  "{{.v}}",
+		},
+		{
+			name:     "array nested in records",
+			data:     `{"groups": [{"name": "g", "members": [{"user": "u", "role": "admin"}]}]}`,
+			path:     "{name}/{user}/role=role",
+			selector: []string{"$.groups[*]", "$.members[*]"},
+			dirName:  "{{.user}}",
+			content:  "{{.role}}",
+		},
+		{
+			name:     "most frequent location wins",
+			data:     `{"id": "doc", "items": [{"id": "a"}, {"id": "b"}], "x": {"extra": [{"id": "c"}]}}`,
+			path:     "{id}/raw=.",
+			selector: []string{"$.items[*]"},
+			dirName:  "{{.id}}",
+			content:  "{{. | json}}",
+		},
+		{
+			name:     "key that isn't an identifier",
+			data:     `[{"cve-id": "a", "short desc": "d"}]`,
+			path:     "{cve-id}/d=short desc",
+			selector: []string{"$[*]"},
+			dirName:  `{{index . "cve-id"}}`,
+			content:  `{{index . "short desc"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topo, err := SchemaFromExamples(decodeSample(t, tt.data), []string{tt.path})
+			require.NoError(t, err)
+			nodes := topo.Nodes
+			var n api.Node
+			for _, sel := range tt.selector {
+				require.Len(t, nodes, 1)
+				n = nodes[0]
+				assert.Equal(t, sel, n.Selector)
+				nodes = n.Children
+			}
+			assert.Equal(t, tt.dirName, n.Name)
+			require.Len(t, n.Files, 1)
+			assert.Equal(t, tt.content, n.Files[0].ContentTemplate)
+		})
+	}
+}
+
+func TestSchemaFromExamples_Errors(t *testing.T) {
+	doc := decodeSample(t, kevSample)
+	for spec, want := range map[string]string{
+		"vulns/{cveID}/description":           "want <dirs>/<file>=<field>",
+		"description=shortDescription":        "needs a directory above it",
+		"vulns/{missing}/description=cveID":   `field "missing" not found`,
+		"vulns/{cveID}/d=missing":             `field "missing" not found in the records`,
+		"vulns//{cveID}/d=cveID":              "empty directory name",
+		"vulns/{cveID}/{vendorProject}=cveID": "must be static",
+	} {
+		_, err := SchemaFromExamples(doc, []string{spec})
+		require.Error(t, err, spec)
+		assert.Contains(t, err.Error(), want, spec)
+	}
+}
@@ -0,0 +1,464 @@
+package ingest
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// SCIP (https://github.com/sourcegraph/scip) is the index format written by
+// LSP-grade indexers such as scip-go, scip-typescript, and scip-python. Only
+// the fields mapped onto refs are decoded; the rest are skipped.
+
+// Protobuf field numbers of the SCIP messages read below.
+const (
+	scipIndexMetadata      = 1
+	scipIndexDocuments     = 2
+	scipMetadataRoot       = 3
+	scipDocumentPath       = 1
+	scipDocumentOccurrence = 2
+	scipOccurrenceRange    = 1
+	scipOccurrenceSymbol   = 2
+	scipOccurrenceRoles    = 3
+	scipRoleDefinition     = 1
+)
+
+type scipIndex struct {
+	projectRoot string
+	documents   []scipDocument
+}
+
+type scipDocument struct {
+	relativePath string
+	occurrences  []scipOccurrence
+}
+
+// scipOccurrence is one mention of a symbol. rng is [startLine, startChar,
+// endLine, endChar], or [startLine, startChar, endChar] on one line, all
+// zero-based.
+type scipOccurrence struct {
+	symbol string
+	roles  uint64
+	rng    []int32
+}
+
+// decodeSCIP decodes a serialized SCIP Index.
+func decodeSCIP(data []byte) (*scipIndex, error) {
+	idx := &scipIndex{}
+	err := protoFields(data, func(num int, _ uint64, b []byte) error {
+		switch num {
+		case scipIndexMetadata:
+			return protoFields(b, func(num int, _ uint64, b []byte) error {
+				if num == scipMetadataRoot {
+					idx.projectRoot = string(b)
+				}
+				return nil
+			})
+		case scipIndexDocuments:
+			doc, err := decodeSCIPDocument(b)
+			if err != nil {
+				return err
+			}
+			idx.documents = append(idx.documents, doc)
+		}
+		return nil
+	})
+	return idx, err
+}
+
+func decodeSCIPDocument(data []byte) (scipDocument, error) {
+	var doc scipDocument
+	err := protoFields(data, func(num int, _ uint64, b []byte) error {
+		switch num {
+		case scipDocumentPath:
+			doc.relativePath = string(b)
+		case scipDocumentOccurrence:
+			var occ scipOccurrence
+			err := protoFields(b, func(num int, v uint64, b []byte) error {
+				switch num {
+				case scipOccurrenceRange:
+					if b == nil { // unpacked
+						occ.rng = append(occ.rng, int32(v))
+						return nil
+					}
+					for len(b) > 0 {
+						x, n := binary.Uvarint(b)
+						if n <= 0 {
+							return errors.New("malformed occurrence range")
+						}
+						occ.rng = append(occ.rng, int32(x))
+						b = b[n:]
+					}
+				case scipOccurrenceSymbol:
+					occ.symbol = string(b)
+				case scipOccurrenceRoles:
+					occ.roles = v
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			doc.occurrences = append(doc.occurrences, occ)
+		}
+		return nil
+	})
+	return doc, err
+}
+
+// protoFields calls fn for each field of a protobuf message: v holds a
+// varint or fixed-width value, b a length-delimited one (nil otherwise).
+func protoFields(data []byte, fn func(num int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("malformed protobuf field key")
+		}
+		data = data[n:]
+		num := int(key >> 3)
+		var v uint64
+		var b []byte
+		switch key & 7 {
+		case 0:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errors.New("malformed protobuf varint")
+			}
+			data = data[n:]
+		case 1:
+			if len(data) < 8 {
+				return errors.New("truncated protobuf fixed64")
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case 2:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return errors.New("truncated protobuf field")
+			}
+			b, data = data[n:n+int(size)], data[n+int(size):]
+			if b == nil {
+				b = []byte{}
+			}
+		case 5:
+			if len(data) < 4 {
+				return errors.New("truncated protobuf fixed32")
+			}
+			v, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", key&7)
+		}
+		if err := fn(num, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SCIPImport summarizes what ImportSCIP added to an index DB.
+type SCIPImport struct {
+	Documents  int // SCIP documents matched to source files in the DB
+	Unmatched  int // documents for files the DB doesn't hold
+	Stale      int // constructs whose source no longer matches the file on disk
+	Defs       int // symbols resolved to the construct defining them
+	References int // cross-construct references added
+}
+
+// scipSpan is the byte range of one construct's source in its file.
+type scipSpan struct {
+	sourceID, dirID string
+	start, end      int
+}
+
+// scipFile is a source file of the DB matched to a SCIP document.
+type scipFile struct {
+	doc     scipDocument
+	content []byte
+	lines   []int // byte offset of each line
+	spans   []scipSpan
+}
+
+// ImportSCIP reads the SCIP index at indexPath and adds its precise
+// cross-references to the index DB at dbPath, built by mache build: each
+// reference from one construct to a symbol defined by another becomes a
+// node_refs row under the defining construct's name, so it shows up in
+// that construct's callers/, and an imported_refs row, so the defining
+// construct shows up in the referencing one's callees/.
+//
+// Documents are matched to the DB's source files by path relative to root
+// (the index's project root when ""), and occurrences to constructs by
+// locating each construct's source in the file, so the files must be
+// unchanged since the build. Local symbols are skipped.
+func ImportSCIP(dbPath, indexPath, root string) (SCIPImport, error) {
+	var stats SCIPImport
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return stats, err
+	}
+	idx, err := decodeSCIP(data)
+	if err != nil {
+		return stats, fmt.Errorf("decode %s: %w", indexPath, err)
+	}
+	if root == "" {
+		root = strings.TrimPrefix(idx.projectRoot, "file://")
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return stats, fmt.Errorf("open sqlite %s: %w", dbPath, err)
+	}
+	defer func() { _ = db.Close() }()
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type='table' AND name='nodes'").Scan(&n); err != nil || n == 0 {
+		return stats, fmt.Errorf("%s is not an index DB built by mache build", dbPath)
+	}
+
+	sourceFiles, err := dbSourceFiles(db)
+	if err != nil {
+		return stats, err
+	}
+	var files []*scipFile
+	for _, doc := range idx.documents {
+		sf := matchSourceFile(sourceFiles, root, doc.relativePath)
+		if sf == "" {
+			stats.Unmatched++
+			continue
+		}
+		f, stale, err := loadSCIPFile(db, sf, doc)
+		if err != nil {
+			return stats, err
+		}
+		stats.Documents++
+		stats.Stale += stale
+		files = append(files, f)
+	}
+
+	// Resolve each symbol to the constructs its definitions name.
+	defs := make(map[string][]string)
+	for _, f := range files {
+		for _, occ := range f.doc.occurrences {
+			if occ.roles&scipRoleDefinition == 0 || strings.HasPrefix(occ.symbol, "local ") {
+				continue
+			}
+			start, end, ok := f.byteRange(occ.rng)
+			if !ok {
+				continue
+			}
+			span := f.innermost(start)
+			if span == nil || !namesConstruct(span.dirID, string(f.content[start:end])) {
+				continue
+			}
+			defs[occ.symbol] = append(defs[occ.symbol], span.dirID)
+			stats.Defs++
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return stats, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS imported_refs (
+		node_id TEXT,
+		dir_id TEXT,
+		PRIMARY KEY (node_id, dir_id)
+	) WITHOUT ROWID`); err != nil {
+		return stats, fmt.Errorf("create imported_refs: %w", err)
+	}
+	for _, f := range files {
+		for _, occ := range f.doc.occurrences {
+			if occ.roles&scipRoleDefinition != 0 || len(defs[occ.symbol]) == 0 {
+				continue
+			}
+			start, _, ok := f.byteRange(occ.rng)
+			if !ok {
+				continue
+			}
+			span := f.innermost(start)
+			if span == nil {
+				continue
+			}
+			for _, defID := range defs[occ.symbol] {
+				if defID == span.dirID {
+					continue
+				}
+				res, err := tx.Exec("INSERT OR IGNORE INTO imported_refs (node_id, dir_id) VALUES (?, ?)", span.sourceID, defID)
+				if err != nil {
+					return stats, fmt.Errorf("insert imported ref: %w", err)
+				}
+				if added, _ := res.RowsAffected(); added == 0 {
+					continue
+				}
+				token := path.Base(defID)
+				if _, err := tx.Exec("INSERT OR IGNORE INTO node_refs (token, node_id) VALUES (?, ?)", token, span.sourceID); err != nil {
+					return stats, fmt.Errorf("insert ref: %w", err)
+				}
+				if _, err := tx.Exec("INSERT OR IGNORE INTO node_defs (token, dir_id) VALUES (?, ?)", token, defID); err != nil {
+					return stats, fmt.Errorf("insert def: %w", err)
+				}
+				stats.References++
+			}
+		}
+	}
+	return stats, tx.Commit()
+}
+
+// dbSourceFiles returns the distinct source files of the DB's nodes.
+func dbSourceFiles(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT source_file FROM nodes WHERE source_file IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("query source files: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var files []string
+	for rows.Next() {
+		var sf string
+		if err := rows.Scan(&sf); err != nil {
+			return nil, err
+		}
+		files = append(files, sf)
+	}
+	return files, rows.Err()
+}
+
+// matchSourceFile returns the source file of sourceFiles that rel, relative
+// to root, names: the one at root/rel, else the one ending in /rel.
+func matchSourceFile(sourceFiles []string, root, rel string) string {
+	rel = filepath.FromSlash(rel)
+	want := ""
+	if root != "" {
+		want = filepath.Join(root, rel)
+	}
+	match := ""
+	for _, sf := range sourceFiles {
+		switch {
+		case sf == want:
+			return sf
+		case match == "" && (sf == rel || strings.HasSuffix(sf, string(filepath.Separator)+rel)):
+			match = sf
+		}
+	}
+	return match
+}
+
+// loadSCIPFile reads sf and locates the source of each of its constructs,
+// returning how many could not be found.
+func loadSCIPFile(db *sql.DB, sf string, doc scipDocument) (*scipFile, int, error) {
+	content, err := os.ReadFile(sf)
+	if err != nil {
+		return nil, 0, err
+	}
+	f := &scipFile{doc: doc, content: content, lines: []int{0}}
+	for i, c := range content {
+		if c == '\n' {
+			f.lines = append(f.lines, i+1)
+		}
+	}
+
+	// A construct's code leaf is "source" unless its schema node set
+	// source_leaf, which the construct directory records.
+	rows, err := db.Query(`SELECT n.id, n.parent_id, n.name, n.record, p.record FROM nodes n
+		LEFT JOIN nodes p ON p.id = n.parent_id AND p.kind = 1
+		WHERE n.source_file = ? AND n.kind = 0 ORDER BY n.id`, sf)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query constructs of %s: %w", sf, err)
+	}
+	defer func() { _ = rows.Close() }()
+	claimed := make(map[int]bool)
+	stale := 0
+	for rows.Next() {
+		var id, name string
+		var parent sql.NullString
+		var record, parentRecord []byte
+		if err := rows.Scan(&id, &parent, &name, &record, &parentRecord); err != nil {
+			return nil, 0, err
+		}
+		var props map[string][]byte
+		_ = json.Unmarshal(parentRecord, &props)
+		if name != graph.SourceLeafOf(props) {
+			continue
+		}
+		start := locate(content, record, claimed)
+		if start < 0 || !parent.Valid {
+			stale++
+			continue
+		}
+		claimed[start] = true
+		f.spans = append(f.spans, scipSpan{sourceID: id, dirID: parent.String, start: start, end: start + len(record)})
+	}
+	return f, stale, rows.Err()
+}
+
+// locate returns the offset of the first occurrence of src in content not
+// already claimed by another construct, or -1.
+func locate(content, src []byte, claimed map[int]bool) int {
+	if len(src) == 0 {
+		return -1
+	}
+	for off := 0; ; {
+		i := bytes.Index(content[off:], src)
+		if i < 0 {
+			return -1
+		}
+		if !claimed[off+i] {
+			return off + i
+		}
+		off += i + 1
+	}
+}
+
+// byteRange converts a SCIP range to byte offsets into the file, reading
+// characters as UTF-8 bytes.
+func (f *scipFile) byteRange(rng []int32) (start, end int, ok bool) {
+	var endLine, endChar int32
+	switch len(rng) {
+	case 3:
+		endLine, endChar = rng[0], rng[2]
+	case 4:
+		endLine, endChar = rng[2], rng[3]
+	default:
+		return 0, 0, false
+	}
+	start, ok = f.offset(rng[0], rng[1])
+	if !ok {
+		return 0, 0, false
+	}
+	end, ok = f.offset(endLine, endChar)
+	return start, end, ok && end >= start
+}
+
+func (f *scipFile) offset(line, char int32) (int, bool) {
+	if line < 0 || char < 0 || int(line) >= len(f.lines) {
+		return 0, false
+	}
+	off := f.lines[line] + int(char)
+	return off, off <= len(f.content)
+}
+
+// innermost returns the smallest construct span containing offset.
+func (f *scipFile) innermost(offset int) *scipSpan {
+	var best *scipSpan
+	for i := range f.spans {
+		s := &f.spans[i]
+		if s.start <= offset && offset < s.end && (best == nil || s.end-s.start < best.end-best.start) {
+			best = s
+		}
+	}
+	return best
+}
+
+// namesConstruct reports whether name, a definition's identifier, is the
+// name of the construct directory dirID ("Validate", or "Server.Validate"
+// for a method named by its receiver).
+func namesConstruct(dirID, name string) bool {
+	base := path.Base(dirID)
+	return name != "" && (base == name || strings.HasSuffix(base, "."+name))
+}
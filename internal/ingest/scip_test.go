@@ -0,0 +1,200 @@
+package ingest
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// protoField encodes a length-delimited protobuf field.
+func protoField(num int, b []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(num)<<3|2)
+	out = binary.AppendUvarint(out, uint64(len(b)))
+	return append(out, b...)
+}
+
+// scipOcc encodes a SCIP Occurrence with a packed range.
+func scipOcc(symbol string, roles uint64, rng ...int32) []byte {
+	var packed []byte
+	for _, r := range rng {
+		packed = binary.AppendUvarint(packed, uint64(r))
+	}
+	out := protoField(scipOccurrenceRange, packed)
+	out = append(out, protoField(scipOccurrenceSymbol, []byte(symbol))...)
+	if roles != 0 {
+		out = binary.AppendUvarint(out, scipOccurrenceRoles<<3)
+		out = binary.AppendUvarint(out, roles)
+	}
+	return out
+}
+
+func scipDoc(path string, occs ...[]byte) []byte {
+	out := protoField(scipDocumentPath, []byte(path))
+	for _, o := range occs {
+		out = append(out, protoField(scipDocumentOccurrence, o)...)
+	}
+	return out
+}
+
+func TestImportSCIP(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "demo.go"), []byte(`package demo
+
+type Greeter interface{ Greet() string }
+
+type English struct{}
+
+func (English) Greet() string { return "hi" }
+
+func Run(g Greeter) string {
+	return g.Greet()
+}
+`), 0o644))
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, NewEngine(loadGoSchema(t), w).Ingest(src))
+	require.NoError(t, w.Close())
+
+	const (
+		greeter      = "scip-go gomod demo v1 `demo`/Greeter#"
+		greeterGreet = "scip-go gomod demo v1 `demo`/Greeter#Greet()."
+		englishGreet = "scip-go gomod demo v1 `demo`/English#Greet()."
+		run          = "scip-go gomod demo v1 `demo`/Run()."
+	)
+	index := protoField(scipIndexMetadata, protoField(scipMetadataRoot, []byte("file://"+src)))
+	index = append(index, protoField(scipIndexDocuments, scipDoc("demo.go",
+		scipOcc(greeter, scipRoleDefinition, 2, 5, 12),
+		scipOcc(greeterGreet, scipRoleDefinition, 2, 24, 29),
+		scipOcc(englishGreet, scipRoleDefinition, 6, 15, 20),
+		scipOcc(run, scipRoleDefinition, 8, 5, 8, 8),
+		scipOcc("local 0", scipRoleDefinition, 8, 9, 10),
+		scipOcc(greeter, 0, 8, 11, 18),
+		scipOcc("local 0", 0, 9, 8, 9),
+		scipOcc(greeterGreet, 0, 9, 10, 15),
+	))...)
+	index = append(index, protoField(scipIndexDocuments, scipDoc("other.go"))...)
+	indexPath := filepath.Join(t.TempDir(), "index.scip")
+	require.NoError(t, os.WriteFile(indexPath, index, 0o644))
+
+	stats, err := ImportSCIP(dbPath, indexPath, "")
+	require.NoError(t, err)
+	assert.Equal(t, SCIPImport{Documents: 1, Unmatched: 1, Defs: 4, References: 2}, stats)
+
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	var callers []string
+	rows, err := db.Query("SELECT node_id FROM node_refs WHERE token = 'Greet' ORDER BY node_id")
+	require.NoError(t, err)
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		callers = append(callers, id)
+	}
+	require.NoError(t, rows.Err())
+	assert.Contains(t, callers, "demo/functions/Run/source", "the interface call is a precise reference")
+
+	g, err := graph.OpenSQLiteGraph(dbPath, loadGoSchema(t), nil)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	callees, err := g.GetCallees("demo/functions/Run")
+	require.NoError(t, err)
+	var ids []string
+	for _, n := range callees {
+		ids = append(ids, n.ID)
+	}
+	assert.ElementsMatch(t, []string{"demo/types/Greeter", "demo/types/Greeter/methods/Greet"}, ids)
+
+	// Importing again adds nothing new.
+	stats, err = ImportSCIP(dbPath, indexPath, "")
+	require.NoError(t, err)
+	assert.Zero(t, stats.References)
+}
+
+func TestImportSCIP_StaleSource(t *testing.T) {
+	src := t.TempDir()
+	file := filepath.Join(src, "demo.go")
+	require.NoError(t, os.WriteFile(file, []byte("package demo\n\nfunc A() {}\n\nfunc B() { A() }\n"), 0o644))
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, NewEngine(loadGoSchema(t), w).Ingest(src))
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(file, []byte("package demo\n\nfunc A() {}\n\nfunc B() { A(); A() }\n"), 0o644))
+
+	indexPath := filepath.Join(t.TempDir(), "index.scip")
+	require.NoError(t, os.WriteFile(indexPath, protoField(scipIndexDocuments, scipDoc("demo.go",
+		scipOcc("a", scipRoleDefinition, 2, 5, 6),
+		scipOcc("a", 0, 4, 11, 12),
+	)), 0o644))
+
+	stats, err := ImportSCIP(dbPath, indexPath, src)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Stale, "B changed since the build")
+	assert.Zero(t, stats.References, "references from a changed construct are dropped")
+
+	_, err = ImportSCIP(dbPath, filepath.Join(src, "demo.go"), src)
+	assert.Error(t, err, "a file that isn't a SCIP index")
+}
+
+func TestImportSCIP_SourceLeaf(t *testing.T) {
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "functions",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:       "{{.name}}",
+				Selector:   "(function_declaration name: (identifier) @name) @scope",
+				SourceLeaf: "code",
+				Files: []api.Leaf{
+					{Name: "code", ContentTemplate: "{{.scope}}"},
+					{Name: "source", ContentTemplate: "{{.scope}}"},
+				},
+			}},
+		}},
+	}
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "demo.go"), []byte("package demo\n\nfunc A() {}\n\nfunc B() { A() }\n"), 0o644))
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+	w, err := NewSQLiteWriter(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, NewEngine(schema, w).Ingest(src))
+	require.NoError(t, w.Close())
+
+	indexPath := filepath.Join(t.TempDir(), "index.scip")
+	require.NoError(t, os.WriteFile(indexPath, protoField(scipIndexDocuments, scipDoc("demo.go",
+		scipOcc("a", scipRoleDefinition, 2, 5, 6),
+		scipOcc("a", 0, 4, 11, 12),
+	)), 0o644))
+	stats, err := ImportSCIP(dbPath, indexPath, src)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.References)
+
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	var node string
+	require.NoError(t, db.QueryRow("SELECT node_id FROM imported_refs").Scan(&node))
+	assert.Equal(t, "functions/B/code", node, "references attach to the declared code leaf")
+
+	g, err := graph.OpenSQLiteGraph(dbPath, schema, nil)
+	require.NoError(t, err)
+	defer func() { _ = g.Close() }()
+	callees, err := g.GetCallees("functions/B")
+	require.NoError(t, err)
+	var ids []string
+	for _, n := range callees {
+		ids = append(ids, n.ID)
+	}
+	assert.Contains(t, ids, "functions/A")
+}
@@ -0,0 +1,121 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+
+	"github.com/agentic-research/mache/internal/lang"
+)
+
+// maxSExprLeafText bounds the source text shown after a leaf node.
+const maxSExprLeafText = 40
+
+// SExpr re-parses the source file at path as langName (or by its
+// extension when langName is "") and returns the tree-sitter S-expression
+// of the node spanning bytes [start, end): one named node per line,
+// indented by depth, with field names and each leaf's source text in a
+// trailing comment. It is what a selector matches against.
+func SExpr(path, langName string, start, end uint32) ([]byte, error) {
+	n, content, err := parseSpan(path, langName, start, end)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for _, line := range appendSExpr(nil, n, "", 0, content) {
+		b.WriteString(line.text)
+		if line.comment != "" {
+			b.WriteString(" ; " + line.comment)
+		}
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
+
+// parseSpan re-parses the source file at path as langName (or by its
+// extension when langName is "") and returns the node spanning bytes
+// [start, end) with the file's content.
+func parseSpan(path, langName string, start, end uint32) (*sitter.Node, []byte, error) {
+	l := lang.ForName(langName)
+	if l == nil {
+		l = lang.ForPath(path)
+	}
+	if l == nil || l.Grammar == nil {
+		return nil, nil, fmt.Errorf("%s: no tree-sitter grammar", path)
+	}
+	content, _, err := readSourceFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if end > uint32(len(content)) || start > end {
+		return nil, nil, fmt.Errorf("%s: byte range %d-%d is outside the file; it changed since ingest", path, start, end)
+	}
+	tree, err := sitter.ParseCtx(context.Background(), content, l.Grammar())
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nodeSpanning(tree, start, end), content, nil
+}
+
+// nodeSpanning returns the outermost node below root spanning exactly
+// [start, end), or the smallest one containing the range when none does.
+func nodeSpanning(root *sitter.Node, start, end uint32) *sitter.Node {
+	n := root
+	for n.StartByte() != start || n.EndByte() != end {
+		var next *sitter.Node
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			if c := n.NamedChild(i); c.StartByte() <= start && c.EndByte() >= end {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		n = next
+	}
+	return n
+}
+
+// sexprLine is one node of an S-expression: its text, with the closing
+// parentheses of the nodes it ends, and the comment that follows them.
+type sexprLine struct {
+	text, comment string
+}
+
+// appendSExpr appends n's named subtree to lines, prefixed by its field
+// name, one node per line.
+func appendSExpr(lines []sexprLine, n *sitter.Node, field string, depth int, source []byte) []sexprLine {
+	text := strings.Repeat("  ", depth)
+	if field != "" {
+		text += field + ": "
+	}
+	text += "(" + n.Type()
+	if n.IsMissing() {
+		text += " MISSING"
+	}
+	lines = append(lines, sexprLine{text: text})
+	self := len(lines) - 1
+	for i := 0; i < int(n.ChildCount()); i++ {
+		if c := n.Child(i); c.IsNamed() {
+			lines = appendSExpr(lines, c, n.FieldNameForChild(i), depth+1, source)
+		}
+	}
+	if len(lines)-1 == self {
+		lines[self].comment = quoteLeafText(n.Content(source))
+	}
+	lines[len(lines)-1].text += ")"
+	return lines
+}
+
+// quoteLeafText quotes a leaf's source text for a one-line comment,
+// truncated to maxSExprLeafText runes.
+func quoteLeafText(text string) string {
+	if r := []rune(text); len(r) > maxSExprLeafText {
+		text = string(r[:maxSExprLeafText]) + "…"
+	}
+	return strconv.Quote(text)
+}
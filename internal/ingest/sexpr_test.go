@@ -0,0 +1,42 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSExpr(t *testing.T) {
+	src := "package auth\n\nfunc Login(user string) error {\n\treturn nil\n}\n"
+	path := filepath.Join(t.TempDir(), "auth.go")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	start := strings.Index(src, "func")
+	end := strings.LastIndex(src, "}") + 1
+
+	got, err := SExpr(path, "go", uint32(start), uint32(end))
+	require.NoError(t, err)
+	assert.Equal(t, `(function_declaration
+  name: (identifier) ; "Login"
+  parameters: (parameter_list
+    (parameter_declaration
+      name: (identifier) ; "user"
+      type: (type_identifier))) ; "string"
+  result: (type_identifier) ; "error"
+  body: (block
+    (return_statement
+      (expression_list
+        (nil))))) ; "nil"
+`, string(got))
+
+	// Without a language, the extension decides.
+	byExt, err := SExpr(path, "", uint32(start), uint32(end))
+	require.NoError(t, err)
+	assert.Equal(t, got, byExt)
+
+	_, err = SExpr(path, "go", 0, uint32(len(src)+10))
+	assert.Error(t, err, "a range past the end means the file changed")
+}
@@ -0,0 +1,224 @@
+package ingest
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/lang"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Single-file components (.vue, .svelte) are parsed as HTML first. Their
+// <script>, <template>, and <style> blocks are then re-parsed with the
+// JS/TS, HTML, and CSS grammars, restricted to the blocks' byte ranges, so
+// every node keeps its offset in the component file and write-back splices
+// into the right place. Schema nodes opt into a section with a language of
+// "<component language>/<section>", e.g. "vue/script" or "svelte/style".
+
+// SFC section names.
+const (
+	sfcScript   = "script"
+	sfcTemplate = "template"
+	sfcStyle    = "style"
+)
+
+// sfcSection is one section of a single-file component: all of its blocks,
+// parsed together as one tree with the section's grammar.
+type sfcSection struct {
+	name     string // sfcScript, sfcTemplate, or sfcStyle
+	language string // schema language, e.g. "vue/script"
+	langName string // grammar language, e.g. "typescript"
+	lang     *sitter.Language
+	tree     *sitter.Tree
+}
+
+// sfcBlock is the byte range of one block's content and the grammar it is
+// written in.
+type sfcBlock struct {
+	section  string
+	langName string
+	rng      sitter.Range
+}
+
+// isSingleFileComponent reports whether langName is a component format.
+func isSingleFileComponent(langName string) bool {
+	l := lang.ForName(langName)
+	return l != nil && l.SingleFileComponent
+}
+
+// parseSFCSections splits a component already parsed as HTML into its
+// sections and parses each one. Blocks in a language without a grammar
+// here (<style lang="scss">) are left to the HTML projection.
+func parseSFCSections(ctx context.Context, root *sitter.Node, source []byte, langName string) []sfcSection {
+	var sections []sfcSection
+	byKey := make(map[string]int) // section + grammar → index into ranges
+	var ranges [][]sitter.Range
+	for _, b := range sfcBlocks(root, source, langName == "svelte") {
+		key := b.section + "\x00" + b.langName
+		i, ok := byKey[key]
+		if !ok {
+			l := lang.ForName(b.langName)
+			if l == nil {
+				continue
+			}
+			i = len(sections)
+			byKey[key] = i
+			sections = append(sections, sfcSection{
+				name:     b.section,
+				language: langName + "/" + b.section,
+				langName: b.langName,
+				lang:     l.Grammar(),
+			})
+			ranges = append(ranges, nil)
+		}
+		ranges[i] = append(ranges[i], b.rng)
+	}
+
+	parsed := sections[:0]
+	for i, sec := range sections {
+		parser := sitter.NewParser()
+		parser.SetLanguage(sec.lang)
+		parser.SetIncludedRanges(ranges[i])
+		tree, err := parseSource(ctx, parser, source)
+		parser.Close()
+		if err != nil {
+			continue
+		}
+		sec.tree = tree
+		parsed = append(parsed, sec)
+	}
+	return parsed
+}
+
+// sfcBlocks lists the section blocks among the document's top-level nodes.
+// Vue markup is the content of the top-level <template>; Svelte markup is
+// everything outside <script> and <style>.
+func sfcBlocks(root *sitter.Node, source []byte, svelte bool) []sfcBlock {
+	var blocks []sfcBlock
+	for i := 0; i < int(root.NamedChildCount()); i++ {
+		child := root.NamedChild(i)
+		switch child.Type() {
+		case "script_element", "style_element":
+			raw := childOfType(child, "raw_text")
+			if raw == nil {
+				continue
+			}
+			section, grammar := sfcScript, "javascript"
+			attr := strings.ToLower(startTagAttr(child, source, "lang"))
+			if child.Type() == "style_element" {
+				section, grammar = sfcStyle, "css"
+				if attr != "" && attr != "css" {
+					continue
+				}
+			} else if attr == "ts" || attr == "tsx" || attr == "typescript" {
+				grammar = "typescript"
+			}
+			blocks = append(blocks, sfcBlock{section: section, langName: grammar, rng: nodeRange(raw)})
+		case "element":
+			if svelte {
+				blocks = append(blocks, sfcBlock{section: sfcTemplate, langName: "html", rng: nodeRange(child)})
+				continue
+			}
+			start, end := childOfType(child, "start_tag"), childOfType(child, "end_tag")
+			if start == nil || end == nil || !strings.EqualFold(nodeText(childOfType(start, "tag_name"), source), "template") {
+				continue
+			}
+			blocks = append(blocks, sfcBlock{section: sfcTemplate, langName: "html", rng: sitter.Range{
+				StartPoint: start.EndPoint(),
+				EndPoint:   end.StartPoint(),
+				StartByte:  start.EndByte(),
+				EndByte:    end.StartByte(),
+			}})
+		default:
+			if svelte {
+				blocks = append(blocks, sfcBlock{section: sfcTemplate, langName: "html", rng: nodeRange(child)})
+			}
+		}
+	}
+	return blocks
+}
+
+// startTagAttr returns the value of the named attribute on el's start tag,
+// or "" when it has none.
+func startTagAttr(el *sitter.Node, source []byte, name string) string {
+	start := childOfType(el, "start_tag")
+	if start == nil {
+		return ""
+	}
+	for i := 0; i < int(start.NamedChildCount()); i++ {
+		attr := start.NamedChild(i)
+		if attr.Type() != "attribute" || !strings.EqualFold(nodeText(childOfType(attr, "attribute_name"), source), name) {
+			continue
+		}
+		if v := childOfType(attr, "quoted_attribute_value"); v != nil {
+			return nodeText(childOfType(v, "attribute_value"), source)
+		}
+		return nodeText(childOfType(attr, "attribute_value"), source)
+	}
+	return ""
+}
+
+// childOfType returns n's first named child of type typ, or nil.
+func childOfType(n *sitter.Node, typ string) *sitter.Node {
+	if n == nil {
+		return nil
+	}
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		if c := n.NamedChild(i); c.Type() == typ {
+			return c
+		}
+	}
+	return nil
+}
+
+// nodeText returns n's source text, or "" for a nil node.
+func nodeText(n *sitter.Node, source []byte) string {
+	if n == nil {
+		return ""
+	}
+	return string(source[n.StartByte():n.EndByte()])
+}
+
+// nodeRange is the source range n spans.
+func nodeRange(n *sitter.Node) sitter.Range {
+	return sitter.Range{
+		StartPoint: n.StartPoint(),
+		EndPoint:   n.EndPoint(),
+		StartByte:  n.StartByte(),
+		EndByte:    n.EndByte(),
+	}
+}
+
+// schemaPass is one application of top-level schema nodes to a parsed
+// file: the whole file, or one section of a single-file component.
+type schemaPass struct {
+	nodes        []api.Node
+	root         any
+	parentValues map[string]any // nil for the whole file
+}
+
+// filterNodesBySection returns the top-level schema nodes for a component
+// section language ("vue/script"). Unlike filterNodesByLanguage, nodes
+// without a language don't match: they already apply to the whole file.
+func filterNodesBySection(nodes []api.Node, language string) []api.Node {
+	var result []api.Node
+	for _, node := range nodes {
+		if node.Language == language {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// sfcParentValues are the values a section's top-level schema nodes see as
+// {{._parent.component}} and {{._parent.section}}: the component's file
+// name without extension, and the section name.
+func sfcParentValues(path, section string) map[string]any {
+	base := filepath.Base(path)
+	return map[string]any{
+		"component": strings.TrimSuffix(base, filepath.Ext(base)),
+		"section":   section,
+	}
+}
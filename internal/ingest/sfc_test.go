@@ -0,0 +1,86 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/internal/graph"
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/html"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseSFC(t *testing.T, src, langName string) []sfcSection {
+	t.Helper()
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(html.GetLanguage())
+	tree, err := parser.ParseCtx(context.Background(), nil, []byte(src))
+	require.NoError(t, err)
+	return parseSFCSections(context.Background(), tree.RootNode(), []byte(src), langName)
+}
+
+func TestParseSFCSections_Vue(t *testing.T) {
+	src := `<template><p>{{ msg }}</p></template>
+<script>export const msg = "hi"</script>
+<script setup lang="ts">const n: number = 1</script>
+<style>p { color: red; }</style>
+<style lang="scss">$x: 1; p { a { color: $x; } }</style>
+`
+	sections := parseSFC(t, src, "vue")
+	got := map[string]string{}
+	for _, sec := range sections {
+		root := sec.tree.RootNode()
+		got[sec.language+" "+sec.langName] = src[root.StartByte():root.EndByte()]
+	}
+	assert.Equal(t, map[string]string{
+		"vue/template html":     "<p>{{ msg }}</p>",
+		"vue/script javascript": `export const msg = "hi"`,
+		"vue/script typescript": "const n: number = 1",
+		"vue/style css":         "p { color: red; }",
+	}, got, "each block parses at its offset in the file; SCSS is left to the HTML projection")
+}
+
+func TestEngine_IngestSvelteSections(t *testing.T) {
+	schema := &api.Topology{Version: "v1", Nodes: []api.Node{
+		{
+			Name:     "{{._parent.component}}",
+			Selector: "$",
+			Language: "svelte/script",
+			Children: []api.Node{{
+				Name:     "{{.name}}",
+				Selector: "(function_declaration name: (identifier) @name) @scope",
+				Files:    []api.Leaf{{Name: "source", ContentTemplate: "{{.scope}}"}},
+			}},
+		},
+		{
+			Name:     "{{._parent.component}}",
+			Selector: "$",
+			Language: "svelte/template",
+			Children: []api.Node{{
+				Name:     "{{.tag}}",
+				Selector: "(element (start_tag (tag_name) @tag)) @scope",
+				Files:    []api.Leaf{{Name: "source", ContentTemplate: "{{.scope}}"}},
+			}},
+		},
+	}}
+	dir := t.TempDir()
+	src := "<script>\nfunction inc() { count += 1 }\n</script>\n\n<button on:click={inc}>{count}</button>\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Counter.svelte"), []byte(src), 0o644))
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(dir))
+
+	fn, err := store.GetNode("Counter/inc/source")
+	require.NoError(t, err)
+	assert.Equal(t, "function inc() { count += 1 }", string(fn.Data))
+	btn, err := store.GetNode("Counter/button/source")
+	require.NoError(t, err)
+	assert.Equal(t, "<button on:click={inc}>{count}</button>", string(btn.Data))
+	require.NotNil(t, btn.Origin)
+	assert.Equal(t, string(btn.Data), src[btn.Origin.StartByte:btn.Origin.EndByte])
+}
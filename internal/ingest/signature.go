@@ -0,0 +1,84 @@
+package ingest
+
+import (
+	"bytes"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// SignaturesOnly cuts each construct's source leaf down to its declaration
+// (the function signature, the type or class header, with any doc comment
+// above it) for a small mount suited to skimming a codebase. The whole
+// construct stays readable, and writable, as FullSourceFile beside it; the
+// cut-down source is read-only. Constructs without a body (a Go type alias,
+// a constant) keep their whole source. Off by default. Configurable via
+// --signatures-only.
+var SignaturesOnly bool
+
+// FullSourceFile is the leaf holding a construct's whole source under
+// SignaturesOnly.
+const FullSourceFile = "_full"
+
+// bodyTypes are node types that hold a declaration's body where the
+// grammar doesn't mark it as a body field: a Go struct's field list, a
+// C++ namespace's declaration list, a TypeScript object type.
+var bodyTypes = map[string]bool{
+	"block":                  true,
+	"statement_block":        true,
+	"compound_statement":     true,
+	"field_declaration_list": true,
+	"declaration_list":       true,
+	"class_body":             true,
+	"interface_body":         true,
+	"enum_body":              true,
+	"enum_variant_list":      true,
+	"object_type":            true,
+	"body_statement":         true,
+}
+
+// maxBodyDepth bounds how far below the declaration signatureEnd looks for
+// its body, so a nested literal deep in an initializer isn't taken for it.
+const maxBodyDepth = 3
+
+// signatureEnd returns the offset where scope's declaration ends and its
+// body begins. The body is the declaration's body field when the grammar
+// has one, else the first node below it that looks like a body: one of
+// bodyTypes, another node's body field, or an opening brace (a Go
+// interface lists its methods directly between braces). ok is false for a
+// declaration without a body.
+func signatureEnd(scope *sitter.Node) (uint32, bool) {
+	if body := scope.ChildByFieldName("body"); body != nil {
+		return body.StartByte(), true
+	}
+	return findBody(scope, 0)
+}
+
+func findBody(n *sitter.Node, depth int) (uint32, bool) {
+	for i := 0; i < int(n.ChildCount()); i++ {
+		c := n.Child(i)
+		if c.Type() == "{" || bodyTypes[c.Type()] || n.FieldNameForChild(i) == "body" {
+			return c.StartByte(), true
+		}
+		if depth < maxBodyDepth {
+			if end, ok := findBody(c, depth+1); ok {
+				return end, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// signatureOf returns the declaration of match's construct, from start
+// (where its doc comment begins) up to its body, or ok false when it has
+// no body to leave out.
+func signatureOf(match Match, start uint32) (sig []byte, ok bool) {
+	root, isSitter := match.Context().(SitterRoot)
+	if !isSitter || root.Node == nil {
+		return nil, false
+	}
+	end, ok := signatureEnd(root.Node)
+	if !ok || end <= start || end > uint32(len(root.Source)) {
+		return nil, false
+	}
+	return bytes.TrimRight(root.Source[start:end], " \t\r\n"), true
+}
@@ -63,10 +63,12 @@ type SitterWalker struct {
 	// addressRefQueryCache caches compiled address ref queries keyed by
 	// (language name, scheme) pair.
 	addressRefQueryCache sync.Map // addressRefQueryCacheKey -> *sitter.Query
+	// typeRefQueryCache caches compiled type reference queries.
+	typeRefQueryCache sync.Map // string (language name) -> *sitter.Query
 	// schemaQueryCache caches compiled schema selector queries keyed by
 	// (selector, language) pair. Schema selectors are the same across all
 	// files of the same language, so caching avoids recompilation on every file.
-	schemaQueryCache sync.Map // schemaQueryKey -> *sitter.Query
+	schemaQueryCache sync.Map // schemaQueryKey -> *schemaQuery
 }
 
 func NewSitterWalker() *SitterWalker {
@@ -111,10 +113,11 @@ func (w *SitterWalker) Query(root any, selector string) ([]Match, error) {
 	}
 
 	// Compile the query (cached per selector+language pair).
-	q, err := w.getSchemaQuery(sr.Lang, selector)
+	sq, err := w.getSchemaQuery(sr.Lang, selector)
 	if err != nil {
 		return nil, fmt.Errorf("invalid query '%s': %w", selector, err)
 	}
+	q := sq.q
 	// Do NOT close q here — it is owned by the cache.
 
 	// Execute query
@@ -130,10 +133,8 @@ func (w *SitterWalker) Query(root any, selector string) ([]Match, error) {
 			break
 		}
 
-		// Enforce #eq? / #not-eq? predicates in the query.
-		// When no predicates exist this is a no-op (copies captures through unchanged).
-		m = qc.FilterPredicates(m, sr.Source)
-		if len(m.Captures) == 0 {
+		// Enforce #eq?, #not-eq?, #match?, and #not-match? predicates.
+		if len(m.Captures) == 0 || !sq.accept(m, sr.Source) {
 			continue
 		}
 
@@ -225,29 +226,29 @@ func (m *sitterMatch) Context() any {
 // The compiled query is reused across all files of the same language, avoiding
 // recompilation of the same S-expression on every file (e.g., 50K files × 20
 // selectors = 1M compilations reduced to ~20).
-func (w *SitterWalker) getSchemaQuery(lang *sitter.Language, selector string) (*sitter.Query, error) {
+func (w *SitterWalker) getSchemaQuery(lang *sitter.Language, selector string) (*schemaQuery, error) {
 	key := schemaQueryKey{selector: selector, lang: uintptr(unsafe.Pointer(lang))}
 	if cached, ok := w.schemaQueryCache.Load(key); ok {
-		return cached.(*sitter.Query), nil
+		return cached.(*schemaQuery), nil
 	}
 
-	q, err := sitter.NewQuery([]byte(selector), lang)
+	sq, err := compileSchemaQuery(lang, selector)
 	if err != nil {
 		return nil, err
 	}
-	actual, loaded := w.schemaQueryCache.LoadOrStore(key, q)
+	actual, loaded := w.schemaQueryCache.LoadOrStore(key, sq)
 	if loaded {
-		q.Close()
-		return actual.(*sitter.Query), nil
+		sq.q.Close()
+		return actual.(*schemaQuery), nil
 	}
-	return q, nil
+	return sq, nil
 }
 
 // Close releases all cached compiled queries. Call when the SitterWalker is
 // no longer needed (e.g., after ingestion completes).
 func (w *SitterWalker) Close() {
 	w.schemaQueryCache.Range(func(_, v any) bool {
-		v.(*sitter.Query).Close()
+		v.(*schemaQuery).q.Close()
 		return true
 	})
 	w.callQueryCache.Range(func(_, v any) bool {
@@ -266,6 +267,10 @@ func (w *SitterWalker) Close() {
 		v.(*sitter.Query).Close()
 		return true
 	})
+	w.typeRefQueryCache.Range(func(_, v any) bool {
+		v.(*sitter.Query).Close()
+		return true
+	})
 }
 
 // getContextQuery returns a cached compiled query for context extraction.
@@ -296,7 +301,9 @@ func (w *SitterWalker) getContextQuery(lang *sitter.Language, langName string) (
 	return q, nil
 }
 
-// ExtractContext finds package-level context nodes.
+// ExtractContext returns the file-level context (imports, globals) captured
+// by the language's context query as @ctx, separated by blank lines. Other
+// captures, e.g. @_name for a predicate, are not emitted.
 func (w *SitterWalker) ExtractContext(root *sitter.Node, source []byte, lang *sitter.Language, langName string) ([]byte, error) {
 	q, err := w.getContextQuery(lang, langName)
 	if err != nil {
@@ -320,9 +327,10 @@ func (w *SitterWalker) ExtractContext(root *sitter.Node, source []byte, lang *si
 		if !ok {
 			break
 		}
+		m = qc.FilterPredicates(m, source)
 
 		for _, c := range m.Captures {
-			if seen[c.Node.StartByte()] {
+			if q.CaptureNameForId(c.Index) != "ctx" || seen[c.Node.StartByte()] {
 				continue
 			}
 			seen[c.Node.StartByte()] = true
@@ -446,9 +454,21 @@ func (w *SitterWalker) getCallQuery(lang *sitter.Language, langName string) (*si
 // ExtractCalls finds all function calls in the given node using a predefined query.
 // The compiled query is cached per language to avoid recompilation on every call.
 func (w *SitterWalker) ExtractCalls(root *sitter.Node, source []byte, lang *sitter.Language, langName string) ([]string, error) {
+	calls, _, err := w.extractCalls(root, source, lang, langName, false)
+	return calls, err
+}
+
+// ExtractCallSites is ExtractCalls plus the 1-based source lines of every
+// call site, keyed by token. Lines are in file order and may repeat when a
+// token is called more than once on the same line.
+func (w *SitterWalker) ExtractCallSites(root *sitter.Node, source []byte, lang *sitter.Language, langName string) ([]string, map[string][]int, error) {
+	return w.extractCalls(root, source, lang, langName, true)
+}
+
+func (w *SitterWalker) extractCalls(root *sitter.Node, source []byte, lang *sitter.Language, langName string, withLines bool) ([]string, map[string][]int, error) {
 	q, err := w.getCallQuery(lang, langName)
 	if err != nil {
-		return nil, fmt.Errorf("invalid call query: %w", err)
+		return nil, nil, fmt.Errorf("invalid call query: %w", err)
 	}
 	// Do NOT close q here — it is owned by the cache.
 
@@ -457,8 +477,12 @@ func (w *SitterWalker) ExtractCalls(root *sitter.Node, source []byte, lang *sitt
 
 	qc.Exec(q, root)
 
-	seen := make(map[string]bool)
+	seen := make(map[string]string) // token -> canonical (heap) copy
 	var calls []string
+	var lines map[string][]int
+	if withLines {
+		lines = make(map[string][]int)
+	}
 
 	for {
 		m, ok := qc.NextMatch()
@@ -481,16 +505,19 @@ func (w *SitterWalker) ExtractCalls(root *sitter.Node, source []byte, lang *sitt
 				// This avoids a heap allocation for tokens already encountered
 				// (e.g., "Println" appearing hundreds of times). Only new,
 				// unique tokens get a real string allocation via string().
-				key := unsafe.String(&source[start], int(end-start))
-				if !seen[key] {
-					token := string(source[start:end])
-					seen[token] = true
+				token, ok := seen[unsafe.String(&source[start], int(end-start))]
+				if !ok {
+					token = string(source[start:end])
+					seen[token] = token
 					calls = append(calls, token)
 				}
+				if withLines {
+					lines[token] = append(lines[token], int(c.Node.StartPoint().Row)+1)
+				}
 			}
 		}
 	}
-	return calls, nil
+	return calls, lines, nil
 }
 
 // getQualifiedCallQuery returns a cached compiled query for qualified call
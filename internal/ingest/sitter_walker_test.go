@@ -6,7 +6,9 @@ import (
 
 	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/javascript"
 	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -537,6 +539,71 @@ func main() {
 	assert.Contains(t, string(ctx), "globalVar")
 }
 
+func TestContextQueryRegistry_Python(t *testing.T) {
+	code := []byte(`"""Module docs."""
+from __future__ import annotations
+
+import os
+from typing import List as L
+
+__all__ = ["run"]
+OTHER = 1
+
+
+def run():
+    import json
+    return json.dumps(os.environ)
+`)
+	ctx := extractTestContext(t, python.GetLanguage(), "python", code)
+	assert.Contains(t, ctx, "from __future__ import annotations")
+	assert.Contains(t, ctx, "import os")
+	assert.Contains(t, ctx, "from typing import List as L")
+	assert.Contains(t, ctx, `__all__ = ["run"]`)
+	assert.NotContains(t, ctx, "OTHER", "only __all__ among module assignments")
+	assert.NotContains(t, ctx, "import json", "function-local imports are not file context")
+}
+
+func TestContextQueryRegistry_JavaScriptAndTypeScript(t *testing.T) {
+	code := []byte(`import fs from "fs";
+import { join } from "path";
+const util = require("util");
+export const LIMIT = 10;
+let counter = 0;
+
+function read() {
+  const local = 1;
+  return fs.readFileSync(join("a", "b"));
+}
+`)
+	for name, lang := range map[string]*sitter.Language{
+		"javascript": javascript.GetLanguage(),
+		"typescript": typescript.GetLanguage(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := extractTestContext(t, lang, name, code)
+			assert.Contains(t, ctx, `import fs from "fs";`)
+			assert.Contains(t, ctx, `import { join } from "path";`)
+			assert.Contains(t, ctx, `const util = require("util");`)
+			assert.Contains(t, ctx, "export const LIMIT = 10;")
+			assert.NotContains(t, ctx, "counter")
+			assert.NotContains(t, ctx, "local")
+		})
+	}
+}
+
+func extractTestContext(t *testing.T, lang *sitter.Language, langName string, code []byte) string {
+	t.Helper()
+	parser := sitter.NewParser()
+	parser.SetLanguage(lang)
+	tree, err := parser.ParseCtx(context.Background(), nil, code)
+	require.NoError(t, err)
+	w := NewSitterWalker()
+	defer w.Close()
+	ctx, err := w.ExtractContext(tree.RootNode(), code, lang, langName)
+	require.NoError(t, err)
+	return string(ctx)
+}
+
 func TestContextQueryRegistry_UnsupportedReturnsNil(t *testing.T) {
 	w := NewSitterWalker()
 	// YAML has no context query registered
@@ -668,3 +735,40 @@ func TestSitterWalkerGo_PredicateFiltering(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, matches, 0, "wrong predicate should filter out the match")
 }
+
+func TestSitterWalkerGo_TextPredicates(t *testing.T) {
+	root := parseSitterRoot(t, []byte(`package demo
+
+func TestAlpha(t *testing.T) {}
+func TestBeta(t *testing.T) {}
+func helper() {}
+func Run() {}
+`))
+	w := NewSitterWalker()
+
+	names := func(selector string) []string {
+		t.Helper()
+		matches, err := w.Query(root, selector)
+		require.NoError(t, err)
+		var out []string
+		for _, m := range matches {
+			out = append(out, m.Values()["name"].(string))
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"TestAlpha", "TestBeta"},
+		names(`(function_declaration name: (identifier) @name (#match? @name "^Test"))`))
+	assert.Equal(t, []string{"helper", "Run"},
+		names(`(function_declaration name: (identifier) @name (#not-match? @name "^Test"))`))
+	assert.Equal(t, []string{"TestAlpha", "TestBeta", "helper"},
+		names(`(function_declaration name: (identifier) @name (#not-eq? @name "Run"))`))
+	assert.Equal(t, []string{"Run"},
+		names(`(function_declaration name: (identifier) @name (#eq? @name "Run"))`))
+	// Predicates combine: tests other than TestBeta.
+	assert.Equal(t, []string{"TestAlpha"},
+		names(`(function_declaration name: (identifier) @name (#match? @name "^Test") (#not-eq? @name "TestBeta"))`))
+
+	_, err := w.Query(root, `(function_declaration name: (identifier) @name (#match? @name "(unclosed"))`)
+	assert.Error(t, err, "a bad regex is a query error, not a panic")
+}
@@ -39,19 +39,46 @@ func StreamSQLite(dbPath string, fn func(recordID string, record any) error) err
 	return rows.Err()
 }
 
+// CountSQLiteRecords returns the number of rows in the results table.
+// Used to size progress reporting before streaming.
+func CountSQLiteRecords(dbPath string) (int, error) {
+	return countSQLiteTable(dbPath, "results")
+}
+
+// countSQLiteTable returns the number of rows in table.
+func countSQLiteTable(dbPath, table string) (int, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return 0, fmt.Errorf("open sqlite %s: %w", dbPath, err)
+	}
+	defer func() { _ = db.Close() }() // safe to ignore
+
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count %s: %w", table, err)
+	}
+	return n, nil
+}
+
 // StreamSQLiteRaw iterates over all records yielding raw (id, json) strings
 // without parsing. Used by the parallel ingestion pipeline where workers
 // handle JSON parsing on their own goroutines.
 func StreamSQLiteRaw(dbPath string, fn func(id, raw string) error) error {
+	return streamSQLiteTableRaw(dbPath, "results", "record", fn)
+}
+
+// streamSQLiteTableRaw is StreamSQLiteRaw over the given records table and
+// JSON column (see api.Topology.RecordSource).
+func streamSQLiteTableRaw(dbPath, table, column string, fn func(id, raw string) error) error {
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
 		return fmt.Errorf("open sqlite %s: %w", dbPath, err)
 	}
 	defer func() { _ = db.Close() }() // safe to ignore
 
-	rows, err := db.Query("SELECT id, record FROM results")
+	rows, err := db.Query("SELECT id, " + column + " FROM " + table)
 	if err != nil {
-		return fmt.Errorf("query results: %w", err)
+		return fmt.Errorf("query %s: %w", table, err)
 	}
 	defer func() { _ = rows.Close() }() // safe to ignore
 
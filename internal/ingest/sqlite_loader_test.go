@@ -1,10 +1,18 @@
 package ingest
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/agentic-research/mache/api"
+	"github.com/agentic-research/mache/graph"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -103,3 +111,231 @@ func TestLoadSQLite_Integration(t *testing.T) {
 	_, hasItem := first["item"]
 	assert.True(t, hasItem, "record should have 'item' key")
 }
+
+func TestCountSQLiteRecords(t *testing.T) {
+	dbPath := createTestDB(t, []string{`{"a":1}`, `{"a":2}`, `{"a":3}`})
+	n, err := CountSQLiteRecords(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	_, err = CountSQLiteRecords(filepath.Join(t.TempDir(), "empty.db"))
+	assert.Error(t, err, "missing results table should error")
+}
+
+func TestEngine_IngestSQLite_MaxInFlightRecords(t *testing.T) {
+	var records []string
+	for i := 0; i < 20; i++ {
+		records = append(records, fmt.Sprintf(`{"item":{"name":"rec%02d"}}`, i))
+	}
+	dbPath := createTestDB(t, records)
+
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "items",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "{{.item.name}}",
+				Selector: "$[*]",
+				Files:    []api.Leaf{{Name: "name", ContentTemplate: "{{.item.name}}"}},
+			}},
+		}},
+	}
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	engine.MaxInFlightRecords = 1
+
+	done := make(chan error, 1)
+	go func() { done <- engine.Ingest(dbPath) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("ingest deadlocked with MaxInFlightRecords=1")
+	}
+
+	for i := 0; i < 20; i++ {
+		node, err := store.GetNode(fmt.Sprintf("items/rec%02d/name", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("rec%02d", i), string(node.Data))
+	}
+}
+
+func TestEngine_IngestSQLite_Workers(t *testing.T) {
+	var records []string
+	for i := 0; i < 20; i++ {
+		records = append(records, fmt.Sprintf(`{"item":{"name":"rec%02d"}}`, i))
+	}
+	dbPath := createTestDB(t, records)
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "items",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "{{.item.name}}",
+				Selector: "$[*]",
+				Files:    []api.Leaf{{Name: "name", ContentTemplate: "{{.item.name}}"}},
+			}},
+		}},
+	}
+
+	old := IngestWorkers
+	defer func() { IngestWorkers = old }()
+	for _, n := range []int{1, 3, 64} {
+		IngestWorkers = n
+		assert.Equal(t, n, ingestWorkers())
+
+		store := graph.NewMemoryStore()
+		require.NoError(t, NewEngine(schema, store).Ingest(dbPath))
+		children, err := store.ListChildren("items")
+		require.NoError(t, err)
+		assert.Len(t, children, 20, "workers=%d", n)
+	}
+
+	IngestWorkers = 0
+	assert.Equal(t, runtime.NumCPU(), ingestWorkers())
+}
+
+func TestEngine_IngestSQLite_UnnamedBucket(t *testing.T) {
+	dbPath := createTestDB(t, []string{
+		`{"item":{"name":"alpha"}}`,
+		`{"item":{"other":"no name here"}}`,
+	})
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "items",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "{{.item.name}}",
+				Selector: "$[*]",
+				Files:    []api.Leaf{{Name: "other", ContentTemplate: "{{.item.other}}"}},
+			}},
+		}},
+	}
+
+	old := UnnamedBucket
+	defer func() { UnnamedBucket = old }()
+	UnnamedBucket = true
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(dbPath))
+
+	items, err := store.ListChildren("items")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"items/alpha", "items/_unnamed"}, items)
+	unnamed, err := store.ListChildren("items/_unnamed")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"items/_unnamed/b"}, unnamed, "the nameless record is kept under its record ID")
+	node, err := store.GetNode("items/_unnamed/b/other")
+	require.NoError(t, err)
+	assert.Equal(t, "no name here", string(node.Data))
+}
+
+func TestEngine_IngestSQLite_RecordSource(t *testing.T) {
+	bio := strings.Repeat("x", inlineThreshold+1)
+	dbPath := createTestDB(t, []string{`{"item":{"name":"alpha","bio":"` + bio + `"}}`})
+	db, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec("ALTER TABLE results RENAME COLUMN record TO payload; ALTER TABLE results RENAME TO people")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	schema := &api.Topology{
+		Version:      "v1",
+		Table:        "people",
+		RecordColumn: "payload",
+		Nodes: []api.Node{{
+			Name:     "items",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "{{.item.name}}",
+				Selector: "$[*]",
+				Files:    []api.Leaf{{Name: "bio", ContentTemplate: "{{.item.bio}}"}},
+			}},
+		}},
+	}
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(dbPath))
+
+	node, err := store.GetNode("items/alpha/bio")
+	require.NoError(t, err)
+	require.NotNil(t, node.Ref, "large content stays in the database")
+	assert.Equal(t, "people", node.Ref.Table)
+	assert.Equal(t, "payload", node.Ref.Column)
+
+	resolver := graph.NewSQLiteResolver(RenderTemplate)
+	defer resolver.Close()
+	content, err := resolver.Resolve(node.Ref)
+	require.NoError(t, err)
+	assert.Equal(t, bio, string(content))
+}
+
+func TestEngine_IngestSQLite_DebugSchema(t *testing.T) {
+	dbPath := createTestDB(t, []string{`{"item":{"name":"alpha","tag":"x"}}`})
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "items",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "{{.item.name}}",
+				Selector: "$[*]",
+				Children: []api.Node{{Name: "{{.item.tag}}", Selector: "$"}},
+			}},
+		}},
+	}
+
+	old := DebugSchema
+	defer func() { DebugSchema = old }()
+	DebugSchema = true
+
+	store := graph.NewMemoryStore()
+	require.NoError(t, NewEngine(schema, store).Ingest(dbPath))
+
+	node, err := store.GetNode("items/alpha")
+	require.NoError(t, err)
+	assert.Equal(t, "items > {{.item.name}}", string(node.Properties["schema_path"]))
+	node, err = store.GetNode("items/alpha/x")
+	require.NoError(t, err)
+	assert.Equal(t, "items > {{.item.name}} > {{.item.tag}}", string(node.Properties["schema_path"]))
+}
+
+func TestEngine_IngestSQLite_Cancelled(t *testing.T) {
+	var records []string
+	for i := 0; i < 200; i++ {
+		records = append(records, fmt.Sprintf(`{"item":{"name":"rec%03d"}}`, i))
+	}
+	dbPath := createTestDB(t, records)
+	schema := &api.Topology{
+		Version: "v1",
+		Nodes: []api.Node{{
+			Name:     "items",
+			Selector: "$",
+			Children: []api.Node{{
+				Name:     "{{.item.name}}",
+				Selector: "$[*]",
+			}},
+		}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store := graph.NewMemoryStore()
+	engine := NewEngine(schema, store)
+	engine.MaxInFlightRecords = 1
+
+	done := make(chan error, 1)
+	go func() { done <- engine.IngestContext(ctx, dbPath) }()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(10 * time.Second):
+		t.Fatal("cancelled ingest did not return")
+	}
+	items, err := store.ListChildren("items")
+	require.NoError(t, err)
+	assert.Empty(t, items, "no records are applied after cancellation")
+}
@@ -68,6 +68,7 @@ func NewSQLiteWriter(dbPath string) (*SQLiteWriter, error) {
 	CREATE TABLE IF NOT EXISTS node_refs (
 		token TEXT,
 		node_id TEXT,
+		lines TEXT,
 		PRIMARY KEY (token, node_id)
 	) WITHOUT ROWID;
 
@@ -116,7 +117,16 @@ func (w *SQLiteWriter) beginTx() error {
 		return err
 	}
 
-	w.stmtRef, err = w.tx.Prepare(`INSERT OR IGNORE INTO node_refs (token, node_id) VALUES (?, ?)`)
+	// Call-site lines of a reference added again (a construct's later
+	// clauses) are appended to those already recorded.
+	w.stmtRef, err = w.tx.Prepare(`
+		INSERT INTO node_refs (token, node_id, lines) VALUES (?, ?, ?)
+		ON CONFLICT (token, node_id) DO UPDATE SET lines = CASE
+			WHEN excluded.lines IS NULL THEN node_refs.lines
+			WHEN node_refs.lines IS NULL THEN excluded.lines
+			ELSE node_refs.lines || ',' || excluded.lines
+		END
+	`)
 	if err != nil {
 		return err
 	}
@@ -309,12 +319,18 @@ func (w *SQLiteWriter) AddRoot(n *graph.Node) {
 	w.AddNode(n)
 }
 
-func (w *SQLiteWriter) AddRef(token, nodeID string) error {
+// AddRef records a reference from nodeID to token, with the call-site
+// lines if given (see graph.FormatLines).
+func (w *SQLiteWriter) AddRef(token, nodeID string, lines ...int) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	var linesCol any
+	if len(lines) > 0 {
+		linesCol = graph.FormatLines(lines)
+	}
 	// We use the same transaction as nodes
-	_, err := w.stmtRef.Exec(token, nodeID)
+	_, err := w.stmtRef.Exec(token, nodeID, linesCol)
 	return err
 }
 
@@ -376,8 +392,9 @@ func (w *SQLiteWriter) GetNode(id string) (*graph.Node, error) {
 
 	var kind int
 	var mtimeNano int64
+	var record []byte
 	// parent_id can be NULL for root
-	err := w.tx.QueryRow("SELECT kind, mtime FROM nodes WHERE id = ?", id).Scan(&kind, &mtimeNano)
+	err := w.tx.QueryRow("SELECT kind, mtime, record FROM nodes WHERE id = ?", id).Scan(&kind, &mtimeNano, &record)
 	if err == sql.ErrNoRows {
 		return nil, graph.ErrNotFound
 	}
@@ -390,13 +407,20 @@ func (w *SQLiteWriter) GetNode(id string) (*graph.Node, error) {
 		mode = os.ModeDir | 0o555
 	}
 
-	return &graph.Node{
+	node := &graph.Node{
 		ID:      id,
 		Mode:    mode,
 		ModTime: time.Unix(0, mtimeNano),
 		// Children: nil -- Engine will append to this and call AddNode,
 		// but our AddNode ignores n.Children, so this is safe.
-	}, nil
+	}
+	// A directory's record holds its properties (see AddNode); return
+	// them so the engine's later update of the node (lang, pkg, ...)
+	// doesn't drop them.
+	if kind == 1 && len(record) > 0 {
+		_ = json.Unmarshal(record, &node.Properties)
+	}
+	return node, nil
 }
 
 func (w *SQLiteWriter) ListChildren(id string) ([]string, error) {
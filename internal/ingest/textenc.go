@@ -0,0 +1,84 @@
+package ingest
+
+import (
+	"bytes"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Byte order marks. A UTF-8 BOM is left in place: offsets into the file
+// stay valid for write-back, and the grammars skip it as whitespace.
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// maxOddPercent is the share of a file's first bytes, in percent, that may
+// be control characters or invalid UTF-8 before the file counts as binary.
+// Latin-1 text, whose accented letters are invalid UTF-8, stays well below.
+const maxOddPercent = 10
+
+// isUTF16 reports whether content starts with a UTF-16 byte order mark.
+func isUTF16(content []byte) bool {
+	return bytes.HasPrefix(content, bomUTF16LE) || bytes.HasPrefix(content, bomUTF16BE)
+}
+
+// looksBinary reports whether head, the first bytes of a file, are binary
+// content. Text with a byte order mark is text, even UTF-16 with its NUL
+// high bytes. Otherwise a NUL byte means binary, as it does to git, and so
+// does a share of control characters and invalid UTF-8 above
+// maxOddPercent, which catches binaries without an early NUL.
+func looksBinary(head []byte) bool {
+	if bytes.HasPrefix(head, bomUTF8) || isUTF16(head) {
+		return false
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	odd := 0
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			if !utf8.FullRune(head[i:]) {
+				// A character cut off by the end of head.
+				i = len(head)
+				continue
+			}
+			odd++
+		case r < 0x20 && !isTextControl(r), r == 0x7F:
+			odd++
+		}
+		i += size
+	}
+	return odd*100 > len(head)*maxOddPercent
+}
+
+// isTextControl reports whether control character r is common in text:
+// whitespace, backspace, and the escape that starts terminal color codes.
+func isTextControl(r rune) bool {
+	switch r {
+	case '\t', '\n', '\v', '\f', '\r', '\b', 0x1B:
+		return true
+	}
+	return false
+}
+
+// readSourceFile reads the file at path for ingest, transcoding UTF-16 (by
+// its byte order mark) to UTF-8 so the grammars can parse it. transcoded
+// reports whether it did: the content's offsets then aren't the file's, so
+// write-back refuses such files (see writeback.Splice).
+func readSourceFile(path string) (content []byte, transcoded bool, err error) {
+	content, err = os.ReadFile(path)
+	if err != nil || !isUTF16(content) {
+		return content, false, err
+	}
+	utf8Content, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(content)
+	if err != nil {
+		return nil, false, err
+	}
+	return utf8Content, true, nil
+}
@@ -0,0 +1,69 @@
+package ingest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileTiming records how long one source file took to ingest, for finding
+// the files (often generated ones) that dominate ingest time.
+type FileTiming struct {
+	Path    string        // relative to the ingest root
+	Parse   time.Duration // tree-sitter parse
+	Process time.Duration // projection through the schema into the store
+	Nodes   int           // file nodes projected
+}
+
+// Total is the file's parse plus process time.
+func (t FileTiming) Total() time.Duration {
+	return t.Parse + t.Process
+}
+
+// recordTiming stores the timing for the parsed file, replacing any from an
+// earlier ingest of it.
+func (e *Engine) recordTiming(result *parsedTreeSitterFile, process time.Duration, nodes int) {
+	path := result.realPath
+	if path == "" {
+		path = result.job.path
+	}
+	path = e.relPath(path)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.fileTimings == nil {
+		e.fileTimings = make(map[string]FileTiming)
+	}
+	e.fileTimings[path] = FileTiming{Path: path, Parse: result.parseTime, Process: process, Nodes: nodes}
+}
+
+// FileTimings returns the per-file timings of the tree-sitter files ingested
+// so far, slowest first.
+func (e *Engine) FileTimings() []FileTiming {
+	e.mu.Lock()
+	timings := make([]FileTiming, 0, len(e.fileTimings))
+	for _, t := range e.fileTimings {
+		timings = append(timings, t)
+	}
+	e.mu.Unlock()
+
+	sort.Slice(timings, func(i, j int) bool {
+		if ti, tj := timings[i].Total(), timings[j].Total(); ti != tj {
+			return ti > tj
+		}
+		return timings[i].Path < timings[j].Path
+	})
+	return timings
+}
+
+// FormatFileTimings renders timings as an aligned table, one file per line,
+// in the order given. Durations are rounded to the microsecond.
+func FormatFileTimings(timings []FileTiming) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%12s %12s %12s %8s  %s\n", "total", "parse", "process", "nodes", "file")
+	for _, t := range timings {
+		fmt.Fprintf(&b, "%12s %12s %12s %8d  %s\n",
+			t.Total().Round(time.Microsecond), t.Parse.Round(time.Microsecond), t.Process.Round(time.Microsecond), t.Nodes, t.Path)
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,97 @@
+package ingest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/agentic-research/mache/internal/graph"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// typeRefQueryRegistry stores language-specific type reference queries.
+// Each query captures referenced type names as @type; a capture whose
+// parent is also captured (the name inside a qualified type) is skipped in
+// favour of the qualified form.
+var typeRefQueryRegistry sync.Map // string (language name) -> string
+
+// RegisterTypeRefQuery registers a type reference extraction query for a
+// specific language. Languages without one get no types-used/ entries.
+func RegisterTypeRefQuery(langName, query string) {
+	typeRefQueryRegistry.Store(langName, query)
+}
+
+func init() {
+	RegisterTypeRefQuery("go", `
+		(type_identifier) @type
+		(qualified_type) @type
+	`)
+	RegisterTypeRefQuery("typescript", `(type_identifier) @type`)
+	RegisterTypeRefQuery("rust", `(type_identifier) @type`)
+}
+
+// getTypeRefQuery returns a cached compiled type reference query, or nil
+// when langName has none registered.
+func (w *SitterWalker) getTypeRefQuery(lang *sitter.Language, langName string) (*sitter.Query, error) {
+	if cached, ok := w.typeRefQueryCache.Load(langName); ok {
+		return cached.(*sitter.Query), nil
+	}
+	val, ok := typeRefQueryRegistry.Load(langName)
+	if !ok {
+		return nil, nil
+	}
+	q, err := sitter.NewQuery([]byte(val.(string)), lang)
+	if err != nil {
+		return nil, err
+	}
+	actual, loaded := w.typeRefQueryCache.LoadOrStore(langName, q)
+	if loaded {
+		q.Close()
+		return actual.(*sitter.Query), nil
+	}
+	return q, nil
+}
+
+// ExtractTypeRefs finds the types referenced within root — parameter,
+// result, local variable, and field types — and returns them as
+// graph.TypeRefPrefix tokens ("type:User", "type:auth.Token"), deduplicated
+// in source order.
+func (w *SitterWalker) ExtractTypeRefs(root *sitter.Node, source []byte, lang *sitter.Language, langName string) ([]string, error) {
+	if root == nil || lang == nil {
+		return nil, nil
+	}
+	q, err := w.getTypeRefQuery(lang, langName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type ref query: %w", err)
+	}
+	if q == nil {
+		return nil, nil
+	}
+
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+	qc.Exec(q, root)
+
+	seen := make(map[string]bool)
+	var tokens []string
+	for {
+		m, ok := qc.NextMatch()
+		if !ok {
+			break
+		}
+		for _, c := range m.Captures {
+			if p := c.Node.Parent(); p != nil && p.Type() == "qualified_type" {
+				continue
+			}
+			start, end := c.Node.StartByte(), c.Node.EndByte()
+			if start >= end || end > uint32(len(source)) {
+				continue
+			}
+			token := graph.TypeRefPrefix + string(source[start:end])
+			if !seen[token] {
+				seen[token] = true
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens, nil
+}
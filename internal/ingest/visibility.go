@@ -0,0 +1,91 @@
+package ingest
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/agentic-research/mache/internal/graph"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// SplitVisibility files each construct of a grouping node ("functions",
+// "types", ...) under an exported/ or internal/ subdirectory, following the
+// language's visibility rules, so a package's public API is a directory
+// listing. Off by default. Configurable via --split-visibility.
+var SplitVisibility bool
+
+// visibilityRule decides the visibility of a language's declarations.
+type visibilityRule struct {
+	// decls are the scope node types the rule applies to. Other constructs
+	// (imports, methods of JS classes) stay where they are.
+	decls map[string]bool
+	// exported reports whether the declaration named name, whose @scope
+	// capture is scope, is visible outside its module.
+	exported func(name string, scope *sitter.Node) bool
+}
+
+func declTypes(types ...string) map[string]bool {
+	m := make(map[string]bool, len(types))
+	for _, t := range types {
+		m[t] = true
+	}
+	return m
+}
+
+// esVisibility is the rule for JavaScript and TypeScript: a declaration is
+// exported when its top-level statement is an export statement.
+var esVisibility = visibilityRule{
+	decls: declTypes("function_declaration", "class_declaration", "interface_declaration",
+		"type_alias_declaration", "enum_declaration", "lexical_declaration", "export_statement"),
+	exported: func(_ string, scope *sitter.Node) bool {
+		n := scope
+		for p := n.Parent(); p != nil && p.Type() != "program"; p = p.Parent() {
+			n = p
+		}
+		return n.Type() == "export_statement"
+	},
+}
+
+var visibilityRules = map[string]visibilityRule{
+	"go": {
+		decls: declTypes("function_declaration", "method_declaration", "type_declaration",
+			"type_spec", "const_spec", "var_spec"),
+		exported: func(name string, _ *sitter.Node) bool {
+			r, _ := utf8.DecodeRuneInString(name)
+			return unicode.IsUpper(r)
+		},
+	},
+	"python": {
+		decls: declTypes("class_definition", "function_definition", "decorated_definition"),
+		exported: func(name string, _ *sitter.Node) bool {
+			dunder := strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__")
+			return dunder || !strings.HasPrefix(name, "_")
+		},
+	},
+	"javascript": esVisibility,
+	"typescript": esVisibility,
+}
+
+// visibilityDir returns the directory, graph.ExportedDir or
+// graph.InternalDir, that match belongs in under SplitVisibility. ok is
+// false when the match's language has no visibility rule or the match
+// isn't a declaration the rule covers. The name capture is preferred over
+// the rendered name, which may be qualified (Receiver.Method).
+func visibilityDir(match Match, name string) (dir string, ok bool) {
+	root, isSitter := match.Context().(SitterRoot)
+	if !isSitter || root.Node == nil {
+		return "", false
+	}
+	rule, ok := visibilityRules[root.LangName]
+	if !ok || !rule.decls[root.Node.Type()] {
+		return "", false
+	}
+	if v, isString := match.Values()["name"].(string); isString && v != "" {
+		name = v
+	}
+	if rule.exported(name, root.Node) {
+		return graph.ExportedDir, true
+	}
+	return graph.InternalDir, true
+}
@@ -285,8 +285,8 @@ func (w *Watcher) shouldIgnoreDir(path string) bool {
 	return false
 }
 
-// isSourceFile returns true if the path has a recognized source extension.
-// Delegates to the lang registry (all tree-sitter languages + .json).
+// isSourceFile returns true if the path has a recognized source extension
+// or basename. Delegates to the lang registry (all tree-sitter languages + .json).
 func isSourceFile(path string) bool {
-	return lang.IsSourceExt(filepath.Ext(path))
+	return lang.IsSourcePath(path)
 }
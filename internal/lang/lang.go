@@ -6,6 +6,7 @@ package lang
 
 import (
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/smacker/go-tree-sitter/kotlin"
 	"github.com/smacker/go-tree-sitter/lua"
 	markdownts "github.com/smacker/go-tree-sitter/markdown/tree-sitter-markdown"
+	"github.com/smacker/go-tree-sitter/ocaml"
 	"github.com/smacker/go-tree-sitter/php"
 	"github.com/smacker/go-tree-sitter/protobuf"
 	"github.com/smacker/go-tree-sitter/python"
@@ -47,10 +49,22 @@ type Language struct {
 	Aliases       []string                                 // backward-compat names: e.g. "hcl" for terraform
 	DisplayName   string                                   // human label: "Go", "Python", "HCL/Terraform"
 	Extensions    []string                                 // file extensions including dot: ".go", ".py"
+	Filenames     []string                                 // exact basenames without a telling extension: "Dockerfile"
 	Grammar       func() *sitter.Language                  // tree-sitter grammar factory (lazy, CGO-safe)
 	PresetSchema  string                                   // embedded schema key (empty = no preset)
 	SentinelFiles []string                                 // files that identify a project: "go.mod", "Cargo.toml"
 	EnrichNode    func(n *sitter.Node, rec map[string]any) // language-specific AST enrichment (nil for most)
+	// SingleFileComponent marks component formats (.vue, .svelte) whose
+	// files are parsed as HTML, then split into script, template, and style
+	// sections that are each re-parsed with their own grammar.
+	SingleFileComponent bool
+	// Overloads marks languages where functions may share a name and
+	// differ in parameters; colliding constructs are told apart by arity.
+	Overloads bool
+	// InterfaceExtensions are extensions of interface files (OCaml's .mli)
+	// that pair with the implementation file of the same base name to
+	// define one module. They parse with Grammar, like Extensions.
+	InterfaceExtensions []string
 }
 
 // Registry is the authoritative list of all supported languages.
@@ -59,45 +73,50 @@ var Registry = []Language{
 	{Name: "go", DisplayName: "Go", Extensions: []string{".go"}, Grammar: golang.GetLanguage, PresetSchema: "go", SentinelFiles: []string{"go.mod", "go.sum"}},
 	{Name: "python", DisplayName: "Python", Extensions: []string{".py"}, Grammar: python.GetLanguage, PresetSchema: "python", SentinelFiles: []string{"pyproject.toml", "requirements.txt", "setup.py"}},
 	{Name: "javascript", DisplayName: "JavaScript", Extensions: []string{".js"}, Grammar: javascript.GetLanguage, PresetSchema: "javascript", SentinelFiles: []string{"package.json"}},
-	{Name: "typescript", DisplayName: "TypeScript", Extensions: []string{".ts", ".tsx"}, Grammar: typescript.GetLanguage, PresetSchema: "typescript"},
+	{Name: "typescript", DisplayName: "TypeScript", Extensions: []string{".ts", ".tsx"}, Grammar: typescript.GetLanguage, PresetSchema: "typescript", Overloads: true},
 	{Name: "sql", DisplayName: "SQL", Extensions: []string{".sql"}, Grammar: sql.GetLanguage, PresetSchema: "sql"},
 	{Name: "terraform", Aliases: []string{"hcl"}, DisplayName: "HCL/Terraform", Extensions: []string{".tf", ".hcl"}, Grammar: hcl.GetLanguage, PresetSchema: "terraform", EnrichNode: enrichHCLNode},
 	{Name: "yaml", DisplayName: "YAML", Extensions: []string{".yaml", ".yml"}, Grammar: yaml.GetLanguage, PresetSchema: "yaml"},
 	{Name: "rust", DisplayName: "Rust", Extensions: []string{".rs"}, Grammar: rust.GetLanguage, PresetSchema: "rust", SentinelFiles: []string{"Cargo.toml"}},
 	{Name: "toml", DisplayName: "TOML", Extensions: []string{".toml"}, Grammar: toml.GetLanguage, PresetSchema: "toml"},
 	{Name: "elixir", DisplayName: "Elixir", Extensions: []string{".ex", ".exs"}, Grammar: elixir.GetLanguage, PresetSchema: "elixir", SentinelFiles: []string{"mix.exs"}},
-	{Name: "java", DisplayName: "Java", Extensions: []string{".java"}, Grammar: java.GetLanguage, PresetSchema: "java", SentinelFiles: []string{"pom.xml", "build.gradle"}},
+	{Name: "java", DisplayName: "Java", Extensions: []string{".java"}, Grammar: java.GetLanguage, PresetSchema: "java", SentinelFiles: []string{"pom.xml", "build.gradle"}, Overloads: true},
 	{Name: "c", DisplayName: "C", Extensions: []string{".c", ".h"}, Grammar: treec.GetLanguage, PresetSchema: "c"},
-	{Name: "cpp", DisplayName: "C++", Extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hxx", ".hh"}, Grammar: cpp.GetLanguage, PresetSchema: "cpp", SentinelFiles: []string{"CMakeLists.txt"}},
+	{Name: "cpp", DisplayName: "C++", Extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hxx", ".hh"}, Grammar: cpp.GetLanguage, PresetSchema: "cpp", SentinelFiles: []string{"CMakeLists.txt"}, Overloads: true},
 	{Name: "ruby", DisplayName: "Ruby", Extensions: []string{".rb"}, Grammar: ruby.GetLanguage, PresetSchema: "ruby", SentinelFiles: []string{"Gemfile"}},
 	{Name: "php", DisplayName: "PHP", Extensions: []string{".php"}, Grammar: php.GetLanguage, PresetSchema: "php", SentinelFiles: []string{"composer.json"}},
-	{Name: "kotlin", DisplayName: "Kotlin", Extensions: []string{".kt", ".kts"}, Grammar: kotlin.GetLanguage, PresetSchema: "kotlin"},
+	{Name: "kotlin", DisplayName: "Kotlin", Extensions: []string{".kt", ".kts"}, Grammar: kotlin.GetLanguage, PresetSchema: "kotlin", Overloads: true},
 	{Name: "swift", DisplayName: "Swift", Extensions: []string{".swift"}, Grammar: swift.GetLanguage, PresetSchema: "swift", SentinelFiles: []string{"Package.swift"}},
-	{Name: "scala", DisplayName: "Scala", Extensions: []string{".scala", ".sc"}, Grammar: scala.GetLanguage, PresetSchema: "scala", SentinelFiles: []string{"build.sbt"}},
+	{Name: "scala", DisplayName: "Scala", Extensions: []string{".scala", ".sc"}, Grammar: scala.GetLanguage, PresetSchema: "scala", SentinelFiles: []string{"build.sbt"}, Overloads: true},
+	{Name: "html", DisplayName: "HTML", Extensions: []string{".html", ".htm"}, Grammar: html.GetLanguage, PresetSchema: "html"},
+	{Name: "ocaml", DisplayName: "OCaml", Extensions: []string{".ml"}, InterfaceExtensions: []string{".mli"}, Grammar: ocaml.GetLanguage, PresetSchema: "ocaml", SentinelFiles: []string{"dune-project"}},
 	// --- Added grammars (no preset schemas yet) ---
 	{Name: "bash", DisplayName: "Bash", Extensions: []string{".sh", ".bash"}, Grammar: bash.GetLanguage},
-	{Name: "csharp", DisplayName: "C#", Extensions: []string{".cs"}, Grammar: csharp.GetLanguage},
+	{Name: "csharp", DisplayName: "C#", Extensions: []string{".cs"}, Grammar: csharp.GetLanguage, Overloads: true},
 	{Name: "css", DisplayName: "CSS", Extensions: []string{".css"}, Grammar: css.GetLanguage},
 	{Name: "cue", DisplayName: "CUE", Extensions: []string{".cue"}, Grammar: cue.GetLanguage},
-	{Name: "dockerfile", DisplayName: "Dockerfile", Extensions: []string{".dockerfile"}, Grammar: dockerfile.GetLanguage, SentinelFiles: []string{"Dockerfile"}},
+	{Name: "dockerfile", DisplayName: "Dockerfile", Extensions: []string{".dockerfile"}, Filenames: []string{"Dockerfile", "Containerfile"}, Grammar: dockerfile.GetLanguage, SentinelFiles: []string{"Dockerfile"}},
 	{Name: "groovy", DisplayName: "Groovy", Extensions: []string{".groovy"}, Grammar: groovy.GetLanguage, SentinelFiles: []string{"Jenkinsfile"}},
-	{Name: "html", DisplayName: "HTML", Extensions: []string{".html", ".htm"}, Grammar: html.GetLanguage},
 	{Name: "lua", DisplayName: "Lua", Extensions: []string{".lua"}, Grammar: lua.GetLanguage},
 	{Name: "markdown", DisplayName: "Markdown", Extensions: []string{".md", ".markdown"}, Grammar: markdownts.GetLanguage},
 	{Name: "protobuf", DisplayName: "Protocol Buffers", Extensions: []string{".proto"}, Grammar: protobuf.GetLanguage},
+	{Name: "vue", DisplayName: "Vue", Extensions: []string{".vue"}, Grammar: html.GetLanguage, SingleFileComponent: true},
+	{Name: "svelte", DisplayName: "Svelte", Extensions: []string{".svelte"}, Grammar: html.GetLanguage, SingleFileComponent: true},
 }
 
 // Derived indexes — built once at init, never mutated.
 var (
-	byExt  map[string]*Language
-	byName map[string]*Language
-	srcSet map[string]bool // all extensions + .json
+	byExt      map[string]*Language
+	byName     map[string]*Language
+	byFilename map[string]*Language
+	srcSet     map[string]bool // all extensions + .json
 )
 
 func init() {
 	byExt = make(map[string]*Language, 32)
 	byName = make(map[string]*Language, len(Registry))
 	srcSet = make(map[string]bool, 32)
+	byFilename = make(map[string]*Language)
 
 	for i := range Registry {
 		l := &Registry[i]
@@ -105,10 +124,13 @@ func init() {
 		for _, alias := range l.Aliases {
 			byName[alias] = l // backward compat: ForName("hcl") → terraform
 		}
-		for _, ext := range l.Extensions {
+		for _, ext := range slices.Concat(l.Extensions, l.InterfaceExtensions) {
 			byExt[ext] = l
 			srcSet[ext] = true
 		}
+		for _, name := range l.Filenames {
+			byFilename[name] = l
+		}
 	}
 	// Data format extensions are source files but not tree-sitter languages.
 	srcSet[".json"] = true
@@ -125,17 +147,35 @@ func ForName(name string) *Language {
 	return byName[name]
 }
 
-// ForPath returns the language for a file path (by extension), or nil.
+// ForPath returns the language for a file path, or nil. An exact basename
+// match (Dockerfile) wins over the extension, so "Dockerfile.dev" still
+// falls back to its extension.
 func ForPath(path string) *Language {
+	if l, ok := byFilename[filepath.Base(path)]; ok {
+		return l
+	}
 	return byExt[strings.ToLower(filepath.Ext(path))]
 }
 
+// IsInterface reports whether path is one of l's interface files (.mli).
+func (l *Language) IsInterface(path string) bool {
+	return slices.Contains(l.InterfaceExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
 // IsSourceExt returns true if the extension is a recognized source file
 // (tree-sitter languages + .json).
 func IsSourceExt(ext string) bool {
 	return srcSet[strings.ToLower(ext)]
 }
 
+// IsSourcePath is IsSourceExt for a path, also accepting registered basenames.
+func IsSourcePath(path string) bool {
+	if _, ok := byFilename[filepath.Base(path)]; ok {
+		return true
+	}
+	return IsSourceExt(filepath.Ext(path))
+}
+
 // Extensions returns all recognized file extensions in sorted order.
 func Extensions() []string {
 	out := make([]string, 0, len(srcSet))
@@ -1,6 +1,7 @@
 package lang
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,7 +14,7 @@ func TestRegistry_Has28Languages(t *testing.T) {
 		"yaml", "rust", "toml", "elixir", "java", "c", "cpp",
 		"ruby", "php", "kotlin", "swift", "scala",
 		"bash", "csharp", "css", "cue", "dockerfile", "groovy",
-		"html", "lua", "markdown", "protobuf",
+		"html", "lua", "markdown", "protobuf", "ocaml",
 	}
 	for _, name := range expected {
 		l := ForName(name)
@@ -38,6 +39,7 @@ func TestForExt_AllExtensions(t *testing.T) {
 		".rb": "ruby", ".php": "php",
 		".kt": "kotlin", ".kts": "kotlin",
 		".swift": "swift", ".scala": "scala", ".sc": "scala",
+		".ml": "ocaml", ".mli": "ocaml",
 		// New grammars
 		".sh": "bash", ".bash": "bash",
 		".cs": "csharp", ".css": "css", ".cue": "cue",
@@ -112,10 +114,25 @@ func TestForPath(t *testing.T) {
 	assert.Nil(t, ForPath("/foo/data.csv"))
 }
 
+func TestForPath_Filenames(t *testing.T) {
+	for _, p := range []string{"/repo/Dockerfile", "Containerfile", "/repo/build/app.dockerfile"} {
+		l := ForPath(p)
+		require.NotNil(t, l, p)
+		assert.Equal(t, "dockerfile", l.Name, p)
+	}
+	assert.Nil(t, ForPath("/repo/dockerfile"), "basenames match exactly")
+	assert.Nil(t, ForPath("/repo/Dockerfile.dev"), "a suffixed basename falls back to its extension")
+	assert.Nil(t, ForExt(""), "basenames don't leak into the extension index")
+
+	assert.True(t, IsSourcePath("/repo/Dockerfile"))
+	assert.True(t, IsSourcePath("/repo/main.go"))
+	assert.False(t, IsSourcePath("/repo/Makefile"))
+}
+
 func TestNoDuplicateExtensions(t *testing.T) {
 	seen := map[string]string{}
 	for _, l := range Registry {
-		for _, ext := range l.Extensions {
+		for _, ext := range slices.Concat(l.Extensions, l.InterfaceExtensions) {
 			if prev, ok := seen[ext]; ok {
 				t.Errorf("extension %s claimed by both %s and %s", ext, prev, l.Name)
 			}
@@ -124,6 +141,36 @@ func TestNoDuplicateExtensions(t *testing.T) {
 	}
 }
 
+func TestNoDuplicateFilenames(t *testing.T) {
+	seen := map[string]string{}
+	for _, l := range Registry {
+		for _, name := range l.Filenames {
+			if prev, ok := seen[name]; ok {
+				t.Errorf("filename %s claimed by both %s and %s", name, prev, l.Name)
+			}
+			seen[name] = l.Name
+		}
+	}
+}
+
+func TestIsInterface(t *testing.T) {
+	l := ForName("ocaml")
+	require.NotNil(t, l)
+	assert.True(t, l.IsInterface("/repo/lib/shapes.mli"))
+	assert.False(t, l.IsInterface("/repo/lib/shapes.ml"))
+	assert.False(t, ForName("c").IsInterface("/repo/shapes.h"))
+}
+
+func TestSingleFileComponents(t *testing.T) {
+	for ext, name := range map[string]string{".vue": "vue", ".svelte": "svelte"} {
+		l := ForExt(ext)
+		require.NotNil(t, l, ext)
+		assert.Equal(t, name, l.Name)
+		assert.True(t, l.SingleFileComponent)
+	}
+	assert.False(t, ForName("html").SingleFileComponent)
+}
+
 func TestEnrichNode_Terraform(t *testing.T) {
 	l := ForName("terraform")
 	require.NotNil(t, l)
@@ -0,0 +1,130 @@
+package lattice
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/agentic-research/mache/api"
+)
+
+// inferredLattice is one FCA run: the formal context, its concepts, and the
+// schema projected from them.
+type inferredLattice struct {
+	language string
+	ast      bool
+	ctx      *FormalContext
+	concepts []Concept
+	schema   *api.Topology
+}
+
+// selectorTypeRe extracts the node type a tree-sitter selector matches.
+var selectorTypeRe = regexp.MustCompile(`^\(\s*([A-Za-z_][A-Za-z0-9_]*)`)
+
+// DumpLattice writes the formal concepts of every FCA run since the
+// Inferrer was created (objects = records, attributes = field presence and
+// scaled values), marking which concepts became schema nodes and which were
+// pruned. Requires Config.KeepLattice; greedy inference builds no lattice.
+func (inf *Inferrer) DumpLattice(w io.Writer) error {
+	if len(inf.lattices) == 0 {
+		_, err := fmt.Fprintln(w, "no concept lattice computed (greedy inference, or KeepLattice unset)")
+		return err
+	}
+	for i, l := range inf.lattices {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if err := l.dump(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *inferredLattice) dump(w io.Writer) error {
+	label := l.language
+	if label == "" {
+		label = "records"
+	}
+	truncated := ""
+	if len(l.concepts) >= MaxConcepts {
+		truncated = fmt.Sprintf(" (truncated at MaxConcepts=%d)", MaxConcepts)
+	}
+	if _, err := fmt.Fprintf(w, "lattice %s: %d objects, %d attributes, %d concepts%s\n",
+		label, l.ctx.ObjectCount, len(l.ctx.Attributes), len(l.concepts), truncated); err != nil {
+		return err
+	}
+
+	nodes := l.conceptNodes()
+	for i, c := range l.concepts {
+		role := "pruned"
+		if paths := nodes[i]; len(paths) > 0 {
+			role = "node " + strings.Join(paths, ", ")
+		}
+		if _, err := fmt.Fprintf(w, "  #%d extent=%d intent={%s} -> %s\n",
+			i, c.Extent.GetCardinality(), l.intentNames(c.Intent), role); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "  %d of %d concepts became schema nodes\n", len(nodes), len(l.concepts))
+	return err
+}
+
+func (l *inferredLattice) intentNames(intent *roaring.Bitmap) string {
+	names := make([]string, 0, intent.GetCardinality())
+	iter := intent.Iterator()
+	for iter.HasNext() {
+		names = append(names, l.ctx.Attributes[iter.Next()].Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// conceptNodes maps concept indices to the schema node paths projected from
+// them. For AST data a container node comes from the attribute concept of
+// its type=T attribute (see ProjectAST); for records the whole schema hangs
+// off the top concept (see Project).
+func (l *inferredLattice) conceptNodes() map[int][]string {
+	nodes := make(map[int][]string)
+	if len(l.concepts) == 0 || l.schema == nil {
+		return nodes
+	}
+
+	if !l.ast {
+		top := 0
+		for i, c := range l.concepts {
+			if c.Extent.GetCardinality() > l.concepts[top].Extent.GetCardinality() {
+				top = i
+			}
+		}
+		for _, n := range l.schema.Nodes {
+			nodes[top] = append(nodes[top], n.Name)
+		}
+		return nodes
+	}
+
+	byIntent := make(map[string]int, len(l.concepts))
+	for i, c := range l.concepts {
+		byIntent[c.Intent.String()] = i
+	}
+	var walk func(ns []api.Node, prefix string)
+	walk = func(ns []api.Node, prefix string) {
+		for _, n := range ns {
+			p := prefix + n.Name
+			if m := selectorTypeRe.FindStringSubmatch(n.Selector); m != nil {
+				if j, ok := l.ctx.attrIndex["type="+m[1]]; ok {
+					closure := l.ctx.Closure(roaring.BitmapOf(uint32(j)))
+					if i, ok := byIntent[closure.String()]; ok {
+						nodes[i] = append(nodes[i], p)
+					}
+				}
+			}
+			walk(n.Children, p+"/")
+		}
+	}
+	walk(l.schema.Nodes, "")
+	return nodes
+}
@@ -0,0 +1,61 @@
+package lattice
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpLattice_AST(t *testing.T) {
+	src := []byte("class Greeter:\n    def greet(self):\n        pass\n\ndef main():\n    pass\n")
+	parser := sitter.NewParser()
+	parser.SetLanguage(python.GetLanguage())
+	tree, err := parser.ParseCtx(context.Background(), nil, src)
+	require.NoError(t, err)
+
+	inf := &Inferrer{Config: InferConfig{KeepLattice: true, Language: "python"}}
+	_, err = inf.InferFromTreeSitter(tree.RootNode())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, inf.DumpLattice(&buf))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "lattice python: "), out)
+	assert.Regexp(t, `type=class_definition.*\} -> node classes/\{\{\.name\}\}\n`, out)
+	assert.Regexp(t, `type=function_definition.*\} -> node classes/\{\{\.name\}\}/\{\{\.name\}\}, functions/\{\{\.name\}\}\n`, out)
+	assert.Contains(t, out, "-> pruned")
+	assert.Contains(t, out, "2 of ")
+}
+
+func TestDumpLattice_Records(t *testing.T) {
+	inf := &Inferrer{Config: InferConfig{Method: "fca", RootName: "vulns", KeepLattice: true}}
+	_, err := inf.InferFromRecords(makeKEVRecords(5))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, inf.DumpLattice(&buf))
+	assert.Contains(t, buf.String(), "lattice records: 5 objects")
+	assert.Contains(t, buf.String(), "-> node vulns\n")
+}
+
+func TestDumpLattice_NoneKept(t *testing.T) {
+	for _, cfg := range []InferConfig{
+		{Method: "greedy", KeepLattice: true}, // greedy builds no lattice
+		{Method: "fca"},                       // lattice computed but not kept
+	} {
+		inf := &Inferrer{Config: cfg}
+		_, err := inf.InferFromRecords(makeKEVRecords(3))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, inf.DumpLattice(&buf))
+		assert.Contains(t, buf.String(), "no concept lattice computed")
+	}
+}
@@ -21,6 +21,10 @@ type InferConfig struct {
 	MaxDepth   int               // max depth for greedy inference (default 5)
 	Hints      map[string]string // user-provided type hints
 	Language   string            // language hint for generated nodes (e.g., "go", "terraform")
+
+	// KeepLattice retains each FCA run's concepts for DumpLattice. Off by
+	// default: a lattice holds a bitmap per concept and attribute.
+	KeepLattice bool
 }
 
 // DefaultInferConfig returns sensible defaults.
@@ -37,6 +41,8 @@ func DefaultInferConfig() InferConfig {
 // Inferrer orchestrates FCA-based schema inference.
 type Inferrer struct {
 	Config InferConfig
+
+	lattices []inferredLattice // FCA runs kept for DumpLattice (Config.KeepLattice)
 }
 
 // InferFromRecords infers a topology from pre-loaded records.
@@ -92,11 +98,22 @@ func (inf *Inferrer) InferFromRecords(records []any) (*api.Topology, error) {
 		config.RootName = "records"
 	}
 
+	var topo *api.Topology
 	if isAST {
-		return ProjectAST(concepts, ctx, config), nil
+		topo = ProjectAST(concepts, ctx, config)
+	} else {
+		topo = Project(concepts, ctx, config)
 	}
-
-	return Project(concepts, ctx, config), nil
+	if inf.Config.KeepLattice {
+		inf.lattices = append(inf.lattices, inferredLattice{
+			language: config.Language,
+			ast:      isAST,
+			ctx:      ctx,
+			concepts: concepts,
+			schema:   topo,
+		})
+	}
+	return topo, nil
 }
 
 // InferFromTreeSitter infers a topology from a parsed Tree-sitter AST.
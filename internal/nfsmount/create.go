@@ -0,0 +1,155 @@
+package nfsmount
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	billy "github.com/go-git/go-billy/v5"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// pendingCreate is a construct directory or source file made on a writable
+// mount that has no graph node yet. It lives only in GraphFS until its source
+// is first written; the write-back then appends the content to filePath and
+// re-ingests it, and the real node replaces the pending one.
+type pendingCreate struct {
+	dir      bool
+	filePath string // source file the new construct is appended to
+}
+
+func (fs *GraphFS) pendingEntry(p string) (pendingCreate, bool) {
+	fs.pendingMu.Lock()
+	defer fs.pendingMu.Unlock()
+	pc, ok := fs.pending[p]
+	return pc, ok
+}
+
+func (fs *GraphFS) addPending(p string, pc pendingCreate) {
+	fs.pendingMu.Lock()
+	defer fs.pendingMu.Unlock()
+	if fs.pending == nil {
+		fs.pending = make(map[string]pendingCreate)
+	}
+	fs.pending[p] = pc
+}
+
+// dropPending forgets pending entries, e.g. once write-back made them real.
+func (fs *GraphFS) dropPending(paths ...string) {
+	fs.pendingMu.Lock()
+	defer fs.pendingMu.Unlock()
+	for _, p := range paths {
+		delete(fs.pending, p)
+	}
+}
+
+// pendingChildren returns the pending entries directly under dir.
+func (fs *GraphFS) pendingChildren(dir string) []string {
+	fs.pendingMu.Lock()
+	defer fs.pendingMu.Unlock()
+	var children []string
+	for p := range fs.pending {
+		if path.Dir(p) == dir {
+			children = append(children, p)
+		}
+	}
+	return children
+}
+
+// exists reports whether filename names a graph node, virtual entry, or
+// pending creation.
+func (fs *GraphFS) exists(filename string) bool {
+	if _, err := fs.graph.GetNode(filename); err == nil {
+		return true
+	}
+	if _, ok := fs.pendingEntry(filename); ok {
+		return true
+	}
+	return fs.resolver.Resolve(filename) != nil
+}
+
+// appendTarget returns the source file new constructs under groupDir are
+// appended to: the file holding the first sibling construct's source, or ""
+// if groupDir has no construct with a source file.
+func (fs *GraphFS) appendTarget(groupDir string) string {
+	children, err := fs.graph.ListChildren(groupDir)
+	if err != nil {
+		return ""
+	}
+	for _, child := range children {
+		if !path.IsAbs(child) {
+			child = "/" + child
+		}
+		if n, err := fs.graph.GetNode(child); err != nil || !n.Mode.IsDir() {
+			continue
+		}
+		if file := graph.RawSourceFile(fs.graph, child); file != "" {
+			return file
+		}
+	}
+	return ""
+}
+
+// mkdirPending registers dir as a new construct directory, provided its
+// parent is an existing group of constructs (e.g. functions/) whose source
+// file it can be appended to.
+func (fs *GraphFS) mkdirPending(dir string) error {
+	if node, err := fs.graph.GetNode(dir); err == nil {
+		if node.Mode.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: dir, Err: os.ErrExist}
+	}
+	if pc, ok := fs.pendingEntry(dir); ok {
+		if pc.dir {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: dir, Err: os.ErrExist}
+	}
+	file := fs.appendTarget(path.Dir(dir))
+	if file == "" {
+		return &os.PathError{Op: "mkdir", Path: dir, Err: fmt.Errorf("no sibling construct to place a new one beside: %w", os.ErrPermission)}
+	}
+	fs.addPending(dir, pendingCreate{dir: true, filePath: file})
+	return nil
+}
+
+// createPending registers filename as the source of a new construct. Only
+// "source" under a pending construct directory can be created.
+func (fs *GraphFS) createPending(filename string) error {
+	if _, ok := fs.pendingEntry(filename); ok {
+		return nil
+	}
+	parent, ok := fs.pendingEntry(path.Dir(filename))
+	if !ok || path.Base(filename) != "source" {
+		return &os.PathError{Op: "create", Path: filename, Err: os.ErrNotExist}
+	}
+	fs.addPending(filename, pendingCreate{filePath: parent.filePath})
+	return nil
+}
+
+// openPending opens a pending source for writing. Its origin is the empty
+// range at the end of the target file, so write-back inserts rather than
+// replaces; the pending entries are dropped once that succeeds.
+func (fs *GraphFS) openPending(filename string, pc pendingCreate) (billy.File, error) {
+	if pc.dir {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: fmt.Errorf("is a directory")}
+	}
+	info, err := os.Stat(pc.filePath)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: err}
+	}
+	end := uint32(info.Size())
+	return &writeFile{
+		id:     filename,
+		origin: graph.SourceOrigin{FilePath: pc.filePath, StartByte: end, EndByte: end},
+		onClose: func(nodeID string, origin graph.SourceOrigin, content []byte) error {
+			if err := fs.writeBack(nodeID, origin, content); err != nil {
+				return err
+			}
+			fs.dropPending(nodeID, path.Dir(nodeID))
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,172 @@
+package nfsmount
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	billy "github.com/go-git/go-billy/v5"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// Ops of the change events served by /_events, one "<op> <path>" line each.
+const (
+	EventUpdate     = "update"     // content written back to the source
+	EventRemove     = "remove"     // construct deleted by writing it empty
+	EventInvalidate = "invalidate" // cached size and content dropped
+)
+
+// eventWindow bounds how much of the event stream is kept. A reader that
+// falls further behind loses the events in between.
+const eventWindow = 1 << 20
+
+// eventReadWait is how long a read at the end of /_events waits for the
+// next event before returning EOF. NFS clients retry a read that takes too
+// long, so it stays well under their timeouts; tail -f polls past it.
+const eventReadWait = time.Second
+
+// eventLog is the change stream read through /_events. It is an
+// append-only byte stream: NFS reads are stateless, so each reader's
+// position in it is just the offset it reads at, and the log keeps the
+// last eventWindow bytes for all of them. Bytes that have left the window
+// read as newlines, so a lagging reader stays line-aligned.
+type eventLog struct {
+	mu      sync.Mutex
+	data    []byte        // the retained tail of the stream
+	start   int64         // stream offset of data[0]
+	modTime time.Time     // time of the latest event
+	wake    chan struct{} // closed and replaced on every publish
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{modTime: time.Now(), wake: make(chan struct{})}
+}
+
+// publish appends an event line and wakes waiting readers.
+func (l *eventLog) publish(op, id string) {
+	line := op + " /" + graph.NormalizeID(id) + "\n"
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.data = append(l.data, line...)
+	if len(l.data) > eventWindow {
+		// Trim back to half the window, dropping whole lines so it starts
+		// at an event; halving spreads the copy over many publishes.
+		over := len(l.data) - eventWindow/2
+		cut := len(l.data)
+		if i := bytes.IndexByte(l.data[over:], '\n'); i >= 0 {
+			cut = over + i + 1
+		}
+		l.data = l.data[:copy(l.data, l.data[cut:])]
+		l.start += int64(cut)
+	}
+	l.modTime = time.Now()
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// stat returns the stream's length so far and the time of its last event.
+func (l *eventLog) stat() (int64, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.start + int64(len(l.data)), l.modTime
+}
+
+// readAt copies the stream at off into p. At the end of the stream it
+// first waits up to wait for another event.
+func (l *eventLog) readAt(p []byte, off int64, wait time.Duration) int {
+	l.mu.Lock()
+	if off >= l.start+int64(len(l.data)) && wait > 0 {
+		wake := l.wake
+		l.mu.Unlock()
+		select {
+		case <-wake:
+		case <-time.After(wait):
+		}
+		l.mu.Lock()
+	}
+	defer l.mu.Unlock()
+
+	end := l.start + int64(len(l.data))
+	n := 0
+	for ; n < len(p) && off+int64(n) < min(l.start, end); n++ {
+		p[n] = '\n'
+	}
+	if pos := off + int64(n); n < len(p) && pos >= l.start && pos < end {
+		n += copy(p[n:], l.data[pos-l.start:])
+	}
+	return n
+}
+
+// PublishEvent appends op on the node at id to /_events. It does nothing
+// unless the mount is writable.
+func (fs *GraphFS) PublishEvent(op, id string) {
+	if fs.events != nil {
+		fs.events.publish(op, id)
+	}
+}
+
+// isEventsPath reports whether filename is /_events on a mount serving it.
+func (fs *GraphFS) isEventsPath(filename string) bool {
+	return fs.events != nil && filename == "/"+graph.EventsFile
+}
+
+// eventsInfo stats /_events.
+func (fs *GraphFS) eventsInfo() *staticFileInfo {
+	size, modTime := fs.events.stat()
+	return newFileInfo("/"+graph.EventsFile, size, 0o444, modTime)
+}
+
+// eventsFile is an open /_events: a read-only view of the event log whose
+// reads at the end wait briefly for the next event.
+type eventsFile struct {
+	log *eventLog
+	pos int64
+}
+
+func (f *eventsFile) Name() string { return graph.EventsFile }
+
+func (f *eventsFile) Read(p []byte) (int, error) {
+	n := f.log.readAt(p, f.pos, eventReadWait)
+	if n == 0 {
+		return 0, io.EOF
+	}
+	f.pos += int64(n)
+	return n, nil
+}
+
+func (f *eventsFile) ReadAt(p []byte, off int64) (int, error) {
+	n := f.log.readAt(p, off, eventReadWait)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *eventsFile) Seek(offset int64, whence int) (int64, error) {
+	var newPos int64
+	switch whence {
+	case io.SeekStart:
+		newPos = offset
+	case io.SeekCurrent:
+		newPos = f.pos + offset
+	case io.SeekEnd:
+		size, _ := f.log.stat()
+		newPos = size + offset
+	}
+	if newPos < 0 {
+		newPos = 0
+	}
+	f.pos = newPos
+	return f.pos, nil
+}
+
+func (f *eventsFile) Write([]byte) (int, error) { return 0, errReadOnly }
+func (f *eventsFile) Truncate(int64) error      { return errReadOnly }
+func (f *eventsFile) Lock() error               { return nil }
+func (f *eventsFile) Unlock() error             { return nil }
+func (f *eventsFile) Close() error              { return nil }
+
+var _ billy.File = (*eventsFile)(nil)
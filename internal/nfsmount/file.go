@@ -20,6 +20,14 @@ type graphFile struct {
 	size  int64
 	graph graph.Graph
 	pos   int64
+	limit readLimit // shared by the mount's files (see GraphFS.SetMaxConcurrency)
+}
+
+// readContent is graph.ReadContent within the mount's concurrency limit.
+func (f *graphFile) readContent(p []byte, off int64) (int, error) {
+	f.limit.acquire()
+	defer f.limit.release()
+	return f.graph.ReadContent(f.id, p, off)
 }
 
 func (f *graphFile) Name() string { return f.id }
@@ -28,7 +36,7 @@ func (f *graphFile) Read(p []byte) (int, error) {
 	if f.pos >= f.size {
 		return 0, io.EOF
 	}
-	n, err := f.graph.ReadContent(f.id, p, f.pos)
+	n, err := f.readContent(p, f.pos)
 	if err != nil {
 		return 0, err
 	}
@@ -46,7 +54,7 @@ func (f *graphFile) ReadAt(p []byte, off int64) (int, error) {
 	if off >= f.size {
 		return 0, io.EOF
 	}
-	n, err := f.graph.ReadContent(f.id, p, off)
+	n, err := f.readContent(p, off)
 	if err != nil {
 		return 0, err
 	}
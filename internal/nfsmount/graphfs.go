@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"hash/fnv"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -19,6 +21,7 @@ import (
 	"github.com/agentic-research/mache/api"
 	"github.com/agentic-research/mache/internal/graph"
 	"github.com/agentic-research/mache/internal/vfs"
+	"github.com/agentic-research/mache/internal/writeback"
 )
 
 var errReadOnly = fmt.Errorf("read-only filesystem")
@@ -32,12 +35,25 @@ type GraphFS struct {
 	mountTime  time.Time
 	writable   bool
 	writeBack  WriteBackFunc
+	denyWrite  []string        // path.Match globs rejected with EACCES even when writable
+	allowWrite []string        // if set, only paths matching these globs are writable
+	hidden     map[string]bool // entry names left out of directory listings
 
 	// Virtual path resolver — shared with FUSE backend.
 	resolver *vfs.Resolver
 
 	// Optional prompt content served as /PROMPT.txt virtual file (agent mode).
 	promptContent []byte
+
+	// New constructs created on the mount but not yet written (see create.go).
+	pendingMu sync.Mutex
+	pending   map[string]pendingCreate
+
+	// Change events served as /_events on writable mounts (see events.go).
+	events *eventLog
+
+	// limit bounds concurrent content reads; nil is unlimited.
+	limit readLimit
 }
 
 // NewGraphFS creates a billy.Filesystem backed by a mache Graph.
@@ -67,24 +83,228 @@ func (fs *GraphFS) SetPromptContent(content []byte) {
 	fs.resolver.SetPromptContent(content)
 }
 
+// SetIngestTimings serves fn's per-file ingest timings report as
+// /_diagnostics/ingest-timings.
+func (fs *GraphFS) SetIngestTimings(fn func() []byte) {
+	fs.resolver.SetIngestTimings(fn)
+}
+
+// SetIdentifiers serves fn's ref token frequency report as
+// /_diagnostics/identifiers.
+func (fs *GraphFS) SetIdentifiers(fn func() []byte) {
+	fs.resolver.SetIdentifiers(fn)
+}
+
+// SetImportCycles serves fn's circular import report as
+// /_diagnostics/import-cycles while it renders non-nil.
+func (fs *GraphFS) SetImportCycles(fn func() []byte) {
+	fs.resolver.SetImportCycles(fn)
+}
+
+// SetSExpr serves parse's S-expression of each construct's syntax tree as
+// its _sexp file (see ingest.SExpr).
+func (fs *GraphFS) SetSExpr(parse func(file, langName string, start, end uint32) ([]byte, error)) {
+	fs.resolver.SetSExpr(parse)
+}
+
+// SetAST serves parse's JSON serialization of each construct's syntax tree
+// as its _ast.json file (see ingest.ASTJSON).
+func (fs *GraphFS) SetAST(parse func(file, langName string, start, end uint32) ([]byte, error)) {
+	fs.resolver.SetAST(parse)
+}
+
+// SetMaxConcurrency limits how many file reads resolve content at once to
+// n, so a client issuing many parallel reads of rendered or lazily parsed
+// files waits for a slot instead of running them all. n <= 0 is unlimited.
+// Files opened before the call keep the previous limit.
+func (fs *GraphFS) SetMaxConcurrency(n int) {
+	fs.limit = newReadLimit(n)
+}
+
+// SetManifest serves fn's listing of the ingested source files as
+// /_manifest.json.
+func (fs *GraphFS) SetManifest(fn func() []byte) {
+	fs.resolver.SetManifest(fn)
+}
+
+// SetHistory serves h's commits as /_history/ and, in each construct
+// directory with a source range, the commits that changed it as _history/.
+func (fs *GraphFS) SetHistory(h vfs.CommitHistory) {
+	fs.resolver.SetHistory(h)
+}
+
+// SetCoverage projects c as each construct's _coverage file.
+func (fs *GraphFS) SetCoverage(c vfs.CoverageProfile) {
+	fs.resolver.SetCoverage(c)
+}
+
+// SetSchema replaces the schema served as /_schema.json, e.g. after a
+// schema reload swapped the graph underneath.
+func (fs *GraphFS) SetSchema(schema *api.Topology) {
+	sj, _ := json.MarshalIndent(schema, "", "  ")
+	sj = append(sj, '\n')
+	fs.resolver.SetSchemaJSON(sj)
+}
+
+// SetInferredSchema serves content, the schema --infer produced with how
+// it was inferred, as /_schema.inferred.json.
+func (fs *GraphFS) SetInferredSchema(content []byte) {
+	fs.resolver.SetInferredSchemaJSON(content)
+}
+
 // SetWriteBack enables write support. The callback is invoked when a
-// written file is closed, triggering the splice pipeline.
+// written file is closed, triggering the splice pipeline. Calls for the
+// same source file are serialized, and one whose node moved in that file
+// since it was opened fails with EAGAIN instead of splicing at stale
+// offsets. Each successful write-back is published to /_events.
 func (fs *GraphFS) SetWriteBack(fn WriteBackFunc) {
 	fs.writable = true
-	fs.writeBack = fn
+	fs.events = newEventLog()
+	fs.writeBack = func(nodeID string, origin graph.SourceOrigin, content []byte) error {
+		// Handles are checked when opened; re-check so no path bypasses it.
+		if fs.writeDenied(nodeID) {
+			return &os.PathError{Op: "write", Path: nodeID, Err: os.ErrPermission}
+		}
+		unlock := writeback.LockFile(origin.FilePath)
+		defer unlock()
+		if fs.originChanged(nodeID, origin) {
+			return &os.PathError{Op: "write", Path: nodeID, Err: syscall.EAGAIN}
+		}
+		if err := fn(nodeID, origin, content); err != nil {
+			return err
+		}
+		op := EventUpdate
+		if len(content) == 0 {
+			op = EventRemove
+		}
+		fs.events.publish(op, nodeID)
+		return nil
+	}
 	fs.resolver.SetWritable(true, nil)
 }
 
+// originChanged reports whether another write moved or replaced the node
+// at nodeID in its source file after a handle captured origin. A construct
+// being created has no node yet; its origin is the end of the file, which
+// a write to a sibling moves too.
+func (fs *GraphFS) originChanged(nodeID string, origin graph.SourceOrigin) bool {
+	node, err := fs.graph.GetNode(nodeID)
+	if err != nil {
+		if origin.StartByte != origin.EndByte || origin.JSONPath != "" {
+			return false
+		}
+		info, err := os.Stat(origin.FilePath)
+		return err == nil && info.Size() != int64(origin.EndByte)
+	}
+	return node.Origin != nil && *node.Origin != origin
+}
+
+// originOf copies node's origin under its source file's write-back lock;
+// a write-back to a sibling construct shifts origins in place.
+func originOf(node *graph.Node) graph.SourceOrigin {
+	unlock := writeback.LockFile(node.Origin.FilePath)
+	defer unlock()
+	return *node.Origin
+}
+
+// SetDenyWrite rejects writes to paths matching any of globs with EACCES,
+// even on a writable mount. Globs use path.Match syntax relative to the
+// mount root (e.g. "_project_files", "*/generated_*"); a glob matching a
+// directory denies everything beneath it.
+func (fs *GraphFS) SetDenyWrite(globs []string) error {
+	if err := ValidateWriteGlobs(globs); err != nil {
+		return err
+	}
+	fs.denyWrite = globs
+	return nil
+}
+
+// SetWritablePaths limits writes on a writable mount to paths matching any
+// of globs (e.g. "src"); everything else is rejected with EACCES and shown
+// read-only. Globs are matched as in SetDenyWrite, which still applies
+// within them. No globs leaves the whole mount writable.
+func (fs *GraphFS) SetWritablePaths(globs []string) error {
+	if err := ValidateWriteGlobs(globs); err != nil {
+		return err
+	}
+	fs.allowWrite = globs
+	return nil
+}
+
+// SetHidden leaves entries with any of names (e.g. "_schema.json",
+// "_diagnostics") out of directory listings, for tools that trip over
+// synthetic entries. Hidden entries stay reachable by path.
+func (fs *GraphFS) SetHidden(names []string) {
+	fs.hidden = make(map[string]bool, len(names))
+	for _, n := range names {
+		fs.hidden[n] = true
+	}
+}
+
+// visible drops hidden entries from a directory listing.
+func (fs *GraphFS) visible(infos []os.FileInfo) []os.FileInfo {
+	if len(fs.hidden) == 0 {
+		return infos
+	}
+	kept := infos[:0]
+	for _, info := range infos {
+		if !fs.hidden[info.Name()] {
+			kept = append(kept, info)
+		}
+	}
+	return kept
+}
+
+// ValidateWriteGlobs checks that every glob is a well-formed path.Match
+// pattern.
+func ValidateWriteGlobs(globs []string) error {
+	for _, g := range globs {
+		if _, err := path.Match(g, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", g, err)
+		}
+	}
+	return nil
+}
+
+// writeDenied reports whether filename is kept read-only: it or one of its
+// parent directories matches a deny-write glob, or writable paths are set
+// and neither it nor any parent matches one.
+func (fs *GraphFS) writeDenied(filename string) bool {
+	if len(fs.denyWrite) == 0 && len(fs.allowWrite) == 0 {
+		return false
+	}
+	rel := strings.TrimPrefix(cleanPath(filename), "/")
+	return matchesPathOrParent(fs.denyWrite, rel) ||
+		len(fs.allowWrite) > 0 && !matchesPathOrParent(fs.allowWrite, rel)
+}
+
+// matchesPathOrParent reports whether rel or any of its parent directories
+// matches one of globs.
+func matchesPathOrParent(globs []string, rel string) bool {
+	for p := rel; p != "." && p != ""; p = path.Dir(p) {
+		for _, g := range globs {
+			if ok, _ := path.Match(g, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // --- billy.Basic ---
 
 // Create signals success for existing writable files (NFS CREATE on existing file).
 // go-nfs closes this file immediately — the actual writes come via separate
 // OpenFile calls from WRITE RPCs. We return a no-op file to avoid premature splice.
+// Creating "source" in a directory made by MkdirAll starts a new construct.
 func (fs *GraphFS) Create(filename string) (billy.File, error) {
 	if !fs.writable {
 		return nil, errReadOnly
 	}
 	filename = cleanPath(filename)
+	if fs.writeDenied(filename) {
+		return nil, &os.PathError{Op: "create", Path: filename, Err: os.ErrPermission}
+	}
 
 	// Block AppleDouble / metadata files (silently succeed to avoid log spam)
 	if strings.HasPrefix(filepath.Base(filename), "._") {
@@ -93,11 +313,17 @@ func (fs *GraphFS) Create(filename string) (billy.File, error) {
 
 	node, err := fs.graph.GetNode(filename)
 	if err != nil {
-		return nil, &os.PathError{Op: "create", Path: filename, Err: os.ErrNotExist}
+		if err := fs.createPending(filename); err != nil {
+			return nil, err
+		}
+		return &bytesFile{name: filename, data: nil}, nil
 	}
 	if node.Origin == nil {
 		return nil, &os.PathError{Op: "create", Path: filename, Err: fmt.Errorf("no source origin")}
 	}
+	if node.Generated() {
+		return nil, &os.PathError{Op: "create", Path: filename, Err: os.ErrPermission}
+	}
 
 	// Return a no-op file — go-nfs will close this immediately.
 	// The real content writes come through OpenFile via WRITE RPCs.
@@ -117,9 +343,25 @@ func (fs *GraphFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.
 		if !fs.writable {
 			return nil, errReadOnly
 		}
+		if fs.writeDenied(filename) {
+			return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrPermission}
+		}
+		if flag&os.O_CREATE != 0 {
+			if fs.exists(filename) {
+				if flag&os.O_EXCL != 0 {
+					return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrExist}
+				}
+			} else if err := fs.createPending(filename); err != nil {
+				return nil, err
+			}
+		}
 		return fs.openWritable(filename, flag)
 	}
 
+	if fs.isEventsPath(filename) {
+		return &eventsFile{log: fs.events}, nil
+	}
+
 	// Virtual paths: delegate to resolver
 	if entry := fs.resolver.Resolve(filename); entry != nil {
 		switch entry.Kind {
@@ -133,10 +375,15 @@ func (fs *GraphFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.
 			if err != nil {
 				return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
 			}
-			return &graphFile{id: nodeID, size: refNode.ContentSize(), graph: fs.graph}, nil
+			return &graphFile{id: nodeID, size: refNode.ContentSize(), graph: fs.graph, limit: fs.limit}, nil
 		default:
-			// KindFile: return content as bytesFile
-			return &bytesFile{name: filepath.Base(filename), data: entry.Content}, nil
+			// KindFile: return content as bytesFile. Handlers whose content
+			// is expensive (e.g. _raw) leave it out of Stat; read it now.
+			data := entry.Content
+			if data == nil {
+				data, _ = fs.resolver.ReadContent(filename)
+			}
+			return &bytesFile{name: filepath.Base(filename), data: data}, nil
 		}
 	}
 
@@ -152,17 +399,22 @@ func (fs *GraphFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.
 		id:    filename,
 		size:  node.ContentSize(),
 		graph: fs.graph,
+		limit: fs.limit,
 	}, nil
 }
 
-// openWritable returns a writeFile for nodes that have a SourceOrigin.
+// openWritable returns a writeFile for nodes that have a SourceOrigin,
+// or for the pending source of a construct being created.
 func (fs *GraphFS) openWritable(filename string, flag int) (billy.File, error) {
-	if filename == "/"+graph.SchemaDotJSON {
+	if filename == "/"+graph.SchemaDotJSON || filename == "/"+graph.SchemaInferredJSON || fs.isEventsPath(filename) {
 		return nil, &os.PathError{Op: "open", Path: filename, Err: fmt.Errorf("read-only virtual file")}
 	}
 
 	node, err := fs.graph.GetNode(filename)
 	if err != nil {
+		if pc, ok := fs.pendingEntry(filename); ok {
+			return fs.openPending(filename, pc)
+		}
 		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
 	}
 	if node.Mode.IsDir() {
@@ -171,6 +423,9 @@ func (fs *GraphFS) openWritable(filename string, flag int) (billy.File, error) {
 	if node.Origin == nil {
 		return nil, &os.PathError{Op: "open", Path: filename, Err: fmt.Errorf("no source origin for write-back")}
 	}
+	if node.Generated() {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrPermission}
+	}
 
 	// Pre-fill buffer with existing content (for O_RDWR / partial writes)
 	var buf []byte
@@ -195,7 +450,7 @@ func (fs *GraphFS) openWritable(filename string, flag int) (billy.File, error) {
 
 	return &writeFile{
 		id:      filename,
-		origin:  *node.Origin,
+		origin:  originOf(node),
 		buf:     buf,
 		onClose: fs.writeBack,
 	}, nil
@@ -214,18 +469,32 @@ func (fs *GraphFS) Remove(filename string) error {
 		return errReadOnly
 	}
 	filename = cleanPath(filename)
+	if fs.writeDenied(filename) {
+		return &os.PathError{Op: "remove", Path: filename, Err: os.ErrPermission}
+	}
 
 	node, err := fs.graph.GetNode(filename)
 	if err != nil {
+		if _, ok := fs.pendingEntry(filename); ok {
+			fs.dropPending(filename)
+			return nil
+		}
 		return &os.PathError{Op: "remove", Path: filename, Err: os.ErrNotExist}
 	}
 	if node.Origin == nil {
 		return &os.PathError{Op: "remove", Path: filename, Err: fmt.Errorf("no source origin for delete")}
 	}
+	if node.Origin.JSONPath != "" {
+		// A JSON field can be edited but not deleted from the document.
+		return &os.PathError{Op: "remove", Path: filename, Err: os.ErrPermission}
+	}
+	if node.Generated() {
+		return &os.PathError{Op: "remove", Path: filename, Err: os.ErrPermission}
+	}
 
 	// Splice empty content to "delete" the node
 	if fs.writeBack != nil {
-		return fs.writeBack(filename, *node.Origin, []byte{})
+		return fs.writeBack(filename, originOf(node), []byte{})
 	}
 	return nil
 }
@@ -242,6 +511,9 @@ func (fs *GraphFS) TempFile(dir, prefix string) (billy.File, error) {
 
 // --- billy.Dir ---
 
+// ReadDir lists a directory. go-nfs pages the result to clients by READDIR
+// cookie, serving later pages from its listing cache (keyed by the cookie
+// verifier), so each listing is built once per client scan, not per page.
 func (fs *GraphFS) ReadDir(path string) ([]os.FileInfo, error) {
 	path = cleanPath(path)
 
@@ -255,13 +527,18 @@ func (fs *GraphFS) ReadDir(path string) ([]os.FileInfo, error) {
 			} else {
 				fullPath = path + "/" + de.Name
 			}
-			// For symlink entries (callers/callees), resolve the full entry
-			// to get the referenced node's content size via vEntryToFileInfo.
+			// Symlink entries (callers/callees) are served as files sized like
+			// the referenced node. Resolving that costs a lookup per entry, so
+			// defer it until a client actually asks for the size (READDIRPLUS
+			// or GETATTR), keeping plain READDIR of huge listings O(names).
 			if de.Kind == vfs.KindSymlink {
-				if entry := fs.resolver.Resolve(fullPath); entry != nil {
-					infos = append(infos, fs.vEntryToFileInfo(fullPath, entry, fs.mountTime))
-					continue
-				}
+				infos = append(infos, newLazyFileInfo(fullPath, 0o444, fs.mountTime, func() int64 {
+					if entry := fs.resolver.Resolve(fullPath); entry != nil {
+						return fs.vEntryToFileInfo(fullPath, entry, fs.mountTime).Size()
+					}
+					return 0
+				}))
+				continue
 			}
 			mode := os.FileMode(de.Perm)
 			if de.Kind == vfs.KindDir {
@@ -269,11 +546,14 @@ func (fs *GraphFS) ReadDir(path string) ([]os.FileInfo, error) {
 			}
 			infos = append(infos, newFileInfo(fullPath, de.Size, mode, fs.mountTime))
 		}
-		return infos, nil
+		return fs.visible(infos), nil
 	}
 
 	node, err := fs.graph.GetNode(path)
 	if err != nil && path != "/" {
+		if pc, ok := fs.pendingEntry(path); ok && pc.dir {
+			return fs.pendingInfos(path, nil), nil
+		}
 		return nil, &os.PathError{Op: "readdir", Path: path, Err: os.ErrNotExist}
 	}
 	if node != nil && !node.Mode.IsDir() {
@@ -301,16 +581,39 @@ func (fs *GraphFS) ReadDir(path string) ([]os.FileInfo, error) {
 		}
 		infos = append(infos, newFileInfo(fullPath, extra.Size, mode, fs.mountTime))
 	}
+	if path == "/" && fs.events != nil {
+		infos = append(infos, fs.eventsInfo())
+	}
 
 	for _, stat := range childStats {
 		infos = append(infos, fs.statToFileInfo(stat))
 	}
 
-	return infos, nil
+	return fs.visible(fs.pendingInfos(path, infos)), nil
 }
 
+// pendingInfos appends the pending creations under dir to infos.
+func (fs *GraphFS) pendingInfos(dir string, infos []os.FileInfo) []os.FileInfo {
+	for _, p := range fs.pendingChildren(dir) {
+		if info, err := fs.Lstat(p); err == nil {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// MkdirAll creates a directory for a new construct on a writable mount (see
+// Create). Nothing reaches the source file until the construct's source is
+// written.
 func (fs *GraphFS) MkdirAll(filename string, perm os.FileMode) error {
-	return errReadOnly
+	if !fs.writable {
+		return errReadOnly
+	}
+	filename = cleanPath(filename)
+	if fs.writeDenied(filename) {
+		return &os.PathError{Op: "mkdir", Path: filename, Err: os.ErrPermission}
+	}
+	return fs.mkdirPending(filename)
 }
 
 // --- billy.Symlink ---
@@ -327,6 +630,9 @@ func (fs *GraphFS) Lstat(filename string) (os.FileInfo, error) {
 		}
 		return newFileInfo("/", 0, os.ModeDir|0o555, modTime), nil
 	}
+	if fs.isEventsPath(filename) {
+		return fs.eventsInfo(), nil
+	}
 
 	// Virtual paths: delegate to resolver
 	if entry := fs.resolver.Resolve(filename); entry != nil {
@@ -335,6 +641,12 @@ func (fs *GraphFS) Lstat(filename string) (os.FileInfo, error) {
 
 	node, err := fs.graph.GetNode(filename)
 	if err != nil {
+		if pc, ok := fs.pendingEntry(filename); ok {
+			if pc.dir {
+				return newFileInfo(filename, 0, os.ModeDir|0o755, fs.mountTime), nil
+			}
+			return newFileInfo(filename, 0, 0o644, fs.mountTime), nil
+		}
 		return nil, &os.PathError{Op: "lstat", Path: filename, Err: os.ErrNotExist}
 	}
 
@@ -406,7 +718,7 @@ func (fs *GraphFS) statToFileInfo(s graph.NodeStat) os.FileInfo {
 	mode := os.FileMode(0o444)
 	if s.IsDir {
 		mode = os.ModeDir | 0o555
-	} else if s.HasOrigin {
+	} else if s.HasOrigin && !s.Generated && !fs.writeDenied(s.ID) {
 		mode = 0o644
 	}
 	var size int64
@@ -429,7 +741,7 @@ func (fs *GraphFS) nodeToFileInfo(n *graph.Node) os.FileInfo {
 	mode := os.FileMode(0o444)
 	if n.Mode.IsDir() {
 		mode = os.ModeDir | 0o555
-	} else if n.Origin != nil {
+	} else if n.Origin != nil && !n.Generated() && !fs.writeDenied(n.ID) {
 		mode = 0o644
 	}
 	var size int64
@@ -484,6 +796,22 @@ func (fi *staticFileInfo) Sys() any {
 	}
 }
 
+// lazyFileInfo is a staticFileInfo whose size is computed on first use.
+type lazyFileInfo struct {
+	*staticFileInfo
+	once   sync.Once
+	sizeFn func() int64
+}
+
+func newLazyFileInfo(fullPath string, mode os.FileMode, modTime time.Time, sizeFn func() int64) *lazyFileInfo {
+	return &lazyFileInfo{staticFileInfo: newFileInfo(fullPath, 0, mode, modTime), sizeFn: sizeFn}
+}
+
+func (fi *lazyFileInfo) Size() int64 {
+	fi.once.Do(func() { fi.size = fi.sizeFn() })
+	return fi.size
+}
+
 // pathIno computes a stable unique inode number from a full path.
 func pathIno(fullPath string) uint64 {
 	h := fnv.New64a()
@@ -1,15 +1,22 @@
 package nfsmount
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/agentic-research/mache/api"
 	"github.com/agentic-research/mache/internal/graph"
+	"github.com/agentic-research/mache/internal/writeback"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -147,6 +154,20 @@ func TestOpenSchemaJSON(t *testing.T) {
 	assert.Contains(t, string(buf[:n]), "v1alpha1")
 }
 
+func TestSetSchema(t *testing.T) {
+	gfs := NewGraphFS(newTestGraph(), newTestSchema())
+	gfs.SetSchema(&api.Topology{Version: "v2"})
+
+	f, err := gfs.Open("/_schema.json")
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	buf := make([]byte, 4096)
+	n, _ := f.Read(buf)
+	assert.Contains(t, string(buf[:n]), `"v2"`)
+	assert.NotContains(t, string(buf[:n]), "v1alpha1")
+}
+
 func TestReadAt(t *testing.T) {
 	gfs := NewGraphFS(newTestGraph(), newTestSchema())
 
@@ -160,6 +181,67 @@ func TestReadAt(t *testing.T) {
 	assert.Equal(t, `"id": "CVE`, string(buf[:n]))
 }
 
+// peakGraph records the most ReadContent calls it saw running at once.
+type peakGraph struct {
+	*graph.MemoryStore
+
+	mu            sync.Mutex
+	running, peak int
+}
+
+func (g *peakGraph) ReadContent(id string, buf []byte, offset int64) (int, error) {
+	g.mu.Lock()
+	g.running++
+	g.peak = max(g.peak, g.running)
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		g.running--
+		g.mu.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+	return g.MemoryStore.ReadContent(id, buf, offset)
+}
+
+func TestSetMaxConcurrency(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		limit int
+	}{
+		{"limited", 2},
+		{"unlimited", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &peakGraph{MemoryStore: newTestGraph()}
+			gfs := NewGraphFS(g, newTestSchema())
+			gfs.SetMaxConcurrency(tc.limit)
+
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					f, err := gfs.Open("/vulns/CVE-2024-0001.json")
+					if !assert.NoError(t, err) {
+						return
+					}
+					defer func() { _ = f.Close() }()
+					buf := make([]byte, 256)
+					n, _ := f.Read(buf) // io.EOF with n > 0 at the end
+					assert.Contains(t, string(buf[:n]), "CVE-2024-0001")
+				}()
+			}
+			wg.Wait()
+
+			if tc.limit > 0 {
+				assert.LessOrEqual(t, g.peak, tc.limit)
+			} else {
+				assert.Greater(t, g.peak, 1, "unlimited reads should overlap")
+			}
+		})
+	}
+}
+
 func TestSeek(t *testing.T) {
 	gfs := NewGraphFS(newTestGraph(), newTestSchema())
 
@@ -266,6 +348,219 @@ func TestWritableOpenAndClose(t *testing.T) {
 	assert.Contains(t, string(capturedContent), "CRITICAL")
 }
 
+func TestDenyWrite(t *testing.T) {
+	store := newTestGraph()
+	for _, id := range []string{"vulns/CVE-2024-0001.json", "vulns/CVE-2024-0002.json"} {
+		store.AddNode(&graph.Node{
+			ID:     id,
+			Data:   []byte(`{}`),
+			Origin: &graph.SourceOrigin{FilePath: "/tmp/test-source.json", EndByte: 2},
+		})
+	}
+
+	gfs := NewGraphFS(store, newTestSchema())
+	var written []string
+	gfs.SetWriteBack(func(nodeID string, _ graph.SourceOrigin, _ []byte) error {
+		written = append(written, nodeID)
+		return nil
+	})
+	require.NoError(t, gfs.SetDenyWrite([]string{"*/CVE-2024-0002.json"}))
+
+	_, err := gfs.OpenFile("/vulns/CVE-2024-0002.json", os.O_RDWR, 0)
+	assert.ErrorIs(t, err, os.ErrPermission)
+	_, err = gfs.Create("/vulns/CVE-2024-0002.json")
+	assert.ErrorIs(t, err, os.ErrPermission)
+	assert.ErrorIs(t, gfs.Remove("/vulns/CVE-2024-0002.json"), os.ErrPermission)
+
+	info, err := gfs.Stat("/vulns/CVE-2024-0002.json")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o444), info.Mode().Perm(), "denied files are reported read-only")
+
+	// Reads and non-matching writes are unaffected.
+	_, err = gfs.Open("/vulns/CVE-2024-0002.json")
+	require.NoError(t, err)
+	f, err := gfs.OpenFile("/vulns/CVE-2024-0001.json", os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(`{"ok":1}`))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, []string{"/vulns/CVE-2024-0001.json"}, written)
+
+	// A glob matching a directory denies everything beneath it.
+	require.NoError(t, gfs.SetDenyWrite([]string{"vulns"}))
+	_, err = gfs.OpenFile("/vulns/CVE-2024-0001.json", os.O_RDWR, 0)
+	assert.ErrorIs(t, err, os.ErrPermission)
+
+	assert.Error(t, gfs.SetDenyWrite([]string{"[bad"}))
+}
+
+func TestWritablePaths(t *testing.T) {
+	store := graph.NewMemoryStore()
+	store.AddRoot(&graph.Node{ID: "src", Mode: fs.ModeDir, Children: []string{"src/main.go", "src/pkg"}})
+	store.AddNode(&graph.Node{ID: "src/pkg", Mode: fs.ModeDir, Children: []string{"src/pkg/util.go"}})
+	store.AddRoot(&graph.Node{ID: "config", Mode: fs.ModeDir, Children: []string{"config/app.yaml"}})
+	for _, id := range []string{"src/main.go", "src/pkg/util.go", "config/app.yaml"} {
+		store.AddNode(&graph.Node{
+			ID:     id,
+			Data:   []byte(`{}`),
+			Origin: &graph.SourceOrigin{FilePath: "/tmp/test-source.json", EndByte: 2},
+		})
+	}
+
+	gfs := NewGraphFS(store, newTestSchema())
+	var written []string
+	gfs.SetWriteBack(func(nodeID string, _ graph.SourceOrigin, _ []byte) error {
+		written = append(written, nodeID)
+		return nil
+	})
+	require.NoError(t, gfs.SetWritablePaths([]string{"src"}))
+
+	for _, tc := range []struct {
+		path string
+		perm os.FileMode
+	}{
+		{"/src/main.go", 0o644},
+		{"/src/pkg/util.go", 0o644},
+		{"/config/app.yaml", 0o444},
+	} {
+		info, err := gfs.Stat(tc.path)
+		require.NoError(t, err)
+		assert.Equal(t, tc.perm, info.Mode().Perm(), tc.path)
+	}
+	infos, err := gfs.ReadDir("/config")
+	require.NoError(t, err)
+	perms := make(map[string]os.FileMode)
+	for _, info := range infos {
+		perms[info.Name()] = info.Mode().Perm()
+	}
+	assert.Equal(t, os.FileMode(0o444), perms["app.yaml"], "listings agree with Stat")
+
+	_, err = gfs.OpenFile("/config/app.yaml", os.O_RDWR, 0)
+	assert.ErrorIs(t, err, os.ErrPermission)
+	assert.ErrorIs(t, gfs.Remove("/config/app.yaml"), os.ErrPermission)
+	_, err = gfs.Open("/config/app.yaml")
+	require.NoError(t, err, "paths outside the globs stay readable")
+
+	f, err := gfs.OpenFile("/src/pkg/util.go", os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(`{"ok":1}`))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, []string{"/src/pkg/util.go"}, written)
+
+	// Deny-write globs still apply within writable paths.
+	require.NoError(t, gfs.SetDenyWrite([]string{"src/pkg"}))
+	_, err = gfs.OpenFile("/src/pkg/util.go", os.O_RDWR, 0)
+	assert.ErrorIs(t, err, os.ErrPermission)
+	_, err = gfs.OpenFile("/src/main.go", os.O_RDWR, 0)
+	require.NoError(t, err)
+
+	assert.Error(t, gfs.SetWritablePaths([]string{"[bad"}))
+}
+
+func TestCreateConstruct(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(src, []byte("package main\n\nfunc Foo() {}\n"), 0o644))
+
+	store := graph.NewMemoryStore()
+	store.AddRoot(&graph.Node{ID: "functions", Mode: fs.ModeDir, Children: []string{"functions/Foo"}})
+	store.AddNode(&graph.Node{ID: "functions/Foo", Mode: fs.ModeDir, Children: []string{"functions/Foo/source"}})
+	store.AddNode(&graph.Node{
+		ID:     "functions/Foo/source",
+		Data:   []byte("func Foo() {}"),
+		Origin: &graph.SourceOrigin{FilePath: src, StartByte: 14, EndByte: 27},
+	})
+
+	gfs := NewGraphFS(store, newTestSchema())
+	assert.Equal(t, errReadOnly, gfs.MkdirAll("/functions/Bar", 0o755))
+
+	var gotID string
+	var gotOrigin graph.SourceOrigin
+	var gotContent []byte
+	gfs.SetWriteBack(func(nodeID string, origin graph.SourceOrigin, content []byte) error {
+		gotID, gotOrigin, gotContent = nodeID, origin, append([]byte(nil), content...)
+		return nil
+	})
+
+	// O_EXCL refuses to clobber an existing construct's source.
+	_, err := gfs.OpenFile("/functions/Foo/source", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	assert.ErrorIs(t, err, os.ErrExist)
+
+	// Only a new directory beside existing constructs can be made.
+	assert.ErrorIs(t, gfs.MkdirAll("/nowhere/Bar", 0o755), os.ErrPermission)
+	_, err = gfs.Create("/functions/Bar/source")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, gfs.MkdirAll("/functions/Bar", 0o755))
+	info, err := gfs.Stat("/functions/Bar")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	_, err = gfs.Create("/functions/Bar/notes")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	f, err := gfs.Create("/functions/Bar/source")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	_, err = gfs.OpenFile("/functions/Bar/source", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	assert.ErrorIs(t, err, os.ErrExist)
+
+	infos, err := gfs.ReadDir("/functions")
+	require.NoError(t, err)
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	assert.Contains(t, names, "Bar")
+	infos, err = gfs.ReadDir("/functions/Bar")
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "source", infos[0].Name())
+
+	// The write lands as an insertion at the end of the sibling's file.
+	f, err = gfs.OpenFile("/functions/Bar/source", os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("func Bar() {}\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, "/functions/Bar/source", gotID)
+	assert.Equal(t, graph.SourceOrigin{FilePath: src, StartByte: 28, EndByte: 28}, gotOrigin)
+	assert.Equal(t, "func Bar() {}\n", string(gotContent))
+
+	// Once written, the pending entries give way to the re-ingested nodes.
+	_, err = gfs.Stat("/functions/Bar")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGeneratedNodesReadOnly(t *testing.T) {
+	store := newTestGraph()
+	store.AddNode(&graph.Node{
+		ID:         "vulns/CVE-2024-0001.json",
+		Data:       []byte(`{}`),
+		Origin:     &graph.SourceOrigin{FilePath: "/tmp/test-source.json", EndByte: 2},
+		Properties: map[string][]byte{"generated": []byte("true")},
+	})
+
+	gfs := NewGraphFS(store, newTestSchema())
+	gfs.SetWriteBack(func(string, graph.SourceOrigin, []byte) error {
+		t.Fatal("write-back must not run for generated nodes")
+		return nil
+	})
+
+	_, err := gfs.OpenFile("/vulns/CVE-2024-0001.json", os.O_RDWR, 0)
+	assert.ErrorIs(t, err, os.ErrPermission)
+	_, err = gfs.Create("/vulns/CVE-2024-0001.json")
+	assert.ErrorIs(t, err, os.ErrPermission)
+	assert.ErrorIs(t, gfs.Remove("/vulns/CVE-2024-0001.json"), os.ErrPermission)
+
+	info, err := gfs.Stat("/vulns/CVE-2024-0001.json")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o444), info.Mode().Perm())
+
+	_, err = gfs.Open("/vulns/CVE-2024-0001.json")
+	require.NoError(t, err)
+}
+
 func TestWritableCapabilities(t *testing.T) {
 	gfs := NewGraphFS(newTestGraph(), newTestSchema())
 
@@ -308,6 +603,233 @@ func TestRemoveWithWriteBack(t *testing.T) {
 	assert.Empty(t, deletedContent) // splice with empty content = delete
 }
 
+// newSiblingsGraph projects Foo and Bar, two functions of one source file.
+func newSiblingsGraph(t *testing.T) (*graph.MemoryStore, string) {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(src, []byte("package main\n\nfunc Foo() {}\n\nfunc Bar() {}\n"), 0o644))
+
+	store := graph.NewMemoryStore()
+	store.AddRoot(&graph.Node{ID: "functions", Mode: fs.ModeDir, Children: []string{"functions/Foo", "functions/Bar"}})
+	for name, start := range map[string]uint32{"Foo": 14, "Bar": 29} {
+		store.AddNode(&graph.Node{ID: "functions/" + name, Mode: fs.ModeDir, Children: []string{"functions/" + name + "/source"}})
+		store.AddNode(&graph.Node{
+			ID:     "functions/" + name + "/source",
+			Data:   []byte("func " + name + "() {}"),
+			Origin: &graph.SourceOrigin{FilePath: src, StartByte: start, EndByte: start + 13},
+		})
+	}
+	return store, src
+}
+
+// spliceWriteBack is the mount's source write-back without validation or
+// formatting: splice, shift the constructs after it, update the node.
+func spliceWriteBack(store *graph.MemoryStore) WriteBackFunc {
+	return func(nodeID string, origin graph.SourceOrigin, content []byte) error {
+		spliced, err := writeback.Splice(origin, content)
+		if err != nil {
+			return err
+		}
+		written := spliced.Content
+		if delta := int32(len(written)) - int32(origin.EndByte-origin.StartByte); delta != 0 {
+			store.ShiftOrigins(origin.FilePath, origin.EndByte, delta)
+		}
+		newOrigin := &graph.SourceOrigin{FilePath: origin.FilePath, StartByte: origin.StartByte, EndByte: origin.StartByte + uint32(len(written))}
+		return store.UpdateNodeContent(nodeID, written, newOrigin, time.Now())
+	}
+}
+
+func writeSource(gfs *GraphFS, id, content string) error {
+	f, err := gfs.OpenFile(id, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write([]byte(content)); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func TestWriteBack_StaleOriginFailsWithEAGAIN(t *testing.T) {
+	store, src := newSiblingsGraph(t)
+	gfs := NewGraphFS(store, newTestSchema())
+	gfs.SetWriteBack(spliceWriteBack(store))
+
+	foo, err := gfs.OpenFile("/functions/Foo/source", os.O_WRONLY, 0)
+	require.NoError(t, err)
+	bar, err := gfs.OpenFile("/functions/Bar/source", os.O_WRONLY, 0)
+	require.NoError(t, err)
+
+	_, err = foo.Write([]byte("func Foo() { println(1) }"))
+	require.NoError(t, err)
+	require.NoError(t, foo.Close())
+
+	// Bar moved when Foo grew; splicing at the offsets bar was opened
+	// with would cut into Foo.
+	_, err = bar.Write([]byte("func Bar() { println(2) }"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, bar.Close(), syscall.EAGAIN)
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc Foo() { println(1) }\n\nfunc Bar() {}\n", string(data))
+
+	// A retry opens at the shifted origin.
+	require.NoError(t, writeSource(gfs, "/functions/Bar/source", "func Bar() { println(2) }"))
+	data, err = os.ReadFile(src)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc Foo() { println(1) }\n\nfunc Bar() { println(2) }\n", string(data))
+}
+
+func TestWriteBack_SequentialCRLFEdits(t *testing.T) {
+	store, src := newSiblingsGraph(t)
+	require.NoError(t, os.WriteFile(src, []byte("package main\r\n\r\nfunc Foo() {}\r\n\r\nfunc Bar() {}\r\n"), 0o644))
+	for name, start := range map[string]uint32{"Foo": 16, "Bar": 33} {
+		node, err := store.GetNode("functions/" + name + "/source")
+		require.NoError(t, err)
+		node.Origin.StartByte, node.Origin.EndByte = start, start+13
+	}
+	gfs := NewGraphFS(store, newTestSchema())
+	gfs.SetWriteBack(spliceWriteBack(store))
+
+	// Formatter-style LF edits grow by a CR per line once spliced, and
+	// every later edit must still land on its construct.
+	require.NoError(t, writeSource(gfs, "/functions/Foo/source", "func Foo() {\n\tprintln(1)\n}"))
+	require.NoError(t, writeSource(gfs, "/functions/Bar/source", "func Bar() {\n\tprintln(2)\n}"))
+	require.NoError(t, writeSource(gfs, "/functions/Foo/source", "func Foo() {\n}"))
+
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\r\n\r\nfunc Foo() {\r\n}\r\n\r\nfunc Bar() {\r\n\tprintln(2)\r\n}\r\n", string(data))
+	for _, name := range []string{"Foo", "Bar"} {
+		node, err := store.GetNode("functions/" + name + "/source")
+		require.NoError(t, err)
+		assert.Equal(t, string(node.Data), string(data[node.Origin.StartByte:node.Origin.EndByte]), "%s's origin tracks the file", name)
+	}
+}
+
+func TestWriteBack_ConcurrentSiblings(t *testing.T) {
+	store, src := newSiblingsGraph(t)
+	gfs := NewGraphFS(store, newTestSchema())
+	gfs.SetWriteBack(spliceWriteBack(store))
+
+	const rounds = 50
+	body := func(name string, i int) string {
+		// Vary the length so every write shifts the construct after it.
+		return fmt.Sprintf("func %s() { println(%q) }", name, strings.Repeat("x", i%7))
+	}
+	var wg sync.WaitGroup
+	for _, name := range []string{"Foo", "Bar"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				for {
+					err := writeSource(gfs, "/functions/"+name+"/source", body(name, i))
+					if !errors.Is(err, syscall.EAGAIN) {
+						assert.NoError(t, err)
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	foo, bar := body("Foo", rounds-1), body("Bar", rounds-1)
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\n"+foo+"\n\n"+bar+"\n", string(data))
+	for name, want := range map[string]string{"Foo": foo, "Bar": bar} {
+		node, err := store.GetNode("functions/" + name + "/source")
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data[node.Origin.StartByte:node.Origin.EndByte]), "%s's origin tracks the file", name)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// /_events tests
+// ---------------------------------------------------------------------------
+
+func TestEvents_WriteBackPublishes(t *testing.T) {
+	store := newTestGraph()
+	store.AddNode(&graph.Node{
+		ID:     "vulns/CVE-2024-0001.json",
+		Data:   []byte(`test`),
+		Origin: &graph.SourceOrigin{FilePath: "/tmp/test.json", EndByte: 4},
+	})
+	gfs := NewGraphFS(store, newTestSchema())
+
+	_, err := gfs.Stat("/_events")
+	assert.Error(t, err, "read-only mounts have no events")
+
+	gfs.SetWriteBack(func(string, graph.SourceOrigin, []byte) error { return nil })
+	info, err := gfs.Stat("/_events")
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	f, err := gfs.OpenFile("/vulns/CVE-2024-0001.json", os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("edited"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.NoError(t, gfs.Remove("/vulns/CVE-2024-0001.json"))
+	gfs.PublishEvent(EventInvalidate, "vulns/CVE-2024-0002.json")
+
+	want := "update /vulns/CVE-2024-0001.json\n" +
+		"remove /vulns/CVE-2024-0001.json\n" +
+		"invalidate /vulns/CVE-2024-0002.json\n"
+	info, err = gfs.Stat("/_events")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(want)), info.Size())
+
+	ev, err := gfs.Open("/_events")
+	require.NoError(t, err)
+	buf := make([]byte, len(want))
+	n, err := ev.ReadAt(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, want, string(buf[:n]))
+
+	infos, err := gfs.ReadDir("/")
+	require.NoError(t, err)
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	assert.Contains(t, names, "_events")
+
+	_, err = gfs.OpenFile("/_events", os.O_WRONLY, 0)
+	assert.Error(t, err)
+}
+
+func TestEvents_ReadAtEndWaitsForNextEvent(t *testing.T) {
+	l := newEventLog()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		l.publish(EventUpdate, "a/source")
+	}()
+	buf := make([]byte, 64)
+	n := l.readAt(buf, 0, 5*time.Second)
+	assert.Equal(t, "update /a/source\n", string(buf[:n]))
+
+	start := time.Now()
+	assert.Zero(t, l.readAt(buf, int64(n), 10*time.Millisecond), "no event: returns empty after the wait")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestEvents_LaggingReaderStaysLineAligned(t *testing.T) {
+	l := newEventLog()
+	for i := 0; i < eventWindow/16+10; i++ {
+		l.publish(EventUpdate, fmt.Sprintf("n/%06d", i))
+	}
+	size, _ := l.stat()
+	require.Greater(t, l.start, int64(0), "the oldest events left the window")
+	assert.LessOrEqual(t, size-l.start, int64(eventWindow))
+
+	buf := make([]byte, 64)
+	n := l.readAt(buf, l.start-3, 0)
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "\n\n\nupdate /n/"), "lost bytes read as blank lines: %q", buf[:n])
+}
+
 // ---------------------------------------------------------------------------
 // _diagnostics/ virtual directory tests
 // ---------------------------------------------------------------------------
@@ -419,10 +941,34 @@ func TestDiagnostics_InReadDirListing(t *testing.T) {
 	assert.Contains(t, names, "_diagnostics")
 }
 
+func TestSetHidden(t *testing.T) {
+	gfs := NewGraphFS(newTestGraph(), newTestSchema())
+	gfs.SetWriteBack(func(string, graph.SourceOrigin, []byte) error { return nil })
+	gfs.SetHidden([]string{"_schema.json", "_diagnostics", graph.EventsFile})
+
+	names := func(dir string) []string {
+		entries, err := gfs.ReadDir(dir)
+		require.NoError(t, err)
+		out := make([]string, len(entries))
+		for i, e := range entries {
+			out[i] = e.Name()
+		}
+		return out
+	}
+	assert.Equal(t, []string{"vulns"}, names("/"))
+	assert.ElementsMatch(t, []string{"CVE-2024-0001.json", "CVE-2024-0002.json"}, names("/vulns"))
+
+	// Hidden entries stay reachable by path.
+	_, err := gfs.Stat("/_schema.json")
+	assert.NoError(t, err)
+	_, err = gfs.ReadDir("/vulns/_diagnostics")
+	assert.NoError(t, err)
+}
+
 func TestNFSServerStarts(t *testing.T) {
 	gfs := NewGraphFS(newTestGraph(), newTestSchema())
 
-	srv, err := NewServer(gfs)
+	srv, err := NewServer(gfs, "")
 	require.NoError(t, err)
 	defer func() { _ = srv.Close() }()
 
@@ -434,6 +980,23 @@ func TestNFSServerStarts(t *testing.T) {
 	_ = conn.Close()
 }
 
+func TestNFSServer_FixedAddr(t *testing.T) {
+	// Reserve a free port, then ask for it explicitly.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	require.NoError(t, ln.Close())
+
+	srv, err := NewServer(NewGraphFS(newTestGraph(), newTestSchema()), fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	defer func() { _ = srv.Close() }()
+	assert.Equal(t, port, srv.Port())
+	assert.Equal(t, "localhost", srv.MountHost())
+
+	_, err = NewServer(NewGraphFS(newTestGraph(), newTestSchema()), "127.0.0.1")
+	assert.Error(t, err, "an address without a port is rejected")
+}
+
 func TestDraftMode(t *testing.T) {
 	store := newTestGraph()
 	// Add a writable node
@@ -561,6 +1124,19 @@ func TestCallers_ReadDir(t *testing.T) {
 	assert.Equal(t, int64(20), entries[0].Size())
 }
 
+func TestLazyFileInfo_SizeComputedOnce(t *testing.T) {
+	calls := 0
+	fi := newLazyFileInfo("/a/b", 0o444, time.Time{}, func() int64 {
+		calls++
+		return 42
+	})
+	assert.Equal(t, "b", fi.Name())
+	assert.Zero(t, calls, "size must not be resolved eagerly")
+	assert.Equal(t, int64(42), fi.Size())
+	assert.Equal(t, int64(42), fi.Size())
+	assert.Equal(t, 1, calls)
+}
+
 func TestCallers_InParentReadDir(t *testing.T) {
 	gfs := NewGraphFS(newTestGraphWithCallers(), newTestSchema())
 
@@ -623,14 +1199,14 @@ func TestUniqueFileids(t *testing.T) {
 	normalize := func(p string) string { return filepath.Clean(p) }
 	checkEntries := func(dir string, entries []os.FileInfo) {
 		for _, e := range entries {
-			fi, ok := e.(*staticFileInfo)
-			require.True(t, ok, "entry %s should be *staticFileInfo", e.Name())
-			assert.NotZero(t, fi.ino, "entry %s in %s has ino=0", e.Name(), dir)
+			st, ok := e.Sys().(*syscall.Stat_t)
+			require.True(t, ok, "entry %s should expose *syscall.Stat_t", e.Name())
+			assert.NotZero(t, st.Ino, "entry %s in %s has ino=0", e.Name(), dir)
 			key := normalize(dir + "/" + e.Name())
-			if prev, dup := seen[fi.ino]; dup && prev != key {
-				t.Errorf("duplicate ino %d: %s and %s", fi.ino, key, prev)
+			if prev, dup := seen[st.Ino]; dup && prev != key {
+				t.Errorf("duplicate ino %d: %s and %s", st.Ino, key, prev)
 			}
-			seen[fi.ino] = key
+			seen[st.Ino] = key
 		}
 	}
 
@@ -739,7 +1315,7 @@ func TestHotSwapNFSProtocol(t *testing.T) {
 	// Wrap GraphFS in a logging proxy to track ReadDir calls.
 	spy := &readDirSpy{GraphFS: gfs}
 
-	srv, err := NewServer(spy)
+	srv, err := NewServer(spy, "")
 	require.NoError(t, err)
 	defer func() { _ = srv.Close() }()
 
@@ -802,3 +1378,103 @@ func infoNames(entries []os.FileInfo) []string {
 	}
 	return names
 }
+
+// newTestGraphWithConstructs is two function constructs of package pkg,
+// defined so the flat and group views list them.
+func newTestGraphWithConstructs(t *testing.T) *graph.MemoryStore {
+	store := graph.NewMemoryStore()
+	store.AddRoot(&graph.Node{ID: "pkg", Mode: fs.ModeDir, Children: []string{"pkg/functions"}})
+	store.AddNode(&graph.Node{ID: "pkg/functions", Mode: fs.ModeDir, Children: []string{"pkg/functions/Foo", "pkg/functions/Bar"}})
+	for name, src := range map[string]string{"Foo": "func Foo() { Bar() }", "Bar": "func Bar() {}"} {
+		id := "pkg/functions/" + name
+		store.AddNode(&graph.Node{ID: id, Mode: fs.ModeDir, Children: []string{id + "/source"}})
+		store.AddNode(&graph.Node{ID: id + "/source", Data: []byte(src)})
+		require.NoError(t, store.AddDef(name, id))
+	}
+	return store
+}
+
+// readVFile reads a whole file through gfs.
+func readVFile(t *testing.T, gfs *GraphFS, name string) string {
+	t.Helper()
+	f, err := gfs.Open(name)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestAllFunctions_ReadThroughEntry(t *testing.T) {
+	gfs := NewGraphFS(newTestGraphWithConstructs(t), newTestSchema())
+
+	entries, err := gfs.ReadDir("/" + graph.AllFunctionsDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "pkg.Bar", entries[0].Name())
+	assert.False(t, entries[0].IsDir())
+	assert.Equal(t, int64(len("func Bar() {}")), entries[0].Size())
+
+	info, err := gfs.Stat("/" + graph.AllFunctionsDir + "/pkg.Foo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("func Foo() { Bar() }")), info.Size())
+	assert.Equal(t, "func Foo() { Bar() }", readVFile(t, gfs, "/"+graph.AllFunctionsDir+"/pkg.Foo"))
+}
+
+func TestGroupViews_ReadThroughEntry(t *testing.T) {
+	gfs := NewGraphFS(newTestGraphWithConstructs(t), newTestSchema())
+
+	entries, err := gfs.ReadDir("/pkg/functions/" + graph.SortedBySizeDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "1-Foo", entries[0].Name())
+	assert.False(t, entries[0].IsDir())
+	assert.Equal(t, int64(len("func Foo() { Bar() }")), entries[0].Size())
+
+	info, err := gfs.Stat("/pkg/functions/" + graph.RecentDir + "/2-Foo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("func Foo() { Bar() }")), info.Size())
+	assert.Equal(t, "func Bar() {}", readVFile(t, gfs, "/pkg/functions/"+graph.SortedBySizeDir+"/2-Bar"))
+}
+
+func TestTypesUsed_ReadThroughEntry(t *testing.T) {
+	store := newTestGraphWithConstructs(t)
+	store.AddNode(&graph.Node{ID: "pkg/types", Mode: fs.ModeDir, Children: []string{"pkg/types/User"}})
+	store.AddNode(&graph.Node{ID: "pkg/types/User", Mode: fs.ModeDir, Children: []string{"pkg/types/User/source"}})
+	store.AddNode(&graph.Node{ID: "pkg/types/User/source", Data: []byte("type User struct{}")})
+	require.NoError(t, store.AddDef("User", "pkg/types/User"))
+	require.NoError(t, store.AddRef(graph.TypeRefPrefix+"User", "pkg/functions/Foo/source"))
+	gfs := NewGraphFS(store, newTestSchema())
+
+	entries, err := gfs.ReadDir("/pkg/functions/Foo/" + graph.TypesUsedDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "pkg_types_User", entries[0].Name())
+	assert.False(t, entries[0].IsDir())
+	assert.Equal(t, int64(len("type User struct{}")), entries[0].Size())
+
+	info, err := gfs.Stat("/pkg/functions/Foo/" + graph.TypesUsedDir + "/pkg_types_User")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("type User struct{}")), info.Size())
+	assert.Equal(t, "type User struct{}", readVFile(t, gfs, "/pkg/functions/Foo/"+graph.TypesUsedDir+"/pkg_types_User"))
+}
+
+func TestRaw_ReadThroughEntry(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "foo.go")
+	full := "package pkg\n\nfunc Foo() {}\n"
+	require.NoError(t, os.WriteFile(src, []byte(full), 0o644))
+
+	store := graph.NewMemoryStore()
+	store.AddRoot(&graph.Node{ID: "Foo", Mode: fs.ModeDir, Children: []string{"Foo/source"}})
+	store.AddNode(&graph.Node{
+		ID:     "Foo/source",
+		Data:   []byte("func Foo() {}"),
+		Origin: &graph.SourceOrigin{FilePath: src, StartByte: 13, EndByte: 26},
+	})
+	gfs := NewGraphFS(store, newTestSchema())
+
+	info, err := gfs.Stat("/Foo/" + graph.RawFile)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(full)), info.Size())
+	assert.Equal(t, full, readVFile(t, gfs, "/Foo/"+graph.RawFile))
+}
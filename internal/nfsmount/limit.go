@@ -0,0 +1,29 @@
+package nfsmount
+
+// readLimit bounds how many content reads run at once: each takes a slot
+// for the length of its graph.ReadContent, which may render a template or
+// parse a source file. A nil readLimit is unlimited.
+type readLimit chan struct{}
+
+// newReadLimit returns a limit of n concurrent reads; nil (unlimited) when
+// n is zero or less.
+func newReadLimit(n int) readLimit {
+	if n <= 0 {
+		return nil
+	}
+	return make(readLimit, n)
+}
+
+// acquire takes a slot, blocking while all are taken.
+func (l readLimit) acquire() {
+	if l != nil {
+		l <- struct{}{}
+	}
+}
+
+// release returns a slot taken by acquire.
+func (l readLimit) release() {
+	if l != nil {
+		<-l
+	}
+}
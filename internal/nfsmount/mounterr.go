@@ -0,0 +1,86 @@
+package nfsmount
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// serveHint points at the way to use mache without a mount at all.
+const serveHint = "To skip mounting, `mache serve` exposes the same projection over MCP."
+
+// checkMountpoint fails early, before sudo asks for a password, when
+// mountpoint can't be mounted on.
+func checkMountpoint(mountpoint string) error {
+	info, err := os.Stat(mountpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("mount point %s does not exist; create it with: mkdir -p %s", mountpoint, mountpoint)
+	}
+	if err != nil {
+		return fmt.Errorf("mount point %s: %w", mountpoint, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("mount point %s is not a directory", mountpoint)
+	}
+	return nil
+}
+
+// mountFailure reports a failed mount command with its output and, when
+// the cause is recognized, what to do about it.
+func mountFailure(goos, mountpoint string, err error, output string) error {
+	var detail string
+	if out := strings.TrimSpace(output); out != "" {
+		detail += "\n" + out
+	}
+	if hint := mountHint(goos, mountpoint, err, output); hint != "" {
+		detail += "\n" + hint
+	}
+	return fmt.Errorf("mount failed: %w%s", err, detail)
+}
+
+// mountHint maps the failure of `sudo mount -t nfs` to a suggested fix, or
+// "" when the cause isn't one it knows.
+func mountHint(goos, mountpoint string, err error, output string) string {
+	out := strings.ToLower(output)
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return "sudo was not found, and mounting NFS needs root. Run mache as root or install sudo. " + serveHint
+	case containsAny(out, "a terminal is required", "a password is required", "no tty present"):
+		return "sudo needs a password but has no terminal to ask on. Run mache from an interactive shell, or allow passwordless sudo for mount and umount. " + serveHint
+	case containsAny(out, "need a /sbin/mount.", "mount.nfs: not found", "unknown filesystem type 'nfs'"):
+		return "The NFS client is not installed. Install nfs-common (Debian, Ubuntu) or nfs-utils (Fedora, RHEL, Arch), then retry. " + serveHint
+	case containsAny(out, "resource busy", "already mounted"):
+		return fmt.Sprintf("%s is busy or already mounted. Unmount it first: sudo umount %s", mountpoint, mountpoint)
+	case containsAny(out, "connection refused", "program not registered", "port mapper failure", "timed out"):
+		return "The kernel NFS client could not reach mache's server on localhost. A firewall, VPN, or sandbox blocking loopback TCP is the usual cause."
+	case containsAny(out, "operation not permitted", "permission denied", "not permitted"):
+		if goos == "linux" {
+			return "The kernel refused the mount. Inside a container, NFS mounts need CAP_SYS_ADMIN (e.g. docker run --privileged). " + serveHint
+		}
+		return "macOS refused the mount. Mount on a directory you own, and check that the terminal running mache is allowed to access it under System Settings > Privacy & Security. " + serveHint
+	}
+	return ""
+}
+
+// listenFailure explains why the NFS server could not listen on loopback.
+func listenFailure(err error) error {
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		return fmt.Errorf("nfs listen: %w (no free loopback port; close other servers or retry)", err)
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return fmt.Errorf("nfs listen: %w (listening on 127.0.0.1 is blocked, likely by a sandbox or firewall). %s", err, serveHint)
+	}
+	return fmt.Errorf("nfs listen: %w", err)
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,30 +3,63 @@ package nfsmount
 import (
 	"fmt"
 	"net"
+	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	billy "github.com/go-git/go-billy/v5"
 	nfs "github.com/willscott/go-nfs"
 	nfshelper "github.com/willscott/go-nfs/helpers"
 )
 
+// handleCacheSize bounds the NFS file handle cache. Every entry returned by
+// READDIRPLUS gets a handle, and a client paging through a directory keeps
+// using handles from earlier pages, so the cache must outlast the largest
+// listing (e.g. hundreds of thousands of CVEs under vulns/) or those handles
+// go stale mid-scan. Entries are a UUID plus a path, allocated on demand.
+const handleCacheSize = 1 << 20
+
+// listingCacheSize bounds how many directory listings are kept for paged
+// READDIR continuations. Each holds a full listing, so keep it small.
+const listingCacheSize = 256
+
 // Server manages the NFS server lifecycle.
 type Server struct {
 	listener net.Listener
 	port     int
 }
 
-// NewServer starts an NFS server on an ephemeral port backed by the given filesystem.
-func NewServer(fs billy.Filesystem) (*Server, error) {
-	listener, err := net.Listen("tcp", "127.0.0.1:0")
+// DefaultAddr is where the NFS server listens unless told otherwise:
+// loopback only, on an ephemeral port.
+const DefaultAddr = "127.0.0.1:0"
+
+// NewServer starts an NFS server backed by the given filesystem, listening
+// on addr ("host:port"; empty for DefaultAddr). A privileged port the
+// process may not bind is rejected up front.
+func NewServer(fs billy.Filesystem, addr string) (*Server, error) {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	_, portStr, err := net.SplitHostPort(addr)
 	if err != nil {
-		return nil, fmt.Errorf("nfs listen: %w", err)
+		return nil, fmt.Errorf("nfs listen address %q: %w", addr, err)
+	}
+	if p, err := strconv.Atoi(portStr); err == nil {
+		if err := checkListenPort(runtime.GOOS, p); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, listenFailure(err)
 	}
 	port := listener.Addr().(*net.TCPAddr).Port
 
 	handler := nfshelper.NewNullAuthHandler(fs)
-	cacheHelper := nfshelper.NewCachingHandler(handler, 4096)
+	cacheHelper := nfshelper.NewCachingHandlerWithVerifierLimit(handler, handleCacheSize, listingCacheSize)
 
 	go func() {
 		_ = nfs.Serve(listener, cacheHelper)
@@ -40,24 +73,69 @@ func (s *Server) Port() int {
 	return s.port
 }
 
+// MountHost returns the host the local mount command should reach the
+// server at: localhost for loopback and wildcard listeners, else the
+// listening interface's address.
+func (s *Server) MountHost() string {
+	ip := s.listener.Addr().(*net.TCPAddr).IP
+	switch {
+	case ip == nil || ip.IsLoopback() || ip.IsUnspecified():
+		return "localhost"
+	case ip.To4() == nil:
+		return "[" + ip.String() + "]"
+	}
+	return ip.String()
+}
+
 // Close stops the NFS server by closing the listener.
 func (s *Server) Close() error {
 	return s.listener.Close()
 }
 
+// CacheTimeouts controls how long the kernel NFS client may cache attributes
+// before revalidating with the server. The zero value disables attribute
+// caching (noac), which write-back and hot-swapped graphs need so changes
+// are visible immediately. Read-only mounts of a static tree can raise the
+// timeouts to avoid a LOOKUP/GETATTR round-trip on every path component.
+// Values are rounded up to whole seconds, the NFS option granularity.
+type CacheTimeouts struct {
+	// Attr bounds file attribute caching (acregmin/acregmax).
+	Attr time.Duration
+	// Entry bounds directory attribute caching, which also governs how long
+	// cached lookups and listings are trusted (acdirmin/acdirmax).
+	Entry time.Duration
+}
+
+// mountOpt renders the timeouts as NFS mount options.
+func (c CacheTimeouts) mountOpt() string {
+	if c.Attr <= 0 && c.Entry <= 0 {
+		return "noac"
+	}
+	attr, entry := ceilSeconds(c.Attr), ceilSeconds(c.Entry)
+	return fmt.Sprintf("acregmin=%d,acregmax=%d,acdirmin=%d,acdirmax=%d", attr, attr, entry, entry)
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
+
 // BuildMountOpts returns the NFS mount options string for the given OS.
-func BuildMountOpts(goos string, port int, writable bool, extraOpts string) (string, error) {
+func BuildMountOpts(goos string, port int, writable bool, cache CacheTimeouts, extraOpts string) (string, error) {
 	var opts string
 	switch goos {
 	case "darwin":
-		// noac: disable attribute caching so dynamic graph changes (new tabs, schema updates)
-		// are visible immediately. Without this, macOS NFS client caches empty dir listings.
-		opts = fmt.Sprintf("port=%d,mountport=%d,vers=3,tcp,locallocks,noresvport,noac", port, port)
+		// noac (zero CacheTimeouts): disable attribute caching so dynamic graph
+		// changes (new tabs, schema updates) are visible immediately. Without
+		// this, macOS NFS client caches empty dir listings.
+		opts = fmt.Sprintf("port=%d,mountport=%d,vers=3,tcp,locallocks,noresvport,%s", port, port, cache.mountOpt())
 		if !writable {
 			opts += ",rdonly"
 		}
 	case "linux":
-		opts = fmt.Sprintf("port=%d,mountport=%d,vers=3,tcp,local_lock=all,nolock,noac", port, port)
+		opts = fmt.Sprintf("port=%d,mountport=%d,vers=3,tcp,local_lock=all,nolock,%s", port, port, cache.mountOpt())
 		if !writable {
 			opts += ",ro"
 		}
@@ -70,22 +148,29 @@ func BuildMountOpts(goos string, port int, writable bool, extraOpts string) (str
 	return opts, nil
 }
 
-// Mount calls the system mount command to mount the NFS server at mountpoint.
+// Mount calls the system mount command to mount the NFS server at
+// host:port (see Server.MountHost) on mountpoint.
 // Requires sudo on macOS. The writable flag controls read-only vs read-write.
+// cache sets the client attribute cache lifetimes (zero value: noac).
 // extraOpts is appended verbatim to the mount options string (comma-separated).
-func Mount(port int, mountpoint string, writable bool, extraOpts string) error {
-	opts, err := BuildMountOpts(runtime.GOOS, port, writable, extraOpts)
+// Failures with a known cause (no NFS client, sudo without a terminal, a busy
+// mount point) carry a hint at the fix.
+func Mount(host string, port int, mountpoint string, writable bool, cache CacheTimeouts, extraOpts string) error {
+	opts, err := BuildMountOpts(runtime.GOOS, port, writable, cache, extraOpts)
 	if err != nil {
 		return err
 	}
+	if err := checkMountpoint(mountpoint); err != nil {
+		return err
+	}
 
 	cmd := exec.Command("sudo", "mount", "-t", "nfs",
 		"-o", opts,
-		"localhost:/", mountpoint)
+		host+":/", mountpoint)
 	cmd.Stdin = nil // sudo may need terminal for password
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("mount failed: %w\n%s", err, string(output))
+		return mountFailure(runtime.GOOS, mountpoint, err, string(output))
 	}
 	return nil
 }
@@ -113,3 +198,45 @@ func Unmount(mountpoint string) error {
 	}
 	return nil
 }
+
+// capNetBindService is the CAP_NET_BIND_SERVICE bit in a capability mask.
+const capNetBindService = 1 << 10
+
+// checkListenPort rejects port when it is privileged on goos and the
+// process lacks the permission to bind it, so the mount fails with a clear
+// message instead of a bare EACCES. Ports outside 0-65535 are rejected too.
+func checkListenPort(goos string, port int) error {
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("nfs port %d out of range (0-65535)", port)
+	}
+	// macOS lets any user bind low ports; only Linux guards them.
+	if goos != "linux" || port == 0 || os.Geteuid() == 0 {
+		return nil
+	}
+	start := 1024
+	if data, err := os.ReadFile("/proc/sys/net/ipv4/ip_unprivileged_port_start"); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			start = n
+		}
+	}
+	if port >= start || effectiveCaps()&capNetBindService != 0 {
+		return nil
+	}
+	return fmt.Errorf("nfs port %d is privileged (below %d): run as root, grant CAP_NET_BIND_SERVICE, or pick a port of %d or above", port, start, start)
+}
+
+// effectiveCaps returns the process's effective capability mask on Linux,
+// or 0 when it can't be read.
+func effectiveCaps() uint64 {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "CapEff:"); ok {
+			caps, _ := strconv.ParseUint(strings.TrimSpace(v), 16, 64)
+			return caps
+		}
+	}
+	return 0
+}
@@ -1,15 +1,20 @@
 package nfsmount
 
 import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestBuildMountOpts_Darwin_ReadOnly(t *testing.T) {
-	opts, err := BuildMountOpts("darwin", 12345, false, "")
+	opts, err := BuildMountOpts("darwin", 12345, false, CacheTimeouts{}, "")
 	require.NoError(t, err)
 	assert.Contains(t, opts, "port=12345")
 	assert.Contains(t, opts, "mountport=12345")
@@ -20,14 +25,14 @@ func TestBuildMountOpts_Darwin_ReadOnly(t *testing.T) {
 }
 
 func TestBuildMountOpts_Darwin_Writable(t *testing.T) {
-	opts, err := BuildMountOpts("darwin", 9999, true, "")
+	opts, err := BuildMountOpts("darwin", 9999, true, CacheTimeouts{}, "")
 	require.NoError(t, err)
 	assert.NotContains(t, opts, "rdonly")
 	assert.Contains(t, opts, "port=9999")
 }
 
 func TestBuildMountOpts_Linux_ReadOnly(t *testing.T) {
-	opts, err := BuildMountOpts("linux", 8888, false, "")
+	opts, err := BuildMountOpts("linux", 8888, false, CacheTimeouts{}, "")
 	require.NoError(t, err)
 	assert.Contains(t, opts, "ro")
 	assert.Contains(t, opts, "nolock")
@@ -35,7 +40,7 @@ func TestBuildMountOpts_Linux_ReadOnly(t *testing.T) {
 }
 
 func TestBuildMountOpts_ExtraOpts_Appended(t *testing.T) {
-	opts, err := BuildMountOpts("darwin", 5555, false, "rsize=32768,wsize=32768")
+	opts, err := BuildMountOpts("darwin", 5555, false, CacheTimeouts{}, "rsize=32768,wsize=32768")
 	require.NoError(t, err)
 	assert.True(t, strings.HasSuffix(opts, "rsize=32768,wsize=32768"))
 	// Extra opts come after the defaults
@@ -43,14 +48,82 @@ func TestBuildMountOpts_ExtraOpts_Appended(t *testing.T) {
 }
 
 func TestBuildMountOpts_ExtraOpts_Empty(t *testing.T) {
-	withExtra, _ := BuildMountOpts("darwin", 5555, false, "")
-	withoutExtra, _ := BuildMountOpts("darwin", 5555, false, "")
+	withExtra, _ := BuildMountOpts("darwin", 5555, false, CacheTimeouts{}, "")
+	withoutExtra, _ := BuildMountOpts("darwin", 5555, false, CacheTimeouts{}, "")
 	assert.Equal(t, withExtra, withoutExtra)
 	assert.False(t, strings.HasSuffix(withExtra, ","))
 }
 
 func TestBuildMountOpts_UnsupportedOS(t *testing.T) {
-	_, err := BuildMountOpts("windows", 1234, false, "")
+	_, err := BuildMountOpts("windows", 1234, false, CacheTimeouts{}, "")
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "unsupported OS")
 }
+
+func TestBuildMountOpts_CacheTimeouts(t *testing.T) {
+	for _, goos := range []string{"darwin", "linux"} {
+		opts, err := BuildMountOpts(goos, 1234, false, CacheTimeouts{Attr: 1500 * time.Millisecond, Entry: 5 * time.Second}, "")
+		require.NoError(t, err)
+		assert.NotContains(t, opts, "noac")
+		// Sub-second remainders round up to the next whole second.
+		assert.Contains(t, opts, "acregmin=2,acregmax=2,acdirmin=5,acdirmax=5")
+	}
+}
+
+func TestBuildMountOpts_CacheTimeouts_EntryOnly(t *testing.T) {
+	opts, err := BuildMountOpts("linux", 1234, false, CacheTimeouts{Entry: time.Second}, "")
+	require.NoError(t, err)
+	assert.Contains(t, opts, "acregmin=0,acregmax=0,acdirmin=1,acdirmax=1")
+}
+
+func TestMountHint(t *testing.T) {
+	exitErr := errors.New("exit status 32")
+	tests := []struct {
+		goos   string
+		err    error
+		output string
+		want   string
+	}{
+		{"linux", &exec.Error{Name: "sudo", Err: exec.ErrNotFound}, "", "sudo was not found"},
+		{"linux", exitErr, "sudo: a terminal is required to read the password", "no terminal"},
+		{"linux", exitErr, "mount: /mnt/x: bad option; for several filesystems (e.g. nfs, cifs) you might need a /sbin/mount.<type> helper program.", "nfs-common"},
+		{"linux", exitErr, "mount.nfs: /mnt/x is busy or already mounted", "sudo umount /mnt/x"},
+		{"darwin", exitErr, "mount_nfs: can't mount / from localhost onto /mnt/x: Connection refused", "could not reach"},
+		{"linux", exitErr, "mount: /mnt/x: permission denied.", "CAP_SYS_ADMIN"},
+		{"darwin", exitErr, "mount_nfs: can't mount with remote locks when server (localhost) is not running rpc.statd: Operation not permitted", "macOS refused"},
+		{"linux", exitErr, "something else entirely", ""},
+	}
+	for _, tt := range tests {
+		hint := mountHint(tt.goos, "/mnt/x", tt.err, tt.output)
+		if tt.want == "" {
+			assert.Empty(t, hint, tt.output)
+			continue
+		}
+		assert.Contains(t, hint, tt.want, tt.output)
+	}
+
+	err := mountFailure("linux", "/mnt/x", exitErr, "mount.nfs: /mnt/x is busy or already mounted\n")
+	assert.ErrorIs(t, err, exitErr)
+	assert.Equal(t, "mount failed: exit status 32\nmount.nfs: /mnt/x is busy or already mounted\n"+
+		"/mnt/x is busy or already mounted. Unmount it first: sudo umount /mnt/x", err.Error())
+}
+
+func TestMount_MissingMountpoint(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "nope")
+	err := Mount("localhost", 2049, missing, false, CacheTimeouts{}, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mkdir -p "+missing)
+}
+
+func TestCheckListenPort(t *testing.T) {
+	assert.NoError(t, checkListenPort("linux", 0))
+	assert.NoError(t, checkListenPort("linux", 20490))
+	assert.NoError(t, checkListenPort("darwin", 111), "macOS lets any user bind low ports")
+	assert.Error(t, checkListenPort("linux", 70000))
+	assert.Error(t, checkListenPort("darwin", -1))
+	if os.Geteuid() != 0 && effectiveCaps()&capNetBindService == 0 {
+		err := checkListenPort("linux", 111)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "CAP_NET_BIND_SERVICE")
+	}
+}
@@ -0,0 +1,177 @@
+package refsvtab
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"modernc.org/sqlite/vtab"
+)
+
+// NodeRow is one row of the mache_nodes table. Metrics a node lacks are
+// NULL.
+type NodeRow struct {
+	Path       string
+	Kind       string // "dir" or "file"
+	Lang       string
+	Size       int64
+	Lines      sql.NullInt64
+	RefCount   sql.NullInt64
+	Complexity sql.NullInt64
+}
+
+// NodeSource lists a graph's nodes for a mache_nodes scan.
+type NodeSource func() ([]NodeRow, error)
+
+var (
+	nodesOnce      sync.Once
+	nodesSingleton *NodesModule
+	nodesInitErr   error
+)
+
+// NodesModule implements vtab.Module for mache_nodes, a read-only table of
+// a graph's nodes and their metrics. Like RefsModule it is a process-wide
+// singleton; each graph registers the NodeSource its table scans.
+type NodesModule struct {
+	mu      sync.RWMutex
+	sources map[string]NodeSource
+}
+
+// RegisterNodes registers the mache_nodes module with the global SQLite
+// driver. Safe to call multiple times — only the first call registers.
+func RegisterNodes() (*NodesModule, error) {
+	nodesOnce.Do(func() {
+		nodesSingleton = &NodesModule{sources: make(map[string]NodeSource)}
+		if err := vtab.RegisterModule(nil, "mache_nodes", nodesSingleton); err != nil {
+			nodesInitErr = fmt.Errorf("refsvtab: register nodes module: %w", err)
+			nodesSingleton = nil
+		}
+	})
+	return nodesSingleton, nodesInitErr
+}
+
+// RegisterSource registers the node source behind
+// CREATE VIRTUAL TABLE ... USING mache_nodes(id).
+func (m *NodesModule) RegisterSource(id string, src NodeSource) {
+	m.mu.Lock()
+	m.sources[id] = src
+	m.mu.Unlock()
+}
+
+// UnregisterSource removes a node source from the registry.
+func (m *NodesModule) UnregisterSource(id string) {
+	m.mu.Lock()
+	delete(m.sources, id)
+	m.mu.Unlock()
+}
+
+func (m *NodesModule) Create(ctx vtab.Context, args []string) (vtab.Table, error) {
+	// argv: [0]=module, [1]=database, [2]=table, [3]=source ID.
+	if len(args) < 4 {
+		return nil, fmt.Errorf("mache_nodes: missing source ID argument (expected USING mache_nodes(id))")
+	}
+	m.mu.RLock()
+	src, ok := m.sources[args[3]]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("mache_nodes: unknown source ID %q", args[3])
+	}
+
+	if err := ctx.Declare("CREATE TABLE x(path TEXT, kind TEXT, lang TEXT, size INTEGER, lines INTEGER, refcount INTEGER, complexity INTEGER)"); err != nil {
+		return nil, err
+	}
+	return &nodesTable{src: src}, nil
+}
+
+func (m *NodesModule) Connect(ctx vtab.Context, args []string) (vtab.Table, error) {
+	return m.Create(ctx, args)
+}
+
+type nodesTable struct {
+	src NodeSource
+}
+
+// BestIndex always plans a full scan: the source lists every node, and
+// SQLite applies the WHERE clause to the rows.
+func (t *nodesTable) BestIndex(info *vtab.IndexInfo) error {
+	info.IdxNum = 0
+	info.EstimatedCost = 1e6
+	info.EstimatedRows = 1e6
+	return nil
+}
+
+func (t *nodesTable) Open() (vtab.Cursor, error) {
+	return &nodesCursor{table: t}, nil
+}
+
+func (t *nodesTable) Disconnect() error { return nil }
+func (t *nodesTable) Destroy() error    { return nil }
+
+type nodesCursor struct {
+	table *nodesTable
+	rows  []NodeRow
+	pos   int
+}
+
+func (c *nodesCursor) Filter(_ int, _ string, _ []vtab.Value) error {
+	rows, err := c.table.src()
+	if err != nil {
+		return fmt.Errorf("mache_nodes: list nodes: %w", err)
+	}
+	c.rows, c.pos = rows, 0
+	return nil
+}
+
+func (c *nodesCursor) Next() error {
+	c.pos++
+	return nil
+}
+
+func (c *nodesCursor) Eof() bool {
+	return c.pos >= len(c.rows)
+}
+
+func (c *nodesCursor) Column(col int) (vtab.Value, error) {
+	if c.pos >= len(c.rows) {
+		return nil, nil
+	}
+	r := &c.rows[c.pos]
+	switch col {
+	case 0:
+		return r.Path, nil
+	case 1:
+		return r.Kind, nil
+	case 2:
+		if r.Lang == "" {
+			return nil, nil
+		}
+		return r.Lang, nil
+	case 3:
+		return r.Size, nil
+	case 4:
+		return nullable(r.Lines), nil
+	case 5:
+		return nullable(r.RefCount), nil
+	case 6:
+		return nullable(r.Complexity), nil
+	default:
+		return nil, nil
+	}
+}
+
+// nullable returns v's value, or nil (NULL) when it is not set.
+func nullable(v sql.NullInt64) vtab.Value {
+	if !v.Valid {
+		return nil
+	}
+	return v.Int64
+}
+
+func (c *nodesCursor) Rowid() (int64, error) {
+	return int64(c.pos), nil
+}
+
+func (c *nodesCursor) Close() error {
+	c.rows = nil
+	return nil
+}
@@ -14,7 +14,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"maps"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -82,6 +84,35 @@ var Funcs = template.FuncMap{
 	"split": func(s, sep string) []string {
 		return strings.Split(s, sep)
 	},
+	// last/initial: final element and all-but-final elements of a slice, e.g.
+	// {{last (split .mod ".")}} → Bar and {{initial (split .mod ".") | join "/"}}
+	// → Foo for "Foo.Bar". Accepts both []string and []any.
+	"last": func(v any) any {
+		switch s := v.(type) {
+		case []string:
+			if len(s) > 0 {
+				return s[len(s)-1]
+			}
+		case []any:
+			if len(s) > 0 {
+				return s[len(s)-1]
+			}
+		}
+		return nil
+	},
+	"initial": func(v any) any {
+		switch s := v.(type) {
+		case []string:
+			if len(s) > 0 {
+				return s[:len(s)-1]
+			}
+		case []any:
+			if len(s) > 0 {
+				return s[:len(s)-1]
+			}
+		}
+		return v
+	},
 	// join: {{join ", " .parts}} or pipeline {{split .s ":" | join ", "}}.
 	// sep is first so the piped value (slice) arrives as the last arg.
 	// Accepts both []string and []any (JSON-parsed slices).
@@ -184,6 +215,64 @@ var Funcs = template.FuncMap{
 		}
 		return fmt.Sprint(current)
 	},
+	// htmlAttr: attribute value from an HTML start tag, "" when absent.
+	// {{htmlAttr .open "id"}} → "main" from `<div id="main" class="x">`.
+	"htmlAttr": func(tag, name string) string {
+		for _, a := range parseHTMLAttrs(tag) {
+			if strings.EqualFold(a[0], name) {
+				return a[1]
+			}
+		}
+		return ""
+	},
+	// htmlAttrs: all attributes of an HTML start tag as name=value lines.
+	// {{htmlAttrs .open}} → "id=main\nclass=x\n".
+	"htmlAttrs": func(tag string) string {
+		var b strings.Builder
+		for _, a := range parseHTMLAttrs(tag) {
+			b.WriteString(a[0] + "=" + a[1] + "\n")
+		}
+		return b.String()
+	},
+	// htmlText: visible text of an HTML fragment with tags stripped,
+	// entities decoded, and whitespace collapsed.
+	// {{htmlText .scope}} → "hi there" from "<p>hi <b>there</b></p>".
+	"htmlText": func(s string) string {
+		s = htmlTagRe.ReplaceAllString(s, " ")
+		return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+	},
+}
+
+var (
+	// htmlTagRe matches comments and tags, including quoted attribute values
+	// that contain '>'.
+	htmlTagRe = regexp.MustCompile(`(?s)<!--.*?-->|<(?:"[^"]*"|'[^']*'|[^'">])*>`)
+	// htmlAttrRe matches name, name=value, name="value" and name='value'.
+	htmlAttrRe = regexp.MustCompile(`([^\s"'<>/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>]+)))?`)
+)
+
+// parseHTMLAttrs returns the [name, value] pairs of an HTML start tag in
+// source order. Names are lowercased and values entity-decoded. Only the
+// first tag in s is considered; the tag name itself is skipped.
+func parseHTMLAttrs(s string) [][2]string {
+	start := strings.IndexByte(s, '<')
+	if start < 0 {
+		return nil
+	}
+	tag := htmlTagRe.FindString(s[start:])
+	if tag == "" {
+		return nil
+	}
+	tag = strings.TrimRight(strings.TrimSuffix(tag[1:], ">"), "/")
+	fields := htmlAttrRe.FindAllStringSubmatch(tag, -1)
+	if len(fields) < 2 {
+		return nil
+	}
+	attrs := make([][2]string, 0, len(fields)-1)
+	for _, f := range fields[1:] {
+		attrs = append(attrs, [2]string{strings.ToLower(f[1]), html.UnescapeString(f[2] + f[3] + f[4])})
+	}
+	return attrs
 }
 
 // cache stores parsed templates keyed by their source string.
@@ -197,48 +286,73 @@ var bufPool = sync.Pool{
 	New: func() any { return new(bytes.Buffer) },
 }
 
+// Delims are the action delimiters templates are parsed with. The zero
+// value, or an empty field, means Go's default {{ or }}.
+type Delims struct {
+	Left, Right string
+}
+
+// key is the cache key for tmpl parsed with d. Default delimiters key by the
+// template alone so Render and Delims{}.Render share entries.
+func (d Delims) key(tmpl string) string {
+	if (d.Left == "" || d.Left == "{{") && (d.Right == "" || d.Right == "}}") {
+		return tmpl
+	}
+	return d.Left + "\x00" + d.Right + "\x00" + tmpl
+}
+
 // Render renders a Go text/template with the standard mache template functions.
 // Parsed templates are cached — repeated calls with the same template string skip parsing.
 func Render(tmpl string, values map[string]any) (string, error) {
+	return Delims{}.Render(tmpl, values)
+}
+
+// RenderWithFuncs renders a Go text/template with the standard mache
+// template functions plus additional per-engine functions (e.g., {{diagram}}).
+// Templates are cached in the provided cache; the caller must ensure the
+// extraFuncs map is stable for the cache's lifetime.
+func RenderWithFuncs(tmpl string, values map[string]any, extraFuncs template.FuncMap, c *sync.Map) (string, error) {
+	return Delims{}.RenderWithFuncs(tmpl, values, extraFuncs, c)
+}
+
+// Render is the package Render with d's delimiters.
+func (d Delims) Render(tmpl string, values map[string]any) (string, error) {
 	var t *template.Template
-	if cached, ok := cache.Load(tmpl); ok {
+	key := d.key(tmpl)
+	if cached, ok := cache.Load(key); ok {
 		t = cached.(*template.Template)
 	} else {
 		var err error
-		t, err = template.New("").Funcs(Funcs).Parse(tmpl)
+		t, err = template.New("").Delims(d.Left, d.Right).Funcs(Funcs).Parse(tmpl)
 		if err != nil {
 			return "", err
 		}
-		cache.Store(tmpl, t)
-	}
-	buf := bufPool.Get().(*bytes.Buffer)
-	buf.Reset()
-	defer bufPool.Put(buf)
-	if err := t.Execute(buf, values); err != nil {
-		return "", err
+		cache.Store(key, t)
 	}
-	return buf.String(), nil
+	return execute(t, values)
 }
 
-// RenderWithFuncs renders a Go text/template with the standard mache
-// template functions plus additional per-engine functions (e.g., {{diagram}}).
-// Templates are cached in the provided cache; the caller must ensure the
-// extraFuncs map is stable for the cache's lifetime.
-func RenderWithFuncs(tmpl string, values map[string]any, extraFuncs template.FuncMap, c *sync.Map) (string, error) {
+// RenderWithFuncs is the package RenderWithFuncs with d's delimiters.
+func (d Delims) RenderWithFuncs(tmpl string, values map[string]any, extraFuncs template.FuncMap, c *sync.Map) (string, error) {
 	var t *template.Template
-	if cached, ok := c.Load(tmpl); ok {
+	key := d.key(tmpl)
+	if cached, ok := c.Load(key); ok {
 		t = cached.(*template.Template)
 	} else {
 		merged := make(template.FuncMap, len(Funcs)+len(extraFuncs))
 		maps.Copy(merged, Funcs)
 		maps.Copy(merged, extraFuncs)
 		var err error
-		t, err = template.New("").Funcs(merged).Parse(tmpl)
+		t, err = template.New("").Delims(d.Left, d.Right).Funcs(merged).Parse(tmpl)
 		if err != nil {
 			return "", err
 		}
-		c.Store(tmpl, t)
+		c.Store(key, t)
 	}
+	return execute(t, values)
+}
+
+func execute(t *template.Template, values map[string]any) (string, error) {
 	buf := bufPool.Get().(*bytes.Buffer)
 	buf.Reset()
 	defer bufPool.Put(buf)
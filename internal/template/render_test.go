@@ -157,12 +157,48 @@ func TestRender_GoldenPath(t *testing.T) {
 			values: map[string]any{"name": "amazon linux"},
 			want:   "Amazon Linux",
 		},
+		{
+			name:   "last of split",
+			tmpl:   `{{last (split .mod ".")}}`,
+			values: map[string]any{"mod": "MyApp.Accounts.User"},
+			want:   "User",
+		},
+		{
+			name:   "initial of split, joined",
+			tmpl:   `{{initial (split .mod ".") | join "/"}}`,
+			values: map[string]any{"mod": "MyApp.Accounts.User"},
+			want:   "MyApp/Accounts",
+		},
+		{
+			name:   "initial of single element is empty",
+			tmpl:   `{{initial (split .mod ".") | join "/"}}`,
+			values: map[string]any{"mod": "MyApp"},
+			want:   "",
+		},
 		{
 			name:   "pipeline: split then join",
 			tmpl:   `{{split .id ":" | join ", "}}`,
 			values: map[string]any{"id": "alpine:3.18:amd64"},
 			want:   "alpine, 3.18, amd64",
 		},
+		{
+			name:   "htmlAttr quoted, unquoted and missing",
+			tmpl:   `{{htmlAttr .open "id"}}|{{htmlAttr .open "data-x"}}|{{htmlAttr .open "href"}}`,
+			values: map[string]any{"open": `<DIV ID="main" class='a b' data-x=1 hidden>`},
+			want:   "main|1|",
+		},
+		{
+			name:   "htmlAttrs lists attributes in order",
+			tmpl:   `{{htmlAttrs .open}}`,
+			values: map[string]any{"open": `<input name="q" disabled value="a &amp; b"/>`},
+			want:   "name=q\ndisabled=\nvalue=a & b\n",
+		},
+		{
+			name:   "htmlText strips tags and collapses whitespace",
+			tmpl:   `{{htmlText .scope}}`,
+			values: map[string]any{"scope": "<p title=\"a>b\">hi\n  <b>there</b><!-- x --> &lt;3</p>"},
+			want:   "hi there <3",
+		},
 	}
 
 	for _, tt := range tests {
@@ -223,6 +259,26 @@ func TestRenderWithFuncs_ExtraFunctions(t *testing.T) {
 	assert.Equal(t, "hi-zz", got)
 }
 
+func TestDelims_Render(t *testing.T) {
+	d := Delims{Left: "[[", Right: "]]"}
+	got, err := d.Render(`<p>{{ greeting }}</p> [[upper .name]]`, map[string]any{"name": "vue"})
+	require.NoError(t, err)
+	assert.Equal(t, "<p>{{ greeting }}</p> VUE", got)
+
+	// Same source, different delimiters: cached separately.
+	got, err = Render(`[[.name]] {{.name}}`, map[string]any{"name": "x"})
+	require.NoError(t, err)
+	assert.Equal(t, "[[.name]] x", got)
+	got, err = d.Render(`[[.name]] {{.name}}`, map[string]any{"name": "x"})
+	require.NoError(t, err)
+	assert.Equal(t, "x {{.name}}", got)
+
+	got, err = d.RenderWithFuncs(`[[shout .name]]`, map[string]any{"name": "a"},
+		template.FuncMap{"shout": func(s string) string { return s + "!" }}, &sync.Map{})
+	require.NoError(t, err)
+	assert.Equal(t, "a!", got)
+}
+
 // TestRender_ConcurrentSafety exercises the template cache under concurrent access.
 func TestRender_ConcurrentSafety(t *testing.T) {
 	const goroutines = 50
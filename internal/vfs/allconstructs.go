@@ -0,0 +1,192 @@
+package vfs
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// allConstructsTTL bounds how stale the flat index may get after the graph
+// changes (write-back, re-ingest, schema reload).
+const allConstructsTTL = 5 * time.Second
+
+// allConstructKinds maps each root index directory to the schema category
+// directories whose constructs it lists.
+var allConstructKinds = map[string][]string{
+	graph.AllFunctionsDir: {"functions"},
+	graph.AllMethodsDir:   {"methods"},
+	graph.AllTypesDir:     graph.TypeCategories,
+}
+
+// AllConstructsHandler serves the root _all-functions/, _all-types/, and
+// _all-methods/ directories: every construct of that kind in the mount as a
+// <pkg>.<name> symlink to its source leaf, which NFS serves as a file
+// holding the source, like callees/. The index is built lazily from the
+// definition index and requires a graph.DefsProvider.
+type AllConstructsHandler struct {
+	Graph graph.Graph
+
+	mu      sync.Mutex
+	index   map[string]map[string]string // vdir → entry name → source ID
+	builtAt time.Time
+}
+
+// entries returns the entry name → source ID map for one index directory,
+// rebuilding the whole index when it is older than allConstructsTTL.
+func (h *AllConstructsHandler) entries(vdir string) map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.index == nil || time.Since(h.builtAt) > allConstructsTTL {
+		h.index = h.build()
+		h.builtAt = time.Now()
+	}
+	return h.index[vdir]
+}
+
+func (h *AllConstructsHandler) build() map[string]map[string]string {
+	dp, ok := h.Graph.(graph.DefsProvider)
+	if !ok {
+		return nil
+	}
+	kindOf := make(map[string]string)
+	for vdir, categories := range allConstructKinds {
+		for _, c := range categories {
+			kindOf[c] = vdir
+		}
+	}
+
+	seen := make(map[string]bool)
+	var dirIDs []string
+	for _, ids := range dp.DefsMap() {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				dirIDs = append(dirIDs, id)
+			}
+		}
+	}
+	// Sorted, so the first of two constructs sharing a name wins stably.
+	sort.Strings(dirIDs)
+
+	index := make(map[string]map[string]string)
+	for _, id := range dirIDs {
+		prefix, category := graph.ConstructCategory(id)
+		vdir, ok := kindOf[category]
+		if !ok {
+			continue
+		}
+		n, err := h.Graph.GetNode(id)
+		if err != nil {
+			continue
+		}
+		pkg := strings.ReplaceAll(strings.TrimSuffix(prefix, "/"), "/", ".")
+		if p := string(n.Properties["pkg"]); p != "" {
+			pkg = p
+		}
+		name := path.Base(id)
+		if pkg != "" {
+			name = pkg + "." + name
+		}
+		if index[vdir] == nil {
+			index[vdir] = make(map[string]string)
+		}
+		if _, dup := index[vdir][name]; dup {
+			continue
+		}
+		if src := graph.FindSourceChild(h.Graph, id); src != "" {
+			index[vdir][name] = src
+		}
+	}
+	return index
+}
+
+// parseAllConstructsPath splits a root index path into its directory and
+// entry name. ok is false for any other path.
+func parseAllConstructsPath(p string) (vdir, entryName string, ok bool) {
+	rest, found := strings.CutPrefix(p, "/")
+	if !found {
+		return "", "", false
+	}
+	vdir, entryName, _ = strings.Cut(rest, "/")
+	if _, known := allConstructKinds[vdir]; !known || strings.Contains(entryName, "/") {
+		return "", "", false
+	}
+	return vdir, entryName, true
+}
+
+func (h *AllConstructsHandler) Match(path string) bool {
+	_, _, ok := parseAllConstructsPath(path)
+	return ok
+}
+
+func (h *AllConstructsHandler) Stat(path string) *VEntry {
+	vdir, entryName, ok := parseAllConstructsPath(path)
+	if !ok {
+		return nil
+	}
+	entries := h.entries(vdir)
+	if len(entries) == 0 {
+		return nil
+	}
+	if entryName == "" {
+		return &VEntry{Kind: KindDir, Perm: 0o555}
+	}
+	id, ok := entries[entryName]
+	if !ok {
+		return nil
+	}
+	target := graph.VDirSymlinkTarget("/", id)
+	return &VEntry{
+		Kind:    KindSymlink,
+		Size:    int64(len(target)),
+		Perm:    0o777,
+		Content: []byte(target),
+		NodeID:  id,
+	}
+}
+
+func (h *AllConstructsHandler) ReadContent(path string) ([]byte, bool) {
+	entry := h.Stat(path)
+	if entry == nil || entry.Kind != KindSymlink {
+		return nil, false
+	}
+	return entry.Content, true
+}
+
+func (h *AllConstructsHandler) ListDir(path string) ([]DirExtra, bool) {
+	vdir, entryName, ok := parseAllConstructsPath(path)
+	if !ok || entryName != "" {
+		return nil, false
+	}
+	entries := h.entries(vdir)
+	if len(entries) == 0 {
+		return nil, false
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	extras := make([]DirExtra, 0, len(names))
+	for _, name := range names {
+		extras = append(extras, DirExtra{Name: name, Kind: KindSymlink, Perm: 0o777})
+	}
+	return extras, true
+}
+
+func (h *AllConstructsHandler) DirExtras(parentPath string, _ *graph.Node) []DirExtra {
+	if parentPath != "/" {
+		return nil
+	}
+	var extras []DirExtra
+	for _, vdir := range []string{graph.AllFunctionsDir, graph.AllMethodsDir, graph.AllTypesDir} {
+		if len(h.entries(vdir)) > 0 {
+			extras = append(extras, DirExtra{Name: vdir, Kind: KindDir, Perm: 0o555})
+		}
+	}
+	return extras
+}
@@ -2,16 +2,38 @@ package vfs
 
 import (
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/agentic-research/mache/internal/graph"
 )
 
 // CallersHandler serves the virtual callers/ directory and its symlink entries.
+// Entries are named after the flattened caller ID; when the graph records
+// call sites (graph.CallSiteLocator), the source line numbers are appended,
+// e.g. "pkg_main_source:42,57". The bare flattened name still resolves.
 type CallersHandler struct {
 	Graph graph.Graph
 }
 
+// entryName returns the callers/ entry name for caller's references to token.
+func (h *CallersHandler) entryName(token string, caller *graph.Node) string {
+	name := strings.ReplaceAll(caller.ID, "/", "_")
+	loc, ok := h.Graph.(graph.CallSiteLocator)
+	if !ok {
+		return name
+	}
+	lines := loc.CallSiteLines(token, caller.ID)
+	if len(lines) == 0 {
+		return name
+	}
+	strs := make([]string, len(lines))
+	for i, l := range lines {
+		strs[i] = strconv.Itoa(l)
+	}
+	return name + ":" + strings.Join(strs, ",")
+}
+
 func (h *CallersHandler) Match(path string) bool {
 	return graph.IsCallersPath(path)
 }
@@ -34,7 +56,7 @@ func (h *CallersHandler) Stat(path string) *VEntry {
 	}
 	for _, caller := range callers {
 		flatName := strings.ReplaceAll(caller.ID, "/", "_")
-		if flatName == entryName {
+		if flatName == entryName || h.entryName(token, caller) == entryName {
 			target := graph.VDirSymlinkTarget(parentDir, caller.ID)
 			return &VEntry{
 				Kind:    KindSymlink,
@@ -73,9 +95,8 @@ func (h *CallersHandler) ListDir(path string) ([]DirExtra, bool) {
 	}
 	entries := make([]DirExtra, 0, len(callers))
 	for _, c := range callers {
-		flatName := strings.ReplaceAll(c.ID, "/", "_")
 		entries = append(entries, DirExtra{
-			Name: flatName,
+			Name: h.entryName(token, c),
 			Kind: KindSymlink,
 			Perm: 0o777,
 		})
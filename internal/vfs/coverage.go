@@ -0,0 +1,85 @@
+package vfs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// CoverageProfile is the test coverage the _coverage files project.
+// ingest.GoCoverProfile reads it from a Go cover profile.
+type CoverageProfile interface {
+	// Statements counts the statements starting within lines start
+	// through end (1-based, inclusive) of file, and how many of them ran.
+	// ok is false when the profile doesn't cover file.
+	Statements(file string, start, end int) (covered, total int, ok bool)
+}
+
+// CoverageHandler serves the virtual "_coverage" file inside construct
+// directories whose source file the profile covers: the construct's
+// covered and total statements and their percentage, e.g.
+// "3/4 statements (75.0%)". Like _history it maps the source's byte range
+// to the lines of the file as it is now, so the profile should be as
+// fresh as the source. Constructs without statements (types, constants)
+// have none. Coverage is nil (and there are no _coverage files) unless the
+// mount asked for them.
+type CoverageHandler struct {
+	Graph    graph.Graph
+	Coverage CoverageProfile
+}
+
+func (h *CoverageHandler) Match(path string) bool {
+	return h.Coverage != nil && strings.HasSuffix(path, "/"+graph.CoverageFile)
+}
+
+func (h *CoverageHandler) Stat(path string) *VEntry {
+	data, ok := h.ReadContent(path)
+	if !ok {
+		return nil
+	}
+	return &VEntry{
+		Kind:    KindFile,
+		Size:    int64(len(data)),
+		Perm:    0o444,
+		Content: data,
+	}
+}
+
+func (h *CoverageHandler) ReadContent(path string) ([]byte, bool) {
+	return h.coverage(filepath.Dir(path))
+}
+
+func (h *CoverageHandler) ListDir(_ string) ([]DirExtra, bool) {
+	return nil, false
+}
+
+func (h *CoverageHandler) DirExtras(_ string, node *graph.Node) []DirExtra {
+	if h.Coverage == nil || node == nil {
+		return nil
+	}
+	data, ok := h.coverage(node.ID)
+	if !ok {
+		return nil
+	}
+	return []DirExtra{{
+		Name: graph.CoverageFile,
+		Kind: KindFile,
+		Size: int64(len(data)),
+		Perm: 0o444,
+	}}
+}
+
+// coverage renders the _coverage content for construct directory dirID.
+func (h *CoverageHandler) coverage(dirID string) ([]byte, bool) {
+	file, start, end, ok := sourceLines(h.Graph, dirID)
+	if !ok {
+		return nil, false
+	}
+	covered, total, ok := h.Coverage.Statements(file, start, end)
+	if !ok || total == 0 {
+		return nil, false
+	}
+	return []byte(fmt.Sprintf("%d/%d statements (%.1f%%)\n", covered, total, 100*float64(covered)/float64(total))), true
+}
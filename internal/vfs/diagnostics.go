@@ -1,20 +1,74 @@
 package vfs
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/agentic-research/mache/internal/graph"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 // DiagnosticsHandler serves the /_diagnostics/ virtual directory.
 // Requires Writable=true and a DiagStatus sync.Map (shared with MemoryStore.WriteStatus).
+// With a Graph it also serves draft-diff while a child holds a rejected draft,
+// and whitespace while the construct's source file mixes line endings or its
+// lines carry trailing whitespace.
+// With IngestTimings, Identifiers, or ImportCycles, the matching report is
+// served under /_diagnostics/ on any mount, while it renders non-nil.
 type DiagnosticsHandler struct {
-	Writable   bool
-	DiagStatus *sync.Map // parentDir → status string
+	Writable      bool
+	DiagStatus    *sync.Map     // parentDir → status string
+	Graph         graph.Graph   // optional; enables draft-diff and whitespace
+	IngestTimings func() []byte // optional; per-file ingest timings report
+	Identifiers   func() []byte // optional; ref token frequency report
+	ImportCycles  func() []byte // optional; circular imports, nil without any
 }
 
 func (h *DiagnosticsHandler) Match(path string) bool {
-	return h.Writable && graph.IsDiagPath(path)
+	if !graph.IsDiagPath(path) {
+		return false
+	}
+	if h.Writable {
+		return true
+	}
+	parentDir, _ := graph.ParseDiagPath(path)
+	return len(h.rootReports(parentDir)) > 0
+}
+
+// rootReports returns the reports served in dir's diagnostics, by file
+// name: those set, when dir is the root.
+func (h *DiagnosticsHandler) rootReports(dir string) map[string]func() []byte {
+	if dir != "/" {
+		return nil
+	}
+	reports := make(map[string]func() []byte, 3)
+	if h.IngestTimings != nil {
+		reports[graph.DiagIngestTimes] = h.IngestTimings
+	}
+	if h.Identifiers != nil {
+		reports[graph.DiagIdentifiers] = h.Identifiers
+	}
+	if h.ImportCycles != nil {
+		reports[graph.DiagImportCycles] = h.ImportCycles
+	}
+	return reports
+}
+
+// listsRootReports reports whether dir's diagnostics list any report.
+// Import cycles are looked for only when no other report is set, so
+// listing the root doesn't walk the graph.
+func (h *DiagnosticsHandler) listsRootReports(dir string) bool {
+	reports := h.rootReports(dir)
+	if cycles := reports[graph.DiagImportCycles]; cycles != nil && len(reports) == 1 {
+		return cycles() != nil
+	}
+	return len(reports) > 0
 }
 
 func (h *DiagnosticsHandler) Stat(path string) *VEntry {
@@ -43,19 +97,35 @@ func (h *DiagnosticsHandler) ReadContent(path string) ([]byte, bool) {
 }
 
 func (h *DiagnosticsHandler) ListDir(path string) ([]DirExtra, bool) {
-	_, fileName := graph.ParseDiagPath(path)
+	parentDir, fileName := graph.ParseDiagPath(path)
 	if fileName != "" {
 		return nil, false // not a directory
 	}
-	return []DirExtra{
-		{Name: graph.DiagLastWrite, Kind: KindFile, Perm: 0o444},
-		{Name: graph.DiagASTErrors, Kind: KindFile, Perm: 0o444},
-		{Name: graph.DiagLint, Kind: KindFile, Perm: 0o444},
-	}, true
+	var entries []DirExtra
+	if h.Writable {
+		entries = []DirExtra{
+			{Name: graph.DiagLastWrite, Kind: KindFile, Perm: 0o444},
+			{Name: graph.DiagASTErrors, Kind: KindFile, Perm: 0o444},
+			{Name: graph.DiagLint, Kind: KindFile, Perm: 0o444},
+		}
+		if h.draftDiff(parentDir) != nil {
+			entries = append(entries, DirExtra{Name: graph.DiagDraftDiff, Kind: KindFile, Perm: 0o444})
+		}
+		if h.whitespace(parentDir) != nil {
+			entries = append(entries, DirExtra{Name: graph.DiagWhitespace, Kind: KindFile, Perm: 0o444})
+		}
+	}
+	reports := h.rootReports(parentDir)
+	for _, name := range []string{graph.DiagIngestTimes, graph.DiagIdentifiers, graph.DiagImportCycles} {
+		if reports[name] != nil && reports[name]() != nil {
+			entries = append(entries, DirExtra{Name: name, Kind: KindFile, Perm: 0o444})
+		}
+	}
+	return entries, true
 }
 
 func (h *DiagnosticsHandler) DirExtras(parentPath string, _ *graph.Node) []DirExtra {
-	if h.Writable && parentPath != "/" {
+	if (h.Writable && parentPath != "/") || h.listsRootReports(parentPath) {
 		return []DirExtra{{
 			Name: graph.DiagnosticsDir,
 			Kind: KindDir,
@@ -68,6 +138,18 @@ func (h *DiagnosticsHandler) DirExtras(parentPath string, _ *graph.Node) []DirEx
 // diagContent returns the content of a diagnostics virtual file.
 // Unifies the FUSE and NFS implementations, including DiagLint.
 func (h *DiagnosticsHandler) diagContent(parentDir, fileName string) ([]byte, bool) {
+	switch fileName {
+	case graph.DiagIngestTimes, graph.DiagIdentifiers, graph.DiagImportCycles:
+		report := h.rootReports(parentDir)[fileName]
+		if report == nil {
+			return nil, false
+		}
+		content := report()
+		return content, content != nil
+	}
+	if !h.Writable {
+		return nil, false
+	}
 	switch fileName {
 	case graph.DiagLastWrite:
 		val, ok := h.DiagStatus.Load(parentDir)
@@ -91,7 +173,108 @@ func (h *DiagnosticsHandler) diagContent(parentDir, fileName string) ([]byte, bo
 			return []byte("clean\n"), true
 		}
 		return []byte(val.(string)), true
+	case graph.DiagDraftDiff:
+		diff := h.draftDiff(parentDir)
+		return diff, diff != nil
+	case graph.DiagWhitespace:
+		note := h.whitespace(parentDir)
+		return note, note != nil
 	default:
 		return nil, false
 	}
 }
+
+// draftDiff returns a unified diff from each child's committed Data to its
+// DraftData (an edit that failed validation), or nil when no child of dir
+// holds a draft.
+func (h *DiagnosticsHandler) draftDiff(dir string) []byte {
+	if h.Graph == nil {
+		return nil
+	}
+	children, err := h.Graph.ListChildren(dir)
+	if err != nil {
+		return nil
+	}
+	var out strings.Builder
+	for _, child := range children {
+		id := graph.NormalizeID(child)
+		if !strings.Contains(id, "/") {
+			id = path.Join(graph.NormalizeID(dir), id)
+		}
+		node, err := h.Graph.GetNode(id)
+		if err != nil || node.DraftData == nil {
+			continue
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        diffLines(node.Data),
+			B:        diffLines(node.DraftData),
+			FromFile: id,
+			ToFile:   id + " (draft)",
+			Context:  3,
+		})
+		if err != nil {
+			continue
+		}
+		out.WriteString(diff)
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	return []byte(out.String())
+}
+
+// whitespace returns a note on the line endings of the source file of the
+// construct at dir, when it mixes CRLF and LF, and on the construct's lines
+// with trailing spaces or tabs; nil when there is neither or dir has no
+// source file. Write-back converts edits to the file's dominant ending.
+func (h *DiagnosticsHandler) whitespace(dir string) []byte {
+	if h.Graph == nil {
+		return nil
+	}
+	file, start, end, ok := sourceLines(h.Graph, dir)
+	if !ok {
+		return nil
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var out strings.Builder
+	crlf := bytes.Count(src, []byte("\r\n"))
+	if lf := bytes.Count(src, []byte("\n")) - crlf; crlf > 0 && lf > 0 {
+		dominant := "LF"
+		if crlf > lf {
+			dominant = "CRLF"
+		}
+		fmt.Fprintf(&out, "%s: mixed line endings (%d CRLF, %d LF); write-back uses %s\n", filepath.Base(file), crlf, lf, dominant)
+	}
+	var trailing []string
+	for i, line := range bytes.Split(src, []byte("\n")) {
+		if n := i + 1; n >= start && n <= end {
+			if line = bytes.TrimSuffix(line, []byte("\r")); len(line) > 0 && (line[len(line)-1] == ' ' || line[len(line)-1] == '\t') {
+				trailing = append(trailing, strconv.Itoa(n))
+			}
+		}
+	}
+	if len(trailing) > 0 {
+		fmt.Fprintf(&out, "trailing whitespace: lines %s\n", strings.Join(trailing, ", "))
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	return []byte(out.String())
+}
+
+// diffLines splits data into newline-terminated lines. Unlike
+// difflib.SplitLines it adds no phantom empty line after a trailing newline,
+// and it terminates an unterminated last line so it can't run into the next
+// hunk.
+func diffLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	last := len(lines) - 1
+	if lines[last] == "" {
+		return lines[:last]
+	}
+	lines[last] += "\n"
+	return lines
+}
@@ -0,0 +1,211 @@
+package vfs
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// groupViewTTL bounds how stale a group view may get after the graph
+// changes, like allConstructsTTL.
+const groupViewTTL = 5 * time.Second
+
+// groupViewOrders orders a construct group's constructs for each view
+// directory, ties broken by name.
+var groupViewOrders = map[string]func(a, b groupConstruct) int{
+	// Most recently modified source file first.
+	graph.RecentDir: func(a, b groupConstruct) int { return b.modTime.Compare(a.modTime) },
+	// Largest source first.
+	graph.SortedBySizeDir: func(a, b groupConstruct) int { return cmp.Compare(b.size, a.size) },
+}
+
+// groupConstruct is a construct of a group with the attributes the views
+// order by, those of its source file.
+type groupConstruct struct {
+	name    string
+	source  string // ID of its source leaf
+	size    int64
+	modTime time.Time
+}
+
+// groupView is one view's entries: entry name → the construct's source
+// leaf ID, and the entry names in order.
+type groupView struct {
+	builtAt time.Time
+	names   []string
+	targets map[string]string
+}
+
+// GroupViewsHandler serves the virtual _recent/ and _sorted-by-size/ directories
+// inside construct groups (directories of construct directories, like
+// auth/functions/): the group's constructs as symlinks to their source
+// leaves, which NFS serves as files holding the source, most recently
+// modified or largest source first. Entries are numbered ("01-Login") so
+// that a listing sorted by name, as ls sorts it, keeps the order.
+type GroupViewsHandler struct {
+	Graph graph.Graph
+
+	mu    sync.Mutex
+	views map[string]*groupView // groupDir + "/" + view dir → view
+}
+
+// parseGroupViewPath splits a group view path into the group directory,
+// the view directory, and the entry name. ok is false for any other path.
+func parseGroupViewPath(p string) (groupDir, view, entryName string, ok bool) {
+	for v := range groupViewOrders {
+		if groupDir, entryName = parseViewSegment(p, v); groupDir != "" && !strings.Contains(entryName, "/") {
+			return groupDir, v, entryName, true
+		}
+	}
+	return "", "", "", false
+}
+
+// parseViewSegment returns the directory holding view in p and the entry
+// below it, or "" when p isn't inside view.
+func parseViewSegment(p, view string) (dir, entryName string) {
+	if d, ok := strings.CutSuffix(p, "/"+view); ok {
+		return d, ""
+	}
+	if i := strings.LastIndex(p, "/"+view+"/"); i >= 0 {
+		return p[:i], p[i+len(view)+2:]
+	}
+	return "", ""
+}
+
+// isGroup reports whether dir holds constructs, judging by its first
+// child: a group's children all are, and a check of every child would make
+// listing any directory cost a lookup per child.
+func (h *GroupViewsHandler) isGroup(dir string) bool {
+	children, err := h.Graph.ListChildren(dir)
+	if err != nil || len(children) == 0 {
+		return false
+	}
+	return graph.FindSourceChild(h.Graph, children[0]) != ""
+}
+
+// constructs returns the constructs of the group at dir.
+func (h *GroupViewsHandler) constructs(dir string) []groupConstruct {
+	children, err := h.Graph.ListChildStats(dir)
+	if err != nil {
+		return nil
+	}
+	var out []groupConstruct
+	for _, c := range children {
+		if !c.IsDir {
+			continue
+		}
+		files, err := h.Graph.ListChildStats(c.ID)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			if path.Base(f.ID) == "source" && !f.IsDir {
+				out = append(out, groupConstruct{name: path.Base(c.ID), source: f.ID, size: f.ContentSize, modTime: f.ModTime})
+				break
+			}
+		}
+	}
+	return out
+}
+
+// view returns the group at dir in view's order, rebuilding it when older
+// than groupViewTTL. It is nil when dir isn't a group.
+func (h *GroupViewsHandler) view(dir, view string) *groupView {
+	key := dir + "/" + view
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if v, ok := h.views[key]; ok && time.Since(v.builtAt) <= groupViewTTL {
+		return v
+	}
+	if !h.isGroup(dir) {
+		return nil
+	}
+	constructs := h.constructs(dir)
+	order := groupViewOrders[view]
+	slices.SortFunc(constructs, func(a, b groupConstruct) int {
+		return cmp.Or(order(a, b), strings.Compare(a.name, b.name))
+	})
+	width := len(fmt.Sprint(len(constructs)))
+	v := &groupView{builtAt: time.Now(), targets: make(map[string]string, len(constructs))}
+	for i, c := range constructs {
+		name := fmt.Sprintf("%0*d-%s", width, i+1, c.name)
+		v.names = append(v.names, name)
+		v.targets[name] = c.source
+	}
+	if h.views == nil {
+		h.views = make(map[string]*groupView)
+	}
+	h.views[key] = v
+	return v
+}
+
+func (h *GroupViewsHandler) Match(path string) bool {
+	_, _, _, ok := parseGroupViewPath(path)
+	return ok
+}
+
+func (h *GroupViewsHandler) Stat(path string) *VEntry {
+	dir, view, entryName, ok := parseGroupViewPath(path)
+	if !ok {
+		return nil
+	}
+	v := h.view(dir, view)
+	if v == nil {
+		return nil
+	}
+	if entryName == "" {
+		return &VEntry{Kind: KindDir, Perm: 0o555}
+	}
+	src, ok := v.targets[entryName]
+	if !ok {
+		return nil
+	}
+	target := "../" + strings.TrimPrefix(src, strings.TrimPrefix(dir, "/")+"/")
+	return &VEntry{
+		Kind:    KindSymlink,
+		Size:    int64(len(target)),
+		Perm:    0o777,
+		Content: []byte(target),
+		NodeID:  src,
+	}
+}
+
+func (h *GroupViewsHandler) ReadContent(path string) ([]byte, bool) {
+	entry := h.Stat(path)
+	if entry == nil || entry.Kind != KindSymlink {
+		return nil, false
+	}
+	return entry.Content, true
+}
+
+func (h *GroupViewsHandler) ListDir(path string) ([]DirExtra, bool) {
+	dir, view, entryName, ok := parseGroupViewPath(path)
+	if !ok || entryName != "" {
+		return nil, false
+	}
+	v := h.view(dir, view)
+	if v == nil {
+		return nil, false
+	}
+	extras := make([]DirExtra, 0, len(v.names))
+	for _, name := range v.names {
+		extras = append(extras, DirExtra{Name: name, Kind: KindSymlink, Perm: 0o777})
+	}
+	return extras, true
+}
+
+func (h *GroupViewsHandler) DirExtras(parentPath string, node *graph.Node) []DirExtra {
+	if parentPath == "/" || node == nil || !h.isGroup(parentPath) {
+		return nil
+	}
+	return []DirExtra{
+		{Name: graph.RecentDir, Kind: KindDir, Perm: 0o555},
+		{Name: graph.SortedBySizeDir, Kind: KindDir, Perm: 0o555},
+	}
+}
@@ -1,8 +1,13 @@
 package vfs
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/agentic-research/mache/internal/graph"
 	"github.com/stretchr/testify/assert"
@@ -30,6 +35,27 @@ func TestSchemaHandler(t *testing.T) {
 	assert.Equal(t, "_schema.json", extras[0].Name)
 
 	assert.Nil(t, h.DirExtras("/sub", nil))
+
+	h.SetContent([]byte(`{"name":"reloaded"}`))
+	data, _ = h.ReadContent("/_schema.json")
+	assert.Equal(t, []byte(`{"name":"reloaded"}`), data)
+	assert.Equal(t, int64(19), h.Stat("/_schema.json").Size)
+}
+
+func TestSchemaHandler_Named(t *testing.T) {
+	h := &SchemaHandler{Name: graph.SchemaInferredJSON}
+
+	// Absent until content is set.
+	assert.True(t, h.Match("/_schema.inferred.json"))
+	assert.False(t, h.Match("/_schema.json"))
+	assert.Nil(t, h.Stat("/_schema.inferred.json"))
+	assert.Nil(t, h.DirExtras("/", nil))
+
+	h.SetContent([]byte(`{"inference":{}}`))
+	require.NotNil(t, h.Stat("/_schema.inferred.json"))
+	extras := h.DirExtras("/", nil)
+	require.Len(t, extras, 1)
+	assert.Equal(t, "_schema.inferred.json", extras[0].Name)
 }
 
 func TestPromptHandler_Empty(t *testing.T) {
@@ -110,6 +136,63 @@ func TestDiagnosticsHandler_ListDir(t *testing.T) {
 	assert.Equal(t, "lint", entries[2].Name)
 }
 
+func TestDiagnosticsHandler_DraftDiff(t *testing.T) {
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{ID: "funcs/Foo", Mode: 0o40000 | 0o555, Children: []string{"funcs/Foo/source"}})
+	store.AddNode(&graph.Node{ID: "funcs/Foo/source", Data: []byte("func Foo() {\n\treturn\n}\n")})
+	h := &DiagnosticsHandler{Writable: true, DiagStatus: &sync.Map{}, Graph: store}
+
+	// No draft: draft-diff is absent.
+	assert.Nil(t, h.Stat("/funcs/Foo/_diagnostics/draft-diff"))
+	entries, ok := h.ListDir("/funcs/Foo/_diagnostics")
+	require.True(t, ok)
+	assert.Len(t, entries, 3)
+
+	node, err := store.GetNode("funcs/Foo/source")
+	require.NoError(t, err)
+	node.DraftData = []byte("func Foo() {\n\treturn 1 +\n}\n")
+
+	entries, ok = h.ListDir("/funcs/Foo/_diagnostics")
+	require.True(t, ok)
+	require.Len(t, entries, 4)
+	assert.Equal(t, graph.DiagDraftDiff, entries[3].Name)
+
+	e := h.Stat("/funcs/Foo/_diagnostics/draft-diff")
+	require.NotNil(t, e)
+	assert.Equal(t, "--- funcs/Foo/source\n+++ funcs/Foo/source (draft)\n@@ -1,3 +1,3 @@\n func Foo() {\n-\treturn\n+\treturn 1 +\n }\n", string(e.Content))
+}
+
+func TestDiagnosticsHandler_Whitespace(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "foo.go")
+	full := "package pkg\r\n\r\nfunc Foo() { \n\treturn\t\r\n}\r\n"
+	require.NoError(t, os.WriteFile(src, []byte(full), 0o644))
+	start := uint32(strings.Index(full, "func Foo"))
+
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{ID: "pkg/Foo", Mode: 0o40000 | 0o555, Children: []string{"pkg/Foo/source"}})
+	store.AddNode(&graph.Node{
+		ID:     "pkg/Foo/source",
+		Data:   []byte(full[start:]),
+		Origin: &graph.SourceOrigin{FilePath: src, StartByte: start, EndByte: uint32(len(full) - 2)},
+	})
+	h := &DiagnosticsHandler{Writable: true, DiagStatus: &sync.Map{}, Graph: store}
+
+	entries, ok := h.ListDir("/pkg/Foo/_diagnostics")
+	require.True(t, ok)
+	require.Len(t, entries, 4)
+	assert.Equal(t, graph.DiagWhitespace, entries[3].Name)
+	data, ok := h.ReadContent("/pkg/Foo/_diagnostics/whitespace")
+	require.True(t, ok)
+	assert.Equal(t, "foo.go: mixed line endings (4 CRLF, 1 LF); write-back uses CRLF\ntrailing whitespace: lines 3, 4\n", string(data))
+
+	// A clean file has no note.
+	require.NoError(t, os.WriteFile(src, []byte(strings.ReplaceAll(strings.ReplaceAll(full, " \n", "\r\n"), "\t\r", "\r")), 0o644))
+	assert.Nil(t, h.Stat("/pkg/Foo/_diagnostics/whitespace"))
+	entries, ok = h.ListDir("/pkg/Foo/_diagnostics")
+	require.True(t, ok)
+	assert.Len(t, entries, 3)
+}
+
 func TestDiagnosticsHandler_DirExtras(t *testing.T) {
 	h := &DiagnosticsHandler{Writable: true, DiagStatus: &sync.Map{}}
 
@@ -122,6 +205,81 @@ func TestDiagnosticsHandler_DirExtras(t *testing.T) {
 	assert.Nil(t, h.DirExtras("/", nil))
 }
 
+func TestDiagnosticsHandler_IngestTimings(t *testing.T) {
+	h := &DiagnosticsHandler{DiagStatus: &sync.Map{}, IngestTimings: func() []byte { return []byte("report\n") }}
+
+	// Read-only: only the root _diagnostics/ exists, holding ingest-timings.
+	assert.True(t, h.Match("/_diagnostics/ingest-timings"))
+	assert.False(t, h.Match("/foo/_diagnostics"))
+	extras := h.DirExtras("/", nil)
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.DiagnosticsDir, extras[0].Name)
+	assert.Nil(t, h.DirExtras("/foo", nil))
+
+	entries, ok := h.ListDir("/_diagnostics")
+	require.True(t, ok)
+	require.Len(t, entries, 1)
+	assert.Equal(t, graph.DiagIngestTimes, entries[0].Name)
+
+	e := h.Stat("/_diagnostics/ingest-timings")
+	require.NotNil(t, e)
+	assert.Equal(t, "report\n", string(e.Content))
+	assert.Nil(t, h.Stat("/_diagnostics/last-write-status"))
+
+	// Writable: per-directory diagnostics don't offer the timings.
+	h.Writable = true
+	assert.Nil(t, h.Stat("/foo/_diagnostics/ingest-timings"))
+	entries, ok = h.ListDir("/_diagnostics")
+	require.True(t, ok)
+	assert.Len(t, entries, 4)
+}
+
+func TestDiagnosticsHandler_Identifiers(t *testing.T) {
+	h := &DiagnosticsHandler{DiagStatus: &sync.Map{}, Identifiers: func() []byte { return []byte("counts\n") }}
+
+	assert.True(t, h.Match("/_diagnostics/identifiers"))
+	require.Len(t, h.DirExtras("/", nil), 1)
+	entries, ok := h.ListDir("/_diagnostics")
+	require.True(t, ok)
+	require.Len(t, entries, 1)
+	assert.Equal(t, graph.DiagIdentifiers, entries[0].Name)
+	assert.Nil(t, h.Stat("/_diagnostics/ingest-timings"))
+
+	content, ok := h.ReadContent("/_diagnostics/identifiers")
+	require.True(t, ok)
+	assert.Equal(t, "counts\n", string(content))
+
+	h.IngestTimings = func() []byte { return []byte("report\n") }
+	entries, ok = h.ListDir("/_diagnostics")
+	require.True(t, ok)
+	require.Len(t, entries, 2)
+	assert.Equal(t, graph.DiagIngestTimes, entries[0].Name)
+	assert.Equal(t, graph.DiagIdentifiers, entries[1].Name)
+}
+
+func TestDiagnosticsHandler_ImportCycles(t *testing.T) {
+	var report []byte
+	h := &DiagnosticsHandler{DiagStatus: &sync.Map{}, ImportCycles: func() []byte { return report }}
+
+	// Without cycles there is nothing to show.
+	assert.Nil(t, h.DirExtras("/", nil))
+	entries, ok := h.ListDir("/_diagnostics")
+	require.True(t, ok)
+	assert.Empty(t, entries)
+	assert.Nil(t, h.Stat("/_diagnostics/import-cycles"))
+
+	report = []byte("2 packages import each other: a, b\n  a -> b -> a\n")
+	require.Len(t, h.DirExtras("/", nil), 1)
+	entries, ok = h.ListDir("/_diagnostics")
+	require.True(t, ok)
+	require.Len(t, entries, 1)
+	assert.Equal(t, graph.DiagImportCycles, entries[0].Name)
+	e := h.Stat("/_diagnostics/import-cycles")
+	require.NotNil(t, e)
+	assert.Equal(t, string(report), string(e.Content))
+	assert.Nil(t, h.Stat("/pkg/_diagnostics/import-cycles"), "only at the root")
+}
+
 func TestContextHandler(t *testing.T) {
 	store := graph.NewMemoryStore()
 	store.AddNode(&graph.Node{ID: "pkg/Foo", Mode: 0o40000, Context: []byte("import context")})
@@ -221,6 +379,232 @@ func TestLocationHandler_DirExtras(t *testing.T) {
 	assert.Nil(t, h.DirExtras("/pkg/Foo", nil))
 }
 
+func TestSchemaPathHandler(t *testing.T) {
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{
+		ID:         "vulns/CVE-1",
+		Mode:       0o40000,
+		Properties: map[string][]byte{graph.SchemaPathProperty: []byte("vulns > {{.item.cveID}}")},
+	})
+	store.AddNode(&graph.Node{ID: "vulns/CVE-2", Mode: 0o40000})
+
+	h := &SchemaPathHandler{Graph: store}
+	assert.True(t, h.Match("/vulns/CVE-1/_schema_path"))
+	assert.False(t, h.Match("/vulns/CVE-1/location"))
+
+	e := h.Stat("/vulns/CVE-1/_schema_path")
+	require.NotNil(t, e)
+	assert.Equal(t, KindFile, e.Kind)
+	data, ok := h.ReadContent("/vulns/CVE-1/_schema_path")
+	assert.True(t, ok)
+	assert.Equal(t, "vulns > {{.item.cveID}}", string(data))
+
+	node, err := store.GetNode("vulns/CVE-1")
+	require.NoError(t, err)
+	extras := h.DirExtras("/vulns/CVE-1", node)
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.SchemaPathFile, extras[0].Name)
+
+	assert.Nil(t, h.Stat("/vulns/CVE-2/_schema_path"))
+	node, err = store.GetNode("vulns/CVE-2")
+	require.NoError(t, err)
+	assert.Nil(t, h.DirExtras("/vulns/CVE-2", node))
+}
+
+func TestRawHandler(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "foo.go")
+	full := "package pkg\n\nimport \"fmt\"\n\nfunc Foo() { fmt.Println() }\n"
+	require.NoError(t, os.WriteFile(src, []byte(full), 0o644))
+
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{ID: "pkg/Foo", Mode: 0o40000, Children: []string{"pkg/Foo/source"}})
+	store.AddNode(&graph.Node{
+		ID:     "pkg/Foo/source",
+		Data:   []byte("func Foo() { fmt.Println() }"),
+		Origin: &graph.SourceOrigin{FilePath: src, StartByte: 26, EndByte: 54},
+	})
+	store.AddNode(&graph.Node{ID: "pkg/Bar", Mode: 0o40000})
+
+	h := &RawHandler{Graph: store}
+
+	assert.True(t, h.Match("/pkg/Foo/_raw"))
+	assert.False(t, h.Match("/pkg/Foo/source"))
+
+	e := h.Stat("/pkg/Foo/_raw")
+	require.NotNil(t, e)
+	assert.Equal(t, KindFile, e.Kind)
+	assert.Equal(t, uint32(0o444), e.Perm)
+	assert.Equal(t, int64(len(full)), e.Size)
+	assert.Nil(t, e.Content, "Stat should not read the source file")
+
+	data, ok := h.ReadContent("/pkg/Foo/_raw")
+	assert.True(t, ok)
+	assert.Equal(t, full, string(data))
+
+	extras := h.DirExtras("/pkg/Foo", &graph.Node{ID: "pkg/Foo"})
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.RawFile, extras[0].Name)
+	assert.Equal(t, int64(len(full)), extras[0].Size)
+
+	// No source child → nothing
+	assert.Nil(t, h.Stat("/pkg/Bar/_raw"))
+	assert.Nil(t, h.DirExtras("/pkg/Bar", &graph.Node{ID: "pkg/Bar"}))
+	assert.Nil(t, h.DirExtras("/pkg/Bar", nil))
+}
+
+func TestOriginHandler(t *testing.T) {
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{
+		ID: "pkg/Foo", Mode: 0o40000, Children: []string{"pkg/Foo/source"},
+		Properties: map[string][]byte{graph.OriginProperty: []byte("/src/foo.go:4:12")},
+	})
+	store.AddNode(&graph.Node{ID: "pkg/Foo/source", Data: []byte("func Foo() {}")})
+	store.AddNode(&graph.Node{ID: "pkg/Bar", Mode: 0o40000, Children: []string{"pkg/Bar/source"}})
+	store.AddNode(&graph.Node{ID: "pkg/Bar/source", Data: []byte("func Bar() {}")})
+
+	h := &OriginHandler{Graph: store}
+
+	assert.True(t, h.Match("/pkg/Foo/_origin"))
+	assert.False(t, h.Match("/pkg/Foo/_raw"))
+
+	want := "/src/foo.go:4:12\n"
+	e := h.Stat("/pkg/Foo/_origin")
+	require.NotNil(t, e)
+	assert.Equal(t, uint32(0o444), e.Perm)
+	assert.Equal(t, want, string(e.Content))
+
+	data, ok := h.ReadContent("/pkg/Foo/_origin")
+	assert.True(t, ok)
+	assert.Equal(t, want, string(data))
+
+	foo, err := store.GetNode("pkg/Foo")
+	require.NoError(t, err)
+	extras := h.DirExtras("/pkg/Foo", foo)
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.OriginFile, extras[0].Name)
+	assert.Equal(t, int64(len(want)), extras[0].Size)
+	assert.Equal(t, "/src/foo.go:4:12", string(foo.Properties[graph.OriginProperty]), "reads don't alias the property")
+
+	// A construct without a recorded position has no _origin.
+	assert.Nil(t, h.Stat("/pkg/Bar/_origin"))
+	assert.Nil(t, h.DirExtras("/pkg/Bar", &graph.Node{ID: "pkg/Bar"}))
+	assert.Nil(t, h.DirExtras("/pkg/Bar", nil))
+}
+
+func TestSExprHandler(t *testing.T) {
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{
+		ID: "pkg/Foo", Mode: 0o40000, Children: []string{"pkg/Foo/source"},
+		Properties: map[string][]byte{"lang": []byte("go")},
+	})
+	store.AddNode(&graph.Node{
+		ID:     "pkg/Foo/source",
+		Data:   []byte("func Foo() {}"),
+		Origin: &graph.SourceOrigin{FilePath: "/src/foo.go", StartByte: 13, EndByte: 26},
+	})
+	store.AddNode(&graph.Node{ID: "pkg/Bar", Mode: 0o40000, Children: []string{"pkg/Bar/source"}})
+	store.AddNode(&graph.Node{ID: "pkg/Bar/source", Data: []byte("func Bar() {}")})
+
+	var calls []string
+	h := &SExprHandler{Graph: store}
+	assert.False(t, h.Match("/pkg/Foo/_sexp"), "off until Parse is set")
+	assert.Nil(t, h.DirExtras("/pkg/Foo", &graph.Node{ID: "pkg/Foo"}))
+
+	h.Parse = func(file, langName string, start, end uint32) ([]byte, error) {
+		calls = append(calls, fmt.Sprintf("%s %s %d-%d", file, langName, start, end))
+		return []byte("(function_declaration)\n"), nil
+	}
+	assert.True(t, h.Match("/pkg/Foo/_sexp"))
+	assert.False(t, h.Match("/pkg/Foo/_origin"))
+
+	e := h.Stat("/pkg/Foo/_sexp")
+	require.NotNil(t, e)
+	assert.Equal(t, uint32(0o444), e.Perm)
+	assert.Equal(t, "(function_declaration)\n", string(e.Content))
+	assert.Equal(t, []string{"/src/foo.go go 13-26"}, calls)
+
+	foo, err := store.GetNode("pkg/Foo")
+	require.NoError(t, err)
+	extras := h.DirExtras("/pkg/Foo", foo)
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.SExprFile, extras[0].Name)
+	assert.Equal(t, int64(len("(function_declaration)\n")), extras[0].Size)
+
+	// A source without an origin has no syntax tree to show.
+	assert.Nil(t, h.Stat("/pkg/Bar/_sexp"))
+	assert.Nil(t, h.DirExtras("/pkg/Bar", &graph.Node{ID: "pkg/Bar"}))
+}
+
+func TestSExprHandler_Named(t *testing.T) {
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{ID: "pkg/Foo", Mode: 0o40000, Children: []string{"pkg/Foo/source"}})
+	store.AddNode(&graph.Node{
+		ID:     "pkg/Foo/source",
+		Data:   []byte("func Foo() {}"),
+		Origin: &graph.SourceOrigin{FilePath: "/src/foo.go", StartByte: 13, EndByte: 26},
+	})
+
+	h := &SExprHandler{Graph: store, Name: graph.ASTFile}
+	h.Parse = func(string, string, uint32, uint32) ([]byte, error) {
+		return []byte(`{"type":"function_declaration"}`), nil
+	}
+	assert.True(t, h.Match("/pkg/Foo/_ast.json"))
+	assert.False(t, h.Match("/pkg/Foo/_sexp"))
+
+	e := h.Stat("/pkg/Foo/_ast.json")
+	require.NotNil(t, e)
+	assert.Equal(t, `{"type":"function_declaration"}`, string(e.Content))
+
+	foo, err := store.GetNode("pkg/Foo")
+	require.NoError(t, err)
+	extras := h.DirExtras("/pkg/Foo", foo)
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.ASTFile, extras[0].Name)
+}
+
+func TestRefCountHandler(t *testing.T) {
+	store := graph.NewMemoryStore()
+	for _, id := range []string{"funcs/Foo", "funcs/Bar", "funcs/Baz"} {
+		store.AddNode(&graph.Node{ID: id, Mode: 0o40000, Children: []string{id + "/source"}})
+		store.AddNode(&graph.Node{ID: id + "/source", Data: []byte("code")})
+	}
+	store.AddNode(&graph.Node{ID: "funcs", Mode: 0o40000, Children: []string{"funcs/Foo", "funcs/Bar", "funcs/Baz"}})
+	require.NoError(t, store.AddRef("Foo", "funcs/Bar"))
+	require.NoError(t, store.AddRef("Foo", "funcs/Baz"))
+
+	h := &RefCountHandler{Graph: store}
+
+	assert.True(t, h.Match("/funcs/Foo/_refcount"))
+	assert.False(t, h.Match("/funcs/Foo/_origin"))
+
+	e := h.Stat("/funcs/Foo/_refcount")
+	require.NotNil(t, e)
+	assert.Equal(t, uint32(0o444), e.Perm)
+	assert.Equal(t, "2\n", string(e.Content))
+
+	data, ok := h.ReadContent("/funcs/Bar/_refcount")
+	assert.True(t, ok)
+	assert.Equal(t, "0\n", string(data), "uncalled constructs still report a count")
+
+	extras := h.DirExtras("/funcs/Foo", &graph.Node{ID: "funcs/Foo"})
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.RefCountFile, extras[0].Name)
+	assert.Equal(t, int64(2), extras[0].Size)
+
+	// Directories without a source child are not constructs.
+	assert.Nil(t, h.Stat("/funcs/_refcount"))
+	assert.Nil(t, h.DirExtras("/funcs", &graph.Node{ID: "funcs"}))
+	assert.Nil(t, h.DirExtras("/funcs", nil))
+
+	// Counts are cached until the TTL lapses.
+	require.NoError(t, store.AddRef("Foo", "funcs/Foo"))
+	data, _ = h.ReadContent("/funcs/Foo/_refcount")
+	assert.Equal(t, "2\n", string(data))
+	h.builtAt = time.Now().Add(-refCountTTL - time.Second)
+	data, _ = h.ReadContent("/funcs/Foo/_refcount")
+	assert.Equal(t, "3\n", string(data))
+}
+
 func TestCallersHandler(t *testing.T) {
 	store := graph.NewMemoryStore()
 	store.AddNode(&graph.Node{ID: "funcs/Foo", Mode: 0o40000})
@@ -265,6 +649,150 @@ func TestCallersHandler(t *testing.T) {
 	assert.Nil(t, h.DirExtras("/", nil))
 }
 
+func TestCallersHandler_CallSiteLines(t *testing.T) {
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{ID: "funcs/Foo", Mode: 0o40000})
+	store.AddNode(&graph.Node{ID: "funcs/Bar/source", Mode: 0, Data: []byte("bar code")})
+	require.NoError(t, store.AddRef("Foo", "funcs/Bar/source", 57, 42))
+
+	h := &CallersHandler{Graph: store}
+
+	entries, ok := h.ListDir("/funcs/Foo/callers")
+	require.True(t, ok)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "funcs_Bar_source:42,57", entries[0].Name)
+
+	// Both the annotated and the bare name resolve to the caller.
+	for _, name := range []string{"funcs_Bar_source:42,57", "funcs_Bar_source"} {
+		e := h.Stat("/funcs/Foo/callers/" + name)
+		require.NotNil(t, e, name)
+		assert.Equal(t, "funcs/Bar/source", e.NodeID)
+	}
+	assert.Nil(t, h.Stat("/funcs/Foo/callers/funcs_Bar_source:1"))
+}
+
+func TestTestsHandler(t *testing.T) {
+	store := graph.NewMemoryStore()
+	goLang := map[string][]byte{"lang": []byte("go")}
+	for _, name := range []string{"Foo", "TestFoo", "TestFoo_Empty"} {
+		dir := "funcs/" + name
+		store.AddNode(&graph.Node{ID: dir, Mode: 0o40000 | 0o555, Properties: goLang, Children: []string{dir + "/source"}})
+		store.AddNode(&graph.Node{ID: dir + "/source", Data: []byte("func " + name + "() {}")})
+		require.NoError(t, store.AddDef(name, dir))
+	}
+
+	h := &TestsHandler{Graph: store}
+
+	assert.True(t, h.Match("/funcs/Foo/_tests"))
+	assert.True(t, h.Match("/funcs/Foo/_tests/funcs_TestFoo_source"))
+	assert.False(t, h.Match("/funcs/Foo/source"))
+
+	extras := h.DirExtras("/funcs/Foo", nil)
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.TestsDir, extras[0].Name)
+
+	entries, ok := h.ListDir("/funcs/Foo/_tests")
+	require.True(t, ok)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	assert.Equal(t, []string{"funcs_TestFoo_source", "funcs_TestFoo_Empty_source"}, names)
+
+	e := h.Stat("/funcs/Foo/_tests/funcs_TestFoo_source")
+	require.NotNil(t, e)
+	assert.Equal(t, KindSymlink, e.Kind)
+	assert.Equal(t, "funcs/TestFoo/source", e.NodeID)
+	assert.Equal(t, "../../../funcs/TestFoo/source", string(e.Content))
+	assert.Nil(t, h.Stat("/funcs/Foo/_tests/nope"))
+
+	// Tests themselves have no tests.
+	assert.Nil(t, h.DirExtras("/funcs/TestFoo", nil))
+	assert.Nil(t, h.DirExtras("/", nil))
+}
+
+func TestTypesUsedHandler(t *testing.T) {
+	store := graph.NewMemoryStore()
+	for _, id := range []string{"app/functions/Login", "app/types/User"} {
+		store.AddNode(&graph.Node{ID: id, Mode: 0o40000 | 0o555, Children: []string{id + "/source"}})
+		store.AddNode(&graph.Node{ID: id + "/source"})
+	}
+	require.NoError(t, store.AddDef("User", "app/types/User"))
+	require.NoError(t, store.AddRef(graph.TypeRefPrefix+"User", "app/functions/Login/source"))
+
+	h := &TypesUsedHandler{Graph: store}
+
+	assert.True(t, h.Match("/app/functions/Login/types-used"))
+	assert.True(t, h.Match("/app/functions/Login/types-used/app_types_User"))
+	assert.False(t, h.Match("/app/functions/Login/source"))
+
+	extras := h.DirExtras("/app/functions/Login", nil)
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.TypesUsedDir, extras[0].Name)
+
+	entries, ok := h.ListDir("/app/functions/Login/types-used")
+	require.True(t, ok)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "app_types_User", entries[0].Name)
+
+	e := h.Stat("/app/functions/Login/types-used/app_types_User")
+	require.NotNil(t, e)
+	assert.Equal(t, KindSymlink, e.Kind)
+	assert.Equal(t, "app/types/User/source", e.NodeID)
+	assert.Equal(t, "../../../../app/types/User/source", string(e.Content))
+	assert.Nil(t, h.Stat("/app/functions/Login/types-used/nope"))
+
+	assert.Nil(t, h.DirExtras("/app/types/User", nil))
+	assert.Nil(t, h.DirExtras("/", nil))
+}
+
+// topologyGraph is a MemoryStore that describes a fixed topology.
+type topologyGraph struct {
+	*graph.MemoryStore
+	topo *graph.Topology
+}
+
+func (g topologyGraph) Topology() *graph.Topology { return g.topo }
+
+func TestTopologyHandler(t *testing.T) {
+	h := &TopologyHandler{Graph: topologyGraph{graph.NewMemoryStore(), &graph.Topology{
+		Roots: []graph.TopologyLevel{{Name: "vulns", Levels: 1, Samples: []string{"vulns"}}},
+	}}}
+	assert.True(t, h.Match("/_topology.json"))
+	assert.False(t, h.Match("/vulns/_topology.json"))
+
+	data, ok := h.ReadContent("/_topology.json")
+	require.True(t, ok)
+	assert.JSONEq(t, `{"roots":[{"name":"vulns","depth":0,"levels":1,"samples":["vulns"]}]}`, string(data))
+	e := h.Stat("/_topology.json")
+	require.NotNil(t, e)
+	assert.Equal(t, int64(len(data)), e.Size)
+	require.Len(t, h.DirExtras("/", nil), 1)
+
+	plain := &TopologyHandler{Graph: graph.NewMemoryStore()}
+	assert.Nil(t, plain.Stat("/_topology.json"), "graphs without a topology have no file")
+	assert.Nil(t, plain.DirExtras("/", nil))
+}
+
+func TestManifestHandler(t *testing.T) {
+	h := &ManifestHandler{Content: func() []byte { return []byte(`{"files":[]}` + "\n") }}
+	assert.True(t, h.Match("/_manifest.json"))
+	assert.False(t, h.Match("/pkg/_manifest.json"))
+
+	data, ok := h.ReadContent("/_manifest.json")
+	require.True(t, ok)
+	assert.JSONEq(t, `{"files":[]}`, string(data))
+	e := h.Stat("/_manifest.json")
+	require.NotNil(t, e)
+	assert.Equal(t, uint32(0o444), e.Perm)
+	require.Len(t, h.DirExtras("/", nil), 1)
+	assert.Nil(t, h.DirExtras("/pkg", nil))
+
+	unset := &ManifestHandler{}
+	assert.Nil(t, unset.Stat("/_manifest.json"), "no manifest without an ingest")
+	assert.Nil(t, unset.DirExtras("/", nil))
+}
+
 func TestCalleesHandler(t *testing.T) {
 	store := graph.NewMemoryStore()
 	store.AddNode(&graph.Node{ID: "funcs/Foo", Mode: 0o40000})
@@ -289,3 +817,289 @@ func TestCalleesHandler(t *testing.T) {
 	assert.Nil(t, h.DirExtras("/funcs/Foo", nil))
 	assert.Nil(t, h.DirExtras("/", nil))
 }
+
+func TestAllConstructsHandler(t *testing.T) {
+	store := graph.NewMemoryStore()
+	for _, id := range []string{"graph/functions/NewStore", "graph/methods/Store.Get", "graph/types/Store", "vfs/functions/NewStore"} {
+		store.AddNode(&graph.Node{ID: id, Mode: 0o40000 | 0o555, Children: []string{id + "/source"}})
+		store.AddNode(&graph.Node{ID: id + "/source", Data: []byte("code")})
+		require.NoError(t, store.AddDef(filepath.Base(id), id))
+	}
+	// Schema-provided package names win over the path prefix.
+	store.AddNode(&graph.Node{ID: "x/functions/Run", Mode: 0o40000 | 0o555, Children: []string{"x/functions/Run/source"}, Properties: map[string][]byte{"pkg": []byte("main")}})
+	store.AddNode(&graph.Node{ID: "x/functions/Run/source", Data: []byte("code")})
+	require.NoError(t, store.AddDef("Run", "x/functions/Run"))
+	// A construct without a source leaf has nothing to point at.
+	store.AddNode(&graph.Node{ID: "x/functions/Bare", Mode: 0o40000 | 0o555})
+	require.NoError(t, store.AddDef("Bare", "x/functions/Bare"))
+
+	h := &AllConstructsHandler{Graph: store}
+
+	assert.True(t, h.Match("/_all-functions"))
+	assert.True(t, h.Match("/_all-functions/graph.NewStore"))
+	assert.False(t, h.Match("/graph/_all-functions"))
+	assert.False(t, h.Match("/_all-functions/a/b"))
+
+	names := func(vdir string) []string {
+		entries, ok := h.ListDir("/" + vdir)
+		require.True(t, ok, vdir)
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.Name)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"graph.NewStore", "main.Run", "vfs.NewStore"}, names(graph.AllFunctionsDir))
+	assert.Equal(t, []string{"graph.Store.Get"}, names(graph.AllMethodsDir))
+	assert.Equal(t, []string{"graph.Store"}, names(graph.AllTypesDir))
+
+	e := h.Stat("/_all-functions/graph.NewStore")
+	require.NotNil(t, e)
+	assert.Equal(t, KindSymlink, e.Kind)
+	assert.Equal(t, "graph/functions/NewStore/source", e.NodeID)
+	assert.Equal(t, "../graph/functions/NewStore/source", string(e.Content))
+	assert.Nil(t, h.Stat("/_all-functions/nope"))
+
+	extras := h.DirExtras("/", nil)
+	require.Len(t, extras, 3)
+	assert.Equal(t, graph.AllFunctionsDir, extras[0].Name)
+	assert.Nil(t, h.DirExtras("/graph", nil))
+
+	// No definitions, no index directories.
+	empty := &AllConstructsHandler{Graph: graph.NewMemoryStore()}
+	assert.Nil(t, empty.DirExtras("/", nil))
+	assert.Nil(t, empty.Stat("/_all-functions"))
+}
+
+func TestOutlineHandler(t *testing.T) {
+	store := graph.NewMemoryStore()
+	for id, loc := range map[string]string{
+		"demo/types/Greeter":         "pkg/demo/greet.go:3:12",
+		"demo/methods/Greeter.Greet": "pkg/demo/greet.go:14:16",
+		"demo/functions/Hello":       "pkg/demo/greet.go:18:20",
+		"demo/types/Greeter/Inner":   "pkg/demo/greet.go:5:8",
+		"demo/functions/main":        "main.go:3:5",
+		"demo/functions/Outside":     "../elsewhere.go:1:2",
+	} {
+		store.AddNode(&graph.Node{ID: id, Mode: 0o40000 | 0o555, Properties: map[string][]byte{"location": []byte(loc)}})
+		require.NoError(t, store.AddDef(filepath.Base(id), id))
+	}
+	store.AddNode(&graph.Node{ID: "demo/functions/NoLoc", Mode: 0o40000 | 0o555})
+	require.NoError(t, store.AddDef("NoLoc", "demo/functions/NoLoc"))
+
+	h := &OutlineHandler{Graph: store}
+	assert.True(t, h.Match("/_outline"))
+	assert.True(t, h.Match("/_outline/pkg/demo/greet.go"))
+	assert.False(t, h.Match("/_outlines"))
+	assert.False(t, h.Match("/demo/_outline"))
+
+	entries, ok := h.ListDir("/_outline")
+	require.True(t, ok)
+	require.Len(t, entries, 2, "the outside file is left out")
+	assert.Equal(t, "main.go", entries[0].Name)
+	assert.Equal(t, KindFile, entries[0].Kind)
+	assert.Equal(t, "pkg", entries[1].Name)
+	assert.Equal(t, KindDir, entries[1].Kind)
+	assert.Equal(t, KindDir, h.Stat("/_outline/pkg/demo").Kind)
+
+	data, ok := h.ReadContent("/_outline/pkg/demo/greet.go")
+	require.True(t, ok)
+	assert.Equal(t, `3-12 demo/types/Greeter
+  5-8 demo/types/Greeter/Inner
+14-16 demo/methods/Greeter.Greet
+18-20 demo/functions/Hello
+`, string(data), "source order, nested constructs indented")
+	e := h.Stat("/_outline/pkg/demo/greet.go")
+	require.NotNil(t, e)
+	assert.Equal(t, KindFile, e.Kind)
+	assert.Equal(t, int64(len(data)), e.Size)
+	assert.Nil(t, h.Stat("/_outline/pkg/demo/nope.go"))
+
+	require.Len(t, h.DirExtras("/", nil), 1)
+	assert.Nil(t, h.DirExtras("/demo", nil))
+	assert.Nil(t, (&OutlineHandler{Graph: graph.NewMemoryStore()}).DirExtras("/", nil))
+}
+
+// fakeHistory is a CommitHistory of two commits, recording the line
+// ranges asked about.
+type fakeHistory struct {
+	asked []string
+}
+
+func (f *fakeHistory) Commits() ([]string, error) {
+	return []string{"bbbbbbbbbbbb", "aaaaaaaaaaaa"}, nil
+}
+
+func (f *fakeHistory) CommitFiles(id string) (map[string][]byte, error) {
+	return map[string][]byte{"message": []byte(id + "\n"), "changes": []byte("M\tfoo.go\n")}, nil
+}
+
+func (f *fakeHistory) LineCommits(file string, start, end int) ([]string, error) {
+	f.asked = append(f.asked, fmt.Sprintf("%s:%d,%d", filepath.Base(file), start, end))
+	return []string{"aaaaaaaaaaaa"}, nil
+}
+
+func TestHistoryHandler(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "foo.go")
+	full := "package pkg\n\nfunc Foo() {\n}\n"
+	require.NoError(t, os.WriteFile(src, []byte(full), 0o644))
+	start := uint32(strings.Index(full, "func Foo"))
+
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{ID: "pkg", Mode: 0o40000, Children: []string{"pkg/Foo"}})
+	store.AddNode(&graph.Node{ID: "pkg/Foo", Mode: 0o40000, Children: []string{"pkg/Foo/source"}})
+	store.AddNode(&graph.Node{
+		ID:     "pkg/Foo/source",
+		Data:   []byte("func Foo() {\n}"),
+		Origin: &graph.SourceOrigin{FilePath: src, StartByte: start, EndByte: uint32(len(full) - 1)},
+	})
+
+	h := &HistoryHandler{Graph: store}
+	assert.False(t, h.Match("/_history"), "no history without a CommitHistory")
+	assert.Nil(t, h.DirExtras("/", nil))
+
+	fake := &fakeHistory{}
+	h.History = fake
+	assert.True(t, h.Match("/_history"))
+	assert.True(t, h.Match("/pkg/Foo/_history/aaaaaaaaaaaa"))
+	assert.False(t, h.Match("/pkg/Foo/_origin"))
+
+	// Root: a directory per commit, files per commit.
+	e := h.Stat("/_history")
+	require.NotNil(t, e)
+	assert.Equal(t, KindDir, e.Kind)
+	entries, ok := h.ListDir("/_history")
+	require.True(t, ok)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "bbbbbbbbbbbb", entries[0].Name)
+	entries, ok = h.ListDir("/_history/aaaaaaaaaaaa")
+	require.True(t, ok)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "changes", entries[0].Name)
+	data, ok := h.ReadContent("/_history/aaaaaaaaaaaa/message")
+	require.True(t, ok)
+	assert.Equal(t, "aaaaaaaaaaaa\n", string(data))
+	assert.Nil(t, h.Stat("/_history/cccccccccccc"), "not a projected commit")
+	assert.Nil(t, h.Stat("/_history/aaaaaaaaaaaa/nope"))
+
+	// Construct: symlinks to the commits that changed its lines.
+	entries, ok = h.ListDir("/pkg/Foo/_history")
+	require.True(t, ok)
+	require.Len(t, entries, 1)
+	assert.Equal(t, KindSymlink, entries[0].Kind)
+	assert.Equal(t, []string{"foo.go:3,4"}, fake.asked, "from the first line to the one with its last byte")
+	e = h.Stat("/pkg/Foo/_history/aaaaaaaaaaaa")
+	require.NotNil(t, e)
+	assert.Equal(t, "../../../_history/aaaaaaaaaaaa", string(e.Content))
+	assert.Nil(t, h.Stat("/pkg/Foo/_history/bbbbbbbbbbbb"))
+
+	// Listings advertise _history without running the history.
+	fake.asked = nil
+	assert.Len(t, h.DirExtras("/", nil), 1)
+	assert.Len(t, h.DirExtras("/pkg/Foo", &graph.Node{ID: "pkg/Foo"}), 1)
+	assert.Nil(t, h.DirExtras("/pkg", &graph.Node{ID: "pkg"}), "no source child")
+	assert.Empty(t, fake.asked)
+}
+
+// fakeCoverage is a CoverageProfile covering 3 of 4 statements of every
+// file, recording the line ranges asked about.
+type fakeCoverage struct {
+	asked []string
+}
+
+func (f *fakeCoverage) Statements(file string, start, end int) (covered, total int, ok bool) {
+	f.asked = append(f.asked, fmt.Sprintf("%s:%d,%d", filepath.Base(file), start, end))
+	return 3, 4, true
+}
+
+func TestCoverageHandler(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "foo.go")
+	full := "package pkg\n\nfunc Foo() {\n}\n"
+	require.NoError(t, os.WriteFile(src, []byte(full), 0o644))
+	start := uint32(strings.Index(full, "func Foo"))
+
+	store := graph.NewMemoryStore()
+	store.AddNode(&graph.Node{ID: "pkg", Mode: 0o40000, Children: []string{"pkg/Foo"}})
+	store.AddNode(&graph.Node{ID: "pkg/Foo", Mode: 0o40000, Children: []string{"pkg/Foo/source"}})
+	store.AddNode(&graph.Node{
+		ID:     "pkg/Foo/source",
+		Data:   []byte("func Foo() {\n}"),
+		Origin: &graph.SourceOrigin{FilePath: src, StartByte: start, EndByte: uint32(len(full) - 1)},
+	})
+
+	h := &CoverageHandler{Graph: store}
+	assert.False(t, h.Match("/pkg/Foo/_coverage"), "no coverage without a profile")
+	assert.Nil(t, h.DirExtras("/pkg", &graph.Node{ID: "pkg/Foo"}))
+
+	fake := &fakeCoverage{}
+	h.Coverage = fake
+	assert.True(t, h.Match("/pkg/Foo/_coverage"))
+	assert.False(t, h.Match("/pkg/Foo/_origin"))
+
+	data, ok := h.ReadContent("pkg/Foo/_coverage")
+	require.True(t, ok)
+	assert.Equal(t, "3/4 statements (75.0%)\n", string(data))
+	assert.Equal(t, []string{"foo.go:3,4"}, fake.asked)
+	e := h.Stat("pkg/Foo/_coverage")
+	require.NotNil(t, e)
+	assert.Equal(t, KindFile, e.Kind)
+	assert.Equal(t, int64(len(data)), e.Size)
+
+	extras := h.DirExtras("/pkg", &graph.Node{ID: "pkg/Foo"})
+	require.Len(t, extras, 1)
+	assert.Equal(t, graph.CoverageFile, extras[0].Name)
+	assert.Nil(t, h.DirExtras("/", &graph.Node{ID: "pkg"}), "no source child")
+	assert.Nil(t, h.Stat("pkg/_coverage"))
+}
+
+func TestGroupViewsHandler(t *testing.T) {
+	store := graph.NewMemoryStore()
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.AddRoot(&graph.Node{ID: "pkg", Mode: os.ModeDir | 0o555, Children: []string{"pkg/functions"}})
+	store.AddNode(&graph.Node{ID: "pkg/functions", Mode: os.ModeDir | 0o555, Children: []string{"pkg/functions/A", "pkg/functions/B", "pkg/functions/C"}})
+	for _, c := range []struct {
+		name    string
+		src     string
+		modTime time.Time
+	}{
+		{"A", "func A() {}", old},
+		{"B", "func B() { return }", old.Add(time.Hour)},
+		{"C", "func C() { x := 1; _ = x }", old},
+	} {
+		id := "pkg/functions/" + c.name
+		store.AddNode(&graph.Node{ID: id, Mode: os.ModeDir | 0o555, Children: []string{id + "/source"}})
+		store.AddNode(&graph.Node{ID: id + "/source", Data: []byte(c.src), ModTime: c.modTime})
+	}
+	h := &GroupViewsHandler{Graph: store}
+
+	assert.True(t, h.Match("/pkg/functions/_recent"))
+	assert.True(t, h.Match("/pkg/functions/_sorted-by-size/1-C"))
+	assert.False(t, h.Match("/_recent"))
+	assert.False(t, h.Match("/pkg/functions/A/source"))
+
+	extras := h.DirExtras("/pkg/functions", &graph.Node{ID: "pkg/functions"})
+	require.Len(t, extras, 2)
+	assert.Equal(t, graph.RecentDir, extras[0].Name)
+	assert.Nil(t, h.DirExtras("/pkg", &graph.Node{ID: "pkg"}), "a package isn't a group")
+	assert.Nil(t, h.DirExtras("/pkg/functions/A", &graph.Node{ID: "pkg/functions/A"}), "nor is a construct")
+	assert.Nil(t, h.Stat("/pkg/_recent"))
+
+	names := func(path string) []string {
+		entries, ok := h.ListDir(path)
+		require.True(t, ok, path)
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.Name)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"1-B", "2-A", "3-C"}, names("/pkg/functions/_recent"), "newest first, ties by name")
+	assert.Equal(t, []string{"1-C", "2-B", "3-A"}, names("/pkg/functions/_sorted-by-size"))
+
+	e := h.Stat("/pkg/functions/_sorted-by-size/1-C")
+	require.NotNil(t, e)
+	assert.Equal(t, KindSymlink, e.Kind)
+	assert.Equal(t, "../C/source", string(e.Content))
+	assert.Equal(t, "pkg/functions/C/source", e.NodeID)
+	assert.Nil(t, h.Stat("/pkg/functions/_sorted-by-size/1-A"))
+}
@@ -0,0 +1,243 @@
+package vfs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// CommitHistory is the version history the _history directories project.
+// ingest.GitHistory reads it from a git repository.
+type CommitHistory interface {
+	// Commits lists the IDs of the commits reachable from HEAD, newest first.
+	Commits() ([]string, error)
+	// CommitFiles returns the files of a commit's directory by name.
+	CommitFiles(id string) (map[string][]byte, error)
+	// LineCommits lists the commits, newest first, that changed lines
+	// start through end (1-based, inclusive) of file.
+	LineCommits(file string, start, end int) ([]string, error)
+}
+
+// HistoryHandler serves the virtual _history/ directories. At the root,
+// _history/<commit>/ holds each commit's message, author, date, and the
+// files it changed. Inside a construct directory, _history/ lists the
+// commits that changed the construct's lines, newest first, as symlinks
+// into the root _history/. It needs the source's byte range.
+// The lines are those of the source file as it is now, looked up in HEAD,
+// so uncommitted edits above a construct shift which lines are asked
+// about. History is nil (and there are no _history directories) unless the
+// mount asked for them.
+type HistoryHandler struct {
+	Graph   graph.Graph
+	History CommitHistory
+}
+
+// commit reports whether id names one of the projected commits.
+func (h *HistoryHandler) commit(id string) bool {
+	ids, err := h.History.Commits()
+	return err == nil && slices.Contains(ids, id)
+}
+
+// lineCommits returns the commits that changed the construct at dir, ok
+// false when it has no source range to look up.
+func (h *HistoryHandler) lineCommits(dir string) (ids []string, ok bool) {
+	file, start, end, ok := sourceLines(h.Graph, dir)
+	if !ok {
+		return nil, false
+	}
+	ids, err := h.History.LineCommits(file, start, end)
+	return ids, err == nil
+}
+
+// sourceLines returns the source file of the construct at dir and the
+// lines (1-based, inclusive) its source spans there now, ok false when its
+// source has no byte range in a file.
+func sourceLines(g graph.Graph, dir string) (file string, start, end int, ok bool) {
+	srcID := graph.FindSourceChild(g, dir)
+	if srcID == "" {
+		return "", 0, 0, false
+	}
+	n, err := g.GetNode(srcID)
+	if err != nil || !hasFileOrigin(n) {
+		return "", 0, 0, false
+	}
+	start, _, err = lineCol(n.Origin.FilePath, n.Origin.StartByte)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	end = start
+	if n.Origin.EndByte > n.Origin.StartByte {
+		// The line of the construct's last byte, not of the one after it.
+		if end, _, err = lineCol(n.Origin.FilePath, n.Origin.EndByte-1); err != nil {
+			return "", 0, 0, false
+		}
+	}
+	return n.Origin.FilePath, start, end, true
+}
+
+// lineCol returns the 1-based line and column (in characters) of byte
+// offset off in file, reading only the bytes before it.
+func lineCol(file string, off uint32) (line, col int, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = f.Close() }()
+	prefix := make([]byte, off)
+	if _, err := io.ReadFull(f, prefix); err != nil {
+		return 0, 0, err
+	}
+	lastNL := bytes.LastIndexByte(prefix, '\n')
+	line = bytes.Count(prefix, []byte("\n")) + 1
+	col = utf8.RuneCount(prefix[lastNL+1:]) + 1
+	return line, col, nil
+}
+
+// hasFileOrigin reports whether n's content is a byte range of a source
+// file, which a JSON record's is not.
+func hasFileOrigin(n *graph.Node) bool {
+	return n.Origin != nil && n.Origin.FilePath != "" && n.Origin.JSONPath == ""
+}
+
+func (h *HistoryHandler) Match(path string) bool {
+	return h.History != nil && graph.IsHistoryPath(path)
+}
+
+func (h *HistoryHandler) Stat(path string) *VEntry {
+	parentDir, entryName := graph.ParseHistoryPath(path)
+	if parentDir != "/" {
+		ids, ok := h.lineCommits(parentDir)
+		switch {
+		case !ok:
+			return nil
+		case entryName == "":
+			return &VEntry{Kind: KindDir, Perm: 0o555}
+		case !slices.Contains(ids, entryName):
+			return nil
+		}
+		target := graph.VDirSymlinkTarget(parentDir, graph.HistoryDir+"/"+entryName)
+		return &VEntry{
+			Kind:    KindSymlink,
+			Size:    int64(len(target)),
+			Perm:    0o777,
+			Content: []byte(target),
+		}
+	}
+
+	if entryName == "" {
+		if _, err := h.History.Commits(); err != nil {
+			return nil
+		}
+		return &VEntry{Kind: KindDir, Perm: 0o555}
+	}
+	id, file, _ := strings.Cut(entryName, "/")
+	if !h.commit(id) {
+		return nil
+	}
+	if file == "" {
+		return &VEntry{Kind: KindDir, Perm: 0o555}
+	}
+	files, err := h.History.CommitFiles(id)
+	if err != nil {
+		return nil
+	}
+	data, ok := files[file]
+	if !ok {
+		return nil
+	}
+	return &VEntry{
+		Kind:    KindFile,
+		Size:    int64(len(data)),
+		Perm:    0o444,
+		Content: data,
+	}
+}
+
+func (h *HistoryHandler) ReadContent(path string) ([]byte, bool) {
+	entry := h.Stat(path)
+	if entry == nil || entry.Kind == KindDir {
+		return nil, false
+	}
+	return entry.Content, true
+}
+
+func (h *HistoryHandler) ListDir(path string) ([]DirExtra, bool) {
+	parentDir, entryName := graph.ParseHistoryPath(path)
+	if parentDir != "/" {
+		if entryName != "" {
+			return nil, false
+		}
+		ids, ok := h.lineCommits(parentDir)
+		if !ok {
+			return nil, false
+		}
+		entries := make([]DirExtra, 0, len(ids))
+		for _, id := range ids {
+			entries = append(entries, DirExtra{Name: id, Kind: KindSymlink, Perm: 0o777})
+		}
+		return entries, true
+	}
+
+	if entryName == "" {
+		ids, err := h.History.Commits()
+		if err != nil {
+			return nil, false
+		}
+		entries := make([]DirExtra, 0, len(ids))
+		for _, id := range ids {
+			entries = append(entries, DirExtra{Name: id, Kind: KindDir, Perm: 0o555})
+		}
+		return entries, true
+	}
+	if strings.Contains(entryName, "/") || !h.commit(entryName) {
+		return nil, false
+	}
+	files, err := h.History.CommitFiles(entryName)
+	if err != nil {
+		return nil, false
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]DirExtra, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, DirExtra{
+			Name: name,
+			Kind: KindFile,
+			Size: int64(len(files[name])),
+			Perm: 0o444,
+		})
+	}
+	return entries, true
+}
+
+func (h *HistoryHandler) DirExtras(parentPath string, node *graph.Node) []DirExtra {
+	if h.History == nil {
+		return nil
+	}
+	if parentPath == "/" {
+		return []DirExtra{{Name: graph.HistoryDir, Kind: KindDir, Perm: 0o555}}
+	}
+	if node == nil {
+		return nil
+	}
+	// Only the cheap check here: running git for every directory listed
+	// would make listings slow. The commits are looked up when _history/
+	// itself is listed.
+	srcID := graph.FindSourceChild(h.Graph, node.ID)
+	if srcID == "" {
+		return nil
+	}
+	if n, err := h.Graph.GetNode(srcID); err != nil || !hasFileOrigin(n) {
+		return nil
+	}
+	return []DirExtra{{Name: graph.HistoryDir, Kind: KindDir, Perm: 0o555}}
+}
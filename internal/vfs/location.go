@@ -20,34 +20,12 @@ func (h *LocationHandler) Match(path string) bool {
 }
 
 func (h *LocationHandler) Stat(path string) *VEntry {
-	parentDir := filepath.Dir(path)
-	node, err := h.Graph.GetNode(parentDir)
-	if err != nil {
-		return nil
-	}
-	loc, ok := node.Properties["location"]
-	if !ok || len(loc) == 0 {
-		return nil
-	}
-	return &VEntry{
-		Kind:    KindFile,
-		Size:    int64(len(loc)),
-		Perm:    0o444,
-		Content: loc,
-	}
+	return propertyFileStat(h.Graph, path, "location")
 }
 
 func (h *LocationHandler) ReadContent(path string) ([]byte, bool) {
-	parentDir := filepath.Dir(path)
-	node, err := h.Graph.GetNode(parentDir)
-	if err != nil {
-		return nil, false
-	}
-	loc, ok := node.Properties["location"]
-	if !ok || len(loc) == 0 {
-		return nil, false
-	}
-	return loc, true
+	loc := dirProperty(h.Graph, path, "location")
+	return loc, loc != nil
 }
 
 func (h *LocationHandler) ListDir(_ string) ([]DirExtra, bool) {
@@ -55,15 +33,47 @@ func (h *LocationHandler) ListDir(_ string) ([]DirExtra, bool) {
 }
 
 func (h *LocationHandler) DirExtras(parentPath string, node *graph.Node) []DirExtra {
-	if node != nil && node.Properties != nil {
-		if loc, ok := node.Properties["location"]; ok && len(loc) > 0 {
-			return []DirExtra{{
-				Name: graph.LocationFile,
-				Kind: KindFile,
-				Size: int64(len(loc)),
-				Perm: 0o444,
-			}}
-		}
+	return propertyFileExtras(node, graph.LocationFile, "location")
+}
+
+// dirProperty returns the non-empty Properties[key] of the directory holding
+// the virtual file at path, or nil.
+func dirProperty(g graph.Graph, path, key string) []byte {
+	node, err := g.GetNode(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	if v := node.Properties[key]; len(v) > 0 {
+		return v
 	}
 	return nil
 }
+
+// propertyFileStat stats the virtual file at path serving its directory's
+// Properties[key].
+func propertyFileStat(g graph.Graph, path, key string) *VEntry {
+	v := dirProperty(g, path, key)
+	if v == nil {
+		return nil
+	}
+	return &VEntry{
+		Kind:    KindFile,
+		Size:    int64(len(v)),
+		Perm:    0o444,
+		Content: v,
+	}
+}
+
+// propertyFileExtras lists the file name serving node's Properties[key],
+// when the property is set.
+func propertyFileExtras(node *graph.Node, name, key string) []DirExtra {
+	if node == nil || len(node.Properties[key]) == 0 {
+		return nil
+	}
+	return []DirExtra{{
+		Name: name,
+		Kind: KindFile,
+		Size: int64(len(node.Properties[key])),
+		Perm: 0o444,
+	}}
+}
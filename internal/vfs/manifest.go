@@ -0,0 +1,62 @@
+package vfs
+
+import (
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// ManifestHandler serves the /_manifest.json virtual file: every source
+// file the mount's ingest read, with its mtime, size, language, and content
+// hash. Content is nil (and the file absent) when the graph wasn't ingested
+// by this process, e.g. a .db mounted directly.
+type ManifestHandler struct {
+	Content func() []byte
+}
+
+func (h *ManifestHandler) content() []byte {
+	if h.Content == nil {
+		return nil
+	}
+	return h.Content()
+}
+
+func (h *ManifestHandler) Match(path string) bool {
+	return path == "/"+graph.ManifestJSON
+}
+
+func (h *ManifestHandler) Stat(path string) *VEntry {
+	content := h.content()
+	if content == nil {
+		return nil
+	}
+	return &VEntry{
+		Kind:    KindFile,
+		Size:    int64(len(content)),
+		Perm:    0o444,
+		Content: content,
+	}
+}
+
+func (h *ManifestHandler) ReadContent(path string) ([]byte, bool) {
+	content := h.content()
+	return content, content != nil
+}
+
+func (h *ManifestHandler) ListDir(_ string) ([]DirExtra, bool) {
+	return nil, false
+}
+
+func (h *ManifestHandler) DirExtras(parentPath string, _ *graph.Node) []DirExtra {
+	if parentPath != "/" {
+		return nil
+	}
+	content := h.content()
+	if content == nil {
+		return nil
+	}
+	return []DirExtra{{
+		Name: graph.ManifestJSON,
+		Kind: KindFile,
+		Size: int64(len(content)),
+		Perm: 0o444,
+	}}
+}
@@ -0,0 +1,58 @@
+package vfs
+
+import (
+	"strings"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// OriginHandler serves the virtual "_origin" file inside construct
+// directories: "FilePath:line:col" of where the construct starts, with an
+// absolute path and 1-based line and column, in the form editors and
+// terminals make clickable. The position is computed at ingest and kept in
+// the directory's graph.OriginProperty, like "location", so it reflects the
+// source as of the last ingest.
+type OriginHandler struct {
+	Graph graph.Graph
+}
+
+func (h *OriginHandler) Match(path string) bool {
+	return strings.HasSuffix(path, "/"+graph.OriginFile)
+}
+
+func (h *OriginHandler) Stat(path string) *VEntry {
+	data, ok := h.ReadContent(path)
+	if !ok {
+		return nil
+	}
+	return &VEntry{
+		Kind:    KindFile,
+		Size:    int64(len(data)),
+		Perm:    0o444,
+		Content: data,
+	}
+}
+
+func (h *OriginHandler) ReadContent(path string) ([]byte, bool) {
+	pos := dirProperty(h.Graph, path, graph.OriginProperty)
+	if pos == nil {
+		return nil, false
+	}
+	return append(pos[:len(pos):len(pos)], '\n'), true
+}
+
+func (h *OriginHandler) ListDir(_ string) ([]DirExtra, bool) {
+	return nil, false
+}
+
+func (h *OriginHandler) DirExtras(parentPath string, node *graph.Node) []DirExtra {
+	if node == nil || len(node.Properties[graph.OriginProperty]) == 0 {
+		return nil
+	}
+	return []DirExtra{{
+		Name: graph.OriginFile,
+		Kind: KindFile,
+		Size: int64(len(node.Properties[graph.OriginProperty]) + 1),
+		Perm: 0o444,
+	}}
+}
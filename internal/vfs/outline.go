@@ -0,0 +1,222 @@
+package vfs
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// outlineTTL bounds how stale the outlines may get after the graph
+// changes, like allConstructsTTL.
+const outlineTTL = 5 * time.Second
+
+// outlineEntry is one construct of a file's outline.
+type outlineEntry struct {
+	id         string
+	start, end int
+}
+
+// OutlineHandler serves the root _outline/ directory, which mirrors the
+// mounted source tree: _outline/<path> is the outline of that source file,
+// one "start-end dir" line per construct in source order, indented two
+// spaces per construct enclosing it. Constructs come from the definition
+// index (a graph.DefsProvider) and their lines from the construct
+// directory's "location" property.
+type OutlineHandler struct {
+	Graph graph.Graph
+
+	mu      sync.Mutex
+	files   map[string][]outlineEntry // source path → constructs in order
+	builtAt time.Time
+}
+
+// outlines returns every source file's outline entries, rebuilding them
+// when they are older than outlineTTL.
+func (h *OutlineHandler) outlines() map[string][]outlineEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.files == nil || time.Since(h.builtAt) > outlineTTL {
+		h.files = h.build()
+		h.builtAt = time.Now()
+	}
+	return h.files
+}
+
+func (h *OutlineHandler) build() map[string][]outlineEntry {
+	files := make(map[string][]outlineEntry)
+	dp, ok := h.Graph.(graph.DefsProvider)
+	if !ok {
+		return files
+	}
+	seen := make(map[string]bool)
+	for _, ids := range dp.DefsMap() {
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			n, err := h.Graph.GetNode(id)
+			if err != nil {
+				continue
+			}
+			file, start, end, ok := parseLocation(string(n.Properties["location"]))
+			if !ok || path.IsAbs(file) || file == ".." || strings.HasPrefix(file, "../") {
+				continue
+			}
+			files[file] = append(files[file], outlineEntry{id: id, start: start, end: end})
+		}
+	}
+	for _, entries := range files {
+		// Enclosing constructs before the ones inside them.
+		slices.SortFunc(entries, func(a, b outlineEntry) int {
+			return cmp.Or(cmp.Compare(a.start, b.start), cmp.Compare(b.end, a.end), strings.Compare(a.id, b.id))
+		})
+	}
+	return files
+}
+
+// parseLocation splits a "path:start:end" location property.
+func parseLocation(loc string) (file string, start, end int, ok bool) {
+	rest, endStr, found := cutLast(loc, ':')
+	if !found {
+		return "", 0, 0, false
+	}
+	file, startStr, found := cutLast(rest, ':')
+	if !found || file == "" {
+		return "", 0, 0, false
+	}
+	start, err1 := strconv.Atoi(startStr)
+	end, err2 := strconv.Atoi(endStr)
+	if err1 != nil || err2 != nil {
+		return "", 0, 0, false
+	}
+	return path.Clean(file), start, end, true
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s string, sep byte) (before, after string, found bool) {
+	if i := strings.LastIndexByte(s, sep); i >= 0 {
+		return s[:i], s[i+1:], true
+	}
+	return s, "", false
+}
+
+// renderOutline formats a file's outline.
+func renderOutline(entries []outlineEntry) []byte {
+	var out strings.Builder
+	var enclosing []int // ends of the constructs enclosing the current one
+	for _, e := range entries {
+		for len(enclosing) > 0 && enclosing[len(enclosing)-1] < e.end {
+			enclosing = enclosing[:len(enclosing)-1]
+		}
+		fmt.Fprintf(&out, "%s%d-%d %s\n", strings.Repeat("  ", len(enclosing)), e.start, e.end, e.id)
+		enclosing = append(enclosing, e.end)
+	}
+	return []byte(out.String())
+}
+
+// outlinePath returns the source path of an _outline/ path ("" for the
+// directory itself), ok false for any other path.
+func outlinePath(p string) (rel string, ok bool) {
+	rest, found := strings.CutPrefix(p, "/"+graph.OutlineDir)
+	if !found || (rest != "" && rest[0] != '/') {
+		return "", false
+	}
+	return strings.Trim(rest, "/"), true
+}
+
+func (h *OutlineHandler) Match(path string) bool {
+	_, ok := outlinePath(path)
+	return ok
+}
+
+func (h *OutlineHandler) Stat(p string) *VEntry {
+	rel, ok := outlinePath(p)
+	if !ok {
+		return nil
+	}
+	files := h.outlines()
+	if entries, ok := files[rel]; ok && rel != "" {
+		data := renderOutline(entries)
+		return &VEntry{Kind: KindFile, Size: int64(len(data)), Perm: 0o444, Content: data}
+	}
+	if len(outlineChildren(files, rel)) > 0 {
+		return &VEntry{Kind: KindDir, Perm: 0o555}
+	}
+	return nil
+}
+
+func (h *OutlineHandler) ReadContent(p string) ([]byte, bool) {
+	rel, ok := outlinePath(p)
+	if !ok || rel == "" {
+		return nil, false
+	}
+	entries, ok := h.outlines()[rel]
+	if !ok {
+		return nil, false
+	}
+	return renderOutline(entries), true
+}
+
+func (h *OutlineHandler) ListDir(p string) ([]DirExtra, bool) {
+	rel, ok := outlinePath(p)
+	if !ok {
+		return nil, false
+	}
+	children := outlineChildren(h.outlines(), rel)
+	if len(children) == 0 {
+		return nil, false
+	}
+	return children, true
+}
+
+// outlineChildren lists the directory dir ("" for the root) of the
+// outlined source tree: a file per outlined source file in it and a
+// directory per subdirectory holding one.
+func outlineChildren(files map[string][]outlineEntry, dir string) []DirExtra {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+	kinds := make(map[string]EntryKind)
+	for file := range files {
+		rest, ok := strings.CutPrefix(file, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		if name, _, nested := strings.Cut(rest, "/"); nested {
+			kinds[name] = KindDir
+		} else if _, dup := kinds[name]; !dup {
+			kinds[name] = KindFile
+		}
+	}
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	extras := make([]DirExtra, 0, len(names))
+	for _, name := range names {
+		if kinds[name] == KindDir {
+			extras = append(extras, DirExtra{Name: name, Kind: KindDir, Perm: 0o555})
+			continue
+		}
+		data := renderOutline(files[prefix+name])
+		extras = append(extras, DirExtra{Name: name, Kind: KindFile, Size: int64(len(data)), Perm: 0o444})
+	}
+	return extras
+}
+
+func (h *OutlineHandler) DirExtras(parentPath string, _ *graph.Node) []DirExtra {
+	if parentPath != "/" || len(h.outlines()) == 0 {
+		return nil
+	}
+	return []DirExtra{{Name: graph.OutlineDir, Kind: KindDir, Perm: 0o555}}
+}
@@ -0,0 +1,75 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// RawHandler serves the virtual "_raw" file inside construct directories:
+// the complete, current source file the construct was projected from.
+// It is an escape hatch for context beyond the construct and its "context"
+// file. Always read-only — edits go through the construct's "source".
+type RawHandler struct {
+	Graph graph.Graph
+}
+
+func (h *RawHandler) Match(path string) bool {
+	return strings.HasSuffix(path, "/"+graph.RawFile)
+}
+
+// Stat sizes the file with os.Stat and leaves Content nil: source files
+// can be large, so the bytes are read only by ReadContent.
+func (h *RawHandler) Stat(path string) *VEntry {
+	src := graph.RawSourceFile(h.Graph, filepath.Dir(path))
+	if src == "" {
+		return nil
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil
+	}
+	return &VEntry{
+		Kind: KindFile,
+		Size: info.Size(),
+		Perm: 0o444,
+	}
+}
+
+func (h *RawHandler) ReadContent(path string) ([]byte, bool) {
+	src := graph.RawSourceFile(h.Graph, filepath.Dir(path))
+	if src == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func (h *RawHandler) ListDir(_ string) ([]DirExtra, bool) {
+	return nil, false
+}
+
+func (h *RawHandler) DirExtras(parentPath string, node *graph.Node) []DirExtra {
+	if node == nil {
+		return nil
+	}
+	src := graph.RawSourceFile(h.Graph, node.ID)
+	if src == "" {
+		return nil
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil
+	}
+	return []DirExtra{{
+		Name: graph.RawFile,
+		Kind: KindFile,
+		Size: info.Size(),
+		Perm: 0o444,
+	}}
+}
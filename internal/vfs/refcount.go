@@ -0,0 +1,94 @@
+package vfs
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// refCountTTL bounds how stale a cached count may get after the refs index
+// changes (write-back, re-ingest).
+const refCountTTL = 5 * time.Second
+
+// RefCountHandler serves the virtual "_refcount" file inside construct
+// directories (those with a source child): the number of callers
+// GetCallers reports for the construct, so agents can rank constructs by
+// fan-in. Constructs nobody calls read "0". Counts are computed on first
+// read and cached for refCountTTL.
+type RefCountHandler struct {
+	Graph graph.Graph
+
+	mu      sync.Mutex
+	counts  map[string]int // dir ID → caller count
+	builtAt time.Time
+}
+
+func (h *RefCountHandler) Match(path string) bool {
+	return strings.HasSuffix(path, "/"+graph.RefCountFile)
+}
+
+func (h *RefCountHandler) Stat(path string) *VEntry {
+	data, ok := h.ReadContent(path)
+	if !ok {
+		return nil
+	}
+	return &VEntry{
+		Kind:    KindFile,
+		Size:    int64(len(data)),
+		Perm:    0o444,
+		Content: data,
+	}
+}
+
+func (h *RefCountHandler) ReadContent(path string) ([]byte, bool) {
+	return h.refCount(filepath.Dir(path))
+}
+
+func (h *RefCountHandler) ListDir(_ string) ([]DirExtra, bool) {
+	return nil, false
+}
+
+func (h *RefCountHandler) DirExtras(_ string, node *graph.Node) []DirExtra {
+	if node == nil {
+		return nil
+	}
+	data, ok := h.refCount(node.ID)
+	if !ok {
+		return nil
+	}
+	return []DirExtra{{
+		Name: graph.RefCountFile,
+		Kind: KindFile,
+		Size: int64(len(data)),
+		Perm: 0o444,
+	}}
+}
+
+// refCount renders the _refcount content for construct directory dirID.
+func (h *RefCountHandler) refCount(dirID string) ([]byte, bool) {
+	dirID = strings.TrimPrefix(dirID, "/")
+	if dirID == "" || graph.FindSourceChild(h.Graph, dirID) == "" {
+		return nil, false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.counts == nil || time.Since(h.builtAt) > refCountTTL {
+		h.counts = make(map[string]int)
+		h.builtAt = time.Now()
+	}
+	n, ok := h.counts[dirID]
+	if !ok {
+		callers, err := h.Graph.GetCallers(filepath.Base(dirID))
+		if err != nil {
+			return nil, false
+		}
+		n = len(callers)
+		h.counts[dirID] = n
+	}
+	return []byte(strconv.Itoa(n) + "\n"), true
+}
@@ -12,11 +12,18 @@ type Resolver struct {
 	handlers []VHandler
 
 	// Typed references for post-construction configuration.
-	// Backends call SetPromptContent/EnableQuery/SetWritable
+	// Backends call SetPromptContent/SetSchemaJSON/EnableQuery/SetWritable
 	// instead of holding direct handler pointers.
-	promptH *PromptHandler
-	queryH  *QueryHandler
-	diagH   *DiagnosticsHandler
+	schemaH   *SchemaHandler
+	inferredH *SchemaHandler
+	promptH   *PromptHandler
+	queryH    *QueryHandler
+	diagH     *DiagnosticsHandler
+	manifestH *ManifestHandler
+	historyH  *HistoryHandler
+	coverageH *CoverageHandler
+	sexprH    *SExprHandler
+	astH      *SExprHandler
 }
 
 // NewResolver creates a Resolver with the given handlers.
@@ -31,20 +38,43 @@ func NewResolver(handlers ...VHandler) *Resolver {
 func NewDefaultResolver(g graph.Graph, schemaJSON []byte) *Resolver {
 	promptH := &PromptHandler{}
 	queryH := &QueryHandler{}
-	diagH := &DiagnosticsHandler{DiagStatus: &sync.Map{}}
+	diagH := &DiagnosticsHandler{DiagStatus: &sync.Map{}, Graph: g}
 	schemaH := &SchemaHandler{Content: schemaJSON}
+	inferredH := &SchemaHandler{Name: graph.SchemaInferredJSON}
+	topologyH := &TopologyHandler{Graph: g}
+	manifestH := &ManifestHandler{}
 	contextH := &ContextHandler{Graph: g}
 	locationH := &LocationHandler{Graph: g}
+	schemaPathH := &SchemaPathHandler{Graph: g}
+	sexprH := &SExprHandler{Graph: g}
+	astH := &SExprHandler{Graph: g, Name: graph.ASTFile}
+	rawH := &RawHandler{Graph: g}
+	originH := &OriginHandler{Graph: g}
+	refCountH := &RefCountHandler{Graph: g}
 	callersH := &CallersHandler{Graph: g}
 	calleesH := &CalleesHandler{Graph: g}
+	testsH := &TestsHandler{Graph: g}
+	typesUsedH := &TypesUsedHandler{Graph: g}
+	historyH := &HistoryHandler{Graph: g}
+	coverageH := &CoverageHandler{Graph: g}
+	groupViewsH := &GroupViewsHandler{Graph: g}
+	allH := &AllConstructsHandler{Graph: g}
+	outlineH := &OutlineHandler{Graph: g}
 
 	// Order matters: query before callers/callees (both can have "/" paths).
 	r := NewResolver(
-		schemaH, promptH, queryH, diagH, contextH, locationH, callersH, calleesH,
+		schemaH, inferredH, topologyH, manifestH, promptH, queryH, diagH, contextH, locationH, schemaPathH, sexprH, astH, rawH, originH, refCountH, coverageH, callersH, calleesH, testsH, typesUsedH, historyH, groupViewsH, allH, outlineH,
 	)
+	r.schemaH = schemaH
+	r.inferredH = inferredH
 	r.promptH = promptH
 	r.queryH = queryH
 	r.diagH = diagH
+	r.manifestH = manifestH
+	r.historyH = historyH
+	r.coverageH = coverageH
+	r.sexprH = sexprH
+	r.astH = astH
 	return r
 }
 
@@ -55,6 +85,21 @@ func (r *Resolver) SetPromptContent(content []byte) {
 	}
 }
 
+// SetSchemaJSON replaces the content of the /_schema.json virtual file.
+func (r *Resolver) SetSchemaJSON(content []byte) {
+	if r.schemaH != nil {
+		r.schemaH.SetContent(content)
+	}
+}
+
+// SetInferredSchemaJSON serves content as the /_schema.inferred.json
+// virtual file, absent until set.
+func (r *Resolver) SetInferredSchemaJSON(content []byte) {
+	if r.inferredH != nil {
+		r.inferredH.SetContent(content)
+	}
+}
+
 // EnableQuery marks the /.query/ magic directory as active.
 func (r *Resolver) EnableQuery() {
 	if r.queryH != nil {
@@ -72,6 +117,67 @@ func (r *Resolver) SetWritable(writable bool, diagStatus *sync.Map) {
 	}
 }
 
+// SetIngestTimings serves fn's report as /_diagnostics/ingest-timings.
+func (r *Resolver) SetIngestTimings(fn func() []byte) {
+	if r.diagH != nil {
+		r.diagH.IngestTimings = fn
+	}
+}
+
+// SetIdentifiers serves fn's ref token frequency report as
+// /_diagnostics/identifiers.
+func (r *Resolver) SetIdentifiers(fn func() []byte) {
+	if r.diagH != nil {
+		r.diagH.Identifiers = fn
+	}
+}
+
+// SetImportCycles serves fn's circular import report as
+// /_diagnostics/import-cycles while it renders non-nil.
+func (r *Resolver) SetImportCycles(fn func() []byte) {
+	if r.diagH != nil {
+		r.diagH.ImportCycles = fn
+	}
+}
+
+// SetSExpr serves parse's S-expression of each construct's syntax tree as
+// its _sexp file; nil removes them.
+func (r *Resolver) SetSExpr(parse func(file, langName string, start, end uint32) ([]byte, error)) {
+	if r.sexprH != nil {
+		r.sexprH.Parse = parse
+	}
+}
+
+// SetAST serves parse's JSON serialization of each construct's syntax tree
+// as its _ast.json file; nil removes them.
+func (r *Resolver) SetAST(parse func(file, langName string, start, end uint32) ([]byte, error)) {
+	if r.astH != nil {
+		r.astH.Parse = parse
+	}
+}
+
+// SetManifest serves fn's listing of the ingested source files as
+// /_manifest.json.
+func (r *Resolver) SetManifest(fn func() []byte) {
+	if r.manifestH != nil {
+		r.manifestH.Content = fn
+	}
+}
+
+// SetHistory projects h as the _history/ directories; nil removes them.
+func (r *Resolver) SetHistory(h CommitHistory) {
+	if r.historyH != nil {
+		r.historyH.History = h
+	}
+}
+
+// SetCoverage projects c as the _coverage files; nil removes them.
+func (r *Resolver) SetCoverage(c CoverageProfile) {
+	if r.coverageH != nil {
+		r.coverageH.Coverage = c
+	}
+}
+
 // Resolve returns a VEntry for the path, or nil if no handler matches.
 // When a handler matches but Stat returns nil (e.g., a node named "context"
 // that has no virtual content), resolution continues to the next handler
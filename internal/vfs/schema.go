@@ -1,27 +1,62 @@
 package vfs
 
-import "github.com/agentic-research/mache/internal/graph"
+import (
+	"sync"
 
-// SchemaHandler serves the /_schema.json virtual file.
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// SchemaHandler serves a schema as a read-only root file: the effective
+// schema as /_schema.json, and under --infer the inferred schema with its
+// provenance as /_schema.inferred.json. The file is absent while Content
+// is nil.
 type SchemaHandler struct {
+	Name    string // root file name; defaults to graph.SchemaDotJSON
+	mu      sync.RWMutex
 	Content []byte // Serialized schema JSON
 }
 
+// SetContent replaces the served schema JSON. Safe for concurrent use
+// with reads (schema hot-reload).
+func (h *SchemaHandler) SetContent(content []byte) {
+	h.mu.Lock()
+	h.Content = content
+	h.mu.Unlock()
+}
+
+func (h *SchemaHandler) content() []byte {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.Content
+}
+
+func (h *SchemaHandler) name() string {
+	if h.Name == "" {
+		return graph.SchemaDotJSON
+	}
+	return h.Name
+}
+
 func (h *SchemaHandler) Match(path string) bool {
-	return path == "/"+graph.SchemaDotJSON
+	return path == "/"+h.name()
 }
 
 func (h *SchemaHandler) Stat(path string) *VEntry {
+	content := h.content()
+	if content == nil {
+		return nil
+	}
 	return &VEntry{
 		Kind:    KindFile,
-		Size:    int64(len(h.Content)),
+		Size:    int64(len(content)),
 		Perm:    0o444,
-		Content: h.Content,
+		Content: content,
 	}
 }
 
 func (h *SchemaHandler) ReadContent(path string) ([]byte, bool) {
-	return h.Content, true
+	content := h.content()
+	return content, content != nil
 }
 
 func (h *SchemaHandler) ListDir(_ string) ([]DirExtra, bool) {
@@ -29,13 +64,17 @@ func (h *SchemaHandler) ListDir(_ string) ([]DirExtra, bool) {
 }
 
 func (h *SchemaHandler) DirExtras(parentPath string, _ *graph.Node) []DirExtra {
-	if parentPath == "/" {
-		return []DirExtra{{
-			Name: graph.SchemaDotJSON,
-			Kind: KindFile,
-			Size: int64(len(h.Content)),
-			Perm: 0o444,
-		}}
+	if parentPath != "/" {
+		return nil
+	}
+	content := h.content()
+	if content == nil {
+		return nil
 	}
-	return nil
+	return []DirExtra{{
+		Name: h.name(),
+		Kind: KindFile,
+		Size: int64(len(content)),
+		Perm: 0o444,
+	}}
 }
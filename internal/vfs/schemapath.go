@@ -0,0 +1,35 @@
+package vfs
+
+import (
+	"strings"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// SchemaPathHandler serves the virtual "_schema_path" file inside directory
+// nodes projected with --debug-schema: the names of the schema nodes leading
+// to the rule that produced the directory (e.g., "vulns > {{.item.cveID}}").
+type SchemaPathHandler struct {
+	Graph graph.Graph
+}
+
+func (h *SchemaPathHandler) Match(path string) bool {
+	return strings.HasSuffix(path, "/"+graph.SchemaPathFile)
+}
+
+func (h *SchemaPathHandler) Stat(path string) *VEntry {
+	return propertyFileStat(h.Graph, path, graph.SchemaPathProperty)
+}
+
+func (h *SchemaPathHandler) ReadContent(path string) ([]byte, bool) {
+	v := dirProperty(h.Graph, path, graph.SchemaPathProperty)
+	return v, v != nil
+}
+
+func (h *SchemaPathHandler) ListDir(_ string) ([]DirExtra, bool) {
+	return nil, false
+}
+
+func (h *SchemaPathHandler) DirExtras(_ string, node *graph.Node) []DirExtra {
+	return propertyFileExtras(node, graph.SchemaPathFile, graph.SchemaPathProperty)
+}
@@ -0,0 +1,92 @@
+package vfs
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// SExprHandler serves the virtual "_sexp" file inside construct directories
+// once Parse is set (--debug-schema): the tree-sitter parse tree of the
+// construct as an S-expression, naming the node types and fields a
+// selector can target. Named graph.ASTFile, it serves the tree as JSON
+// instead (--ast). It appears only for constructs whose source child
+// carries a byte range, and is re-parsed from the source file on each
+// read.
+type SExprHandler struct {
+	Graph graph.Graph
+	Name  string // file name; defaults to graph.SExprFile
+	// Parse renders the syntax tree of bytes [start, end) of file, parsed
+	// as langName (see ingest.SExpr and ingest.ASTJSON).
+	Parse func(file, langName string, start, end uint32) ([]byte, error)
+}
+
+func (h *SExprHandler) name() string {
+	if h.Name == "" {
+		return graph.SExprFile
+	}
+	return h.Name
+}
+
+func (h *SExprHandler) Match(path string) bool {
+	return h.Parse != nil && strings.HasSuffix(path, "/"+h.name())
+}
+
+func (h *SExprHandler) Stat(path string) *VEntry {
+	data, ok := h.ReadContent(path)
+	if !ok {
+		return nil
+	}
+	return &VEntry{
+		Kind:    KindFile,
+		Size:    int64(len(data)),
+		Perm:    0o444,
+		Content: data,
+	}
+}
+
+func (h *SExprHandler) ReadContent(path string) ([]byte, bool) {
+	dir, err := h.Graph.GetNode(graph.NormalizeID(filepath.Dir(path)))
+	if err != nil {
+		return nil, false
+	}
+	return h.sexpr(dir)
+}
+
+func (h *SExprHandler) ListDir(_ string) ([]DirExtra, bool) {
+	return nil, false
+}
+
+func (h *SExprHandler) DirExtras(_ string, node *graph.Node) []DirExtra {
+	if h.Parse == nil || node == nil {
+		return nil
+	}
+	data, ok := h.sexpr(node)
+	if !ok {
+		return nil
+	}
+	return []DirExtra{{
+		Name: h.name(),
+		Kind: KindFile,
+		Size: int64(len(data)),
+		Perm: 0o444,
+	}}
+}
+
+// sexpr renders the file's content for construct directory dir.
+func (h *SExprHandler) sexpr(dir *graph.Node) ([]byte, bool) {
+	srcID := graph.FindSourceChild(h.Graph, dir.ID)
+	if srcID == "" {
+		return nil, false
+	}
+	n, err := h.Graph.GetNode(srcID)
+	if err != nil || n.Origin == nil || n.Origin.FilePath == "" || n.Origin.JSONPath != "" {
+		return nil, false
+	}
+	data, err := h.Parse(n.Origin.FilePath, string(dir.Properties["lang"]), n.Origin.StartByte, n.Origin.EndByte)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
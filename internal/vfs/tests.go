@@ -0,0 +1,106 @@
+package vfs
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// TestsHandler serves the virtual _tests/ directory: for a construct, the
+// test functions that likely cover it by name (graph.TestNamer), as symlink
+// entries pointing at each test's source. Requires a graph.TestLocator.
+type TestsHandler struct {
+	Graph graph.Graph
+}
+
+// testSources returns the source node IDs of tests covering the construct
+// at dir, or nil when dir is not a construct or has no tests.
+func (h *TestsHandler) testSources(dir string) []string {
+	loc, ok := h.Graph.(graph.TestLocator)
+	if !ok || dir == "/" {
+		return nil
+	}
+	ids := loc.TestsFor(filepath.Base(dir))
+	if len(ids) == 0 || graph.FindSourceChild(h.Graph, dir) == "" {
+		return nil
+	}
+	self := strings.TrimPrefix(dir, "/")
+	var sources []string
+	for _, id := range ids {
+		if id == self {
+			continue
+		}
+		if src := graph.FindSourceChild(h.Graph, id); src != "" {
+			sources = append(sources, src)
+		}
+	}
+	return sources
+}
+
+func (h *TestsHandler) Match(path string) bool {
+	return graph.IsTestsPath(path)
+}
+
+func (h *TestsHandler) Stat(path string) *VEntry {
+	parentDir, entryName := graph.ParseTestsPath(path)
+	sources := h.testSources(parentDir)
+	if len(sources) == 0 {
+		return nil
+	}
+	if entryName == "" {
+		return &VEntry{Kind: KindDir, Perm: 0o555}
+	}
+	for _, src := range sources {
+		if strings.ReplaceAll(src, "/", "_") == entryName {
+			target := graph.VDirSymlinkTarget(parentDir, src)
+			return &VEntry{
+				Kind:    KindSymlink,
+				Size:    int64(len(target)),
+				Perm:    0o777,
+				Content: []byte(target),
+				NodeID:  src,
+			}
+		}
+	}
+	return nil
+}
+
+func (h *TestsHandler) ReadContent(path string) ([]byte, bool) {
+	entry := h.Stat(path)
+	if entry == nil || entry.Kind != KindSymlink {
+		return nil, false
+	}
+	return entry.Content, true
+}
+
+func (h *TestsHandler) ListDir(path string) ([]DirExtra, bool) {
+	parentDir, entryName := graph.ParseTestsPath(path)
+	if entryName != "" {
+		return nil, false
+	}
+	sources := h.testSources(parentDir)
+	if len(sources) == 0 {
+		return nil, false
+	}
+	entries := make([]DirExtra, 0, len(sources))
+	for _, src := range sources {
+		entries = append(entries, DirExtra{
+			Name: strings.ReplaceAll(src, "/", "_"),
+			Kind: KindSymlink,
+			Perm: 0o777,
+		})
+	}
+	return entries, true
+}
+
+func (h *TestsHandler) DirExtras(parentPath string, _ *graph.Node) []DirExtra {
+	if len(h.testSources(parentPath)) == 0 {
+		return nil
+	}
+	return []DirExtra{{
+		Name: graph.TestsDir,
+		Kind: KindDir,
+		Perm: 0o555,
+	}}
+}
@@ -0,0 +1,72 @@
+package vfs
+
+import (
+	"encoding/json"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// TopologyHandler serves the /_topology.json virtual file: the realized
+// structure of the projection (graph.Topology), computed on read so it
+// follows schema reloads. Requires a graph.TopologyDescriber.
+type TopologyHandler struct {
+	Graph graph.Graph
+}
+
+func (h *TopologyHandler) content() []byte {
+	td, ok := h.Graph.(graph.TopologyDescriber)
+	if !ok {
+		return nil
+	}
+	t := td.Topology()
+	if t == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return nil
+	}
+	return append(data, '\n')
+}
+
+func (h *TopologyHandler) Match(path string) bool {
+	return path == "/"+graph.TopologyJSON
+}
+
+func (h *TopologyHandler) Stat(path string) *VEntry {
+	content := h.content()
+	if content == nil {
+		return nil
+	}
+	return &VEntry{
+		Kind:    KindFile,
+		Size:    int64(len(content)),
+		Perm:    0o444,
+		Content: content,
+	}
+}
+
+func (h *TopologyHandler) ReadContent(path string) ([]byte, bool) {
+	content := h.content()
+	return content, content != nil
+}
+
+func (h *TopologyHandler) ListDir(_ string) ([]DirExtra, bool) {
+	return nil, false
+}
+
+func (h *TopologyHandler) DirExtras(parentPath string, _ *graph.Node) []DirExtra {
+	if parentPath != "/" {
+		return nil
+	}
+	content := h.content()
+	if content == nil {
+		return nil
+	}
+	return []DirExtra{{
+		Name: graph.TopologyJSON,
+		Kind: KindFile,
+		Size: int64(len(content)),
+		Perm: 0o444,
+	}}
+}
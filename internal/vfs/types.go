@@ -1,6 +1,6 @@
 // Package vfs provides a pluggable virtual handler chain for mache's
 // virtual path types (_schema.json, PROMPT.txt, _diagnostics/, context,
-// callers/, callees/, .query/). Both the FUSE and NFS backends delegate
+// location, _raw, callers/, callees/, .query/). Both the FUSE and NFS backends delegate
 // to a shared Resolver instead of duplicating if-chains.
 package vfs
 
@@ -0,0 +1,100 @@
+package vfs
+
+import (
+	"strings"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// TypesUsedHandler serves the virtual types-used/ directory: for a construct,
+// the type constructs it references in parameters, results, locals, and
+// fields, as symlink entries named for each type's directory and pointing
+// at its source leaf, which NFS serves as a file holding the type's source,
+// like callees/. Requires a graph.TypeRefLocator.
+type TypesUsedHandler struct {
+	Graph graph.Graph
+}
+
+// typesUsed returns the dir IDs of the types the construct at dir uses.
+func (h *TypesUsedHandler) typesUsed(dir string) []string {
+	loc, ok := h.Graph.(graph.TypeRefLocator)
+	if !ok || dir == "/" {
+		return nil
+	}
+	return loc.TypesUsed(strings.TrimPrefix(dir, "/"))
+}
+
+func (h *TypesUsedHandler) Match(path string) bool {
+	return graph.IsTypesUsedPath(path)
+}
+
+func (h *TypesUsedHandler) Stat(path string) *VEntry {
+	parentDir, entryName := graph.ParseTypesUsedPath(path)
+	ids := h.typesUsed(parentDir)
+	if len(ids) == 0 {
+		return nil
+	}
+	if entryName == "" {
+		return &VEntry{Kind: KindDir, Perm: 0o555}
+	}
+	for _, id := range ids {
+		if strings.ReplaceAll(id, "/", "_") != entryName {
+			continue
+		}
+		src := graph.FindSourceChild(h.Graph, id)
+		if src == "" {
+			return nil
+		}
+		target := graph.VDirSymlinkTarget(parentDir, src)
+		return &VEntry{
+			Kind:    KindSymlink,
+			Size:    int64(len(target)),
+			Perm:    0o777,
+			Content: []byte(target),
+			NodeID:  src,
+		}
+	}
+	return nil
+}
+
+func (h *TypesUsedHandler) ReadContent(path string) ([]byte, bool) {
+	entry := h.Stat(path)
+	if entry == nil || entry.Kind != KindSymlink {
+		return nil, false
+	}
+	return entry.Content, true
+}
+
+func (h *TypesUsedHandler) ListDir(path string) ([]DirExtra, bool) {
+	parentDir, entryName := graph.ParseTypesUsedPath(path)
+	if entryName != "" {
+		return nil, false
+	}
+	ids := h.typesUsed(parentDir)
+	if len(ids) == 0 {
+		return nil, false
+	}
+	entries := make([]DirExtra, 0, len(ids))
+	for _, id := range ids {
+		if graph.FindSourceChild(h.Graph, id) == "" {
+			continue
+		}
+		entries = append(entries, DirExtra{
+			Name: strings.ReplaceAll(id, "/", "_"),
+			Kind: KindSymlink,
+			Perm: 0o777,
+		})
+	}
+	return entries, true
+}
+
+func (h *TypesUsedHandler) DirExtras(parentPath string, _ *graph.Node) []DirExtra {
+	if len(h.typesUsed(parentPath)) == 0 {
+		return nil
+	}
+	return []DirExtra{{
+		Name: graph.TypesUsedDir,
+		Kind: KindDir,
+		Perm: 0o555,
+	}}
+}
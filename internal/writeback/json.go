@@ -0,0 +1,181 @@
+package writeback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ohler55/ojg/jp"
+
+	"github.com/agentic-research/mache/internal/graph"
+)
+
+// SetJSONField sets the scalar field at origin.JSONPath in the JSON file
+// origin.FilePath to content and rewrites the file atomically. A string
+// field takes content verbatim minus one trailing newline; any other scalar
+// field takes content parsed as a JSON scalar (a number, true/false, null, or
+// a quoted string).
+//
+// Only the field's value is replaced; every other byte of the file, key
+// order and formatting included, is kept as written.
+func SetJSONField(origin graph.SourceOrigin, content []byte) error {
+	x, err := jp.ParseString(origin.JSONPath)
+	if err != nil {
+		return fmt.Errorf("invalid json path %q: %w", origin.JSONPath, err)
+	}
+	path, err := normalizedPath(x)
+	if err != nil {
+		return fmt.Errorf("json path %q: %w", origin.JSONPath, err)
+	}
+	info, err := os.Stat(origin.FilePath)
+	if err != nil {
+		return fmt.Errorf("stat source %s: %w", origin.FilePath, err)
+	}
+	if info.Size() > MaxSpliceFileSize {
+		return fmt.Errorf("source file %s is %d bytes (max %d)", origin.FilePath, info.Size(), MaxSpliceFileSize)
+	}
+	src, err := os.ReadFile(origin.FilePath)
+	if err != nil {
+		return fmt.Errorf("read source %s: %w", origin.FilePath, err)
+	}
+
+	var data any
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return fmt.Errorf("parse %s: %w", origin.FilePath, err)
+	}
+	cur, found := x.FirstFound(data)
+	if !found {
+		return fmt.Errorf("%s: no field at %s", origin.FilePath, origin.JSONPath)
+	}
+
+	text := bytes.TrimSuffix(bytes.TrimSuffix(content, []byte("\n")), []byte("\r"))
+	var value any
+	switch cur.(type) {
+	case string:
+		value = string(text)
+	case json.Number, bool, nil:
+		if value, err = parseJSONScalar(text); err != nil {
+			return fmt.Errorf("%s: %w", origin.JSONPath, err)
+		}
+	default:
+		return fmt.Errorf("%s: field holds a %T, not a scalar", origin.JSONPath, cur)
+	}
+
+	var enc bytes.Buffer
+	e := json.NewEncoder(&enc)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(value); err != nil {
+		return fmt.Errorf("encode %s: %w", origin.JSONPath, err)
+	}
+	start, end, err := jsonValueSpan(src, path)
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", origin.FilePath, origin.JSONPath, err)
+	}
+
+	result := make([]byte, 0, len(src)+enc.Len())
+	result = append(result, src[:start]...)
+	result = append(result, bytes.TrimSuffix(enc.Bytes(), []byte("\n"))...)
+	result = append(result, src[end:]...)
+	return writeAtomic(origin.FilePath, result, info.Mode())
+}
+
+// normalizedPath converts a normalized JSONPath ($.a[0].b, as ingest
+// records it) to its object keys (string) and array indices (int).
+func normalizedPath(x jp.Expr) ([]any, error) {
+	var path []any
+	for _, f := range x {
+		switch f := f.(type) {
+		case jp.Root:
+		case jp.Child:
+			path = append(path, string(f))
+		case jp.Nth:
+			path = append(path, int(f))
+		default:
+			return nil, fmt.Errorf("not a normalized path (%T)", f)
+		}
+	}
+	return path, nil
+}
+
+// jsonValueSpan returns the byte range in src of the scalar value at path.
+func jsonValueSpan(src []byte, path []any) (start, end int, err error) {
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+	for {
+		// The decoder's offset is the end of the last token; the value
+		// starts after any separator and whitespace.
+		start = int(dec.InputOffset())
+		for start < len(src) && bytes.IndexByte([]byte(" \t\r\n:,"), src[start]) >= 0 {
+			start++
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(path) == 0 {
+			if _, ok := tok.(json.Delim); ok {
+				return 0, 0, fmt.Errorf("not a scalar")
+			}
+			return start, int(dec.InputOffset()), nil
+		}
+		if found, err := seekChild(dec, tok, path[0]); err != nil {
+			return 0, 0, err
+		} else if !found {
+			return 0, 0, fmt.Errorf("no field")
+		}
+		path = path[1:]
+	}
+}
+
+// seekChild advances dec, positioned just after the container token tok,
+// to just before the value of its child key (a string key or int index),
+// reporting false when the container has no such child.
+func seekChild(dec *json.Decoder, tok json.Token, key any) (bool, error) {
+	delim, _ := tok.(json.Delim)
+	for i := 0; dec.More(); i++ {
+		switch delim {
+		case '{':
+			k, err := dec.Token()
+			if err != nil {
+				return false, err
+			}
+			if k == key {
+				return true, nil
+			}
+		case '[':
+			if i == key {
+				return true, nil
+			}
+		default:
+			return false, nil
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// parseJSONScalar decodes text as exactly one JSON number, boolean, null,
+// or string.
+func parseJSONScalar(text []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("not a JSON value: %q", text)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("not a single JSON value: %q", text)
+	}
+	switch v.(type) {
+	case map[string]any, []any:
+		return nil, fmt.Errorf("not a JSON scalar: %q", text)
+	}
+	return v, nil
+}
@@ -0,0 +1,70 @@
+package writeback
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentic-research/mache/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetJSONField(t *testing.T) {
+	src := "{\n    \"users\": [\n        {\"name\": \"Alice\", \"role\": \"admin\", \"age\": 30, \"id\": 12345678901234567890, \"active\": true}\n    ]\n}\n"
+	path := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	origin := func(p string) graph.SourceOrigin { return graph.SourceOrigin{FilePath: path, JSONPath: p} }
+
+	require.NoError(t, SetJSONField(origin("$.users[0].role"), []byte("owner\n")))
+	require.NoError(t, SetJSONField(origin("$.users[0].age"), []byte("31\n")))
+	require.NoError(t, SetJSONField(origin("$.users[0].active"), []byte("false")))
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+    "users": [
+        {"name": "Alice", "role": "owner", "age": 31, "id": 12345678901234567890, "active": false}
+    ]
+}
+`, string(got), "key order, layout, untouched numbers, and the trailing newline survive")
+
+	assert.Error(t, SetJSONField(origin("$.users[0].age"), []byte("thirty")))
+	assert.Error(t, SetJSONField(origin("$.users[0].age"), []byte("[1]")))
+	assert.Error(t, SetJSONField(origin("$.users[0]"), []byte("x")), "objects are not scalar fields")
+	assert.Error(t, SetJSONField(origin("$.users[0].missing"), []byte("x")))
+
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, got, after, "failed writes leave the file alone")
+}
+
+func TestSetJSONField_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"a":{"b":"<x>"}}`), 0o644))
+
+	require.NoError(t, SetJSONField(graph.SourceOrigin{FilePath: path, JSONPath: "$.a.b"}, []byte("<y>\n")))
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":{"b":"<y>"}}`, string(got))
+}
+
+func TestSetJSONField_KeepsSurroundingBytes(t *testing.T) {
+	src := "{\"z\": 1,\n  \"a\" :\t{ \"list\": [ \"x\", {\"k\": \"old\"} ] },\r\n\"m\": null }"
+	path := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+
+	require.NoError(t, SetJSONField(graph.SourceOrigin{FilePath: path, JSONPath: "$.a.list[1].k"}, []byte("a \"new\" <value>\n")))
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	before, after, ok := strings.Cut(src, `"old"`)
+	require.True(t, ok)
+	assert.Equal(t, before+`"a \"new\" <value>"`+after, string(got), "only the edited value's bytes change")
+
+	require.NoError(t, SetJSONField(graph.SourceOrigin{FilePath: path, JSONPath: "$.m"}, []byte("42")))
+	got, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(got), "\r\n\"m\": 42 }"))
+	assert.True(t, strings.HasPrefix(string(got), "{\"z\": 1,\n  \"a\" :\t{ \"list\""))
+}
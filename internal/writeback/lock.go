@@ -0,0 +1,19 @@
+package writeback
+
+import "sync"
+
+// fileLocks holds a mutex per source file path. Entries are never removed;
+// there is at most one per file a mount has written to.
+var fileLocks sync.Map // path → *sync.Mutex
+
+// LockFile serializes write-back to the source file at path and returns the
+// function that releases it. A splice shifts the offsets of every construct
+// after it in the file, so callers hold the lock from checking a node's
+// origin until the node table reflects the splice; two splices to one file
+// at once would each write the file from its own stale copy.
+func LockFile(path string) (unlock func()) {
+	v, _ := fileLocks.LoadOrStore(path, new(sync.Mutex))
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
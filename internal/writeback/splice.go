@@ -13,36 +13,56 @@ import (
 // Prevents OOM on accidentally large files (e.g., generated code, vendored blobs).
 const MaxSpliceFileSize = 100 * 1024 * 1024 // 100MB
 
+// Spliced describes what Splice wrote.
+type Spliced struct {
+	// Content replaced the range. It can differ from the content passed in,
+	// so callers size the new origin and shift later origins by it.
+	Content []byte
+	// EndLine is the 1-based line of the range's last byte (for an empty
+	// range, the line before it), and LineDelta the number of lines the
+	// splice added to the file, negative when it removed lines. Lines after
+	// EndLine move by LineDelta.
+	EndLine, LineDelta int
+}
+
 // Splice replaces the byte range identified by origin with newContent in the source file.
+// newContent's line endings are converted to the file's dominant ones, so an
+// LF edit (or formatter output) spliced into a CRLF file doesn't mix them.
 // The write is atomic: content is written to a temp file first, then renamed.
-func Splice(origin graph.SourceOrigin, newContent []byte) error {
+func Splice(origin graph.SourceOrigin, newContent []byte) (Spliced, error) {
 	info, err := os.Stat(origin.FilePath)
 	if err != nil {
-		return fmt.Errorf("stat source %s: %w", origin.FilePath, err)
+		return Spliced{}, fmt.Errorf("stat source %s: %w", origin.FilePath, err)
 	}
 	if info.Size() > MaxSpliceFileSize {
-		return fmt.Errorf("source file %s is %d bytes (max %d)", origin.FilePath, info.Size(), MaxSpliceFileSize)
+		return Spliced{}, fmt.Errorf("source file %s is %d bytes (max %d)", origin.FilePath, info.Size(), MaxSpliceFileSize)
 	}
 
 	src, err := os.ReadFile(origin.FilePath)
 	if err != nil {
-		return fmt.Errorf("read source %s: %w", origin.FilePath, err)
+		return Spliced{}, fmt.Errorf("read source %s: %w", origin.FilePath, err)
+	}
+	// Ingest parses UTF-16 files transcoded to UTF-8, so their origins
+	// index the transcoded text rather than these bytes.
+	if bytes.HasPrefix(src, []byte{0xFF, 0xFE}) || bytes.HasPrefix(src, []byte{0xFE, 0xFF}) {
+		return Spliced{}, fmt.Errorf("source %s is UTF-16: write-back supports only UTF-8 files", origin.FilePath)
 	}
 
 	start := origin.StartByte
 	end := origin.EndByte
 
 	if int(start) > len(src) || int(end) > len(src) || start > end {
-		return fmt.Errorf("invalid byte range [%d:%d] for file of length %d", start, end, len(src))
+		return Spliced{}, fmt.Errorf("invalid byte range [%d:%d] for file of length %d", start, end, len(src))
 	}
 
 	// Normalize trailing newlines: match the original region's pattern.
 	// Agents often write via echo/heredoc which appends a trailing \n that
 	// wasn't present in the original source region. Strip it to avoid
 	// introducing blank-line artifacts.
+	newContent = matchLineEndings(newContent, src)
 	originalRegion := src[start:end]
 	if len(originalRegion) > 0 && originalRegion[len(originalRegion)-1] != '\n' {
-		newContent = bytes.TrimRight(newContent, "\n")
+		newContent = bytes.TrimRight(newContent, "\r\n")
 	}
 
 	// result = prefix + newContent + suffix
@@ -51,15 +71,50 @@ func Splice(origin graph.SourceOrigin, newContent []byte) error {
 	result = append(result, newContent...)
 	result = append(result, src[end:]...)
 
-	// Atomic write: temp file in same dir, then rename
-	dir := filepath.Dir(origin.FilePath)
+	// Preserve original file permissions (reuse stat from size guard)
+	if err := writeAtomic(origin.FilePath, result, info.Mode()); err != nil {
+		return Spliced{}, err
+	}
+	endLine := 1 + bytes.Count(src[:start], []byte("\n"))
+	if end > start {
+		endLine = 1 + bytes.Count(src[:end-1], []byte("\n"))
+	} else {
+		endLine--
+	}
+	return Spliced{
+		Content:   newContent,
+		EndLine:   endLine,
+		LineDelta: bytes.Count(newContent, []byte("\n")) - bytes.Count(originalRegion, []byte("\n")),
+	}, nil
+}
+
+// matchLineEndings converts content's line endings to CRLF when most of
+// src's lines end in CRLF, and to LF when most end in LF. content is
+// returned unchanged when src has no line breaks.
+func matchLineEndings(content, src []byte) []byte {
+	crlf := bytes.Count(src, []byte("\r\n"))
+	lf := bytes.Count(src, []byte("\n")) - crlf
+	if crlf == 0 && lf == 0 {
+		return content
+	}
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	if crlf > lf {
+		content = bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n"))
+	}
+	return content
+}
+
+// writeAtomic replaces path with data by writing a temp file in the same
+// directory and renaming it over the original.
+func writeAtomic(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
 	tmp, err := os.CreateTemp(dir, ".mache-splice-*")
 	if err != nil {
 		return fmt.Errorf("create temp file: %w", err)
 	}
 	tmpName := tmp.Name()
 
-	if _, err := tmp.Write(result); err != nil {
+	if _, err := tmp.Write(data); err != nil {
 		_ = tmp.Close()
 		_ = os.Remove(tmpName) // best-effort cleanup
 		return fmt.Errorf("write temp: %w", err)
@@ -69,12 +124,11 @@ func Splice(origin graph.SourceOrigin, newContent []byte) error {
 		return fmt.Errorf("close temp: %w", err)
 	}
 
-	// Preserve original file permissions (reuse stat from size guard)
-	_ = os.Chmod(tmpName, info.Mode())
+	_ = os.Chmod(tmpName, mode)
 
-	if err := os.Rename(tmpName, origin.FilePath); err != nil {
+	if err := os.Rename(tmpName, path); err != nil {
 		_ = os.Remove(tmpName) // best-effort cleanup
-		return fmt.Errorf("rename temp to %s: %w", origin.FilePath, err)
+		return fmt.Errorf("rename temp to %s: %w", path, err)
 	}
 
 	return nil
@@ -30,7 +30,7 @@ func TestSplice_NormalizesTrailingNewline(t *testing.T) {
 	// Agent writes with trailing newline (from echo command)
 	newContent := []byte("const maxRetries = 5\n")
 
-	err := Splice(origin, newContent)
+	_, err := Splice(origin, newContent)
 	require.NoError(t, err)
 
 	got, _ := os.ReadFile(path)
@@ -51,7 +51,7 @@ func TestSplice_PreservesTrailingNewlineWhenOriginalHasOne(t *testing.T) {
 	}
 
 	// Agent writes with trailing newline — should be preserved since original had one
-	err := Splice(origin, []byte("func B() { return 1 }\n"))
+	_, err := Splice(origin, []byte("func B() { return 1 }\n"))
 	require.NoError(t, err)
 
 	got, _ := os.ReadFile(path)
@@ -71,7 +71,7 @@ func TestSplice_NoNewlineOriginal_AgentAddsNewline(t *testing.T) {
 	}
 
 	// Agent writes with trailing \n (echo artifact)
-	err := Splice(origin, []byte("y = 25\n"))
+	_, err := Splice(origin, []byte("y = 25\n"))
 	require.NoError(t, err)
 
 	got, _ := os.ReadFile(path)
@@ -93,10 +93,71 @@ func TestSplice_MultipleTrailingNewlines(t *testing.T) {
 	}
 
 	// Agent writes with two trailing newlines
-	err := Splice(origin, []byte("LINE2\n\n"))
+	_, err := Splice(origin, []byte("LINE2\n\n"))
 	require.NoError(t, err)
 
 	got, _ := os.ReadFile(path)
 	assert.Equal(t, "line1\nLINE2\nline3\n", string(got),
 		"should strip all extra trailing newlines to match original region")
 }
+
+func TestSplice_MatchesCRLFLineEndings(t *testing.T) {
+	original := "func A() {}\r\nfunc B() {}\r\nfunc C() {}\r\n"
+	path := tempFile(t, original)
+
+	// Formatter output uses LF; the file uses CRLF throughout.
+	origin := graph.SourceOrigin{FilePath: path, StartByte: 13, EndByte: 24} // "func B() {}"
+	_, err := Splice(origin, []byte("func B() {\n\treturn\n}\n"))
+	require.NoError(t, err)
+
+	got, _ := os.ReadFile(path)
+	assert.Equal(t, "func A() {}\r\nfunc B() {\r\n\treturn\r\n}\r\nfunc C() {}\r\n", string(got),
+		"no LF-only lines and no trailing newline artifact")
+}
+
+func TestSplice_SequentialCRLFEdits(t *testing.T) {
+	original := "func A() {}\r\nfunc B() {}\r\nfunc C() {}\r\n"
+	path := tempFile(t, original)
+	a := graph.SourceOrigin{FilePath: path, StartByte: 0, EndByte: 11}
+	c := graph.SourceOrigin{FilePath: path, StartByte: 26, EndByte: 37}
+
+	// Each LF becomes CRLF, so the range grows by more than the content
+	// passed in; sizing A and shifting C by what was written keeps them on
+	// their constructs for the next edit.
+	sp, err := Splice(a, []byte("func A() {\n\treturn\n}"))
+	require.NoError(t, err)
+	written := sp.Content
+	assert.Equal(t, "func A() {\r\n\treturn\r\n}", string(written))
+	assert.Equal(t, 1, sp.EndLine)
+	assert.Equal(t, 2, sp.LineDelta)
+	delta := uint32(len(written)) - (a.EndByte - a.StartByte)
+	a.EndByte = a.StartByte + uint32(len(written))
+	c.StartByte += delta
+	c.EndByte += delta
+
+	_, err = Splice(c, []byte("func C() {\n}"))
+	require.NoError(t, err)
+	_, err = Splice(a, []byte("func A() {}"))
+	require.NoError(t, err)
+
+	got, _ := os.ReadFile(path)
+	assert.Equal(t, "func A() {}\r\nfunc B() {}\r\nfunc C() {\r\n}\r\n", string(got))
+}
+
+func TestSplice_MatchesLFLineEndings(t *testing.T) {
+	original := "func A() {}\nfunc B() {}\nfunc C() {}\n"
+	path := tempFile(t, original)
+
+	origin := graph.SourceOrigin{FilePath: path, StartByte: 12, EndByte: 24}
+	_, err := Splice(origin, []byte("func B() {\r\n}\r\n"))
+	require.NoError(t, err)
+
+	got, _ := os.ReadFile(path)
+	assert.Equal(t, "func A() {}\nfunc B() {\n}\nfunc C() {}\n", string(got))
+}
+
+func TestMatchLineEndings(t *testing.T) {
+	assert.Equal(t, "a\r\nb\r\n", string(matchLineEndings([]byte("a\nb\r\n"), []byte("x\r\ny\r\nz\n"))))
+	assert.Equal(t, "a\nb\n", string(matchLineEndings([]byte("a\r\nb\n"), []byte("x\ny\r\n"))), "a tie goes to LF")
+	assert.Equal(t, "a\r\nb\n", string(matchLineEndings([]byte("a\r\nb\n"), []byte("no breaks"))))
+}
@@ -27,7 +27,7 @@ func TestSplice_ReplaceMiddle(t *testing.T) {
 		StartByte: 12, // start of "func B() {}\n"
 		EndByte:   24, // end of "func B() {}\n"
 	}
-	err := Splice(origin, []byte("func B() { return 1 }\n"))
+	_, err := Splice(origin, []byte("func B() { return 1 }\n"))
 	require.NoError(t, err)
 
 	got, _ := os.ReadFile(path)
@@ -41,7 +41,7 @@ func TestSplice_ShorterContent(t *testing.T) {
 		StartByte: 0,
 		EndByte:   39,
 	}
-	err := Splice(origin, []byte("func S() {}\n"))
+	_, err := Splice(origin, []byte("func S() {}\n"))
 	require.NoError(t, err)
 
 	got, _ := os.ReadFile(path)
@@ -55,7 +55,7 @@ func TestSplice_LongerContent(t *testing.T) {
 		StartByte: 0,
 		EndByte:   12,
 	}
-	err := Splice(origin, []byte("func LongName() { /* lots of code */ }\n"))
+	_, err := Splice(origin, []byte("func LongName() { /* lots of code */ }\n"))
 	require.NoError(t, err)
 
 	got, _ := os.ReadFile(path)
@@ -69,17 +69,27 @@ func TestSplice_EmptyContent(t *testing.T) {
 		StartByte: 4, // "BBB\n"
 		EndByte:   8,
 	}
-	err := Splice(origin, []byte{})
+	_, err := Splice(origin, []byte{})
 	require.NoError(t, err)
 
 	got, _ := os.ReadFile(path)
 	assert.Equal(t, "AAA\nCCC\n", string(got))
 }
 
+func TestSplice_RefusesUTF16(t *testing.T) {
+	content := "\xff\xfef\x00u\x00n\x00c\x00"
+	path := tempFile(t, content)
+	_, err := Splice(graph.SourceOrigin{FilePath: path, StartByte: 0, EndByte: 4}, []byte("func"))
+	assert.ErrorContains(t, err, "UTF-16")
+
+	got, _ := os.ReadFile(path)
+	assert.Equal(t, content, string(got), "file untouched")
+}
+
 func TestSplice_InvalidRange(t *testing.T) {
 	path := tempFile(t, "short")
 	// EndByte beyond file length
-	err := Splice(graph.SourceOrigin{
+	_, err := Splice(graph.SourceOrigin{
 		FilePath:  path,
 		StartByte: 0,
 		EndByte:   100,
@@ -87,7 +97,7 @@ func TestSplice_InvalidRange(t *testing.T) {
 	assert.Error(t, err)
 
 	// StartByte > EndByte
-	err = Splice(graph.SourceOrigin{
+	_, err = Splice(graph.SourceOrigin{
 		FilePath:  path,
 		StartByte: 3,
 		EndByte:   1,
@@ -99,7 +109,7 @@ func TestSplice_PreservesPermissions(t *testing.T) {
 	path := tempFile(t, "content")
 	require.NoError(t, os.Chmod(path, 0o755))
 
-	err := Splice(graph.SourceOrigin{
+	_, err := Splice(graph.SourceOrigin{
 		FilePath:  path,
 		StartByte: 0,
 		EndByte:   7,
@@ -125,7 +135,7 @@ func TestSplice_RejectsOversizedFile(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, f.Close())
 
-	err = Splice(graph.SourceOrigin{
+	_, err = Splice(graph.SourceOrigin{
 		FilePath:  path,
 		StartByte: 0,
 		EndByte:   13,
@@ -134,7 +144,7 @@ func TestSplice_RejectsOversizedFile(t *testing.T) {
 }
 
 func TestSplice_NonexistentFile(t *testing.T) {
-	err := Splice(graph.SourceOrigin{
+	_, err := Splice(graph.SourceOrigin{
 		FilePath:  filepath.Join(t.TempDir(), "nope.go"),
 		StartByte: 0,
 		EndByte:   5,
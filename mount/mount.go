@@ -11,11 +11,18 @@ import (
 	"github.com/agentic-research/mache/internal/nfsmount"
 )
 
+// CacheTimeouts controls kernel NFS attribute caching.
+// The zero value disables caching (noac).
+type CacheTimeouts = nfsmount.CacheTimeouts
+
 // Options configures the NFS mount.
 type Options struct {
 	// ExtraNFSOpts is appended verbatim to the default NFS mount options
 	// (comma-separated, e.g. "rsize=32768,wsize=32768").
 	ExtraNFSOpts string
+	// Cache sets attribute cache timeouts. Raise them when the graph does
+	// not change while mounted; leave zero if it does.
+	Cache CacheTimeouts
 }
 
 // Server wraps an NFS server lifecycle.
@@ -32,15 +39,17 @@ func (s *Server) Close() error { return s.inner.Close() }
 // Pass nil for opts to use defaults.
 func NFS(g graph.Graph, mountPoint string, opts *Options) (*Server, error) {
 	gfs := nfsmount.NewGraphFS(g, &api.Topology{Version: "v1"})
-	srv, err := nfsmount.NewServer(gfs)
+	srv, err := nfsmount.NewServer(gfs, "")
 	if err != nil {
 		return nil, err
 	}
 	var extraOpts string
+	var cache CacheTimeouts
 	if opts != nil {
 		extraOpts = opts.ExtraNFSOpts
+		cache = opts.Cache
 	}
-	if err := nfsmount.Mount(srv.Port(), mountPoint, false, extraOpts); err != nil {
+	if err := nfsmount.Mount(srv.MountHost(), srv.Port(), mountPoint, false, cache, extraOpts); err != nil {
 		_ = srv.Close()
 		return nil, err
 	}
@@ -121,21 +121,23 @@ func realWriteBack(store *graph.MemoryStore) func(string, graph.SourceOrigin, []
 
 		// 4. Splice into source file
 		oldLen := origin.EndByte - origin.StartByte
-		if err := writeback.Splice(origin, formatted); err != nil {
+		spliced, err := writeback.Splice(origin, formatted)
+		if err != nil {
 			return err
 		}
+		written := spliced.Content
 
 		// 5. Surgical node update
 		newOrigin := &graph.SourceOrigin{
 			FilePath:  origin.FilePath,
 			StartByte: origin.StartByte,
-			EndByte:   origin.StartByte + uint32(len(formatted)),
+			EndByte:   origin.StartByte + uint32(len(written)),
 		}
-		delta := int32(len(formatted)) - int32(oldLen)
+		delta := int32(len(written)) - int32(oldLen)
 		if delta != 0 {
 			store.ShiftOrigins(origin.FilePath, origin.EndByte, delta)
 		}
-		_ = store.UpdateNodeContent(nodeID, formatted, newOrigin, time.Now())
+		_ = store.UpdateNodeContent(nodeID, written, newOrigin, time.Now())
 		store.WriteStatus.Store(filepath.Dir(nodeID), "ok")
 		node.DraftData = nil
 